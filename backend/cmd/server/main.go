@@ -11,6 +11,12 @@ import (
 
 	"pryanik_studio/internal/api"
 	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/currency"
+	"pryanik_studio/internal/i18n"
+	"pryanik_studio/internal/invoice"
+	"pryanik_studio/internal/payment"
+	"pryanik_studio/internal/reminder"
+	"pryanik_studio/internal/security"
 	"pryanik_studio/internal/storage"
 	"pryanik_studio/internal/utils"
 )
@@ -51,6 +57,19 @@ func main() {
 	// Инициализируем репозиторий
 	repo := storage.NewPostgresRepository(db, log)
 
+	// Загружаем реестр языков; цепочки отката переводов берутся из него
+	languages := i18n.NewRegistry(repo, log)
+	if err := languages.Load(context.Background()); err != nil {
+		log.WithError(err).Fatal("Ошибка при загрузке реестра языков")
+	}
+	repo.SetFallbackChain(languages.FallbackChain)
+
+	// Загружаем курсы валют для пересчета цен
+	converter := currency.NewConverter(repo, cfg.Currency.ReferenceCurrency, log)
+	if err := converter.Load(context.Background()); err != nil {
+		log.WithError(err).Fatal("Ошибка при загрузке курсов валют")
+	}
+
 	// Инициализируем отправитель email
 	var emailSender utils.Sender
 
@@ -67,8 +86,31 @@ func main() {
 		emailSender = utils.NewGomailSender(cfg.Email, log)
 	}
 
+	// Инициализируем платежного провайдера (nil, если онлайн-оплата отключена)
+	paymentProvider, err := payment.NewProvider(cfg.Payment)
+	if err != nil {
+		log.WithError(err).Fatal("Ошибка при инициализации платежного провайдера")
+	}
+
+	// Загружаем шрифты счетов; без них сервер работает, но счета не формируются
+	invoices, err := invoice.NewGenerator(repo, &cfg)
+	if err != nil {
+		log.WithError(err).Warn("Не удалось загрузить шрифты счетов, формирование счетов отключено")
+	}
+
+	// Запускаем отправку напоминаний о незавершенных заказах, уведомлений о снижении цен
+	// и уведомлений о сообщениях с формы обратной связи; останавливаются при завершении сервера
+	jobs, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	reminders := reminder.NewScheduler(repo, repo, emailSender, security.NewLinkSigner(cfg.Security.LinkSecret), &cfg, log)
+	go reminders.Run(jobs)
+	priceWatcher := reminder.NewPriceWatcher(repo, repo, emailSender, &cfg, log)
+	go priceWatcher.Run(jobs)
+	contactNotifier := reminder.NewContactNotifier(repo, emailSender, &cfg, log)
+	go contactNotifier.Run(jobs)
+
 	// Инициализируем роутер
-	router := api.SetupRouter(repo, emailSender, &cfg, log)
+	router := api.SetupRouter(repo, emailSender, paymentProvider, invoices, contactNotifier, languages, converter, &cfg, log)
 
 	// Создаем HTTP-сервер
 	server := &http.Server{
@@ -101,6 +143,7 @@ func main() {
 	// Ожидаем сигнал завершения
 	<-quit
 	log.Info("Завершение работы сервера...")
+	stopJobs()
 
 	// Создаем контекст с таймаутом для корректного завершения
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
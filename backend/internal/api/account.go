@@ -0,0 +1,354 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/auth"
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/i18n"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+	"pryanik_studio/internal/utils"
+)
+
+// Сообщения об отправке ссылки для входа на разных языках. Сообщение не зависит от того,
+// есть ли учетная запись с этим email
+var loginLinkMessages = map[string]string{
+	"ru": "Мы отправили ссылку для входа на указанный email",
+	"en": "We have sent a sign-in link to the email address provided",
+	"es": "Hemos enviado un enlace de acceso al correo electrónico indicado",
+}
+
+// maxOrdersPageSize максимальный размер страницы истории заказов
+const maxOrdersPageSize = 50
+
+// AccountHandler обработчик запросов личного кабинета покупателя: вход по ссылке из письма,
+// профиль, сохраненные адреса и история заказов
+type AccountHandler struct {
+	repo        storage.CustomerRepository
+	jwtAuth     *auth.JWTAuth
+	languages   *i18n.Registry
+	emailSender utils.Sender
+	config      config.AccountConfig
+	publicURL   string
+	logger      *logrus.Logger
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+// NewAccountHandler создает новый экземпляр AccountHandler
+func NewAccountHandler(
+	repo storage.CustomerRepository,
+	jwtAuth *auth.JWTAuth,
+	languages *i18n.Registry,
+	emailSender utils.Sender,
+	cfg *config.Config,
+	logger *logrus.Logger,
+) *AccountHandler {
+	return &AccountHandler{
+		repo:        repo,
+		jwtAuth:     jwtAuth,
+		languages:   languages,
+		emailSender: emailSender,
+		config:      cfg.Account,
+		publicURL:   cfg.Server.PublicURL,
+		logger:      logger,
+	}
+}
+
+// RequestLoginLink обработчик для отправки одноразовой ссылки для входа на email покупателя.
+// Повторная ссылка на тот же email раньше заданного интервала не отправляется
+func (h *AccountHandler) RequestLoginLink(c *gin.Context) {
+	var request models.LoginLinkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	if request.Language == "" {
+		request.Language = i18n.FromContext(c)
+	} else if !h.languages.IsEnabled(request.Language) {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "Language", Message: "Неподдерживаемый язык"},
+		}))
+		return
+	}
+
+	ctx := c.Request.Context()
+	h.cleanup(ctx)
+
+	token, err := newUploadID()
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при создании токена для входа")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при отправке ссылки для входа"))
+		return
+	}
+
+	email := normalizeEmail(request.Email)
+	ttl := time.Duration(h.config.LoginLinkTTLMinutes) * time.Minute
+	interval := time.Duration(h.config.LoginLinkIntervalSeconds) * time.Second
+	tokenHash := secretHash(token)
+	created, err := h.repo.CreateLoginToken(ctx, email, tokenHash, ttl, interval)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при отправке ссылки для входа"))
+		return
+	}
+
+	if created {
+		link := &models.LoginLink{
+			Email:     email,
+			Language:  request.Language,
+			URL:       fmt.Sprintf("%s/account/login?token=%s", h.publicURL, url.QueryEscape(token)),
+			ExpiresAt: time.Now().Add(ttl),
+		}
+		if err := h.emailSender.SendLoginLink(link); err != nil {
+			h.logger.WithError(err).Error("Ошибка при отправке ссылки для входа")
+			// Ссылка не отправлена: токен удаляется, чтобы интервал не блокировал повторный запрос
+			if err := h.repo.DeleteLoginToken(ctx, tokenHash); err != nil {
+				h.logger.WithError(err).Warn("Повторная ссылка для входа будет доступна только после интервала")
+			}
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при отправке ссылки для входа"))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"message": h.languages.Localize(loginLinkMessages, request.Language),
+	}))
+}
+
+// VerifyLoginLink обработчик для входа по токену из ссылки. Токен погашается, покупателю
+// выдается токен личного кабинета; при первом входе создается учетная запись
+func (h *AccountHandler) VerifyLoginLink(c *gin.Context) {
+	var request models.LoginVerifyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	customer, err := h.repo.ConsumeLoginToken(c.Request.Context(), secretHash(request.Token))
+	if err != nil {
+		if errors.Is(err, storage.ErrLoginTokenInvalid) {
+			h.logger.Warnf("Попытка входа по недействительной ссылке от %s", c.ClientIP())
+			c.JSON(http.StatusUnauthorized, models.NewErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при входе"))
+		return
+	}
+
+	ttl := time.Duration(h.config.TokenTTLHours) * time.Hour
+	token, expiresAt, err := h.jwtAuth.GenerateCustomerToken(customer.ID, customer.Email, ttl)
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка генерации токена покупателя")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при входе"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(models.CustomerLoginResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		Customer:  customer,
+	}))
+}
+
+// GetProfile обработчик для получения данных покупателя
+func (h *AccountHandler) GetProfile(c *gin.Context) {
+	customerID, _ := auth.CustomerFromContext(c)
+
+	customer, err := h.repo.GetCustomer(c.Request.Context(), customerID)
+	if err != nil {
+		h.customerError(c, err, "Ошибка при получении данных покупателя")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(customer))
+}
+
+// UpdateProfile обработчик для изменения имени и телефона покупателя
+func (h *AccountHandler) UpdateProfile(c *gin.Context) {
+	customerID, _ := auth.CustomerFromContext(c)
+
+	var request models.CustomerUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	phone := strings.TrimSpace(request.Phone)
+	if phone != "" {
+		normalized, ok := normalizePhone(phone)
+		if !ok {
+			c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+				{Field: "Phone", Message: "Некорректный номер телефона"},
+			}))
+			return
+		}
+		phone = normalized
+	}
+
+	customer, err := h.repo.UpdateCustomer(c.Request.Context(), customerID, strings.TrimSpace(request.Name), phone)
+	if err != nil {
+		h.customerError(c, err, "Ошибка при изменении данных покупателя")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(customer))
+}
+
+// GetOrders обработчик для получения истории заказов покупателя, начиная с последних.
+// Заказы связываются с покупателем по email
+func (h *AccountHandler) GetOrders(c *gin.Context) {
+	_, email := auth.CustomerFromContext(c)
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > maxOrdersPageSize {
+		pageSize = maxOrdersPageSize
+	}
+
+	orders, err := h.repo.GetOrdersByEmail(c.Request.Context(), email, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении заказов"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(orders))
+}
+
+// GetAddresses обработчик для получения сохраненных адресов покупателя
+func (h *AccountHandler) GetAddresses(c *gin.Context) {
+	customerID, _ := auth.CustomerFromContext(c)
+
+	addresses, err := h.repo.GetCustomerAddresses(c.Request.Context(), customerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении адресов"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(addresses))
+}
+
+// CreateAddress обработчик для сохранения нового адреса покупателя
+func (h *AccountHandler) CreateAddress(c *gin.Context) {
+	address, ok := h.bindAddress(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.CreateCustomerAddress(c.Request.Context(), &address); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении адреса"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(address))
+}
+
+// UpdateAddress обработчик для изменения адреса покупателя
+func (h *AccountHandler) UpdateAddress(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID адреса"))
+		return
+	}
+
+	address, ok := h.bindAddress(c)
+	if !ok {
+		return
+	}
+	address.ID = id
+
+	if err := h.repo.UpdateCustomerAddress(c.Request.Context(), &address); err != nil {
+		h.customerError(c, err, "Ошибка при сохранении адреса")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(address))
+}
+
+// DeleteAddress обработчик для удаления адреса покупателя
+func (h *AccountHandler) DeleteAddress(c *gin.Context) {
+	customerID, _ := auth.CustomerFromContext(c)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID адреса"))
+		return
+	}
+
+	if err := h.repo.DeleteCustomerAddress(c.Request.Context(), customerID, id); err != nil {
+		h.customerError(c, err, "Ошибка при удалении адреса")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil))
+}
+
+// bindAddress разбирает запрос на сохранение адреса текущего покупателя.
+// При ошибке отправляет ответ клиенту и возвращает false
+func (h *AccountHandler) bindAddress(c *gin.Context) (models.CustomerAddress, bool) {
+	customerID, _ := auth.CustomerFromContext(c)
+
+	var request models.CustomerAddressRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return models.CustomerAddress{}, false
+	}
+
+	return models.CustomerAddress{
+		CustomerID: customerID,
+		Label:      strings.TrimSpace(request.Label),
+		Address:    *normalizeAddress(&request.Address),
+		IsDefault:  request.IsDefault,
+	}, true
+}
+
+// customerError отправляет ответ клиенту по ошибке работы с учетной записью покупателя
+func (h *AccountHandler) customerError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, storage.ErrCustomerNotFound):
+		// Учетная запись удалена после выдачи токена
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(err.Error()))
+	case errors.Is(err, storage.ErrCustomerAddressNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(message))
+	}
+}
+
+// cleanup удаляет истекшие токены для входа не чаще одного раза в час
+func (h *AccountHandler) cleanup(ctx context.Context) {
+	h.mu.Lock()
+	if time.Since(h.lastCleanup) < time.Hour {
+		h.mu.Unlock()
+		return
+	}
+	h.lastCleanup = time.Now()
+	h.mu.Unlock()
+
+	deleted, err := h.repo.DeleteExpiredLoginTokens(ctx)
+	if err != nil {
+		return
+	}
+	if deleted > 0 {
+		h.logger.Infof("Удалено истекших токенов для входа: %d", deleted)
+	}
+}
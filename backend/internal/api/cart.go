@@ -0,0 +1,504 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/i18n"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+)
+
+const (
+	// cartCookieName cookie с токеном доступа к корзине
+	cartCookieName = "cart_token"
+	// cartCleanupInterval интервал удаления истекших корзин
+	cartCleanupInterval = time.Hour
+)
+
+// CartHandler обработчик запросов для корзин покупателей. Корзина создается без авторизации,
+// доступ к ней дает случайный токен из cookie. Цены позиций пересчитываются по текущим ценам
+// товаров при каждом получении корзины, заказ оформляется по тем же правилам, что и POST /orders
+type CartHandler struct {
+	repo   storage.CartRepository
+	orders *OrderHandler
+	ttl    time.Duration
+	logger *logrus.Logger
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+// NewCartHandler создает новый экземпляр CartHandler
+func NewCartHandler(repo storage.CartRepository, orders *OrderHandler, cfg config.CartConfig, logger *logrus.Logger) *CartHandler {
+	return &CartHandler{
+		repo:   repo,
+		orders: orders,
+		ttl:    time.Duration(cfg.TTLDays) * 24 * time.Hour,
+		logger: logger,
+	}
+}
+
+// CreateCart обработчик для создания пустой корзины; токен доступа сохраняется в cookie
+func (h *CartHandler) CreateCart(c *gin.Context) {
+	ctx := c.Request.Context()
+	h.cleanup(ctx)
+
+	id, err := newUploadID()
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при генерации ID корзины")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании корзины"))
+		return
+	}
+	token, err := newUploadID()
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при генерации токена корзины")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании корзины"))
+		return
+	}
+
+	cart, err := h.repo.CreateCart(ctx, id, secretHash(token), h.ttl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании корзины"))
+		return
+	}
+
+	if err := h.priceCart(ctx, &cart, i18n.FromContext(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при расчете корзины"))
+		return
+	}
+
+	h.setCookie(c, token)
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(cart))
+}
+
+// GetCart обработчик для получения корзины с пересчитанными ценами и предупреждениями по позициям
+func (h *CartHandler) GetCart(c *gin.Context) {
+	cart, ok := h.loadCart(c)
+	if !ok {
+		return
+	}
+
+	if err := h.priceCart(c.Request.Context(), &cart, i18n.FromContext(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при расчете корзины"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(cart))
+}
+
+// AddItem обработчик для добавления товара в корзину. Если такая позиция (тот же товар,
+// вариант, расчет и персонализация) уже есть, количество в ней увеличивается
+func (h *CartHandler) AddItem(c *gin.Context) {
+	cart, ok := h.loadCart(c)
+	if !ok {
+		return
+	}
+
+	var request models.CartItemRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на добавление товара в корзину")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	if message := h.orders.quantityError(request.Quantity); message != "" {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "Quantity", Message: message},
+		}))
+		return
+	}
+
+	item := models.OrderItem{
+		ProductID:       request.ProductID,
+		VariantID:       request.VariantID,
+		EstimateID:      request.EstimateID,
+		Personalization: request.Personalization,
+		Quantity:        request.Quantity,
+	}
+
+	// Ищем такую же позицию в корзине
+	var existing *models.CartItem
+	key := itemKey(item)
+	for i := range cart.Items {
+		if itemKey(cart.Items[i].OrderItem()) == key {
+			existing = &cart.Items[i]
+			item.Quantity += existing.Quantity
+			break
+		}
+	}
+	if existing == nil && len(cart.Items) >= h.orders.limits.MaxLines {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "Items", Message: "В корзине уже максимальное количество позиций"},
+		}))
+		return
+	}
+
+	priced, ok := h.checkItem(c, item)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var err error
+	if existing != nil {
+		err = h.repo.UpdateCartItem(ctx, cart.ID, existing.ID, item.Quantity, priced.Price, h.ttl)
+	} else {
+		err = h.repo.AddCartItem(ctx, cart.ID, &models.CartItem{
+			ProductID:       item.ProductID,
+			VariantID:       item.VariantID,
+			EstimateID:      item.EstimateID,
+			Personalization: item.Personalization,
+			Quantity:        item.Quantity,
+			SavedPrice:      priced.Price,
+		}, h.ttl)
+	}
+	if err != nil {
+		h.cartError(c, err, "Ошибка при добавлении товара в корзину")
+		return
+	}
+
+	h.respondCart(c, cart.ID)
+}
+
+// UpdateItem обработчик для изменения количества в позиции корзины
+func (h *CartHandler) UpdateItem(c *gin.Context) {
+	cart, ok := h.loadCart(c)
+	if !ok {
+		return
+	}
+	itemID, ok := cartItemID(c, cart)
+	if !ok {
+		return
+	}
+
+	var request models.CartItemUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на изменение позиции корзины")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	var item models.OrderItem
+	for _, existing := range cart.Items {
+		if existing.ID == itemID {
+			item = existing.OrderItem()
+		}
+	}
+	item.Quantity = request.Quantity
+
+	priced, ok := h.checkItem(c, item)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.UpdateCartItem(c.Request.Context(), cart.ID, itemID, item.Quantity, priced.Price, h.ttl); err != nil {
+		h.cartError(c, err, "Ошибка при изменении позиции корзины")
+		return
+	}
+
+	h.respondCart(c, cart.ID)
+}
+
+// DeleteItem обработчик для удаления позиции из корзины
+func (h *CartHandler) DeleteItem(c *gin.Context) {
+	cart, ok := h.loadCart(c)
+	if !ok {
+		return
+	}
+	itemID, ok := cartItemID(c, cart)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteCartItem(c.Request.Context(), cart.ID, itemID, h.ttl); err != nil {
+		h.cartError(c, err, "Ошибка при удалении позиции корзины")
+		return
+	}
+
+	h.respondCart(c, cart.ID)
+}
+
+// Checkout обработчик для оформления заказа из корзины. Позиции проверяются и оцениваются
+// так же, как при создании заказа; ошибки позиций Items[i] относятся к позициям корзины
+// в порядке добавления. После оформления корзина не изменяется
+func (h *CartHandler) Checkout(c *gin.Context) {
+	cart, ok := h.loadCart(c)
+	if !ok {
+		return
+	}
+
+	var request models.CartCheckoutRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на оформление заказа из корзины")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	if len(cart.Items) == 0 {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Корзина пуста"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.repo.StartCartCheckout(ctx, cart.ID); err != nil {
+		h.cartError(c, err, "Ошибка при оформлении заказа")
+		return
+	}
+
+	order := models.OrderRequest{
+		Name:           request.Name,
+		Email:          request.Email,
+		Phone:          request.Phone,
+		Comment:        request.Comment,
+		Language:       request.Language,
+		PromoCode:      request.PromoCode,
+		ShippingMethod: request.ShippingMethod,
+		Address:        request.Address,
+		DraftID:        request.DraftID,
+	}
+	for _, item := range cart.Items {
+		order.Items = append(order.Items, item.OrderItem())
+	}
+
+	// Отметка об оформлении снимается или фиксируется, даже если клиент отключился
+	ctx = context.WithoutCancel(ctx)
+	orderID, ok := h.orders.placeOrder(c, order)
+	if !ok {
+		if err := h.repo.CancelCartCheckout(ctx, cart.ID); err != nil {
+			h.logger.WithError(err).Errorf("Корзина %s не освобождена после ошибки оформления заказа", cart.ID)
+		}
+		return
+	}
+
+	if err := h.repo.FinishCartCheckout(ctx, cart.ID, orderID); err != nil {
+		h.logger.WithError(err).Errorf("Корзина %s не связана с заказом ID=%d", cart.ID, orderID)
+	}
+}
+
+// loadCart возвращает корзину из параметра id, если токен из cookie дает к ней доступ.
+// При ошибке отправляет ответ клиенту и возвращает false
+func (h *CartHandler) loadCart(c *gin.Context) (models.Cart, bool) {
+	id := c.Param("id")
+	if !uploadIDPattern.MatchString(id) {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse("Корзина не найдена"))
+		return models.Cart{}, false
+	}
+
+	cart, err := h.repo.GetCart(c.Request.Context(), id)
+	if err != nil {
+		h.cartError(c, err, "Ошибка при получении корзины")
+		return cart, false
+	}
+
+	// Чужая корзина неотличима от несуществующей
+	token, err := c.Cookie(cartCookieName)
+	if err != nil || subtle.ConstantTimeCompare([]byte(secretHash(token)), []byte(cart.TokenHash)) != 1 {
+		h.logger.Warnf("Запрос к корзине %s без действительного токена от %s", id, c.ClientIP())
+		c.JSON(http.StatusNotFound, models.NewErrorResponse("Корзина не найдена"))
+		return cart, false
+	}
+
+	return cart, true
+}
+
+// checkItem проверяет позицию корзины по правилам заказа и рассчитывает ее цену.
+// При ошибке отправляет ответ клиенту и возвращает false
+func (h *CartHandler) checkItem(c *gin.Context, item models.OrderItem) (models.OrderItem, bool) {
+	ctx := c.Request.Context()
+	product, err := h.orders.productRepo.GetProductByID(ctx, item.ProductID, i18n.FromContext(c))
+	if errors.Is(err, storage.ErrProductNotFound) {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "ProductID", Message: "Товар не найден"},
+		}))
+		return item, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при проверке товара"))
+		return item, false
+	}
+
+	if validationErr := lineError(product, item, h.orders.quantityError(item.Quantity)); validationErr != nil {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{*validationErr}))
+		return item, false
+	}
+
+	return h.orders.priceItem(c, orderLine{item: item, product: product})
+}
+
+// priceCart пересчитывает позиции корзины по текущим ценам товаров и сумму доступных позиций.
+// Позиции, которые нельзя заказать, отмечаются недоступными с причиной
+func (h *CartHandler) priceCart(ctx context.Context, cart *models.Cart, language string) error {
+	products := make(map[int64]models.ProductDetail)
+	var subtotal models.Money
+
+	for i := range cart.Items {
+		item := &cart.Items[i]
+
+		priced, warning, err := h.priceCartItem(ctx, item, products, language)
+		if err != nil {
+			return err
+		}
+		if warning != "" {
+			item.Warning = warning
+			continue
+		}
+
+		total, err := subtotal.Add(priced.Price.Mul(item.Quantity))
+		if err != nil {
+			item.Warning = "Товар указан в другой валюте, чем остальные товары корзины"
+			continue
+		}
+		subtotal = total
+
+		price := priced.Price
+		item.Price = &price
+		item.SKU = priced.SKU
+		item.VariantName = priced.VariantName
+		item.Available = true
+		if item.SavedPrice != price {
+			previous := item.SavedPrice
+			item.PreviousPrice = &previous
+			item.Warning = "Цена изменилась после добавления товара в корзину"
+		}
+	}
+
+	if subtotal.Currency == "" {
+		subtotal = models.NewMoney(0, h.orders.languages.CurrencyFor(language))
+	}
+	cart.Subtotal = subtotal
+	cart.Currency = subtotal.Currency
+	return nil
+}
+
+// priceCartItem проверяет позицию корзины по текущим данным товара и рассчитывает ее цену.
+// Если позицию нельзя заказать, возвращает причину
+func (h *CartHandler) priceCartItem(ctx context.Context, item *models.CartItem, products map[int64]models.ProductDetail, language string) (models.OrderItem, string, error) {
+	product, ok := products[item.ProductID]
+	if !ok {
+		var err error
+		product, err = h.orders.productRepo.GetProductByID(ctx, item.ProductID, language)
+		if errors.Is(err, storage.ErrProductNotFound) {
+			return models.OrderItem{}, "Товар больше не продается", nil
+		}
+		if err != nil {
+			return models.OrderItem{}, "", err
+		}
+		products[item.ProductID] = product
+	}
+
+	item.ProductName = product.Name
+	if len(product.Images) > 0 {
+		item.ProductImage = product.Images[0]
+	}
+
+	orderItem := item.OrderItem()
+	if validationErr := lineError(product, orderItem, h.orders.quantityError(item.Quantity)); validationErr != nil {
+		return orderItem, validationErr.Message, nil
+	}
+
+	return h.orders.itemPrice(ctx, orderLine{item: orderItem, product: product})
+}
+
+// respondCart отправляет клиенту корзину после изменения и продлевает срок действия cookie
+func (h *CartHandler) respondCart(c *gin.Context, id string) {
+	ctx := c.Request.Context()
+	cart, err := h.repo.GetCart(ctx, id)
+	if err != nil {
+		h.cartError(c, err, "Ошибка при получении корзины")
+		return
+	}
+
+	if err := h.priceCart(ctx, &cart, i18n.FromContext(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при расчете корзины"))
+		return
+	}
+
+	if token, err := c.Cookie(cartCookieName); err == nil {
+		h.setCookie(c, token)
+	}
+	c.JSON(http.StatusOK, models.NewSuccessResponse(cart))
+}
+
+// setCookie сохраняет токен корзины в cookie на срок действия корзины
+func (h *CartHandler) setCookie(c *gin.Context, token string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(
+		cartCookieName,
+		token,
+		int(h.ttl/time.Second),
+		"/api/carts",
+		"",
+		c.Request.TLS != nil, // Secure только для HTTPS
+		true,
+	)
+}
+
+// cartError отправляет клиенту ответ на ошибку хранилища корзин
+func (h *CartHandler) cartError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, storage.ErrCartNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorResponse("Корзина не найдена"))
+	case errors.Is(err, storage.ErrCartItemNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorResponse("Позиция корзины не найдена"))
+	case errors.Is(err, storage.ErrCartCheckedOut):
+		c.JSON(http.StatusConflict, models.NewErrorResponse("Заказ по корзине уже оформлен"))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(message))
+	}
+}
+
+// cleanup удаляет истекшие корзины не чаще одного раза в cartCleanupInterval
+func (h *CartHandler) cleanup(ctx context.Context) {
+	h.mu.Lock()
+	if time.Since(h.lastCleanup) < cartCleanupInterval {
+		h.mu.Unlock()
+		return
+	}
+	h.lastCleanup = time.Now()
+	h.mu.Unlock()
+
+	deleted, err := h.repo.DeleteExpiredCarts(ctx)
+	if err != nil {
+		return
+	}
+	if deleted > 0 {
+		h.logger.Infof("Удалено истекших корзин: %d", deleted)
+	}
+}
+
+// cartItemID возвращает ID позиции из параметра itemId, если позиция есть в корзине.
+// При ошибке отправляет ответ клиенту и возвращает false
+func cartItemID(c *gin.Context, cart models.Cart) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("itemId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID позиции корзины"))
+		return 0, false
+	}
+
+	for _, item := range cart.Items {
+		if item.ID == id {
+			return id, true
+		}
+	}
+
+	c.JSON(http.StatusNotFound, models.NewErrorResponse("Позиция корзины не найдена"))
+	return 0, false
+}
+
+// secretHash возвращает хеш секретного токена (корзины, ссылки для входа), который хранится в базе вместо него
+func secretHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,259 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/i18n"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/reminder"
+	"pryanik_studio/internal/security"
+	"pryanik_studio/internal/storage"
+)
+
+// Сообщения об отказе от напоминаний на разных языках
+var unsubscribeMessages = map[string]string{
+	"ru": "Вы больше не будете получать напоминания о незавершенных заказах",
+	"en": "You will no longer receive reminders about unfinished orders",
+	"es": "Ya no recibirá recordatorios sobre pedidos sin terminar",
+}
+
+// draftEmailPeriod период, за который ограничивается количество черновиков на один email
+const draftEmailPeriod = 24 * time.Hour
+
+// CheckoutDraftHandler обработчик запросов для черновиков заказов и напоминаний о них
+type CheckoutDraftHandler struct {
+	repo      storage.CheckoutDraftRepository
+	carts     storage.CartRepository
+	languages *i18n.Registry
+	signer    *security.LinkSigner
+	limits    config.OrderConfig
+	// Максимальное количество черновиков на один email за draftEmailPeriod: напоминания
+	// отправляются на непроверенный адрес, поэтому его нельзя засыпать письмами
+	maxPerEmail int
+	logger      *logrus.Logger
+}
+
+// NewCheckoutDraftHandler создает новый экземпляр CheckoutDraftHandler
+func NewCheckoutDraftHandler(
+	repo storage.CheckoutDraftRepository,
+	carts storage.CartRepository,
+	languages *i18n.Registry,
+	signer *security.LinkSigner,
+	cfg *config.Config,
+	logger *logrus.Logger,
+) *CheckoutDraftHandler {
+	return &CheckoutDraftHandler{
+		repo:        repo,
+		carts:       carts,
+		languages:   languages,
+		signer:      signer,
+		limits:      cfg.Order,
+		maxPerEmail: cfg.Reminder.MaxDraftsPerEmail,
+		logger:      logger,
+	}
+}
+
+// CreateDraft обработчик для сохранения черновика заказа, пока покупатель заполняет форму.
+// Возвращает черновик с ID, по которому его можно изменять и восстанавливать
+func (h *CheckoutDraftHandler) CreateDraft(c *gin.Context) {
+	id, err := newUploadID()
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при создании идентификатора черновика заказа")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении черновика заказа"))
+		return
+	}
+
+	draft, ok := h.bindDraft(c, id)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.CreateCheckoutDraft(c.Request.Context(), &draft); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении черновика заказа"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(draft))
+}
+
+// UpdateDraft обработчик для изменения черновика заказа. Время до напоминания отсчитывается
+// от последнего изменения
+func (h *CheckoutDraftHandler) UpdateDraft(c *gin.Context) {
+	id, ok := draftID(c)
+	if !ok {
+		return
+	}
+
+	draft, ok := h.bindDraft(c, id)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.UpdateCheckoutDraft(c.Request.Context(), &draft); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrCheckoutDraftNotFound):
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Черновик заказа не найден"))
+		case errors.Is(err, storage.ErrCheckoutDraftConverted):
+			c.JSON(http.StatusConflict, models.NewErrorResponse("Заказ по черновику уже оформлен"))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении черновика заказа"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(draft))
+}
+
+// GetDraft обработчик для восстановления черновика заказа по ссылке из напоминания
+func (h *CheckoutDraftHandler) GetDraft(c *gin.Context) {
+	id, ok := draftID(c)
+	if !ok {
+		return
+	}
+
+	draft, err := h.repo.GetCheckoutDraft(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrCheckoutDraftNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Черновик заказа не найден"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении черновика заказа"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(draft))
+}
+
+// Unsubscribe обработчик для отказа от напоминаний по подписанной ссылке из письма
+func (h *CheckoutDraftHandler) Unsubscribe(c *gin.Context) {
+	email := normalizeEmail(c.Query("email"))
+	if email == "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Не указан email"))
+		return
+	}
+
+	err := h.signer.Verify(reminder.UnsubscribeResource(email), c.Query("expires"), c.Query("signature"))
+	switch {
+	case errors.Is(err, security.ErrSignatureExpired):
+		c.JSON(http.StatusGone, models.NewErrorResponse(err.Error()))
+		return
+	case err != nil:
+		h.logger.Warnf("Недействительная ссылка на отказ от напоминаний от %s", c.ClientIP())
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(err.Error()))
+		return
+	}
+
+	if err := h.repo.UnsubscribeFromReminders(c.Request.Context(), email); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при отказе от напоминаний"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"message": h.languages.Localize(unsubscribeMessages, i18n.FromContext(c)),
+	}))
+}
+
+// GetReminderStats обработчик для получения статистики напоминаний за последние days дней
+func (h *CheckoutDraftHandler) GetReminderStats(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный период"))
+		return
+	}
+
+	stats, err := h.repo.GetReminderStats(c.Request.Context(), time.Duration(days)*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении статистики напоминаний"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(stats))
+}
+
+// bindDraft разбирает и проверяет запрос на сохранение черновика с ID id.
+// При ошибке отправляет ответ клиенту и возвращает false
+func (h *CheckoutDraftHandler) bindDraft(c *gin.Context, id string) (models.CheckoutDraft, bool) {
+	var request models.CheckoutDraftRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return models.CheckoutDraft{}, false
+	}
+
+	var validationErrors []models.ValidationError
+	if request.Language == "" {
+		request.Language = i18n.FromContext(c)
+	} else if !h.languages.IsEnabled(request.Language) {
+		validationErrors = append(validationErrors, models.ValidationError{Field: "Language", Message: "Неподдерживаемый язык"})
+	}
+
+	if len(request.Items) > h.limits.MaxLines {
+		validationErrors = append(validationErrors, models.ValidationError{
+			Field:   "Items",
+			Message: fmt.Sprintf("В заказе может быть не более %d позиций", h.limits.MaxLines),
+		})
+	} else {
+		for i, item := range request.Items {
+			if item.Quantity < 1 || item.Quantity > h.limits.MaxItemQuantity {
+				validationErrors = append(validationErrors, itemError(i, "Quantity",
+					fmt.Sprintf("Количество должно быть от 1 до %d", h.limits.MaxItemQuantity)))
+			}
+		}
+	}
+
+	if request.CartID != nil {
+		_, err := h.carts.GetCart(c.Request.Context(), *request.CartID)
+		if errors.Is(err, storage.ErrCartNotFound) {
+			validationErrors = append(validationErrors, models.ValidationError{Field: "CartID", Message: "Корзина не найдена"})
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении черновика заказа"))
+			return models.CheckoutDraft{}, false
+		}
+	}
+
+	if len(validationErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse(validationErrors))
+		return models.CheckoutDraft{}, false
+	}
+
+	email := normalizeEmail(request.Email)
+	count, err := h.repo.CountCheckoutDrafts(c.Request.Context(), email, id, draftEmailPeriod)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении черновика заказа"))
+		return models.CheckoutDraft{}, false
+	}
+	if count >= h.maxPerEmail {
+		h.logger.Warnf("Превышено количество черновиков заказа для одного email от %s", c.ClientIP())
+		c.JSON(http.StatusTooManyRequests, models.NewErrorResponse("Слишком много черновиков заказа для этого email, попробуйте позже"))
+		return models.CheckoutDraft{}, false
+	}
+
+	return models.CheckoutDraft{
+		ID:       id,
+		Email:    email,
+		Name:     strings.TrimSpace(request.Name),
+		Phone:    strings.TrimSpace(request.Phone),
+		Language: request.Language,
+		CartID:   request.CartID,
+		Items:    request.Items,
+	}, true
+}
+
+// draftID возвращает ID черновика из пути запроса.
+// При ошибке отправляет ответ клиенту и возвращает false
+func draftID(c *gin.Context) (string, bool) {
+	id := c.Param("id")
+	if !uploadIDPattern.MatchString(id) {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse("Черновик заказа не найден"))
+		return "", false
+	}
+	return id, true
+}
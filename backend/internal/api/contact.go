@@ -0,0 +1,254 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/i18n"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/reminder"
+	"pryanik_studio/internal/storage"
+)
+
+// Сообщения об успешной отправке формы обратной связи на разных языках
+var contactSuccessMessages = map[string]string{
+	"ru": "Сообщение успешно отправлено",
+	"en": "Message sent successfully",
+	"es": "Mensaje enviado exitosamente",
+}
+
+// maxContactPageSize максимальный размер страницы входящих сообщений
+const maxContactPageSize = 100
+
+// ContactHandler обработчик запросов для формы обратной связи и входящих сообщений администратора.
+// Сообщение сохраняется до отправки уведомления, уведомление отправляется в фоне
+type ContactHandler struct {
+	repo      storage.ContactRepository
+	notifier  *reminder.ContactNotifier
+	languages *i18n.Registry
+	validator *validator.Validate
+	logger    *logrus.Logger
+}
+
+// NewContactHandler создает новый экземпляр ContactHandler
+func NewContactHandler(
+	repo storage.ContactRepository,
+	notifier *reminder.ContactNotifier,
+	languages *i18n.Registry,
+	logger *logrus.Logger,
+) *ContactHandler {
+	return &ContactHandler{
+		repo:      repo,
+		notifier:  notifier,
+		languages: languages,
+		validator: validator.New(),
+		logger:    logger,
+	}
+}
+
+// SubmitContactForm обработчик для отправки формы обратной связи
+func (h *ContactHandler) SubmitContactForm(c *gin.Context) {
+	var request models.ContactFormRequest
+
+	// Парсим JSON из тела запроса
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса формы обратной связи")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	// Валидируем данные формы
+	if err := h.validator.Struct(request); err != nil {
+		h.logger.WithError(err).Error("Ошибка валидации данных формы обратной связи")
+
+		// Формируем детальные ошибки валидации
+		var validationErrors []models.ValidationError
+		for _, err := range err.(validator.ValidationErrors) {
+			validationErrors = append(validationErrors, models.ValidationError{
+				Field:   err.Field(),
+				Message: getValidationErrorMessage(err),
+			})
+		}
+
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse(validationErrors))
+		return
+	}
+
+	// Если язык не указан, используем язык запроса; указанный язык должен быть включен
+	if request.Language == "" {
+		request.Language = i18n.FromContext(c)
+	} else if !h.languages.IsEnabled(request.Language) {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "Language", Message: "Неподдерживаемый язык"},
+		}))
+		return
+	}
+
+	// Сохраняем сообщение; уведомление по email отправляется в фоне и не влияет на ответ
+	message := &models.ContactMessage{
+		Name:     strings.TrimSpace(request.Name),
+		Email:    normalizeEmail(request.Email),
+		Phone:    strings.TrimSpace(request.Phone),
+		Message:  strings.TrimSpace(request.Message),
+		Language: request.Language,
+	}
+	if err := h.repo.CreateContactMessage(c.Request.Context(), message); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при отправке сообщения"))
+		return
+	}
+	h.notifier.Wake()
+
+	// Возвращаем успешный ответ с сообщением на соответствующем языке
+	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]interface{}{
+		"message": h.languages.Localize(contactSuccessMessages, request.Language),
+	}))
+}
+
+// GetContactMessages обработчик для получения входящих сообщений с фильтром по статусу (status),
+// ответственному (assigned_to; пустое значение - не назначенные) и поиском по тексту (q)
+func (h *ContactHandler) GetContactMessages(c *gin.Context) {
+	filter := models.ContactMessageFilter{
+		Status: c.Query("status"),
+		Search: strings.TrimSpace(c.Query("q")),
+	}
+	if filter.Status != "" && !containsString(models.ContactStatuses, filter.Status) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный статус сообщения"))
+		return
+	}
+	if assignedTo, ok := c.GetQuery("assigned_to"); ok {
+		assignedTo = strings.TrimSpace(assignedTo)
+		filter.AssignedTo = &assignedTo
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if err != nil || pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > maxContactPageSize {
+		pageSize = maxContactPageSize
+	}
+	filter.Page, filter.PageSize = page, pageSize
+
+	messages, err := h.repo.GetContactMessages(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении сообщений"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(messages))
+}
+
+// GetContactMessage обработчик для получения сообщения с внутренними заметками
+func (h *ContactHandler) GetContactMessage(c *gin.Context) {
+	id, ok := contactMessageID(c)
+	if !ok {
+		return
+	}
+
+	h.respondMessage(c, id)
+}
+
+// UpdateContactMessage обработчик для изменения статуса сообщения (прочитано, отвечено) и ответственного
+func (h *ContactHandler) UpdateContactMessage(c *gin.Context) {
+	id, ok := contactMessageID(c)
+	if !ok {
+		return
+	}
+
+	var request models.ContactMessageUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на изменение сообщения")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+	if request.Status == "" && request.AssignedTo == nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Укажите статус сообщения или ответственного"))
+		return
+	}
+	if request.AssignedTo != nil {
+		assignedTo := strings.TrimSpace(*request.AssignedTo)
+		request.AssignedTo = &assignedTo
+	}
+
+	err := h.repo.UpdateContactMessage(c.Request.Context(), id, request.Status, request.AssignedTo)
+	if err != nil {
+		h.contactError(c, err, "Ошибка при изменении сообщения")
+		return
+	}
+
+	h.respondMessage(c, id)
+}
+
+// AddContactNote обработчик для добавления внутренней заметки к сообщению; автор - текущий администратор
+func (h *ContactHandler) AddContactNote(c *gin.Context) {
+	id, ok := contactMessageID(c)
+	if !ok {
+		return
+	}
+
+	var request models.ContactNoteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+	text := strings.TrimSpace(request.Text)
+	if text == "" {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "Text", Message: "Поле обязательно для заполнения"},
+		}))
+		return
+	}
+
+	note := &models.ContactNote{
+		MessageID: id,
+		Author:    c.GetString("username"),
+		Text:      text,
+	}
+	if err := h.repo.AddContactNote(c.Request.Context(), note); err != nil {
+		h.contactError(c, err, "Ошибка при добавлении заметки")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(note))
+}
+
+// respondMessage отправляет клиенту сообщение с заметками
+func (h *ContactHandler) respondMessage(c *gin.Context, id int64) {
+	message, err := h.repo.GetContactMessage(c.Request.Context(), id)
+	if err != nil {
+		h.contactError(c, err, "Ошибка при получении сообщения")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(message))
+}
+
+// contactError отправляет клиенту ответ на ошибку работы с сообщением
+func (h *ContactHandler) contactError(c *gin.Context, err error, message string) {
+	if errors.Is(err, storage.ErrContactMessageNotFound) {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.NewErrorResponse(message))
+}
+
+// contactMessageID возвращает ID сообщения из пути запроса.
+// При ошибке отправляет ответ клиенту и возвращает false
+func contactMessageID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID сообщения"))
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/currency"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+)
+
+// maxRatesFileSize максимальный размер загружаемого файла курсов (1 МБ)
+const maxRatesFileSize = 1 << 20
+
+// CurrencyHandler обработчик запросов для валют и курсов
+type CurrencyHandler struct {
+	repo      storage.ExchangeRateRepository
+	converter *currency.Converter
+	logger    *logrus.Logger
+}
+
+// NewCurrencyHandler создает новый экземпляр CurrencyHandler
+func NewCurrencyHandler(repo storage.ExchangeRateRepository, converter *currency.Converter, logger *logrus.Logger) *CurrencyHandler {
+	return &CurrencyHandler{
+		repo:      repo,
+		converter: converter,
+		logger:    logger,
+	}
+}
+
+// GetCurrencies обработчик для получения списка валют, в которых можно показать цены
+func (h *CurrencyHandler) GetCurrencies(c *gin.Context) {
+	codes := []string{h.converter.Reference()}
+	for _, rate := range h.converter.Rates() {
+		if rate.Currency != h.converter.Reference() {
+			codes = append(codes, rate.Currency)
+		}
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(codes))
+}
+
+// GetExchangeRates обработчик для получения курсов валют (для администратора)
+func (h *CurrencyHandler) GetExchangeRates(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]interface{}{
+		"reference": h.converter.Reference(),
+		"rates":     h.converter.Rates(),
+	}))
+}
+
+// SetExchangeRate обработчик для ручной установки курса валюты и правила округления
+func (h *CurrencyHandler) SetExchangeRate(c *gin.Context) {
+	code := strings.ToUpper(c.Param("currency"))
+	if !currencyCodePattern.MatchString(code) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный код валюты"))
+		return
+	}
+
+	var request models.ExchangeRateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на установку курса валюты")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	value, ok := new(big.Rat).SetString(strings.ReplaceAll(request.Rate, ",", "."))
+	if !ok || value.Sign() <= 0 {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Курс должен быть положительным числом"))
+		return
+	}
+
+	// Курс опорной валюты всегда равен единице, для нее настраивается только округление
+	if code == h.converter.Reference() && value.Cmp(big.NewRat(1, 1)) != 0 {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Курс опорной валюты должен быть равен 1"))
+		return
+	}
+
+	rate := models.ExchangeRate{
+		Currency:     code,
+		Rate:         currency.FormatRate(value),
+		RoundingStep: 1,
+		RoundingMode: models.RoundingHalfUp,
+		Source:       models.ExchangeRateSourceManual,
+	}
+
+	// Сохраняем текущее правило округления, если новое не указано
+	for _, current := range h.converter.Rates() {
+		if current.Currency == code {
+			rate.RoundingStep = current.RoundingStep
+			rate.RoundingMode = current.RoundingMode
+		}
+	}
+	if request.RoundingStep != nil {
+		rate.RoundingStep = *request.RoundingStep
+	}
+	if request.RoundingMode != "" {
+		rate.RoundingMode = request.RoundingMode
+	}
+
+	if rate.RoundingStep <= 0 {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Шаг округления должен быть положительным"))
+		return
+	}
+	if !currency.IsValidRoundingMode(rate.RoundingMode) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный режим округления"))
+		return
+	}
+
+	if err := h.repo.SaveExchangeRate(c.Request.Context(), &rate); err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при сохранении курса валюты %s", code)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении курса валюты"))
+		return
+	}
+
+	repriced := h.reload(c)
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]interface{}{
+		"rate":     rate,
+		"repriced": repriced,
+	}))
+}
+
+// ImportExchangeRates обработчик для импорта курсов из XML-файла ЦБ РФ.
+// Файл передается полем file формы multipart или телом запроса
+func (h *CurrencyHandler) ImportExchangeRates(c *gin.Context) {
+	var reader io.Reader = http.MaxBytesReader(c.Writer, c.Request.Body, maxRatesFileSize)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Файл курсов не передан"))
+			return
+		}
+		if file.Size > maxRatesFileSize {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Файл курсов слишком большой"))
+			return
+		}
+
+		opened, err := file.Open()
+		if err != nil {
+			h.logger.WithError(err).Error("Ошибка при открытии файла курсов")
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при чтении файла курсов"))
+			return
+		}
+		defer opened.Close()
+		reader = opened
+	}
+
+	result, err := h.converter.ImportCBR(c.Request.Context(), reader)
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при импорте курсов ЦБ РФ")
+		if errors.Is(err, currency.ErrInvalidRatesFile) || errors.Is(err, currency.ErrUnknownCurrency) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при импорте курсов"))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"date":     result.Date,
+		"imported": len(result.Imported),
+		"repriced": result.Repriced,
+	}).Info("Курсы ЦБ РФ импортированы")
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(result))
+}
+
+// reload перезагружает курсы и пересчитывает цены товаров с базовой ценой.
+// Возвращает количество обновленных переводов
+func (h *CurrencyHandler) reload(c *gin.Context) int {
+	if err := h.converter.Load(c.Request.Context()); err != nil {
+		h.logger.WithError(err).Error("Ошибка при перезагрузке курсов валют")
+		return 0
+	}
+
+	repriced, err := h.converter.RepriceProducts(c.Request.Context(), 0)
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при пересчете цен товаров")
+	}
+	return repriced
+}
@@ -0,0 +1,437 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/estimate"
+	"pryanik_studio/internal/mesh"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+	"pryanik_studio/internal/vector"
+)
+
+// maxEstimateQuantity максимальный тираж для автоматического расчета
+const maxEstimateQuantity = 10000
+
+// laserOperations допустимые операции лазерной обработки
+var laserOperations = []string{
+	models.LaserOperationCut,
+	models.LaserOperationEngrave,
+	models.LaserOperationCutEngrave,
+}
+
+// EstimateHandler обработчик запросов на автоматический расчет стоимости изготовления
+type EstimateHandler struct {
+	estimates      storage.EstimateRepository
+	printMaterials storage.PrintMaterialRepository
+	laserMaterials storage.LaserMaterialRepository
+	uploads        storage.UploadRepository
+	config         config.UploadConfig
+	estimateConfig config.EstimateConfig
+	logger         *logrus.Logger
+}
+
+// NewEstimateHandler создает новый экземпляр EstimateHandler
+func NewEstimateHandler(
+	estimates storage.EstimateRepository,
+	printMaterials storage.PrintMaterialRepository,
+	laserMaterials storage.LaserMaterialRepository,
+	uploads storage.UploadRepository,
+	config config.UploadConfig,
+	estimateConfig config.EstimateConfig,
+	logger *logrus.Logger,
+) *EstimateHandler {
+	return &EstimateHandler{
+		estimates:      estimates,
+		printMaterials: printMaterials,
+		laserMaterials: laserMaterials,
+		uploads:        uploads,
+		config:         config,
+		estimateConfig: estimateConfig,
+		logger:         logger,
+	}
+}
+
+// GetPrintMaterials обработчик для получения доступных материалов 3D-печати
+func (h *EstimateHandler) GetPrintMaterials(c *gin.Context) {
+	materials, err := h.printMaterials.GetPrintMaterials(c.Request.Context(), true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении материалов"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(materials))
+}
+
+// GetAllPrintMaterials обработчик для получения всех материалов 3D-печати, включая отключенные
+func (h *EstimateHandler) GetAllPrintMaterials(c *gin.Context) {
+	materials, err := h.printMaterials.GetPrintMaterials(c.Request.Context(), false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении материалов"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(materials))
+}
+
+// SavePrintMaterial обработчик для создания или изменения материала 3D-печати
+func (h *EstimateHandler) SavePrintMaterial(c *gin.Context) {
+	code := c.Param("code")
+	if !optionCodePattern.MatchString(code) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный код материала"))
+		return
+	}
+
+	var request models.PrintMaterialRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на сохранение материала")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	currencyCode := strings.ToUpper(request.Currency)
+	if !currencyCodePattern.MatchString(currencyCode) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный код валюты"))
+		return
+	}
+	for _, price := range []*models.Money{request.PricePerGram, request.MachineHourRate, request.MinimumCharge} {
+		if price.IsNegative() {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Цена не может быть отрицательной"))
+			return
+		}
+	}
+
+	material := models.PrintMaterial{
+		Code:            code,
+		Name:            strings.TrimSpace(request.Name),
+		Density:         request.Density,
+		PrintSpeed:      request.PrintSpeed,
+		PricePerGram:    models.NewMoney(request.PricePerGram.Amount, currencyCode),
+		MachineHourRate: models.NewMoney(request.MachineHourRate.Amount, currencyCode),
+		MinimumCharge:   models.NewMoney(request.MinimumCharge.Amount, currencyCode),
+		Currency:        currencyCode,
+		Enabled:         request.Enabled == nil || *request.Enabled,
+	}
+
+	if err := h.printMaterials.SavePrintMaterial(c.Request.Context(), &material); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении материала"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(material))
+}
+
+// Estimate3D обработчик для расчета стоимости 3D-печати модели STL или 3MF.
+// Модель передается полем file формы multipart или ID ранее загруженного файла в поле upload_id;
+// поля material и quantity задают материал и тираж. Расчет сохраняется, и его ID можно указать
+// в позиции заказа
+func (h *EstimateHandler) Estimate3D(c *gin.Context) {
+	quantity, ok := estimateQuantity(c)
+	if !ok {
+		return
+	}
+
+	material, err := h.printMaterials.GetPrintMaterial(c.Request.Context(), c.PostForm("material"))
+	if err != nil || !material.Enabled {
+		if err == nil || errors.Is(err, storage.ErrPrintMaterialNotFound) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Материал не найден"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при расчете стоимости"))
+		return
+	}
+
+	data, fileName, uploadID, ok := h.readEstimateFile(c)
+	if !ok {
+		return
+	}
+
+	var model *mesh.Mesh
+	switch detectContentType(data, fileName) {
+	case "model/stl":
+		model, err = mesh.ParseSTL(data)
+	case "model/3mf":
+		model, err = mesh.Parse3MF(data, int64(h.config.MaxSizeMB)<<20*mesh.MaxUnpackRatio)
+	default:
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Допускаются только файлы STL и 3MF"))
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Warnf("Не удалось разобрать модель %s", fileName)
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+		return
+	}
+
+	result, err := estimate.Print3D(mesh.Analyze(model), material, quantity)
+	if err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при расчете стоимости печати из материала %s", material.Code)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при расчете стоимости"))
+		return
+	}
+
+	if !h.saveEstimate(c, &result, uploadID) {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(result))
+}
+
+// GetLaserMaterials обработчик для получения доступных материалов лазерной обработки
+func (h *EstimateHandler) GetLaserMaterials(c *gin.Context) {
+	materials, err := h.laserMaterials.GetLaserMaterials(c.Request.Context(), true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении материалов"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(materials))
+}
+
+// GetAllLaserMaterials обработчик для получения всех материалов лазерной обработки, включая отключенные
+func (h *EstimateHandler) GetAllLaserMaterials(c *gin.Context) {
+	materials, err := h.laserMaterials.GetLaserMaterials(c.Request.Context(), false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении материалов"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(materials))
+}
+
+// SaveLaserMaterial обработчик для создания или изменения материала лазерной обработки
+func (h *EstimateHandler) SaveLaserMaterial(c *gin.Context) {
+	code := c.Param("code")
+	if !optionCodePattern.MatchString(code) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный код материала"))
+		return
+	}
+
+	var request models.LaserMaterialRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на сохранение материала")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	currencyCode := strings.ToUpper(request.Currency)
+	if !currencyCodePattern.MatchString(currencyCode) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный код валюты"))
+		return
+	}
+	prices := []*models.Money{request.CutPricePerMeter, request.EngravePricePerCM2, request.SheetPricePerCM2, request.MinimumCharge}
+	for _, price := range prices {
+		if price.IsNegative() {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Цена не может быть отрицательной"))
+			return
+		}
+	}
+
+	material := models.LaserMaterial{
+		Code:               code,
+		Name:               strings.TrimSpace(request.Name),
+		CutPricePerMeter:   models.NewMoney(request.CutPricePerMeter.Amount, currencyCode),
+		EngravePricePerCM2: models.NewMoney(request.EngravePricePerCM2.Amount, currencyCode),
+		SheetPricePerCM2:   models.NewMoney(request.SheetPricePerCM2.Amount, currencyCode),
+		MinimumCharge:      models.NewMoney(request.MinimumCharge.Amount, currencyCode),
+		Currency:           currencyCode,
+		Enabled:            request.Enabled == nil || *request.Enabled,
+	}
+
+	if err := h.laserMaterials.SaveLaserMaterial(c.Request.Context(), &material); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении материала"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(material))
+}
+
+// EstimateLaser обработчик для расчета стоимости лазерной резки и гравировки макета SVG или DXF.
+// Макет передается так же, как модель для 3D-печати; поле operation задает операцию
+// (cut, engrave или cut_engrave, по умолчанию cut_engrave)
+func (h *EstimateHandler) EstimateLaser(c *gin.Context) {
+	quantity, ok := estimateQuantity(c)
+	if !ok {
+		return
+	}
+
+	operation := c.DefaultPostForm("operation", models.LaserOperationCutEngrave)
+	if !containsString(laserOperations, operation) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректная операция"))
+		return
+	}
+
+	material, err := h.laserMaterials.GetLaserMaterial(c.Request.Context(), c.PostForm("material"))
+	if err != nil || !material.Enabled {
+		if err == nil || errors.Is(err, storage.ErrLaserMaterialNotFound) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Материал не найден"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при расчете стоимости"))
+		return
+	}
+
+	data, fileName, uploadID, ok := h.readEstimateFile(c)
+	if !ok {
+		return
+	}
+
+	var drawing *vector.Drawing
+	switch detectContentType(data, fileName) {
+	case "image/svg+xml":
+		drawing, err = vector.ParseSVG(data)
+	case "image/vnd.dxf":
+		drawing, err = vector.ParseDXF(data)
+	default:
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Допускаются только файлы SVG и DXF"))
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Warnf("Не удалось разобрать макет %s", fileName)
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+		return
+	}
+
+	result, err := estimate.Laser(vector.Analyze(drawing), material, operation, quantity)
+	if err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при расчете стоимости лазерной обработки материала %s", material.Code)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при расчете стоимости"))
+		return
+	}
+
+	if !h.saveEstimate(c, &result, uploadID) {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(result))
+}
+
+// GetEstimate обработчик для получения сохраненного расчета стоимости по ID
+func (h *EstimateHandler) GetEstimate(c *gin.Context) {
+	id := c.Param("id")
+	if !uploadIDPattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID расчета"))
+		return
+	}
+
+	result, err := h.estimates.GetEstimate(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrEstimateNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Расчет не найден"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении расчета"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(result))
+}
+
+// saveEstimate сохраняет расчет, чтобы его можно было прикрепить к позиции заказа.
+// При ошибке отправляет ответ клиенту и возвращает false
+func (h *EstimateHandler) saveEstimate(c *gin.Context, result *models.Estimate, uploadID string) bool {
+	id, err := newUploadID()
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при генерации ID расчета")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при расчете стоимости"))
+		return false
+	}
+
+	result.ID = id
+	result.UploadID = uploadID
+	result.CreatedAt = time.Now()
+	result.ExpiresAt = result.CreatedAt.AddDate(0, 0, h.estimateConfig.ValidDays)
+
+	if err := h.estimates.CreateEstimate(c.Request.Context(), result); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении расчета"))
+		return false
+	}
+
+	return true
+}
+
+// estimateQuantity читает тираж из поля quantity формы (по умолчанию 1).
+// При ошибке отправляет ответ клиенту и возвращает false
+func estimateQuantity(c *gin.Context) (int, bool) {
+	value := c.PostForm("quantity")
+	if value == "" {
+		return 1, true
+	}
+
+	quantity, err := strconv.Atoi(value)
+	if err != nil || quantity < 1 || quantity > maxEstimateQuantity {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректное количество"))
+		return 0, false
+	}
+	return quantity, true
+}
+
+// readEstimateFile читает файл модели или макета из формы или из ранее загруженных файлов
+// и возвращает его содержимое, имя и ID загруженного файла.
+// При ошибке отправляет ответ клиенту и возвращает false
+func (h *EstimateHandler) readEstimateFile(c *gin.Context) ([]byte, string, string, bool) {
+	maxSize := int64(h.config.MaxSizeMB) << 20
+
+	if id := c.PostForm("upload_id"); id != "" {
+		if !uploadIDPattern.MatchString(id) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Файл не найден"))
+			return nil, "", "", false
+		}
+		upload, err := h.uploads.GetUpload(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, storage.ErrUploadNotFound) {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse("Файл не найден"))
+				return nil, "", "", false
+			}
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при чтении файла"))
+			return nil, "", "", false
+		}
+		data, err := os.ReadFile(upload.Path)
+		if err != nil {
+			h.logger.WithError(err).Errorf("Ошибка при чтении файла %s", upload.Path)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при чтении файла"))
+			return nil, "", "", false
+		}
+		return data, upload.FileName, upload.ID, true
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Файл не передан"))
+		return nil, "", "", false
+	}
+	if file.Size > maxSize {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Файл слишком большой"))
+		return nil, "", "", false
+	}
+
+	opened, err := file.Open()
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при открытии файла для расчета")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при чтении файла"))
+		return nil, "", "", false
+	}
+	defer opened.Close()
+
+	data, err := io.ReadAll(io.LimitReader(opened, maxSize+1))
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при чтении файла для расчета")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при чтении файла"))
+		return nil, "", "", false
+	}
+	if int64(len(data)) > maxSize {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Файл слишком большой"))
+		return nil, "", "", false
+	}
+
+	return data, file.Filename, "", true
+}
@@ -7,21 +7,24 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 
+	"pryanik_studio/internal/i18n"
 	"pryanik_studio/internal/models"
 	"pryanik_studio/internal/storage"
 )
 
 // GalleryHandler обработчик запросов для галереи
 type GalleryHandler struct {
-	repo   storage.GalleryRepository
-	logger *logrus.Logger
+	repo      storage.GalleryRepository
+	languages *i18n.Registry
+	logger    *logrus.Logger
 }
 
 // NewGalleryHandler создает новый экземпляр GalleryHandler
-func NewGalleryHandler(repo storage.GalleryRepository, logger *logrus.Logger) *GalleryHandler {
+func NewGalleryHandler(repo storage.GalleryRepository, languages *i18n.Registry, logger *logrus.Logger) *GalleryHandler {
 	return &GalleryHandler{
-		repo:   repo,
-		logger: logger,
+		repo:      repo,
+		languages: languages,
+		logger:    logger,
 	}
 }
 
@@ -29,8 +32,8 @@ func NewGalleryHandler(repo storage.GalleryRepository, logger *logrus.Logger) *G
 func (h *GalleryHandler) GetGalleryItems(c *gin.Context) {
 	var filter models.GalleryFilter
 
-	// Получаем язык из запроса, по умолчанию "ru"
-	filter.Language = c.DefaultQuery("language", "ru")
+	// Получаем язык запроса
+	filter.Language = i18n.FromContext(c)
 
 	// Получаем параметры фильтрации по категории
 	categoryIDStr := c.Query("category")
@@ -80,12 +83,20 @@ func (h *GalleryHandler) CreateGalleryItem(c *gin.Context) {
 		return
 	}
 
-	// Проверка наличия переводов для русского языка (обязательно)
-	if _, ok := request.Translations["ru"]; !ok {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Отсутствует обязательный перевод для русского языка"))
+	// Проверка наличия перевода для языка по умолчанию (обязательно)
+	if _, ok := request.Translations[h.languages.Default()]; !ok {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Отсутствует обязательный перевод для языка по умолчанию"))
 		return
 	}
 
+	// Переводы допускаются только для зарегистрированных языков
+	for lang := range request.Translations {
+		if !h.languages.IsRegistered(lang) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Неподдерживаемый язык перевода: "+lang))
+			return
+		}
+	}
+
 	// Создаем модель элемента галереи из запроса
 	galleryItem := &models.GalleryItem{
 		CategoryID:   request.CategoryID,
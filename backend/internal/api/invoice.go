@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/invoice"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/security"
+	"pryanik_studio/internal/storage"
+	"pryanik_studio/internal/utils"
+)
+
+// InvoiceHandler обработчик запросов для счетов по заказам
+type InvoiceHandler struct {
+	orders    storage.OrderRepository
+	invoices  *invoice.Generator
+	signer    *security.LinkSigner
+	config    config.InvoiceConfig
+	publicURL string
+	logger    *logrus.Logger
+}
+
+// NewInvoiceHandler создает новый экземпляр InvoiceHandler; invoices равен nil, если шрифты счета не загружены
+func NewInvoiceHandler(
+	orders storage.OrderRepository,
+	invoices *invoice.Generator,
+	signer *security.LinkSigner,
+	cfg *config.Config,
+	logger *logrus.Logger,
+) *InvoiceHandler {
+	return &InvoiceHandler{
+		orders:    orders,
+		invoices:  invoices,
+		signer:    signer,
+		config:    cfg.Invoice,
+		publicURL: cfg.Server.PublicURL,
+		logger:    logger,
+	}
+}
+
+// GetInvoice обработчик для скачивания счета по заказу администратором.
+// Если счет еще не выставлен, он выставляется. Язык счета - параметр lang или язык заказа
+func (h *InvoiceHandler) GetInvoice(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID заказа"))
+		return
+	}
+
+	h.sendInvoice(c, id)
+}
+
+// GetCustomerInvoice обработчик для скачивания счета покупателем по подписанной ссылке
+func (h *InvoiceHandler) GetCustomerInvoice(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID заказа"))
+		return
+	}
+
+	err = h.signer.Verify(orderInvoiceResource(id), c.Query("expires"), c.Query("signature"))
+	switch {
+	case errors.Is(err, security.ErrSignatureExpired):
+		c.JSON(http.StatusGone, models.NewErrorResponse(err.Error()))
+		return
+	case err != nil:
+		h.logger.Warnf("Недействительная ссылка на счет заказа ID=%d от %s", id, c.ClientIP())
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(err.Error()))
+		return
+	}
+
+	h.sendInvoice(c, id)
+}
+
+// GetInvoiceLink обработчик для получения подписанной ссылки на счет, которую можно отправить покупателю
+func (h *InvoiceHandler) GetInvoiceLink(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID заказа"))
+		return
+	}
+
+	if _, err := h.orders.GetOrderByID(c.Request.Context(), id); err != nil {
+		if errors.Is(err, storage.ErrOrderNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Заказ не найден"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении заказа"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"url": orderInvoiceLink(h.signer, h.publicURL, id, h.config.LinkValidDays),
+	}))
+}
+
+// sendInvoice выставляет (при необходимости) счет по заказу и отправляет его в PDF
+func (h *InvoiceHandler) sendInvoice(c *gin.Context, id int64) {
+	if h.invoices == nil {
+		c.JSON(http.StatusServiceUnavailable, models.NewErrorResponse("Формирование счетов недоступно"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	order, err := h.orders.GetOrderByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrOrderNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Заказ не найден"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении заказа"))
+		return
+	}
+
+	issued, err := h.invoices.Issue(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrOrderStatus) {
+			c.JSON(http.StatusConflict, models.NewErrorResponse("По отмененному заказу счет не выставляется"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при выставлении счета"))
+		return
+	}
+
+	lang := c.DefaultQuery("lang", order.Language)
+	document, err := h.invoices.Render(issued, order, lang)
+	if err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при формировании счета %s", issued.Number)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при формировании счета"))
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, invoice.FileName(issued)))
+	c.Data(http.StatusOK, "application/pdf", document)
+}
+
+// confirmationAttachments выставляет счет по новому заказу и возвращает его PDF для письма
+// с подтверждением. При ошибке письмо отправляется без счета: счет можно получить позже
+func confirmationAttachments(ctx context.Context, invoices *invoice.Generator, order *models.Order, logger *logrus.Logger) []utils.Attachment {
+	if invoices == nil {
+		return nil
+	}
+
+	issued, err := invoices.Issue(ctx, order.ID)
+	if err != nil {
+		logger.WithError(err).Errorf("Ошибка при выставлении счета по заказу ID=%d", order.ID)
+		return nil
+	}
+
+	document, err := invoices.Render(issued, *order, order.Language)
+	if err != nil {
+		logger.WithError(err).Errorf("Ошибка при формировании счета %s", issued.Number)
+		return nil
+	}
+
+	return []utils.Attachment{{Name: invoice.FileName(issued), ContentType: "application/pdf", Data: document}}
+}
+
+// orderInvoiceLink возвращает подписанную ссылку на скачивание счета по заказу
+func orderInvoiceLink(signer *security.LinkSigner, publicURL string, id int64, validDays int) string {
+	expires := time.Now().AddDate(0, 0, validDays)
+	query := signer.Query(orderInvoiceResource(id), expires)
+	return fmt.Sprintf("%s/api/orders/%d/invoice?%s", publicURL, id, query.Encode())
+}
+
+// orderInvoiceResource возвращает имя ресурса для подписи ссылки на счет
+func orderInvoiceResource(id int64) string {
+	return fmt.Sprintf("order-invoice:%d", id)
+}
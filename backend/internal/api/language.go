@@ -0,0 +1,198 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/i18n"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+)
+
+var (
+	// languageCodePattern допустимый формат кода языка: en, es, pt-BR
+	languageCodePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z]{2})?$`)
+
+	// currencyCodePattern допустимый формат кода валюты по ISO 4217
+	currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// LanguageHandler обработчик запросов для управления языками
+type LanguageHandler struct {
+	repo      storage.LanguageRepository
+	languages *i18n.Registry
+	logger    *logrus.Logger
+}
+
+// NewLanguageHandler создает новый экземпляр LanguageHandler
+func NewLanguageHandler(repo storage.LanguageRepository, languages *i18n.Registry, logger *logrus.Logger) *LanguageHandler {
+	return &LanguageHandler{
+		repo:      repo,
+		languages: languages,
+		logger:    logger,
+	}
+}
+
+// GetLanguages обработчик для получения списка включенных языков
+func (h *LanguageHandler) GetLanguages(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(h.languages.Enabled()))
+}
+
+// GetAllLanguages обработчик для получения всех зарегистрированных языков (для администратора)
+func (h *LanguageHandler) GetAllLanguages(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(h.languages.All()))
+}
+
+// CreateLanguage обработчик для добавления языка
+func (h *LanguageHandler) CreateLanguage(c *gin.Context) {
+	var request models.LanguageCreateRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на добавление языка")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	language := &models.Language{
+		Code:            request.Code,
+		Name:            request.Name,
+		DefaultCurrency: strings.ToUpper(request.DefaultCurrency),
+		Enabled:         true,
+		IsDefault:       request.IsDefault,
+		SortOrder:       request.SortOrder,
+	}
+
+	if request.Enabled != nil {
+		language.Enabled = *request.Enabled
+	}
+
+	if request.Fallback != nil && *request.Fallback != "" {
+		language.Fallback = request.Fallback
+	}
+
+	if h.languages.IsRegistered(language.Code) {
+		c.JSON(http.StatusConflict, models.NewErrorResponse("Язык уже зарегистрирован"))
+		return
+	}
+
+	if message := h.validateLanguage(language); message != "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(message))
+		return
+	}
+
+	if err := h.repo.CreateLanguage(c.Request.Context(), language); err != nil {
+		h.logger.WithError(err).Error("Ошибка при добавлении языка")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при добавлении языка"))
+		return
+	}
+
+	h.reload(c)
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(language))
+}
+
+// UpdateLanguage обработчик для обновления языка
+func (h *LanguageHandler) UpdateLanguage(c *gin.Context) {
+	code := c.Param("code")
+
+	language, ok := h.languages.Get(code)
+	if !ok {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse("Язык не найден"))
+		return
+	}
+
+	var request models.LanguageUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на обновление языка")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	if request.Name != nil {
+		language.Name = *request.Name
+	}
+	if request.DefaultCurrency != nil {
+		language.DefaultCurrency = strings.ToUpper(*request.DefaultCurrency)
+	}
+	if request.Enabled != nil {
+		language.Enabled = *request.Enabled
+	}
+	if request.IsDefault != nil {
+		// Снять признак по умолчанию можно только назначив другой язык языком по умолчанию
+		if !*request.IsDefault && language.IsDefault {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Назначьте другой язык языком по умолчанию"))
+			return
+		}
+		language.IsDefault = *request.IsDefault
+	}
+	if request.Fallback != nil {
+		if *request.Fallback == "" {
+			language.Fallback = nil
+		} else {
+			language.Fallback = request.Fallback
+		}
+	}
+	if request.SortOrder != nil {
+		language.SortOrder = *request.SortOrder
+	}
+
+	if message := h.validateLanguage(&language); message != "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(message))
+		return
+	}
+
+	if err := h.repo.UpdateLanguage(c.Request.Context(), &language); err != nil {
+		if errors.Is(err, storage.ErrLanguageNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Язык не найден"))
+			return
+		}
+		h.logger.WithError(err).Errorf("Ошибка при обновлении языка %s", code)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при обновлении языка"))
+		return
+	}
+
+	h.reload(c)
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(language))
+}
+
+// validateLanguage проверяет согласованность настроек языка и возвращает текст ошибки
+func (h *LanguageHandler) validateLanguage(language *models.Language) string {
+	if !languageCodePattern.MatchString(language.Code) {
+		return "Некорректный код языка"
+	}
+
+	if !currencyCodePattern.MatchString(language.DefaultCurrency) {
+		return "Некорректный код валюты"
+	}
+
+	if language.IsDefault && !language.Enabled {
+		return "Язык по умолчанию не может быть отключен"
+	}
+
+	if language.Fallback != nil {
+		if *language.Fallback == language.Code {
+			return "Язык не может откатываться сам на себя"
+		}
+		if !h.languages.IsRegistered(*language.Fallback) {
+			return "Язык отката не зарегистрирован"
+		}
+		if h.languages.WouldCreateCycle(language.Code, *language.Fallback) {
+			return "Язык отката образует цикл"
+		}
+	}
+
+	return ""
+}
+
+// reload перезагружает реестр языков после изменения
+func (h *LanguageHandler) reload(c *gin.Context) {
+	if err := h.languages.Load(c.Request.Context()); err != nil {
+		h.logger.WithError(err).Error("Ошибка при перезагрузке реестра языков")
+	}
+}
@@ -1,21 +1,57 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/sirupsen/logrus"
 
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/currency"
+	"pryanik_studio/internal/i18n"
+	"pryanik_studio/internal/invoice"
 	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/promo"
+	"pryanik_studio/internal/security"
+	"pryanik_studio/internal/shipping"
 	"pryanik_studio/internal/storage"
 	"pryanik_studio/internal/utils"
 )
 
-// OrderHandler обработчик запросов для заказов и форм обратной связи
+// Сообщения об успешном создании заказа на разных языках
+var orderSuccessMessages = map[string]string{
+	"ru": "Заказ успешно создан",
+	"en": "Order successfully created",
+	"es": "Pedido creado exitosamente",
+}
+
+// OrderHandler обработчик запросов для заказов
 type OrderHandler struct {
 	repo        storage.OrderRepository
 	productRepo storage.ProductRepository
+	uploads     storage.UploadRepository
+	estimates   storage.EstimateRepository
+	promos      storage.PromoRepository
+	shipping    storage.ShippingRepository
+	drafts      storage.CheckoutDraftRepository
+	reviews     storage.ReviewRepository
+	languages   *i18n.Registry
+	converter   *currency.Converter
+	inventory   config.InventoryConfig
+	limits      config.OrderConfig
+	parcels     config.ShippingConfig
+	payments    config.PaymentConfig
+	publicURL   string
+	signer      *security.LinkSigner
+	invoices    *invoice.Generator
+	invoicing   config.InvoiceConfig
+	reviewing   config.ReviewConfig
 	emailSender utils.Sender
 	validator   *validator.Validate
 	logger      *logrus.Logger
@@ -25,12 +61,40 @@ type OrderHandler struct {
 func NewOrderHandler(
 	repo storage.OrderRepository,
 	productRepo storage.ProductRepository,
+	uploads storage.UploadRepository,
+	estimates storage.EstimateRepository,
+	promos storage.PromoRepository,
+	shipping storage.ShippingRepository,
+	drafts storage.CheckoutDraftRepository,
+	reviews storage.ReviewRepository,
+	languages *i18n.Registry,
+	converter *currency.Converter,
+	signer *security.LinkSigner,
+	invoices *invoice.Generator,
 	emailSender utils.Sender,
+	cfg *config.Config,
 	logger *logrus.Logger,
 ) *OrderHandler {
 	return &OrderHandler{
 		repo:        repo,
 		productRepo: productRepo,
+		uploads:     uploads,
+		estimates:   estimates,
+		promos:      promos,
+		shipping:    shipping,
+		drafts:      drafts,
+		reviews:     reviews,
+		languages:   languages,
+		converter:   converter,
+		inventory:   cfg.Inventory,
+		limits:      cfg.Order,
+		parcels:     cfg.Shipping,
+		payments:    cfg.Payment,
+		publicURL:   cfg.Server.PublicURL,
+		signer:      signer,
+		invoices:    invoices,
+		invoicing:   cfg.Invoice,
+		reviewing:   cfg.Review,
 		emailSender: emailSender,
 		validator:   validator.New(),
 		logger:      logger,
@@ -48,6 +112,16 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
+	h.placeOrder(c, request)
+}
+
+// placeOrder проверяет запрос, создает заказ, отправляет подтверждение и ответ клиенту.
+// Возвращает ID созданного заказа; при ошибке отправляет ответ клиенту и возвращает false
+func (h *OrderHandler) placeOrder(c *gin.Context, request models.OrderRequest) (int64, bool) {
+	// Приводим контакты к единому виду до проверки формата
+	request.Name = strings.TrimSpace(request.Name)
+	request.Email = normalizeEmail(request.Email)
+
 	// Валидируем данные формы
 	if err := h.validator.Struct(request); err != nil {
 		h.logger.WithError(err).Error("Ошибка валидации данных заказа")
@@ -62,67 +136,193 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		}
 
 		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse(validationErrors))
-		return
+		return 0, false
 	}
 
-	// Если язык не указан, используем язык из заголовка Accept-Language или "ru" по умолчанию
+	phone, ok := normalizePhone(request.Phone)
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "Phone", Message: "Некорректный номер телефона"},
+		}))
+		return 0, false
+	}
+	request.Phone = phone
+
+	// Если язык не указан, используем язык запроса; указанный язык должен быть включен
 	if request.Language == "" {
-		request.Language = getPreferredLanguage(c)
+		request.Language = i18n.FromContext(c)
+	} else if !h.languages.IsEnabled(request.Language) {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "Language", Message: "Неподдерживаемый язык"},
+		}))
+		return 0, false
+	}
+
+	// Проверяем состав заказа; одинаковые позиции объединяются
+	lines, ok := h.validateItems(c, request.Items, request.Language)
+	if !ok {
+		return 0, false
 	}
 
 	// Создаем объект заказа
 	order := &models.Order{
-		Name:      request.Name,
-		Email:     request.Email,
-		Phone:     request.Phone,
-		Comment:   request.Comment,
-		Language:  request.Language,
-		Status:    "new",
-		TotalCost: 0,
-		Items:     request.Items,
+		Name:     request.Name,
+		Email:    request.Email,
+		Phone:    request.Phone,
+		Comment:  request.Comment,
+		Language: request.Language,
+		Status:   "new",
+		Items:    request.Items,
 	}
 
 	// Если в заказе есть товары, проверяем их и рассчитываем общую стоимость
 	if len(request.Items) > 0 {
 		// Для хранения обработанных товаров
 		var processedItems []models.OrderItem
-		totalCost := 0.0
+		var totalCost models.Money
 
-		// Получаем информацию о каждом товаре и рассчитываем стоимость
-		for _, item := range request.Items {
-			// Получаем товар из базы данных для проверки наличия и цены
-			product, err := h.productRepo.GetProductByID(c.Request.Context(), item.ProductID, request.Language)
-			if err != nil {
-				h.logger.WithError(err).Errorf("Товар с ID=%d не найден", item.ProductID)
-				c.JSON(http.StatusBadRequest, models.NewErrorResponse("Один из товаров не найден"))
-				return
+		// Рассчитываем стоимость каждой позиции
+		for _, line := range lines {
+			item, ok := h.priceItem(c, line)
+			if !ok {
+				return 0, false
 			}
+			product := line.product
 
-			// Заполняем дополнительные данные о товаре
-			item.Price = product.Price
-			item.ProductName = product.Name
-			if len(product.Images) > 0 {
-				item.ProductImage = product.Images[0]
+			// Проверяем значения персонализации по схеме товара
+			personalization, message, err := resolvePersonalization(
+				c.Request.Context(), h.uploads, product.Personalization, item.Personalization,
+				request.Language, h.languages.Default(),
+			)
+			if err != nil {
+				h.logger.WithError(err).Errorf("Ошибка при проверке персонализации товара ID=%d", item.ProductID)
+				c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании заказа"))
+				return 0, false
+			}
+			if message != "" {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse(message))
+				return 0, false
 			}
+			item.Personalization = personalization
 
-			// Рассчитываем стоимость позиции
-			itemCost := product.Price * float64(item.Quantity)
-			totalCost += itemCost
+			// Рассчитываем стоимость позиции; все позиции заказа должны быть в одной валюте
+			totalCost, err = totalCost.Add(item.Price.Mul(item.Quantity))
+			if err != nil {
+				h.logger.WithError(err).Warnf("Товар ID=%d указан в другой валюте, чем остальные товары заказа", item.ProductID)
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse("Товары в заказе указаны в разных валютах"))
+				return 0, false
+			}
 
 			processedItems = append(processedItems, item)
 		}
 
 		// Обновляем заказ с обработанными товарами и общей стоимостью
 		order.Items = processedItems
+		order.Subtotal = totalCost
 		order.TotalCost = totalCost
+		order.Currency = totalCost.Currency
+		order.Discount = models.NewMoney(0, order.Currency)
+
+		// Применяем промокод; при ошибке заказ не создается, чтобы покупатель не потерял скидку незаметно
+		if request.PromoCode != "" {
+			discount, message, err := h.applyPromo(c.Request.Context(), request.PromoCode, order.Items, request.Email)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании заказа"))
+				return 0, false
+			}
+			if message != "" {
+				c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+					{Field: "PromoCode", Message: message},
+				}))
+				return 0, false
+			}
+			order.PromoCode = discount.Code
+			order.Discount = discount.Amount
+			order.TotalCost = models.NewMoney(totalCost.Amount-discount.Amount.Amount, order.Currency)
+		}
+
+		// Доставка: если способы доставки настроены, один из них обязателен
+		order.ShippingCost = models.NewMoney(0, order.Currency)
+		if request.ShippingMethod == "" {
+			methods, err := h.shipping.GetShippingMethods(c.Request.Context(), true)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании заказа"))
+				return 0, false
+			}
+			if len(methods) > 0 {
+				c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+					{Field: "ShippingMethod", Message: "Выберите способ доставки"},
+				}))
+				return 0, false
+			}
+		} else {
+			address := normalizeAddress(request.Address)
+			quote, message, err := h.quoteShipping(c.Request.Context(), request.ShippingMethod, address, order.Items, order.TotalCost)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании заказа"))
+				return 0, false
+			}
+			if message != "" {
+				c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+					{Field: "ShippingMethod", Message: message},
+				}))
+				return 0, false
+			}
+			order.ShippingMethod = quote.Method
+			order.ShippingMethodName = quote.Name
+			order.ShippingCost = quote.Cost
+			if quote.Kind != models.ShippingKindPickup {
+				order.ShippingAddress = address
+			}
+			order.TotalCost = models.NewMoney(order.TotalCost.Amount+quote.Cost.Amount, order.Currency)
+		}
+	} else if request.PromoCode != "" {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "PromoCode", Message: "Промокод применяется только к заказу с товарами"},
+		}))
+		return 0, false
+	} else if request.ShippingMethod != "" {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "ShippingMethod", Message: "Доставка оформляется только для заказа с товарами"},
+		}))
+		return 0, false
+	}
+
+	// Заказ без товаров оформляется в валюте языка заказа
+	if order.Currency == "" {
+		order.Currency = h.languages.CurrencyFor(request.Language)
+		order.TotalCost = models.NewMoney(0, order.Currency)
+		order.Subtotal = order.TotalCost
+		order.Discount = order.TotalCost
+		order.ShippingCost = order.TotalCost
 	}
 
 	// Сохраняем заказ в базе данных
 	orderID, err := h.repo.CreateOrder(c.Request.Context(), order)
 	if err != nil {
+		if errors.Is(err, storage.ErrInsufficientStock) {
+			h.logger.WithError(err).Warn("Недостаточно товара на складе для заказа")
+			c.JSON(http.StatusConflict, models.NewErrorResponse("Одного из товаров недостаточно на складе"))
+			return 0, false
+		}
+		if errors.Is(err, storage.ErrPromoLimit) || errors.Is(err, storage.ErrPromoNotFound) {
+			c.JSON(http.StatusConflict, models.NewErrorResponse("Промокод больше нельзя использовать"))
+			return 0, false
+		}
+		if errors.Is(err, storage.ErrEstimateUsed) {
+			c.JSON(http.StatusConflict, models.NewErrorResponse("Расчет стоимости уже использован в другом заказе"))
+			return 0, false
+		}
 		h.logger.WithError(err).Error("Ошибка при создании заказа")
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании заказа"))
-		return
+		return 0, false
+	}
+
+	// Черновик заказа больше не требует напоминаний; заказ засчитывается как его конверсия
+	if request.DraftID != "" {
+		if err := h.drafts.MarkCheckoutDraftConverted(c.Request.Context(), request.DraftID, orderID); err != nil {
+			h.logger.WithError(err).Warnf("Черновик не связан с заказом ID=%d", orderID)
+		}
 	}
 
 	// Получаем полную информацию о заказе для отправки email
@@ -131,113 +331,480 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		h.logger.WithError(err).Errorf("Ошибка при получении созданного заказа ID=%d", orderID)
 		// Продолжаем выполнение, так как заказ уже создан
 	} else {
-		// Отправляем уведомление о заказе по email
-		if err := h.emailSender.SendOrderConfirmation(&createdOrder); err != nil {
+		// Отправляем уведомление о заказе по email (со счетом, если он прикладывается к подтверждению)
+		var attachments []utils.Attachment
+		if h.invoicing.AttachToConfirmation {
+			attachments = confirmationAttachments(c.Request.Context(), h.invoices, &createdOrder, h.logger)
+		}
+		if err := h.emailSender.SendOrderConfirmation(&createdOrder, attachments...); err != nil {
 			h.logger.WithError(err).Errorf("Ошибка при отправке уведомления о заказе ID=%d", orderID)
 			// Продолжаем выполнение, так как основная операция создания заказа уже выполнена успешно
 		}
 	}
 
+	// Сообщаем компании о товарах, остаток которых опустился до порога
+	if lowStock := h.lowStockItems(order.Items); len(lowStock) > 0 {
+		if err := h.emailSender.SendLowStockAlert(lowStock); err != nil {
+			h.logger.WithError(err).Errorf("Ошибка при отправке уведомления о низком остатке по заказу ID=%d", orderID)
+		}
+	}
+
 	// Возвращаем успешный ответ с сообщением на соответствующем языке
-	message := getOrderSuccessMessage(request.Language)
-	c.JSON(http.StatusOK, models.OrderResponse{
+	message := h.languages.Localize(orderSuccessMessages, request.Language)
+	response := models.OrderResponse{
 		Success: true,
 		OrderID: orderID,
 		Message: message,
-	})
+	}
+
+	// Если онлайн-оплата включена, заказ с ненулевой суммой можно оплатить по ссылке
+	if h.payments.Provider != "" && !order.TotalCost.IsZero() {
+		response.PaymentLink = orderPaymentLink(h.signer, h.publicURL, orderID, h.payments.LinkValidDays)
+	}
+	if h.invoices != nil {
+		response.InvoiceLink = orderInvoiceLink(h.signer, h.publicURL, orderID, h.invoicing.LinkValidDays)
+	}
+
+	c.JSON(http.StatusOK, response)
+	return orderID, true
 }
 
-// SubmitContactForm обработчик для отправки формы обратной связи
-func (h *OrderHandler) SubmitContactForm(c *gin.Context) {
-	var request models.ContactFormRequest
+// QuoteCart обработчик для предварительного расчета корзины: цены позиций, сумма, скидка
+// по промокоду, доставка и итог. Если промокод не применим или доставка недоступна,
+// расчет возвращается без них с причиной
+func (h *OrderHandler) QuoteCart(c *gin.Context) {
+	var request models.CartQuoteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на расчет корзины")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	if request.Language == "" || !h.languages.IsEnabled(request.Language) {
+		request.Language = i18n.FromContext(c)
+	}
 
-	// Парсим JSON из тела запроса
+	request.Email = normalizeEmail(request.Email)
+	lines, ok := h.validateItems(c, request.Items, request.Language)
+	if !ok {
+		return
+	}
+
+	var quote models.CartQuote
+	for _, line := range lines {
+		item, ok := h.priceItem(c, line)
+		if !ok {
+			return
+		}
+
+		var err error
+		if quote.Subtotal, err = quote.Subtotal.Add(item.Price.Mul(item.Quantity)); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Товары в заказе указаны в разных валютах"))
+			return
+		}
+		quote.Items = append(quote.Items, item)
+	}
+
+	quote.Currency = quote.Subtotal.Currency
+	quote.Total = quote.Subtotal
+
+	if request.PromoCode != "" {
+		discount, message, err := h.applyPromo(c.Request.Context(), request.PromoCode, quote.Items, request.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при расчете скидки"))
+			return
+		}
+		if message != "" {
+			quote.PromoError = message
+		} else {
+			quote.Discount = &discount
+			quote.Total = models.NewMoney(quote.Subtotal.Amount-discount.Amount.Amount, quote.Currency)
+		}
+	}
+
+	if request.ShippingMethod != "" {
+		address := normalizeAddress(request.Address)
+		shippingQuote, message, err := h.quoteShipping(c.Request.Context(), request.ShippingMethod, address, quote.Items, quote.Total)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при расчете доставки"))
+			return
+		}
+		if message != "" {
+			quote.ShippingError = message
+		} else {
+			quote.Shipping = &shippingQuote
+			quote.Total = models.NewMoney(quote.Total.Amount+shippingQuote.Cost.Amount, quote.Currency)
+		}
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(quote))
+}
+
+// ShippingOptions обработчик для расчета доставки корзины всеми включенными способами.
+// Порог бесплатной доставки проверяется по сумме со скидкой, если промокод применим
+func (h *OrderHandler) ShippingOptions(c *gin.Context) {
+	var request models.ShippingOptionsRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса формы обратной связи")
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на расчет доставки")
 		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
 		return
 	}
 
-	// Валидируем данные формы
-	if err := h.validator.Struct(request); err != nil {
-		h.logger.WithError(err).Error("Ошибка валидации данных формы обратной связи")
+	if request.Language == "" || !h.languages.IsEnabled(request.Language) {
+		request.Language = i18n.FromContext(c)
+	}
 
-		// Формируем детальные ошибки валидации
-		var validationErrors []models.ValidationError
-		for _, err := range err.(validator.ValidationErrors) {
-			validationErrors = append(validationErrors, models.ValidationError{
-				Field:   err.Field(),
-				Message: getValidationErrorMessage(err),
-			})
+	lines, ok := h.validateItems(c, request.Items, request.Language)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var items []models.OrderItem
+	var goods models.Money
+	for _, line := range lines {
+		item, ok := h.priceItem(c, line)
+		if !ok {
+			return
 		}
 
-		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse(validationErrors))
+		var err error
+		if goods, err = goods.Add(item.Price.Mul(item.Quantity)); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Товары в заказе указаны в разных валютах"))
+			return
+		}
+		items = append(items, item)
+	}
+
+	if request.PromoCode != "" {
+		discount, message, err := h.applyPromo(ctx, request.PromoCode, items, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при расчете доставки"))
+			return
+		}
+		if message == "" {
+			goods = models.NewMoney(goods.Amount-discount.Amount.Amount, goods.Currency)
+		}
+	}
+
+	methods, err := h.shipping.GetShippingMethods(ctx, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при расчете доставки"))
+		return
+	}
+	weight, err := h.parcelWeight(ctx, items)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при расчете доставки"))
 		return
 	}
 
-	// Если язык не указан, используем язык из заголовка Accept-Language или "ru" по умолчанию
-	if request.Language == "" {
-		request.Language = getPreferredLanguage(c)
+	address := normalizeAddress(request.Address)
+	options := make([]models.ShippingOption, 0, len(methods))
+	for _, method := range methods {
+		quote, err := shipping.Calculate(method, address, weight, goods)
+		option := models.ShippingOption{ShippingQuote: quote, Description: method.Description, Available: err == nil}
+		var shippingErr *shipping.Error
+		if errors.As(err, &shippingErr) {
+			option.Reason = shippingErr.Reason
+		} else if err != nil {
+			h.logger.WithError(err).Errorf("Ошибка при расчете доставки способом %s", method.Code)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при расчете доставки"))
+			return
+		}
+		options = append(options, option)
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(options))
+}
+
+// priceItem рассчитывает цену проверенной позиции на сервере.
+// При ошибке отправляет ответ клиенту и возвращает false
+func (h *OrderHandler) priceItem(c *gin.Context, line orderLine) (models.OrderItem, bool) {
+	item, message, err := h.itemPrice(c.Request.Context(), line)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при расчете стоимости заказа"))
+		return item, false
+	}
+	if message != "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(message))
+		return item, false
+	}
+	return item, true
+}
+
+// itemPrice рассчитывает цену проверенной позиции на сервере: по варианту товара
+// или по прикрепленному расчету стоимости. Персонализация не проверяется.
+// Если цену рассчитать нельзя, возвращает сообщение для покупателя
+func (h *OrderHandler) itemPrice(ctx context.Context, line orderLine) (models.OrderItem, string, error) {
+	item, product := line.item, line.product
+
+	// Цена рассчитывается на сервере: для товара с вариантами - по выбранному варианту
+	price := product.Price
+	if item.VariantID != nil {
+		variant, _ := product.FindVariant(*item.VariantID)
+
+		var err error
+		price, err = h.converter.VariantPrice(product.Price, variant)
+		if err != nil {
+			h.logger.WithError(err).Errorf("Ошибка при расчете цены варианта ID=%d", variant.ID)
+			return item, "Не удалось рассчитать цену варианта товара", nil
+		}
+
+		item.SKU = variant.SKU
+		item.VariantName = product.VariantName(variant)
 	}
 
-	// Отправляем уведомление по email
-	if err := h.emailSender.SendContactForm(&request); err != nil {
-		h.logger.WithError(err).Error("Ошибка при отправке уведомления о сообщении")
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при отправке сообщения"))
+	// Для позиции с прикрепленным расчетом цена берется из расчета
+	if item.EstimateID != nil {
+		var message string
+		var err error
+		if price, message, err = h.estimateUnitPrice(ctx, *item.EstimateID, product.FabricationKind, item.Quantity); err != nil || message != "" {
+			return item, message, err
+		}
+	}
+
+	// Заполняем дополнительные данные о товаре
+	item.Price = price
+	item.Currency = price.Currency
+	item.Discount = models.NewMoney(0, price.Currency)
+	item.ProductName = product.Name
+	item.CategoryID = product.CategoryID
+	item.SubcategoryID = product.SubcategoryID
+	if len(product.Images) > 0 {
+		item.ProductImage = product.Images[0]
+	}
+
+	return item, "", nil
+}
+
+// applyPromo применяет промокод к позициям и распределяет скидку по ним.
+// Если промокод не применим, возвращает сообщение для покупателя
+func (h *OrderHandler) applyPromo(ctx context.Context, code string, items []models.OrderItem, email string) (models.AppliedDiscount, string, error) {
+	promoCode, err := h.promos.GetPromoCode(ctx, promo.NormalizeCode(code))
+	if err != nil {
+		if errors.Is(err, storage.ErrPromoNotFound) {
+			return models.AppliedDiscount{}, "Промокод не найден", nil
+		}
+		return models.AppliedDiscount{}, "", err
+	}
+
+	if err := h.promos.CheckPromoUsage(ctx, promoCode, email); err != nil {
+		if errors.Is(err, storage.ErrPromoLimit) {
+			return models.AppliedDiscount{}, "Промокод больше нельзя использовать", nil
+		}
+		return models.AppliedDiscount{}, "", err
+	}
+
+	discount, err := promo.Apply(promoCode, items, time.Now())
+	if err != nil {
+		var promoErr *promo.Error
+		if errors.As(err, &promoErr) {
+			return models.AppliedDiscount{}, promoErr.Reason, nil
+		}
+		return models.AppliedDiscount{}, "", err
+	}
+
+	return discount, "", nil
+}
+
+// quoteShipping рассчитывает стоимость доставки позиций способом code; goods - сумма товаров со скидкой.
+// Если способ доставки недоступен, возвращает сообщение для покупателя
+func (h *OrderHandler) quoteShipping(ctx context.Context, code string, address *models.Address, items []models.OrderItem, goods models.Money) (models.ShippingQuote, string, error) {
+	method, err := h.shipping.GetShippingMethod(ctx, code)
+	if err != nil {
+		if errors.Is(err, storage.ErrShippingMethodNotFound) {
+			return models.ShippingQuote{}, "Способ доставки не найден", nil
+		}
+		return models.ShippingQuote{}, "", err
+	}
+
+	weight, err := h.parcelWeight(ctx, items)
+	if err != nil {
+		return models.ShippingQuote{}, "", err
+	}
+
+	quote, err := shipping.Calculate(method, address, weight, goods)
+	if err != nil {
+		var shippingErr *shipping.Error
+		if errors.As(err, &shippingErr) {
+			return models.ShippingQuote{}, shippingErr.Reason, nil
+		}
+		return models.ShippingQuote{}, "", err
+	}
+
+	return quote, "", nil
+}
+
+// parcelWeight рассчитывает вес посылки по весу и габаритам товаров
+func (h *OrderHandler) parcelWeight(ctx context.Context, items []models.OrderItem) (int, error) {
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ProductID)
+	}
+
+	dimensions, err := h.shipping.GetProductDimensions(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	return shipping.ParcelWeight(items, dimensions, h.parcels), nil
+}
+
+// normalizeAddress убирает лишние пробелы в адресе и приводит код страны к верхнему регистру
+func normalizeAddress(address *models.Address) *models.Address {
+	if address == nil {
+		return nil
+	}
+	normalized := models.Address{
+		Country:    strings.ToUpper(strings.TrimSpace(address.Country)),
+		Region:     strings.TrimSpace(address.Region),
+		City:       strings.TrimSpace(address.City),
+		PostalCode: strings.TrimSpace(address.PostalCode),
+		Street:     strings.TrimSpace(address.Street),
+		Apartment:  strings.TrimSpace(address.Apartment),
+	}
+	return &normalized
+}
+
+// UpdateOrderStatus обработчик для изменения статуса заказа; при отмене остатки возвращаются на склад,
+// после выполнения покупателю отправляется приглашение оставить отзыв
+func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID заказа"))
 		return
 	}
 
-	// Возвращаем успешный ответ с сообщением на соответствующем языке
-	message := getContactSuccessMessage(request.Language)
-	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]interface{}{
-		"message": message,
-	}))
+	var request models.OrderStatusRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на изменение статуса заказа")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+	if !containsString(models.OrderStatuses, request.Status) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный статус заказа"))
+		return
+	}
+
+	err = h.repo.UpdateOrderStatus(c.Request.Context(), id, request.Status)
+	switch {
+	case errors.Is(err, storage.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorResponse("Заказ не найден"))
+		return
+	case errors.Is(err, storage.ErrOrderStatus):
+		c.JSON(http.StatusConflict, models.NewErrorResponse("Отмененный заказ нельзя вернуть в работу"))
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при изменении статуса заказа"))
+		return
+	}
+
+	if request.Status == models.OrderStatusCompleted {
+		h.inviteReview(c.Request.Context(), id)
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{"id": id, "status": request.Status}))
 }
 
-// getPreferredLanguage определяет предпочтительный язык пользователя
-func getPreferredLanguage(c *gin.Context) string {
-	// Получаем заголовок Accept-Language
-	acceptLanguage := c.GetHeader("Accept-Language")
-	if acceptLanguage == "" {
-		return "ru" // По умолчанию русский
+// inviteReview отправляет покупателю выполненного заказа письмо со ссылкой для отзыва о товарах.
+// Приглашение отправляется один раз; если письмо отправить не удалось, оно будет отправлено
+// при следующей отметке заказа выполненным
+func (h *OrderHandler) inviteReview(ctx context.Context, id int64) {
+	marked, err := h.reviews.MarkReviewInvitation(ctx, id)
+	if err != nil || !marked {
+		return
 	}
 
-	// Разбираем заголовок (простая реализация)
-	// Формат: ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7
-	languages := []string{"ru", "en", "es"} // Поддерживаемые языки
+	order, err := h.repo.GetOrderByID(ctx, id)
+	if err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при получении заказа ID=%d для приглашения к отзыву", id)
+		h.releaseReviewInvitation(ctx, id)
+		return
+	}
 
-	// Проверяем наличие поддерживаемых языков в заголовке
-	for _, lang := range languages {
-		if len(acceptLanguage) >= len(lang) && acceptLanguage[:len(lang)] == lang {
-			return lang
+	invitation := &models.ReviewInvitation{
+		OrderID:  id,
+		Email:    order.Email,
+		Name:     order.Name,
+		Language: order.Language,
+		URL:      orderReviewLink(h.signer, h.publicURL, id, h.reviewing.LinkValidDays),
+	}
+	for _, item := range order.Items {
+		if !containsString(invitation.Products, item.ProductName) {
+			invitation.Products = append(invitation.Products, item.ProductName)
 		}
 	}
 
-	return "ru" // По умолчанию русский
+	if err := h.emailSender.SendReviewInvitation(invitation); err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при отправке приглашения к отзыву по заказу ID=%d", id)
+		h.releaseReviewInvitation(ctx, id)
+	}
 }
 
-// getOrderSuccessMessage возвращает сообщение об успешном создании заказа на нужном языке
-func getOrderSuccessMessage(lang string) string {
-	switch lang {
-	case "en":
-		return "Order successfully created"
-	case "es":
-		return "Pedido creado exitosamente"
-	default:
-		return "Заказ успешно создан"
+// releaseReviewInvitation снимает отметку о приглашении к отзыву, чтобы его можно было отправить повторно
+func (h *OrderHandler) releaseReviewInvitation(ctx context.Context, id int64) {
+	if err := h.reviews.ReleaseReviewInvitation(ctx, id); err != nil {
+		h.logger.WithError(err).Warnf("Приглашение к отзыву по заказу ID=%d не будет отправлено повторно", id)
 	}
 }
 
-// getContactSuccessMessage возвращает сообщение об успешной отправке формы на нужном языке
-func getContactSuccessMessage(lang string) string {
-	switch lang {
-	case "en":
-		return "Message sent successfully"
-	case "es":
-		return "Mensaje enviado exitosamente"
-	default:
-		return "Сообщение успешно отправлено"
+// lowStockItems возвращает позиции, после списания которых остаток впервые опустился до порога
+func (h *OrderHandler) lowStockItems(items []models.OrderItem) []models.LowStockItem {
+	var result []models.LowStockItem
+	for _, item := range items {
+		if item.StockLeft == nil {
+			continue
+		}
+		left := *item.StockLeft
+		if left > h.inventory.LowStockThreshold || left+item.Quantity <= h.inventory.LowStockThreshold {
+			continue
+		}
+		result = append(result, models.LowStockItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Name:      item.DisplayName(),
+			SKU:       item.SKU,
+			Remaining: left,
+		})
+	}
+	return result
+}
+
+// estimateUnitPrice возвращает цену за единицу из сохраненного расчета стоимости.
+// Расчет принимается только для товара, изготавливаемого по расчету того же вида (kind),
+// и только если он еще не использован в другом заказе.
+// Если расчет не подходит для позиции, возвращает сообщение для покупателя
+func (h *OrderHandler) estimateUnitPrice(ctx context.Context, id, kind string, quantity int) (models.Money, string, error) {
+	if kind == "" {
+		return models.Money{}, "Расчет стоимости нельзя прикрепить к этому товару", nil
+	}
+	if !uploadIDPattern.MatchString(id) {
+		return models.Money{}, "Расчет стоимости не найден", nil
+	}
+
+	result, err := h.estimates.GetEstimate(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrEstimateNotFound) {
+			return models.Money{}, "Расчет стоимости не найден", nil
+		}
+		return models.Money{}, "", err
 	}
+
+	if result.Kind != kind {
+		return models.Money{}, "Расчет стоимости выполнен для другого вида изготовления", nil
+	}
+	if result.OrderID != nil {
+		return models.Money{}, "Расчет стоимости уже использован в другом заказе", nil
+	}
+	if result.Expired(time.Now()) {
+		return models.Money{}, "Срок действия расчета стоимости истек, выполните расчет заново", nil
+	}
+	// Цена за единицу зависит от тиража, поэтому количество должно совпадать с расчетом
+	if result.Quantity != quantity {
+		return models.Money{}, "Количество в позиции не совпадает с тиражом в расчете стоимости", nil
+	}
+
+	return result.UnitPrice, "", nil
 }
 
 // getValidationErrorMessage возвращает сообщение об ошибке валидации на нужном языке
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+)
+
+// orderLine позиция заказа после проверки вместе с товаром
+type orderLine struct {
+	item    models.OrderItem
+	product models.ProductDetail
+}
+
+// validateItems проверяет состав заказа: количество позиций и единиц в позиции, наличие
+// и публикацию товаров, выбор варианта, остатки и минимальное количество для заказа.
+// Одинаковые позиции (тот же товар, вариант, расчет и персонализация) объединяются.
+// Ошибки возвращаются по каждой позиции с ее номером в запросе.
+// При ошибке отправляет ответ клиенту и возвращает false
+func (h *OrderHandler) validateItems(c *gin.Context, items []models.OrderItem, language string) ([]orderLine, bool) {
+	if len(items) > h.limits.MaxLines {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "Items", Message: fmt.Sprintf("В заказе может быть не более %d позиций", h.limits.MaxLines)},
+		}))
+		return nil, false
+	}
+
+	var validationErrors []models.ValidationError
+	for i, item := range items {
+		if message := h.quantityError(item.Quantity); message != "" {
+			validationErrors = append(validationErrors, itemError(i, "Quantity", message))
+		}
+	}
+	if len(validationErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse(validationErrors))
+		return nil, false
+	}
+
+	merged, positions := mergeItems(items)
+
+	ctx := c.Request.Context()
+	products := make(map[int64]models.ProductDetail)
+	lines := make([]orderLine, 0, len(merged))
+	for i, item := range merged {
+		position := positions[i]
+
+		product, ok := products[item.ProductID]
+		if !ok {
+			var err error
+			product, err = h.productRepo.GetProductByID(ctx, item.ProductID, language)
+			if errors.Is(err, storage.ErrProductNotFound) {
+				validationErrors = append(validationErrors, itemError(position, "ProductID", "Товар не найден"))
+				continue
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при проверке товаров заказа"))
+				return nil, false
+			}
+			products[item.ProductID] = product
+		}
+
+		if message := lineError(product, item, h.quantityError(item.Quantity)); message != nil {
+			validationErrors = append(validationErrors, itemError(position, message.Field, message.Message))
+			continue
+		}
+
+		lines = append(lines, orderLine{item: item, product: product})
+	}
+
+	if len(validationErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse(validationErrors))
+		return nil, false
+	}
+
+	return lines, true
+}
+
+// quantityError возвращает сообщение об ошибке, если количество единиц в позиции вне допустимых границ
+func (h *OrderHandler) quantityError(quantity int) string {
+	if quantity < 1 {
+		return "Количество должно быть больше нуля"
+	}
+	if quantity > h.limits.MaxItemQuantity {
+		return fmt.Sprintf("Количество должно быть не больше %d", h.limits.MaxItemQuantity)
+	}
+	return ""
+}
+
+// lineError проверяет позицию по товару: публикацию, вариант, остаток и минимальное количество.
+// quantityMessage - ошибка границ количества объединенной позиции. Возвращает поле позиции
+// и сообщение об ошибке или nil
+func lineError(product models.ProductDetail, item models.OrderItem, quantityMessage string) *models.ValidationError {
+	if !product.Published {
+		return &models.ValidationError{Field: "ProductID", Message: "Товар недоступен для заказа"}
+	}
+	if quantityMessage != "" {
+		return &models.ValidationError{Field: "Quantity", Message: quantityMessage}
+	}
+
+	if item.VariantID != nil {
+		variant, ok := product.FindVariant(*item.VariantID)
+		if !ok {
+			return &models.ValidationError{Field: "VariantID", Message: "Выбранный вариант товара не найден"}
+		}
+		if !variant.InStock(item.Quantity) {
+			return &models.ValidationError{Field: "Quantity", Message: "Выбранного варианта товара недостаточно на складе"}
+		}
+	} else if len(product.Variants) > 0 {
+		return &models.ValidationError{Field: "VariantID", Message: "Выберите вариант товара"}
+	} else if product.AvailableQuantity != nil && *product.AvailableQuantity < item.Quantity {
+		// Окончательная проверка с блокировкой выполняется при сохранении заказа
+		return &models.ValidationError{Field: "Quantity", Message: fmt.Sprintf("На складе осталось %d шт.", *product.AvailableQuantity)}
+	}
+
+	if item.Quantity < product.MinOrderQuantity {
+		return &models.ValidationError{
+			Field:   "Quantity",
+			Message: fmt.Sprintf("Минимальное количество для заказа: %d", product.MinOrderQuantity),
+		}
+	}
+
+	return nil
+}
+
+// mergeItems объединяет одинаковые позиции, складывая количество. Возвращает позиции
+// в порядке первого появления и номер первой из объединенных позиций в запросе
+func mergeItems(items []models.OrderItem) ([]models.OrderItem, []int) {
+	merged := make([]models.OrderItem, 0, len(items))
+	positions := make([]int, 0, len(items))
+	index := make(map[string]int, len(items))
+
+	for i, item := range items {
+		key := itemKey(item)
+		if j, ok := index[key]; ok {
+			merged[j].Quantity += item.Quantity
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, item)
+		positions = append(positions, i)
+	}
+
+	return merged, positions
+}
+
+// itemKey возвращает ключ позиции для поиска одинаковых позиций
+func itemKey(item models.OrderItem) string {
+	var variantID int64
+	if item.VariantID != nil {
+		variantID = *item.VariantID
+	}
+	var estimateID string
+	if item.EstimateID != nil {
+		estimateID = *item.EstimateID
+	}
+	personalization, _ := json.Marshal(item.Personalization)
+	return fmt.Sprintf("%d|%d|%s|%s", item.ProductID, variantID, estimateID, personalization)
+}
+
+// itemError возвращает ошибку валидации поля позиции заказа с номером position
+func itemError(position int, field, message string) models.ValidationError {
+	return models.ValidationError{Field: fmt.Sprintf("Items[%d].%s", position, field), Message: message}
+}
+
+// normalizeEmail убирает пробелы по краям email и приводит его к нижнему регистру
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// normalizePhone приводит номер телефона к виду +79991234567 или 89991234567: убирает пробелы,
+// скобки, дефисы и точки, префикс 00 заменяет на +. Возвращает false, если номер содержит
+// другие символы или в нем меньше 7 либо больше 15 цифр
+func normalizePhone(phone string) (string, bool) {
+	phone = strings.TrimSpace(phone)
+	international := strings.HasPrefix(phone, "+")
+	if international {
+		phone = phone[1:]
+	}
+
+	var digits strings.Builder
+	for _, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case unicode.IsSpace(r), r == '(', r == ')', r == '-', r == '.':
+		default:
+			return "", false
+		}
+	}
+
+	number := digits.String()
+	if !international && strings.HasPrefix(number, "00") {
+		international = true
+		number = number[2:]
+	}
+	if len(number) < 7 || len(number) > 15 {
+		return "", false
+	}
+
+	if international {
+		return "+" + number, true
+	}
+	return number, true
+}
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/payment"
+	"pryanik_studio/internal/security"
+	"pryanik_studio/internal/storage"
+	"pryanik_studio/internal/utils"
+)
+
+// maxWebhookSize максимальный размер тела вебхука платежного провайдера
+const maxWebhookSize = 1 << 20
+
+// PaymentHandler обработчик запросов для онлайн-оплаты заказов и вебхуков провайдера
+type PaymentHandler struct {
+	repo     storage.PaymentRepository
+	orders   storage.OrderRepository
+	provider payment.Provider
+	signer   *security.LinkSigner
+	sender   utils.Sender
+	config   config.PaymentConfig
+	logger   *logrus.Logger
+}
+
+// NewPaymentHandler создает новый экземпляр PaymentHandler; provider равен nil, если онлайн-оплата отключена
+func NewPaymentHandler(
+	repo storage.PaymentRepository,
+	orders storage.OrderRepository,
+	provider payment.Provider,
+	signer *security.LinkSigner,
+	sender utils.Sender,
+	config config.PaymentConfig,
+	logger *logrus.Logger,
+) *PaymentHandler {
+	return &PaymentHandler{
+		repo:     repo,
+		orders:   orders,
+		provider: provider,
+		signer:   signer,
+		sender:   sender,
+		config:   config,
+		logger:   logger,
+	}
+}
+
+// StartPayment обработчик для оплаты заказа по подписанной ссылке: резервирует платеж (или берет
+// еще не оплаченный), при необходимости создает его у провайдера и возвращает страницу оплаты
+func (h *PaymentHandler) StartPayment(c *gin.Context) {
+	if h.provider == nil {
+		c.JSON(http.StatusServiceUnavailable, models.NewErrorResponse("Онлайн-оплата недоступна"))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID заказа"))
+		return
+	}
+
+	err = h.signer.Verify(orderPaymentResource(id), c.Query("expires"), c.Query("signature"))
+	switch {
+	case errors.Is(err, security.ErrSignatureExpired):
+		c.JSON(http.StatusGone, models.NewErrorResponse(err.Error()))
+		return
+	case err != nil:
+		h.logger.Warnf("Недействительная ссылка на оплату заказа ID=%d от %s", id, c.ClientIP())
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	order, err := h.orders.GetOrderByID(ctx, id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	started, err := h.repo.StartPayment(ctx, id, h.provider.Name())
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	// Платеж у провайдера создается только для зарезервированной строки платежа. Если параллельный
+	// запрос уже создает его, ключ идемпотентности по ID платежа вернет тот же платеж
+	if started.ProviderPaymentID == "" {
+		created, err := h.provider.CreatePayment(ctx, paymentRequest(started, order, h.config.ReturnURL))
+		if err != nil {
+			h.logger.WithError(err).Errorf("Ошибка при создании платежа ID=%d у провайдера", started.ID)
+			if err := h.repo.FailPayment(ctx, started.ID, "Платеж не создан провайдером"); err != nil {
+				h.logger.WithError(err).Errorf("Ошибка при отметке неуспешного платежа ID=%d", started.ID)
+			}
+			c.JSON(http.StatusBadGateway, models.NewErrorResponse("Не удалось создать платеж, попробуйте позже"))
+			return
+		}
+
+		if err := h.repo.SetPaymentCreated(ctx, started.ID, created.ProviderPaymentID, created.ConfirmationURL); err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании платежа"))
+			return
+		}
+		started.ConfirmationURL = created.ConfirmationURL
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(models.PaymentStartResponse{
+		PaymentID:       started.ID,
+		ConfirmationURL: started.ConfirmationURL,
+	}))
+}
+
+// Webhook обработчик вебхуков платежного провайдера. Подпись проверяется провайдером;
+// повторно доставленные события пропускаются. Ответ с ошибкой означает, что провайдер повторит доставку
+func (h *PaymentHandler) Webhook(c *gin.Context) {
+	if h.provider == nil || c.Param("provider") != h.provider.Name() {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse("Платежный провайдер не найден"))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	event, err := h.provider.ParseWebhook(c.Request.Header, body)
+	switch {
+	case errors.Is(err, payment.ErrIgnoredEvent):
+		c.JSON(http.StatusOK, models.NewSuccessResponse(nil))
+		return
+	case errors.Is(err, payment.ErrInvalidSignature):
+		h.logger.Warnf("Вебхук %s с недействительной подписью от %s", h.provider.Name(), c.ClientIP())
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(err.Error()))
+		return
+	case err != nil:
+		h.logger.WithError(err).Errorf("Ошибка при разборе вебхука %s", h.provider.Name())
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	result, err := h.repo.ApplyPaymentEvent(c.Request.Context(), h.provider.Name(), event)
+	switch {
+	case errors.Is(err, storage.ErrPaymentNotFound):
+		// Платеж создан не нами (например, вручную в кабинете провайдера) - повторять доставку бессмысленно
+		h.logger.Warnf("Событие %s относится к неизвестному платежу %s", event.ID, event.ProviderPaymentID)
+		c.JSON(http.StatusOK, models.NewSuccessResponse(nil))
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при обработке события"))
+		return
+	}
+
+	if result.Applied {
+		updated := result.Payment
+		h.logger.Infof("Событие %s (%s) применено к платежу ID=%d заказа ID=%d, статус платежа: %s",
+			event.ID, event.Type, updated.ID, updated.OrderID, updated.Status)
+	}
+	if result.Anomaly != "" {
+		h.alertAnomaly(result.Payment)
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil))
+}
+
+// GetOrderPayments обработчик для получения платежей заказа
+func (h *PaymentHandler) GetOrderPayments(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID заказа"))
+		return
+	}
+
+	payments, err := h.repo.GetOrderPayments(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении платежей"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(payments))
+}
+
+// RefundPayment обработчик для возврата платежа (полного или частичного). Результат возврата
+// применяется сразу; событие о нем из вебхука затем будет пропущено, так как сумма уже учтена
+func (h *PaymentHandler) RefundPayment(c *gin.Context) {
+	if h.provider == nil {
+		c.JSON(http.StatusServiceUnavailable, models.NewErrorResponse("Онлайн-оплата недоступна"))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID платежа"))
+		return
+	}
+
+	var request models.RefundRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на возврат платежа")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	current, err := h.repo.GetPayment(ctx, id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	if current.Provider != h.provider.Name() {
+		c.JSON(http.StatusConflict, models.NewErrorResponse("Платеж проведен через другого провайдера"))
+		return
+	}
+	if current.Status != models.PaymentStatusSucceeded {
+		c.JSON(http.StatusConflict, models.NewErrorResponse("Возврат возможен только по оплаченному платежу"))
+		return
+	}
+
+	remaining := current.Amount.Amount - current.RefundedAmount.Amount
+	amount := models.NewMoney(remaining, current.Currency)
+	if request.Amount != nil {
+		amount = models.NewMoney(request.Amount.Amount, current.Currency)
+	}
+	if amount.Amount <= 0 || amount.Amount > remaining {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			fmt.Sprintf("Сумма возврата должна быть больше нуля и не больше %s", models.NewMoney(remaining, current.Currency)),
+		))
+		return
+	}
+
+	refund, err := h.provider.Refund(ctx, current, amount)
+	if err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при возврате платежа ID=%d", id)
+		c.JSON(http.StatusBadGateway, models.NewErrorResponse("Не удалось выполнить возврат, попробуйте позже"))
+		return
+	}
+
+	event := models.PaymentEvent{
+		ID:                "refund:" + refund.ID,
+		Type:              models.PaymentEventRefunded,
+		ProviderPaymentID: current.ProviderPaymentID,
+		RefundedAmount:    models.NewMoney(current.RefundedAmount.Amount+refund.Amount.Amount, current.Currency),
+	}
+	result, err := h.repo.ApplyPaymentEvent(ctx, h.provider.Name(), event)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Возврат выполнен, но не сохранен; он будет учтен по уведомлению провайдера"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(result.Payment))
+}
+
+// alertAnomaly записывает в журнал аномалию оплаты и сообщает о ней компании. Ошибка отправки
+// письма не влияет на ответ провайдеру: аномалия уже сохранена у платежа
+func (h *PaymentHandler) alertAnomaly(anomaly models.Payment) {
+	h.logger.Errorf("Аномалия оплаты: %s; платеж ID=%d заказа ID=%d на сумму %s требует возврата",
+		anomaly.Anomaly, anomaly.ID, anomaly.OrderID, anomaly.Amount)
+	if err := h.sender.SendPaymentAnomalyAlert(&anomaly); err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при отправке уведомления об аномалии платежа ID=%d", anomaly.ID)
+	}
+}
+
+// respondError преобразует ошибку хранилища в ответ API
+func (h *PaymentHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, storage.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorResponse("Заказ не найден"))
+	case errors.Is(err, storage.ErrPaymentNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(err.Error()))
+	case errors.Is(err, storage.ErrOrderStatus):
+		c.JSON(http.StatusConflict, models.NewErrorResponse("Отмененный заказ нельзя оплатить"))
+	case errors.Is(err, storage.ErrOrderPaid), errors.Is(err, storage.ErrNothingToPay):
+		c.JSON(http.StatusConflict, models.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при обработке платежа"))
+	}
+}
+
+// paymentRequest возвращает параметры создания платежа у провайдера для заказа
+func paymentRequest(started models.Payment, order models.Order, returnURL string) payment.CreateRequest {
+	return payment.CreateRequest{
+		PaymentID:   started.ID,
+		OrderID:     order.ID,
+		Amount:      started.Amount,
+		Description: fmt.Sprintf("Заказ №%d", order.ID),
+		Email:       order.Email,
+		ReturnURL:   returnURL,
+	}
+}
+
+// orderPaymentLink возвращает подписанную ссылку на страницу оплаты заказа
+func orderPaymentLink(signer *security.LinkSigner, publicURL string, id int64, validDays int) string {
+	expires := time.Now().AddDate(0, 0, validDays)
+	query := signer.Query(orderPaymentResource(id), expires)
+	return fmt.Sprintf("%s/orders/%d/payment?%s", publicURL, id, query.Encode())
+}
+
+// orderPaymentResource возвращает имя ресурса для подписи ссылки на оплату заказа
+func orderPaymentResource(id int64) string {
+	return fmt.Sprintf("order-payment:%d", id)
+}
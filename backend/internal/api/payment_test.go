@@ -0,0 +1,200 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/payment"
+	"pryanik_studio/internal/storage"
+	"pryanik_studio/internal/utils"
+)
+
+// webhookRepository хранилище платежей для тестов вебхука: запоминает переданные события и
+// возвращает заданный тестом результат. Логика применения событий проверяется в пакете storage
+type webhookRepository struct {
+	storage.PaymentRepository
+
+	providerPaymentID string
+	result            models.PaymentEventResult
+	err               error
+	events            []models.PaymentEvent
+}
+
+func (r *webhookRepository) ApplyPaymentEvent(ctx context.Context, provider string, event models.PaymentEvent) (models.PaymentEventResult, error) {
+	if event.ProviderPaymentID != r.providerPaymentID {
+		return models.PaymentEventResult{}, storage.ErrPaymentNotFound
+	}
+	r.events = append(r.events, event)
+	return r.result, r.err
+}
+
+// anomalySender запоминает уведомления об аномалиях оплаты
+type anomalySender struct {
+	utils.Sender
+	alerts []models.Payment
+}
+
+func (s *anomalySender) SendPaymentAnomalyAlert(payment *models.Payment) error {
+	s.alerts = append(s.alerts, *payment)
+	return nil
+}
+
+// webhookTest обработчик вебхуков с тестовым провайдером
+type webhookTest struct {
+	provider *payment.FakeProvider
+	repo     *webhookRepository
+	sender   *anomalySender
+	router   *gin.Engine
+}
+
+func newWebhookTest(result models.PaymentEventResult, err error) *webhookTest {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	test := &webhookTest{
+		provider: payment.NewFakeProvider("secret"),
+		repo:     &webhookRepository{providerPaymentID: "fake_7", result: result, err: err},
+		sender:   &anomalySender{},
+		router:   gin.New(),
+	}
+	handler := NewPaymentHandler(test.repo, nil, test.provider, nil, test.sender, config.PaymentConfig{}, logger)
+	test.router.POST("/payments/webhook/:provider", handler.Webhook)
+	return test
+}
+
+// send отправляет событие в вебхук; signature пустая - подпись тестового провайдера
+func (w *webhookTest) send(t *testing.T, event payment.FakeEvent, signature string) int {
+	t.Helper()
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature == "" {
+		signature = w.provider.Sign(body)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/payments/webhook/fake", bytes.NewReader(body))
+	request.Header.Set("X-Fake-Signature", signature)
+	recorder := httptest.NewRecorder()
+	w.router.ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+// succeededPayment платеж после применения события об успешной оплате
+var succeededPayment = models.Payment{ID: 7, OrderID: 3, ProviderPaymentID: "fake_7", Status: models.PaymentStatusSucceeded}
+
+func TestWebhookRejectsInvalidSignature(t *testing.T) {
+	test := newWebhookTest(models.PaymentEventResult{Payment: succeededPayment, Applied: true}, nil)
+
+	code := test.send(t, payment.FakeEvent{ID: "evt_1", Type: "succeeded", PaymentID: "fake_7"}, "bad")
+	if code != http.StatusBadRequest {
+		t.Fatalf("код ответа %d, ожидался %d", code, http.StatusBadRequest)
+	}
+	if len(test.repo.events) != 0 {
+		t.Fatalf("событие с недействительной подписью передано в хранилище: %+v", test.repo.events)
+	}
+}
+
+func TestWebhookUnknownProvider(t *testing.T) {
+	test := newWebhookTest(models.PaymentEventResult{}, nil)
+
+	request := httptest.NewRequest(http.MethodPost, "/payments/webhook/stripe", bytes.NewReader([]byte(`{}`)))
+	recorder := httptest.NewRecorder()
+	test.router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("код ответа %d, ожидался %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+func TestWebhookPassesEventToRepository(t *testing.T) {
+	test := newWebhookTest(models.PaymentEventResult{Payment: succeededPayment, Applied: true}, nil)
+
+	if code := test.send(t, payment.FakeEvent{ID: "evt_1", Type: "succeeded", PaymentID: "fake_7"}, ""); code != http.StatusOK {
+		t.Fatalf("код ответа %d, ожидался %d", code, http.StatusOK)
+	}
+	if len(test.repo.events) != 1 {
+		t.Fatalf("передано событий: %d, ожидалось 1", len(test.repo.events))
+	}
+	if event := test.repo.events[0]; event.ID != "evt_1" || event.Type != models.PaymentEventSucceeded {
+		t.Fatalf("событие %+v", event)
+	}
+	if len(test.sender.alerts) != 0 {
+		t.Fatalf("неожиданные уведомления об аномалии: %+v", test.sender.alerts)
+	}
+}
+
+func TestWebhookIgnoresUnknownEvents(t *testing.T) {
+	test := newWebhookTest(models.PaymentEventResult{}, nil)
+
+	if code := test.send(t, payment.FakeEvent{ID: "evt_1", Type: "created", PaymentID: "fake_7"}, ""); code != http.StatusOK {
+		t.Fatalf("код ответа %d, ожидался %d", code, http.StatusOK)
+	}
+	if code := test.send(t, payment.FakeEvent{ID: "evt_2", Type: "succeeded", PaymentID: "fake_unknown"}, ""); code != http.StatusOK {
+		t.Fatalf("код ответа для неизвестного платежа %d, ожидался %d", code, http.StatusOK)
+	}
+	if len(test.repo.events) != 0 {
+		t.Fatalf("переданы события: %+v", test.repo.events)
+	}
+}
+
+func TestWebhookRepositoryError(t *testing.T) {
+	test := newWebhookTest(models.PaymentEventResult{}, errors.New("база данных недоступна"))
+
+	// Провайдер повторит доставку события после ответа с ошибкой
+	if code := test.send(t, payment.FakeEvent{ID: "evt_1", Type: "succeeded", PaymentID: "fake_7"}, ""); code != http.StatusInternalServerError {
+		t.Fatalf("код ответа %d, ожидался %d", code, http.StatusInternalServerError)
+	}
+}
+
+func TestWebhookAlertsAnomaly(t *testing.T) {
+	anomalous := succeededPayment
+	anomalous.Anomaly = models.PaymentAnomalyDuplicate
+
+	tests := []struct {
+		name       string
+		result     models.PaymentEventResult
+		wantAlerts int
+	}{
+		{
+			name:       "новая аномалия",
+			result:     models.PaymentEventResult{Payment: anomalous, Applied: true, Anomaly: models.PaymentAnomalyDuplicate},
+			wantAlerts: 1,
+		},
+		{
+			name:   "аномалия обнаружена раньше",
+			result: models.PaymentEventResult{Payment: anomalous, Applied: true},
+		},
+		{
+			name:   "повторная доставка события",
+			result: models.PaymentEventResult{Payment: anomalous},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := newWebhookTest(tt.result, nil)
+
+			if code := test.send(t, payment.FakeEvent{ID: "evt_1", Type: "succeeded", PaymentID: "fake_7"}, ""); code != http.StatusOK {
+				t.Fatalf("код ответа %d, ожидался %d", code, http.StatusOK)
+			}
+			if len(test.sender.alerts) != tt.wantAlerts {
+				t.Fatalf("уведомлений об аномалии: %d, ожидалось %d", len(test.sender.alerts), tt.wantAlerts)
+			}
+			if tt.wantAlerts > 0 && test.sender.alerts[0].ID != anomalous.ID {
+				t.Fatalf("уведомление %+v", test.sender.alerts[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"pryanik_studio/internal/i18n"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+)
+
+// maxPersonalizationTextLength верхняя граница длины текстового поля персонализации
+const maxPersonalizationTextLength = 1000
+
+// colorPattern формат произвольного цвета персонализации
+var colorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// validatePersonalizationSchema проверяет схему персонализации товара и возвращает текст ошибки
+func validatePersonalizationSchema(schema models.PersonalizationSchema, languages *i18n.Registry) string {
+	codes := make(map[string]bool, len(schema))
+	for _, field := range schema {
+		if !optionCodePattern.MatchString(field.Code) {
+			return "Некорректный код поля персонализации: " + field.Code
+		}
+		if codes[field.Code] {
+			return "Поле персонализации указано дважды: " + field.Code
+		}
+		codes[field.Code] = true
+
+		if strings.TrimSpace(field.Labels[languages.Default()]) == "" {
+			return "Отсутствует подпись поля персонализации на языке по умолчанию: " + field.Code
+		}
+		for lang := range field.Labels {
+			if !languages.IsRegistered(lang) {
+				return "Неподдерживаемый язык перевода: " + lang
+			}
+		}
+
+		switch field.Type {
+		case models.PersonalizationText:
+			if field.MaxLength <= 0 || field.MaxLength > maxPersonalizationTextLength {
+				return "Некорректная максимальная длина поля персонализации: " + field.Code
+			}
+		case models.PersonalizationFont:
+			if len(field.Options) == 0 {
+				return "Не указаны шрифты для поля персонализации: " + field.Code
+			}
+		case models.PersonalizationColor:
+			for _, option := range field.Options {
+				if !colorPattern.MatchString(option) {
+					return "Некорректный цвет " + option + " в поле персонализации: " + field.Code
+				}
+			}
+		case models.PersonalizationFile:
+			for _, contentType := range field.Accept {
+				if _, ok := uploadContentTypes[contentType]; !ok {
+					return "Недопустимый тип файла " + contentType + " в поле персонализации: " + field.Code
+				}
+			}
+		default:
+			return "Некорректный тип поля персонализации: " + field.Code
+		}
+	}
+
+	return ""
+}
+
+// resolvePersonalization проверяет значения персонализации позиции заказа по схеме товара
+// и дополняет их подписями на языке заказа. Возвращает текст ошибки для клиента
+// или err при ошибке хранилища
+func resolvePersonalization(
+	ctx context.Context,
+	uploads storage.UploadRepository,
+	schema models.PersonalizationSchema,
+	values models.Personalization,
+	language string,
+	defaultLanguage string,
+) (models.Personalization, string, error) {
+	submitted := make(map[string]string, len(values))
+	for _, value := range values {
+		if _, ok := schema.Field(value.Code); !ok {
+			return nil, "Неизвестное поле персонализации: " + value.Code, nil
+		}
+		if _, exists := submitted[value.Code]; exists {
+			return nil, "Поле персонализации указано дважды: " + value.Code, nil
+		}
+		submitted[value.Code] = strings.TrimSpace(value.Value)
+	}
+
+	// Значения сохраняются в порядке полей схемы
+	result := make(models.Personalization, 0, len(submitted))
+	for _, field := range schema {
+		label := field.Label(language, defaultLanguage)
+
+		value := submitted[field.Code]
+		if value == "" {
+			if field.Required {
+				return nil, "Заполните поле «" + label + "»", nil
+			}
+			continue
+		}
+
+		resolved := models.PersonalizationValue{
+			Code:  field.Code,
+			Value: value,
+			Label: label,
+			Type:  field.Type,
+		}
+
+		switch field.Type {
+		case models.PersonalizationText:
+			if utf8.RuneCountInString(value) > field.MaxLength {
+				return nil, "Слишком длинное значение поля «" + label + "»", nil
+			}
+		case models.PersonalizationFont:
+			if !containsString(field.Options, value) {
+				return nil, "Недопустимый шрифт в поле «" + label + "»", nil
+			}
+		case models.PersonalizationColor:
+			if len(field.Options) > 0 && !containsString(field.Options, value) ||
+				len(field.Options) == 0 && !colorPattern.MatchString(value) {
+				return nil, "Недопустимый цвет в поле «" + label + "»", nil
+			}
+		case models.PersonalizationFile:
+			if !uploadIDPattern.MatchString(value) {
+				return nil, "Файл в поле «" + label + "» не найден", nil
+			}
+			upload, err := uploads.GetUpload(ctx, value)
+			if err != nil {
+				if errors.Is(err, storage.ErrUploadNotFound) {
+					return nil, "Файл в поле «" + label + "» не найден", nil
+				}
+				return nil, "", err
+			}
+			if len(field.Accept) > 0 && !containsString(field.Accept, upload.ContentType) {
+				return nil, "Недопустимый тип файла в поле «" + label + "»", nil
+			}
+			resolved.FileName = upload.FileName
+		}
+
+		result = append(result, resolved)
+	}
+
+	return result, "", nil
+}
+
+// containsString проверяет наличие строки в списке
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
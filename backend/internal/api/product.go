@@ -1,27 +1,67 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/currency"
+	"pryanik_studio/internal/i18n"
 	"pryanik_studio/internal/models"
 	"pryanik_studio/internal/storage"
 )
 
 // ProductHandler обработчик запросов для товаров
 type ProductHandler struct {
-	repo   storage.ProductRepository
-	logger *logrus.Logger
+	repo      storage.ProductRepository
+	languages *i18n.Registry
+	converter *currency.Converter
+	catalog   config.CatalogConfig
+	logger    *logrus.Logger
 }
 
 // NewProductHandler создает новый экземпляр ProductHandler
-func NewProductHandler(repo storage.ProductRepository, logger *logrus.Logger) *ProductHandler {
+func NewProductHandler(
+	repo storage.ProductRepository,
+	languages *i18n.Registry,
+	converter *currency.Converter,
+	catalog config.CatalogConfig,
+	logger *logrus.Logger,
+) *ProductHandler {
 	return &ProductHandler{
-		repo:   repo,
-		logger: logger,
+		repo:      repo,
+		languages: languages,
+		converter: converter,
+		catalog:   catalog,
+		logger:    logger,
+	}
+}
+
+// displayCurrency возвращает валюту из параметра currency, в которую нужно пересчитать цены.
+// Если валюта не поддерживается, отправляет ответ 400 и возвращает false
+func (h *ProductHandler) displayCurrency(c *gin.Context) (string, bool) {
+	code := strings.ToUpper(c.Query("currency"))
+	if code != "" && !h.converter.IsSupported(code) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Неподдерживаемая валюта"))
+		return "", false
+	}
+	return code, true
+}
+
+// applyDisplayCurrency пересчитывает цены товаров в запрошенную валюту.
+// Если цену пересчитать не удалось, она остается в исходной валюте
+func (h *ProductHandler) applyDisplayCurrency(products []models.Product, code string) {
+	for i := range products {
+		if err := h.converter.ApplyDisplayCurrency(&products[i], code); err != nil {
+			h.logger.WithError(err).Warnf("Не удалось пересчитать цену товара ID=%d в %s", products[i].ID, code)
+		}
 	}
 }
 
@@ -29,8 +69,8 @@ func NewProductHandler(repo storage.ProductRepository, logger *logrus.Logger) *P
 func (h *ProductHandler) GetProducts(c *gin.Context) {
 	var filter models.ProductFilter
 
-	// Получаем язык из запроса, по умолчанию "ru"
-	filter.Language = c.DefaultQuery("language", "ru")
+	// Получаем язык запроса
+	filter.Language = i18n.FromContext(c)
 
 	// Получаем параметры пагинации
 	page := c.DefaultQuery("page", "1")
@@ -71,10 +111,29 @@ func (h *ProductHandler) GetProducts(c *gin.Context) {
 		filter.Search = &search
 	}
 
-	// Получаем параметр сортировки по цене
-	sortPrice := c.Query("sort_price")
-	if sortPrice != "" && (sortPrice == "asc" || sortPrice == "desc") {
-		filter.SortByPrice = &sortPrice
+	// Получаем параметр сортировки и проверяем его по белому списку
+	filter.Sort = c.Query("sort")
+	if filter.Sort != "" && !models.IsValidProductSort(filter.Sort) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный параметр сортировки"))
+		return
+	}
+
+	// Поддерживаем устаревший параметр sort_price, если sort не указан
+	if filter.Sort == "" {
+		switch c.Query("sort_price") {
+		case "asc":
+			filter.Sort = models.ProductSortPrice
+		case "desc":
+			filter.Sort = models.ProductSortPriceDesc
+		}
+	}
+
+	filter.PopularityWindow = time.Duration(h.catalog.PopularityWindowDays) * 24 * time.Hour
+
+	// Валюта отображения цен не зависит от языка
+	displayCurrency, ok := h.displayCurrency(c)
+	if !ok {
+		return
 	}
 
 	// Получаем список товаров из репозитория
@@ -85,6 +144,8 @@ func (h *ProductHandler) GetProducts(c *gin.Context) {
 		return
 	}
 
+	h.applyDisplayCurrency(products.Items, displayCurrency)
+
 	c.JSON(http.StatusOK, models.NewSuccessResponse(products))
 }
 
@@ -98,8 +159,13 @@ func (h *ProductHandler) GetProductByID(c *gin.Context) {
 		return
 	}
 
-	// Получаем язык из запроса, по умолчанию "ru"
-	language := c.DefaultQuery("language", "ru")
+	// Получаем язык запроса
+	language := i18n.FromContext(c)
+
+	displayCurrency, ok := h.displayCurrency(c)
+	if !ok {
+		return
+	}
 
 	// Получаем товар из репозитория
 	product, err := h.repo.GetProductByID(c.Request.Context(), id, language)
@@ -108,14 +174,54 @@ func (h *ProductHandler) GetProductByID(c *gin.Context) {
 		c.JSON(http.StatusNotFound, models.NewErrorResponse("Товар не найден"))
 		return
 	}
+	// Снятый с публикации товар в каталоге не показывается
+	if !product.Published {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse("Товар не найден"))
+		return
+	}
+
+	// Цены вариантов рассчитываются от цены товара до пересчета в валюту отображения
+	h.priceVariants(&product, displayCurrency)
+
+	if err := h.converter.ApplyDisplayCurrency(&product.Product, displayCurrency); err != nil {
+		h.logger.WithError(err).Warnf("Не удалось пересчитать цену товара ID=%d в %s", id, displayCurrency)
+	}
+	h.applyDisplayCurrency(product.RelatedProducts, displayCurrency)
 
 	c.JSON(http.StatusOK, models.NewSuccessResponse(product))
 }
 
+// priceVariants рассчитывает итоговые цены вариантов товара и при необходимости
+// пересчитывает их в валюту отображения. Варианты, цену которых рассчитать не удалось, скрываются
+func (h *ProductHandler) priceVariants(product *models.ProductDetail, displayCurrency string) {
+	variants := make([]models.ProductVariant, 0, len(product.Variants))
+	for _, variant := range product.Variants {
+		price, err := h.converter.VariantPrice(product.Price, variant)
+		if err != nil {
+			h.logger.WithError(err).Warnf("Не удалось рассчитать цену варианта %s товара ID=%d", variant.SKU, product.ID)
+			continue
+		}
+
+		if displayCurrency != "" && displayCurrency != price.Currency {
+			converted, err := h.converter.Convert(price, displayCurrency)
+			if err != nil {
+				h.logger.WithError(err).Warnf("Не удалось пересчитать цену варианта %s в %s", variant.SKU, displayCurrency)
+			} else {
+				price = converted
+			}
+		}
+
+		variant.Price = price
+		variant.Currency = price.Currency
+		variants = append(variants, variant)
+	}
+	product.Variants = variants
+}
+
 // GetCategories обработчик для получения списка категорий
 func (h *ProductHandler) GetCategories(c *gin.Context) {
-	// Получаем язык из запроса, по умолчанию "ru"
-	language := c.DefaultQuery("language", "ru")
+	// Получаем язык запроса
+	language := i18n.FromContext(c)
 
 	// Получаем категории из репозитория
 	categories, err := h.repo.GetCategories(c.Request.Context(), language)
@@ -138,8 +244,8 @@ func (h *ProductHandler) GetRelatedProducts(c *gin.Context) {
 		return
 	}
 
-	// Получаем язык из запроса, по умолчанию "ru"
-	language := c.DefaultQuery("language", "ru")
+	// Получаем язык запроса
+	language := i18n.FromContext(c)
 
 	// Получаем лимит связанных товаров, по умолчанию 5
 	limitStr := c.DefaultQuery("limit", "5")
@@ -148,6 +254,11 @@ func (h *ProductHandler) GetRelatedProducts(c *gin.Context) {
 		limit = 5
 	}
 
+	displayCurrency, ok := h.displayCurrency(c)
+	if !ok {
+		return
+	}
+
 	// Получаем связанные товары из репозитория
 	products, err := h.repo.GetRelatedProducts(c.Request.Context(), id, limit, language)
 	if err != nil {
@@ -156,6 +267,8 @@ func (h *ProductHandler) GetRelatedProducts(c *gin.Context) {
 		return
 	}
 
+	h.applyDisplayCurrency(products, displayCurrency)
+
 	c.JSON(http.StatusOK, models.NewSuccessResponse(products))
 }
 
@@ -170,17 +283,43 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 		return
 	}
 
-	// Проверка наличия переводов для русского языка (обязательно)
-	if _, ok := request.Translations["ru"]; !ok {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Отсутствует обязательный перевод для русского языка"))
+	// Проверка наличия перевода для языка по умолчанию (обязательно)
+	if _, ok := request.Translations[h.languages.Default()]; !ok {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Отсутствует обязательный перевод для языка по умолчанию"))
+		return
+	}
+
+	// Переводы допускаются только для зарегистрированных языков
+	for lang := range request.Translations {
+		if !h.languages.IsRegistered(lang) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Неподдерживаемый язык перевода: "+lang))
+			return
+		}
+	}
+
+	// Проверяем базовую цену: при ее наличии цены переводов рассчитываются по курсам
+	var basePrice *models.Money
+	if request.BasePrice != nil {
+		price, message := h.validateBasePrice(request.BasePrice.Amount, request.BaseCurrency)
+		if message != "" {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(message))
+			return
+		}
+		basePrice = &price
+	}
+
+	if message := validatePersonalizationSchema(request.Personalization, h.languages); message != "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(message))
 		return
 	}
 
 	// Создаем модель товара из запроса
 	product := &models.Product{
-		CategoryID:   request.CategoryID,
-		Images:       request.Images,
-		Translations: make(map[string]*models.ProductTranslation),
+		CategoryID:      request.CategoryID,
+		Images:          request.Images,
+		BasePrice:       basePrice,
+		Personalization: request.Personalization,
+		Translations:    make(map[string]*models.ProductTranslation),
 	}
 
 	// Устанавливаем подкатегорию, если она указана
@@ -190,11 +329,27 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 
 	// Преобразуем переводы
 	for lang, translation := range request.Translations {
+		price := models.Money{}
+		switch {
+		case translation.Price != nil && translation.Currency != "":
+			if translation.Price.IsNegative() {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse("Цена не может быть отрицательной"))
+				return
+			}
+			price = models.NewMoney(translation.Price.Amount, translation.Currency)
+		case basePrice != nil:
+			// Цена будет рассчитана по курсу сразу после создания товара
+			price = *basePrice
+		default:
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Не указана цена перевода для языка "+lang))
+			return
+		}
+
 		product.Translations[lang] = &models.ProductTranslation{
 			Name:            translation.Name,
 			Description:     translation.Description,
-			Price:           translation.Price,
-			Currency:        translation.Currency,
+			Price:           price,
+			Currency:        price.Currency,
 			Characteristics: translation.Characteristics,
 		}
 	}
@@ -207,8 +362,15 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 		return
 	}
 
+	// Рассчитываем цены переводов из базовой цены
+	if basePrice != nil {
+		if _, err := h.converter.RepriceProducts(c.Request.Context(), productID); err != nil {
+			h.logger.WithError(err).Errorf("Ошибка при пересчете цен товара ID=%d", productID)
+		}
+	}
+
 	// Получаем созданный товар для возврата полной информации
-	createdProduct, err := h.repo.GetProductByID(c.Request.Context(), productID, "ru")
+	createdProduct, err := h.repo.GetProductByID(c.Request.Context(), productID, h.languages.Default())
 	if err != nil {
 		h.logger.WithError(err).Errorf("Ошибка при получении созданного товара ID=%d", productID)
 		// Продолжаем выполнение, так как товар уже создан
@@ -227,6 +389,66 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 	c.JSON(http.StatusCreated, models.NewSuccessResponse(response))
 }
 
+// SetProductStock обработчик для изменения остатка товара без вариантов
+// (остатки вариантов задаются вместе с вариантами)
+func (h *ProductHandler) SetProductStock(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID товара"))
+		return
+	}
+
+	var request models.StockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на изменение остатка")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+	if request.Stock != nil && *request.Stock < 0 {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Остаток не может быть отрицательным"))
+		return
+	}
+
+	if err := h.repo.SetProductStock(c.Request.Context(), id, request.Stock); err != nil {
+		if errors.Is(err, storage.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Товар не найден"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при изменении остатка"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{"id": id, "stock": request.Stock}))
+}
+
+// SetProductOrderSettings обработчик для изменения публикации товара, минимального количества для заказа
+// и вида изготовления по расчету стоимости
+func (h *ProductHandler) SetProductOrderSettings(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID товара"))
+		return
+	}
+
+	var request models.ProductOrderSettings
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на изменение настроек заказа товара")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	if err := h.repo.SetProductOrderSettings(c.Request.Context(), id, request); err != nil {
+		if errors.Is(err, storage.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Товар не найден"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при изменении настроек заказа товара"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(request))
+}
+
 // UpdateProduct обработчик для обновления существующего товара
 func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	// Получаем ID товара из URL
@@ -238,8 +460,8 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	}
 
 	// Проверяем существование товара в базе
-	language := c.DefaultQuery("language", "ru") // Получаем язык из запроса
-	_, err = h.repo.GetProductByID(c.Request.Context(), id, language)
+	language := i18n.FromContext(c) // Получаем язык запроса
+	currentProduct, err := h.repo.GetProductByID(c.Request.Context(), id, language)
 	if err != nil {
 		h.logger.WithError(err).Errorf("Товар с ID=%d не найден", id)
 		c.JSON(http.StatusNotFound, models.NewErrorResponse("Товар не найден"))
@@ -254,11 +476,25 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 
+	// Переводы допускаются только для зарегистрированных языков
+	for lang := range request.Translations {
+		if !h.languages.IsRegistered(lang) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Неподдерживаемый язык перевода: "+lang))
+			return
+		}
+	}
+
+	if message := validatePersonalizationSchema(request.Personalization, h.languages); message != "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(message))
+		return
+	}
+
 	// Создаем модель товара для обновления
 	product := &models.Product{
-		ID:           id,
-		Images:       request.Images,
-		Translations: make(map[string]*models.ProductTranslation),
+		ID:              id,
+		Images:          request.Images,
+		Personalization: request.Personalization,
+		Translations:    make(map[string]*models.ProductTranslation),
 	}
 
 	// Устанавливаем CategoryID, если он предоставлен
@@ -271,6 +507,35 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		product.SubcategoryID = request.SubcategoryID
 	}
 
+	// Обновляем базовую цену: пустая валюта отключает пересчет, отсутствующие поля берутся из текущей
+	switch {
+	case request.BaseCurrency != nil && *request.BaseCurrency == "":
+		product.BasePrice = &models.Money{}
+	case request.BasePrice != nil || request.BaseCurrency != nil:
+		var amount int64
+		var baseCurrency string
+		if currentProduct.BasePrice != nil {
+			amount = currentProduct.BasePrice.Amount
+			baseCurrency = currentProduct.BasePrice.Currency
+		} else if request.BasePrice == nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Не указана базовая цена"))
+			return
+		}
+		if request.BasePrice != nil {
+			amount = request.BasePrice.Amount
+		}
+		if request.BaseCurrency != nil {
+			baseCurrency = *request.BaseCurrency
+		}
+
+		price, message := h.validateBasePrice(amount, baseCurrency)
+		if message != "" {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(message))
+			return
+		}
+		product.BasePrice = &price
+	}
+
 	// Преобразуем переводы, если они есть
 	for lang, translation := range request.Translations {
 		// Создаем перевод с значениями по умолчанию
@@ -287,14 +552,18 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 			productTranslation.Description = *translation.Description
 		}
 
-		if translation.Price != nil {
-			productTranslation.Price = *translation.Price
-		}
-
 		if translation.Currency != nil {
 			productTranslation.Currency = *translation.Currency
 		}
 
+		if translation.Price != nil {
+			if translation.Price.IsNegative() {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse("Цена не может быть отрицательной"))
+				return
+			}
+			productTranslation.Price = models.NewMoney(translation.Price.Amount, productTranslation.Currency)
+		}
+
 		// Добавляем перевод в карту переводов
 		product.Translations[lang] = productTranslation
 	}
@@ -307,6 +576,11 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 
+	// Пересчитываем цены переводов, если товар использует базовую цену
+	if _, err := h.converter.RepriceProducts(c.Request.Context(), id); err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при пересчете цен товара ID=%d", id)
+	}
+
 	// Получаем обновленный товар для возврата
 	updatedProduct, err := h.repo.GetProductByID(c.Request.Context(), id, language)
 	if err != nil {
@@ -326,3 +600,18 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.NewSuccessResponse(response))
 }
+
+// validateBasePrice проверяет базовую цену товара и возвращает ее или текст ошибки
+func (h *ProductHandler) validateBasePrice(amount int64, baseCurrency string) (models.Money, string) {
+	baseCurrency = strings.ToUpper(baseCurrency)
+	if amount < 0 {
+		return models.Money{}, "Цена не может быть отрицательной"
+	}
+	if baseCurrency == "" {
+		return models.Money{}, "Не указана валюта базовой цены"
+	}
+	if !h.converter.IsSupported(baseCurrency) {
+		return models.Money{}, fmt.Sprintf("Нет курса для валюты %s", baseCurrency)
+	}
+	return models.NewMoney(amount, baseCurrency), ""
+}
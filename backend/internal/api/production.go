@@ -0,0 +1,452 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/production"
+	"pryanik_studio/internal/storage"
+)
+
+const (
+	// dateLayout формат дат в запросах (срок изготовления, период плана)
+	dateLayout = "2006-01-02"
+
+	// maxTimelineDays максимальная длина периода плана производства
+	maxTimelineDays = 92
+)
+
+// ProductionHandler обработчик запросов для планирования производства
+type ProductionHandler struct {
+	repo      storage.ProductionRepository
+	orders    storage.OrderRepository
+	estimates storage.EstimateRepository
+	config    config.ProductionConfig
+	logger    *logrus.Logger
+}
+
+// NewProductionHandler создает новый экземпляр ProductionHandler
+func NewProductionHandler(
+	repo storage.ProductionRepository,
+	orders storage.OrderRepository,
+	estimates storage.EstimateRepository,
+	config config.ProductionConfig,
+	logger *logrus.Logger,
+) *ProductionHandler {
+	return &ProductionHandler{
+		repo:      repo,
+		orders:    orders,
+		estimates: estimates,
+		config:    config,
+		logger:    logger,
+	}
+}
+
+// GetMachines обработчик для получения станков мастерской
+func (h *ProductionHandler) GetMachines(c *gin.Context) {
+	machines, err := h.repo.GetMachines(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении станков"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(machines))
+}
+
+// SaveMachine обработчик для создания или изменения станка; после изменения план пересчитывается
+func (h *ProductionHandler) SaveMachine(c *gin.Context) {
+	code := c.Param("code")
+	if !optionCodePattern.MatchString(code) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный код станка"))
+		return
+	}
+
+	var request models.MachineRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на сохранение станка")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	capabilities := make([]string, 0, len(request.Capabilities))
+	for _, capability := range request.Capabilities {
+		if !optionCodePattern.MatchString(capability) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный вид работ: "+capability))
+			return
+		}
+		if !containsString(capabilities, capability) {
+			capabilities = append(capabilities, capability)
+		}
+	}
+
+	machine := models.Machine{
+		Code:         code,
+		Name:         strings.TrimSpace(request.Name),
+		Capabilities: capabilities,
+		Enabled:      request.Enabled == nil || *request.Enabled,
+	}
+
+	if err := h.repo.SaveMachine(c.Request.Context(), &machine); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении станка"))
+		return
+	}
+
+	h.reschedule(c.Request.Context())
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(machine))
+}
+
+// SetProductionSettings обработчик для изменения параметров изготовления товара каталога
+func (h *ProductionHandler) SetProductionSettings(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID товара"))
+		return
+	}
+
+	var request models.ProductionSettingsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на изменение параметров изготовления")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+	if request.Kind != "" && !optionCodePattern.MatchString(request.Kind) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный вид работ"))
+		return
+	}
+
+	settings := models.ProductionSettings{Kind: request.Kind, Minutes: request.Minutes}
+	if err := h.repo.SetProductionSettings(c.Request.Context(), id, settings); err != nil {
+		if errors.Is(err, storage.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Товар не найден"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при изменении параметров изготовления"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(settings))
+}
+
+// CreateOrderJobs обработчик для создания производственных заданий по позициям заказа.
+// Длительность оценивается по прикрепленному расчету стоимости или по параметрам изготовления
+// товара; позиции без них (товары со склада) пропускаются. Новые задания сразу планируются
+func (h *ProductionHandler) CreateOrderJobs(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID заказа"))
+		return
+	}
+
+	var request models.CreateJobsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на создание заданий")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	var dueDate *time.Time
+	if request.DueDate != "" {
+		date, err := time.Parse(dateLayout, request.DueDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный срок изготовления"))
+			return
+		}
+		dueDate = &date
+	}
+
+	ctx := c.Request.Context()
+	order, err := h.orders.GetOrderByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrOrderNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Заказ не найден"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении заказа"))
+		return
+	}
+	if order.Status == models.OrderStatusCancelled || order.Status == models.OrderStatusCompleted {
+		c.JSON(http.StatusConflict, models.NewErrorResponse("Заказ уже закрыт"))
+		return
+	}
+
+	productIDs := make([]int64, 0, len(order.Items))
+	for _, item := range order.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	settings, err := h.repo.GetProductionSettings(ctx, productIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении параметров изготовления"))
+		return
+	}
+
+	response := models.CreateJobsResponse{SkippedItems: []int64{}}
+	var jobs []models.ProductionJob
+	for _, item := range order.Items {
+		job := models.ProductionJob{
+			OrderID:     order.ID,
+			OrderItemID: item.ID,
+			Title:       fmt.Sprintf("Заказ №%d: %s × %d", order.ID, item.DisplayName(), item.Quantity),
+			Quantity:    item.Quantity,
+			Priority:    request.Priority,
+			DueDate:     dueDate,
+		}
+
+		switch itemSettings, ok := settings[item.ProductID]; {
+		case item.EstimateID != nil:
+			estimate, err := h.estimates.GetEstimate(ctx, *item.EstimateID)
+			if err != nil {
+				h.logger.WithError(err).Errorf("Не удалось получить расчет %s позиции ID=%d", *item.EstimateID, item.ID)
+				response.SkippedItems = append(response.SkippedItems, item.ID)
+				continue
+			}
+			job.Kind = estimate.Kind
+			job.EstimatedMinutes = production.EstimateMinutes(estimate, item.Quantity, h.config)
+		case ok:
+			job.Kind = itemSettings.Kind
+			job.EstimatedMinutes = production.ProductMinutes(itemSettings, item.Quantity, h.config)
+		default:
+			response.SkippedItems = append(response.SkippedItems, item.ID)
+			continue
+		}
+
+		// Задание не может быть нулевой длительности
+		if job.EstimatedMinutes < 1 {
+			job.EstimatedMinutes = 1
+		}
+		jobs = append(jobs, job)
+	}
+
+	created, err := h.repo.CreateProductionJobs(ctx, jobs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании заданий"))
+		return
+	}
+	for _, job := range jobs {
+		if !jobCreated(created, job.OrderItemID) {
+			response.SkippedItems = append(response.SkippedItems, job.OrderItemID)
+		}
+	}
+
+	h.reschedule(ctx)
+
+	// Возвращаем задания с рассчитанным планом
+	response.Jobs, err = h.repo.GetProductionJobs(ctx, models.ProductionJobFilter{OrderID: &order.ID, ActiveOnly: true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении заданий"))
+		return
+	}
+	h.markLate(response.Jobs)
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(response))
+}
+
+// GetOrderJobs обработчик для получения всех производственных заданий заказа
+func (h *ProductionHandler) GetOrderJobs(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID заказа"))
+		return
+	}
+
+	jobs, err := h.repo.GetProductionJobs(c.Request.Context(), models.ProductionJobFilter{OrderID: &id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении заданий"))
+		return
+	}
+	h.markLate(jobs)
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(jobs))
+}
+
+// UpdateJob обработчик для изменения производственного задания: статуса, станка, приоритета, срока
+// и длительности. Статус заказа обновляется по его заданиям, план пересчитывается
+func (h *ProductionHandler) UpdateJob(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID задания"))
+		return
+	}
+
+	var request models.JobUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на изменение задания")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	update := models.JobUpdate{
+		Status:           request.Status,
+		MachineID:        request.MachineID,
+		Priority:         request.Priority,
+		EstimatedMinutes: request.EstimatedMinutes,
+	}
+	// Статус scheduled назначает только планировщик
+	if request.Status != nil && (*request.Status == models.JobStatusScheduled || !containsString(models.JobStatuses, *request.Status)) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный статус задания"))
+		return
+	}
+	if request.MachineID != nil && *request.MachineID < 0 {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID станка"))
+		return
+	}
+	if request.DueDate != nil {
+		if *request.DueDate == "" {
+			update.ClearDueDate = true
+		} else {
+			date, err := time.Parse(dateLayout, *request.DueDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный срок изготовления"))
+				return
+			}
+			update.DueDate = &date
+		}
+	}
+
+	job, orderStatus, err := h.repo.UpdateProductionJob(c.Request.Context(), id, update)
+	switch {
+	case errors.Is(err, storage.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorResponse("Задание не найдено"))
+		return
+	case errors.Is(err, storage.ErrMachineNotFound):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Станок не найден"))
+		return
+	case errors.Is(err, storage.ErrJobStatus):
+		c.JSON(http.StatusConflict, models.NewErrorResponse("Отмененное задание нельзя изменить"))
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при изменении задания"))
+		return
+	}
+
+	h.reschedule(c.Request.Context())
+
+	job.Late = production.Late(job, time.Now())
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{"job": job, "order_status": orderStatus}))
+}
+
+// Schedule обработчик для пересчета плана производства
+func (h *ProductionHandler) Schedule(c *gin.Context) {
+	updated, err := h.schedule(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при планировании производства"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{"updated": updated}))
+}
+
+// GetTimeline обработчик для получения плана производства по станкам за период.
+// Параметры from и to задаются датами YYYY-MM-DD; по умолчанию - со вчерашнего дня на две недели вперед
+func (h *ProductionHandler) GetTimeline(c *gin.Context) {
+	today := time.Now().Truncate(24 * time.Hour)
+	from, to := today.AddDate(0, 0, -1), today.AddDate(0, 0, 14)
+
+	if value := c.Query("from"); value != "" {
+		date, err := time.Parse(dateLayout, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректная дата начала периода"))
+			return
+		}
+		from = date
+	}
+	if value := c.Query("to"); value != "" {
+		date, err := time.Parse(dateLayout, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректная дата окончания периода"))
+			return
+		}
+		// Дата окончания входит в период
+		to = date.AddDate(0, 0, 1)
+	}
+	if !to.After(from) || to.Sub(from) > maxTimelineDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(fmt.Sprintf("Период должен быть не длиннее %d дней", maxTimelineDays)))
+		return
+	}
+
+	ctx := c.Request.Context()
+	machines, err := h.repo.GetMachines(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении станков"))
+		return
+	}
+	jobs, err := h.repo.GetProductionJobs(ctx, models.ProductionJobFilter{From: &from, To: &to})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении заданий"))
+		return
+	}
+	h.markLate(jobs)
+
+	timeline := models.ProductionTimeline{
+		From:        from,
+		To:          to,
+		Machines:    make([]models.MachineTimeline, 0, len(machines)),
+		Unscheduled: []models.ProductionJob{},
+	}
+	index := make(map[int64]int, len(machines))
+	for i, machine := range machines {
+		index[machine.ID] = i
+		timeline.Machines = append(timeline.Machines, models.MachineTimeline{Machine: machine, Jobs: []models.ProductionJob{}})
+	}
+	for _, job := range jobs {
+		if job.MachineID != nil {
+			if i, ok := index[*job.MachineID]; ok {
+				timeline.Machines[i].Jobs = append(timeline.Machines[i].Jobs, job)
+				continue
+			}
+		}
+		timeline.Unscheduled = append(timeline.Unscheduled, job)
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(timeline))
+}
+
+// schedule пересчитывает план для незавершенных заданий и возвращает количество измененных
+func (h *ProductionHandler) schedule(ctx context.Context) (int, error) {
+	machines, err := h.repo.GetMachines(ctx)
+	if err != nil {
+		return 0, err
+	}
+	jobs, err := h.repo.GetProductionJobs(ctx, models.ProductionJobFilter{ActiveOnly: true})
+	if err != nil {
+		return 0, err
+	}
+
+	return h.repo.ApplySchedule(ctx, production.Schedule(time.Now(), machines, jobs))
+}
+
+// reschedule пересчитывает план после изменений; ошибка не прерывает запрос,
+// план можно пересчитать отдельным запросом
+func (h *ProductionHandler) reschedule(ctx context.Context) {
+	if _, err := h.schedule(ctx); err != nil {
+		h.logger.WithError(err).Error("Ошибка при пересчете плана производства")
+	}
+}
+
+// markLate отмечает задания, которые не успевают к сроку
+func (h *ProductionHandler) markLate(jobs []models.ProductionJob) {
+	now := time.Now()
+	for i := range jobs {
+		jobs[i].Late = production.Late(jobs[i], now)
+	}
+}
+
+// jobCreated проверяет, создано ли задание по позиции заказа
+func jobCreated(jobs []models.ProductionJob, orderItemID int64) bool {
+	for _, job := range jobs {
+		if job.OrderItemID == orderItemID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/promo"
+	"pryanik_studio/internal/storage"
+)
+
+// promoCodePattern допустимый формат промокода (после приведения к верхнему регистру)
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]{2,49}$`)
+
+// PromoHandler обработчик запросов для управления промокодами
+type PromoHandler struct {
+	repo   storage.PromoRepository
+	logger *logrus.Logger
+}
+
+// NewPromoHandler создает новый экземпляр PromoHandler
+func NewPromoHandler(repo storage.PromoRepository, logger *logrus.Logger) *PromoHandler {
+	return &PromoHandler{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// GetPromoCodes обработчик для получения всех промокодов с количеством использований
+func (h *PromoHandler) GetPromoCodes(c *gin.Context) {
+	promos, err := h.repo.GetPromoCodes(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении промокодов"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(promos))
+}
+
+// SavePromoCode обработчик для создания или изменения промокода
+func (h *PromoHandler) SavePromoCode(c *gin.Context) {
+	code := promo.NormalizeCode(c.Param("code"))
+	if !promoCodePattern.MatchString(code) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный промокод"))
+		return
+	}
+
+	var request models.PromoCodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на сохранение промокода")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	promoCode := models.PromoCode{
+		Code:            code,
+		Description:     strings.TrimSpace(request.Description),
+		Kind:            request.Kind,
+		CategoryIDs:     request.CategoryIDs,
+		StartsAt:        request.StartsAt,
+		EndsAt:          request.EndsAt,
+		MaxUses:         request.MaxUses,
+		MaxUsesPerEmail: request.MaxUsesPerEmail,
+		Enabled:         request.Enabled == nil || *request.Enabled,
+	}
+
+	switch request.Kind {
+	case models.PromoKindPercent:
+		if request.Percent < 1 {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Укажите процент скидки от 1 до 100"))
+			return
+		}
+		promoCode.Percent = request.Percent
+	case models.PromoKindFixed:
+		if len(request.Amounts) == 0 {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Укажите сумму скидки хотя бы в одной валюте"))
+			return
+		}
+	case models.PromoKindFreeItem:
+		if request.FreeProductID == nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Укажите бесплатный товар"))
+			return
+		}
+		promoCode.FreeProductID = request.FreeProductID
+	default:
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный вид промокода"))
+		return
+	}
+
+	var ok bool
+	if request.Kind == models.PromoKindFixed {
+		if promoCode.Amounts, ok = currencyAmounts(c, request.Amounts, false); !ok {
+			return
+		}
+	}
+	if promoCode.MinOrderAmounts, ok = currencyAmounts(c, request.MinOrderAmounts, true); !ok {
+		return
+	}
+	if request.StartsAt != nil && request.EndsAt != nil && !request.EndsAt.After(*request.StartsAt) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Окончание действия должно быть позже начала"))
+		return
+	}
+
+	if err := h.repo.SavePromoCode(c.Request.Context(), &promoCode); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении промокода"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(promoCode))
+}
+
+// currencyAmounts проверяет суммы по валютам: коды валют и знак суммы.
+// При ошибке отправляет ответ клиенту и возвращает false
+func currencyAmounts(c *gin.Context, amounts map[string]models.Money, allowZero bool) (models.CurrencyAmounts, bool) {
+	if len(amounts) == 0 {
+		return nil, true
+	}
+
+	result := make(models.CurrencyAmounts, len(amounts))
+	for code, amount := range amounts {
+		currencyCode := strings.ToUpper(code)
+		if !currencyCodePattern.MatchString(currencyCode) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный код валюты: "+code))
+			return nil, false
+		}
+		if amount.IsNegative() || (!allowZero && amount.IsZero()) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректная сумма в валюте "+currencyCode))
+			return nil, false
+		}
+		result[currencyCode] = models.NewMoney(amount.Amount, currencyCode)
+	}
+	return result, true
+}
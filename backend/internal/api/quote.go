@@ -0,0 +1,385 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/i18n"
+	"pryanik_studio/internal/invoice"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/security"
+	"pryanik_studio/internal/storage"
+	"pryanik_studio/internal/utils"
+)
+
+// Сообщения о принятии предложения на разных языках
+var quoteAcceptedMessages = map[string]string{
+	"ru": "Предложение принято, заказ создан",
+	"en": "Quote accepted, order created",
+	"es": "Presupuesto aceptado, pedido creado",
+}
+
+// QuoteHandler обработчик запросов на расчет изделий не из каталога
+type QuoteHandler struct {
+	repo        storage.QuoteRepository
+	uploads     storage.UploadRepository
+	orders      storage.OrderRepository
+	languages   *i18n.Registry
+	signer      *security.LinkSigner
+	invoices    *invoice.Generator
+	invoicing   config.InvoiceConfig
+	payments    config.PaymentConfig
+	emailSender utils.Sender
+	publicURL   string
+	validDays   int
+	logger      *logrus.Logger
+}
+
+// NewQuoteHandler создает новый экземпляр QuoteHandler
+func NewQuoteHandler(
+	repo storage.QuoteRepository,
+	uploads storage.UploadRepository,
+	orders storage.OrderRepository,
+	languages *i18n.Registry,
+	signer *security.LinkSigner,
+	invoices *invoice.Generator,
+	emailSender utils.Sender,
+	cfg *config.Config,
+	logger *logrus.Logger,
+) *QuoteHandler {
+	return &QuoteHandler{
+		repo:        repo,
+		uploads:     uploads,
+		orders:      orders,
+		languages:   languages,
+		signer:      signer,
+		invoices:    invoices,
+		invoicing:   cfg.Invoice,
+		payments:    cfg.Payment,
+		emailSender: emailSender,
+		publicURL:   cfg.Server.PublicURL,
+		validDays:   cfg.Quote.ValidDays,
+		logger:      logger,
+	}
+}
+
+// CreateQuoteRequest обработчик для создания запроса на расчет.
+// Файлы предварительно загружаются через /api/uploads, в запросе передаются их ID
+func (h *QuoteHandler) CreateQuoteRequest(c *gin.Context) {
+	var request models.QuoteRequestCreateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на расчет")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	// Если язык не указан, используем язык запроса; указанный язык должен быть включен
+	if request.Language == "" {
+		request.Language = i18n.FromContext(c)
+	} else if !h.languages.IsEnabled(request.Language) {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "Language", Message: "Неподдерживаемый язык"},
+		}))
+		return
+	}
+
+	quote := &models.QuoteRequest{
+		Name:        strings.TrimSpace(request.Name),
+		Email:       normalizeEmail(request.Email),
+		Phone:       strings.TrimSpace(request.Phone),
+		Description: strings.TrimSpace(request.Description),
+		Material:    strings.TrimSpace(request.Material),
+		Quantity:    request.Quantity,
+		Language:    request.Language,
+	}
+
+	// Проверяем файлы: они должны существовать и быть моделями или чертежами
+	seen := make(map[string]bool, len(request.Files))
+	for _, id := range request.Files {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if !uploadIDPattern.MatchString(id) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Файл не найден"))
+			return
+		}
+		upload, err := h.uploads.GetUpload(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, storage.ErrUploadNotFound) {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse("Файл не найден"))
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании запроса на расчет"))
+			return
+		}
+		if !containsString(quoteContentTypes, upload.ContentType) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Допускаются только файлы STL, 3MF, SVG и DXF: "+upload.FileName))
+			return
+		}
+		quote.Files = append(quote.Files, upload)
+	}
+
+	id, err := h.repo.CreateQuoteRequest(c.Request.Context(), quote)
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при создании запроса на расчет")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании запроса на расчет"))
+		return
+	}
+
+	if err := h.emailSender.SendQuoteRequest(quote); err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при отправке уведомления о запросе на расчет ID=%d", id)
+		// Продолжаем выполнение, так как запрос уже сохранен
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(map[string]interface{}{
+		"id":      id,
+		"message": "Запрос на расчет отправлен",
+	}))
+}
+
+// GetQuote обработчик для просмотра предложения клиентом по подписанной ссылке
+func (h *QuoteHandler) GetQuote(c *gin.Context) {
+	id, ok := h.verifyLink(c)
+	if !ok {
+		return
+	}
+
+	quote, err := h.repo.GetQuoteRequest(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, id)
+		return
+	}
+
+	// Клиенту не показываем служебные пути к файлам и контакты, они ему известны
+	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]interface{}{
+		"id":          quote.ID,
+		"description": quote.Description,
+		"material":    quote.Material,
+		"quantity":    quote.Quantity,
+		"status":      quote.Status,
+		"price":       quote.Price,
+		"comment":     quote.AdminComment,
+		"valid_until": quote.ValidUntil,
+		"order_id":    quote.OrderID,
+	}))
+}
+
+// AcceptQuote обработчик для принятия предложения клиентом по подписанной ссылке.
+// Создает обычный заказ на сумму предложения
+func (h *QuoteHandler) AcceptQuote(c *gin.Context) {
+	id, ok := h.verifyLink(c)
+	if !ok {
+		return
+	}
+
+	quote, err := h.repo.GetQuoteRequest(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, id)
+		return
+	}
+	if quote.Price == nil {
+		c.JSON(http.StatusConflict, models.NewErrorResponse(storage.ErrQuoteStatus.Error()))
+		return
+	}
+
+	order := &models.Order{
+		Name:      quote.Name,
+		Email:     normalizeEmail(quote.Email),
+		Phone:     quote.Phone,
+		Comment:   fmt.Sprintf("Заказ по запросу на расчет №%d: %s", quote.ID, quote.Description),
+		Language:  quote.Language,
+		Status:    "new",
+		TotalCost: *quote.Price,
+		Subtotal:  *quote.Price,
+		Discount:  models.NewMoney(0, quote.Price.Currency),
+		Currency:  quote.Price.Currency,
+
+		ShippingCost: models.NewMoney(0, quote.Price.Currency),
+	}
+
+	orderID, err := h.repo.AcceptQuote(c.Request.Context(), id, order)
+	if err != nil {
+		h.respondError(c, err, id)
+		return
+	}
+
+	// Отправляем обычное подтверждение заказа
+	createdOrder, err := h.orders.GetOrderByID(c.Request.Context(), orderID)
+	if err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при получении созданного заказа ID=%d", orderID)
+	} else {
+		var attachments []utils.Attachment
+		if h.invoicing.AttachToConfirmation {
+			attachments = confirmationAttachments(c.Request.Context(), h.invoices, &createdOrder, h.logger)
+		}
+		if err := h.emailSender.SendOrderConfirmation(&createdOrder, attachments...); err != nil {
+			h.logger.WithError(err).Errorf("Ошибка при отправке уведомления о заказе ID=%d", orderID)
+		}
+	}
+
+	response := models.QuoteAcceptResponse{
+		Success: true,
+		OrderID: orderID,
+		Message: h.languages.Localize(quoteAcceptedMessages, quote.Language),
+	}
+
+	// Как и при обычном оформлении, заказ можно оплатить онлайн и скачать по нему счет
+	if h.payments.Provider != "" && !order.TotalCost.IsZero() {
+		response.PaymentLink = orderPaymentLink(h.signer, h.publicURL, orderID, h.payments.LinkValidDays)
+	}
+	if h.invoices != nil {
+		response.InvoiceLink = orderInvoiceLink(h.signer, h.publicURL, orderID, h.invoicing.LinkValidDays)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetQuoteRequests обработчик для получения списка запросов на расчет (для администратора)
+func (h *QuoteHandler) GetQuoteRequests(c *gin.Context) {
+	status := c.Query("status")
+
+	quotes, err := h.repo.GetQuoteRequests(c.Request.Context(), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении запросов на расчет"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(quotes))
+}
+
+// GetQuoteRequest обработчик для получения запроса на расчет с файлами (для администратора)
+func (h *QuoteHandler) GetQuoteRequest(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID запроса"))
+		return
+	}
+
+	quote, err := h.repo.GetQuoteRequest(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, id)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(quote))
+}
+
+// ReplyQuote обработчик для отправки клиенту предложения с ценой (для администратора)
+func (h *QuoteHandler) ReplyQuote(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID запроса"))
+		return
+	}
+
+	var request models.QuoteReplyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON предложения по запросу на расчет")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	currencyCode := strings.ToUpper(request.Currency)
+	if !currencyCodePattern.MatchString(currencyCode) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный код валюты"))
+		return
+	}
+	if request.Price.IsNegative() || request.Price.IsZero() {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Цена должна быть положительной"))
+		return
+	}
+
+	validUntil := time.Now().AddDate(0, 0, h.validDays)
+	if request.ValidUntil != nil {
+		if !request.ValidUntil.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Срок действия предложения должен быть в будущем"))
+			return
+		}
+		validUntil = *request.ValidUntil
+	}
+
+	price := models.NewMoney(request.Price.Amount, currencyCode)
+	comment := strings.TrimSpace(request.Comment)
+
+	if err := h.repo.SetQuote(c.Request.Context(), id, price, comment, validUntil); err != nil {
+		h.respondError(c, err, id)
+		return
+	}
+
+	quote, err := h.repo.GetQuoteRequest(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, id)
+		return
+	}
+
+	link := h.acceptURL(quote.ID, validUntil)
+	if err := h.emailSender.SendQuote(&quote, link); err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при отправке предложения по запросу ID=%d", id)
+		c.JSON(http.StatusBadGateway, models.NewErrorResponse("Предложение сохранено, но письмо клиенту не отправлено"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]interface{}{
+		"message":    "Предложение отправлено клиенту",
+		"quote":      quote,
+		"accept_url": link,
+	}))
+}
+
+// acceptURL возвращает подписанную ссылку на страницу предложения, действующую до окончания его срока
+func (h *QuoteHandler) acceptURL(id int64, validUntil time.Time) string {
+	query := h.signer.Query(quoteResource(id), validUntil)
+	return fmt.Sprintf("%s/quotes/%d?%s", h.publicURL, id, query.Encode())
+}
+
+// verifyLink проверяет подпись ссылки на предложение и возвращает ID запроса
+func (h *QuoteHandler) verifyLink(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID запроса"))
+		return 0, false
+	}
+
+	err = h.signer.Verify(quoteResource(id), c.Query("expires"), c.Query("signature"))
+	switch {
+	case errors.Is(err, security.ErrSignatureExpired):
+		c.JSON(http.StatusGone, models.NewErrorResponse(storage.ErrQuoteExpired.Error()))
+		return 0, false
+	case err != nil:
+		h.logger.Warnf("Недействительная ссылка на предложение ID=%d от %s", id, c.ClientIP())
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(err.Error()))
+		return 0, false
+	}
+
+	return id, true
+}
+
+// respondError преобразует ошибку хранилища в ответ API
+func (h *QuoteHandler) respondError(c *gin.Context, err error, id int64) {
+	switch {
+	case errors.Is(err, storage.ErrQuoteNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(err.Error()))
+	case errors.Is(err, storage.ErrQuoteStatus):
+		c.JSON(http.StatusConflict, models.NewErrorResponse(err.Error()))
+	case errors.Is(err, storage.ErrQuoteExpired):
+		c.JSON(http.StatusGone, models.NewErrorResponse(err.Error()))
+	default:
+		h.logger.WithError(err).Errorf("Ошибка при обработке запроса на расчет ID=%d", id)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при обработке запроса на расчет"))
+	}
+}
+
+// quoteResource возвращает имя ресурса для подписи ссылки на предложение
+func quoteResource(id int64) string {
+	return fmt.Sprintf("quote:%d", id)
+}
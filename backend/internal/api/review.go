@@ -0,0 +1,405 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/i18n"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/security"
+	"pryanik_studio/internal/storage"
+)
+
+// maxReviewsPageSize максимальный размер страницы списка отзывов
+const maxReviewsPageSize = 50
+
+// reviewPhotoContentTypes типы файлов, которые можно прикрепить к отзыву как фотографии
+var reviewPhotoContentTypes = []string{"image/png", "image/jpeg", "image/webp"}
+
+// ReviewHandler обработчик запросов для отзывов о товарах: отзывы покупателей по подписанной
+// ссылке из письма, опубликованные отзывы товара и очередь модерации
+type ReviewHandler struct {
+	repo      storage.ReviewRepository
+	orders    storage.OrderRepository
+	uploads   storage.UploadRepository
+	languages *i18n.Registry
+	signer    *security.LinkSigner
+	config    config.ReviewConfig
+	logger    *logrus.Logger
+}
+
+// NewReviewHandler создает новый экземпляр ReviewHandler
+func NewReviewHandler(
+	repo storage.ReviewRepository,
+	orders storage.OrderRepository,
+	uploads storage.UploadRepository,
+	languages *i18n.Registry,
+	signer *security.LinkSigner,
+	cfg *config.Config,
+	logger *logrus.Logger,
+) *ReviewHandler {
+	return &ReviewHandler{
+		repo:      repo,
+		orders:    orders,
+		uploads:   uploads,
+		languages: languages,
+		signer:    signer,
+		config:    cfg.Review,
+		logger:    logger,
+	}
+}
+
+// GetProductReviews обработчик для получения опубликованных отзывов о товаре
+func (h *ReviewHandler) GetProductReviews(c *gin.Context) {
+	productID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID товара"))
+		return
+	}
+
+	page, pageSize := reviewsPage(c)
+	reviews, err := h.repo.GetProductReviews(c.Request.Context(), productID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении отзывов"))
+		return
+	}
+
+	for i := range reviews.Items {
+		review := &reviews.Items[i]
+		review.Photos = make([]string, 0, len(review.PhotoIDs))
+		for _, uploadID := range review.PhotoIDs {
+			review.Photos = append(review.Photos, fmt.Sprintf("/api/reviews/%d/photos/%s", review.ID, uploadID))
+		}
+		// Заказ и статус опубликованного отзыва покупателям не показываются
+		review.OrderID = 0
+		review.Status = ""
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(reviews))
+}
+
+// GetReviewPhoto обработчик для просмотра фотографии опубликованного отзыва
+func (h *ReviewHandler) GetReviewPhoto(c *gin.Context) {
+	reviewID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID отзыва"))
+		return
+	}
+	uploadID := c.Param("uploadId")
+	if !uploadIDPattern.MatchString(uploadID) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID файла"))
+		return
+	}
+
+	photo, err := h.repo.GetReviewPhoto(c.Request.Context(), reviewID, uploadID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUploadNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Фотография не найдена"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении фотографии"))
+		return
+	}
+
+	c.Header("Content-Type", photo.ContentType)
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.File(photo.Path)
+}
+
+// GetOrderReviews обработчик для получения товаров выполненного заказа, о которых покупатель
+// может оставить отзыв, по подписанной ссылке из письма
+func (h *ReviewHandler) GetOrderReviews(c *gin.Context) {
+	order, ok := h.reviewOrder(c)
+	if !ok {
+		return
+	}
+
+	reviewed, err := h.repo.GetReviewedProducts(c.Request.Context(), order.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении отзывов по заказу"))
+		return
+	}
+	done := make(map[int64]bool, len(reviewed))
+	for _, productID := range reviewed {
+		done[productID] = true
+	}
+
+	result := models.ReviewOrder{
+		OrderID: order.ID,
+		Name:    order.Name,
+		Items:   []models.ReviewOrderItem{},
+	}
+	// Один товар может быть в заказе в нескольких вариантах, отзыв оставляется о товаре
+	seen := make(map[int64]bool, len(order.Items))
+	for _, item := range order.Items {
+		if seen[item.ProductID] {
+			continue
+		}
+		seen[item.ProductID] = true
+		result.Items = append(result.Items, models.ReviewOrderItem{
+			ProductID:    item.ProductID,
+			ProductName:  item.ProductName,
+			ProductImage: item.ProductImage,
+			Reviewed:     done[item.ProductID],
+		})
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(result))
+}
+
+// CreateReview обработчик для создания отзыва о товаре из выполненного заказа по подписанной ссылке
+// из письма. Отзыв публикуется после модерации
+func (h *ReviewHandler) CreateReview(c *gin.Context) {
+	order, ok := h.reviewOrder(c)
+	if !ok {
+		return
+	}
+
+	var request models.ReviewRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на создание отзыва")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	found := false
+	for _, item := range order.Items {
+		if item.ProductID == request.ProductID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "ProductID", Message: "Товара нет в заказе"},
+		}))
+		return
+	}
+
+	if request.Language == "" {
+		request.Language = order.Language
+	} else if !h.languages.IsEnabled(request.Language) {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "Language", Message: "Неподдерживаемый язык"},
+		}))
+		return
+	}
+
+	photos, ok := h.checkPhotos(c, request.Photos)
+	if !ok {
+		return
+	}
+
+	authorName := strings.TrimSpace(request.AuthorName)
+	if authorName == "" {
+		// По умолчанию показывается только имя покупателя из заказа
+		authorName = strings.TrimSpace(order.Name)
+		if fields := strings.Fields(authorName); len(fields) > 0 {
+			authorName = fields[0]
+		}
+	}
+
+	review := &models.Review{
+		ProductID:  request.ProductID,
+		OrderID:    order.ID,
+		AuthorName: authorName,
+		Rating:     request.Rating,
+		Text:       strings.TrimSpace(request.Text),
+		Language:   request.Language,
+		PhotoIDs:   photos,
+	}
+	if err := h.repo.CreateReview(c.Request.Context(), review); err != nil {
+		if errors.Is(err, storage.ErrReviewExists) {
+			c.JSON(http.StatusConflict, models.NewErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении отзыва"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(gin.H{"id": review.ID, "status": review.Status}))
+}
+
+// GetReviews обработчик для получения очереди модерации: отзывы со статусом status (по умолчанию pending)
+func (h *ReviewHandler) GetReviews(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ReviewStatusPending)
+	if status != "" && !containsString(models.ReviewStatuses, status) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный статус отзыва"))
+		return
+	}
+
+	page, pageSize := reviewsPage(c)
+	reviews, err := h.repo.GetReviews(c.Request.Context(), status, i18n.FromContext(c), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении отзывов"))
+		return
+	}
+
+	for i := range reviews.Items {
+		setAdminReviewPhotos(&reviews.Items[i])
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(reviews))
+}
+
+// ModerateReview обработчик для модерации отзыва: одобрение, отклонение и ответ магазина
+func (h *ReviewHandler) ModerateReview(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID отзыва"))
+		return
+	}
+
+	var request models.ReviewModerationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на модерацию отзыва")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+	if request.Status == "" && request.Reply == nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Укажите статус отзыва или ответ"))
+		return
+	}
+	if request.Reply != nil {
+		reply := strings.TrimSpace(*request.Reply)
+		request.Reply = &reply
+	}
+
+	review, err := h.repo.ModerateReview(c.Request.Context(), id, request.Status, request.Reply)
+	if err != nil {
+		if errors.Is(err, storage.ErrReviewNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при модерации отзыва"))
+		return
+	}
+
+	setAdminReviewPhotos(&review)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(review))
+}
+
+// reviewOrder проверяет подписанную ссылку для отзыва и возвращает выполненный заказ.
+// При ошибке отправляет ответ клиенту и возвращает false
+func (h *ReviewHandler) reviewOrder(c *gin.Context) (models.Order, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID заказа"))
+		return models.Order{}, false
+	}
+
+	err = h.signer.Verify(orderReviewResource(id), c.Query("expires"), c.Query("signature"))
+	switch {
+	case errors.Is(err, security.ErrSignatureExpired):
+		c.JSON(http.StatusGone, models.NewErrorResponse(err.Error()))
+		return models.Order{}, false
+	case err != nil:
+		h.logger.Warnf("Недействительная ссылка для отзыва о заказе ID=%d от %s", id, c.ClientIP())
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(err.Error()))
+		return models.Order{}, false
+	}
+
+	order, err := h.orders.GetOrderByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrOrderNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Заказ не найден"))
+			return order, false
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении заказа"))
+		return order, false
+	}
+	if order.Status != models.OrderStatusCompleted {
+		c.JSON(http.StatusConflict, models.NewErrorResponse("Отзыв можно оставить только о выполненном заказе"))
+		return order, false
+	}
+
+	return order, true
+}
+
+// checkPhotos проверяет фотографии к отзыву: не больше заданного количества, без повторов,
+// только загруженные изображения. При ошибке отправляет ответ клиенту и возвращает false
+func (h *ReviewHandler) checkPhotos(c *gin.Context, ids []string) ([]string, bool) {
+	if len(ids) > h.config.MaxPhotos {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "Photos", Message: fmt.Sprintf("К отзыву можно прикрепить не больше %d фотографий", h.config.MaxPhotos)},
+		}))
+		return nil, false
+	}
+
+	photos := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !uploadIDPattern.MatchString(id) || containsString(photos, id) {
+			c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+				{Field: "Photos", Message: "Некорректный ID фотографии"},
+			}))
+			return nil, false
+		}
+
+		upload, err := h.uploads.GetUpload(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, storage.ErrUploadNotFound) {
+				c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+					{Field: "Photos", Message: "Фотография не найдена"},
+				}))
+				return nil, false
+			}
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при проверке фотографий"))
+			return nil, false
+		}
+		if !containsString(reviewPhotoContentTypes, upload.ContentType) {
+			c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+				{Field: "Photos", Message: "Фотография должна быть в формате PNG, JPEG или WebP"},
+			}))
+			return nil, false
+		}
+		photos = append(photos, id)
+	}
+
+	return photos, true
+}
+
+// setAdminReviewPhotos заполняет ссылки на фотографии отзыва для администратора
+// (фотографии неопубликованных отзывов недоступны по публичным ссылкам)
+func setAdminReviewPhotos(review *models.Review) {
+	review.Photos = make([]string, 0, len(review.PhotoIDs))
+	for _, uploadID := range review.PhotoIDs {
+		review.Photos = append(review.Photos, "/api/admin/uploads/"+uploadID)
+	}
+}
+
+// reviewsPage возвращает номер и размер страницы списка отзывов из параметров запроса
+func reviewsPage(c *gin.Context) (int, int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+	if pageSize > maxReviewsPageSize {
+		pageSize = maxReviewsPageSize
+	}
+	return page, pageSize
+}
+
+// orderReviewLink возвращает подписанную ссылку на страницу отзыва о товарах выполненного заказа
+func orderReviewLink(signer *security.LinkSigner, publicURL string, id int64, validDays int) string {
+	expires := time.Now().AddDate(0, 0, validDays)
+	query := signer.Query(orderReviewResource(id), expires)
+	query.Set("order", strconv.FormatInt(id, 10))
+	return fmt.Sprintf("%s/review?%s", publicURL, query.Encode())
+}
+
+// orderReviewResource возвращает имя ресурса для подписи ссылки для отзыва
+func orderReviewResource(id int64) string {
+	return fmt.Sprintf("order-review:%d", id)
+}
@@ -10,6 +10,12 @@ import (
 
 	"pryanik_studio/internal/auth"
 	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/currency"
+	"pryanik_studio/internal/i18n"
+	"pryanik_studio/internal/idempotency"
+	"pryanik_studio/internal/invoice"
+	"pryanik_studio/internal/payment"
+	"pryanik_studio/internal/reminder"
 	"pryanik_studio/internal/security"
 	"pryanik_studio/internal/storage"
 	"pryanik_studio/internal/utils"
@@ -19,6 +25,11 @@ import (
 func SetupRouter(
 	repo storage.Repository,
 	emailSender utils.Sender,
+	paymentProvider payment.Provider,
+	invoices *invoice.Generator,
+	contactNotifier *reminder.ContactNotifier,
+	languages *i18n.Registry,
+	converter *currency.Converter,
 	cfg *config.Config,
 	logger *logrus.Logger,
 ) *gin.Engine {
@@ -28,9 +39,9 @@ func SetupRouter(
 	// Настройка CORS
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.CORS.AllowedOrigins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-CSRF-Token"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Accept-Language", "Authorization", "X-CSRF-Token", idempotency.HeaderName},
+		ExposeHeaders:    []string{"Content-Length", idempotency.ReplayedHeaderName},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -42,11 +53,36 @@ func SetupRouter(
 	// Инициализируем JWT аутентификацию
 	jwtAuth := auth.NewJWTAuth(cfg.Security.JWTSecret, logger)
 
+	// Подпись ссылок, которые отправляются клиентам по email
+	signer := security.NewLinkSigner(cfg.Security.LinkSecret)
+
+	// Повторные отправки заказов и форм с тем же ключом Idempotency-Key не выполняются дважды
+	idempotent := idempotency.NewGuard(repo, cfg.Idempotency, logger).Middleware()
+
 	// Создаем обработчики
 	authHandler := NewAuthHandler(jwtAuth, logger)
-	productHandler := NewProductHandler(repo, logger)
-	galleryHandler := NewGalleryHandler(repo, logger)
-	orderHandler := NewOrderHandler(repo, repo, emailSender, logger)
+	productHandler := NewProductHandler(repo, languages, converter, cfg.Catalog, logger)
+	galleryHandler := NewGalleryHandler(repo, languages, logger)
+	orderHandler := NewOrderHandler(repo, repo, repo, repo, repo, repo, repo, repo, languages, converter, signer, invoices, emailSender, cfg, logger)
+	variantHandler := NewVariantHandler(repo, repo, languages, logger)
+	uploadHandler := NewUploadHandler(repo, cfg.Upload, logger)
+	estimateHandler := NewEstimateHandler(repo, repo, repo, repo, cfg.Upload, cfg.Estimate, logger)
+	quoteHandler := NewQuoteHandler(repo, repo, repo, languages, signer, invoices, emailSender, cfg, logger)
+	translationHandler := NewTranslationHandler(repo, languages, logger)
+	languageHandler := NewLanguageHandler(repo, languages, logger)
+	currencyHandler := NewCurrencyHandler(repo, converter, logger)
+	workshopHandler := NewWorkshopMaterialHandler(repo, logger)
+	productionHandler := NewProductionHandler(repo, repo, repo, cfg.Production, logger)
+	promoHandler := NewPromoHandler(repo, logger)
+	shippingHandler := NewShippingHandler(repo, logger)
+	paymentHandler := NewPaymentHandler(repo, repo, paymentProvider, signer, emailSender, cfg.Payment, logger)
+	invoiceHandler := NewInvoiceHandler(repo, invoices, signer, cfg, logger)
+	cartHandler := NewCartHandler(repo, orderHandler, cfg.Cart, logger)
+	draftHandler := NewCheckoutDraftHandler(repo, repo, languages, signer, cfg, logger)
+	accountHandler := NewAccountHandler(repo, jwtAuth, languages, emailSender, cfg, logger)
+	wishlistHandler := NewWishlistHandler(repo, repo, cfg, logger)
+	reviewHandler := NewReviewHandler(repo, repo, repo, languages, signer, cfg, logger)
+	contactHandler := NewContactHandler(repo, contactNotifier, languages, logger)
 
 	// Группа API
 	api := router.Group("/api")
@@ -57,28 +93,192 @@ func SetupRouter(
 			auth.POST("/login", authHandler.Login)
 		}
 
-		// Публичные эндпоинты (без авторизации)
-		api.GET("/products", productHandler.GetProducts)
-		api.GET("/products/:id", productHandler.GetProductByID)
-		api.GET("/products/:id/related", productHandler.GetRelatedProducts)
-		api.GET("/categories", productHandler.GetCategories)
-		api.GET("/gallery", galleryHandler.GetGalleryItems)
+		// Вебхуки платежного провайдера; подлинность проверяется по подписи
+		api.POST("/payments/webhook/:provider", paymentHandler.Webhook)
+
+		// Публичные эндпоинты (без авторизации), доступны только включенные языки
+		public := api.Group("")
+		public.Use(languages.Middleware(false))
+		{
+			public.GET("/languages", languageHandler.GetLanguages)
+			public.GET("/currencies", currencyHandler.GetCurrencies)
+			public.GET("/products", productHandler.GetProducts)
+			public.GET("/products/:id", productHandler.GetProductByID)
+			public.GET("/products/:id/related", productHandler.GetRelatedProducts)
+			public.GET("/categories", productHandler.GetCategories)
+			public.GET("/gallery", galleryHandler.GetGalleryItems)
+
+			// Публичные формы
+			public.POST("/orders", idempotent, orderHandler.CreateOrder)
+			public.POST("/cart/quote", orderHandler.QuoteCart)
+			public.POST("/contact", idempotent, contactHandler.SubmitContactForm)
+			public.POST("/uploads", uploadHandler.CreateUpload)
+
+			// Корзина покупателя; доступ к корзине - по токену из cookie
+			public.POST("/carts", cartHandler.CreateCart)
+			public.GET("/carts/:id", cartHandler.GetCart)
+			public.POST("/carts/:id/items", cartHandler.AddItem)
+			public.PATCH("/carts/:id/items/:itemId", cartHandler.UpdateItem)
+			public.DELETE("/carts/:id/items/:itemId", cartHandler.DeleteItem)
+			public.POST("/carts/:id/checkout", idempotent, cartHandler.Checkout)
+
+			// Черновики заказов для напоминаний о брошенной форме; отказ от напоминаний - по подписанной ссылке
+			public.POST("/checkout-drafts", draftHandler.CreateDraft)
+			public.GET("/checkout-drafts/:id", draftHandler.GetDraft)
+			public.PUT("/checkout-drafts/:id", draftHandler.UpdateDraft)
+			public.POST("/reminders/unsubscribe", draftHandler.Unsubscribe)
+
+			// Просмотр списка избранного по публичной ссылке
+			public.GET("/wishlists/shared/:shareId", wishlistHandler.GetSharedWishlist)
+
+			// Вход покупателя в личный кабинет по одноразовой ссылке из письма
+			public.POST("/auth/magic-link", accountHandler.RequestLoginLink)
+			public.POST("/auth/magic-link/verify", accountHandler.VerifyLoginLink)
 
-		// Публичные формы
-		api.POST("/orders", orderHandler.CreateOrder)
-		api.POST("/contact", orderHandler.SubmitContactForm)
+			// Доставка
+			public.GET("/shipping-methods", shippingHandler.GetShippingMethods)
+			public.POST("/shipping/options", orderHandler.ShippingOptions)
+
+			// Онлайн-оплата заказа по подписанной ссылке
+			public.POST("/orders/:id/payment", paymentHandler.StartPayment)
+
+			// Счет по заказу по подписанной ссылке
+			public.GET("/orders/:id/invoice", invoiceHandler.GetCustomerInvoice)
+
+			// Отзывы о товарах: опубликованные отзывы и отзывы покупателей выполненных заказов по подписанной ссылке
+			public.GET("/products/:id/reviews", reviewHandler.GetProductReviews)
+			public.GET("/reviews/:id/photos/:uploadId", reviewHandler.GetReviewPhoto)
+			public.GET("/orders/:id/reviews", reviewHandler.GetOrderReviews)
+			public.POST("/orders/:id/reviews", reviewHandler.CreateReview)
+
+			// Запросы на расчет; просмотр и принятие предложения - по подписанной ссылке из письма
+			public.POST("/quotes", quoteHandler.CreateQuoteRequest)
+			public.GET("/quotes/:id", quoteHandler.GetQuote)
+			public.POST("/quotes/:id/accept", quoteHandler.AcceptQuote)
+
+			// Автоматический расчет стоимости изготовления
+			public.GET("/print-materials", estimateHandler.GetPrintMaterials)
+			public.POST("/estimate/3d", estimateHandler.Estimate3D)
+			public.GET("/laser-materials", estimateHandler.GetLaserMaterials)
+			public.POST("/estimate/laser", estimateHandler.EstimateLaser)
+			public.GET("/estimates/:id", estimateHandler.GetEstimate)
+		}
+
+		// Список избранного: без входа - по токену из cookie, после входа - список покупателя
+		wishlist := api.Group("/wishlist")
+		wishlist.Use(jwtAuth.OptionalCustomerMiddleware(), languages.Middleware(false))
+		{
+			wishlist.GET("", wishlistHandler.GetWishlist)
+			wishlist.POST("/items", wishlistHandler.AddItem)
+			wishlist.PATCH("/items/:productId", wishlistHandler.UpdateItem)
+			wishlist.DELETE("/items/:productId", wishlistHandler.DeleteItem)
+			wishlist.POST("/share", wishlistHandler.Share)
+			wishlist.DELETE("/share", wishlistHandler.Unshare)
+		}
 
-		// Админские эндпоинты (требуют авторизации и роли admin)
+		// Личный кабинет покупателя (требует токена покупателя)
+		me := api.Group("/me")
+		me.Use(jwtAuth.CustomerMiddleware(), languages.Middleware(false))
+		{
+			me.GET("", accountHandler.GetProfile)
+			me.PATCH("", accountHandler.UpdateProfile)
+			me.GET("/orders", accountHandler.GetOrders)
+			me.GET("/addresses", accountHandler.GetAddresses)
+			me.POST("/addresses", accountHandler.CreateAddress)
+			me.PUT("/addresses/:id", accountHandler.UpdateAddress)
+			me.DELETE("/addresses/:id", accountHandler.DeleteAddress)
+		}
+
+		// Админские эндпоинты (требуют авторизации и роли admin), доступны и отключенные языки
 		admin := api.Group("/admin")
-		admin.Use(jwtAuth.Middleware(), jwtAuth.RequireAdmin())
+		admin.Use(jwtAuth.Middleware(), jwtAuth.RequireAdmin(), languages.Middleware(true))
 		{
 			// Управление товарами
 			admin.POST("/products", productHandler.CreateProduct)
 			admin.PATCH("/products/:id", productHandler.UpdateProduct)
+			admin.GET("/products/:id/variants", variantHandler.GetProductVariants)
+			admin.PUT("/products/:id/variants", variantHandler.UpdateProductVariants)
+			admin.PUT("/products/:id/stock", productHandler.SetProductStock)
+			admin.PUT("/products/:id/ordering", productHandler.SetProductOrderSettings)
+			admin.GET("/products/:id/bom", workshopHandler.GetProductBOM)
+			admin.PUT("/products/:id/bom", workshopHandler.UpdateProductBOM)
+			admin.PUT("/products/:id/production", productionHandler.SetProductionSettings)
+			admin.PUT("/products/:id/dimensions", shippingHandler.SetProductDimensions)
+
+			// Управление заказами
+			admin.PATCH("/orders/:id/status", orderHandler.UpdateOrderStatus)
+			admin.GET("/orders/:id/jobs", productionHandler.GetOrderJobs)
+			admin.POST("/orders/:id/jobs", productionHandler.CreateOrderJobs)
+			admin.GET("/orders/:id/payments", paymentHandler.GetOrderPayments)
+			admin.POST("/payments/:id/refund", paymentHandler.RefundPayment)
+			admin.GET("/orders/:id/invoice", invoiceHandler.GetInvoice)
+			admin.GET("/orders/:id/invoice-link", invoiceHandler.GetInvoiceLink)
+
+			// Модерация отзывов
+			admin.GET("/reviews", reviewHandler.GetReviews)
+			admin.PATCH("/reviews/:id", reviewHandler.ModerateReview)
+
+			// Производство: станки, задания и план
+			admin.GET("/machines", productionHandler.GetMachines)
+			admin.PUT("/machines/:code", productionHandler.SaveMachine)
+			admin.PATCH("/production/jobs/:id", productionHandler.UpdateJob)
+			admin.POST("/production/schedule", productionHandler.Schedule)
+			admin.GET("/production/timeline", productionHandler.GetTimeline)
 
 			// Управление галереей
 			admin.POST("/gallery", galleryHandler.CreateGalleryItem)
 			admin.DELETE("/gallery/:id", galleryHandler.DeleteGalleryItem)
+
+			// Отчет об отсутствующих переводах
+			admin.GET("/translations/missing", translationHandler.GetMissingTranslations)
+
+			// Управление языками
+			admin.GET("/languages", languageHandler.GetAllLanguages)
+			admin.POST("/languages", languageHandler.CreateLanguage)
+			admin.PATCH("/languages/:code", languageHandler.UpdateLanguage)
+
+			// Файлы персонализации заказов
+			admin.GET("/uploads/:id", uploadHandler.GetUpload)
+
+			// Запросы на расчет
+			admin.GET("/quotes", quoteHandler.GetQuoteRequests)
+			admin.GET("/quotes/:id", quoteHandler.GetQuoteRequest)
+			admin.POST("/quotes/:id/reply", quoteHandler.ReplyQuote)
+
+			// Материалы 3D-печати и лазерной обработки
+			admin.GET("/print-materials", estimateHandler.GetAllPrintMaterials)
+			admin.PUT("/print-materials/:code", estimateHandler.SavePrintMaterial)
+			admin.GET("/laser-materials", estimateHandler.GetAllLaserMaterials)
+			admin.PUT("/laser-materials/:code", estimateHandler.SaveLaserMaterial)
+
+			// Материалы мастерской
+			admin.GET("/workshop-materials", workshopHandler.GetWorkshopMaterials)
+			admin.GET("/workshop-materials/shortages", workshopHandler.GetMaterialShortages)
+			admin.PUT("/workshop-materials/:code", workshopHandler.SaveWorkshopMaterial)
+			admin.POST("/workshop-materials/:id/adjustments", workshopHandler.AdjustWorkshopMaterial)
+			admin.GET("/workshop-materials/:id/movements", workshopHandler.GetMaterialMovements)
+
+			// Промокоды
+			admin.GET("/promo-codes", promoHandler.GetPromoCodes)
+			admin.PUT("/promo-codes/:code", promoHandler.SavePromoCode)
+
+			// Способы доставки
+			admin.GET("/shipping-methods", shippingHandler.GetAllShippingMethods)
+			admin.PUT("/shipping-methods/:code", shippingHandler.SaveShippingMethod)
+
+			// Курсы валют
+			admin.GET("/exchange-rates", currencyHandler.GetExchangeRates)
+			admin.PUT("/exchange-rates/:currency", currencyHandler.SetExchangeRate)
+			admin.POST("/exchange-rates/import", currencyHandler.ImportExchangeRates)
+
+			// Статистика напоминаний о незавершенных заказах
+			admin.GET("/reminders/stats", draftHandler.GetReminderStats)
+
+			// Входящие сообщения с формы обратной связи
+			admin.GET("/contact-messages", contactHandler.GetContactMessages)
+			admin.GET("/contact-messages/:id", contactHandler.GetContactMessage)
+			admin.PATCH("/contact-messages/:id", contactHandler.UpdateContactMessage)
+			admin.POST("/contact-messages/:id/notes", contactHandler.AddContactNote)
 		}
 	}
 
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+)
+
+// ShippingHandler обработчик запросов для способов доставки и веса товаров
+type ShippingHandler struct {
+	repo   storage.ShippingRepository
+	logger *logrus.Logger
+}
+
+// NewShippingHandler создает новый экземпляр ShippingHandler
+func NewShippingHandler(repo storage.ShippingRepository, logger *logrus.Logger) *ShippingHandler {
+	return &ShippingHandler{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// GetShippingMethods обработчик для получения включенных способов доставки (для покупателей)
+func (h *ShippingHandler) GetShippingMethods(c *gin.Context) {
+	h.shippingMethods(c, true)
+}
+
+// GetAllShippingMethods обработчик для получения всех способов доставки, включая отключенные
+func (h *ShippingHandler) GetAllShippingMethods(c *gin.Context) {
+	h.shippingMethods(c, false)
+}
+
+func (h *ShippingHandler) shippingMethods(c *gin.Context, onlyEnabled bool) {
+	methods, err := h.repo.GetShippingMethods(c.Request.Context(), onlyEnabled)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении способов доставки"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(methods))
+}
+
+// SaveShippingMethod обработчик для создания или изменения способа доставки
+func (h *ShippingHandler) SaveShippingMethod(c *gin.Context) {
+	code := c.Param("code")
+	if !optionCodePattern.MatchString(code) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный код способа доставки"))
+		return
+	}
+
+	var request models.ShippingMethodRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на сохранение способа доставки")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+	if !slices.Contains(models.ShippingKinds, request.Kind) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный вид доставки"))
+		return
+	}
+
+	method := models.ShippingMethod{
+		Code:          code,
+		Name:          strings.TrimSpace(request.Name),
+		Kind:          request.Kind,
+		Description:   strings.TrimSpace(request.Description),
+		PickupAddress: strings.TrimSpace(request.PickupAddress),
+		Rules:         models.ShippingRules{},
+		SortOrder:     request.SortOrder,
+		Enabled:       request.Enabled == nil || *request.Enabled,
+	}
+
+	switch {
+	case method.Kind == models.ShippingKindPickup && method.PickupAddress == "":
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Укажите адрес пункта самовывоза"))
+		return
+	case method.Kind != models.ShippingKindPickup && len(request.Rules) == 0:
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Укажите хотя бы одно правило расчета стоимости"))
+		return
+	}
+
+	// Правила самовывоза не используются: он всегда бесплатный
+	if method.Kind != models.ShippingKindPickup {
+		for i, rule := range request.Rules {
+			rule, ok := shippingRule(c, i+1, rule)
+			if !ok {
+				return
+			}
+			method.Rules = append(method.Rules, rule)
+		}
+	}
+
+	if err := h.repo.SaveShippingMethod(c.Request.Context(), &method); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении способа доставки"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(method))
+}
+
+// SetProductDimensions обработчик для изменения веса и габаритов товара в упаковке
+func (h *ShippingHandler) SetProductDimensions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID товара"))
+		return
+	}
+
+	var request models.ProductDimensions
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на изменение веса и габаритов товара")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	if err := h.repo.SetProductDimensions(c.Request.Context(), id, request); err != nil {
+		if errors.Is(err, storage.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Товар не найден"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при изменении веса и габаритов товара"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(request))
+}
+
+// shippingRule проверяет правило расчета стоимости доставки и приводит коды стран к верхнему регистру.
+// При ошибке отправляет ответ клиенту и возвращает false
+func shippingRule(c *gin.Context, number int, rule models.ShippingRule) (models.ShippingRule, bool) {
+	prefix := "Правило " + strconv.Itoa(number) + ": "
+
+	countries := make([]string, 0, len(rule.Countries))
+	for _, country := range rule.Countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if len(country) != 2 {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(prefix+"некорректный код страны"))
+			return rule, false
+		}
+		countries = append(countries, country)
+	}
+	rule.Countries = countries
+
+	if len(rule.BasePrice) == 0 {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(prefix+"укажите стоимость доставки хотя бы в одной валюте"))
+		return rule, false
+	}
+	if rule.MaxWeightGrams < 0 {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(prefix+"некорректный максимальный вес"))
+		return rule, false
+	}
+
+	var ok bool
+	if rule.BasePrice, ok = currencyAmounts(c, rule.BasePrice, true); !ok {
+		return rule, false
+	}
+	if rule.PricePerKg, ok = currencyAmounts(c, rule.PricePerKg, false); !ok {
+		return rule, false
+	}
+	if rule.FreeFrom, ok = currencyAmounts(c, rule.FreeFrom, false); !ok {
+		return rule, false
+	}
+	rule.DeliveryDays = strings.TrimSpace(rule.DeliveryDays)
+
+	return rule, true
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/i18n"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+)
+
+// TranslationHandler обработчик запросов для работы с переводами
+type TranslationHandler struct {
+	repo      storage.TranslationRepository
+	languages *i18n.Registry
+	logger    *logrus.Logger
+}
+
+// NewTranslationHandler создает новый экземпляр TranslationHandler
+func NewTranslationHandler(repo storage.TranslationRepository, languages *i18n.Registry, logger *logrus.Logger) *TranslationHandler {
+	return &TranslationHandler{
+		repo:      repo,
+		languages: languages,
+		logger:    logger,
+	}
+}
+
+// GetMissingTranslations обработчик для получения отчета об отсутствующих переводах.
+// Если язык не указан, отчет строится по всем зарегистрированным языкам
+func (h *TranslationHandler) GetMissingTranslations(c *gin.Context) {
+	var languages []string
+	for _, language := range h.languages.All() {
+		languages = append(languages, language.Code)
+	}
+	if language := c.Query("language"); language != "" {
+		languages = []string{language}
+	}
+
+	reports := make([]models.MissingTranslationsReport, 0, len(languages))
+	for _, language := range languages {
+		report, err := h.repo.GetMissingTranslations(c.Request.Context(), language)
+		if err != nil {
+			h.logger.WithError(err).Errorf("Ошибка при построении отчета о переводах для языка %s", language)
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при построении отчета о переводах"))
+			return
+		}
+		reports = append(reports, report)
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(reports))
+}
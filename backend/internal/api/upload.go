@@ -0,0 +1,232 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+)
+
+// uploadContentTypes типы файлов, которые клиент может загрузить, и их расширения
+var uploadContentTypes = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/webp":      ".webp",
+	"image/svg+xml":   ".svg",
+	"application/pdf": ".pdf",
+	"model/stl":       ".stl",
+	"model/3mf":       ".3mf",
+	"image/vnd.dxf":   ".dxf",
+}
+
+// modelContentTypes форматы 3D-моделей и чертежей, которые не распознаются по содержимому:
+// расширение -> тип файла и типы, которые возвращает http.DetectContentType для таких файлов
+var modelContentTypes = map[string]struct {
+	contentType string
+	detected    []string
+}{
+	".stl": {"model/stl", []string{"application/octet-stream", "text/plain"}},
+	".3mf": {"model/3mf", []string{"application/zip"}},
+	".dxf": {"image/vnd.dxf", []string{"text/plain"}},
+}
+
+// quoteContentTypes типы файлов, которые принимаются в запросе на расчет
+var quoteContentTypes = []string{"model/stl", "model/3mf", "image/svg+xml", "image/vnd.dxf"}
+
+// uploadIDPattern формат ID загруженного файла
+var uploadIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// uploadCleanupInterval интервал удаления неиспользуемых файлов
+const uploadCleanupInterval = time.Hour
+
+// UploadHandler обработчик запросов для загрузки файлов персонализации и моделей для расчета
+type UploadHandler struct {
+	repo   storage.UploadRepository
+	config config.UploadConfig
+	logger *logrus.Logger
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+// NewUploadHandler создает новый экземпляр UploadHandler
+func NewUploadHandler(repo storage.UploadRepository, config config.UploadConfig, logger *logrus.Logger) *UploadHandler {
+	return &UploadHandler{
+		repo:   repo,
+		config: config,
+		logger: logger,
+	}
+}
+
+// CreateUpload обработчик для загрузки файла (логотипа, макета, 3D-модели) перед оформлением
+// заказа или запроса на расчет. Возвращает ID файла, который указывается в запросе
+func (h *UploadHandler) CreateUpload(c *gin.Context) {
+	h.cleanup(c.Request.Context())
+
+	maxSize := int64(h.config.MaxSizeMB) << 20
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Файл не передан"))
+		return
+	}
+	if file.Size > maxSize {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Файл слишком большой"))
+		return
+	}
+
+	opened, err := file.Open()
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при открытии загруженного файла")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при чтении файла"))
+		return
+	}
+	defer opened.Close()
+
+	data, err := io.ReadAll(io.LimitReader(opened, maxSize+1))
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при чтении загруженного файла")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при чтении файла"))
+		return
+	}
+	if int64(len(data)) > maxSize {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Файл слишком большой"))
+		return
+	}
+
+	// Тип определяем по содержимому, а не по заголовку клиента
+	contentType := detectContentType(data, file.Filename)
+	extension, ok := uploadContentTypes[contentType]
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Недопустимый тип файла"))
+		return
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при генерации ID файла")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении файла"))
+		return
+	}
+
+	if err := os.MkdirAll(h.config.Dir, 0o755); err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при создании каталога %s", h.config.Dir)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении файла"))
+		return
+	}
+
+	path := filepath.Join(h.config.Dir, id+extension)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при записи файла %s", path)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении файла"))
+		return
+	}
+
+	upload := models.Upload{
+		ID:          id,
+		FileName:    filepath.Base(file.Filename),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Path:        path,
+	}
+
+	if err := h.repo.CreateUpload(c.Request.Context(), &upload); err != nil {
+		if removeErr := os.Remove(path); removeErr != nil {
+			h.logger.WithError(removeErr).Warnf("Не удалось удалить файл %s", path)
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении файла"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(upload))
+}
+
+// GetUpload обработчик для скачивания загруженного клиентом файла (для администратора)
+func (h *UploadHandler) GetUpload(c *gin.Context) {
+	id := c.Param("id")
+	if !uploadIDPattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID файла"))
+		return
+	}
+
+	upload, err := h.repo.GetUpload(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrUploadNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Файл не найден"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении файла"))
+		return
+	}
+
+	c.Header("Content-Type", upload.ContentType)
+	c.FileAttachment(upload.Path, upload.FileName)
+}
+
+// cleanup удаляет неиспользуемые файлы старше срока хранения не чаще одного раза в uploadCleanupInterval
+func (h *UploadHandler) cleanup(ctx context.Context) {
+	h.mu.Lock()
+	if time.Since(h.lastCleanup) < uploadCleanupInterval {
+		h.mu.Unlock()
+		return
+	}
+	h.lastCleanup = time.Now()
+	h.mu.Unlock()
+
+	deleted, err := h.repo.DeleteExpiredUploads(ctx, time.Duration(h.config.RetentionDays)*24*time.Hour)
+	if err != nil {
+		return
+	}
+	if deleted > 0 {
+		h.logger.Infof("Удалено неиспользуемых файлов: %d", deleted)
+	}
+}
+
+// detectContentType определяет тип файла по содержимому.
+// SVG распознается как XML или текст, поэтому дополнительно проверяется корневой элемент;
+// для 3D-моделей и чертежей содержимое должно соответствовать расширению
+func detectContentType(data []byte, fileName string) string {
+	contentType := http.DetectContentType(data)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+
+	extension := strings.ToLower(filepath.Ext(fileName))
+
+	if (contentType == "text/xml" || contentType == "text/plain") &&
+		extension == ".svg" &&
+		bytes.Contains(data, []byte("<svg")) {
+		return "image/svg+xml"
+	}
+
+	if model, ok := modelContentTypes[extension]; ok && containsString(model.detected, contentType) {
+		return model.contentType
+	}
+
+	return contentType
+}
+
+// newUploadID генерирует случайный ID файла
+func newUploadID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
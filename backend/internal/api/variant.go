@@ -0,0 +1,212 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/i18n"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+)
+
+// optionCodePattern допустимый формат кода опции и значения: size, 30x40, matte-black
+var optionCodePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// VariantHandler обработчик запросов для опций и вариантов товаров
+type VariantHandler struct {
+	repo        storage.VariantRepository
+	productRepo storage.ProductRepository
+	languages   *i18n.Registry
+	logger      *logrus.Logger
+}
+
+// NewVariantHandler создает новый экземпляр VariantHandler
+func NewVariantHandler(
+	repo storage.VariantRepository,
+	productRepo storage.ProductRepository,
+	languages *i18n.Registry,
+	logger *logrus.Logger,
+) *VariantHandler {
+	return &VariantHandler{
+		repo:        repo,
+		productRepo: productRepo,
+		languages:   languages,
+		logger:      logger,
+	}
+}
+
+// GetProductVariants обработчик для получения всех опций и вариантов товара, включая отключенные
+func (h *VariantHandler) GetProductVariants(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID товара"))
+		return
+	}
+
+	options, variants, err := h.repo.GetProductVariants(c.Request.Context(), id, i18n.FromContext(c), false)
+	if err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при получении вариантов товара ID=%d", id)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении вариантов товара"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]interface{}{
+		"options":  options,
+		"variants": variants,
+	}))
+}
+
+// UpdateProductVariants обработчик для замены опций и вариантов товара
+func (h *VariantHandler) UpdateProductVariants(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID товара"))
+		return
+	}
+
+	language := i18n.FromContext(c)
+	if _, err := h.productRepo.GetProductByID(c.Request.Context(), id, language); err != nil {
+		h.logger.WithError(err).Errorf("Товар с ID=%d не найден", id)
+		c.JSON(http.StatusNotFound, models.NewErrorResponse("Товар не найден"))
+		return
+	}
+
+	var request models.ProductVariantsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на обновление вариантов товара")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	if message := h.validateVariants(&request); message != "" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(message))
+		return
+	}
+
+	if err := h.repo.SaveProductVariants(c.Request.Context(), id, request); err != nil {
+		if errors.Is(err, storage.ErrSKUConflict) {
+			c.JSON(http.StatusConflict, models.NewErrorResponse(err.Error()))
+			return
+		}
+		h.logger.WithError(err).Errorf("Ошибка при сохранении вариантов товара ID=%d", id)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении вариантов товара"))
+		return
+	}
+
+	options, variants, err := h.repo.GetProductVariants(c.Request.Context(), id, language, false)
+	if err != nil {
+		h.logger.WithError(err).Errorf("Ошибка при получении вариантов товара ID=%d", id)
+		// Продолжаем выполнение, так как варианты уже сохранены
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(map[string]interface{}{
+		"message":  "Варианты товара успешно обновлены",
+		"options":  options,
+		"variants": variants,
+	}))
+}
+
+// validateVariants проверяет согласованность опций и вариантов, нормализует коды валют
+// и возвращает текст ошибки
+func (h *VariantHandler) validateVariants(request *models.ProductVariantsRequest) string {
+	defaultLanguage := h.languages.Default()
+
+	// Допустимые значения каждой опции
+	allowed := make(map[string]map[string]bool, len(request.Options))
+	for _, option := range request.Options {
+		if !optionCodePattern.MatchString(option.Code) {
+			return "Некорректный код опции: " + option.Code
+		}
+		if _, exists := allowed[option.Code]; exists {
+			return "Опция указана дважды: " + option.Code
+		}
+		if message := h.validateNames(option.Translations, defaultLanguage); message != "" {
+			return message + " (опция " + option.Code + ")"
+		}
+
+		allowed[option.Code] = make(map[string]bool, len(option.Values))
+		for _, value := range option.Values {
+			if !optionCodePattern.MatchString(value.Code) {
+				return "Некорректный код значения: " + value.Code
+			}
+			if allowed[option.Code][value.Code] {
+				return "Значение указано дважды: " + option.Code + "." + value.Code
+			}
+			if message := h.validateNames(value.Translations, defaultLanguage); message != "" {
+				return message + " (значение " + option.Code + "." + value.Code + ")"
+			}
+			allowed[option.Code][value.Code] = true
+		}
+	}
+
+	skus := make(map[string]bool, len(request.Variants))
+	combinations := make(map[string]string, len(request.Variants))
+	for i := range request.Variants {
+		variant := &request.Variants[i]
+
+		if skus[variant.SKU] {
+			return "SKU указан дважды: " + variant.SKU
+		}
+		skus[variant.SKU] = true
+
+		// Вариант должен выбирать ровно одно значение каждой опции
+		if len(variant.Options) != len(allowed) {
+			return "Вариант " + variant.SKU + " должен задавать значение каждой опции"
+		}
+		keys := make([]string, 0, len(variant.Options))
+		for optionCode, valueCode := range variant.Options {
+			if !allowed[optionCode][valueCode] {
+				return "Вариант " + variant.SKU + " ссылается на неизвестное значение " + optionCode + "." + valueCode
+			}
+			keys = append(keys, optionCode+"="+valueCode)
+		}
+		sort.Strings(keys)
+		key := strings.Join(keys, ";")
+		if other, exists := combinations[key]; exists {
+			return "Варианты " + other + " и " + variant.SKU + " совпадают по набору опций"
+		}
+		combinations[key] = variant.SKU
+
+		switch variant.PriceMode {
+		case models.VariantPriceDelta:
+		case models.VariantPriceAbsolute:
+			if variant.Price.IsNegative() {
+				return "Цена варианта " + variant.SKU + " не может быть отрицательной"
+			}
+		default:
+			return "Некорректный способ задания цены варианта " + variant.SKU
+		}
+
+		variant.Currency = strings.ToUpper(variant.Currency)
+		if !currencyCodePattern.MatchString(variant.Currency) {
+			return "Некорректный код валюты варианта " + variant.SKU
+		}
+
+		if variant.Stock != nil && *variant.Stock < 0 {
+			return "Остаток варианта " + variant.SKU + " не может быть отрицательным"
+		}
+	}
+
+	return ""
+}
+
+// validateNames проверяет переводы названия: обязателен язык по умолчанию,
+// допускаются только зарегистрированные языки
+func (h *VariantHandler) validateNames(names map[string]string, defaultLanguage string) string {
+	if strings.TrimSpace(names[defaultLanguage]) == "" {
+		return "Отсутствует название на языке по умолчанию"
+	}
+	for lang := range names {
+		if !h.languages.IsRegistered(lang) {
+			return "Неподдерживаемый язык перевода: " + lang
+		}
+	}
+	return ""
+}
@@ -0,0 +1,466 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/auth"
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/i18n"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+)
+
+// wishlistCookieName cookie с токеном доступа к списку избранного без личного кабинета
+const wishlistCookieName = "wishlist_token"
+
+// WishlistHandler обработчик запросов для списков избранного. Без входа в личный кабинет список
+// создается при добавлении первого товара, доступ к нему дает случайный токен из cookie. Когда
+// покупатель входит в личный кабинет, товары из такого списка переносятся в список покупателя
+type WishlistHandler struct {
+	repo      storage.WishlistRepository
+	products  storage.ProductRepository
+	config    config.WishlistConfig
+	ttl       time.Duration
+	publicURL string
+	logger    *logrus.Logger
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+// NewWishlistHandler создает новый экземпляр WishlistHandler
+func NewWishlistHandler(repo storage.WishlistRepository, products storage.ProductRepository, cfg *config.Config, logger *logrus.Logger) *WishlistHandler {
+	return &WishlistHandler{
+		repo:      repo,
+		products:  products,
+		config:    cfg.Wishlist,
+		ttl:       time.Duration(cfg.Wishlist.TTLDays) * 24 * time.Hour,
+		publicURL: cfg.Server.PublicURL,
+		logger:    logger,
+	}
+}
+
+// GetWishlist обработчик для получения списка избранного с текущими ценами товаров.
+// Если списка еще нет, возвращается пустой список
+func (h *WishlistHandler) GetWishlist(c *gin.Context) {
+	wishlist, ok := h.loadWishlist(c, false)
+	if !ok {
+		return
+	}
+
+	if err := h.fillItems(c.Request.Context(), wishlist.Items, i18n.FromContext(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении списка избранного"))
+		return
+	}
+
+	h.setShareURL(&wishlist)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(wishlist))
+}
+
+// AddItem обработчик для добавления товара в список избранного. Если товар уже есть в списке,
+// изменяется только подписка на снижение цены
+func (h *WishlistHandler) AddItem(c *gin.Context) {
+	var request models.WishlistItemRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+	if !h.checkNotify(c, request.NotifyPriceDrop) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	language := i18n.FromContext(c)
+	product, err := h.products.GetProductByID(ctx, request.ProductID, language)
+	if errors.Is(err, storage.ErrProductNotFound) || (err == nil && !product.Published) {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "ProductID", Message: "Товар не найден"},
+		}))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при проверке товара"))
+		return
+	}
+
+	wishlist, ok := h.loadWishlist(c, true)
+	if !ok {
+		return
+	}
+
+	exists := false
+	for _, item := range wishlist.Items {
+		if item.ProductID == request.ProductID {
+			exists = true
+			break
+		}
+	}
+	if !exists && len(wishlist.Items) >= h.config.MaxItems {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "ProductID", Message: "В списке избранного уже максимальное количество товаров"},
+		}))
+		return
+	}
+
+	if err := h.repo.AddWishlistItem(ctx, wishlist.ID, request.ProductID, language, request.NotifyPriceDrop, h.ttl); err != nil {
+		h.wishlistError(c, err, "Ошибка при добавлении товара в список избранного")
+		return
+	}
+
+	h.respondWishlist(c, wishlist.ID)
+}
+
+// UpdateItem обработчик для включения и отключения уведомления о снижении цены товара из списка
+func (h *WishlistHandler) UpdateItem(c *gin.Context) {
+	productID, ok := wishlistProductID(c)
+	if !ok {
+		return
+	}
+
+	var request models.WishlistItemUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+	if !h.checkNotify(c, request.NotifyPriceDrop) {
+		return
+	}
+
+	wishlist, ok := h.loadWishlist(c, false)
+	if !ok {
+		return
+	}
+	if wishlist.ID == "" {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(storage.ErrWishlistItemNotFound.Error()))
+		return
+	}
+
+	err := h.repo.UpdateWishlistItem(c.Request.Context(), wishlist.ID, productID, i18n.FromContext(c), request.NotifyPriceDrop, h.ttl)
+	if err != nil {
+		h.wishlistError(c, err, "Ошибка при изменении товара в списке избранного")
+		return
+	}
+
+	h.respondWishlist(c, wishlist.ID)
+}
+
+// DeleteItem обработчик для удаления товара из списка избранного
+func (h *WishlistHandler) DeleteItem(c *gin.Context) {
+	productID, ok := wishlistProductID(c)
+	if !ok {
+		return
+	}
+
+	wishlist, ok := h.loadWishlist(c, false)
+	if !ok {
+		return
+	}
+	if wishlist.ID == "" {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(storage.ErrWishlistItemNotFound.Error()))
+		return
+	}
+
+	if err := h.repo.DeleteWishlistItem(c.Request.Context(), wishlist.ID, productID, h.ttl); err != nil {
+		h.wishlistError(c, err, "Ошибка при удалении товара из списка избранного")
+		return
+	}
+
+	h.respondWishlist(c, wishlist.ID)
+}
+
+// Share обработчик для открытия списка избранного по публичной ссылке. Повторный запрос
+// возвращает ту же ссылку
+func (h *WishlistHandler) Share(c *gin.Context) {
+	wishlist, ok := h.loadWishlist(c, false)
+	if !ok {
+		return
+	}
+	if wishlist.ID == "" || len(wishlist.Items) == 0 {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Список избранного пуст"))
+		return
+	}
+
+	if wishlist.ShareID == nil {
+		shareID, err := newUploadID()
+		if err != nil {
+			h.logger.WithError(err).Error("Ошибка при генерации публичной ссылки на список избранного")
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании ссылки на список избранного"))
+			return
+		}
+		if err := h.repo.SetWishlistShareID(c.Request.Context(), wishlist.ID, &shareID); err != nil {
+			h.wishlistError(c, err, "Ошибка при создании ссылки на список избранного")
+			return
+		}
+		wishlist.ShareID = &shareID
+	}
+
+	h.setShareURL(&wishlist)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+		"share_id":  *wishlist.ShareID,
+		"share_url": wishlist.ShareURL,
+	}))
+}
+
+// Unshare обработчик для закрытия публичной ссылки на список избранного; прежняя ссылка перестает работать
+func (h *WishlistHandler) Unshare(c *gin.Context) {
+	wishlist, ok := h.loadWishlist(c, false)
+	if !ok {
+		return
+	}
+
+	if wishlist.ID != "" {
+		if err := h.repo.SetWishlistShareID(c.Request.Context(), wishlist.ID, nil); err != nil {
+			h.wishlistError(c, err, "Ошибка при закрытии ссылки на список избранного")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(nil))
+}
+
+// GetSharedWishlist обработчик для просмотра списка избранного по публичной ссылке.
+// Владелец списка и его подписки не раскрываются
+func (h *WishlistHandler) GetSharedWishlist(c *gin.Context) {
+	shareID := c.Param("shareId")
+	if !uploadIDPattern.MatchString(shareID) {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(storage.ErrWishlistNotFound.Error()))
+		return
+	}
+
+	wishlist, err := h.repo.GetWishlistByShareID(c.Request.Context(), shareID)
+	if err != nil {
+		h.wishlistError(c, err, "Ошибка при получении списка избранного")
+		return
+	}
+
+	if err := h.fillItems(c.Request.Context(), wishlist.Items, i18n.FromContext(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении списка избранного"))
+		return
+	}
+	for i := range wishlist.Items {
+		wishlist.Items[i].NotifyPriceDrop = false
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(models.SharedWishlist{
+		Items:     wishlist.Items,
+		UpdatedAt: wishlist.UpdatedAt,
+	}))
+}
+
+// loadWishlist возвращает список избранного текущего покупателя. Для покупателя с личным кабинетом
+// список создается при первом обращении; если в запросе есть cookie списка без личного кабинета,
+// его товары переносятся в список покупателя. Без личного кабинета список из cookie создается,
+// только если create, иначе возвращается пустой список без ID.
+// При ошибке отправляет ответ клиенту и возвращает false
+func (h *WishlistHandler) loadWishlist(c *gin.Context, create bool) (models.Wishlist, bool) {
+	ctx := c.Request.Context()
+	customerID, _ := auth.CustomerFromContext(c)
+	token, cookieErr := c.Cookie(wishlistCookieName)
+
+	var anonymous *models.Wishlist
+	if cookieErr == nil && token != "" {
+		wishlist, err := h.repo.GetWishlistByToken(ctx, secretHash(token))
+		switch {
+		case err == nil:
+			anonymous = &wishlist
+		case !errors.Is(err, storage.ErrWishlistNotFound):
+			h.wishlistError(c, err, "Ошибка при получении списка избранного")
+			return models.Wishlist{}, false
+		}
+	}
+
+	if customerID == 0 {
+		if anonymous != nil {
+			return *anonymous, true
+		}
+		if !create {
+			return models.Wishlist{Items: []models.WishlistItem{}}, true
+		}
+		return h.createWishlist(c)
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при генерации ID списка избранного")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении списка избранного"))
+		return models.Wishlist{}, false
+	}
+	wishlist, err := h.repo.GetCustomerWishlist(ctx, customerID, id)
+	if err != nil {
+		h.wishlistError(c, err, "Ошибка при получении списка избранного")
+		return wishlist, false
+	}
+
+	if anonymous != nil {
+		if err := h.repo.MergeWishlists(ctx, anonymous.ID, wishlist.ID, h.config.MaxItems); err != nil {
+			h.wishlistError(c, err, "Ошибка при объединении списков избранного")
+			return wishlist, false
+		}
+		h.logger.Infof("Список избранного %s перенесен в список покупателя ID=%d", anonymous.ID, customerID)
+
+		if wishlist, err = h.repo.GetWishlist(ctx, wishlist.ID); err != nil {
+			h.wishlistError(c, err, "Ошибка при получении списка избранного")
+			return wishlist, false
+		}
+	}
+	if cookieErr == nil {
+		h.setCookie(c, "", -1)
+	}
+
+	return wishlist, true
+}
+
+// createWishlist создает список избранного без личного кабинета и сохраняет токен доступа в cookie.
+// При ошибке отправляет ответ клиенту и возвращает false
+func (h *WishlistHandler) createWishlist(c *gin.Context) (models.Wishlist, bool) {
+	ctx := c.Request.Context()
+	h.cleanup(ctx)
+
+	id, err := newUploadID()
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при генерации ID списка избранного")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании списка избранного"))
+		return models.Wishlist{}, false
+	}
+	token, err := newUploadID()
+	if err != nil {
+		h.logger.WithError(err).Error("Ошибка при генерации токена списка избранного")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании списка избранного"))
+		return models.Wishlist{}, false
+	}
+
+	wishlist, err := h.repo.CreateWishlist(ctx, id, secretHash(token), h.ttl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при создании списка избранного"))
+		return wishlist, false
+	}
+
+	h.setCookie(c, token, int(h.ttl/time.Second))
+	return wishlist, true
+}
+
+// checkNotify проверяет, что уведомление о снижении цены включает покупатель с личным кабинетом:
+// уведомления отправляются только на подтвержденный email.
+// При ошибке отправляет ответ клиенту и возвращает false
+func (h *WishlistHandler) checkNotify(c *gin.Context, notify bool) bool {
+	if customerID, _ := auth.CustomerFromContext(c); notify && customerID == 0 {
+		c.JSON(http.StatusBadRequest, models.NewValidationErrorResponse([]models.ValidationError{
+			{Field: "NotifyPriceDrop", Message: "Войдите в личный кабинет, чтобы получать уведомления о снижении цены"},
+		}))
+		return false
+	}
+	return true
+}
+
+// fillItems заполняет название, изображение и текущую цену товаров списка на языке language.
+// Товары, снятые с продажи, отмечаются недоступными
+func (h *WishlistHandler) fillItems(ctx context.Context, items []models.WishlistItem, language string) error {
+	for i := range items {
+		item := &items[i]
+
+		product, err := h.products.GetProductByID(ctx, item.ProductID, language)
+		if errors.Is(err, storage.ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		item.ProductName = product.Name
+		if len(product.Images) > 0 {
+			item.ProductImage = product.Images[0]
+		}
+		price := product.Price
+		item.Price = &price
+		item.Currency = price.Currency
+		item.Available = product.Published
+	}
+	return nil
+}
+
+// respondWishlist отправляет клиенту список избранного после изменения
+func (h *WishlistHandler) respondWishlist(c *gin.Context, id string) {
+	ctx := c.Request.Context()
+	wishlist, err := h.repo.GetWishlist(ctx, id)
+	if err != nil {
+		h.wishlistError(c, err, "Ошибка при получении списка избранного")
+		return
+	}
+
+	if err := h.fillItems(ctx, wishlist.Items, i18n.FromContext(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении списка избранного"))
+		return
+	}
+
+	h.setShareURL(&wishlist)
+	c.JSON(http.StatusOK, models.NewSuccessResponse(wishlist))
+}
+
+// setShareURL заполняет публичную ссылку на список, если он открыт для просмотра
+func (h *WishlistHandler) setShareURL(wishlist *models.Wishlist) {
+	if wishlist.ShareID != nil {
+		wishlist.ShareURL = h.publicURL + "/wishlist/shared/" + *wishlist.ShareID
+	}
+}
+
+// setCookie сохраняет токен списка избранного в cookie на maxAge секунд; отрицательный maxAge удаляет cookie
+func (h *WishlistHandler) setCookie(c *gin.Context, token string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(
+		wishlistCookieName,
+		token,
+		maxAge,
+		"/api/wishlist",
+		"",
+		c.Request.TLS != nil, // Secure только для HTTPS
+		true,
+	)
+}
+
+// wishlistError отправляет клиенту ответ на ошибку хранилища списков избранного
+func (h *WishlistHandler) wishlistError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, storage.ErrWishlistNotFound), errors.Is(err, storage.ErrWishlistItemNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(message))
+	}
+}
+
+// cleanup удаляет истекшие списки избранного не чаще одного раза в час
+func (h *WishlistHandler) cleanup(ctx context.Context) {
+	h.mu.Lock()
+	if time.Since(h.lastCleanup) < time.Hour {
+		h.mu.Unlock()
+		return
+	}
+	h.lastCleanup = time.Now()
+	h.mu.Unlock()
+
+	deleted, err := h.repo.DeleteExpiredWishlists(ctx)
+	if err != nil {
+		return
+	}
+	if deleted > 0 {
+		h.logger.Infof("Удалено истекших списков избранного: %d", deleted)
+	}
+}
+
+// wishlistProductID возвращает ID товара из параметра productId.
+// При ошибке отправляет ответ клиенту и возвращает false
+func wishlistProductID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("productId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID товара"))
+		return 0, false
+	}
+	return id, true
+}
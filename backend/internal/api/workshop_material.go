@@ -0,0 +1,211 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+)
+
+// materialMovementsLimit количество последних движений материала в истории
+const materialMovementsLimit = 200
+
+// WorkshopMaterialHandler обработчик запросов для учета материалов мастерской
+type WorkshopMaterialHandler struct {
+	repo   storage.WorkshopMaterialRepository
+	logger *logrus.Logger
+}
+
+// NewWorkshopMaterialHandler создает новый экземпляр WorkshopMaterialHandler
+func NewWorkshopMaterialHandler(repo storage.WorkshopMaterialRepository, logger *logrus.Logger) *WorkshopMaterialHandler {
+	return &WorkshopMaterialHandler{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// GetWorkshopMaterials обработчик для получения материалов мастерской с остатками
+func (h *WorkshopMaterialHandler) GetWorkshopMaterials(c *gin.Context) {
+	materials, err := h.repo.GetWorkshopMaterials(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении материалов"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(materials))
+}
+
+// SaveWorkshopMaterial обработчик для создания или изменения материала мастерской.
+// Начальный остаток учитывается только при создании материала
+func (h *WorkshopMaterialHandler) SaveWorkshopMaterial(c *gin.Context) {
+	code := c.Param("code")
+	if !optionCodePattern.MatchString(code) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный код материала"))
+		return
+	}
+
+	var request models.WorkshopMaterialRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на сохранение материала")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	material := models.WorkshopMaterial{
+		Code:             code,
+		Name:             strings.TrimSpace(request.Name),
+		Unit:             strings.TrimSpace(request.Unit),
+		ReorderThreshold: request.ReorderThreshold,
+	}
+	if request.Quantity != nil {
+		material.Quantity = *request.Quantity
+	}
+
+	if err := h.repo.SaveWorkshopMaterial(c.Request.Context(), &material); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении материала"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(material))
+}
+
+// AdjustWorkshopMaterial обработчик для ручной корректировки остатка материала
+// (поступление, инвентаризация, брак)
+func (h *WorkshopMaterialHandler) AdjustWorkshopMaterial(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID материала"))
+		return
+	}
+
+	var request models.MaterialAdjustmentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на корректировку материала")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	material, err := h.repo.AdjustWorkshopMaterial(c.Request.Context(), id, request.Quantity, strings.TrimSpace(request.Comment))
+	if err != nil {
+		if errors.Is(err, storage.ErrWorkshopMaterialNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Материал не найден"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при корректировке остатка"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(material))
+}
+
+// GetMaterialMovements обработчик для получения истории движения материала
+func (h *WorkshopMaterialHandler) GetMaterialMovements(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID материала"))
+		return
+	}
+
+	movements, err := h.repo.GetMaterialMovements(c.Request.Context(), id, materialMovementsLimit)
+	if err != nil {
+		if errors.Is(err, storage.ErrWorkshopMaterialNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Материал не найден"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении движений материала"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(movements))
+}
+
+// GetMaterialShortages обработчик для отчета о материалах, которые нужно дозаказать
+func (h *WorkshopMaterialHandler) GetMaterialShortages(c *gin.Context) {
+	shortages, err := h.repo.GetMaterialShortages(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении отчета о нехватке материалов"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(shortages))
+}
+
+// GetProductBOM обработчик для получения спецификации материалов товара
+func (h *WorkshopMaterialHandler) GetProductBOM(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID товара"))
+		return
+	}
+
+	items, err := h.repo.GetBOM(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при получении спецификации"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(items))
+}
+
+// UpdateProductBOM обработчик для замены спецификации материалов товара.
+// Строки без варианта относятся ко всему товару, строки с вариантом заменяют их для этого варианта
+func (h *WorkshopMaterialHandler) UpdateProductBOM(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный ID товара"))
+		return
+	}
+
+	var request models.BOMRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		h.logger.WithError(err).Error("Ошибка при разборе JSON запроса на изменение спецификации")
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse("Некорректный формат запроса"))
+		return
+	}
+
+	type bomKey struct {
+		variantID  int64
+		materialID int64
+	}
+	seen := make(map[bomKey]bool, len(request.Items))
+	items := make([]models.BOMItem, 0, len(request.Items))
+	for _, item := range request.Items {
+		key := bomKey{materialID: item.MaterialID}
+		if item.VariantID != nil {
+			key.variantID = *item.VariantID
+		}
+		if seen[key] {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Материал указан в спецификации повторно"))
+			return
+		}
+		seen[key] = true
+
+		items = append(items, models.BOMItem{
+			VariantID:  item.VariantID,
+			MaterialID: item.MaterialID,
+			Quantity:   item.Quantity,
+		})
+	}
+
+	if err := h.repo.SaveBOM(c.Request.Context(), id, items); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrProductNotFound):
+			c.JSON(http.StatusNotFound, models.NewErrorResponse("Товар не найден"))
+		case errors.Is(err, storage.ErrBOMVariant):
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Вариант не принадлежит товару"))
+		case errors.Is(err, storage.ErrWorkshopMaterialNotFound):
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse("Материал не найден"))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse("Ошибка при сохранении спецификации"))
+		}
+		return
+	}
+
+	h.GetProductBOM(c)
+}
@@ -18,6 +18,15 @@ var (
 	ErrNoToken      = errors.New("токен отсутствует")
 )
 
+// Аудитории токенов: токен администратора не принимается как токен покупателя и наоборот
+const (
+	AdminAudience    = "prianik-studio-admin"
+	CustomerAudience = "prianik-studio-customer"
+)
+
+// tokenIssuer издатель токенов
+const tokenIssuer = "prianik-studio"
+
 // JWTAuth структура для работы с JWT
 type JWTAuth struct {
 	secret []byte
@@ -68,7 +77,8 @@ func (j *JWTAuth) GenerateToken(username, role string) (string, time.Time, error
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "prianik-studio",
+			Issuer:    tokenIssuer,
+			Audience:  jwt.ClaimStrings{AdminAudience},
 		},
 	}
 
@@ -81,14 +91,11 @@ func (j *JWTAuth) GenerateToken(username, role string) (string, time.Time, error
 	return tokenString, expirationTime, nil
 }
 
-// ValidateToken проверяет и парсит JWT токен
+// ValidateToken проверяет и парсит JWT токен администратора
 func (j *JWTAuth) ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return j.secret, nil
-	})
-
+	token, err := j.parse(tokenString, claims, AdminAudience)
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
 			return nil, ErrTokenExpired
@@ -103,6 +110,17 @@ func (j *JWTAuth) ValidateToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
+// parse разбирает токен и проверяет подпись, издателя и аудиторию
+func (j *JWTAuth) parse(tokenString string, claims jwt.Claims, audience string) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return j.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithAudience(audience),
+	)
+}
+
 // ExtractTokenFromHeader извлекает токен из заголовка Authorization
 func (j *JWTAuth) ExtractTokenFromHeader(authHeader string) (string, error) {
 	if authHeader == "" {
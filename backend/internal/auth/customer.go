@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// CustomerClaims claims токена покупателя; ID покупателя хранится в Subject
+type CustomerClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// CustomerID возвращает ID покупателя из токена
+func (c *CustomerClaims) CustomerID() (int64, error) {
+	return strconv.ParseInt(c.Subject, 10, 64)
+}
+
+// GenerateCustomerToken генерирует токен покупателя, действующий ttl
+func (j *JWTAuth) GenerateCustomerToken(customerID int64, email string, ttl time.Duration) (string, time.Time, error) {
+	expirationTime := time.Now().Add(ttl)
+
+	claims := &CustomerClaims{
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(customerID, 10),
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    tokenIssuer,
+			Audience:  jwt.ClaimStrings{CustomerAudience},
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString(j.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expirationTime, nil
+}
+
+// ValidateCustomerToken проверяет и парсит токен покупателя
+func (j *JWTAuth) ValidateCustomerToken(tokenString string) (*CustomerClaims, error) {
+	claims := &CustomerClaims{}
+
+	token, err := j.parse(tokenString, claims, CustomerAudience)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		return nil, ErrInvalidToken
+	}
+
+	if !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	if _, err := claims.CustomerID(); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// CustomerMiddleware проверяет токен покупателя и добавляет в контекст его ID и email
+func (j *JWTAuth) CustomerMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := j.ExtractTokenFromHeader(c.GetHeader("Authorization"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   "Необходимо войти в личный кабинет",
+			})
+			c.Abort()
+			return
+		}
+
+		claims, err := j.ValidateCustomerToken(token)
+		if err != nil {
+			message := "Недействительный токен"
+			if err == ErrTokenExpired {
+				message = "Сеанс истек, войдите снова"
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   message,
+			})
+			c.Abort()
+			return
+		}
+
+		customerID, _ := claims.CustomerID()
+		c.Set("customer_id", customerID)
+		c.Set("customer_email", claims.Email)
+
+		c.Next()
+	}
+}
+
+// OptionalCustomerMiddleware добавляет в контекст ID и email покупателя, если запрос содержит
+// токен покупателя; запросы без токена пропускаются как анонимные. Недействительный токен отклоняется,
+// чтобы клиент знал, что нужно войти снова
+func (j *JWTAuth) OptionalCustomerMiddleware() gin.HandlerFunc {
+	required := j.CustomerMiddleware()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		required(c)
+	}
+}
+
+// CustomerFromContext возвращает ID и email покупателя, добавленные CustomerMiddleware.
+// Для анонимного запроса возвращает нулевой ID
+func CustomerFromContext(c *gin.Context) (int64, string) {
+	return c.GetInt64("customer_id"), c.GetString("customer_email")
+}
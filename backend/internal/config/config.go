@@ -11,18 +11,39 @@ import (
 
 // Config содержит настройки приложения
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	CORS     CORSConfig
-	Email    EmailConfig
-	Security SecurityConfig
-	Logging  LoggingConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	CORS        CORSConfig
+	Email       EmailConfig
+	Security    SecurityConfig
+	Logging     LoggingConfig
+	Catalog     CatalogConfig
+	Currency    CurrencyConfig
+	Upload      UploadConfig
+	Quote       QuoteConfig
+	Estimate    EstimateConfig
+	Inventory   InventoryConfig
+	Production  ProductionConfig
+	Shipping    ShippingConfig
+	Payment     PaymentConfig
+	Invoice     InvoiceConfig
+	Idempotency IdempotencyConfig
+	Order       OrderConfig
+	Cart        CartConfig
+	Reminder    ReminderConfig
+	Account     AccountConfig
+	Wishlist    WishlistConfig
+	Review      ReviewConfig
+	Contact     ContactConfig
 }
 
 // ServerConfig содержит настройки сервера
 type ServerConfig struct {
 	Port string
 	Mode string
+
+	// Публичный адрес сайта, используется в ссылках из писем
+	PublicURL string
 }
 
 // DatabaseConfig содержит настройки базы данных
@@ -47,6 +68,187 @@ type SecurityConfig struct {
 	EnableHTTPS   bool
 	AdminUsername string
 	AdminPassword string
+
+	// Секрет для подписи ссылок, отправляемых клиентам
+	LinkSecret string
+}
+
+// CatalogConfig содержит настройки каталога товаров
+type CatalogConfig struct {
+	// Окно (в днях), за которое считается популярность товаров по заказам
+	PopularityWindowDays int
+}
+
+// CurrencyConfig содержит настройки пересчета цен между валютами
+type CurrencyConfig struct {
+	// Опорная валюта, относительно которой хранятся курсы
+	ReferenceCurrency string
+}
+
+// UploadConfig содержит настройки загрузки файлов клиентами
+type UploadConfig struct {
+	// Каталог, в котором хранятся загруженные файлы
+	Dir string
+	// Максимальный размер файла в мегабайтах
+	MaxSizeMB int
+	// Срок хранения файлов, не использованных в заказах, корзинах, расчетах и отзывах (в днях)
+	RetentionDays int
+}
+
+// QuoteConfig содержит настройки запросов на расчет
+type QuoteConfig struct {
+	// Срок действия предложения по умолчанию (в днях)
+	ValidDays int
+}
+
+// EstimateConfig содержит настройки автоматических расчетов стоимости
+type EstimateConfig struct {
+	// Срок (в днях), в течение которого расчет можно прикрепить к заказу
+	ValidDays int
+}
+
+// InventoryConfig содержит настройки учета остатков
+type InventoryConfig struct {
+	// Остаток, при достижении которого компании отправляется уведомление
+	LowStockThreshold int
+}
+
+// ProductionConfig содержит нормы для оценки длительности производственных заданий
+type ProductionConfig struct {
+	// Скорость лазерной резки, мм/с
+	LaserCutSpeed float64
+	// Производительность лазерной гравировки, мм²/с
+	LaserEngraveRate float64
+	// Время на подготовку станка к заданию, мин
+	SetupMinutes int
+}
+
+// ShippingConfig содержит настройки расчета доставки
+type ShippingConfig struct {
+	// Вес товара в упаковке, если он не задан в карточке товара, г
+	DefaultWeightGrams int
+	// Делитель объемного веса: объем упаковки в мм³ / делитель = вес в граммах
+	VolumetricDivisor int
+}
+
+// PaymentConfig содержит настройки онлайн-оплаты заказов
+type PaymentConfig struct {
+	// Платежный провайдер: "stripe", "fake" (для разработки и тестов); пусто - онлайн-оплата отключена
+	Provider string
+	// Страница сайта, на которую покупатель возвращается после оплаты
+	ReturnURL string
+	// Срок действия ссылки на оплату заказа (в днях)
+	LinkValidDays int
+
+	// Ключи Stripe: секретный ключ API и секрет подписи вебхуков
+	StripeSecretKey     string
+	StripeWebhookSecret string
+
+	// Секрет подписи вебхуков тестового провайдера
+	FakeWebhookSecret string
+}
+
+// InvoiceConfig содержит настройки счетов по заказам
+type InvoiceConfig struct {
+	// Префикс номера счета, например "PS-" для номеров вида PS-2024-00001
+	NumberPrefix string
+	// Шрифты TrueType с кириллицей для PDF (обычный и полужирный)
+	FontPath     string
+	BoldFontPath string
+	// Срок действия ссылки на счет для покупателя (в днях)
+	LinkValidDays int
+	// Прикладывать счет к письму с подтверждением заказа
+	AttachToConfirmation bool
+
+	// Реквизиты продавца
+	CompanyName        string
+	CompanyAddress     string
+	CompanyTaxID       string
+	CompanyKPP         string
+	CompanyOGRN        string
+	CompanyBankName    string
+	CompanyBankAccount string
+	CompanyBIC         string
+	CompanyCorrAccount string
+	CompanyPhone       string
+	// Примечание внизу счета, например «НДС не облагается»
+	Note string
+}
+
+// IdempotencyConfig содержит настройки ключей идемпотентности (заголовок Idempotency-Key)
+type IdempotencyConfig struct {
+	// Время хранения ключа и сохраненного ответа (в часах)
+	KeyTTLHours int
+}
+
+// OrderConfig содержит ограничения состава заказа
+type OrderConfig struct {
+	// Максимальное количество позиций в заказе
+	MaxLines int
+	// Максимальное количество единиц товара в одной позиции
+	MaxItemQuantity int
+}
+
+// CartConfig содержит настройки корзин покупателей
+type CartConfig struct {
+	// Срок действия корзины с последнего изменения (в днях)
+	TTLDays int
+}
+
+// ReminderConfig содержит настройки напоминаний о незавершенных заказах
+type ReminderConfig struct {
+	// Отправлять ли напоминания
+	Enabled bool
+	// Время без изменений черновика, после которого отправляется напоминание (в часах)
+	IdleHours int
+	// Время, после которого по черновику напоминание уже не отправляется (в часах)
+	MaxAgeHours int
+	// Интервал поиска черновиков для напоминаний (в минутах)
+	CheckIntervalMinutes int
+	// Срок хранения черновиков без заказа (в днях)
+	DraftRetentionDays int
+	// Срок действия ссылки для отказа от напоминаний (в днях)
+	UnsubscribeValidDays int
+	// Максимальное количество черновиков на один email за сутки
+	MaxDraftsPerEmail int
+}
+
+// AccountConfig содержит настройки личных кабинетов покупателей
+type AccountConfig struct {
+	// Срок действия ссылки для входа (в минутах)
+	LoginLinkTTLMinutes int
+	// Минимальный интервал между ссылками для входа на один email (в секундах)
+	LoginLinkIntervalSeconds int
+	// Срок действия токена покупателя после входа (в часах)
+	TokenTTLHours int
+}
+
+// WishlistConfig содержит настройки списков избранного
+type WishlistConfig struct {
+	// Срок хранения списка без входа в личный кабинет с последнего изменения (в днях)
+	TTLDays int
+	// Максимальное количество товаров в списке
+	MaxItems int
+	// Отправлять ли уведомления о снижении цен
+	PriceAlertsEnabled bool
+	// Интервал проверки цен товаров из списков (в минутах)
+	PriceCheckIntervalMinutes int
+}
+
+// ReviewConfig содержит настройки отзывов о товарах
+type ReviewConfig struct {
+	// Срок действия ссылки для отзыва, которая отправляется после выполнения заказа (в днях)
+	LinkValidDays int
+	// Максимальное количество фотографий в отзыве
+	MaxPhotos int
+}
+
+// ContactConfig содержит настройки обработки сообщений с формы обратной связи
+type ContactConfig struct {
+	// Интервал повторной отправки уведомлений, которые не удалось отправить (в минутах)
+	NotifyRetryIntervalMinutes int
+	// Количество попыток отправки уведомления о сообщении
+	NotifyMaxAttempts int
 }
 
 // LoggingConfig содержит настройки логирования
@@ -86,6 +288,8 @@ func LoadConfig() (Config, error) {
 		Server: ServerConfig{
 			Port: getEnv("SERVER_PORT", "8080"),
 			Mode: getEnv("GIN_MODE", "debug"),
+
+			PublicURL: strings.TrimRight(getEnv("PUBLIC_URL", "http://localhost:3000"), "/"),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -124,6 +328,115 @@ func LoadConfig() (Config, error) {
 		Logging: LoggingConfig{
 			Level: getEnv("LOG_LEVEL", "info"),
 		},
+		Catalog: CatalogConfig{
+			PopularityWindowDays: getEnvAsPositiveInt("CATALOG_POPULARITY_WINDOW_DAYS", 30),
+		},
+		Currency: CurrencyConfig{
+			ReferenceCurrency: strings.ToUpper(getEnv("EXCHANGE_REFERENCE_CURRENCY", "RUB")),
+		},
+		Upload: UploadConfig{
+			Dir:           getEnv("UPLOAD_DIR", "uploads"),
+			MaxSizeMB:     getEnvAsInt("UPLOAD_MAX_SIZE_MB", 10),
+			RetentionDays: getEnvAsPositiveInt("UPLOAD_RETENTION_DAYS", 7),
+		},
+		Quote: QuoteConfig{
+			ValidDays: getEnvAsInt("QUOTE_VALID_DAYS", 14),
+		},
+		Estimate: EstimateConfig{
+			ValidDays: getEnvAsInt("ESTIMATE_VALID_DAYS", 7),
+		},
+		Inventory: InventoryConfig{
+			LowStockThreshold: getEnvAsInt("LOW_STOCK_THRESHOLD", 3),
+		},
+		Production: ProductionConfig{
+			LaserCutSpeed:    getEnvAsFloat("PRODUCTION_LASER_CUT_SPEED", 15),
+			LaserEngraveRate: getEnvAsFloat("PRODUCTION_LASER_ENGRAVE_RATE", 200),
+			SetupMinutes:     getEnvAsInt("PRODUCTION_SETUP_MINUTES", 10),
+		},
+		Shipping: ShippingConfig{
+			DefaultWeightGrams: getEnvAsInt("SHIPPING_DEFAULT_WEIGHT_GRAMS", 500),
+			VolumetricDivisor:  getEnvAsInt("SHIPPING_VOLUMETRIC_DIVISOR", 5000),
+		},
+		Payment: PaymentConfig{
+			Provider:            getEnv("PAYMENT_PROVIDER", ""),
+			ReturnURL:           getEnv("PAYMENT_RETURN_URL", ""),
+			LinkValidDays:       getEnvAsInt("PAYMENT_LINK_VALID_DAYS", 7),
+			StripeSecretKey:     getEnv("STRIPE_SECRET_KEY", ""),
+			StripeWebhookSecret: getEnv("STRIPE_WEBHOOK_SECRET", ""),
+			FakeWebhookSecret:   getEnv("PAYMENT_FAKE_WEBHOOK_SECRET", ""),
+		},
+		Invoice: InvoiceConfig{
+			NumberPrefix:         getEnv("INVOICE_NUMBER_PREFIX", "PS-"),
+			FontPath:             getEnv("INVOICE_FONT_PATH", "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf"),
+			BoldFontPath:         getEnv("INVOICE_BOLD_FONT_PATH", "/usr/share/fonts/truetype/dejavu/DejaVuSans-Bold.ttf"),
+			LinkValidDays:        getEnvAsInt("INVOICE_LINK_VALID_DAYS", 30),
+			AttachToConfirmation: getEnvAsBool("INVOICE_ATTACH_TO_CONFIRMATION", true),
+			CompanyName:          getEnv("COMPANY_NAME", ""),
+			CompanyAddress:       getEnv("COMPANY_ADDRESS", ""),
+			CompanyTaxID:         getEnv("COMPANY_TAX_ID", ""),
+			CompanyKPP:           getEnv("COMPANY_KPP", ""),
+			CompanyOGRN:          getEnv("COMPANY_OGRN", ""),
+			CompanyBankName:      getEnv("COMPANY_BANK_NAME", ""),
+			CompanyBankAccount:   getEnv("COMPANY_BANK_ACCOUNT", ""),
+			CompanyBIC:           getEnv("COMPANY_BIC", ""),
+			CompanyCorrAccount:   getEnv("COMPANY_CORR_ACCOUNT", ""),
+			CompanyPhone:         getEnv("COMPANY_PHONE", ""),
+			Note:                 getEnv("INVOICE_NOTE", ""),
+		},
+		Idempotency: IdempotencyConfig{
+			KeyTTLHours: getEnvAsPositiveInt("IDEMPOTENCY_KEY_TTL_HOURS", 24),
+		},
+		Order: OrderConfig{
+			MaxLines:        getEnvAsInt("ORDER_MAX_LINES", 50),
+			MaxItemQuantity: getEnvAsInt("ORDER_MAX_ITEM_QUANTITY", 1000),
+		},
+		Cart: CartConfig{
+			TTLDays: getEnvAsInt("CART_TTL_DAYS", 30),
+		},
+		Reminder: ReminderConfig{
+			Enabled:              getEnvAsBool("REMINDER_ENABLED", true),
+			IdleHours:            getEnvAsInt("REMINDER_IDLE_HOURS", 3),
+			MaxAgeHours:          getEnvAsInt("REMINDER_MAX_AGE_HOURS", 72),
+			CheckIntervalMinutes: getEnvAsPositiveInt("REMINDER_CHECK_INTERVAL_MINUTES", 15),
+			DraftRetentionDays:   getEnvAsInt("REMINDER_DRAFT_RETENTION_DAYS", 90),
+			UnsubscribeValidDays: getEnvAsInt("REMINDER_UNSUBSCRIBE_VALID_DAYS", 365),
+			MaxDraftsPerEmail:    getEnvAsInt("REMINDER_MAX_DRAFTS_PER_EMAIL", 5),
+		},
+		Account: AccountConfig{
+			LoginLinkTTLMinutes:      getEnvAsInt("ACCOUNT_LOGIN_LINK_TTL_MINUTES", 15),
+			LoginLinkIntervalSeconds: getEnvAsInt("ACCOUNT_LOGIN_LINK_INTERVAL_SECONDS", 60),
+			TokenTTLHours:            getEnvAsInt("ACCOUNT_TOKEN_TTL_HOURS", 720),
+		},
+		Wishlist: WishlistConfig{
+			TTLDays:                   getEnvAsInt("WISHLIST_TTL_DAYS", 180),
+			MaxItems:                  getEnvAsInt("WISHLIST_MAX_ITEMS", 200),
+			PriceAlertsEnabled:        getEnvAsBool("WISHLIST_PRICE_ALERTS_ENABLED", true),
+			PriceCheckIntervalMinutes: getEnvAsPositiveInt("WISHLIST_PRICE_CHECK_INTERVAL_MINUTES", 60),
+		},
+		Review: ReviewConfig{
+			LinkValidDays: getEnvAsInt("REVIEW_LINK_VALID_DAYS", 90),
+			MaxPhotos:     getEnvAsInt("REVIEW_MAX_PHOTOS", 5),
+		},
+		Contact: ContactConfig{
+			NotifyRetryIntervalMinutes: getEnvAsPositiveInt("CONTACT_NOTIFY_RETRY_INTERVAL_MINUTES", 5),
+			NotifyMaxAttempts:          getEnvAsInt("CONTACT_NOTIFY_MAX_ATTEMPTS", 5),
+		},
+	}
+
+	// Если отдельный секрет для ссылок не задан, используем секрет JWT
+	config.Security.LinkSecret = getEnv("LINK_SIGNING_SECRET", "")
+	if config.Security.LinkSecret == "" {
+		config.Security.LinkSecret = config.Security.JWTSecret
+	}
+
+	// Если страница возврата после оплаты не задана, используем страницу на публичном сайте
+	if config.Payment.ReturnURL == "" {
+		config.Payment.ReturnURL = config.Server.PublicURL + "/payment/return"
+	}
+
+	// Если название продавца для счетов не задано, используем имя отправителя писем
+	if config.Invoice.CompanyName == "" {
+		config.Invoice.CompanyName = config.Email.MailFromName
 	}
 
 	return config, nil
@@ -150,3 +463,30 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvAsPositiveInt возвращает значение переменной окружения, если оно больше нуля,
+// иначе значение по умолчанию. Используется для интервалов и сроков, которые не имеют
+// смысла при нуле: time.NewTicker с неположительным интервалом вызывает панику, а ключ
+// идемпотентности с нулевым сроком создается уже истекшим
+func getEnvAsPositiveInt(key string, defaultValue int) int {
+	if value := getEnvAsInt(key, defaultValue); value > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
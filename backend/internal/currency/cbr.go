@@ -0,0 +1,154 @@
+package currency
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"pryanik_studio/internal/models"
+)
+
+// ErrInvalidRatesFile возвращается, если файл курсов не удалось разобрать
+var ErrInvalidRatesFile = errors.New("некорректный файл курсов")
+
+// cbrCurrency код рубля, в котором ЦБ РФ публикует курсы
+const cbrCurrency = "RUB"
+
+// CBRRate курс одной валюты из ежедневного файла ЦБ РФ
+type CBRRate struct {
+	CharCode string
+	Nominal  int64
+	// Стоимость Nominal единиц валюты в рублях
+	Value *big.Rat
+}
+
+// CBRRates содержимое файла курсов ЦБ РФ (формат XML_daily.asp)
+type CBRRates struct {
+	Date  string
+	Rates []CBRRate
+}
+
+// cbrDocument структура XML-документа ЦБ РФ
+type cbrDocument struct {
+	XMLName xml.Name `xml:"ValCurs"`
+	Date    string   `xml:"Date,attr"`
+	Valutes []struct {
+		CharCode string `xml:"CharCode"`
+		Nominal  string `xml:"Nominal"`
+		Value    string `xml:"Value"`
+	} `xml:"Valute"`
+}
+
+// ParseCBR разбирает XML-файл курсов ЦБ РФ. Файл обычно в кодировке windows-1251,
+// а значения записаны с десятичной запятой
+func ParseCBR(r io.Reader) (CBRRates, error) {
+	var doc cbrDocument
+
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		switch strings.ToLower(charset) {
+		case "windows-1251", "cp1251":
+			return charmap.Windows1251.NewDecoder().Reader(input), nil
+		case "utf-8", "utf8":
+			return input, nil
+		default:
+			return nil, fmt.Errorf("неподдерживаемая кодировка файла курсов: %s", charset)
+		}
+	}
+
+	if err := decoder.Decode(&doc); err != nil {
+		return CBRRates{}, fmt.Errorf("ошибка при разборе файла курсов ЦБ РФ: %w", err)
+	}
+
+	result := CBRRates{Date: doc.Date}
+	for _, valute := range doc.Valutes {
+		code := strings.ToUpper(strings.TrimSpace(valute.CharCode))
+
+		var nominal int64
+		if _, err := fmt.Sscan(strings.TrimSpace(valute.Nominal), &nominal); err != nil || nominal <= 0 {
+			return CBRRates{}, fmt.Errorf("некорректный номинал валюты %s: %q", code, valute.Nominal)
+		}
+
+		value, ok := new(big.Rat).SetString(strings.ReplaceAll(strings.TrimSpace(valute.Value), ",", "."))
+		if !ok || value.Sign() <= 0 {
+			return CBRRates{}, fmt.Errorf("некорректный курс валюты %s: %q", code, valute.Value)
+		}
+
+		result.Rates = append(result.Rates, CBRRate{CharCode: code, Nominal: nominal, Value: value})
+	}
+
+	if len(result.Rates) == 0 {
+		return CBRRates{}, fmt.Errorf("файл курсов ЦБ РФ не содержит валют")
+	}
+
+	return result, nil
+}
+
+// ImportCBR импортирует курсы из файла ЦБ РФ, пересчитывая их к опорной валюте,
+// перезагружает курсы и пересчитывает цены товаров с базовой ценой
+func (c *Converter) ImportCBR(ctx context.Context, r io.Reader) (models.ExchangeRateImportResult, error) {
+	var result models.ExchangeRateImportResult
+
+	parsed, err := ParseCBR(r)
+	if err != nil {
+		return result, fmt.Errorf("%w: %v", ErrInvalidRatesFile, err)
+	}
+	result.Date = parsed.Date
+
+	// Рублевая стоимость одной единицы каждой валюты
+	rubles := map[string]*big.Rat{cbrCurrency: big.NewRat(1, 1)}
+	for _, rate := range parsed.Rates {
+		rubles[rate.CharCode] = new(big.Rat).Quo(rate.Value, new(big.Rat).SetInt64(rate.Nominal))
+	}
+
+	referenceRubles, ok := rubles[c.reference]
+	if !ok {
+		return result, fmt.Errorf("%w: файл не содержит курс опорной валюты %s", ErrUnknownCurrency, c.reference)
+	}
+
+	var rates []models.ExchangeRate
+	for code, value := range rubles {
+		if code == c.reference {
+			continue
+		}
+		rate := new(big.Rat).Quo(value, referenceRubles)
+		if rate.Sign() <= 0 || FormatRate(rate) == "0" {
+			// Курс не помещается в точность хранения
+			result.Skipped = append(result.Skipped, code)
+			continue
+		}
+		rates = append(rates, models.ExchangeRate{
+			Currency: code,
+			Rate:     FormatRate(rate),
+			Source:   models.ExchangeRateSourceCBR,
+		})
+	}
+	sortRates(rates)
+	sort.Strings(result.Skipped)
+
+	if err := c.repo.ImportExchangeRates(ctx, rates); err != nil {
+		return result, err
+	}
+
+	for _, rate := range rates {
+		result.Imported = append(result.Imported, rate.Currency)
+	}
+
+	if err := c.Load(ctx); err != nil {
+		return result, err
+	}
+
+	result.Repriced, err = c.RepriceProducts(ctx, 0)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
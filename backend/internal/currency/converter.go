@@ -0,0 +1,229 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+)
+
+var (
+	// ErrUnknownCurrency возвращается, если для валюты не задан курс
+	ErrUnknownCurrency = errors.New("нет курса для валюты")
+
+	// ErrInvalidVariantPrice возвращается, если цену варианта невозможно рассчитать
+	ErrInvalidVariantPrice = errors.New("некорректная цена варианта")
+)
+
+// Converter хранит в памяти курсы валют и пересчитывает суммы между валютами.
+// Курсы задаются относительно опорной валюты: rate - стоимость одной единицы валюты в опорной
+type Converter struct {
+	repo      storage.ExchangeRateRepository
+	reference string
+	logger    *logrus.Logger
+
+	mu    sync.RWMutex
+	rates map[string]models.ExchangeRate
+	ratio map[string]*big.Rat
+}
+
+// NewConverter создает новый конвертер валют с указанной опорной валютой
+func NewConverter(repo storage.ExchangeRateRepository, reference string, logger *logrus.Logger) *Converter {
+	return &Converter{
+		repo:      repo,
+		reference: reference,
+		logger:    logger,
+		rates:     make(map[string]models.ExchangeRate),
+		ratio:     make(map[string]*big.Rat),
+	}
+}
+
+// Load загружает курсы из хранилища, заменяя текущее содержимое
+func (c *Converter) Load(ctx context.Context) error {
+	rates, err := c.repo.GetExchangeRates(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка при загрузке курсов валют: %w", err)
+	}
+
+	byCode := make(map[string]models.ExchangeRate, len(rates))
+	ratio := make(map[string]*big.Rat, len(rates))
+	for _, rate := range rates {
+		value, ok := new(big.Rat).SetString(rate.Rate)
+		if !ok || value.Sign() <= 0 {
+			c.logger.Warnf("Пропущен некорректный курс %q для валюты %s", rate.Rate, rate.Currency)
+			continue
+		}
+		byCode[rate.Currency] = rate
+		ratio[rate.Currency] = value
+	}
+
+	c.mu.Lock()
+	c.rates = byCode
+	c.ratio = ratio
+	c.mu.Unlock()
+
+	c.logger.WithField("count", len(byCode)).Info("Курсы валют загружены")
+	return nil
+}
+
+// Reference возвращает код опорной валюты
+func (c *Converter) Reference() string {
+	return c.reference
+}
+
+// Rates возвращает загруженные курсы валют
+func (c *Converter) Rates() []models.ExchangeRate {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make([]models.ExchangeRate, 0, len(c.rates))
+	for _, rate := range c.rates {
+		result = append(result, rate)
+	}
+	sortRates(result)
+	return result
+}
+
+// IsSupported проверяет, что в валюту можно пересчитать цену
+func (c *Converter) IsSupported(code string) bool {
+	if code == c.reference {
+		return true
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.ratio[code]
+	return ok
+}
+
+// Convert пересчитывает сумму в указанную валюту по текущим курсам
+// и округляет результат по правилу целевой валюты
+func (c *Converter) Convert(amount models.Money, to string) (models.Money, error) {
+	if amount.Currency == to {
+		return amount, nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	fromRate, err := c.rateFor(amount.Currency)
+	if err != nil {
+		return models.Money{}, err
+	}
+	toRate, err := c.rateFor(to)
+	if err != nil {
+		return models.Money{}, err
+	}
+
+	// Сумма в минимальных единицах целевой валюты: amount * from / to
+	value := new(big.Rat).SetInt64(amount.Amount)
+	value.Mul(value, fromRate)
+	value.Quo(value, toRate)
+
+	rule := c.rates[to]
+	converted, err := Round(value, rule.RoundingStep, rule.RoundingMode)
+	if err != nil {
+		return models.Money{}, err
+	}
+
+	return models.NewMoney(converted, to), nil
+}
+
+// rateFor возвращает курс валюты; для опорной валюты курс равен единице.
+// Вызывается под блокировкой чтения
+func (c *Converter) rateFor(code string) (*big.Rat, error) {
+	if rate, ok := c.ratio[code]; ok {
+		return rate, nil
+	}
+	if code == c.reference {
+		return big.NewRat(1, 1), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownCurrency, code)
+}
+
+// ApplyDisplayCurrency пересчитывает цену товара в валюту, запрошенную клиентом.
+// Источником служит базовая цена товара, если она задана, иначе цена перевода
+func (c *Converter) ApplyDisplayCurrency(product *models.Product, to string) error {
+	if to == "" {
+		return nil
+	}
+
+	source := product.Price
+	if product.BasePrice != nil {
+		source = *product.BasePrice
+	}
+
+	if source.Currency == to && product.Currency == to {
+		return nil
+	}
+
+	converted, err := c.Convert(source, to)
+	if err != nil {
+		return err
+	}
+
+	product.Price = converted
+	product.Currency = to
+	product.PriceConverted = true
+	return nil
+}
+
+// VariantPrice рассчитывает цену варианта в валюте цены товара: надбавка варианта
+// прибавляется к цене товара, собственная цена пересчитывается по курсу
+func (c *Converter) VariantPrice(productPrice models.Money, variant models.ProductVariant) (models.Money, error) {
+	value, err := c.Convert(variant.PriceValue, productPrice.Currency)
+	if err != nil {
+		return models.Money{}, err
+	}
+
+	var price models.Money
+	switch variant.PriceMode {
+	case models.VariantPriceDelta:
+		price, err = productPrice.Add(value)
+		if err != nil {
+			return models.Money{}, err
+		}
+	case models.VariantPriceAbsolute:
+		price = value
+	default:
+		return models.Money{}, fmt.Errorf("%w: неизвестный способ задания цены %q", ErrInvalidVariantPrice, variant.PriceMode)
+	}
+
+	if price.IsNegative() {
+		return models.Money{}, fmt.Errorf("%w: отрицательная цена варианта %s", ErrInvalidVariantPrice, variant.SKU)
+	}
+
+	return price, nil
+}
+
+// RepriceProducts пересчитывает цены переводов товаров с базовой ценой в валюты их языков.
+// Если productID равен 0, пересчитываются все такие товары. Переводы, для валюты которых
+// нет курса, остаются без изменений. Возвращает количество обновленных переводов
+func (c *Converter) RepriceProducts(ctx context.Context, productID int64) (int, error) {
+	prices, err := c.repo.GetDerivedPrices(ctx, productID)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := make([]models.DerivedPrice, 0, len(prices))
+	for _, price := range prices {
+		converted, err := c.Convert(price.BasePrice, price.TargetCurrency)
+		if err != nil {
+			c.logger.WithError(err).Warnf("Не удалось пересчитать цену товара ID=%d для языка %s", price.ProductID, price.Language)
+			continue
+		}
+		price.Price = converted
+		updated = append(updated, price)
+	}
+
+	if err := c.repo.UpdateDerivedPrices(ctx, updated); err != nil {
+		return 0, err
+	}
+
+	return len(updated), nil
+}
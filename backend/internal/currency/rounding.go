@@ -0,0 +1,88 @@
+package currency
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"pryanik_studio/internal/models"
+)
+
+// IsValidRoundingMode проверяет, что режим округления поддерживается
+func IsValidRoundingMode(mode string) bool {
+	switch mode {
+	case models.RoundingHalfUp, models.RoundingUp, models.RoundingDown:
+		return true
+	default:
+		return false
+	}
+}
+
+// Round округляет сумму в минимальных единицах до кратного step по указанному режиму.
+// Пустой режим и нулевой шаг означают округление до минимальной единицы половиной вверх
+func Round(value *big.Rat, step int64, mode string) (int64, error) {
+	if step <= 0 {
+		step = 1
+	}
+	if mode == "" {
+		mode = models.RoundingHalfUp
+	}
+
+	// Количество шагов в сумме: num / den
+	steps := new(big.Rat).Quo(value, new(big.Rat).SetInt64(step))
+	num := new(big.Int).Set(steps.Num())
+	den := steps.Denom()
+
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+
+	switch mode {
+	case models.RoundingDown:
+		// Отбрасываем дробную часть в сторону минус бесконечности
+		if remainder.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		}
+	case models.RoundingUp:
+		// Дробную часть округляем в сторону плюс бесконечности
+		if remainder.Sign() > 0 {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	case models.RoundingHalfUp:
+		// Сравниваем удвоенный остаток с делителем, половина округляется от нуля
+		twice := new(big.Int).Abs(remainder)
+		twice.Lsh(twice, 1)
+		if twice.Cmp(den) >= 0 {
+			if remainder.Sign() < 0 {
+				quotient.Sub(quotient, big.NewInt(1))
+			} else {
+				quotient.Add(quotient, big.NewInt(1))
+			}
+		}
+	default:
+		return 0, fmt.Errorf("неизвестный режим округления: %s", mode)
+	}
+
+	result := new(big.Int).Mul(quotient, big.NewInt(step))
+	if !result.IsInt64() {
+		return 0, fmt.Errorf("сумма вне допустимого диапазона")
+	}
+	return result.Int64(), nil
+}
+
+// FormatRate форматирует курс как десятичную строку без лишних нулей
+func FormatRate(rate *big.Rat) string {
+	text := rate.FloatString(10)
+	for len(text) > 1 && text[len(text)-1] == '0' {
+		text = text[:len(text)-1]
+	}
+	if text[len(text)-1] == '.' {
+		text = text[:len(text)-1]
+	}
+	return text
+}
+
+// sortRates упорядочивает курсы по коду валюты
+func sortRates(rates []models.ExchangeRate) {
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Currency < rates[j].Currency
+	})
+}
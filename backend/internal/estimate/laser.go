@@ -0,0 +1,85 @@
+package estimate
+
+import (
+	"fmt"
+
+	"pryanik_studio/internal/models"
+)
+
+// Laser рассчитывает стоимость лазерной обработки макета на указанном материале.
+// Материал оплачивается по площади габаритов макета, рез - по длине всех контуров,
+// гравировка - по площади внутри замкнутых контуров. Операция определяет, что из этого выполняется
+func Laser(analysis models.VectorAnalysis, material models.LaserMaterial, operation string, quantity int) (models.Estimate, error) {
+	cut := operation == models.LaserOperationCut || operation == models.LaserOperationCutEngrave
+	engrave := operation == models.LaserOperationEngrave || operation == models.LaserOperationCutEngrave
+	if !cut && !engrave {
+		return models.Estimate{}, fmt.Errorf("неизвестная операция лазерной обработки: %s", operation)
+	}
+	if quantity < 1 {
+		quantity = 1
+	}
+
+	result := models.Estimate{
+		Kind:           models.EstimateKindLaser,
+		Material:       material.Code,
+		Quantity:       quantity,
+		VectorAnalysis: &analysis,
+		Operation:      operation,
+		Currency:       material.Currency,
+	}
+
+	if analysis.Unsupported > 0 {
+		result.Warnings = append(result.Warnings,
+			"Текст, изображения и блоки не учитываются в расчете, переведите их в кривые")
+	}
+	if engrave && analysis.EngraveArea == 0 {
+		result.Warnings = append(result.Warnings,
+			"В макете нет замкнутых контуров, площадь гравировки не рассчитана")
+	}
+
+	// Площадь габаритов в см², длина реза в метрах, площадь гравировки в см²
+	sheetArea := analysis.BoundingBox.Size.X * analysis.BoundingBox.Size.Y / 100
+	cutLength := analysis.CutLength / 1000
+	engraveArea := analysis.EngraveArea / 100
+
+	sheetCost := scale(material.SheetPricePerCM2, sheetArea, material.Currency)
+	result.Lines = append(result.Lines, models.EstimateLine{
+		Code:     LineSheet,
+		Label:    "Материал " + material.Name,
+		Quantity: round(sheetArea*float64(quantity), 2),
+		Unit:     "см²",
+		Amount:   sheetCost.Mul(quantity),
+	})
+	unitPrice := sheetCost
+
+	if cut {
+		cutCost := scale(material.CutPricePerMeter, cutLength, material.Currency)
+		result.Lines = append(result.Lines, models.EstimateLine{
+			Code:     LineCut,
+			Label:    "Резка",
+			Quantity: round(cutLength*float64(quantity), 3),
+			Unit:     "м",
+			Amount:   cutCost.Mul(quantity),
+		})
+		unitPrice.Amount += cutCost.Amount
+	}
+
+	if engrave {
+		engraveCost := scale(material.EngravePricePerCM2, engraveArea, material.Currency)
+		result.Lines = append(result.Lines, models.EstimateLine{
+			Code:     LineEngrave,
+			Label:    "Гравировка",
+			Quantity: round(engraveArea*float64(quantity), 2),
+			Unit:     "см²",
+			Amount:   engraveCost.Mul(quantity),
+		})
+		unitPrice.Amount += engraveCost.Amount
+	}
+
+	result.UnitPrice = unitPrice
+	result.Total = unitPrice.Mul(quantity)
+
+	applyMinimumCharge(&result, material.MinimumCharge)
+
+	return result, nil
+}
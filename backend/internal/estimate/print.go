@@ -0,0 +1,112 @@
+package estimate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"pryanik_studio/internal/models"
+)
+
+// ErrInvalidMaterial возвращается, если параметры материала не позволяют рассчитать стоимость
+var ErrInvalidMaterial = errors.New("некорректные параметры материала")
+
+// Коды строк расчета
+const (
+	LineMaterial      = "material"
+	LineMachineTime   = "machine_time"
+	LineMinimumCharge = "minimum_charge"
+	LineSheet         = "sheet"
+	LineCut           = "cut"
+	LineEngrave       = "engrave"
+)
+
+// Print3D рассчитывает стоимость 3D-печати модели из указанного материала.
+// Масса считается по объему и плотности материала, время печати - по объему и скорости печати.
+// Если итог меньше минимальной стоимости заказа, добавляется доплата до минимальной стоимости
+func Print3D(analysis models.MeshAnalysis, material models.PrintMaterial, quantity int) (models.Estimate, error) {
+	if material.Density <= 0 || material.PrintSpeed <= 0 {
+		return models.Estimate{}, fmt.Errorf("%w: %s", ErrInvalidMaterial, material.Code)
+	}
+	if quantity < 1 {
+		quantity = 1
+	}
+
+	volumeCM3 := analysis.Volume / 1000
+	weight := volumeCM3 * material.Density
+	hours := volumeCM3 / material.PrintSpeed
+
+	result := models.Estimate{
+		Kind:        models.EstimateKind3D,
+		Material:    material.Code,
+		Quantity:    quantity,
+		Analysis:    &analysis,
+		WeightGrams: round(weight, 2),
+		PrintHours:  round(hours, 2),
+		Currency:    material.Currency,
+	}
+
+	if !analysis.Manifold {
+		result.Warnings = append(result.Warnings,
+			"Модель не замкнута, объем и стоимость могут быть рассчитаны неточно")
+	}
+
+	materialCost := scale(material.PricePerGram, weight, material.Currency)
+	machineCost := scale(material.MachineHourRate, hours, material.Currency)
+
+	result.UnitPrice = models.NewMoney(materialCost.Amount+machineCost.Amount, material.Currency)
+	result.Lines = []models.EstimateLine{
+		{
+			Code:     LineMaterial,
+			Label:    "Материал " + material.Name,
+			Quantity: round(weight*float64(quantity), 2),
+			Unit:     "г",
+			Amount:   materialCost.Mul(quantity),
+		},
+		{
+			Code:     LineMachineTime,
+			Label:    "Время печати",
+			Quantity: round(hours*float64(quantity), 2),
+			Unit:     "ч",
+			Amount:   machineCost.Mul(quantity),
+		},
+	}
+	result.Total = result.UnitPrice.Mul(quantity)
+
+	applyMinimumCharge(&result, material.MinimumCharge)
+
+	return result, nil
+}
+
+// applyMinimumCharge добавляет доплату до минимальной стоимости заказа.
+// Цена за единицу округляется вверх, чтобы итог оставался равен цене за единицу, умноженной на тираж
+func applyMinimumCharge(result *models.Estimate, minimum models.Money) {
+	if result.Total.Amount >= minimum.Amount {
+		return
+	}
+
+	quantity := int64(result.Quantity)
+	unitPrice := models.NewMoney((minimum.Amount+quantity-1)/quantity, result.Currency)
+	total := unitPrice.Mul(result.Quantity)
+
+	result.Lines = append(result.Lines, models.EstimateLine{
+		Code:     LineMinimumCharge,
+		Label:    "Доплата до минимальной стоимости заказа",
+		Quantity: 1,
+		Amount:   models.NewMoney(total.Amount-result.Total.Amount, result.Currency),
+	})
+	result.UnitPrice = unitPrice
+	result.Total = total
+	result.Warnings = append(result.Warnings, "Применена минимальная стоимость заказа")
+}
+
+// scale умножает цену за единицу на дробное количество с округлением до минимальных единиц валюты
+func scale(price models.Money, quantity float64, currency string) models.Money {
+	return models.NewMoney(int64(math.Round(float64(price.Amount)*quantity)), currency)
+}
+
+// round округляет число до указанного количества знаков после запятой
+func round(value float64, digits int) float64 {
+	factor := math.Pow(10, float64(digits))
+	return math.Round(value*factor) / factor
+}
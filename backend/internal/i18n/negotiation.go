@@ -0,0 +1,131 @@
+package i18n
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"pryanik_studio/internal/models"
+)
+
+// ContextKey ключ, под которым язык запроса сохраняется в контексте Gin
+const ContextKey = "language"
+
+// LanguageRange элемент заголовка Accept-Language
+type LanguageRange struct {
+	Tag     string
+	Quality float64
+}
+
+// ParseAcceptLanguage разбирает заголовок Accept-Language с учетом весов q.
+// Результат отсортирован по убыванию веса, при равных весах сохраняется исходный порядок.
+// Диапазоны с q=0 («неприемлемо») отбрасываются
+func ParseAcceptLanguage(header string) []LanguageRange {
+	var ranges []LanguageRange
+
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		tag := part
+		quality := 1.0
+
+		if idx := strings.Index(part, ";"); idx >= 0 {
+			tag = strings.TrimSpace(part[:idx])
+			for _, param := range strings.Split(part[idx+1:], ";") {
+				param = strings.TrimSpace(param)
+				if !strings.HasPrefix(param, "q=") {
+					continue
+				}
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err != nil || q < 0 || q > 1 {
+					q = 0
+				}
+				quality = q
+			}
+		}
+
+		if tag == "" || quality == 0 {
+			continue
+		}
+
+		ranges = append(ranges, LanguageRange{Tag: strings.ToLower(tag), Quality: quality})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].Quality > ranges[j].Quality
+	})
+
+	return ranges
+}
+
+// Negotiate выбирает наиболее подходящий включенный язык по заголовку Accept-Language.
+// Сначала ищется точное совпадение тега (en-us), затем совпадение основного подтега (en).
+// Если ничего не подошло, возвращается язык по умолчанию
+func (r *Registry) Negotiate(acceptLanguage string) string {
+	enabled := r.EnabledCodes()
+
+	for _, lr := range ParseAcceptLanguage(acceptLanguage) {
+		if lr.Tag == "*" {
+			return r.Default()
+		}
+
+		for _, code := range enabled {
+			if strings.ToLower(code) == lr.Tag {
+				return code
+			}
+		}
+
+		primary := lr.Tag
+		if idx := strings.Index(primary, "-"); idx >= 0 {
+			primary = primary[:idx]
+		}
+		for _, code := range enabled {
+			if strings.ToLower(code) == primary {
+				return code
+			}
+		}
+	}
+
+	return r.Default()
+}
+
+// Middleware определяет язык запроса и сохраняет его в контексте Gin.
+// Явно указанный параметр language имеет приоритет над заголовком Accept-Language
+// и должен быть включенным языком (или зарегистрированным, если allowDisabled).
+// Неизвестный язык отклоняется с кодом 400
+func (r *Registry) Middleware(allowDisabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if language := c.Query("language"); language != "" {
+			known := r.IsEnabled(language)
+			if allowDisabled {
+				known = r.IsRegistered(language)
+			}
+
+			if !known {
+				r.logger.Warnf("Запрос с неподдерживаемым языком %q от %s", language, c.ClientIP())
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse("Неподдерживаемый язык"))
+				c.Abort()
+				return
+			}
+
+			c.Set(ContextKey, language)
+			c.Next()
+			return
+		}
+
+		c.Set(ContextKey, r.Negotiate(c.GetHeader("Accept-Language")))
+		c.Next()
+	}
+}
+
+// FromContext возвращает язык, определенный Middleware.
+// Если middleware не применялся, возвращается пустая строка
+func FromContext(c *gin.Context) string {
+	return c.GetString(ContextKey)
+}
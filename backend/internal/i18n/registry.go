@@ -0,0 +1,199 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+)
+
+// Registry хранит в памяти зарегистрированные языки и предоставляет
+// согласование языка, цепочки отката и валюты по умолчанию
+type Registry struct {
+	repo   storage.LanguageRepository
+	logger *logrus.Logger
+
+	mu          sync.RWMutex
+	languages   map[string]models.Language
+	ordered     []models.Language
+	defaultCode string
+}
+
+// NewRegistry создает новый реестр языков
+func NewRegistry(repo storage.LanguageRepository, logger *logrus.Logger) *Registry {
+	return &Registry{
+		repo:      repo,
+		logger:    logger,
+		languages: make(map[string]models.Language),
+	}
+}
+
+// Load загружает языки из хранилища, заменяя текущее содержимое реестра
+func (r *Registry) Load(ctx context.Context) error {
+	languages, err := r.repo.GetLanguages(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка при загрузке языков: %w", err)
+	}
+
+	byCode := make(map[string]models.Language, len(languages))
+	defaultCode := ""
+	for _, language := range languages {
+		byCode[language.Code] = language
+		if language.IsDefault {
+			defaultCode = language.Code
+		}
+	}
+
+	// Если язык по умолчанию не отмечен, берем первый включенный
+	if defaultCode == "" {
+		for _, language := range languages {
+			if language.Enabled {
+				defaultCode = language.Code
+				break
+			}
+		}
+	}
+
+	if defaultCode == "" {
+		return fmt.Errorf("не найдено ни одного включенного языка")
+	}
+
+	r.mu.Lock()
+	r.languages = byCode
+	r.ordered = languages
+	r.defaultCode = defaultCode
+	r.mu.Unlock()
+
+	r.logger.WithField("count", len(languages)).Info("Реестр языков загружен")
+	return nil
+}
+
+// Default возвращает код языка по умолчанию
+func (r *Registry) Default() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.defaultCode
+}
+
+// Get возвращает язык по коду
+func (r *Registry) Get(code string) (models.Language, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	language, ok := r.languages[code]
+	return language, ok
+}
+
+// IsRegistered проверяет, что язык зарегистрирован (включен или отключен)
+func (r *Registry) IsRegistered(code string) bool {
+	_, ok := r.Get(code)
+	return ok
+}
+
+// IsEnabled проверяет, что язык зарегистрирован и включен
+func (r *Registry) IsEnabled(code string) bool {
+	language, ok := r.Get(code)
+	return ok && language.Enabled
+}
+
+// All возвращает все зарегистрированные языки в порядке сортировки
+func (r *Registry) All() []models.Language {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]models.Language(nil), r.ordered...)
+}
+
+// Enabled возвращает включенные языки в порядке сортировки
+func (r *Registry) Enabled() []models.Language {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]models.Language, 0, len(r.ordered))
+	for _, language := range r.ordered {
+		if language.Enabled {
+			result = append(result, language)
+		}
+	}
+	return result
+}
+
+// EnabledCodes возвращает коды включенных языков
+func (r *Registry) EnabledCodes() []string {
+	enabled := r.Enabled()
+	codes := make([]string, 0, len(enabled))
+	for _, language := range enabled {
+		codes = append(codes, language.Code)
+	}
+	return codes
+}
+
+// FallbackChain возвращает цепочку языков для поиска перевода: запрошенный язык,
+// затем языки отката по полю fallback и в конце язык по умолчанию
+func (r *Registry) FallbackChain(code string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	chain := []string{code}
+	seen := map[string]bool{code: true}
+
+	current, ok := r.languages[code]
+	for ok && current.Fallback != nil && !seen[*current.Fallback] {
+		next := *current.Fallback
+		chain = append(chain, next)
+		seen[next] = true
+		current, ok = r.languages[next]
+	}
+
+	if r.defaultCode != "" && !seen[r.defaultCode] {
+		chain = append(chain, r.defaultCode)
+	}
+
+	return chain
+}
+
+// CurrencyFor возвращает валюту по умолчанию для языка, при неизвестном языке -
+// валюту языка по умолчанию
+func (r *Registry) CurrencyFor(code string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if language, ok := r.languages[code]; ok {
+		return language.DefaultCurrency
+	}
+	return r.languages[r.defaultCode].DefaultCurrency
+}
+
+// Localize выбирает текст на первом доступном языке из цепочки отката
+func (r *Registry) Localize(texts map[string]string, code string) string {
+	for _, lang := range r.FallbackChain(code) {
+		if text, ok := texts[lang]; ok {
+			return text
+		}
+	}
+	return ""
+}
+
+// WouldCreateCycle проверяет, приведет ли назначение языка отката к циклу
+func (r *Registry) WouldCreateCycle(code, fallback string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := map[string]bool{code: true}
+	next := fallback
+	for next != "" {
+		if seen[next] {
+			return true
+		}
+		seen[next] = true
+
+		language, ok := r.languages[next]
+		if !ok || language.Fallback == nil {
+			return false
+		}
+		next = *language.Fallback
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+)
+
+const (
+	// HeaderName заголовок с ключом идемпотентности, который клиент генерирует для каждой операции
+	HeaderName = "Idempotency-Key"
+	// ReplayedHeaderName заголовок, которым отмечается сохраненный ответ на повторный запрос
+	ReplayedHeaderName = "Idempotent-Replayed"
+
+	// maxKeyLength максимальная длина ключа
+	maxKeyLength = 255
+	// maxBodySize максимальный размер тела запроса с ключом
+	maxBodySize = 1 << 20
+	// cleanupInterval интервал удаления истекших ключей
+	cleanupInterval = time.Hour
+)
+
+// Guard обеспечивает однократное выполнение запросов с заголовком Idempotency-Key:
+// повторный запрос с тем же ключом и телом получает сохраненный ответ, а запрос с тем же
+// ключом, но другим телом отклоняется
+type Guard struct {
+	repo   storage.IdempotencyRepository
+	ttl    time.Duration
+	logger *logrus.Logger
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+// NewGuard создает новый экземпляр Guard
+func NewGuard(repo storage.IdempotencyRepository, cfg config.IdempotencyConfig, logger *logrus.Logger) *Guard {
+	return &Guard{
+		repo:   repo,
+		ttl:    time.Duration(cfg.KeyTTLHours) * time.Hour,
+		logger: logger,
+	}
+}
+
+// responseRecorder копирует тело ответа, чтобы сохранить его для повторных запросов
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Middleware возвращает middleware для маршрута. Запросы без заголовка выполняются как обычно.
+// Ответы с ошибкой сервера не сохраняются: ключ освобождается, и запрос можно повторить
+func (g *Guard) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderName)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			abort(c, http.StatusBadRequest, "Некорректный ключ идемпотентности")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize))
+		if err != nil {
+			abort(c, http.StatusBadRequest, "Некорректный формат запроса")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Сохранение ответа не должно прерываться, если клиент отключился, не дождавшись его
+		ctx := context.WithoutCancel(c.Request.Context())
+		g.cleanup(ctx)
+
+		scope := c.FullPath()
+		hash := requestHash(c.Request, body)
+		record, reserved, err := g.repo.ReserveIdempotencyKey(ctx, scope, key, hash, g.ttl)
+		if err != nil {
+			abort(c, http.StatusInternalServerError, "Ошибка при обработке запроса")
+			return
+		}
+
+		if !reserved {
+			switch {
+			case record.RequestHash != hash:
+				g.logger.Warnf("Ключ идемпотентности для %s повторно использован с другим запросом от %s", scope, c.ClientIP())
+				abort(c, http.StatusConflict, "Ключ идемпотентности уже использован для другого запроса")
+			case record.StatusCode == nil:
+				c.Header("Retry-After", "1")
+				abort(c, http.StatusConflict, "Запрос с этим ключом идемпотентности еще выполняется")
+			default:
+				c.Header(ReplayedHeaderName, "true")
+				c.Data(*record.StatusCode, record.ContentType, record.Response)
+				c.Abort()
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			if err := g.repo.ReleaseIdempotencyKey(ctx, scope, key); err != nil {
+				g.logger.WithError(err).Errorf("Ключ идемпотентности для %s не освобожден", scope)
+			}
+			return
+		}
+
+		contentType := recorder.Header().Get("Content-Type")
+		if err := g.repo.CompleteIdempotencyKey(ctx, scope, key, status, contentType, recorder.body.Bytes()); err != nil {
+			g.logger.WithError(err).Errorf("Ответ для ключа идемпотентности %s не сохранен", scope)
+		}
+	}
+}
+
+// cleanup удаляет истекшие ключи не чаще одного раза в cleanupInterval
+func (g *Guard) cleanup(ctx context.Context) {
+	g.mu.Lock()
+	if time.Since(g.lastCleanup) < cleanupInterval {
+		g.mu.Unlock()
+		return
+	}
+	g.lastCleanup = time.Now()
+	g.mu.Unlock()
+
+	deleted, err := g.repo.DeleteExpiredIdempotencyKeys(ctx)
+	if err != nil {
+		return
+	}
+	if deleted > 0 {
+		g.logger.Infof("Удалено истекших ключей идемпотентности: %d", deleted)
+	}
+}
+
+// requestHash возвращает хеш метода, пути и тела запроса
+func requestHash(request *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, request.Method+" "+request.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// abort прерывает обработку запроса с ошибкой
+func abort(c *gin.Context, status int, message string) {
+	c.JSON(status, models.NewErrorResponse(message))
+	c.Abort()
+}
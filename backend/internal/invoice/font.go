@@ -0,0 +1,433 @@
+package invoice
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// errFontFormat возвращается, если файл шрифта поврежден или не является шрифтом TrueType
+var errFontFormat = errors.New("неподдерживаемый формат шрифта")
+
+// Таблицы, которые остаются в подмножестве шрифта, встраиваемом в PDF
+var subsetTables = []string{"cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "prep"}
+
+// Font шрифт TrueType: метрики для расчета ширины текста и таблицы для встраивания в PDF
+type Font struct {
+	name       string
+	unitsPerEm int
+	ascent     int
+	descent    int
+	bbox       [4]int
+
+	cmap     map[rune]uint16
+	advances []uint16
+	tables   map[string][]byte
+	// Смещения глифов в таблице glyf (numGlyphs+1 значений)
+	loca []uint32
+}
+
+// LoadFont читает и разбирает файл шрифта TrueType
+func LoadFont(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при чтении шрифта: %w", err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	font, err := parseFont(name, data)
+	if err != nil {
+		return nil, fmt.Errorf("шрифт %s: %w", path, err)
+	}
+	return font, nil
+}
+
+// parseFont разбирает таблицы шрифта, необходимые для вывода текста
+func parseFont(name string, data []byte) (*Font, error) {
+	if len(data) < 12 || binary.BigEndian.Uint32(data) != 0x00010000 {
+		return nil, errFontFormat
+	}
+
+	tables := make(map[string][]byte)
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < count; i++ {
+		record := 12 + i*16
+		if record+16 > len(data) {
+			return nil, errFontFormat
+		}
+		offset := binary.BigEndian.Uint32(data[record+8:])
+		length := binary.BigEndian.Uint32(data[record+12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil, errFontFormat
+		}
+		tables[string(data[record:record+4])] = data[offset : offset+length]
+	}
+
+	for _, tag := range []string{"head", "hhea", "hmtx", "maxp", "loca", "glyf", "cmap"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, fmt.Errorf("%w: нет таблицы %s", errFontFormat, tag)
+		}
+	}
+
+	head, hhea, maxp := tables["head"], tables["hhea"], tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errFontFormat
+	}
+
+	font := &Font{
+		name:       strings.ReplaceAll(name, " ", ""),
+		unitsPerEm: int(binary.BigEndian.Uint16(head[18:])),
+		ascent:     int(int16(binary.BigEndian.Uint16(hhea[4:]))),
+		descent:    int(int16(binary.BigEndian.Uint16(hhea[6:]))),
+		tables:     tables,
+	}
+	for i := range font.bbox {
+		font.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+i*2:])))
+	}
+	if font.unitsPerEm == 0 {
+		return nil, errFontFormat
+	}
+
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	if err := font.parseMetrics(numGlyphs, int(binary.BigEndian.Uint16(hhea[34:]))); err != nil {
+		return nil, err
+	}
+	if err := font.parseLoca(numGlyphs, int16(binary.BigEndian.Uint16(head[50:]))); err != nil {
+		return nil, err
+	}
+	if err := font.parseCmap(); err != nil {
+		return nil, err
+	}
+
+	return font, nil
+}
+
+// parseMetrics читает ширины глифов; глифы после numberOfHMetrics имеют ширину последнего из них
+func (f *Font) parseMetrics(numGlyphs, numMetrics int) error {
+	hmtx := f.tables["hmtx"]
+	if numMetrics == 0 || numMetrics > numGlyphs || len(hmtx) < numMetrics*4 {
+		return errFontFormat
+	}
+
+	f.advances = make([]uint16, numGlyphs)
+	for i := range f.advances {
+		if i < numMetrics {
+			f.advances[i] = binary.BigEndian.Uint16(hmtx[i*4:])
+		} else {
+			f.advances[i] = f.advances[numMetrics-1]
+		}
+	}
+	return nil
+}
+
+// parseLoca читает смещения глифов в коротком (format 0) или длинном (format 1) формате
+func (f *Font) parseLoca(numGlyphs int, format int16) error {
+	loca, glyf := f.tables["loca"], f.tables["glyf"]
+
+	f.loca = make([]uint32, numGlyphs+1)
+	for i := range f.loca {
+		if format == 0 {
+			if len(loca) < (i+1)*2 {
+				return errFontFormat
+			}
+			f.loca[i] = uint32(binary.BigEndian.Uint16(loca[i*2:])) * 2
+		} else {
+			if len(loca) < (i+1)*4 {
+				return errFontFormat
+			}
+			f.loca[i] = binary.BigEndian.Uint32(loca[i*4:])
+		}
+		if f.loca[i] > uint32(len(glyf)) || (i > 0 && f.loca[i] < f.loca[i-1]) {
+			return errFontFormat
+		}
+	}
+	return nil
+}
+
+// parseCmap строит соответствие символов глифам по юникодной подтаблице cmap (format 12 или 4)
+func (f *Font) parseCmap() error {
+	cmap := f.tables["cmap"]
+	if len(cmap) < 4 {
+		return errFontFormat
+	}
+
+	var format4, format12 []byte
+	count := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < count; i++ {
+		record := 4 + i*8
+		if record+8 > len(cmap) {
+			return errFontFormat
+		}
+		platform := binary.BigEndian.Uint16(cmap[record:])
+		encoding := binary.BigEndian.Uint16(cmap[record+2:])
+		offset := binary.BigEndian.Uint32(cmap[record+4:])
+		if platform != 0 && !(platform == 3 && (encoding == 1 || encoding == 10)) {
+			continue
+		}
+		if uint64(offset)+4 > uint64(len(cmap)) {
+			return errFontFormat
+		}
+		switch binary.BigEndian.Uint16(cmap[offset:]) {
+		case 4:
+			format4 = cmap[offset:]
+		case 12:
+			format12 = cmap[offset:]
+		}
+	}
+
+	f.cmap = make(map[rune]uint16)
+	switch {
+	case format12 != nil:
+		return f.parseCmap12(format12)
+	case format4 != nil:
+		return f.parseCmap4(format4)
+	default:
+		return fmt.Errorf("%w: нет юникодной таблицы символов", errFontFormat)
+	}
+}
+
+// parseCmap4 разбирает подтаблицу cmap формата 4 (символы из базовой плоскости Юникода)
+func (f *Font) parseCmap4(table []byte) error {
+	if len(table) < 14 {
+		return errFontFormat
+	}
+	segments := int(binary.BigEndian.Uint16(table[6:])) / 2
+	ends := 14
+	starts := ends + segments*2 + 2
+	deltas := starts + segments*2
+	rangeOffsets := deltas + segments*2
+	if len(table) < rangeOffsets+segments*2 {
+		return errFontFormat
+	}
+
+	for i := 0; i < segments; i++ {
+		end := int(binary.BigEndian.Uint16(table[ends+i*2:]))
+		start := int(binary.BigEndian.Uint16(table[starts+i*2:]))
+		delta := int(binary.BigEndian.Uint16(table[deltas+i*2:]))
+		rangeOffset := int(binary.BigEndian.Uint16(table[rangeOffsets+i*2:]))
+
+		for c := start; c <= end && c != 0xFFFF; c++ {
+			var glyph int
+			if rangeOffset == 0 {
+				glyph = (c + delta) & 0xFFFF
+			} else {
+				position := rangeOffsets + i*2 + rangeOffset + (c-start)*2
+				if position+2 > len(table) {
+					return errFontFormat
+				}
+				glyph = int(binary.BigEndian.Uint16(table[position:]))
+				if glyph != 0 {
+					glyph = (glyph + delta) & 0xFFFF
+				}
+			}
+			if glyph != 0 && glyph < len(f.advances) {
+				f.cmap[rune(c)] = uint16(glyph)
+			}
+		}
+	}
+	return nil
+}
+
+// parseCmap12 разбирает подтаблицу cmap формата 12 (группы символов всего диапазона Юникода)
+func (f *Font) parseCmap12(table []byte) error {
+	if len(table) < 16 {
+		return errFontFormat
+	}
+	groups := int(binary.BigEndian.Uint32(table[12:]))
+	if len(table) < 16+groups*12 {
+		return errFontFormat
+	}
+
+	for i := 0; i < groups; i++ {
+		group := table[16+i*12:]
+		start := binary.BigEndian.Uint32(group)
+		end := binary.BigEndian.Uint32(group[4:])
+		glyph := binary.BigEndian.Uint32(group[8:])
+		for c := start; c <= end && c <= 0x10FFFF; c++ {
+			if g := glyph + (c - start); g < uint32(len(f.advances)) {
+				f.cmap[rune(c)] = uint16(g)
+			}
+		}
+	}
+	return nil
+}
+
+// Glyph возвращает номер глифа символа; 0 (.notdef), если символа в шрифте нет
+func (f *Font) Glyph(r rune) uint16 {
+	return f.cmap[r]
+}
+
+// Width возвращает ширину текста в пунктах при заданном кегле
+func (f *Font) Width(text string, size float64) float64 {
+	var units int
+	for _, r := range text {
+		units += int(f.advances[f.Glyph(r)])
+	}
+	return float64(units) * size / float64(f.unitsPerEm)
+}
+
+// scale переводит значение из единиц шрифта в единицы PDF (1/1000 кегля)
+func (f *Font) scale(value int) int {
+	return value * 1000 / f.unitsPerEm
+}
+
+// Subset возвращает файл шрифта, в котором оставлены только использованные глифы (и глифы,
+// из которых состоят составные). Номера глифов не меняются, остальные глифы становятся пустыми
+func (f *Font) Subset(used map[uint16]bool) []byte {
+	glyf := f.tables["glyf"]
+
+	keep := map[uint16]bool{0: true}
+	pending := make([]uint16, 0, len(used))
+	for glyph := range used {
+		pending = append(pending, glyph)
+	}
+	for len(pending) > 0 {
+		glyph := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if keep[glyph] || int(glyph) >= len(f.advances) {
+			continue
+		}
+		keep[glyph] = true
+		pending = append(pending, componentGlyphs(glyf[f.loca[glyph]:f.loca[glyph+1]])...)
+	}
+
+	// Глифы переписываются в длинном формате loca с выравниванием по 4 байта
+	numGlyphs := len(f.advances)
+	newGlyf := make([]byte, 0, len(glyf)/4)
+	newLoca := make([]byte, (numGlyphs+1)*4)
+	for glyph := 0; glyph < numGlyphs; glyph++ {
+		binary.BigEndian.PutUint32(newLoca[glyph*4:], uint32(len(newGlyf)))
+		if keep[uint16(glyph)] {
+			newGlyf = append(newGlyf, glyf[f.loca[glyph]:f.loca[glyph+1]]...)
+			for len(newGlyf)%4 != 0 {
+				newGlyf = append(newGlyf, 0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(newLoca[numGlyphs*4:], uint32(len(newGlyf)))
+
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)  // checksumAdjustment пересчитывается ниже
+	binary.BigEndian.PutUint16(head[50:], 1) // indexToLocFormat: длинный формат
+
+	tables := map[string][]byte{"glyf": newGlyf, "loca": newLoca, "head": head}
+	for _, tag := range subsetTables {
+		if _, ok := tables[tag]; !ok {
+			if data, ok := f.tables[tag]; ok {
+				tables[tag] = data
+			}
+		}
+	}
+
+	result := writeFont(tables)
+	binary.BigEndian.PutUint32(result[headOffset(result)+8:], 0xB1B0AFBA-tableChecksum(result))
+	return result
+}
+
+// componentGlyphs возвращает глифы, из которых состоит составной глиф
+func componentGlyphs(data []byte) []uint16 {
+	if len(data) < 10 || int16(binary.BigEndian.Uint16(data)) >= 0 {
+		return nil
+	}
+
+	const (
+		argsAreWords   = 0x0001
+		haveScale      = 0x0008
+		moreComponents = 0x0020
+		haveXYScale    = 0x0040
+		haveTwoByTwo   = 0x0080
+	)
+
+	var glyphs []uint16
+	offset := 10
+	for offset+4 <= len(data) {
+		flags := binary.BigEndian.Uint16(data[offset:])
+		glyphs = append(glyphs, binary.BigEndian.Uint16(data[offset+2:]))
+		offset += 4
+		if flags&argsAreWords != 0 {
+			offset += 4
+		} else {
+			offset += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			offset += 2
+		case flags&haveXYScale != 0:
+			offset += 4
+		case flags&haveTwoByTwo != 0:
+			offset += 8
+		}
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+	return glyphs
+}
+
+// writeFont собирает файл TrueType из таблиц
+func writeFont(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	count := len(tags)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= count {
+		entrySelector++
+	}
+	searchRange := (1 << entrySelector) * 16
+
+	header := make([]byte, 12+count*16)
+	binary.BigEndian.PutUint32(header, 0x00010000)
+	binary.BigEndian.PutUint16(header[4:], uint16(count))
+	binary.BigEndian.PutUint16(header[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(header[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(header[10:], uint16(count*16-searchRange))
+
+	result := header
+	for i, tag := range tags {
+		data := tables[tag]
+		offset := len(result)
+		result = append(result, data...)
+
+		record := result[12+i*16:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], tableChecksum(data))
+		binary.BigEndian.PutUint32(record[8:], uint32(offset))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(data)))
+
+		for len(result)%4 != 0 {
+			result = append(result, 0)
+		}
+	}
+	return result
+}
+
+// headOffset возвращает смещение таблицы head в собранном файле шрифта
+func headOffset(font []byte) int {
+	count := int(binary.BigEndian.Uint16(font[4:]))
+	for i := 0; i < count; i++ {
+		record := font[12+i*16:]
+		if string(record[:4]) == "head" {
+			return int(binary.BigEndian.Uint32(record[8:]))
+		}
+	}
+	return 0
+}
+
+// tableChecksum возвращает контрольную сумму TrueType: сумму 32-битных слов по модулю 2^32
+func tableChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
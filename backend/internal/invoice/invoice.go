@@ -0,0 +1,344 @@
+package invoice
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+	"pryanik_studio/internal/utils"
+)
+
+// Поля страницы и колонки таблицы позиций, пт
+const (
+	marginLeft   = 40.0
+	marginRight  = pageWidth - 40
+	marginTop    = pageHeight - 45
+	marginBottom = 55.0
+
+	columnNumber = marginLeft                  // №
+	columnName   = columnNumber + 25           // наименование
+	columnQty    = marginRight - 175           // правый край количества
+	columnPrice  = marginRight - 90            // правый край цены
+	columnAmount = marginRight                 // правый край суммы
+	nameWidth    = columnQty - 45 - columnName // ширина наименования
+	textSize     = 9.0
+	lineHeight   = 12.0
+)
+
+// labels подписи счета на разных языках
+var labels = map[string]map[string]string{
+	"ru": {
+		"title": "Счет № %s от %s", "seller": "Продавец", "buyer": "Покупатель",
+		"tax_id": "ИНН", "kpp": "КПП", "ogrn": "ОГРН", "bank": "Банк", "account": "Р/с", "bic": "БИК",
+		"corr_account": "К/с", "phone": "Тел.", "email": "E-mail", "address": "Адрес доставки",
+		"order": "Заказ № %d от %s", "currency": "Валюта: %s", "sku": "Артикул",
+		"number": "№", "name": "Наименование", "qty": "Кол-во", "price": "Цена", "amount": "Сумма",
+		"subtotal": "Сумма товаров", "discount": "Скидка", "shipping": "Доставка", "total": "Итого к оплате",
+		"paid": "Счет оплачен", "page": "Стр. %d из %d",
+	},
+	"en": {
+		"title": "Invoice No. %s dated %s", "seller": "Seller", "buyer": "Buyer",
+		"tax_id": "Tax ID", "kpp": "KPP", "ogrn": "OGRN", "bank": "Bank", "account": "Account", "bic": "BIC",
+		"corr_account": "Corr. account", "phone": "Phone", "email": "E-mail", "address": "Shipping address",
+		"order": "Order #%d of %s", "currency": "Currency: %s", "sku": "SKU",
+		"number": "#", "name": "Item", "qty": "Qty", "price": "Price", "amount": "Amount",
+		"subtotal": "Subtotal", "discount": "Discount", "shipping": "Shipping", "total": "Total due",
+		"paid": "Invoice paid", "page": "Page %d of %d",
+	},
+	"es": {
+		"title": "Factura n.º %s del %s", "seller": "Vendedor", "buyer": "Cliente",
+		"tax_id": "NIF", "kpp": "KPP", "ogrn": "OGRN", "bank": "Banco", "account": "Cuenta", "bic": "BIC",
+		"corr_account": "Cuenta corresponsal", "phone": "Tel.", "email": "E-mail", "address": "Dirección de envío",
+		"order": "Pedido #%d del %s", "currency": "Moneda: %s", "sku": "SKU",
+		"number": "#", "name": "Artículo", "qty": "Cant.", "price": "Precio", "amount": "Importe",
+		"subtotal": "Subtotal", "discount": "Descuento", "shipping": "Envío", "total": "Total a pagar",
+		"paid": "Factura pagada", "page": "Página %d de %d",
+	},
+}
+
+// dateFormats форматы дат на разных языках (как в письмах о заказе)
+var dateFormats = map[string]string{
+	"ru": "02.01.2006",
+	"en": "01/02/2006",
+	"es": "02/01/2006",
+}
+
+// Generator выставляет счета по заказам и формирует их в PDF
+type Generator struct {
+	repo   storage.InvoiceRepository
+	prefix string
+	seller models.CompanyRequisites
+
+	regular *Font
+	bold    *Font
+}
+
+// NewGenerator создает новый экземпляр Generator и загружает шрифты счета
+func NewGenerator(repo storage.InvoiceRepository, cfg *config.Config) (*Generator, error) {
+	regular, err := LoadFont(cfg.Invoice.FontPath)
+	if err != nil {
+		return nil, err
+	}
+	bold, err := LoadFont(cfg.Invoice.BoldFontPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Generator{
+		repo:   repo,
+		prefix: cfg.Invoice.NumberPrefix,
+		seller: models.CompanyRequisites{
+			Name:        cfg.Invoice.CompanyName,
+			Address:     cfg.Invoice.CompanyAddress,
+			TaxID:       cfg.Invoice.CompanyTaxID,
+			KPP:         cfg.Invoice.CompanyKPP,
+			OGRN:        cfg.Invoice.CompanyOGRN,
+			BankName:    cfg.Invoice.CompanyBankName,
+			BankAccount: cfg.Invoice.CompanyBankAccount,
+			BIC:         cfg.Invoice.CompanyBIC,
+			CorrAccount: cfg.Invoice.CompanyCorrAccount,
+			Email:       cfg.Email.CompanyEmail,
+			Phone:       cfg.Invoice.CompanyPhone,
+			Note:        cfg.Invoice.Note,
+		},
+		regular: regular,
+		bold:    bold,
+	}, nil
+}
+
+// Issue выставляет счет по заказу (или возвращает уже выставленный)
+func (g *Generator) Issue(ctx context.Context, orderID int64) (models.Invoice, error) {
+	return g.repo.IssueInvoice(ctx, orderID, g.prefix, g.seller)
+}
+
+// FileName возвращает имя файла счета
+func FileName(invoice models.Invoice) string {
+	return "invoice-" + invoice.Number + ".pdf"
+}
+
+// Render формирует PDF счета по заказу на языке lang (по умолчанию русском)
+func (g *Generator) Render(invoice models.Invoice, order models.Order, lang string) ([]byte, error) {
+	text, ok := labels[lang]
+	if !ok {
+		lang = "ru"
+		text = labels[lang]
+	}
+	dateFormat := dateFormats[lang]
+
+	title := fmt.Sprintf(text["title"], invoice.Number, invoice.IssuedAt.Format(dateFormat))
+	doc := newDocument(title, g.regular, g.bold)
+	l := &layout{doc: doc, regular: doc.fonts[0], bold: doc.fonts[1]}
+	l.addPage()
+
+	l.write(l.bold, 15, title)
+	l.y -= 6
+
+	// Продавец
+	seller := invoice.Seller
+	l.write(l.bold, 10, text["seller"])
+	l.paragraph(seller.Name)
+	l.paragraph(seller.Address)
+	l.paragraph(joinFields(", ", text["tax_id"], seller.TaxID, text["kpp"], seller.KPP, text["ogrn"], seller.OGRN))
+	l.paragraph(joinFields(", ", text["bank"], seller.BankName))
+	l.paragraph(joinFields(", ", text["account"], seller.BankAccount, text["bic"], seller.BIC, text["corr_account"], seller.CorrAccount))
+	l.paragraph(joinFields(", ", text["phone"], seller.Phone, text["email"], seller.Email))
+	l.y -= 6
+
+	// Покупатель
+	l.write(l.bold, 10, text["buyer"])
+	l.paragraph(order.Name)
+	l.paragraph(joinFields(", ", text["phone"], order.Phone, text["email"], order.Email))
+	if order.ShippingAddress != nil {
+		l.paragraph(text["address"] + ": " + utils.FormatAddress(*order.ShippingAddress))
+	}
+	l.y -= 6
+
+	l.paragraph(fmt.Sprintf(text["order"], order.ID, order.CreatedAt.Format(dateFormat)))
+	l.paragraph(fmt.Sprintf(text["currency"], order.Currency))
+	l.y -= 8
+
+	// Позиции заказа; заголовок таблицы повторяется на каждой странице
+	l.tableHeader = func() { l.itemsHeader(text) }
+	l.tableHeader()
+	for i, item := range order.Items {
+		name := l.wrap(l.regular, textSize, item.DisplayName(), nameWidth)
+		if item.SKU != "" {
+			name = append(name, text["sku"]+": "+item.SKU)
+		}
+		l.ensure(float64(len(name))*lineHeight + 4)
+
+		top := l.y
+		l.page.text(l.regular, textSize, columnNumber, top, fmt.Sprintf("%d", i+1))
+		for j, line := range name {
+			l.page.text(l.regular, textSize, columnName, top-float64(j)*lineHeight, line)
+		}
+		l.right(l.regular, textSize, columnQty, top, fmt.Sprintf("%d", item.Quantity))
+		l.right(l.regular, textSize, columnPrice, top, g.money(item.Price))
+		l.right(l.regular, textSize, columnAmount, top, g.money(item.Price.Mul(item.Quantity)))
+
+		l.y = top - float64(len(name))*lineHeight
+		l.page.line(marginLeft, l.y+lineHeight-4, marginRight, l.y+lineHeight-4, 0.3)
+		l.y -= 2
+	}
+	l.tableHeader = nil
+	l.y -= 6
+
+	// Итоги
+	totals := [][2]string{{text["subtotal"], g.money(order.Subtotal)}}
+	if !order.Discount.IsZero() {
+		discount := "-" + g.money(order.Discount)
+		if order.PromoCode != "" {
+			discount = order.PromoCode + ": " + discount
+		}
+		totals = append(totals, [2]string{text["discount"], discount})
+	}
+	if order.ShippingMethod != "" {
+		totals = append(totals, [2]string{text["shipping"] + " (" + order.ShippingMethodName + ")", g.money(order.ShippingCost)})
+	}
+	l.ensure(float64(len(totals)+1)*lineHeight + 4)
+	for _, total := range totals {
+		l.right(l.regular, textSize, columnPrice, l.y, total[0])
+		l.right(l.regular, textSize, columnAmount, l.y, total[1])
+		l.y -= lineHeight
+	}
+	l.y -= 2
+	l.right(l.bold, 11, columnPrice, l.y, text["total"])
+	l.right(l.bold, 11, columnAmount, l.y, g.money(order.TotalCost))
+	l.y -= lineHeight + 10
+
+	if order.PaymentStatus == models.OrderPaymentPaid {
+		l.write(l.bold, 10, text["paid"])
+	}
+	l.paragraph(seller.Note)
+
+	// Номера страниц
+	for i, p := range doc.pages {
+		footer := fmt.Sprintf(text["page"], i+1, len(doc.pages))
+		p.text(l.regular, 8, marginRight-g.regular.Width(footer, 8), marginBottom-25, footer)
+		p.text(l.regular, 8, marginLeft, marginBottom-25, invoice.Number)
+	}
+
+	return doc.bytes()
+}
+
+// money форматирует сумму с символом валюты; если символа нет в шрифте, используется код валюты
+func (g *Generator) money(amount models.Money) string {
+	formatted := utils.FormatCurrency(amount)
+	for _, r := range formatted {
+		if g.regular.Glyph(r) == 0 && r != ' ' {
+			return amount.String() + " " + amount.Currency
+		}
+	}
+	return formatted
+}
+
+// joinFields объединяет пары «подпись - значение», пропуская пустые значения
+func joinFields(separator string, pairs ...string) string {
+	var parts []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			parts = append(parts, pairs[i]+": "+pairs[i+1])
+		}
+	}
+	return strings.Join(parts, separator)
+}
+
+// layout размещает текст на страницах сверху вниз и переносит его на новую страницу
+type layout struct {
+	doc     *document
+	page    *page
+	regular *pdfFont
+	bold    *pdfFont
+	// Текущая базовая линия
+	y float64
+	// Заголовок таблицы, который выводится на новой странице
+	tableHeader func()
+}
+
+// addPage начинает новую страницу
+func (l *layout) addPage() {
+	l.page = l.doc.addPage()
+	l.y = marginTop
+}
+
+// ensure начинает новую страницу, если на текущей не помещается блок заданной высоты
+func (l *layout) ensure(height float64) {
+	if l.y-height >= marginBottom {
+		return
+	}
+	l.addPage()
+	if l.tableHeader != nil {
+		l.tableHeader()
+	}
+}
+
+// write выводит строку заданным шрифтом и переходит на следующую
+func (l *layout) write(font *pdfFont, size float64, text string) {
+	l.ensure(size + 3)
+	l.page.text(font, size, marginLeft, l.y, text)
+	l.y -= size + 5
+}
+
+// paragraph выводит текст обычным шрифтом с переносом по ширине страницы; пустой текст пропускается
+func (l *layout) paragraph(text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range l.wrap(l.regular, textSize, text, marginRight-marginLeft) {
+		l.ensure(lineHeight)
+		l.page.text(l.regular, textSize, marginLeft, l.y, line)
+		l.y -= lineHeight
+	}
+}
+
+// right выводит текст, выровненный по правому краю x
+func (l *layout) right(font *pdfFont, size, x, y float64, text string) {
+	l.page.text(font, size, x-font.Width(text, size), y, text)
+}
+
+// itemsHeader выводит заголовок таблицы позиций
+func (l *layout) itemsHeader(text map[string]string) {
+	l.ensure(lineHeight * 3)
+	l.page.fillRect(marginLeft, l.y-4, marginRight-marginLeft, lineHeight+4, 0.92)
+	l.page.text(l.bold, textSize, columnNumber, l.y, text["number"])
+	l.page.text(l.bold, textSize, columnName, l.y, text["name"])
+	l.right(l.bold, textSize, columnQty, l.y, text["qty"])
+	l.right(l.bold, textSize, columnPrice, l.y, text["price"])
+	l.right(l.bold, textSize, columnAmount, l.y, text["amount"])
+	l.y -= lineHeight + 4
+}
+
+// wrap разбивает текст на строки не шире width; слишком длинные слова разрываются
+func (l *layout) wrap(font *pdfFont, size float64, text string, width float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if font.Width(candidate, size) <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+
+		line = ""
+		for _, r := range word {
+			if line != "" && font.Width(line+string(r), size) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			line += string(r)
+		}
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
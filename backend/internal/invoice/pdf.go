@@ -0,0 +1,273 @@
+package invoice
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Размер страницы A4 в пунктах
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+)
+
+// document PDF-документ из страниц A4 с текстом и линиями. Шрифты встраиваются подмножеством
+// использованных глифов, текст выводится номерами глифов (Identity-H), поэтому поддерживается
+// любой алфавит, который есть в шрифте
+type document struct {
+	title string
+	fonts []*pdfFont
+	pages []*page
+}
+
+// pdfFont шрифт документа и использованные им глифы с исходными символами (для копирования текста)
+type pdfFont struct {
+	*Font
+	resource string
+	used     map[uint16]rune
+}
+
+// page содержимое страницы документа
+type page struct {
+	content bytes.Buffer
+}
+
+// newDocument создает документ с заданными шрифтами; шрифты получают имена ресурсов F1, F2, ...
+func newDocument(title string, fonts ...*Font) *document {
+	d := &document{title: title}
+	for i, font := range fonts {
+		d.fonts = append(d.fonts, &pdfFont{Font: font, resource: fmt.Sprintf("F%d", i+1), used: make(map[uint16]rune)})
+	}
+	return d
+}
+
+// addPage добавляет новую страницу
+func (d *document) addPage() *page {
+	p := &page{}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// text выводит строку текста; (x, y) - начало базовой линии от левого нижнего угла страницы
+func (p *page) text(font *pdfFont, size, x, y float64, text string) {
+	var glyphs strings.Builder
+	for _, r := range text {
+		glyph := font.Glyph(r)
+		if _, ok := font.used[glyph]; !ok {
+			font.used[glyph] = r
+		}
+		fmt.Fprintf(&glyphs, "%04X", glyph)
+	}
+	fmt.Fprintf(&p.content, "BT /%s %.1f Tf %.2f %.2f Td <%s> Tj ET\n", font.resource, size, x, y, glyphs.String())
+}
+
+// line выводит отрезок заданной толщины
+func (p *page) line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y1, x2, y2)
+}
+
+// fillRect закрашивает прямоугольник оттенком серого (0 - черный, 1 - белый)
+func (p *page) fillRect(x, y, width, height, gray float64) {
+	fmt.Fprintf(&p.content, "%.2f g %.2f %.2f %.2f %.2f re f 0 g\n", gray, x, y, width, height)
+}
+
+// pdfWriter собирает объекты документа и таблицу ссылок на них
+type pdfWriter struct {
+	objects [][]byte
+}
+
+// reserve резервирует номер объекта, содержимое которого будет задано позже
+func (w *pdfWriter) reserve() int {
+	w.objects = append(w.objects, nil)
+	return len(w.objects)
+}
+
+// set задает содержимое объекта
+func (w *pdfWriter) set(id int, body string) {
+	w.objects[id-1] = []byte(body)
+}
+
+// add добавляет объект и возвращает его номер
+func (w *pdfWriter) add(body string) int {
+	id := w.reserve()
+	w.set(id, body)
+	return id
+}
+
+// addStream добавляет сжатый поток с дополнительными записями словаря
+func (w *pdfWriter) addStream(data []byte, extra string) (int, error) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(data); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+
+	id := w.reserve()
+	w.objects[id-1] = append([]byte(fmt.Sprintf("<< /Length %d /Filter /FlateDecode%s >>\nstream\n", compressed.Len(), extra)),
+		append(compressed.Bytes(), "\nendstream"...)...)
+	return id, nil
+}
+
+// bytes возвращает файл PDF
+func (d *document) bytes() ([]byte, error) {
+	w := &pdfWriter{}
+	catalog := w.reserve()
+	pages := w.reserve()
+
+	fontRefs := make([]string, 0, len(d.fonts))
+	for _, font := range d.fonts {
+		id, err := w.addFont(font)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при встраивании шрифта %s: %w", font.name, err)
+		}
+		fontRefs = append(fontRefs, fmt.Sprintf("/%s %d 0 R", font.resource, id))
+	}
+	resources := "<< /Font << " + strings.Join(fontRefs, " ") + " >> >>"
+
+	kids := make([]string, 0, len(d.pages))
+	for _, p := range d.pages {
+		content, err := w.addStream(p.content.Bytes(), "")
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сжатии страницы: %w", err)
+		}
+		id := w.add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources %s /Contents %d 0 R >>",
+			pages, pageWidth, pageHeight, resources, content))
+		kids = append(kids, fmt.Sprintf("%d 0 R", id))
+	}
+
+	w.set(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	w.set(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	info := w.add(fmt.Sprintf("<< /Title %s /Producer (Pryanik Studio) /CreationDate (D:%s) >>",
+		textString(d.title), time.Now().UTC().Format("20060102150405Z")))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+	offsets := make([]int, len(w.objects))
+	for i, body := range w.objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n", i+1)
+		out.Write(body)
+		out.WriteString("\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(w.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(w.objects)+1, catalog, info, xref)
+
+	return out.Bytes(), nil
+}
+
+// addFont встраивает подмножество шрифта как составной шрифт Type0 и возвращает номер его объекта
+func (w *pdfWriter) addFont(font *pdfFont) (int, error) {
+	glyphs := make([]int, 0, len(font.used))
+	for glyph := range font.used {
+		glyphs = append(glyphs, int(glyph))
+	}
+	sort.Ints(glyphs)
+
+	keep := make(map[uint16]bool, len(glyphs))
+	for _, glyph := range glyphs {
+		keep[uint16(glyph)] = true
+	}
+	subset := font.Subset(keep)
+	name := subsetTag(glyphs) + "+" + font.name
+
+	file, err := w.addStream(subset, fmt.Sprintf(" /Length1 %d", len(subset)))
+	if err != nil {
+		return 0, err
+	}
+	descriptor := w.add(fmt.Sprintf(
+		"<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 "+
+			"/Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		name, font.scale(font.bbox[0]), font.scale(font.bbox[1]), font.scale(font.bbox[2]), font.scale(font.bbox[3]),
+		font.scale(font.ascent), font.scale(font.descent), font.scale(font.ascent), file,
+	))
+
+	var widths strings.Builder
+	for _, glyph := range glyphs {
+		fmt.Fprintf(&widths, "%d [%d] ", glyph, font.scale(int(font.advances[glyph])))
+	}
+	descendant := w.add(fmt.Sprintf(
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+			"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+			"/FontDescriptor %d 0 R /W [%s] /CIDToGIDMap /Identity >>",
+		name, descriptor, widths.String(),
+	))
+
+	toUnicode, err := w.addStream(toUnicodeCMap(font.used, glyphs), "")
+	if err != nil {
+		return 0, err
+	}
+
+	return w.add(fmt.Sprintf(
+		"<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		name, descendant, toUnicode,
+	)), nil
+}
+
+// toUnicodeCMap возвращает таблицу соответствия глифов символам, по которой программы
+// просмотра восстанавливают текст при копировании и поиске
+func toUnicodeCMap(used map[uint16]rune, glyphs []int) []byte {
+	var cmap bytes.Buffer
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	// В одном блоке bfchar допускается не более 100 записей
+	for start := 0; start < len(glyphs); start += 100 {
+		end := min(start+100, len(glyphs))
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", end-start)
+		for _, glyph := range glyphs[start:end] {
+			var code strings.Builder
+			for _, unit := range utf16.Encode([]rune{used[uint16(glyph)]}) {
+				fmt.Fprintf(&code, "%04X", unit)
+			}
+			fmt.Fprintf(&cmap, "<%04X> <%s>\n", glyph, code.String())
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return cmap.Bytes()
+}
+
+// subsetTag возвращает префикс имени подмножества шрифта из шести заглавных букв
+func subsetTag(glyphs []int) string {
+	h := fnv.New32a()
+	for _, glyph := range glyphs {
+		fmt.Fprintf(h, "%d,", glyph)
+	}
+	sum := h.Sum32()
+
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = byte('A' + sum%26)
+		sum /= 26
+	}
+	return string(tag)
+}
+
+// textString возвращает строку PDF в кодировке UTF-16BE для метаданных документа
+func textString(text string) string {
+	var result strings.Builder
+	result.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(&result, "%04X", unit)
+	}
+	result.WriteString(">")
+	return result.String()
+}
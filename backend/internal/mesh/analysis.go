@@ -0,0 +1,110 @@
+package mesh
+
+import (
+	"math"
+
+	"pryanik_studio/internal/models"
+)
+
+// weldPrecision точность совмещения вершин при проверке замкнутости (0,0001 мм)
+const weldPrecision = 1e4
+
+// vertexKey координаты вершины, округленные для совмещения совпадающих вершин
+type vertexKey [3]int64
+
+// edgeKey ребро как пара номеров вершин (меньший номер первым)
+type edgeKey [2]int
+
+// Analyze рассчитывает объем, площадь поверхности, габариты модели и проверяет ее замкнутость.
+// Объем считается как сумма ориентированных объемов тетраэдров и корректен только для замкнутой модели
+func Analyze(m *Mesh) models.MeshAnalysis {
+	analysis := models.MeshAnalysis{Triangles: len(m.Triangles)}
+	if len(m.Triangles) == 0 {
+		return analysis
+	}
+
+	min := m.Triangles[0][0]
+	max := min
+
+	var volume, area float64
+	vertices := make(map[vertexKey]int)
+	edges := make(map[edgeKey]int)
+
+	for _, t := range m.Triangles {
+		a, b, c := t[0], t[1], t[2]
+
+		// Ориентированный объем тетраэдра с вершиной в начале координат
+		volume += dot(a, cross(b, c)) / 6
+		area += length(cross(sub(b, a), sub(c, a))) / 2
+
+		var ids [3]int
+		for i, v := range t {
+			min = models.Vec3{X: math.Min(min.X, v.X), Y: math.Min(min.Y, v.Y), Z: math.Min(min.Z, v.Z)}
+			max = models.Vec3{X: math.Max(max.X, v.X), Y: math.Max(max.Y, v.Y), Z: math.Max(max.Z, v.Z)}
+
+			key := vertexKey{
+				int64(math.Round(v.X * weldPrecision)),
+				int64(math.Round(v.Y * weldPrecision)),
+				int64(math.Round(v.Z * weldPrecision)),
+			}
+			id, ok := vertices[key]
+			if !ok {
+				id = len(vertices)
+				vertices[key] = id
+			}
+			ids[i] = id
+		}
+
+		// Вырожденные треугольники не участвуют в проверке ребер
+		if ids[0] == ids[1] || ids[1] == ids[2] || ids[0] == ids[2] {
+			continue
+		}
+		for i := 0; i < 3; i++ {
+			edges[newEdgeKey(ids[i], ids[(i+1)%3])]++
+		}
+	}
+
+	for _, count := range edges {
+		switch {
+		case count == 1:
+			analysis.BoundaryEdges++
+		case count > 2:
+			analysis.NonManifoldEdges++
+		}
+	}
+
+	analysis.Volume = math.Abs(volume)
+	analysis.SurfaceArea = area
+	analysis.BoundingBox = models.BoundingBox{Min: min, Max: max, Size: sub(max, min)}
+	analysis.Manifold = analysis.BoundaryEdges == 0 && analysis.NonManifoldEdges == 0
+
+	return analysis
+}
+
+// newEdgeKey создает ключ ребра независимо от направления обхода
+func newEdgeKey(a, b int) edgeKey {
+	if a > b {
+		a, b = b, a
+	}
+	return edgeKey{a, b}
+}
+
+func sub(a, b models.Vec3) models.Vec3 {
+	return models.Vec3{X: a.X - b.X, Y: a.Y - b.Y, Z: a.Z - b.Z}
+}
+
+func cross(a, b models.Vec3) models.Vec3 {
+	return models.Vec3{
+		X: a.Y*b.Z - a.Z*b.Y,
+		Y: a.Z*b.X - a.X*b.Z,
+		Z: a.X*b.Y - a.Y*b.X,
+	}
+}
+
+func dot(a, b models.Vec3) float64 {
+	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
+}
+
+func length(a models.Vec3) float64 {
+	return math.Sqrt(dot(a, a))
+}
@@ -0,0 +1,163 @@
+package mesh
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"pryanik_studio/internal/models"
+)
+
+// MaxTriangles максимальное количество треугольников в модели. Такой двоичный STL занимает
+// около 50 МБ, а сетка в памяти - около 72 МБ
+const MaxTriangles = 1_000_000
+
+var (
+	// ErrInvalidModel возвращается, если файл модели поврежден или имеет неизвестный формат
+	ErrInvalidModel = errors.New("некорректный файл модели")
+
+	// ErrModelTooLarge возвращается, если модель содержит слишком много треугольников
+	ErrModelTooLarge = errors.New("модель содержит слишком много треугольников")
+
+	// ErrTooManyVertices возвращается, если модель 3MF содержит слишком много вершин
+	ErrTooManyVertices = errors.New("модель содержит слишком много вершин")
+
+	// ErrArchiveTooLarge возвращается, если распакованный архив 3MF превышает допустимый размер
+	ErrArchiveTooLarge = errors.New("распакованный файл 3MF слишком большой")
+)
+
+// Triangle треугольник сетки
+type Triangle [3]models.Vec3
+
+// Mesh треугольная сетка модели в миллиметрах
+type Mesh struct {
+	Triangles []Triangle
+}
+
+// stlHeaderSize размер заголовка двоичного STL
+const stlHeaderSize = 80
+
+// stlTriangleSize размер записи треугольника двоичного STL
+const stlTriangleSize = 50
+
+// ParseSTL разбирает двоичный или текстовый STL. Единицы STL не задаются, принимаются миллиметры
+func ParseSTL(data []byte) (*Mesh, error) {
+	// Текстовый STL начинается с "solid", но так же могут начинаться и заголовки двоичных файлов,
+	// поэтому сначала проверяем, совпадает ли размер файла с размером двоичного STL
+	if len(data) >= stlHeaderSize+4 {
+		count := binary.LittleEndian.Uint32(data[stlHeaderSize:])
+		if uint64(len(data)) == stlHeaderSize+4+uint64(count)*stlTriangleSize {
+			return parseBinarySTL(data, int(count))
+		}
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("solid")) {
+		return parseASCIISTL(data)
+	}
+
+	return nil, fmt.Errorf("%w: не удалось определить формат STL", ErrInvalidModel)
+}
+
+// parseBinarySTL разбирает двоичный STL с известным количеством треугольников
+func parseBinarySTL(data []byte, count int) (*Mesh, error) {
+	if count > MaxTriangles {
+		return nil, ErrModelTooLarge
+	}
+
+	mesh := &Mesh{Triangles: make([]Triangle, 0, count)}
+	offset := stlHeaderSize + 4
+	for i := 0; i < count; i++ {
+		record := data[offset : offset+stlTriangleSize]
+		// Первые 12 байт - нормаль, она пересчитывается из вершин
+		var triangle Triangle
+		for v := 0; v < 3; v++ {
+			base := 12 + v*12
+			triangle[v] = models.Vec3{
+				X: float64(math.Float32frombits(binary.LittleEndian.Uint32(record[base:]))),
+				Y: float64(math.Float32frombits(binary.LittleEndian.Uint32(record[base+4:]))),
+				Z: float64(math.Float32frombits(binary.LittleEndian.Uint32(record[base+8:]))),
+			}
+		}
+		if !triangle.isFinite() {
+			return nil, fmt.Errorf("%w: некорректные координаты треугольника %d", ErrInvalidModel, i)
+		}
+		mesh.Triangles = append(mesh.Triangles, triangle)
+		offset += stlTriangleSize
+	}
+
+	return mesh, nil
+}
+
+// parseASCIISTL разбирает текстовый STL
+func parseASCIISTL(data []byte) (*Mesh, error) {
+	mesh := &Mesh{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var triangle Triangle
+	vertex := 0
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToLower(fields[0]) {
+		case "facet":
+			vertex = 0
+		case "vertex":
+			if len(fields) != 4 || vertex > 2 {
+				return nil, fmt.Errorf("%w: некорректная вершина в строке %d", ErrInvalidModel, line)
+			}
+			var coords [3]float64
+			for i := 0; i < 3; i++ {
+				value, err := strconv.ParseFloat(fields[i+1], 64)
+				if err != nil {
+					return nil, fmt.Errorf("%w: некорректная координата в строке %d", ErrInvalidModel, line)
+				}
+				coords[i] = value
+			}
+			triangle[vertex] = models.Vec3{X: coords[0], Y: coords[1], Z: coords[2]}
+			vertex++
+		case "endfacet":
+			if vertex != 3 {
+				return nil, fmt.Errorf("%w: грань с %d вершинами в строке %d", ErrInvalidModel, vertex, line)
+			}
+			if !triangle.isFinite() {
+				return nil, fmt.Errorf("%w: некорректные координаты в строке %d", ErrInvalidModel, line)
+			}
+			if len(mesh.Triangles) >= MaxTriangles {
+				return nil, ErrModelTooLarge
+			}
+			mesh.Triangles = append(mesh.Triangles, triangle)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidModel, err)
+	}
+
+	if len(mesh.Triangles) == 0 {
+		return nil, fmt.Errorf("%w: модель не содержит треугольников", ErrInvalidModel)
+	}
+
+	return mesh, nil
+}
+
+// isFinite проверяет, что все координаты треугольника конечны
+func (t Triangle) isFinite() bool {
+	for _, v := range t {
+		for _, c := range []float64{v.X, v.Y, v.Z} {
+			if math.IsNaN(c) || math.IsInf(c, 0) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,155 @@
+package mesh
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"pryanik_studio/internal/models"
+)
+
+// MaxUnpackRatio во сколько раз распакованные XML-части 3MF могут превышать допустимый размер
+// загружаемого файла. XML сетки хорошо сжимается, но больший коэффициент означает zip-бомбу
+const MaxUnpackRatio = 20
+
+// MaxVertices максимальное количество вершин во всех сетках модели 3MF
+const MaxVertices = MaxTriangles
+
+// unitScale множители для перевода единиц 3MF в миллиметры
+var unitScale = map[string]float64{
+	"micron":     0.001,
+	"millimeter": 1,
+	"centimeter": 10,
+	"inch":       25.4,
+	"foot":       304.8,
+	"meter":      1000,
+}
+
+// Parse3MF разбирает архив 3MF и объединяет сетки всех объектов модели.
+// Координаты переводятся в миллиметры согласно атрибуту unit. Суммарный размер распакованных
+// XML-частей ограничен maxUnpacked байтами и проверяется по заголовкам архива до распаковки
+func Parse3MF(data []byte, maxUnpacked int64) (*Mesh, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: файл 3MF не является zip-архивом", ErrInvalidModel)
+	}
+
+	var parts []*zip.File
+	var unpacked uint64
+	for _, file := range archive.File {
+		if !strings.EqualFold(path.Ext(file.Name), ".model") {
+			continue
+		}
+		unpacked += file.UncompressedSize64
+		if unpacked > uint64(maxUnpacked) {
+			return nil, ErrArchiveTooLarge
+		}
+		parts = append(parts, file)
+	}
+
+	parser := &threeMFParser{result: &Mesh{}}
+	for _, file := range parts {
+		if err := parser.parsePart(file); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(parser.result.Triangles) == 0 {
+		return nil, fmt.Errorf("%w: модель не содержит треугольников", ErrInvalidModel)
+	}
+
+	return parser.result, nil
+}
+
+// threeMFParser потоково разбирает XML-части 3MF, не загружая документ в память целиком
+type threeMFParser struct {
+	result *Mesh
+	// Общее количество вершин во всех разобранных сетках
+	vertices int
+}
+
+// parsePart разбирает одну XML-часть модели и добавляет ее треугольники к сетке.
+// Вершины хранятся только для текущей сетки: треугольники ссылаются на вершины своего элемента mesh
+func (p *threeMFParser) parsePart(file *zip.File) error {
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidModel, err)
+	}
+	defer reader.Close()
+
+	// Архив не даст прочитать больше заявленного размера, лимит дублирует эту проверку
+	decoder := xml.NewDecoder(io.LimitReader(reader, int64(file.UncompressedSize64)))
+
+	scale := 1.0
+	var vertices []models.Vec3
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: ошибка разбора %s: %v", ErrInvalidModel, file.Name, err)
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch element.Name.Local {
+		case "model":
+			if unit := xmlAttr(element, "unit"); unit != "" {
+				if scale, ok = unitScale[unit]; !ok {
+					return fmt.Errorf("%w: неизвестная единица измерения %q", ErrInvalidModel, unit)
+				}
+			}
+		case "mesh":
+			vertices = vertices[:0]
+		case "vertex":
+			if p.vertices >= MaxVertices {
+				return ErrTooManyVertices
+			}
+			var coords [3]float64
+			for i, name := range [3]string{"x", "y", "z"} {
+				value, err := strconv.ParseFloat(xmlAttr(element, name), 64)
+				if err != nil {
+					return fmt.Errorf("%w: некорректная координата вершины в %s", ErrInvalidModel, file.Name)
+				}
+				coords[i] = value * scale
+			}
+			vertices = append(vertices, models.Vec3{X: coords[0], Y: coords[1], Z: coords[2]})
+			p.vertices++
+		case "triangle":
+			if len(p.result.Triangles) >= MaxTriangles {
+				return ErrModelTooLarge
+			}
+			var triangle Triangle
+			for i, name := range [3]string{"v1", "v2", "v3"} {
+				index, err := strconv.Atoi(xmlAttr(element, name))
+				if err != nil || index < 0 || index >= len(vertices) {
+					return fmt.Errorf("%w: треугольник ссылается на несуществующую вершину %s", ErrInvalidModel, xmlAttr(element, name))
+				}
+				triangle[i] = vertices[index]
+			}
+			if !triangle.isFinite() {
+				return fmt.Errorf("%w: некорректные координаты вершин", ErrInvalidModel)
+			}
+			p.result.Triangles = append(p.result.Triangles, triangle)
+		}
+	}
+}
+
+// xmlAttr возвращает значение атрибута элемента без учета пространства имен
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
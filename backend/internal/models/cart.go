@@ -0,0 +1,96 @@
+package models
+
+import "time"
+
+// Cart корзина покупателя на сервере. Доступ к корзине дает токен из cookie, в базе хранится его хеш
+type Cart struct {
+	ID        string `json:"id" db:"id"`
+	TokenHash string `json:"-" db:"token_hash"`
+
+	// Заказ, оформленный из корзины; после оформления корзина не изменяется
+	OrderID *int64 `json:"order_id,omitempty" db:"order_id"`
+	// Начало оформления заказа; пока заказ оформляется, корзина не изменяется
+	CheckoutStartedAt *time.Time `json:"-" db:"checkout_started_at"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+
+	Items []CartItem `json:"items" db:"-"`
+
+	// Сумма доступных позиций по текущим ценам (заполняется при расчете корзины)
+	Subtotal Money  `json:"subtotal" db:"-"`
+	Currency string `json:"currency" db:"-"`
+}
+
+// CartItem позиция корзины. Цена позиции пересчитывается по текущим ценам товаров
+// при каждом получении корзины
+type CartItem struct {
+	ID              int64           `json:"id" db:"id"`
+	CartID          string          `json:"-" db:"cart_id"`
+	ProductID       int64           `json:"product_id" db:"product_id"`
+	VariantID       *int64          `json:"variant_id,omitempty" db:"variant_id"`
+	EstimateID      *string         `json:"estimate_id,omitempty" db:"estimate_id"`
+	Personalization Personalization `json:"personalization,omitempty" db:"personalization"`
+	Quantity        int             `json:"quantity" db:"quantity"`
+	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
+
+	// Цена за единицу на момент добавления или последнего изменения позиции
+	SavedPrice    Money  `json:"-" db:"price"`
+	SavedCurrency string `json:"-" db:"currency"`
+
+	// Текущая цена за единицу и цена, которую покупатель видел раньше, если она изменилась
+	Price         *Money `json:"price,omitempty" db:"-"`
+	PreviousPrice *Money `json:"previous_price,omitempty" db:"-"`
+
+	ProductName  string `json:"product_name,omitempty" db:"-"`
+	ProductImage string `json:"product_image,omitempty" db:"-"`
+	SKU          string `json:"sku,omitempty" db:"-"`
+	VariantName  string `json:"variant_name,omitempty" db:"-"`
+
+	// Недоступная позиция не входит в сумму и не может быть заказана; причина - в Warning.
+	// Warning также сообщает об изменении цены доступной позиции
+	Available bool   `json:"available" db:"-"`
+	Warning   string `json:"warning,omitempty" db:"-"`
+}
+
+// OrderItem возвращает позицию заказа с теми же товаром, вариантом, расчетом и персонализацией
+func (i CartItem) OrderItem() OrderItem {
+	return OrderItem{
+		ProductID:       i.ProductID,
+		VariantID:       i.VariantID,
+		EstimateID:      i.EstimateID,
+		Personalization: i.Personalization,
+		Quantity:        i.Quantity,
+	}
+}
+
+// CartItemRequest представляет запрос на добавление товара в корзину
+type CartItemRequest struct {
+	ProductID       int64           `json:"product_id" binding:"required"`
+	VariantID       *int64          `json:"variant_id"`
+	EstimateID      *string         `json:"estimate_id"`
+	Personalization Personalization `json:"personalization"`
+	Quantity        int             `json:"quantity"`
+}
+
+// CartItemUpdateRequest представляет запрос на изменение количества в позиции корзины
+type CartItemUpdateRequest struct {
+	Quantity int `json:"quantity"`
+}
+
+// CartCheckoutRequest представляет запрос на оформление заказа из корзины
+type CartCheckoutRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Phone    string `json:"phone" binding:"required"`
+	Comment  string `json:"comment"`
+	Language string `json:"language"`
+
+	PromoCode      string   `json:"promo_code"`
+	ShippingMethod string   `json:"shipping_method"`
+	Address        *Address `json:"address"`
+
+	// Черновик заказа, из которого оформлен заказ
+	DraftID string `json:"draft_id"`
+}
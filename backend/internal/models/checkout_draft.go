@@ -0,0 +1,92 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// CheckoutDraft черновик заказа: контакты и состав корзины, которые покупатель ввел в форму
+// заказа, но еще не отправил. Если черновик долго не изменяется, покупателю отправляется
+// напоминание со ссылкой для восстановления корзины. ID черновика служит ключом доступа к нему
+type CheckoutDraft struct {
+	ID       string     `json:"id" db:"id"`
+	Email    string     `json:"email" db:"email"`
+	Name     string     `json:"name,omitempty" db:"name"`
+	Phone    string     `json:"phone,omitempty" db:"phone"`
+	Language string     `json:"language" db:"language"`
+	CartID   *string    `json:"cart_id,omitempty" db:"cart_id"`
+	Items    DraftItems `json:"items" db:"items"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Время отправки напоминания и количество попыток отправки
+	ReminderSentAt   *time.Time `json:"-" db:"reminder_sent_at"`
+	ReminderAttempts int        `json:"-" db:"reminder_attempts"`
+
+	// Заказ, оформленный после сохранения черновика
+	OrderID     *int64     `json:"order_id,omitempty" db:"order_id"`
+	ConvertedAt *time.Time `json:"-" db:"converted_at"`
+}
+
+// DraftItem позиция корзины в черновике заказа
+type DraftItem struct {
+	ProductID int64  `json:"product_id" binding:"required"`
+	VariantID *int64 `json:"variant_id,omitempty"`
+	Quantity  int    `json:"quantity"`
+}
+
+// DraftItems позиции черновика заказа; хранятся в колонке JSONB
+type DraftItems []DraftItem
+
+// Value реализует driver.Valuer
+func (i DraftItems) Value() (driver.Value, error) {
+	return jsonValue(i)
+}
+
+// Scan реализует sql.Scanner
+func (i *DraftItems) Scan(src interface{}) error {
+	return jsonScan(src, i)
+}
+
+// CheckoutDraftRequest представляет запрос на сохранение черновика заказа
+type CheckoutDraftRequest struct {
+	Email    string      `json:"email" binding:"required,email,max=255"`
+	Name     string      `json:"name" binding:"max=255"`
+	Phone    string      `json:"phone" binding:"max=50"`
+	Language string      `json:"language" binding:"max=10"`
+	CartID   *string     `json:"cart_id" binding:"omitempty,max=32"`
+	Items    []DraftItem `json:"items" binding:"dive"`
+}
+
+// CheckoutReminder письмо-напоминание о незавершенном заказе. Адрес и имя в черновике
+// никто не подтверждал, поэтому введенные покупателем данные, кроме состава корзины, в письмо не попадают
+type CheckoutReminder struct {
+	Email    string
+	Language string
+	Items    []ReminderItem
+
+	// Ссылка для восстановления корзины и ссылка для отказа от напоминаний
+	RestoreURL     string
+	UnsubscribeURL string
+}
+
+// ReminderItem товар в напоминании о незавершенном заказе
+type ReminderItem struct {
+	Name     string
+	Quantity int
+}
+
+// ReminderStats статистика напоминаний о незавершенных заказах
+type ReminderStats struct {
+	// Сохраненные черновики и черновики, по которым отправлено напоминание
+	Drafts   int64 `json:"drafts" db:"drafts"`
+	Reminded int64 `json:"reminded" db:"reminded"`
+
+	// Черновики, превратившиеся в заказ, в том числе после напоминания
+	Converted              int64 `json:"converted" db:"converted"`
+	ConvertedAfterReminder int64 `json:"converted_after_reminder" db:"converted_after_reminder"`
+
+	// Адреса, отказавшиеся от напоминаний
+	Unsubscribed int64 `json:"unsubscribed" db:"unsubscribed"`
+}
@@ -0,0 +1,99 @@
+package models
+
+import "time"
+
+// Статусы сообщения с формы обратной связи
+const (
+	ContactStatusNew      = "new"
+	ContactStatusRead     = "read"
+	ContactStatusAnswered = "answered"
+)
+
+// ContactStatuses допустимые статусы сообщения
+var ContactStatuses = []string{
+	ContactStatusNew,
+	ContactStatusRead,
+	ContactStatusAnswered,
+}
+
+// ContactMessage сообщение с формы обратной связи. Сообщение сохраняется до отправки
+// уведомления по email и обрабатывается администраторами во входящих
+type ContactMessage struct {
+	ID       int64  `json:"id" db:"id"`
+	Name     string `json:"name" db:"name"`
+	Email    string `json:"email" db:"email"`
+	Phone    string `json:"phone" db:"phone"`
+	Message  string `json:"message" db:"message"`
+	Language string `json:"language" db:"language"`
+	Status   string `json:"status" db:"status"`
+
+	// Администратор, который отвечает на сообщение (пусто - не назначен)
+	AssignedTo string `json:"assigned_to" db:"assigned_to"`
+
+	ReadAt     *time.Time `json:"read_at,omitempty" db:"read_at"`
+	AnsweredAt *time.Time `json:"answered_at,omitempty" db:"answered_at"`
+
+	// Время отправки уведомления по email (nil - еще не отправлено) и количество попыток
+	NotifiedAt     *time.Time `json:"notified_at,omitempty" db:"notified_at"`
+	NotifyAttempts int        `json:"notify_attempts" db:"notify_attempts"`
+
+	// Время отправки подтверждения клиенту и уведомления администратору по отдельности
+	CustomerNotifiedAt *time.Time `json:"customer_notified_at,omitempty" db:"customer_notified_at"`
+	AdminNotifiedAt    *time.Time `json:"admin_notified_at,omitempty" db:"admin_notified_at"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Внутренние заметки (заполняются при получении сообщения по ID)
+	Notes []ContactNote `json:"notes,omitempty" db:"-"`
+}
+
+// ContactNote внутренняя заметка администратора к сообщению
+type ContactNote struct {
+	ID        int64     `json:"id" db:"id"`
+	MessageID int64     `json:"-" db:"message_id"`
+	Author    string    `json:"author" db:"author"`
+	Text      string    `json:"text" db:"text"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// ContactMessageFilter параметры отбора сообщений во входящих
+type ContactMessageFilter struct {
+	Status     string
+	AssignedTo *string
+	// Поиск по имени, email, телефону и тексту сообщения
+	Search   string
+	Page     int
+	PageSize int
+}
+
+// ContactMessageList представляет страницу списка сообщений
+type ContactMessageList struct {
+	Items      []ContactMessage `json:"items"`
+	TotalItems int              `json:"total_items"`
+	Page       int              `json:"page"`
+	PageSize   int              `json:"page_size"`
+	TotalPages int              `json:"total_pages"`
+}
+
+// ContactMessageUpdateRequest представляет запрос на изменение статуса сообщения и (или) ответственного
+type ContactMessageUpdateRequest struct {
+	Status     string  `json:"status" binding:"omitempty,oneof=new read answered"`
+	AssignedTo *string `json:"assigned_to" binding:"omitempty,max=100"`
+}
+
+// ContactNoteRequest представляет запрос на добавление заметки к сообщению
+type ContactNoteRequest struct {
+	Text string `json:"text" binding:"required,max=5000"`
+}
+
+// ContactForm возвращает данные сообщения для письма-уведомления
+func (m ContactMessage) ContactForm() *ContactFormRequest {
+	return &ContactFormRequest{
+		Name:     m.Name,
+		Email:    m.Email,
+		Phone:    m.Phone,
+		Message:  m.Message,
+		Language: m.Language,
+	}
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+)
+
+// Режимы округления сконвертированных цен
+const (
+	RoundingHalfUp = "half_up" // к ближайшему шагу, половина - вверх
+	RoundingUp     = "up"      // всегда вверх до шага
+	RoundingDown   = "down"    // всегда вниз до шага
+)
+
+// Источники курсов валют
+const (
+	ExchangeRateSourceManual = "manual"
+	ExchangeRateSourceCBR    = "cbr"
+)
+
+// ExchangeRate представляет курс валюты относительно опорной валюты
+// и правило округления цен, пересчитанных в эту валюту
+type ExchangeRate struct {
+	Currency string `json:"currency" db:"currency"`
+	// Стоимость одной единицы валюты в опорной валюте, десятичная строка без потери точности
+	Rate string `json:"rate" db:"rate"`
+	// Шаг округления в минимальных единицах валюты: 1 - до копеек/центов, 100 - до целых
+	RoundingStep int64     `json:"rounding_step" db:"rounding_step"`
+	RoundingMode string    `json:"rounding_mode" db:"rounding_mode"`
+	Source       string    `json:"source" db:"source"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ExchangeRateRequest представляет запрос на ручную установку курса валюты
+type ExchangeRateRequest struct {
+	Rate         string `json:"rate" binding:"required"`
+	RoundingStep *int64 `json:"rounding_step,omitempty"`
+	RoundingMode string `json:"rounding_mode,omitempty"`
+}
+
+// ExchangeRateImportResult содержит итог импорта курсов из файла ЦБ РФ
+type ExchangeRateImportResult struct {
+	Date     string   `json:"date"`
+	Imported []string `json:"imported"`
+	Skipped  []string `json:"skipped,omitempty"`
+	Repriced int      `json:"repriced"`
+}
+
+// DerivedPrice описывает цену перевода товара, рассчитанную из базовой цены
+type DerivedPrice struct {
+	ProductID int64  `db:"product_id"`
+	Language  string `db:"language"`
+	// Базовая цена товара и валюта языка, в которую ее нужно пересчитать
+	BasePrice      Money  `db:"base_price"`
+	BaseCurrency   string `db:"base_currency"`
+	TargetCurrency string `db:"target_currency"`
+	// Рассчитанная цена
+	Price Money `db:"-"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Customer учетная запись покупателя. Покупатель входит по одноразовой ссылке из письма;
+// заказы связываются с учетной записью по email
+type Customer struct {
+	ID          int64      `json:"id" db:"id"`
+	Email       string     `json:"email" db:"email"`
+	Name        string     `json:"name" db:"name"`
+	Phone       string     `json:"phone" db:"phone"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty" db:"last_login_at"`
+}
+
+// CustomerAddress сохраненный адрес доставки покупателя
+type CustomerAddress struct {
+	ID         int64     `json:"id" db:"id"`
+	CustomerID int64     `json:"-" db:"customer_id"`
+	Label      string    `json:"label" db:"label"`
+	Address    Address   `json:"address" db:"address"`
+	IsDefault  bool      `json:"is_default" db:"is_default"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// LoginLinkRequest представляет запрос на отправку ссылки для входа
+type LoginLinkRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Language string `json:"language"`
+}
+
+// LoginVerifyRequest представляет запрос на вход по токену из ссылки
+type LoginVerifyRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// CustomerLoginResponse ответ на успешный вход покупателя
+type CustomerLoginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Customer  Customer  `json:"customer"`
+}
+
+// CustomerUpdateRequest представляет запрос на изменение данных покупателя
+type CustomerUpdateRequest struct {
+	Name  string `json:"name" binding:"max=255"`
+	Phone string `json:"phone"`
+}
+
+// CustomerAddressRequest представляет запрос на сохранение адреса покупателя
+type CustomerAddressRequest struct {
+	Label     string  `json:"label" binding:"max=100"`
+	Address   Address `json:"address"`
+	IsDefault bool    `json:"is_default"`
+}
+
+// LoginLink письмо со ссылкой для входа в личный кабинет
+type LoginLink struct {
+	Email     string
+	Language  string
+	URL       string
+	ExpiresAt time.Time
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"time"
+)
+
+// Vec3 точка или вектор в пространстве модели (в миллиметрах)
+type Vec3 struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// BoundingBox габариты модели
+type BoundingBox struct {
+	Min  Vec3 `json:"min"`
+	Max  Vec3 `json:"max"`
+	Size Vec3 `json:"size"`
+}
+
+// MeshAnalysis результат анализа 3D-модели
+type MeshAnalysis struct {
+	Triangles   int         `json:"triangles"`
+	Volume      float64     `json:"volume_mm3"`       // объем, мм³
+	SurfaceArea float64     `json:"surface_area_mm2"` // площадь поверхности, мм²
+	BoundingBox BoundingBox `json:"bounding_box"`
+
+	// Модель замкнута: каждое ребро принадлежит ровно двум треугольникам
+	Manifold         bool `json:"manifold"`
+	BoundaryEdges    int  `json:"boundary_edges"`     // ребра только одного треугольника (дыры)
+	NonManifoldEdges int  `json:"non_manifold_edges"` // ребра трех и более треугольников
+}
+
+// VectorAnalysis результат анализа векторного макета для лазерной резки и гравировки
+type VectorAnalysis struct {
+	Paths       int         `json:"paths"`
+	ClosedPaths int         `json:"closed_paths"`
+	CutLength   float64     `json:"cut_length_mm"`    // суммарная длина контуров, мм
+	EngraveArea float64     `json:"engrave_area_mm2"` // площадь внутри замкнутых контуров, мм²
+	BoundingBox BoundingBox `json:"bounding_box"`
+
+	// Элементы, которые не учитываются в расчете (текст, растровые изображения)
+	Unsupported int `json:"unsupported_elements"`
+}
+
+// PrintMaterial материал 3D-печати с параметрами расчета стоимости
+type PrintMaterial struct {
+	Code string `json:"code" db:"code"`
+	Name string `json:"name" db:"name"`
+
+	// Плотность, г/см³
+	Density float64 `json:"density" db:"density"`
+	// Скорость печати, см³ в час
+	PrintSpeed float64 `json:"print_speed" db:"print_speed"`
+
+	// Цены указываются в валюте материала
+	PricePerGram    Money  `json:"price_per_gram" db:"price_per_gram"`
+	MachineHourRate Money  `json:"machine_hour_rate" db:"machine_hour_rate"`
+	MinimumCharge   Money  `json:"minimum_charge" db:"minimum_charge"`
+	Currency        string `json:"currency" db:"currency"`
+
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PrintMaterialRequest представляет запрос на создание или изменение материала печати
+type PrintMaterialRequest struct {
+	Name            string  `json:"name" binding:"required,max=255"`
+	Density         float64 `json:"density" binding:"required,gt=0"`
+	PrintSpeed      float64 `json:"print_speed" binding:"required,gt=0"`
+	PricePerGram    *Money  `json:"price_per_gram" binding:"required"`
+	MachineHourRate *Money  `json:"machine_hour_rate" binding:"required"`
+	MinimumCharge   *Money  `json:"minimum_charge" binding:"required"`
+	Currency        string  `json:"currency" binding:"required,len=3"`
+	Enabled         *bool   `json:"enabled"`
+}
+
+// Операции лазерной обработки
+const (
+	LaserOperationCut        = "cut"
+	LaserOperationEngrave    = "engrave"
+	LaserOperationCutEngrave = "cut_engrave"
+)
+
+// LaserMaterial материал лазерной резки и гравировки с расценками
+type LaserMaterial struct {
+	Code string `json:"code" db:"code"`
+	Name string `json:"name" db:"name"`
+
+	// Цены указываются в валюте материала: рез - за метр, гравировка - за см²,
+	// материал - за см² габаритов макета
+	CutPricePerMeter   Money  `json:"cut_price_per_meter" db:"cut_price_per_meter"`
+	EngravePricePerCM2 Money  `json:"engrave_price_per_cm2" db:"engrave_price_per_cm2"`
+	SheetPricePerCM2   Money  `json:"sheet_price_per_cm2" db:"sheet_price_per_cm2"`
+	MinimumCharge      Money  `json:"minimum_charge" db:"minimum_charge"`
+	Currency           string `json:"currency" db:"currency"`
+
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// LaserMaterialRequest представляет запрос на создание или изменение материала лазерной обработки
+type LaserMaterialRequest struct {
+	Name               string `json:"name" binding:"required,max=255"`
+	CutPricePerMeter   *Money `json:"cut_price_per_meter" binding:"required"`
+	EngravePricePerCM2 *Money `json:"engrave_price_per_cm2" binding:"required"`
+	SheetPricePerCM2   *Money `json:"sheet_price_per_cm2" binding:"required"`
+	MinimumCharge      *Money `json:"minimum_charge" binding:"required"`
+	Currency           string `json:"currency" binding:"required,len=3"`
+	Enabled            *bool  `json:"enabled"`
+}
+
+// Виды расчетов стоимости
+const (
+	EstimateKind3D    = "3d"
+	EstimateKindLaser = "laser"
+)
+
+// EstimateLine строка расчета стоимости
+type EstimateLine struct {
+	Code     string  `json:"code"`
+	Label    string  `json:"label"`
+	Quantity float64 `json:"quantity"` // граммы, часы и т.п.
+	Unit     string  `json:"unit,omitempty"`
+	Amount   Money   `json:"amount"`
+}
+
+// Estimate предварительный расчет стоимости изготовления
+type Estimate struct {
+	// ID сохраненного расчета, по которому расчет прикрепляется к позиции заказа
+	ID       string `json:"id,omitempty"`
+	Kind     string `json:"kind"`
+	Material string `json:"material"`
+	Quantity int    `json:"quantity"`
+
+	// Загруженный файл модели или макета, если расчет выполнен по ранее загруженному файлу
+	UploadID string `json:"upload_id,omitempty"`
+
+	// Анализ 3D-модели (для 3D-печати)
+	Analysis *MeshAnalysis `json:"analysis,omitempty"`
+	// Масса одного изделия, г, и время печати одного изделия, ч
+	WeightGrams float64 `json:"weight_grams,omitempty"`
+	PrintHours  float64 `json:"print_hours,omitempty"`
+
+	// Анализ векторного макета и выбранная операция (для лазерной обработки)
+	VectorAnalysis *VectorAnalysis `json:"vector_analysis,omitempty"`
+	Operation      string          `json:"operation,omitempty"`
+
+	Lines     []EstimateLine `json:"lines"`
+	UnitPrice Money          `json:"unit_price"`
+	Total     Money          `json:"total"`
+	Currency  string         `json:"currency"`
+
+	// Предупреждения (незамкнутая модель, применена минимальная стоимость и т.п.)
+	Warnings []string `json:"warnings,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Заказ, в котором использован расчет (расчет прикрепляется только к одному заказу)
+	OrderID *int64 `json:"-"`
+}
+
+// Expired проверяет, истек ли срок действия расчета
+func (e Estimate) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt)
+}
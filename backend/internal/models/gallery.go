@@ -16,6 +16,9 @@ type GalleryItem struct {
 	Title       string `json:"title" db:"-"`
 	Description string `json:"description" db:"-"`
 
+	// Поля, взятые из другого языка при отсутствии перевода: поле -> фактический язык
+	FallbackFields map[string]string `json:"fallback_fields,omitempty" db:"-"`
+
 	// Сохраняем для внутреннего использования, но не возвращаем в API
 	Translations map[string]*GalleryItemTranslation `json:"-" db:"-"`
 }
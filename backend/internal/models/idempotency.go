@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// IdempotencyRecord запрос, выполненный с ключом идемпотентности, и сохраненный ответ на него.
+// Пока запрос выполняется, StatusCode равен nil
+type IdempotencyRecord struct {
+	Scope       string    `db:"scope"`
+	Key         string    `db:"key"`
+	RequestHash string    `db:"request_hash"`
+	StatusCode  *int      `db:"status_code"`
+	ContentType string    `db:"content_type"`
+	Response    []byte    `db:"response"`
+	CreatedAt   time.Time `db:"created_at"`
+	ExpiresAt   time.Time `db:"expires_at"`
+}
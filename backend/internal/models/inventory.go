@@ -0,0 +1,15 @@
+package models
+
+// StockRequest представляет запрос на изменение остатка товара; null - остаток не учитывается
+type StockRequest struct {
+	Stock *int `json:"stock"`
+}
+
+// LowStockItem товар или вариант, остаток которого опустился до порога
+type LowStockItem struct {
+	ProductID int64  `json:"product_id"`
+	VariantID *int64 `json:"variant_id,omitempty"`
+	Name      string `json:"name"`
+	SKU       string `json:"sku,omitempty"`
+	Remaining int    `json:"remaining"`
+}
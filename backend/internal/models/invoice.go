@@ -0,0 +1,49 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// CompanyRequisites реквизиты продавца, которые печатаются в счете
+type CompanyRequisites struct {
+	Name    string `json:"name"`
+	Address string `json:"address,omitempty"`
+	// ИНН, КПП и ОГРН (ОГРНИП)
+	TaxID string `json:"tax_id,omitempty"`
+	KPP   string `json:"kpp,omitempty"`
+	OGRN  string `json:"ogrn,omitempty"`
+	// Банковские реквизиты: банк, расчетный счет, БИК и корреспондентский счет
+	BankName    string `json:"bank_name,omitempty"`
+	BankAccount string `json:"bank_account,omitempty"`
+	BIC         string `json:"bic,omitempty"`
+	CorrAccount string `json:"corr_account,omitempty"`
+	Email       string `json:"email,omitempty"`
+	Phone       string `json:"phone,omitempty"`
+	// Примечание внизу счета, например «НДС не облагается»
+	Note string `json:"note,omitempty"`
+}
+
+// Value реализует driver.Valuer
+func (r CompanyRequisites) Value() (driver.Value, error) {
+	return jsonValue(r)
+}
+
+// Scan реализует sql.Scanner
+func (r *CompanyRequisites) Scan(src interface{}) error {
+	return jsonScan(src, r)
+}
+
+// Invoice счет по заказу. Номер выдается один раз и не меняется; нумерация сквозная
+// в пределах года. Реквизиты продавца сохраняются на момент выставления счета
+type Invoice struct {
+	ID      int64  `json:"id" db:"id"`
+	OrderID int64  `json:"order_id" db:"order_id"`
+	Number  string `json:"number" db:"number"`
+	// Год и порядковый номер счета в году
+	Year     int `json:"year" db:"year"`
+	Sequence int `json:"sequence" db:"sequence"`
+
+	Seller   CompanyRequisites `json:"seller" db:"seller"`
+	IssuedAt time.Time         `json:"issued_at" db:"issued_at"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// Language представляет язык интерфейса и контента
+type Language struct {
+	Code            string    `json:"code" db:"code"`
+	Name            string    `json:"name" db:"name"`
+	DefaultCurrency string    `json:"default_currency" db:"default_currency"`
+	Enabled         bool      `json:"enabled" db:"enabled"`
+	IsDefault       bool      `json:"is_default" db:"is_default"`
+	Fallback        *string   `json:"fallback,omitempty" db:"fallback"` // язык, на который откатываемся при отсутствии перевода
+	SortOrder       int       `json:"sort_order" db:"sort_order"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// LanguageCreateRequest представляет запрос на добавление языка
+type LanguageCreateRequest struct {
+	Code            string  `json:"code" binding:"required,max=5"`
+	Name            string  `json:"name" binding:"required"`
+	DefaultCurrency string  `json:"default_currency" binding:"required,len=3"`
+	Enabled         *bool   `json:"enabled"`
+	IsDefault       bool    `json:"is_default"`
+	Fallback        *string `json:"fallback"`
+	SortOrder       int     `json:"sort_order"`
+}
+
+// LanguageUpdateRequest представляет запрос на обновление языка
+// Все поля являются указателями, чтобы отличать отсутствующие значения от пустых
+type LanguageUpdateRequest struct {
+	Name            *string `json:"name,omitempty"`
+	DefaultCurrency *string `json:"default_currency,omitempty" binding:"omitempty,len=3"`
+	Enabled         *bool   `json:"enabled,omitempty"`
+	IsDefault       *bool   `json:"is_default,omitempty"`
+	Fallback        *string `json:"fallback,omitempty"` // пустая строка снимает язык отката
+	SortOrder       *int    `json:"sort_order,omitempty"`
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MoneyScale количество знаков после запятой в денежных суммах.
+// Совпадает с точностью колонок DECIMAL(10, 2) в базе данных
+const MoneyScale = 2
+
+// moneyFactor число минимальных единиц в одной единице валюты
+const moneyFactor = 100
+
+var (
+	// ErrCurrencyMismatch возвращается при операциях над суммами в разных валютах
+	ErrCurrencyMismatch = errors.New("суммы указаны в разных валютах")
+
+	// ErrInvalidMoney возвращается при разборе некорректной денежной суммы
+	ErrInvalidMoney = errors.New("некорректная денежная сумма")
+)
+
+// Money представляет денежную сумму в минимальных единицах валюты (копейках, центах)
+type Money struct {
+	Amount   int64  `json:"-"`
+	Currency string `json:"-"`
+}
+
+// NewMoney создает сумму из количества минимальных единиц
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney разбирает десятичную запись суммы ("1500", "1500.5", "1500.50") без потери точности.
+// Дробная часть длиннее MoneyScale знаков считается ошибкой
+func ParseMoney(value string, currency string) (Money, error) {
+	amount, err := parseMinorUnits(value)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// parseMinorUnits переводит десятичную запись в минимальные единицы
+func parseMinorUnits(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, ErrInvalidMoney
+	}
+
+	negative := false
+	if value[0] == '-' || value[0] == '+' {
+		negative = value[0] == '-'
+		value = value[1:]
+	}
+
+	whole, fraction, hasPoint := strings.Cut(value, ".")
+	if whole == "" && fraction == "" || hasPoint && fraction == "" {
+		return 0, ErrInvalidMoney
+	}
+	if len(fraction) > MoneyScale {
+		// Допускаем лишние нули, которые может вернуть база данных или клиент
+		if strings.Trim(fraction[MoneyScale:], "0") != "" {
+			return 0, fmt.Errorf("%w: больше %d знаков после запятой", ErrInvalidMoney, MoneyScale)
+		}
+		fraction = fraction[:MoneyScale]
+	}
+	fraction += strings.Repeat("0", MoneyScale-len(fraction))
+
+	if whole == "" {
+		whole = "0"
+	}
+	for _, r := range whole + fraction {
+		if r < '0' || r > '9' {
+			return 0, ErrInvalidMoney
+		}
+	}
+
+	amount, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidMoney, err)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// String возвращает десятичную запись суммы без символа валюты ("1500.00")
+func (m Money) String() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/moneyFactor, MoneyScale, amount%moneyFactor)
+}
+
+// IsZero проверяет, что сумма равна нулю
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative проверяет, что сумма отрицательна
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add складывает две суммы в одной валюте.
+// Нулевая сумма без валюты считается нейтральным элементом
+func (m Money) Add(other Money) (Money, error) {
+	switch {
+	case m.Currency == "" && m.Amount == 0:
+		return other, nil
+	case other.Currency == "" && other.Amount == 0:
+		return m, nil
+	case m.Currency != other.Currency:
+		return Money{}, fmt.Errorf("%w: %s и %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Mul умножает сумму на целое число (например, на количество товара)
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+// MarshalJSON сериализует сумму как десятичное число, валюта передается отдельным полем
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON разбирает сумму из числа или строки; валюта задается отдельно
+func (m *Money) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "null" {
+		return nil
+	}
+	amount, err := parseMinorUnits(value)
+	if err != nil {
+		return err
+	}
+	m.Amount = amount
+	return nil
+}
+
+// Value сохраняет сумму в колонку DECIMAL в виде десятичной строки
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan читает сумму из колонки DECIMAL; валюта хранится в отдельной колонке
+// и заполняется вызывающим кодом
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		m.Amount = 0
+		return nil
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		m.Amount = v * moneyFactor
+		return nil
+	default:
+		return fmt.Errorf("%w: неподдерживаемый тип %T", ErrInvalidMoney, src)
+	}
+}
+
+// scanString разбирает десятичную строку, полученную из базы данных
+func (m *Money) scanString(value string) error {
+	amount, err := parseMinorUnits(value)
+	if err != nil {
+		return err
+	}
+	m.Amount = amount
+	return nil
+}
@@ -4,6 +4,26 @@ import (
 	"time"
 )
 
+// Статусы заказа
+const (
+	OrderStatusNew          = "new"
+	OrderStatusProcessing   = "processing"
+	OrderStatusInProduction = "in_production"
+	OrderStatusReady        = "ready" // изготовлен, ожидает выдачи или отправки
+	OrderStatusCompleted    = "completed"
+	OrderStatusCancelled    = "cancelled"
+)
+
+// OrderStatuses допустимые статусы заказа
+var OrderStatuses = []string{
+	OrderStatusNew,
+	OrderStatusProcessing,
+	OrderStatusInProduction,
+	OrderStatusReady,
+	OrderStatusCompleted,
+	OrderStatusCancelled,
+}
+
 // Order представляет заказ
 type Order struct {
 	ID        int64     `json:"id" db:"id"`
@@ -12,28 +32,76 @@ type Order struct {
 	Phone     string    `json:"phone" db:"phone"`
 	Comment   string    `json:"comment" db:"comment"`
 	Status    string    `json:"status" db:"status"`
-	TotalCost float64   `json:"total_cost" db:"total_cost"`
+	TotalCost Money     `json:"total_cost" db:"total_cost"`
+	Currency  string    `json:"currency" db:"currency"`
 	Language  string    `json:"language" db:"language"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 
+	// Сумма товаров до скидки и скидка по промокоду; TotalCost = Subtotal - Discount + ShippingCost
+	Subtotal  Money  `json:"subtotal" db:"subtotal"`
+	Discount  Money  `json:"discount" db:"discount"`
+	PromoCode string `json:"promo_code,omitempty" db:"promo_code"`
+
+	// Способ доставки (код и название на момент заказа), стоимость и адрес (nil для самовывоза)
+	ShippingMethod     string   `json:"shipping_method,omitempty" db:"shipping_method"`
+	ShippingMethodName string   `json:"shipping_method_name,omitempty" db:"shipping_method_name"`
+	ShippingCost       Money    `json:"shipping_cost" db:"shipping_cost"`
+	ShippingAddress    *Address `json:"shipping_address,omitempty" db:"shipping_address"`
+
+	// Статус онлайн-оплаты (unpaid, pending, paid, failed, partially_refunded, refunded)
+	PaymentStatus string `json:"payment_status" db:"payment_status"`
+
 	// Связанные данные
 	Items []OrderItem `json:"items" db:"-"`
 }
 
 // OrderItem представляет товар в заказе
 type OrderItem struct {
-	ID        int64   `json:"id" db:"id"`
-	OrderID   int64   `json:"-" db:"order_id"`
-	ProductID int64   `json:"product_id" db:"product_id"`
-	Quantity  int     `json:"quantity" db:"quantity"`
-	Price     float64 `json:"price" db:"price"`
+	ID        int64  `json:"id" db:"id"`
+	OrderID   int64  `json:"-" db:"order_id"`
+	ProductID int64  `json:"product_id" db:"product_id"`
+	Quantity  int    `json:"quantity" db:"quantity"`
+	Price     Money  `json:"price" db:"price"`
+	Currency  string `json:"currency" db:"currency"`
+
+	// Выбранный вариант товара; SKU и название варианта сохраняются на момент заказа
+	VariantID   *int64 `json:"variant_id,omitempty" db:"variant_id"`
+	SKU         string `json:"sku,omitempty" db:"sku"`
+	VariantName string `json:"variant_name,omitempty" db:"variant_name"`
+
+	// Значения полей персонализации (текст гравировки, шрифт, цвет, загруженный файл)
+	Personalization Personalization `json:"personalization,omitempty" db:"personalization"`
+
+	// Прикрепленный автоматический расчет стоимости (3D-печать, лазерная обработка);
+	// цена позиции в этом случае берется из расчета
+	EstimateID *string `json:"estimate_id,omitempty" db:"estimate_id"`
+
+	// Часть скидки заказа, приходящаяся на позицию (за все количество)
+	Discount Money `json:"discount" db:"discount"`
+
+	// Категория и подкатегория товара для проверки условий промокода (заполняются при расчете)
+	CategoryID    int64  `json:"-" db:"-"`
+	SubcategoryID *int64 `json:"-" db:"-"`
+
+	// Остаток списан со склада при создании заказа и возвращается при отмене
+	Reserved bool `json:"-" db:"reserved"`
+	// Остаток после списания (заполняется при создании заказа для учитываемых остатков)
+	StockLeft *int `json:"-" db:"-"`
 
 	// Дополнительная информация о товаре (заполняется при запросе)
 	ProductName  string `json:"product_name,omitempty" db:"-"`
 	ProductImage string `json:"product_image,omitempty" db:"-"`
 }
 
+// DisplayName возвращает название позиции с выбранным вариантом, например «Доска (Размер: L)»
+func (i OrderItem) DisplayName() string {
+	if i.VariantName == "" {
+		return i.ProductName
+	}
+	return i.ProductName + " (" + i.VariantName + ")"
+}
+
 // OrderRequest представляет запрос на создание заказа
 type OrderRequest struct {
 	Name     string      `json:"name" binding:"required"`
@@ -42,6 +110,30 @@ type OrderRequest struct {
 	Comment  string      `json:"comment"`
 	Language string      `json:"language"`
 	Items    []OrderItem `json:"items,omitempty"`
+
+	// Промокод, который применяется к заказу
+	PromoCode string `json:"promo_code"`
+
+	// Код способа доставки и адрес (не нужен для самовывоза)
+	ShippingMethod string   `json:"shipping_method"`
+	Address        *Address `json:"address"`
+
+	// Черновик заказа, из которого оформлен заказ
+	DraftID string `json:"draft_id"`
+}
+
+// OrderList представляет страницу списка заказов
+type OrderList struct {
+	Items      []Order `json:"items"`
+	TotalItems int     `json:"total_items"`
+	Page       int     `json:"page"`
+	PageSize   int     `json:"page_size"`
+	TotalPages int     `json:"total_pages"`
+}
+
+// OrderStatusRequest представляет запрос на изменение статуса заказа
+type OrderStatusRequest struct {
+	Status string `json:"status" binding:"required"`
 }
 
 // ContactFormRequest представляет форму обратной связи
@@ -58,4 +150,9 @@ type OrderResponse struct {
 	Success bool   `json:"success"`
 	OrderID int64  `json:"order_id,omitempty"`
 	Message string `json:"message,omitempty"`
+
+	// Подписанная ссылка на страницу оплаты заказа (если онлайн-оплата включена)
+	PaymentLink string `json:"payment_link,omitempty"`
+	// Подписанная ссылка на скачивание счета по заказу
+	InvoiceLink string `json:"invoice_link,omitempty"`
 }
@@ -0,0 +1,96 @@
+package models
+
+import "time"
+
+// Статусы платежа
+const (
+	PaymentStatusPending   = "pending"   // создан, покупатель еще не оплатил
+	PaymentStatusSucceeded = "succeeded" // оплачен (возможно, частично возвращен)
+	PaymentStatusFailed    = "failed"    // оплата не прошла или срок оплаты истек
+	PaymentStatusRefunded  = "refunded"  // возвращен полностью
+)
+
+// Статусы оплаты заказа
+const (
+	OrderPaymentUnpaid            = "unpaid"
+	OrderPaymentPending           = "pending"
+	OrderPaymentPaid              = "paid"
+	OrderPaymentFailed            = "failed"
+	OrderPaymentPartiallyRefunded = "partially_refunded"
+	OrderPaymentRefunded          = "refunded"
+)
+
+// Типы событий платежа, к которым приводятся события платежных провайдеров
+const (
+	PaymentEventSucceeded = "succeeded" // платеж оплачен
+	PaymentEventFailed    = "failed"    // оплата не прошла или срок оплаты истек
+	PaymentEventRefunded  = "refunded"  // по платежу выполнен возврат (полный или частичный)
+)
+
+// Payment онлайн-платеж по заказу через платежного провайдера
+type Payment struct {
+	ID       int64  `json:"id" db:"id"`
+	OrderID  int64  `json:"order_id" db:"order_id"`
+	Provider string `json:"provider" db:"provider"`
+
+	// Идентификатор платежа у провайдера и дополнительная ссылка на операцию списания
+	// (например, payment intent), по которой выполняются возвраты
+	ProviderPaymentID string `json:"provider_payment_id,omitempty" db:"provider_payment_id"`
+	ProviderReference string `json:"provider_reference,omitempty" db:"provider_reference"`
+
+	Status         string `json:"status" db:"status"`
+	Amount         Money  `json:"amount" db:"amount"`
+	RefundedAmount Money  `json:"refunded_amount" db:"refunded_amount"`
+	Currency       string `json:"currency" db:"currency"`
+
+	// Страница оплаты, на которую перенаправляется покупатель
+	ConfirmationURL string `json:"confirmation_url,omitempty" db:"confirmation_url"`
+	FailureReason   string `json:"failure_reason,omitempty" db:"failure_reason"`
+
+	// Аномалия оплаты, требующая внимания администратора (как правило, возврата денег)
+	Anomaly string `json:"anomaly,omitempty" db:"anomaly"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PaymentEvent событие платежа из вебхука провайдера
+type PaymentEvent struct {
+	// ID события у провайдера; повторно доставленные события с тем же ID не обрабатываются
+	ID   string
+	Type string
+	// Идентификатор платежа у провайдера (или ссылка на операцию списания для возвратов)
+	ProviderPaymentID string
+	// Ссылка на операцию списания, по которой выполняются возвраты (для успешной оплаты)
+	Reference string
+	// Общая сумма возвратов по платежу (для возврата)
+	RefundedAmount Money
+	// Причина неудачной оплаты
+	Reason string
+}
+
+// Аномалии оплаты: деньги получены, но заказ их не ожидал
+const (
+	PaymentAnomalyDuplicate = "Повторная оплата уже оплаченного заказа"
+	PaymentAnomalyCancelled = "Оплата отмененного заказа"
+)
+
+// PaymentEventResult результат применения события провайдера к платежу
+type PaymentEventResult struct {
+	Payment Payment
+	// Событие применено; false, если событие с этим ID уже обработано
+	Applied bool
+	// Аномалия, выявленная при обработке этого события
+	Anomaly string
+}
+
+// PaymentStartResponse ответ на запрос оплаты заказа
+type PaymentStartResponse struct {
+	PaymentID       int64  `json:"payment_id"`
+	ConfirmationURL string `json:"confirmation_url"`
+}
+
+// RefundRequest представляет запрос на возврат платежа; без суммы возвращается весь остаток
+type RefundRequest struct {
+	Amount *Money `json:"amount"`
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Типы полей персонализации
+const (
+	PersonalizationText  = "text"  // произвольный текст (гравировка, надпись)
+	PersonalizationFont  = "font"  // выбор шрифта из списка
+	PersonalizationColor = "color" // выбор цвета из списка или произвольный #RRGGBB
+	PersonalizationFile  = "file"  // загруженный клиентом файл (логотип, макет)
+)
+
+// PersonalizationField описывает поле персонализации, которое клиент заполняет при заказе товара
+type PersonalizationField struct {
+	Code     string            `json:"code"`
+	Type     string            `json:"type"`
+	Labels   map[string]string `json:"labels"` // язык -> подпись поля
+	Required bool              `json:"required"`
+
+	// Максимальная длина текста (для типа text)
+	MaxLength int `json:"max_length,omitempty"`
+	// Допустимые значения (для типов font и color)
+	Options []string `json:"options,omitempty"`
+	// Допустимые MIME-типы файла (для типа file), пусто - любые разрешенные для загрузки
+	Accept []string `json:"accept,omitempty"`
+}
+
+// Label возвращает подпись поля на указанном языке, при ее отсутствии - на запасном языке или код поля
+func (f PersonalizationField) Label(language, fallback string) string {
+	if label := f.Labels[language]; label != "" {
+		return label
+	}
+	if label := f.Labels[fallback]; label != "" {
+		return label
+	}
+	return f.Code
+}
+
+// PersonalizationSchema набор полей персонализации товара; хранится в колонке JSONB
+type PersonalizationSchema []PersonalizationField
+
+// Field возвращает поле схемы по коду
+func (s PersonalizationSchema) Field(code string) (PersonalizationField, bool) {
+	for _, field := range s {
+		if field.Code == code {
+			return field, true
+		}
+	}
+	return PersonalizationField{}, false
+}
+
+// Value реализует driver.Valuer
+func (s PersonalizationSchema) Value() (driver.Value, error) {
+	return jsonValue(s)
+}
+
+// Scan реализует sql.Scanner
+func (s *PersonalizationSchema) Scan(src interface{}) error {
+	return jsonScan(src, s)
+}
+
+// PersonalizationValue значение поля персонализации в позиции заказа.
+// Клиент передает код и значение, подпись и тип заполняются сервером на момент заказа
+type PersonalizationValue struct {
+	Code  string `json:"code"`
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Type  string `json:"type,omitempty"`
+
+	// Имя загруженного файла (для типа file), значение содержит ID загрузки
+	FileName string `json:"file_name,omitempty"`
+}
+
+// Personalization значения персонализации позиции заказа; хранятся в колонке JSONB
+type Personalization []PersonalizationValue
+
+// Value реализует driver.Valuer
+func (p Personalization) Value() (driver.Value, error) {
+	return jsonValue(p)
+}
+
+// Scan реализует sql.Scanner
+func (p *Personalization) Scan(src interface{}) error {
+	return jsonScan(src, p)
+}
+
+// Upload представляет файл, загруженный клиентом для персонализации заказа
+type Upload struct {
+	ID          string    `json:"id" db:"id"`
+	FileName    string    `json:"file_name" db:"file_name"`
+	ContentType string    `json:"content_type" db:"content_type"`
+	Size        int64     `json:"size" db:"size"`
+	Path        string    `json:"-" db:"path"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// jsonValue сериализует значение в JSON для колонки JSONB; пустое значение хранится как NULL
+func jsonValue(v interface{}) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if string(data) == "null" || string(data) == "[]" {
+		return nil, nil
+	}
+	return string(data), nil
+}
+
+// jsonScan читает значение из колонки JSONB
+func jsonScan(src interface{}, dest interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("неподдерживаемый тип значения JSON: %T", src)
+	}
+}
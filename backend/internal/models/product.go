@@ -17,10 +17,38 @@ type Product struct {
 	// Переводимые поля прямо в структуре
 	Name            string            `json:"name" db:"-"`
 	Description     string            `json:"description" db:"-"`
-	Price           float64           `json:"price" db:"-"`    // Добавляем price как переводимое поле
+	Price           Money             `json:"price" db:"-"`    // Добавляем price как переводимое поле
 	Currency        string            `json:"currency" db:"-"` // Добавляем валюту
 	Characteristics map[string]string `json:"characteristics,omitempty" db:"-"`
 
+	// Базовая цена, из которой по курсам рассчитываются цены переводов (nil - цены задаются вручную)
+	BasePrice *Money `json:"base_price,omitempty" db:"-"`
+	// Цена пересчитана в валюту, запрошенную параметром currency
+	PriceConverted bool `json:"price_converted,omitempty" db:"-"`
+
+	// Поля персонализации, которые клиент заполняет при заказе (гравировка, шрифт, логотип)
+	Personalization PersonalizationSchema `json:"personalization,omitempty" db:"-"`
+
+	// Наличие на складе: для товара с вариантами - по включенным вариантам.
+	// Доступное количество null, если остаток не учитывается
+	InStock           bool `json:"in_stock" db:"-"`
+	AvailableQuantity *int `json:"available_quantity" db:"-"`
+
+	// Минимальное количество для заказа; снятые с публикации товары не принимаются в заказы
+	MinOrderQuantity int  `json:"min_order_quantity" db:"-"`
+	Published        bool `json:"-" db:"-"`
+
+	// Вид изготовления по расчету стоимости (3d или laser); пусто для обычного товара.
+	// Только к позициям таких товаров можно прикрепить расчет стоимости того же вида
+	FabricationKind string `json:"fabrication_kind,omitempty" db:"-"`
+
+	// Средняя оценка и количество опубликованных отзывов
+	RatingAvg   float64 `json:"rating_avg" db:"-"`
+	RatingCount int     `json:"rating_count" db:"-"`
+
+	// Поля, взятые из другого языка при отсутствии перевода: поле -> фактический язык
+	FallbackFields map[string]string `json:"fallback_fields,omitempty" db:"-"`
+
 	// Сохраняем для внутреннего использования, но не возвращаем в API
 	Translations map[string]*ProductTranslation `json:"-" db:"-"`
 }
@@ -31,7 +59,7 @@ type ProductTranslation struct {
 	Language        string            `json:"-" db:"language"`
 	Name            string            `json:"name" db:"name"`
 	Description     string            `json:"description" db:"description"`
-	Price           float64           `json:"price" db:"price"`       // Добавляем цену в перевод
+	Price           Money             `json:"price" db:"price"`       // Добавляем цену в перевод
 	Currency        string            `json:"currency" db:"currency"` // Добавляем валюту
 	Characteristics map[string]string `json:"characteristics" db:"-"`
 }
@@ -46,6 +74,9 @@ type Category struct {
 	// Переводимое поле прямо в структуре
 	Name string `json:"name" db:"-"`
 
+	// Поля, взятые из другого языка при отсутствии перевода: поле -> фактический язык
+	FallbackFields map[string]string `json:"fallback_fields,omitempty" db:"-"`
+
 	// Сохраняем для внутреннего использования, но не возвращаем в API
 	Translations map[string]*CategoryTranslation `json:"-" db:"-"`
 
@@ -91,6 +122,37 @@ type ProductCharacteristic struct {
 type ProductDetail struct {
 	Product
 	RelatedProducts []Product `json:"related_products,omitempty"`
+
+	// Опции и варианты товара (размеры, материалы, отделка)
+	Options  []ProductOption  `json:"options,omitempty"`
+	Variants []ProductVariant `json:"variants,omitempty"`
+}
+
+// Допустимые значения параметра сортировки каталога
+const (
+	ProductSortNewest     = "newest"
+	ProductSortOldest     = "oldest"
+	ProductSortName       = "name"
+	ProductSortPrice      = "price"
+	ProductSortPriceDesc  = "price_desc"
+	ProductSortPopularity = "popularity"
+	ProductSortRelevance  = "relevance"
+)
+
+// productSorts белый список значений сортировки каталога
+var productSorts = map[string]bool{
+	ProductSortNewest:     true,
+	ProductSortOldest:     true,
+	ProductSortName:       true,
+	ProductSortPrice:      true,
+	ProductSortPriceDesc:  true,
+	ProductSortPopularity: true,
+	ProductSortRelevance:  true,
+}
+
+// IsValidProductSort проверяет, что значение сортировки входит в белый список
+func IsValidProductSort(sort string) bool {
+	return productSorts[sort]
 }
 
 // ProductFilter содержит параметры фильтрации товаров
@@ -98,35 +160,44 @@ type ProductFilter struct {
 	CategoryID    *int64  `form:"category"`
 	SubcategoryID *int64  `form:"subcategory"`
 	Search        *string `form:"search"`
-	SortByPrice   *string `form:"sort_price"` // "asc", "desc" или пусто
+	Sort          string  `form:"sort"` // одно из значений ProductSort*, пусто - по умолчанию
 	Page          int     `form:"page,default=1"`
 	PageSize      int     `form:"page_size,default=10"`
 	Language      string  `form:"language,default=ru"`
+
+	// Окно, за которое считается популярность товаров (задается из конфигурации)
+	PopularityWindow time.Duration `form:"-"`
 }
 
 // ProductCreateRequest представляет запрос на создание товара
 type ProductCreateRequest struct {
-	CategoryID    int64                                       `json:"category_id" binding:"required"`
-	SubcategoryID *int64                                      `json:"subcategory_id"`
-	Images        []string                                    `json:"images"`
-	Translations  map[string]*ProductTranslationCreateRequest `json:"translations" binding:"required"`
+	CategoryID      int64                                       `json:"category_id" binding:"required"`
+	SubcategoryID   *int64                                      `json:"subcategory_id"`
+	Images          []string                                    `json:"images"`
+	BasePrice       *Money                                      `json:"base_price"`    // если задана, цены переводов рассчитываются по курсам
+	BaseCurrency    string                                      `json:"base_currency"` // валюта базовой цены
+	Personalization PersonalizationSchema                       `json:"personalization"`
+	Translations    map[string]*ProductTranslationCreateRequest `json:"translations" binding:"required"`
 }
 
 // ProductTranslationCreateRequest представляет перевод товара при создании
 type ProductTranslationCreateRequest struct {
 	Name            string            `json:"name" binding:"required"`
 	Description     string            `json:"description"`
-	Price           float64           `json:"price" binding:"required,min=0"` // Добавляем цену
-	Currency        string            `json:"currency" binding:"required"`    // Добавляем валюту
+	Price           *Money            `json:"price"`    // Обязательна, если у товара нет базовой цены
+	Currency        string            `json:"currency"` // Обязательна, если у товара нет базовой цены
 	Characteristics map[string]string `json:"characteristics"`
 }
 
 // ProductUpdateRequest представляет запрос на обновление товара
 type ProductUpdateRequest struct {
-	CategoryID    *int64                                      `json:"category_id,omitempty"`
-	SubcategoryID *int64                                      `json:"subcategory_id,omitempty"`
-	Images        []string                                    `json:"images,omitempty"`
-	Translations  map[string]*ProductTranslationUpdateRequest `json:"translations,omitempty"`
+	CategoryID      *int64                                      `json:"category_id,omitempty"`
+	SubcategoryID   *int64                                      `json:"subcategory_id,omitempty"`
+	Images          []string                                    `json:"images,omitempty"`
+	BasePrice       *Money                                      `json:"base_price,omitempty"`
+	BaseCurrency    *string                                     `json:"base_currency,omitempty"`   // пустая строка отключает пересчет из базовой цены
+	Personalization PersonalizationSchema                       `json:"personalization,omitempty"` // пустой список удаляет персонализацию
+	Translations    map[string]*ProductTranslationUpdateRequest `json:"translations,omitempty"`
 }
 
 // ProductTranslationUpdateRequest представляет перевод товара при обновлении
@@ -134,7 +205,15 @@ type ProductUpdateRequest struct {
 type ProductTranslationUpdateRequest struct {
 	Name            *string           `json:"name,omitempty"`
 	Description     *string           `json:"description,omitempty"`
-	Price           *float64          `json:"price,omitempty"`
+	Price           *Money            `json:"price,omitempty"`
 	Currency        *string           `json:"currency,omitempty"`
 	Characteristics map[string]string `json:"characteristics,omitempty"`
 }
+
+// ProductOrderSettings представляет публикацию товара, минимальное количество для заказа
+// и вид изготовления по расчету стоимости
+type ProductOrderSettings struct {
+	Published        *bool  `json:"published" binding:"required"`
+	MinOrderQuantity int    `json:"min_order_quantity" binding:"required,min=1"`
+	FabricationKind  string `json:"fabrication_kind" binding:"omitempty,oneof=3d laser"`
+}
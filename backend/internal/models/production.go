@@ -0,0 +1,158 @@
+package models
+
+import (
+	"time"
+)
+
+// Статусы производственного задания
+const (
+	JobStatusQueued     = "queued"      // ожидает планирования (нет подходящего станка)
+	JobStatusScheduled  = "scheduled"   // поставлено в очередь станка
+	JobStatusInProgress = "in_progress" // выполняется
+	JobStatusDone       = "done"        // выполнено
+	JobStatusCancelled  = "cancelled"   // отменено
+)
+
+// JobStatuses допустимые статусы производственного задания
+var JobStatuses = []string{
+	JobStatusQueued,
+	JobStatusScheduled,
+	JobStatusInProgress,
+	JobStatusDone,
+	JobStatusCancelled,
+}
+
+// Machine станок мастерской (лазерный станок, 3D-принтер, рабочее место сборки)
+type Machine struct {
+	ID   int64  `json:"id" db:"id"`
+	Code string `json:"code" db:"code"`
+	Name string `json:"name" db:"name"`
+
+	// Виды работ, которые выполняет станок (например, laser, 3d, assembly);
+	// задание ставится только на станок с его видом работ
+	Capabilities []string `json:"capabilities" db:"-"`
+	Enabled      bool     `json:"enabled" db:"enabled"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// MachineRequest представляет запрос на создание или изменение станка
+type MachineRequest struct {
+	Name         string   `json:"name" binding:"required,max=255"`
+	Capabilities []string `json:"capabilities" binding:"required,min=1"`
+	Enabled      *bool    `json:"enabled"`
+}
+
+// ProductionJob производственное задание по позиции заказа
+type ProductionJob struct {
+	ID          int64  `json:"id" db:"id"`
+	OrderID     int64  `json:"order_id" db:"order_id"`
+	OrderItemID int64  `json:"order_item_id" db:"order_item_id"`
+	Kind        string `json:"kind" db:"kind"`
+	Title       string `json:"title" db:"title"`
+	Quantity    int    `json:"quantity" db:"quantity"`
+	Status      string `json:"status" db:"status"`
+
+	// Чем больше приоритет, тем раньше задание ставится в очередь
+	Priority         int        `json:"priority" db:"priority"`
+	DueDate          *time.Time `json:"due_date,omitempty" db:"due_date"`
+	EstimatedMinutes int        `json:"estimated_minutes" db:"estimated_minutes"`
+
+	// Станок; если он назначен вручную (MachineLocked), планировщик его не меняет
+	MachineID     *int64 `json:"machine_id,omitempty" db:"machine_id"`
+	MachineLocked bool   `json:"machine_locked" db:"machine_locked"`
+
+	ScheduledStart *time.Time `json:"scheduled_start,omitempty" db:"scheduled_start"`
+	ScheduledEnd   *time.Time `json:"scheduled_end,omitempty" db:"scheduled_end"`
+	StartedAt      *time.Time `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+
+	// Задание не успевает к сроку по текущему плану (заполняется при запросе)
+	Late bool `json:"late" db:"-"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CreateJobsRequest представляет запрос на создание заданий по позициям заказа
+type CreateJobsRequest struct {
+	Priority int `json:"priority"`
+	// Срок изготовления в формате YYYY-MM-DD
+	DueDate string `json:"due_date"`
+}
+
+// CreateJobsResponse результат создания заданий по заказу
+type CreateJobsResponse struct {
+	Jobs []ProductionJob `json:"jobs"`
+	// Позиции, для которых задание не создано: нет расчета и не заданы параметры производства товара
+	// либо задание уже существует
+	SkippedItems []int64 `json:"skipped_items"`
+}
+
+// JobUpdateRequest представляет запрос на изменение задания; пустые поля не меняются.
+// Для снятия ручного назначения станка передается machine_id = 0
+type JobUpdateRequest struct {
+	Status           *string `json:"status"`
+	MachineID        *int64  `json:"machine_id"`
+	Priority         *int    `json:"priority"`
+	DueDate          *string `json:"due_date"`
+	EstimatedMinutes *int    `json:"estimated_minutes" binding:"omitempty,gt=0"`
+}
+
+// JobSlot место задания в плане производства
+type JobSlot struct {
+	JobID     int64
+	MachineID *int64
+	Start     *time.Time
+	End       *time.Time
+}
+
+// ProductionSettings параметры изготовления товара каталога: вид работ и время на единицу
+type ProductionSettings struct {
+	Kind    string `json:"kind" db:"production_kind"`
+	Minutes int    `json:"minutes" db:"production_minutes"`
+}
+
+// ProductionSettingsRequest представляет запрос на изменение параметров изготовления товара;
+// пустой вид работ означает, что товар не изготавливается (продается со склада)
+type ProductionSettingsRequest struct {
+	Kind    string `json:"kind" binding:"max=50"`
+	Minutes int    `json:"minutes" binding:"gte=0"`
+}
+
+// MachineTimeline очередь заданий станка
+type MachineTimeline struct {
+	Machine Machine         `json:"machine"`
+	Jobs    []ProductionJob `json:"jobs"`
+}
+
+// ProductionTimeline план производства за период
+type ProductionTimeline struct {
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Machines []MachineTimeline `json:"machines"`
+	// Задания, для которых нет подходящего станка
+	Unscheduled []ProductionJob `json:"unscheduled"`
+}
+
+// ProductionJobFilter параметры выборки производственных заданий
+type ProductionJobFilter struct {
+	OrderID *int64
+	// Период плана: задания, запланированные или выполненные в этом периоде, а также все
+	// ожидающие и выполняемые задания
+	From, To *time.Time
+	// Только незавершенные задания (queued, scheduled, in_progress)
+	ActiveOnly bool
+}
+
+// JobUpdate изменения производственного задания; nil - поле не меняется
+type JobUpdate struct {
+	Status *string
+	// Станок, назначенный вручную; 0 - снять ручное назначение
+	MachineID        *int64
+	Priority         *int
+	DueDate          *time.Time
+	ClearDueDate     bool
+	EstimatedMinutes *int
+}
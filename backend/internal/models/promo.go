@@ -0,0 +1,132 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// Виды промокодов
+const (
+	PromoKindPercent  = "percent"   // скидка в процентах от подходящих товаров
+	PromoKindFixed    = "fixed"     // фиксированная скидка, сумма задается для каждой валюты
+	PromoKindFreeItem = "free_item" // одна единица указанного товара бесплатно
+)
+
+// PromoKinds допустимые виды промокодов
+var PromoKinds = []string{PromoKindPercent, PromoKindFixed, PromoKindFreeItem}
+
+// CurrencyAmounts суммы по валютам (код валюты -> сумма), хранятся в JSONB
+type CurrencyAmounts map[string]Money
+
+// Get возвращает сумму в указанной валюте
+func (a CurrencyAmounts) Get(currency string) (Money, bool) {
+	amount, ok := a[currency]
+	if !ok {
+		return Money{}, false
+	}
+	return NewMoney(amount.Amount, currency), true
+}
+
+// Value реализует driver.Valuer
+func (a CurrencyAmounts) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return nil, nil
+	}
+	return jsonValue(a)
+}
+
+// Scan реализует sql.Scanner; валюта сумм восстанавливается по ключам
+func (a *CurrencyAmounts) Scan(src interface{}) error {
+	if err := jsonScan(src, a); err != nil {
+		return err
+	}
+	for currency, amount := range *a {
+		(*a)[currency] = NewMoney(amount.Amount, currency)
+	}
+	return nil
+}
+
+// PromoCode промокод и условия его применения
+type PromoCode struct {
+	ID          int64  `json:"id" db:"id"`
+	Code        string `json:"code" db:"code"`
+	Description string `json:"description" db:"description"`
+	Kind        string `json:"kind" db:"kind"`
+
+	// Процент скидки (для percent)
+	Percent int `json:"percent,omitempty" db:"percent"`
+	// Сумма скидки по валютам (для fixed)
+	Amounts CurrencyAmounts `json:"amounts,omitempty" db:"amounts"`
+	// Бесплатный товар (для free_item); товар должен быть в корзине
+	FreeProductID *int64 `json:"free_product_id,omitempty" db:"free_product_id"`
+
+	// Минимальная сумма заказа по валютам; если задана, в других валютах промокод не действует
+	MinOrderAmounts CurrencyAmounts `json:"min_order_amounts,omitempty" db:"min_order_amounts"`
+	// Категории и подкатегории, на товары которых действует промокод (пусто - на все товары)
+	CategoryIDs []int64 `json:"category_ids,omitempty" db:"-"`
+
+	// Период действия (nil - без ограничения)
+	StartsAt *time.Time `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+
+	// Ограничения количества использований: всего и на один email (nil - без ограничения)
+	MaxUses         *int `json:"max_uses,omitempty" db:"max_uses"`
+	MaxUsesPerEmail *int `json:"max_uses_per_email,omitempty" db:"max_uses_per_email"`
+	// Количество использований в неотмененных заказах
+	Uses int `json:"uses" db:"uses"`
+
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PromoCodeRequest представляет запрос на создание или изменение промокода
+type PromoCodeRequest struct {
+	Description     string           `json:"description" binding:"max=500"`
+	Kind            string           `json:"kind" binding:"required"`
+	Percent         int              `json:"percent" binding:"gte=0,lte=100"`
+	Amounts         map[string]Money `json:"amounts"`
+	FreeProductID   *int64           `json:"free_product_id"`
+	MinOrderAmounts map[string]Money `json:"min_order_amounts"`
+	CategoryIDs     []int64          `json:"category_ids"`
+	StartsAt        *time.Time       `json:"starts_at"`
+	EndsAt          *time.Time       `json:"ends_at"`
+	MaxUses         *int             `json:"max_uses" binding:"omitempty,gt=0"`
+	MaxUsesPerEmail *int             `json:"max_uses_per_email" binding:"omitempty,gt=0"`
+	Enabled         *bool            `json:"enabled"`
+}
+
+// AppliedDiscount скидка, примененная к заказу по промокоду
+type AppliedDiscount struct {
+	Code        string `json:"code"`
+	Kind        string `json:"kind"`
+	Description string `json:"description,omitempty"`
+	Amount      Money  `json:"amount"`
+}
+
+// CartQuoteRequest представляет запрос на предварительный расчет корзины
+type CartQuoteRequest struct {
+	Items     []OrderItem `json:"items" binding:"required,min=1"`
+	PromoCode string      `json:"promo_code"`
+	// Email покупателя для проверки ограничения использований промокода на один email
+	Email    string `json:"email" binding:"omitempty,email"`
+	Language string `json:"language"`
+
+	// Способ доставки и адрес для расчета стоимости доставки (необязательно)
+	ShippingMethod string   `json:"shipping_method"`
+	Address        *Address `json:"address"`
+}
+
+// CartQuote предварительный расчет корзины со скидкой и доставкой
+type CartQuote struct {
+	Items    []OrderItem      `json:"items"`
+	Subtotal Money            `json:"subtotal"`
+	Discount *AppliedDiscount `json:"discount,omitempty"`
+	Shipping *ShippingQuote   `json:"shipping,omitempty"`
+	Total    Money            `json:"total"`
+	Currency string           `json:"currency"`
+
+	// Причины, по которым промокод не применен или доставка не рассчитана
+	PromoError    string `json:"promo_error,omitempty"`
+	ShippingError string `json:"shipping_error,omitempty"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+)
+
+// Статусы запроса на расчет
+const (
+	QuoteStatusNew      = "new"      // запрос получен, ожидает расчета
+	QuoteStatusQuoted   = "quoted"   // клиенту отправлено предложение с ценой
+	QuoteStatusAccepted = "accepted" // клиент принял предложение, создан заказ
+)
+
+// QuoteRequest представляет запрос клиента на расчет изготовления изделия не из каталога
+type QuoteRequest struct {
+	ID          int64  `json:"id" db:"id"`
+	Name        string `json:"name" db:"name"`
+	Email       string `json:"email" db:"email"`
+	Phone       string `json:"phone" db:"phone"`
+	Description string `json:"description" db:"description"`
+	Material    string `json:"material" db:"material"`
+	Quantity    int    `json:"quantity" db:"quantity"`
+	Language    string `json:"language" db:"language"`
+	Status      string `json:"status" db:"status"`
+
+	// Предложение администратора: цена за весь тираж, комментарий и срок действия
+	Price        *Money     `json:"price,omitempty" db:"-"`
+	AdminComment string     `json:"admin_comment,omitempty" db:"admin_comment"`
+	ValidUntil   *time.Time `json:"valid_until,omitempty" db:"valid_until"`
+	QuotedAt     *time.Time `json:"quoted_at,omitempty" db:"quoted_at"`
+
+	// Заказ, созданный после принятия предложения
+	OrderID    *int64     `json:"order_id,omitempty" db:"order_id"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Файлы моделей и макетов
+	Files []Upload `json:"files" db:"-"`
+}
+
+// QuoteRequestCreateRequest представляет запрос клиента на расчет
+type QuoteRequestCreateRequest struct {
+	Name        string   `json:"name" binding:"required,max=255"`
+	Email       string   `json:"email" binding:"required,email"`
+	Phone       string   `json:"phone" binding:"required,max=50"`
+	Description string   `json:"description" binding:"required,max=5000"`
+	Material    string   `json:"material" binding:"max=255"`
+	Quantity    int      `json:"quantity" binding:"required,min=1,max=100000"`
+	Language    string   `json:"language"`
+	Files       []string `json:"files" binding:"required,min=1,max=10"` // ID загруженных файлов
+}
+
+// QuoteReplyRequest представляет предложение администратора по запросу на расчет
+type QuoteReplyRequest struct {
+	Price      *Money     `json:"price" binding:"required"`
+	Currency   string     `json:"currency" binding:"required,len=3"`
+	Comment    string     `json:"comment"`
+	ValidUntil *time.Time `json:"valid_until"` // по умолчанию - через срок из настроек
+}
+
+// QuoteAcceptResponse представляет ответ после принятия предложения
+type QuoteAcceptResponse struct {
+	Success bool   `json:"success"`
+	OrderID int64  `json:"order_id"`
+	Message string `json:"message,omitempty"`
+
+	// Подписанная ссылка на страницу оплаты заказа (если онлайн-оплата включена)
+	PaymentLink string `json:"payment_link,omitempty"`
+	// Подписанная ссылка на скачивание счета по заказу
+	InvoiceLink string `json:"invoice_link,omitempty"`
+}
@@ -0,0 +1,97 @@
+package models
+
+import "time"
+
+// Статусы отзыва
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+)
+
+// ReviewStatuses допустимые статусы отзыва
+var ReviewStatuses = []string{
+	ReviewStatusPending,
+	ReviewStatusApproved,
+	ReviewStatusRejected,
+}
+
+// Review отзыв о товаре. Отзыв оставляет покупатель выполненного заказа по подписанной ссылке
+// из письма, на сайте он показывается после одобрения администратором
+type Review struct {
+	ID         int64  `json:"id" db:"id"`
+	ProductID  int64  `json:"product_id" db:"product_id"`
+	OrderID    int64  `json:"order_id,omitempty" db:"order_id"`
+	AuthorName string `json:"author_name" db:"author_name"`
+	Rating     int    `json:"rating" db:"rating"`
+	Text       string `json:"text" db:"text"`
+	Language   string `json:"language" db:"language"`
+	Status     string `json:"status,omitempty" db:"status"`
+
+	// Ответ магазина на отзыв
+	Reply     string     `json:"reply,omitempty" db:"reply"`
+	RepliedAt *time.Time `json:"replied_at,omitempty" db:"replied_at"`
+
+	ModeratedAt *time.Time `json:"moderated_at,omitempty" db:"moderated_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Ссылки на фотографии к отзыву
+	Photos []string `json:"photos" db:"-"`
+	// ID загруженных файлов с фотографиями (для сохранения отзыва)
+	PhotoIDs []string `json:"-" db:"-"`
+
+	// Название товара (заполняется в очереди модерации)
+	ProductName string `json:"product_name,omitempty" db:"product_name"`
+}
+
+// ReviewList представляет страницу списка отзывов
+type ReviewList struct {
+	Items      []Review `json:"items"`
+	TotalItems int      `json:"total_items"`
+	Page       int      `json:"page"`
+	PageSize   int      `json:"page_size"`
+	TotalPages int      `json:"total_pages"`
+}
+
+// ReviewRequest представляет запрос на создание отзыва о товаре из заказа
+type ReviewRequest struct {
+	ProductID  int64    `json:"product_id" binding:"required"`
+	Rating     int      `json:"rating" binding:"required,min=1,max=5"`
+	Text       string   `json:"text" binding:"max=5000"`
+	AuthorName string   `json:"author_name" binding:"max=100"`
+	Language   string   `json:"language"`
+	Photos     []string `json:"photos"`
+}
+
+// ReviewModerationRequest представляет запрос на модерацию отзыва: изменение статуса и (или) ответ магазина
+type ReviewModerationRequest struct {
+	Status string  `json:"status" binding:"omitempty,oneof=approved rejected"`
+	Reply  *string `json:"reply" binding:"omitempty,max=5000"`
+}
+
+// ReviewOrder товары выполненного заказа, о которых покупатель может оставить отзыв
+type ReviewOrder struct {
+	OrderID int64             `json:"order_id"`
+	Name    string            `json:"name"`
+	Items   []ReviewOrderItem `json:"items"`
+}
+
+// ReviewOrderItem товар заказа для отзыва
+type ReviewOrderItem struct {
+	ProductID    int64  `json:"product_id"`
+	ProductName  string `json:"product_name"`
+	ProductImage string `json:"product_image,omitempty"`
+	// Отзыв о товаре по этому заказу уже оставлен
+	Reviewed bool `json:"reviewed"`
+}
+
+// ReviewInvitation письмо с приглашением оставить отзыв о товарах выполненного заказа
+type ReviewInvitation struct {
+	OrderID  int64
+	Email    string
+	Name     string
+	Language string
+	Products []string
+	URL      string
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// Виды способов доставки
+const (
+	ShippingKindPickup  = "pickup"  // самовывоз из мастерской
+	ShippingKindCourier = "courier" // курьер по зонам (страна, города)
+	ShippingKindPostal  = "postal"  // почта: базовая стоимость и стоимость за килограмм
+)
+
+// ShippingKinds допустимые виды способов доставки
+var ShippingKinds = []string{ShippingKindPickup, ShippingKindCourier, ShippingKindPostal}
+
+// Address адрес доставки
+type Address struct {
+	// Код страны ISO 3166-1 alpha-2
+	Country    string `json:"country" binding:"required,len=2"`
+	Region     string `json:"region,omitempty" binding:"max=100"`
+	City       string `json:"city" binding:"required,max=100"`
+	PostalCode string `json:"postal_code,omitempty" binding:"max=20"`
+	Street     string `json:"street" binding:"required,max=255"`
+	// Квартира, офис, подъезд
+	Apartment string `json:"apartment,omitempty" binding:"max=100"`
+}
+
+// Value реализует driver.Valuer
+func (a Address) Value() (driver.Value, error) {
+	return jsonValue(a)
+}
+
+// Scan реализует sql.Scanner
+func (a *Address) Scan(src interface{}) error {
+	return jsonScan(src, a)
+}
+
+// ShippingRule правило расчета стоимости доставки для страны и городов.
+// Стоимость: базовая + за каждый начатый килограмм; при сумме товаров от FreeFrom доставка бесплатна
+type ShippingRule struct {
+	// Коды стран (пусто - любая страна) и города (пусто - любой город)
+	Countries []string `json:"countries,omitempty"`
+	Cities    []string `json:"cities,omitempty"`
+
+	BasePrice  CurrencyAmounts `json:"base_price"`
+	PricePerKg CurrencyAmounts `json:"price_per_kg,omitempty"`
+	FreeFrom   CurrencyAmounts `json:"free_from,omitempty"`
+
+	// Максимальный вес посылки, г (0 - без ограничения)
+	MaxWeightGrams int `json:"max_weight_grams,omitempty"`
+	// Срок доставки для покупателя, например «2-4 дня»
+	DeliveryDays string `json:"delivery_days,omitempty"`
+}
+
+// ShippingRules правила способа доставки; применяется первое подходящее по адресу
+type ShippingRules []ShippingRule
+
+// Value реализует driver.Valuer
+func (r ShippingRules) Value() (driver.Value, error) {
+	return jsonValue(r)
+}
+
+// Scan реализует sql.Scanner; валюта сумм восстанавливается по ключам
+func (r *ShippingRules) Scan(src interface{}) error {
+	if err := jsonScan(src, r); err != nil {
+		return err
+	}
+	for _, rule := range *r {
+		for _, amounts := range []CurrencyAmounts{rule.BasePrice, rule.PricePerKg, rule.FreeFrom} {
+			for currency, amount := range amounts {
+				amounts[currency] = NewMoney(amount.Amount, currency)
+			}
+		}
+	}
+	return nil
+}
+
+// ShippingMethod способ доставки
+type ShippingMethod struct {
+	ID          int64  `json:"id" db:"id"`
+	Code        string `json:"code" db:"code"`
+	Name        string `json:"name" db:"name"`
+	Kind        string `json:"kind" db:"kind"`
+	Description string `json:"description,omitempty" db:"description"`
+
+	// Адрес пункта самовывоза
+	PickupAddress string `json:"pickup_address,omitempty" db:"pickup_address"`
+
+	Rules     ShippingRules `json:"rules" db:"rules"`
+	SortOrder int           `json:"sort_order" db:"sort_order"`
+	Enabled   bool          `json:"enabled" db:"enabled"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ShippingMethodRequest представляет запрос на создание или изменение способа доставки
+type ShippingMethodRequest struct {
+	Name          string         `json:"name" binding:"required,max=255"`
+	Kind          string         `json:"kind" binding:"required"`
+	Description   string         `json:"description" binding:"max=1000"`
+	PickupAddress string         `json:"pickup_address" binding:"max=500"`
+	Rules         []ShippingRule `json:"rules"`
+	SortOrder     int            `json:"sort_order"`
+	Enabled       *bool          `json:"enabled"`
+}
+
+// ProductDimensions вес и габариты товара в упаковке для расчета доставки (nil - не заданы)
+type ProductDimensions struct {
+	WeightGrams *int `json:"weight_grams" db:"weight_grams" binding:"omitempty,gt=0"`
+	LengthMM    *int `json:"length_mm" db:"length_mm" binding:"omitempty,gt=0"`
+	WidthMM     *int `json:"width_mm" db:"width_mm" binding:"omitempty,gt=0"`
+	HeightMM    *int `json:"height_mm" db:"height_mm" binding:"omitempty,gt=0"`
+}
+
+// ShippingQuote стоимость доставки заказа выбранным способом
+type ShippingQuote struct {
+	Method       string `json:"method"`
+	Name         string `json:"name"`
+	Kind         string `json:"kind"`
+	Cost         Money  `json:"cost"`
+	WeightGrams  int    `json:"weight_grams"`
+	DeliveryDays string `json:"delivery_days,omitempty"`
+
+	PickupAddress string `json:"pickup_address,omitempty"`
+}
+
+// ShippingOption способ доставки с рассчитанной стоимостью или причиной недоступности
+type ShippingOption struct {
+	ShippingQuote
+	Description string `json:"description,omitempty"`
+	Available   bool   `json:"available"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// ShippingOptionsRequest представляет запрос на расчет доставки корзины всеми способами
+type ShippingOptionsRequest struct {
+	Items     []OrderItem `json:"items" binding:"required,min=1"`
+	Address   *Address    `json:"address"`
+	PromoCode string      `json:"promo_code"`
+	Language  string      `json:"language"`
+}
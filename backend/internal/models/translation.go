@@ -0,0 +1,16 @@
+package models
+
+// MissingTranslation описывает сущность, у которой нет перевода на язык
+type MissingTranslation struct {
+	ID int64 `json:"id" db:"id"`
+	// Название на одном из доступных языков, чтобы сущность можно было узнать
+	Name string `json:"name" db:"name"`
+}
+
+// MissingTranslationsReport содержит список сущностей без перевода на указанный язык
+type MissingTranslationsReport struct {
+	Language     string               `json:"language"`
+	Products     []MissingTranslation `json:"products"`
+	GalleryItems []MissingTranslation `json:"gallery_items"`
+	Categories   []MissingTranslation `json:"categories"`
+}
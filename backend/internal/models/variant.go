@@ -0,0 +1,128 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Способы задания цены варианта
+const (
+	VariantPriceDelta    = "delta"    // надбавка к цене товара (может быть отрицательной)
+	VariantPriceAbsolute = "absolute" // собственная цена варианта
+)
+
+// ProductOption представляет опцию товара (размер, материал, отделка)
+type ProductOption struct {
+	ID        int64                `json:"id" db:"id"`
+	ProductID int64                `json:"-" db:"product_id"`
+	Code      string               `json:"code" db:"code"`
+	Name      string               `json:"name" db:"name"`
+	SortOrder int                  `json:"sort_order" db:"sort_order"`
+	Values    []ProductOptionValue `json:"values"`
+
+	// Поля, взятые из другого языка при отсутствии перевода: поле -> фактический язык
+	FallbackFields map[string]string `json:"fallback_fields,omitempty" db:"-"`
+}
+
+// ProductOptionValue представляет значение опции товара
+type ProductOptionValue struct {
+	ID        int64  `json:"id" db:"id"`
+	OptionID  int64  `json:"-" db:"option_id"`
+	Code      string `json:"code" db:"code"`
+	Name      string `json:"name" db:"name"`
+	SortOrder int    `json:"sort_order" db:"sort_order"`
+
+	// Поля, взятые из другого языка при отсутствии перевода: поле -> фактический язык
+	FallbackFields map[string]string `json:"fallback_fields,omitempty" db:"-"`
+}
+
+// ProductVariant представляет конкретное сочетание значений опций товара
+type ProductVariant struct {
+	ID        int64  `json:"id" db:"id"`
+	ProductID int64  `json:"-" db:"product_id"`
+	SKU       string `json:"sku" db:"sku"`
+
+	// Цена в том виде, как ее задал администратор: надбавка или собственная цена
+	PriceMode     string `json:"price_mode" db:"price_mode"`
+	PriceValue    Money  `json:"price_value" db:"price_value"`
+	PriceCurrency string `json:"price_currency" db:"price_currency"`
+
+	// Итоговая цена варианта в валюте товара (рассчитывается при запросе)
+	Price    Money  `json:"price" db:"-"`
+	Currency string `json:"currency" db:"-"`
+
+	// Остаток на складе, nil - не учитывается
+	Stock     *int      `json:"stock,omitempty" db:"stock"`
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Выбранные значения опций: код опции -> код значения
+	Options  map[string]string `json:"options" db:"-"`
+	ValueIDs []int64           `json:"value_ids" db:"-"`
+}
+
+// InStock проверяет, что варианта достаточно на складе для указанного количества
+func (v ProductVariant) InStock(quantity int) bool {
+	return v.Stock == nil || *v.Stock >= quantity
+}
+
+// VariantName возвращает название варианта из названий опций и значений,
+// например «Размер: 30x40, Материал: Дерево»
+func (d ProductDetail) VariantName(variant ProductVariant) string {
+	parts := make([]string, 0, len(d.Options))
+	for _, option := range d.Options {
+		code, ok := variant.Options[option.Code]
+		if !ok {
+			continue
+		}
+		for _, value := range option.Values {
+			if value.Code == code {
+				parts = append(parts, option.Name+": "+value.Name)
+				break
+			}
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FindVariant возвращает вариант товара по ID
+func (d ProductDetail) FindVariant(id int64) (ProductVariant, bool) {
+	for _, variant := range d.Variants {
+		if variant.ID == id {
+			return variant, true
+		}
+	}
+	return ProductVariant{}, false
+}
+
+// ProductVariantsRequest представляет запрос на замену опций и вариантов товара.
+// Опции и значения сопоставляются по коду, варианты - по SKU; отсутствующие в запросе удаляются
+type ProductVariantsRequest struct {
+	Options  []ProductOptionRequest  `json:"options" binding:"dive"`
+	Variants []ProductVariantRequest `json:"variants" binding:"dive"`
+}
+
+// ProductOptionRequest представляет опцию товара в запросе
+type ProductOptionRequest struct {
+	Code         string                      `json:"code" binding:"required,max=50"`
+	Translations map[string]string           `json:"translations" binding:"required"` // язык -> название
+	Values       []ProductOptionValueRequest `json:"values" binding:"required,min=1,dive"`
+}
+
+// ProductOptionValueRequest представляет значение опции в запросе
+type ProductOptionValueRequest struct {
+	Code         string            `json:"code" binding:"required,max=50"`
+	Translations map[string]string `json:"translations" binding:"required"` // язык -> название
+}
+
+// ProductVariantRequest представляет вариант товара в запросе
+type ProductVariantRequest struct {
+	SKU       string            `json:"sku" binding:"required,max=100"`
+	Options   map[string]string `json:"options" binding:"required"` // код опции -> код значения
+	PriceMode string            `json:"price_mode" binding:"required"`
+	Price     *Money            `json:"price" binding:"required"`
+	Currency  string            `json:"currency" binding:"required,len=3"`
+	Stock     *int              `json:"stock"`
+	Enabled   *bool             `json:"enabled"`
+}
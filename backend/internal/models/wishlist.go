@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// Wishlist список избранного. Без входа в личный кабинет доступ к списку дает токен из cookie,
+// в базе хранится его хеш; у покупателя с личным кабинетом один список, привязанный к учетной записи
+type Wishlist struct {
+	ID         string  `json:"id" db:"id"`
+	TokenHash  *string `json:"-" db:"token_hash"`
+	CustomerID *int64  `json:"-" db:"customer_id"`
+
+	// Идентификатор публичной ссылки на список (nil - список не открыт для просмотра)
+	ShareID *string `json:"share_id,omitempty" db:"share_id"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	// Срок хранения списка без личного кабинета (nil для списка покупателя)
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+
+	Items []WishlistItem `json:"items" db:"-"`
+
+	// Публичная ссылка на список (заполняется, если список открыт для просмотра)
+	ShareURL string `json:"share_url,omitempty" db:"-"`
+}
+
+// WishlistItem товар в списке избранного. Название, изображение и цена заполняются
+// по текущим данным товара на языке запроса
+type WishlistItem struct {
+	WishlistID string    `json:"-" db:"wishlist_id"`
+	ProductID  int64     `json:"product_id" db:"product_id"`
+	Language   string    `json:"-" db:"language"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+
+	// Подписка на уведомление о снижении цены (только для покупателей с личным кабинетом)
+	NotifyPriceDrop bool `json:"notify_price_drop" db:"notify_price_drop"`
+
+	ProductName  string `json:"product_name,omitempty" db:"-"`
+	ProductImage string `json:"product_image,omitempty" db:"-"`
+	Price        *Money `json:"price,omitempty" db:"-"`
+	Currency     string `json:"currency,omitempty" db:"-"`
+
+	// Снятый с продажи товар остается в списке, но недоступен для заказа
+	Available bool `json:"available" db:"-"`
+}
+
+// SharedWishlist список избранного, открытый по публичной ссылке только для просмотра
+type SharedWishlist struct {
+	Items     []WishlistItem `json:"items"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// WishlistItemRequest представляет запрос на добавление товара в список избранного
+type WishlistItemRequest struct {
+	ProductID       int64 `json:"product_id" binding:"required"`
+	NotifyPriceDrop bool  `json:"notify_price_drop"`
+}
+
+// WishlistItemUpdateRequest представляет запрос на изменение подписки на снижение цены
+type WishlistItemUpdateRequest struct {
+	NotifyPriceDrop bool `json:"notify_price_drop"`
+}
+
+// PriceDrop снижение цены товара из списка избранного покупателя
+type PriceDrop struct {
+	WishlistID    string `db:"wishlist_id"`
+	ProductID     int64  `db:"product_id"`
+	CustomerID    int64  `db:"customer_id"`
+	Email         string `db:"email"`
+	Name          string `db:"name"`
+	Language      string `db:"language"`
+	PriceLanguage string `db:"price_language"`
+	OldPrice      Money  `db:"old_price"`
+	NewPrice      Money  `db:"new_price"`
+	Currency      string `db:"currency"`
+}
+
+// PriceDropAlert письмо о снижении цен на товары из списка избранного
+type PriceDropAlert struct {
+	Email    string
+	Name     string
+	Language string
+	Items    []PriceDropItem
+
+	// Ссылка на список избранного, где можно отключить уведомления
+	WishlistURL string
+}
+
+// PriceDropItem товар в письме о снижении цен
+type PriceDropItem struct {
+	Name     string
+	OldPrice Money
+	NewPrice Money
+	URL      string
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+)
+
+// Причины движения материалов мастерской
+const (
+	MaterialMovementConsumption = "consumption" // списание на заказ при запуске в производство
+	MaterialMovementAdjustment  = "adjustment"  // ручная корректировка (поступление, инвентаризация, брак)
+)
+
+// WorkshopMaterial материал мастерской (фанера, акрил, филамент)
+type WorkshopMaterial struct {
+	ID   int64  `json:"id" db:"id"`
+	Code string `json:"code" db:"code"`
+	Name string `json:"name" db:"name"`
+	Unit string `json:"unit" db:"unit"` // единица учета: лист, м², катушка, г
+
+	// Текущий остаток; может быть отрицательным, если заказ запущен в производство без материала
+	Quantity float64 `json:"quantity" db:"quantity"`
+	// Остаток, ниже которого материал нужно дозаказать
+	ReorderThreshold float64 `json:"reorder_threshold" db:"reorder_threshold"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WorkshopMaterialRequest представляет запрос на создание или изменение материала мастерской.
+// Остаток задается только при создании, далее он меняется корректировками
+type WorkshopMaterialRequest struct {
+	Name             string   `json:"name" binding:"required,max=255"`
+	Unit             string   `json:"unit" binding:"required,max=20"`
+	Quantity         *float64 `json:"quantity"`
+	ReorderThreshold float64  `json:"reorder_threshold" binding:"gte=0"`
+}
+
+// MaterialMovement движение материала мастерской
+type MaterialMovement struct {
+	ID         int64     `json:"id" db:"id"`
+	MaterialID int64     `json:"material_id" db:"material_id"`
+	Quantity   float64   `json:"quantity" db:"quantity"` // положительное - приход, отрицательное - расход
+	Reason     string    `json:"reason" db:"reason"`
+	OrderID    *int64    `json:"order_id,omitempty" db:"order_id"`
+	Comment    string    `json:"comment,omitempty" db:"comment"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// MaterialAdjustmentRequest представляет запрос на ручную корректировку остатка материала
+type MaterialAdjustmentRequest struct {
+	Quantity float64 `json:"quantity" binding:"required"`
+	Comment  string  `json:"comment" binding:"required,max=500"`
+}
+
+// BOMItem строка спецификации: расход материала на единицу товара или варианта.
+// Строки варианта заменяют строки товара без варианта
+type BOMItem struct {
+	VariantID    *int64  `json:"variant_id,omitempty" db:"variant_id"`
+	MaterialID   int64   `json:"material_id" db:"material_id"`
+	MaterialCode string  `json:"material_code,omitempty" db:"material_code"`
+	MaterialName string  `json:"material_name,omitempty" db:"material_name"`
+	Unit         string  `json:"unit,omitempty" db:"unit"`
+	Quantity     float64 `json:"quantity" db:"quantity"`
+}
+
+// BOMRequest представляет запрос на замену спецификации товара
+type BOMRequest struct {
+	Items []BOMItemRequest `json:"items" binding:"dive"`
+}
+
+// BOMItemRequest представляет строку спецификации в запросе
+type BOMItemRequest struct {
+	VariantID  *int64  `json:"variant_id"`
+	MaterialID int64   `json:"material_id" binding:"required"`
+	Quantity   float64 `json:"quantity" binding:"required,gt=0"`
+}
+
+// MaterialShortage строка отчета о нехватке материалов
+type MaterialShortage struct {
+	WorkshopMaterial
+
+	// Потребность открытых заказов, еще не запущенных в производство
+	Demand float64 `json:"demand" db:"demand"`
+	// Остаток после выполнения открытых заказов
+	Projected float64 `json:"projected" db:"projected"`
+	// Сколько не хватает для выполнения открытых заказов
+	Shortage float64 `json:"shortage" db:"shortage"`
+	// Сколько нужно дозаказать, чтобы после выполнения заказов остаток был не ниже порога
+	ReorderQuantity float64 `json:"reorder_quantity" db:"reorder_quantity"`
+}
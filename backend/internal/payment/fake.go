@@ -0,0 +1,93 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"pryanik_studio/internal/models"
+)
+
+const fakeProviderName = "fake"
+
+// FakeProvider локальный провайдер для разработки и тестов: платежи создаются без внешних запросов,
+// а события отправляются в вебхук вручную, подписанные методом Sign
+type FakeProvider struct {
+	webhookSecret string
+	refunds       atomic.Int64
+}
+
+// NewFakeProvider создает новый экземпляр FakeProvider
+func NewFakeProvider(webhookSecret string) *FakeProvider {
+	return &FakeProvider{webhookSecret: webhookSecret}
+}
+
+// FakeEvent тело вебхука тестового провайдера
+type FakeEvent struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"` // succeeded, failed, refunded
+	PaymentID string `json:"payment_id"`
+	// Общая сумма возвратов в минимальных единицах и ее валюта (для refunded)
+	RefundedAmount int64  `json:"refunded_amount,omitempty"`
+	Currency       string `json:"currency,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+// Name возвращает имя провайдера
+func (p *FakeProvider) Name() string {
+	return fakeProviderName
+}
+
+// CreatePayment возвращает платеж с идентификатором по локальному ID; страница оплаты -
+// страница возврата, чтобы сценарий можно было пройти без внешнего сервиса
+func (p *FakeProvider) CreatePayment(ctx context.Context, request CreateRequest) (Created, error) {
+	id := fmt.Sprintf("fake_%d", request.PaymentID)
+	return Created{
+		ProviderPaymentID: id,
+		ConfirmationURL:   fmt.Sprintf("%s?order_id=%d&payment=%s", request.ReturnURL, request.OrderID, id),
+	}, nil
+}
+
+// Refund сразу подтверждает возврат
+func (p *FakeProvider) Refund(ctx context.Context, payment models.Payment, amount models.Money) (Refund, error) {
+	return Refund{ID: fmt.Sprintf("fake_refund_%d", p.refunds.Add(1)), Amount: amount}, nil
+}
+
+// ParseWebhook проверяет подпись X-Fake-Signature и разбирает FakeEvent
+func (p *FakeProvider) ParseWebhook(header http.Header, body []byte) (models.PaymentEvent, error) {
+	if !hmac.Equal([]byte(header.Get("X-Fake-Signature")), []byte(p.Sign(body))) {
+		return models.PaymentEvent{}, ErrInvalidSignature
+	}
+
+	var event FakeEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return models.PaymentEvent{}, fmt.Errorf("ошибка при разборе события: %w", err)
+	}
+
+	switch event.Type {
+	case models.PaymentEventSucceeded, models.PaymentEventFailed, models.PaymentEventRefunded:
+	default:
+		return models.PaymentEvent{}, ErrIgnoredEvent
+	}
+
+	return models.PaymentEvent{
+		ID:                event.ID,
+		Type:              event.Type,
+		ProviderPaymentID: event.PaymentID,
+		Reference:         event.PaymentID,
+		RefundedAmount:    models.NewMoney(event.RefundedAmount, event.Currency),
+		Reason:            event.Reason,
+	}, nil
+}
+
+// Sign возвращает подпись тела вебхука (HMAC-SHA256 в hex)
+func (p *FakeProvider) Sign(body []byte) string {
+	h := hmac.New(sha256.New, []byte(p.webhookSecret))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,74 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/models"
+)
+
+var (
+	// ErrInvalidSignature возвращается, если подпись вебхука не прошла проверку
+	ErrInvalidSignature = errors.New("недействительная подпись вебхука")
+
+	// ErrIgnoredEvent возвращается для событий провайдера, которые не влияют на платежи
+	ErrIgnoredEvent = errors.New("событие не обрабатывается")
+)
+
+// CreateRequest параметры создания платежа у провайдера
+type CreateRequest struct {
+	// Локальный ID платежа; используется как ключ идемпотентности запроса к провайдеру
+	PaymentID   int64
+	OrderID     int64
+	Amount      models.Money
+	Description string
+	Email       string
+	// Страница, на которую покупатель возвращается после оплаты
+	ReturnURL string
+}
+
+// Created платеж, созданный у провайдера
+type Created struct {
+	ProviderPaymentID string
+	ConfirmationURL   string
+}
+
+// Refund возврат, выполненный провайдером
+type Refund struct {
+	ID     string
+	Amount models.Money
+}
+
+// Provider платежный провайдер: создает платежи, проверяет и разбирает вебхуки, выполняет возвраты
+type Provider interface {
+	// Name возвращает имя провайдера, под которым сохраняются его платежи
+	Name() string
+	CreatePayment(ctx context.Context, request CreateRequest) (Created, error)
+	// ParseWebhook проверяет подпись вебхука и приводит событие к models.PaymentEvent.
+	// Для событий, которые не влияют на платежи, возвращает ErrIgnoredEvent
+	ParseWebhook(header http.Header, body []byte) (models.PaymentEvent, error)
+	Refund(ctx context.Context, payment models.Payment, amount models.Money) (Refund, error)
+}
+
+// NewProvider создает провайдера по конфигурации; если онлайн-оплата отключена, возвращает nil
+func NewProvider(cfg config.PaymentConfig) (Provider, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case stripeProviderName:
+		if cfg.StripeSecretKey == "" || cfg.StripeWebhookSecret == "" {
+			return nil, errors.New("не заданы ключи Stripe (STRIPE_SECRET_KEY, STRIPE_WEBHOOK_SECRET)")
+		}
+		return NewStripeProvider(cfg.StripeSecretKey, cfg.StripeWebhookSecret), nil
+	case fakeProviderName:
+		if cfg.FakeWebhookSecret == "" {
+			return nil, errors.New("не задан секрет вебхуков тестового провайдера (PAYMENT_FAKE_WEBHOOK_SECRET)")
+		}
+		return NewFakeProvider(cfg.FakeWebhookSecret), nil
+	default:
+		return nil, fmt.Errorf("неизвестный платежный провайдер: %s", cfg.Provider)
+	}
+}
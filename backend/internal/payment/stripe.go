@@ -0,0 +1,229 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"pryanik_studio/internal/models"
+)
+
+const (
+	stripeProviderName = "stripe"
+	stripeAPIURL       = "https://api.stripe.com/v1"
+
+	// Допустимое расхождение времени подписи вебхука с текущим временем
+	stripeSignatureTolerance = 5 * time.Minute
+)
+
+// StripeProvider платежи через Stripe Checkout. Суммы передаются в минимальных единицах валюты,
+// что совпадает с хранением models.Money для валют с двумя знаками после запятой
+type StripeProvider struct {
+	secretKey     string
+	webhookSecret string
+	apiURL        string
+	client        *http.Client
+}
+
+// NewStripeProvider создает новый экземпляр StripeProvider
+func NewStripeProvider(secretKey, webhookSecret string) *StripeProvider {
+	return &StripeProvider{
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		apiURL:        stripeAPIURL,
+		client:        &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Name возвращает имя провайдера
+func (p *StripeProvider) Name() string {
+	return stripeProviderName
+}
+
+// stripeObject поля объектов Stripe (сессия оплаты, платеж), используемые при обработке
+type stripeObject struct {
+	ID             string `json:"id"`
+	URL            string `json:"url"`
+	PaymentStatus  string `json:"payment_status"`
+	PaymentIntent  string `json:"payment_intent"`
+	Amount         int64  `json:"amount"`
+	AmountRefunded int64  `json:"amount_refunded"`
+	Currency       string `json:"currency"`
+}
+
+// CreatePayment создает сессию Stripe Checkout на сумму заказа
+func (p *StripeProvider) CreatePayment(ctx context.Context, request CreateRequest) (Created, error) {
+	returnURL := fmt.Sprintf("%s?order_id=%d", request.ReturnURL, request.OrderID)
+
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("success_url", returnURL+"&status=success")
+	form.Set("cancel_url", returnURL+"&status=cancel")
+	form.Set("client_reference_id", strconv.FormatInt(request.OrderID, 10))
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", strings.ToLower(request.Amount.Currency))
+	form.Set("line_items[0][price_data][unit_amount]", strconv.FormatInt(request.Amount.Amount, 10))
+	form.Set("line_items[0][price_data][product_data][name]", request.Description)
+	form.Set("metadata[order_id]", strconv.FormatInt(request.OrderID, 10))
+	form.Set("metadata[payment_id]", strconv.FormatInt(request.PaymentID, 10))
+	if request.Email != "" {
+		form.Set("customer_email", request.Email)
+	}
+
+	var session stripeObject
+	key := fmt.Sprintf("payment-%d", request.PaymentID)
+	if err := p.post(ctx, "/checkout/sessions", form, key, &session); err != nil {
+		return Created{}, err
+	}
+
+	return Created{ProviderPaymentID: session.ID, ConfirmationURL: session.URL}, nil
+}
+
+// Refund выполняет возврат по операции списания платежа
+func (p *StripeProvider) Refund(ctx context.Context, payment models.Payment, amount models.Money) (Refund, error) {
+	if payment.ProviderReference == "" {
+		return Refund{}, fmt.Errorf("у платежа ID=%d нет операции списания для возврата", payment.ID)
+	}
+
+	form := url.Values{}
+	form.Set("payment_intent", payment.ProviderReference)
+	form.Set("amount", strconv.FormatInt(amount.Amount, 10))
+
+	// Ключ зависит от уже возвращенной суммы, чтобы повторный запрос не вернул деньги дважды
+	var refund stripeObject
+	key := fmt.Sprintf("refund-%d-%d-%d", payment.ID, payment.RefundedAmount.Amount, amount.Amount)
+	if err := p.post(ctx, "/refunds", form, key, &refund); err != nil {
+		return Refund{}, err
+	}
+
+	return Refund{ID: refund.ID, Amount: models.NewMoney(refund.Amount, amount.Currency)}, nil
+}
+
+// ParseWebhook проверяет подпись Stripe-Signature и приводит событие Stripe к models.PaymentEvent
+func (p *StripeProvider) ParseWebhook(header http.Header, body []byte) (models.PaymentEvent, error) {
+	if err := p.verifySignature(header.Get("Stripe-Signature"), body, time.Now()); err != nil {
+		return models.PaymentEvent{}, err
+	}
+
+	var event struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object stripeObject `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return models.PaymentEvent{}, fmt.Errorf("ошибка при разборе события Stripe: %w", err)
+	}
+
+	object := event.Data.Object
+	result := models.PaymentEvent{ID: event.ID, ProviderPaymentID: object.ID}
+
+	switch event.Type {
+	case "checkout.session.completed", "checkout.session.async_payment_succeeded":
+		// Для отложенных способов оплаты сессия завершается до поступления денег
+		if object.PaymentStatus != "paid" {
+			return models.PaymentEvent{}, ErrIgnoredEvent
+		}
+		result.Type = models.PaymentEventSucceeded
+		result.Reference = object.PaymentIntent
+	case "checkout.session.async_payment_failed":
+		result.Type = models.PaymentEventFailed
+		result.Reason = "Оплата не прошла"
+	case "checkout.session.expired":
+		result.Type = models.PaymentEventFailed
+		result.Reason = "Срок оплаты истек"
+	case "charge.refunded":
+		// Возврат относится к операции списания, которая сохраняется при успешной оплате
+		result.Type = models.PaymentEventRefunded
+		result.ProviderPaymentID = object.PaymentIntent
+		result.RefundedAmount = models.NewMoney(object.AmountRefunded, strings.ToUpper(object.Currency))
+	default:
+		return models.PaymentEvent{}, ErrIgnoredEvent
+	}
+
+	return result, nil
+}
+
+// verifySignature проверяет заголовок вида "t=<время>,v1=<подпись>[,v1=...]": подпись - HMAC-SHA256
+// строки "<время>.<тело>" секретом вебхука. Старые подписи отклоняются для защиты от повтора
+func (p *StripeProvider) verifySignature(header string, body []byte, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if diff := now.Sub(time.Unix(seconds, 0)); diff > stripeSignatureTolerance || diff < -stripeSignatureTolerance {
+		return ErrInvalidSignature
+	}
+
+	h := hmac.New(sha256.New, []byte(p.webhookSecret))
+	h.Write([]byte(timestamp + "."))
+	h.Write(body)
+	expected := hex.EncodeToString(h.Sum(nil))
+
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// post выполняет запрос к API Stripe с ключом идемпотентности и разбирает ответ в result
+func (p *StripeProvider) post(ctx context.Context, path string, form url.Values, idempotencyKey string, result interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.apiURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("ошибка при создании запроса к Stripe: %w", err)
+	}
+	request.SetBasicAuth(p.secretKey, "")
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Idempotency-Key", idempotencyKey)
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("ошибка запроса к Stripe: %w", err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("ошибка при чтении ответа Stripe: %w", err)
+	}
+
+	if response.StatusCode >= 400 {
+		var apiError struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(body, &apiError)
+		return fmt.Errorf("Stripe вернул ошибку: %d - %s", response.StatusCode, apiError.Error.Message)
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("ошибка при разборе ответа Stripe: %w", err)
+	}
+
+	return nil
+}
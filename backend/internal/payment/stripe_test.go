@@ -0,0 +1,142 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"pryanik_studio/internal/models"
+)
+
+const testWebhookSecret = "whsec_test"
+
+// stripeSignature возвращает заголовок Stripe-Signature для тела, подписанного в момент at
+func stripeSignature(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp + "."))
+	h.Write(body)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(h.Sum(nil)))
+}
+
+func TestStripeVerifySignature(t *testing.T) {
+	provider := NewStripeProvider("sk_test", testWebhookSecret)
+	body := []byte(`{"id":"evt_1"}`)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		header  string
+		wantErr bool
+	}{
+		{"валидная подпись", stripeSignature(testWebhookSecret, now, body), false},
+		{"подпись в пределах допуска", stripeSignature(testWebhookSecret, now.Add(-4*time.Minute), body), false},
+		{"одна из нескольких подписей валидна", stripeSignature(testWebhookSecret, now, body) + ",v1=deadbeef", false},
+		{"другой секрет", stripeSignature("whsec_other", now, body), true},
+		{"устаревшая подпись", stripeSignature(testWebhookSecret, now.Add(-6*time.Minute), body), true},
+		{"подпись из будущего", stripeSignature(testWebhookSecret, now.Add(6*time.Minute), body), true},
+		{"без времени", "v1=deadbeef", true},
+		{"без подписи", fmt.Sprintf("t=%d", now.Unix()), true},
+		{"пустой заголовок", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := provider.verifySignature(tt.header, body, now)
+			if tt.wantErr && !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("ожидалась ErrInvalidSignature, получено %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+		})
+	}
+}
+
+func TestStripeVerifySignatureModifiedBody(t *testing.T) {
+	provider := NewStripeProvider("sk_test", testWebhookSecret)
+	now := time.Now()
+	header := stripeSignature(testWebhookSecret, now, []byte(`{"id":"evt_1","amount":100}`))
+
+	if err := provider.verifySignature(header, []byte(`{"id":"evt_1","amount":1}`), now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("ожидалась ErrInvalidSignature для измененного тела, получено %v", err)
+	}
+}
+
+func TestStripeParseWebhook(t *testing.T) {
+	provider := NewStripeProvider("sk_test", testWebhookSecret)
+
+	tests := []struct {
+		name string
+		body string
+		want models.PaymentEvent
+		err  error
+	}{
+		{
+			name: "оплаченная сессия",
+			body: `{"id":"evt_1","type":"checkout.session.completed","data":{"object":{"id":"cs_1","payment_status":"paid","payment_intent":"pi_1"}}}`,
+			want: models.PaymentEvent{ID: "evt_1", Type: models.PaymentEventSucceeded, ProviderPaymentID: "cs_1", Reference: "pi_1"},
+		},
+		{
+			name: "сессия без поступления денег",
+			body: `{"id":"evt_2","type":"checkout.session.completed","data":{"object":{"id":"cs_1","payment_status":"unpaid"}}}`,
+			err:  ErrIgnoredEvent,
+		},
+		{
+			name: "истекшая сессия",
+			body: `{"id":"evt_3","type":"checkout.session.expired","data":{"object":{"id":"cs_1"}}}`,
+			want: models.PaymentEvent{ID: "evt_3", Type: models.PaymentEventFailed, ProviderPaymentID: "cs_1", Reason: "Срок оплаты истек"},
+		},
+		{
+			name: "возврат",
+			body: `{"id":"evt_4","type":"charge.refunded","data":{"object":{"id":"ch_1","payment_intent":"pi_1","amount_refunded":500,"currency":"usd"}}}`,
+			want: models.PaymentEvent{
+				ID:                "evt_4",
+				Type:              models.PaymentEventRefunded,
+				ProviderPaymentID: "pi_1",
+				RefundedAmount:    models.NewMoney(500, "USD"),
+			},
+		},
+		{
+			name: "неизвестное событие",
+			body: `{"id":"evt_5","type":"customer.created","data":{"object":{"id":"cus_1"}}}`,
+			err:  ErrIgnoredEvent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set("Stripe-Signature", stripeSignature(testWebhookSecret, time.Now(), []byte(tt.body)))
+
+			event, err := provider.ParseWebhook(header, []byte(tt.body))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("ожидалась ошибка %v, получено %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+			if event != tt.want {
+				t.Fatalf("событие %+v, ожидалось %+v", event, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripeParseWebhookRejectsUnsigned(t *testing.T) {
+	provider := NewStripeProvider("sk_test", testWebhookSecret)
+	body := []byte(`{"id":"evt_1","type":"checkout.session.completed","data":{"object":{"id":"cs_1","payment_status":"paid"}}}`)
+
+	if _, err := provider.ParseWebhook(http.Header{}, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("ожидалась ErrInvalidSignature, получено %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package production
+
+import (
+	"math"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/models"
+)
+
+// EstimateMinutes оценивает длительность задания по сохраненному расчету стоимости.
+// Для 3D-печати берется расчетное время печати, для лазерной обработки - длина реза
+// и площадь гравировки с нормами из конфигурации. К результату добавляется подготовка станка
+func EstimateMinutes(estimate models.Estimate, quantity int, cfg config.ProductionConfig) int {
+	var seconds float64
+
+	switch estimate.Kind {
+	case models.EstimateKind3D:
+		seconds = estimate.PrintHours * 3600
+	case models.EstimateKindLaser:
+		if estimate.VectorAnalysis == nil {
+			break
+		}
+		cut := estimate.Operation == models.LaserOperationCut || estimate.Operation == models.LaserOperationCutEngrave
+		engrave := estimate.Operation == models.LaserOperationEngrave || estimate.Operation == models.LaserOperationCutEngrave
+		if cut && cfg.LaserCutSpeed > 0 {
+			seconds += estimate.VectorAnalysis.CutLength / cfg.LaserCutSpeed
+		}
+		if engrave && cfg.LaserEngraveRate > 0 {
+			seconds += estimate.VectorAnalysis.EngraveArea / cfg.LaserEngraveRate
+		}
+	}
+
+	return cfg.SetupMinutes + int(math.Ceil(seconds*float64(quantity)/60))
+}
+
+// ProductMinutes оценивает длительность задания по параметрам изготовления товара каталога
+func ProductMinutes(settings models.ProductionSettings, quantity int, cfg config.ProductionConfig) int {
+	return cfg.SetupMinutes + settings.Minutes*quantity
+}
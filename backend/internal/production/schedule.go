@@ -0,0 +1,127 @@
+package production
+
+import (
+	"sort"
+	"time"
+
+	"pryanik_studio/internal/models"
+)
+
+// Schedule составляет план для заданий в статусах queued и scheduled.
+// Задания обрабатываются по убыванию приоритета, затем по сроку (без срока - последними)
+// и ставятся на подходящий станок, который освободится раньше других; станок, назначенный
+// вручную, не меняется. Выполняемые задания занимают свой станок до расчетного окончания.
+// Станки считаются работающими круглосуточно. Для заданий без подходящего станка
+// возвращается место без станка и времени
+func Schedule(now time.Time, machines []models.Machine, jobs []models.ProductionJob) []models.JobSlot {
+	available := make(map[int64]time.Time, len(machines))
+	for _, machine := range machines {
+		if machine.Enabled {
+			available[machine.ID] = now
+		}
+	}
+
+	var pending []models.ProductionJob
+	for _, job := range jobs {
+		switch job.Status {
+		case models.JobStatusQueued, models.JobStatusScheduled:
+			pending = append(pending, job)
+		case models.JobStatusInProgress:
+			if job.MachineID == nil {
+				continue
+			}
+			if free, ok := available[*job.MachineID]; ok {
+				if end := expectedEnd(job, now); end.After(free) {
+					available[*job.MachineID] = end
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(pending, func(i, j int) bool {
+		a, b := pending[i], pending[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if (a.DueDate == nil) != (b.DueDate == nil) {
+			return a.DueDate != nil
+		}
+		if a.DueDate != nil && !a.DueDate.Equal(*b.DueDate) {
+			return a.DueDate.Before(*b.DueDate)
+		}
+		return a.ID < b.ID
+	})
+
+	slots := make([]models.JobSlot, 0, len(pending))
+	for _, job := range pending {
+		slot := models.JobSlot{JobID: job.ID}
+
+		var machineID int64
+		found := false
+		for _, machine := range machines {
+			free, enabled := available[machine.ID]
+			if !enabled || !canRun(machine, job) {
+				continue
+			}
+			if !found || free.Before(available[machineID]) {
+				machineID = machine.ID
+				found = true
+			}
+		}
+
+		if found {
+			start := available[machineID]
+			end := start.Add(time.Duration(job.EstimatedMinutes) * time.Minute)
+			available[machineID] = end
+			slot.MachineID = &machineID
+			slot.Start = &start
+			slot.End = &end
+		}
+		slots = append(slots, slot)
+	}
+
+	return slots
+}
+
+// Late проверяет, что задание не успевает к сроку: плановое окончание позже конца дня срока
+// или срок уже прошел, а задание не выполнено
+func Late(job models.ProductionJob, now time.Time) bool {
+	if job.DueDate == nil || job.Status == models.JobStatusDone || job.Status == models.JobStatusCancelled {
+		return false
+	}
+	deadline := job.DueDate.AddDate(0, 0, 1)
+
+	end := now
+	switch {
+	case job.Status == models.JobStatusInProgress:
+		end = expectedEnd(job, now)
+	case job.ScheduledEnd != nil && job.ScheduledEnd.After(now):
+		end = *job.ScheduledEnd
+	}
+	return end.After(deadline)
+}
+
+// canRun проверяет, может ли станок выполнить задание
+func canRun(machine models.Machine, job models.ProductionJob) bool {
+	if job.MachineLocked && job.MachineID != nil {
+		return *job.MachineID == machine.ID
+	}
+	for _, capability := range machine.Capabilities {
+		if capability == job.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+// expectedEnd возвращает расчетное окончание выполняемого задания, но не раньше текущего момента
+func expectedEnd(job models.ProductionJob, now time.Time) time.Time {
+	end := now
+	if job.StartedAt != nil {
+		end = job.StartedAt.Add(time.Duration(job.EstimatedMinutes) * time.Minute)
+	}
+	if end.Before(now) {
+		return now
+	}
+	return end
+}
@@ -0,0 +1,150 @@
+package promo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/utils"
+)
+
+// ErrNotApplicable возвращается, если промокод не может быть применен к корзине
+var ErrNotApplicable = errors.New("промокод не применим")
+
+// Error ошибка применения промокода с причиной, которая показывается покупателю
+type Error struct {
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return ErrNotApplicable.Error() + ": " + e.Reason
+}
+
+// Is позволяет проверять ошибку через errors.Is(err, ErrNotApplicable)
+func (e *Error) Is(target error) bool {
+	return target == ErrNotApplicable
+}
+
+// notApplicable возвращает ошибку применения промокода с причиной
+func notApplicable(format string, args ...interface{}) error {
+	return &Error{Reason: fmt.Sprintf(format, args...)}
+}
+
+// NormalizeCode приводит промокод к виду, в котором он хранится
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Apply рассчитывает скидку по промокоду для позиций одной валюты и распределяет ее по позициям
+// (поле Discount). Ограничения количества использований проверяются отдельно
+func Apply(code models.PromoCode, items []models.OrderItem, now time.Time) (models.AppliedDiscount, error) {
+	applied := models.AppliedDiscount{Code: code.Code, Kind: code.Kind, Description: code.Description}
+
+	switch {
+	case !code.Enabled:
+		return applied, notApplicable("Промокод не действует")
+	case code.StartsAt != nil && now.Before(*code.StartsAt):
+		return applied, notApplicable("Промокод еще не действует")
+	case code.EndsAt != nil && !now.Before(*code.EndsAt):
+		return applied, notApplicable("Срок действия промокода истек")
+	case len(items) == 0:
+		return applied, notApplicable("Корзина пуста")
+	}
+
+	currency := items[0].Currency
+	var subtotal, eligibleTotal int64
+	var eligible []int
+	for i, item := range items {
+		items[i].Discount = models.NewMoney(0, item.Currency)
+		subtotal += item.Price.Amount * int64(item.Quantity)
+		if inCategories(code.CategoryIDs, item) {
+			eligible = append(eligible, i)
+			eligibleTotal += item.Price.Amount * int64(item.Quantity)
+		}
+	}
+
+	if len(code.MinOrderAmounts) > 0 {
+		minimum, ok := code.MinOrderAmounts.Get(currency)
+		if !ok {
+			return applied, notApplicable("Промокод не действует для валюты %s", currency)
+		}
+		if subtotal < minimum.Amount {
+			return applied, notApplicable("Минимальная сумма заказа для промокода %s", utils.FormatCurrency(minimum))
+		}
+	}
+	if len(eligible) == 0 {
+		return applied, notApplicable("В корзине нет товаров, на которые действует промокод")
+	}
+
+	var discount int64
+	switch code.Kind {
+	case models.PromoKindPercent:
+		discount = eligibleTotal * int64(code.Percent) / 100
+	case models.PromoKindFixed:
+		amount, ok := code.Amounts.Get(currency)
+		if !ok {
+			return applied, notApplicable("Промокод не действует для валюты %s", currency)
+		}
+		discount = min(amount.Amount, eligibleTotal)
+	case models.PromoKindFreeItem:
+		// Бесплатна одна единица самой дешевой подходящей позиции с указанным товаром
+		free := -1
+		for _, i := range eligible {
+			if code.FreeProductID != nil && items[i].ProductID == *code.FreeProductID &&
+				(free < 0 || items[i].Price.Amount < items[free].Price.Amount) {
+				free = i
+			}
+		}
+		if free < 0 {
+			return applied, notApplicable("Добавьте в корзину товар, который дается по промокоду")
+		}
+		items[free].Discount = models.NewMoney(items[free].Price.Amount, currency)
+		applied.Amount = items[free].Discount
+		return applied, nil
+	default:
+		return applied, notApplicable("Неизвестный вид промокода")
+	}
+
+	distribute(items, eligible, eligibleTotal, discount)
+	applied.Amount = models.NewMoney(discount, currency)
+	return applied, nil
+}
+
+// distribute распределяет скидку по подходящим позициям пропорционально их стоимости.
+// Остаток от округления достается позициям по порядку, так что сумма частей равна скидке
+func distribute(items []models.OrderItem, eligible []int, total, discount int64) {
+	if total == 0 || discount == 0 {
+		return
+	}
+
+	var allocated int64
+	for _, i := range eligible {
+		share := discount * (items[i].Price.Amount * int64(items[i].Quantity)) / total
+		items[i].Discount.Amount = share
+		allocated += share
+	}
+	for _, i := range eligible {
+		if allocated == discount {
+			break
+		}
+		if items[i].Discount.Amount < items[i].Price.Amount*int64(items[i].Quantity) {
+			items[i].Discount.Amount++
+			allocated++
+		}
+	}
+}
+
+// inCategories проверяет, действует ли промокод на товар позиции
+func inCategories(categories []int64, item models.OrderItem) bool {
+	if len(categories) == 0 {
+		return true
+	}
+	for _, id := range categories {
+		if id == item.CategoryID || (item.SubcategoryID != nil && id == *item.SubcategoryID) {
+			return true
+		}
+	}
+	return false
+}
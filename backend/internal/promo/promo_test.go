@@ -0,0 +1,143 @@
+package promo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"pryanik_studio/internal/models"
+)
+
+func TestApplyDistributesDiscount(t *testing.T) {
+	item := func(price int64, quantity int, categoryID int64) models.OrderItem {
+		return models.OrderItem{
+			Price:      models.NewMoney(price, "RUB"),
+			Quantity:   quantity,
+			Currency:   "RUB",
+			CategoryID: categoryID,
+		}
+	}
+	percent := func(value int, categories ...int64) models.PromoCode {
+		return models.PromoCode{Code: "SALE", Kind: models.PromoKindPercent, Percent: value, CategoryIDs: categories, Enabled: true}
+	}
+	fixed := func(amount int64) models.PromoCode {
+		return models.PromoCode{
+			Code:    "MINUS",
+			Kind:    models.PromoKindFixed,
+			Amounts: models.CurrencyAmounts{"RUB": models.NewMoney(amount, "RUB")},
+			Enabled: true,
+		}
+	}
+
+	tests := []struct {
+		name          string
+		code          models.PromoCode
+		items         []models.OrderItem
+		wantDiscount  int64
+		wantDiscounts []int64
+	}{
+		{
+			name:          "процент на одну позицию",
+			code:          percent(10),
+			items:         []models.OrderItem{item(1000, 3, 1)},
+			wantDiscount:  300,
+			wantDiscounts: []int64{300},
+		},
+		{
+			name:          "остаток от округления процента достается первой позиции",
+			code:          percent(10),
+			items:         []models.OrderItem{item(333, 1, 1), item(333, 1, 1), item(334, 1, 1)},
+			wantDiscount:  100,
+			wantDiscounts: []int64{34, 33, 33},
+		},
+		{
+			name:          "процент округляется вниз",
+			code:          percent(15),
+			items:         []models.OrderItem{item(999, 1, 1)},
+			wantDiscount:  149,
+			wantDiscounts: []int64{149},
+		},
+		{
+			name:          "процент только на позиции из категорий промокода",
+			code:          percent(15, 1),
+			items:         []models.OrderItem{item(999, 1, 1), item(500, 1, 2)},
+			wantDiscount:  149,
+			wantDiscounts: []int64{149, 0},
+		},
+		{
+			name:          "полная скидка равна стоимости позиций",
+			code:          percent(100),
+			items:         []models.OrderItem{item(1, 1, 1), item(2, 1, 1)},
+			wantDiscount:  3,
+			wantDiscounts: []int64{1, 2},
+		},
+		{
+			name:          "фиксированная сумма с остатком от округления",
+			code:          fixed(100),
+			items:         []models.OrderItem{item(100, 1, 1), item(100, 1, 1), item(100, 1, 1)},
+			wantDiscount:  100,
+			wantDiscounts: []int64{34, 33, 33},
+		},
+		{
+			name:          "фиксированная сумма пропорционально стоимости позиций",
+			code:          fixed(300),
+			items:         []models.OrderItem{item(500, 1, 1), item(250, 2, 1), item(500, 2, 1)},
+			wantDiscount:  300,
+			wantDiscounts: []int64{75, 75, 150},
+		},
+		{
+			name:          "фиксированная сумма ограничивается стоимостью одной позиции",
+			code:          fixed(5000),
+			items:         []models.OrderItem{item(1000, 3, 1)},
+			wantDiscount:  3000,
+			wantDiscounts: []int64{3000},
+		},
+		{
+			name:          "фиксированная сумма ограничивается стоимостью заказа",
+			code:          fixed(1000),
+			items:         []models.OrderItem{item(200, 1, 1), item(100, 2, 1)},
+			wantDiscount:  400,
+			wantDiscounts: []int64{200, 200},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied, err := Apply(tt.code, tt.items, time.Now())
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if applied.Amount.Amount != tt.wantDiscount || applied.Amount.Currency != "RUB" {
+				t.Errorf("скидка %d %s, ожидалась %d RUB", applied.Amount.Amount, applied.Amount.Currency, tt.wantDiscount)
+			}
+
+			var sum int64
+			for i, item := range tt.items {
+				sum += item.Discount.Amount
+				if item.Discount.Amount != tt.wantDiscounts[i] {
+					t.Errorf("скидка позиции %d: %d, ожидалась %d", i, item.Discount.Amount, tt.wantDiscounts[i])
+				}
+				if item.Discount.Amount > item.Price.Amount*int64(item.Quantity) {
+					t.Errorf("скидка позиции %d больше ее стоимости", i)
+				}
+			}
+			if sum != applied.Amount.Amount {
+				t.Errorf("сумма скидок позиций %d не равна скидке %d", sum, applied.Amount.Amount)
+			}
+		})
+	}
+}
+
+func TestApplyFixedWithoutCurrency(t *testing.T) {
+	code := models.PromoCode{
+		Code:    "MINUS",
+		Kind:    models.PromoKindFixed,
+		Amounts: models.CurrencyAmounts{"EUR": models.NewMoney(100, "EUR")},
+		Enabled: true,
+	}
+	items := []models.OrderItem{{Price: models.NewMoney(1000, "RUB"), Quantity: 1, Currency: "RUB"}}
+
+	if _, err := Apply(code, items, time.Now()); !errors.Is(err, ErrNotApplicable) {
+		t.Fatalf("ошибка %v, ожидалась ErrNotApplicable", err)
+	}
+}
@@ -0,0 +1,123 @@
+package reminder
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+	"pryanik_studio/internal/utils"
+)
+
+// ContactNotifier отправляет уведомления о сообщениях с формы обратной связи в фоне: сразу после
+// сохранения сообщения и повторно с интервалом из настроек, если письмо не удалось отправить
+type ContactNotifier struct {
+	messages storage.ContactRepository
+	sender   utils.Sender
+	config   config.ContactConfig
+	logger   *logrus.Logger
+
+	wake chan struct{}
+}
+
+// NewContactNotifier создает новый экземпляр ContactNotifier
+func NewContactNotifier(
+	messages storage.ContactRepository,
+	sender utils.Sender,
+	cfg *config.Config,
+	logger *logrus.Logger,
+) *ContactNotifier {
+	return &ContactNotifier{
+		messages: messages,
+		sender:   sender,
+		config:   cfg.Contact,
+		logger:   logger,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Wake запускает отправку уведомлений, не дожидаясь очередного интервала. Не блокирует вызывающего
+func (n *ContactNotifier) Wake() {
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run отправляет уведомления по запросу Wake и с интервалом из настроек, пока не отменен ctx
+func (n *ContactNotifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(n.config.NotifyRetryIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		n.SendNotifications(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-n.wake:
+		}
+	}
+}
+
+// SendNotifications отправляет уведомления по всем сообщениям, о которых еще не сообщено
+func (n *ContactNotifier) SendNotifications(ctx context.Context) {
+	for ctx.Err() == nil {
+		messages, err := n.messages.ClaimContactNotifications(ctx, n.config.NotifyMaxAttempts, batchSize)
+		if err != nil {
+			return
+		}
+
+		failed := false
+		for _, message := range messages {
+			if !n.notify(ctx, message) {
+				failed = true
+			}
+		}
+
+		// Неотправленные уведомления возвращены и будут выбраны снова,
+		// поэтому после ошибки отправка откладывается до следующего запуска
+		if failed || len(messages) < batchSize {
+			break
+		}
+	}
+}
+
+// notify отправляет подтверждение клиенту и уведомление администратору о сообщении, пропуская уже
+// отправленные. Если какое-либо письмо отправить не удалось, отметка об отправке снимается,
+// и при следующем запуске повторно отправляется только оно
+func (n *ContactNotifier) notify(ctx context.Context, message models.ContactMessage) bool {
+	form := message.ContactForm()
+	sent := true
+
+	if message.CustomerNotifiedAt == nil {
+		if err := n.sender.SendContactConfirmation(form); err != nil {
+			n.logger.WithError(err).Errorf("Ошибка при отправке подтверждения по сообщению ID=%d (попытка %d из %d)",
+				message.ID, message.NotifyAttempts, n.config.NotifyMaxAttempts)
+			sent = false
+		} else if err := n.messages.MarkContactCustomerNotified(ctx, message.ID); err != nil {
+			n.logger.WithError(err).Warnf("Подтверждение по сообщению ID=%d может быть отправлено повторно", message.ID)
+		}
+	}
+
+	if message.AdminNotifiedAt == nil {
+		if err := n.sender.SendContactNotification(form); err != nil {
+			n.logger.WithError(err).Errorf("Ошибка при отправке уведомления о сообщении ID=%d (попытка %d из %d)",
+				message.ID, message.NotifyAttempts, n.config.NotifyMaxAttempts)
+			sent = false
+		} else if err := n.messages.MarkContactAdminNotified(ctx, message.ID); err != nil {
+			n.logger.WithError(err).Warnf("Уведомление о сообщении ID=%d может быть отправлено повторно", message.ID)
+		}
+	}
+
+	if !sent {
+		if err := n.messages.ReleaseContactNotification(ctx, message.ID); err != nil {
+			n.logger.WithError(err).Errorf("Уведомление о сообщении ID=%d не будет отправлено повторно", message.ID)
+		}
+	}
+	return sent
+}
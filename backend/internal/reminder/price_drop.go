@@ -0,0 +1,160 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/storage"
+	"pryanik_studio/internal/utils"
+)
+
+// PriceWatcher периодически проверяет цены товаров из списков избранного с подпиской
+// на снижение цены и отправляет покупателям одно письмо обо всех подешевевших товарах
+type PriceWatcher struct {
+	wishlists storage.WishlistRepository
+	products  storage.ProductRepository
+	sender    utils.Sender
+	config    config.WishlistConfig
+	publicURL string
+	logger    *logrus.Logger
+}
+
+// NewPriceWatcher создает новый экземпляр PriceWatcher
+func NewPriceWatcher(
+	wishlists storage.WishlistRepository,
+	products storage.ProductRepository,
+	sender utils.Sender,
+	cfg *config.Config,
+	logger *logrus.Logger,
+) *PriceWatcher {
+	return &PriceWatcher{
+		wishlists: wishlists,
+		products:  products,
+		sender:    sender,
+		config:    cfg.Wishlist,
+		publicURL: cfg.Server.PublicURL,
+		logger:    logger,
+	}
+}
+
+// Run проверяет цены с интервалом из настроек, пока не отменен ctx.
+// Если уведомления отключены, сразу возвращает управление
+func (w *PriceWatcher) Run(ctx context.Context) {
+	if !w.config.PriceAlertsEnabled {
+		w.logger.Info("Уведомления о снижении цен отключены")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(w.config.PriceCheckIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		w.SendPriceDrops(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendPriceDrops отправляет уведомления о всех товарах, цена которых стала ниже запомненной
+func (w *PriceWatcher) SendPriceDrops(ctx context.Context) {
+	if err := w.wishlists.RebaseWatchedPrices(ctx); err != nil {
+		return
+	}
+
+	sent := 0
+	for ctx.Err() == nil {
+		drops, err := w.wishlists.ClaimPriceDrops(ctx, batchSize)
+		if err != nil {
+			return
+		}
+
+		// Товары одного покупателя из пакета отправляются одним письмом
+		var order []int64
+		byCustomer := make(map[int64][]models.PriceDrop)
+		for _, drop := range drops {
+			if _, ok := byCustomer[drop.CustomerID]; !ok {
+				order = append(order, drop.CustomerID)
+			}
+			byCustomer[drop.CustomerID] = append(byCustomer[drop.CustomerID], drop)
+		}
+		failed := false
+		for _, customerID := range order {
+			ok, err := w.notify(ctx, byCustomer[customerID])
+			if err != nil {
+				failed = true
+			}
+			if ok {
+				sent++
+			}
+		}
+
+		// Цены из неотправленных уведомлений возвращены и будут выбраны снова,
+		// поэтому после ошибки проверка откладывается до следующего запуска
+		if failed || len(drops) < batchSize {
+			break
+		}
+	}
+
+	if sent > 0 {
+		w.logger.Infof("Отправлено уведомлений о снижении цен: %d", sent)
+	}
+}
+
+// notify отправляет покупателю письмо о снижении цен и сообщает, было ли оно отправлено. Если письмо
+// отправить не удалось, запомненные цены возвращаются, и уведомление будет отправлено при следующей проверке
+func (w *PriceWatcher) notify(ctx context.Context, drops []models.PriceDrop) (bool, error) {
+	first := drops[0]
+	alert := &models.PriceDropAlert{
+		Email:       first.Email,
+		Name:        first.Name,
+		Language:    first.Language,
+		WishlistURL: w.publicURL + "/wishlist",
+	}
+
+	for _, drop := range drops {
+		product, err := w.products.GetProductByID(ctx, drop.ProductID, drop.Language)
+		if errors.Is(err, storage.ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			w.logger.WithError(err).Errorf("Ошибка при подготовке уведомления о снижении цены товара ID=%d", drop.ProductID)
+			w.release(ctx, drops)
+			return false, err
+		}
+		alert.Items = append(alert.Items, models.PriceDropItem{
+			Name:     product.Name,
+			OldPrice: drop.OldPrice,
+			NewPrice: drop.NewPrice,
+			URL:      fmt.Sprintf("%s/catalog/%d", w.publicURL, drop.ProductID),
+		})
+	}
+	if len(alert.Items) == 0 {
+		return false, nil
+	}
+
+	if err := w.sender.SendPriceDropAlert(alert); err != nil {
+		w.logger.WithError(err).Errorf("Ошибка при отправке уведомления о снижении цен покупателю ID=%d", first.CustomerID)
+		w.release(ctx, drops)
+		return false, err
+	}
+	return true, nil
+}
+
+// release возвращает запомненные цены товаров из неотправленного уведомления
+func (w *PriceWatcher) release(ctx context.Context, drops []models.PriceDrop) {
+	for _, drop := range drops {
+		if err := w.wishlists.ReleasePriceDrop(ctx, drop); err != nil {
+			w.logger.WithError(err).Errorf("Уведомление о снижении цены товара ID=%d не будет отправлено повторно", drop.ProductID)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/models"
+	"pryanik_studio/internal/security"
+	"pryanik_studio/internal/storage"
+	"pryanik_studio/internal/utils"
+)
+
+const (
+	// maxAttempts количество попыток отправки напоминания по черновику
+	maxAttempts = 3
+	// batchSize количество черновиков, выбираемых за один запрос
+	batchSize = 50
+)
+
+// UnsubscribeResource возвращает имя ресурса для подписи ссылки на отказ от напоминаний
+func UnsubscribeResource(email string) string {
+	return "reminder-unsubscribe:" + email
+}
+
+// Scheduler периодически находит черновики заказов, которые покупатели бросили,
+// и отправляет по ним напоминания со ссылкой для восстановления корзины
+type Scheduler struct {
+	drafts    storage.CheckoutDraftRepository
+	products  storage.ProductRepository
+	sender    utils.Sender
+	signer    *security.LinkSigner
+	config    config.ReminderConfig
+	publicURL string
+	logger    *logrus.Logger
+}
+
+// NewScheduler создает новый экземпляр Scheduler
+func NewScheduler(
+	drafts storage.CheckoutDraftRepository,
+	products storage.ProductRepository,
+	sender utils.Sender,
+	signer *security.LinkSigner,
+	cfg *config.Config,
+	logger *logrus.Logger,
+) *Scheduler {
+	return &Scheduler{
+		drafts:    drafts,
+		products:  products,
+		sender:    sender,
+		signer:    signer,
+		config:    cfg.Reminder,
+		publicURL: cfg.Server.PublicURL,
+		logger:    logger,
+	}
+}
+
+// Run отправляет напоминания с интервалом из настроек, пока не отменен ctx.
+// Если напоминания отключены, сразу возвращает управление
+func (s *Scheduler) Run(ctx context.Context) {
+	if !s.config.Enabled {
+		s.logger.Info("Напоминания о незавершенных заказах отключены")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(s.config.CheckIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		s.SendReminders(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendReminders удаляет устаревшие черновики и отправляет напоминания по всем черновикам,
+// которые не изменялись дольше заданного времени
+func (s *Scheduler) SendReminders(ctx context.Context) {
+	retention := time.Duration(s.config.DraftRetentionDays) * 24 * time.Hour
+	if deleted, err := s.drafts.DeleteStaleCheckoutDrafts(ctx, retention); err == nil && deleted > 0 {
+		s.logger.Infof("Удалено устаревших черновиков заказов: %d", deleted)
+	}
+
+	idle := time.Duration(s.config.IdleHours) * time.Hour
+	maxAge := time.Duration(s.config.MaxAgeHours) * time.Hour
+
+	sent := 0
+	for ctx.Err() == nil {
+		drafts, err := s.drafts.ClaimCheckoutReminders(ctx, idle, maxAge, maxAttempts, batchSize)
+		if err != nil {
+			return
+		}
+
+		for _, draft := range drafts {
+			if s.remind(ctx, draft) {
+				sent++
+			}
+		}
+
+		if len(drafts) < batchSize {
+			break
+		}
+	}
+
+	if sent > 0 {
+		s.logger.Infof("Отправлено напоминаний о незавершенных заказах: %d", sent)
+	}
+}
+
+// remind отправляет напоминание по черновику. Если письмо отправить не удалось,
+// отметка об отправке снимается, и напоминание будет отправлено при следующем запуске
+func (s *Scheduler) remind(ctx context.Context, draft models.CheckoutDraft) bool {
+	items, err := s.reminderItems(ctx, draft)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Ошибка при подготовке напоминания по черновику %s", draft.ID)
+		s.release(ctx, draft.ID)
+		return false
+	}
+	// Если товары из черновика больше не продаются, напоминать не о чем
+	if len(items) == 0 {
+		return false
+	}
+
+	reminder := &models.CheckoutReminder{
+		Email:          draft.Email,
+		Language:       draft.Language,
+		Items:          items,
+		RestoreURL:     fmt.Sprintf("%s/cart?draft=%s", s.publicURL, draft.ID),
+		UnsubscribeURL: s.unsubscribeURL(draft.Email),
+	}
+	if err := s.sender.SendCheckoutReminder(reminder); err != nil {
+		s.logger.WithError(err).Errorf("Ошибка при отправке напоминания по черновику %s", draft.ID)
+		s.release(ctx, draft.ID)
+		return false
+	}
+	return true
+}
+
+// reminderItems возвращает товары черновика, которые еще можно заказать, с названиями на языке черновика
+func (s *Scheduler) reminderItems(ctx context.Context, draft models.CheckoutDraft) ([]models.ReminderItem, error) {
+	items := make([]models.ReminderItem, 0, len(draft.Items))
+	for _, item := range draft.Items {
+		product, err := s.products.GetProductByID(ctx, item.ProductID, draft.Language)
+		if errors.Is(err, storage.ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !product.Published {
+			continue
+		}
+		items = append(items, models.ReminderItem{Name: product.Name, Quantity: item.Quantity})
+	}
+	return items, nil
+}
+
+// release снимает отметку об отправке напоминания
+func (s *Scheduler) release(ctx context.Context, id string) {
+	if err := s.drafts.ReleaseCheckoutReminder(ctx, id); err != nil {
+		s.logger.WithError(err).Errorf("Напоминание по черновику %s не будет отправлено повторно", id)
+	}
+}
+
+// unsubscribeURL возвращает подписанную ссылку на страницу отказа от напоминаний
+func (s *Scheduler) unsubscribeURL(email string) string {
+	expires := time.Now().AddDate(0, 0, s.config.UnsubscribeValidDays)
+	query := s.signer.Query(UnsubscribeResource(email), expires)
+	query.Set("email", email)
+	return fmt.Sprintf("%s/unsubscribe?%s", s.publicURL, query.Encode())
+}
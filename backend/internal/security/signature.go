@@ -0,0 +1,67 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrInvalidSignature ошибка при недействительной подписи ссылки
+	ErrInvalidSignature = errors.New("недействительная подпись ссылки")
+
+	// ErrSignatureExpired ошибка при истекшем сроке действия ссылки
+	ErrSignatureExpired = errors.New("срок действия ссылки истек")
+)
+
+// LinkSigner подписывает ссылки, которые отправляются клиентам по email
+// (принятие предложения, оплата и т.п.), чтобы их нельзя было подделать
+type LinkSigner struct {
+	secret []byte
+}
+
+// NewLinkSigner создает новый экземпляр LinkSigner
+func NewLinkSigner(secret string) *LinkSigner {
+	return &LinkSigner{
+		secret: []byte(secret),
+	}
+}
+
+// Sign возвращает подпись ресурса (например, "quote:15") со сроком действия
+func (s *LinkSigner) Sign(resource string, expires time.Time) string {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(fmt.Sprintf("%s|%d", resource, expires.Unix())))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Query возвращает параметры expires и signature для подписанной ссылки
+func (s *LinkSigner) Query(resource string, expires time.Time) url.Values {
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", s.Sign(resource, expires))
+	return query
+}
+
+// Verify проверяет подпись ресурса и срок действия ссылки
+func (s *LinkSigner) Verify(resource, expires, signature string) error {
+	timestamp, err := parseTimestamp(expires)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	expected := s.Sign(resource, time.Unix(timestamp, 0))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	if time.Now().Unix() > timestamp {
+		return ErrSignatureExpired
+	}
+
+	return nil
+}
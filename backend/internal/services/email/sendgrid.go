@@ -19,9 +19,10 @@ func NewSendGridService(apiKey, fromEmail, fromName string) *SendGridService {
 	}
 }
 
-func (s *SendGridService) SendEmail(to, subject, htmlContent, textContent string) error {
+func (s *SendGridService) SendEmail(to, subject, htmlContent, textContent string, attachments ...*mail.Attachment) error {
 	toEmail := mail.NewEmail("", to)
 	message := mail.NewSingleEmail(s.from, subject, toEmail, textContent, htmlContent)
+	message.AddAttachment(attachments...)
 
 	// Добавляем категорию для аналитики
 	message.AddCategories("prianik-studio")
@@ -0,0 +1,132 @@
+package shipping
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"pryanik_studio/internal/config"
+	"pryanik_studio/internal/models"
+)
+
+// ErrNotAvailable возвращается, если способ доставки недоступен для адреса или корзины
+var ErrNotAvailable = errors.New("способ доставки недоступен")
+
+// Error ошибка расчета доставки с причиной, которая показывается покупателю
+type Error struct {
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return ErrNotAvailable.Error() + ": " + e.Reason
+}
+
+// Is позволяет проверять ошибку через errors.Is(err, ErrNotAvailable)
+func (e *Error) Is(target error) bool {
+	return target == ErrNotAvailable
+}
+
+// notAvailable возвращает ошибку расчета доставки с причиной
+func notAvailable(format string, args ...interface{}) error {
+	return &Error{Reason: fmt.Sprintf(format, args...)}
+}
+
+// ParcelWeight рассчитывает вес посылки в граммах. Для каждой позиции берется большее из
+// фактического и объемного веса; если вес товара не задан, используется вес по умолчанию
+func ParcelWeight(items []models.OrderItem, dimensions map[int64]models.ProductDimensions, cfg config.ShippingConfig) int {
+	var total int
+	for _, item := range items {
+		dims := dimensions[item.ProductID]
+
+		weight := cfg.DefaultWeightGrams
+		if dims.WeightGrams != nil {
+			weight = *dims.WeightGrams
+		}
+		if dims.LengthMM != nil && dims.WidthMM != nil && dims.HeightMM != nil && cfg.VolumetricDivisor > 0 {
+			volumetric := *dims.LengthMM * *dims.WidthMM * *dims.HeightMM / cfg.VolumetricDivisor
+			weight = max(weight, volumetric)
+		}
+
+		total += weight * item.Quantity
+	}
+	return total
+}
+
+// Calculate рассчитывает стоимость доставки способом method. goods - стоимость товаров
+// со скидкой, по ней проверяется порог бесплатной доставки; валюта доставки совпадает с валютой товаров
+func Calculate(method models.ShippingMethod, address *models.Address, weight int, goods models.Money) (models.ShippingQuote, error) {
+	quote := models.ShippingQuote{
+		Method:      method.Code,
+		Name:        method.Name,
+		Kind:        method.Kind,
+		Cost:        models.NewMoney(0, goods.Currency),
+		WeightGrams: weight,
+	}
+
+	if !method.Enabled {
+		return quote, notAvailable("Способ доставки отключен")
+	}
+	if method.Kind == models.ShippingKindPickup {
+		quote.PickupAddress = method.PickupAddress
+		return quote, nil
+	}
+	if address == nil {
+		return quote, notAvailable("Укажите адрес доставки")
+	}
+
+	rule, ok := matchRule(method.Rules, *address)
+	if !ok {
+		return quote, notAvailable("Доставка по указанному адресу не выполняется")
+	}
+	quote.DeliveryDays = rule.DeliveryDays
+
+	if rule.MaxWeightGrams > 0 && weight > rule.MaxWeightGrams {
+		return quote, notAvailable("Вес посылки превышает %d г", rule.MaxWeightGrams)
+	}
+
+	base, ok := rule.BasePrice.Get(goods.Currency)
+	if !ok {
+		return quote, notAvailable("Доставка недоступна для валюты %s", goods.Currency)
+	}
+	cost := base.Amount
+	if len(rule.PricePerKg) > 0 {
+		perKg, ok := rule.PricePerKg.Get(goods.Currency)
+		if !ok {
+			return quote, notAvailable("Доставка недоступна для валюты %s", goods.Currency)
+		}
+		// Оплачивается каждый начатый килограмм
+		kilograms := (weight + 999) / 1000
+		cost += perKg.Amount * int64(kilograms)
+	}
+
+	if freeFrom, ok := rule.FreeFrom.Get(goods.Currency); ok && goods.Amount >= freeFrom.Amount {
+		cost = 0
+	}
+
+	quote.Cost = models.NewMoney(cost, goods.Currency)
+	return quote, nil
+}
+
+// matchRule возвращает первое правило, подходящее по стране и городу адреса
+func matchRule(rules models.ShippingRules, address models.Address) (models.ShippingRule, bool) {
+	for _, rule := range rules {
+		if matches(rule.Countries, address.Country) && matches(rule.Cities, address.City) {
+			return rule, true
+		}
+	}
+	return models.ShippingRule{}, false
+}
+
+// matches проверяет значение по списку без учета регистра; пустой список подходит для любого значения
+func matches(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	value = strings.TrimSpace(value)
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
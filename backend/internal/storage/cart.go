@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"pryanik_studio/internal/models"
+)
+
+// Ошибки работы с корзинами
+var (
+	ErrCartNotFound     = errors.New("корзина не найдена")
+	ErrCartItemNotFound = errors.New("позиция корзины не найдена")
+	ErrCartCheckedOut   = errors.New("заказ по корзине уже оформлен или оформляется")
+)
+
+// abandonedCheckoutTimeout время, после которого незавершенное оформление заказа из корзины
+// считается прерванным и корзину можно оформить снова
+const abandonedCheckoutTimeout = "5 minutes"
+
+// cartColumns колонки корзины
+const cartColumns = `id, token_hash, order_id, checkout_started_at, created_at, updated_at, expires_at`
+
+// CreateCart создает пустую корзину, действующую ttl с последнего изменения
+func (r *PostgresRepository) CreateCart(ctx context.Context, id, tokenHash string, ttl time.Duration) (models.Cart, error) {
+	var cart models.Cart
+
+	query := `
+	INSERT INTO carts (id, token_hash, created_at, updated_at, expires_at)
+	VALUES ($1, $2, NOW(), NOW(), NOW() + make_interval(secs => $3))
+	RETURNING ` + cartColumns
+	if err := r.db.GetContext(ctx, &cart, query, id, tokenHash, int64(ttl/time.Second)); err != nil {
+		r.logger.WithError(err).Error("Ошибка при создании корзины")
+		return cart, fmt.Errorf("ошибка при создании корзины: %w", err)
+	}
+
+	cart.Items = []models.CartItem{}
+	return cart, nil
+}
+
+// GetCart возвращает действующую корзину с позициями в порядке добавления
+func (r *PostgresRepository) GetCart(ctx context.Context, id string) (models.Cart, error) {
+	var cart models.Cart
+
+	query := `SELECT ` + cartColumns + ` FROM carts WHERE id = $1 AND expires_at > NOW()`
+	if err := r.db.GetContext(ctx, &cart, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return cart, ErrCartNotFound
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении корзины %s", id)
+		return cart, fmt.Errorf("ошибка при получении корзины: %w", err)
+	}
+
+	itemsQuery := `
+	SELECT id, cart_id, product_id, variant_id, estimate_id, personalization, quantity, price, currency, created_at
+	FROM cart_items
+	WHERE cart_id = $1
+	ORDER BY id
+	`
+	cart.Items = []models.CartItem{}
+	if err := r.db.SelectContext(ctx, &cart.Items, itemsQuery, id); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении позиций корзины %s", id)
+		return cart, fmt.Errorf("ошибка при получении позиций корзины: %w", err)
+	}
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		item.SavedPrice = models.NewMoney(item.SavedPrice.Amount, item.SavedCurrency)
+	}
+
+	return cart, nil
+}
+
+// AddCartItem добавляет позицию в корзину и продлевает срок действия корзины
+func (r *PostgresRepository) AddCartItem(ctx context.Context, cartID string, item *models.CartItem, ttl time.Duration) error {
+	return r.updateCart(ctx, cartID, ttl, func(tx *sqlx.Tx) error {
+		query := `
+		INSERT INTO cart_items (cart_id, product_id, variant_id, estimate_id, personalization, quantity, price, currency,
+		                        created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		RETURNING id, created_at
+		`
+		err := tx.QueryRowxContext(ctx, query,
+			cartID,
+			item.ProductID,
+			item.VariantID,
+			item.EstimateID,
+			item.Personalization,
+			item.Quantity,
+			item.SavedPrice,
+			item.SavedPrice.Currency,
+		).Scan(&item.ID, &item.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("ошибка при добавлении позиции в корзину: %w", err)
+		}
+		item.CartID = cartID
+		return nil
+	})
+}
+
+// UpdateCartItem изменяет количество в позиции корзины и запоминает цену, которую видит покупатель
+func (r *PostgresRepository) UpdateCartItem(ctx context.Context, cartID string, itemID int64, quantity int, price models.Money, ttl time.Duration) error {
+	return r.updateCart(ctx, cartID, ttl, func(tx *sqlx.Tx) error {
+		query := `
+		UPDATE cart_items SET quantity = $1, price = $2, currency = $3, updated_at = NOW()
+		WHERE id = $4 AND cart_id = $5
+		`
+		result, err := tx.ExecContext(ctx, query, quantity, price, price.Currency, itemID, cartID)
+		if err != nil {
+			return fmt.Errorf("ошибка при изменении позиции корзины: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return ErrCartItemNotFound
+		}
+		return nil
+	})
+}
+
+// DeleteCartItem удаляет позицию из корзины
+func (r *PostgresRepository) DeleteCartItem(ctx context.Context, cartID string, itemID int64, ttl time.Duration) error {
+	return r.updateCart(ctx, cartID, ttl, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM cart_items WHERE id = $1 AND cart_id = $2`, itemID, cartID)
+		if err != nil {
+			return fmt.Errorf("ошибка при удалении позиции корзины: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return ErrCartItemNotFound
+		}
+		return nil
+	})
+}
+
+// updateCart изменяет позиции корзины в транзакции и продлевает срок ее действия.
+// Корзину, по которой заказ оформлен или оформляется, изменить нельзя
+func (r *PostgresRepository) updateCart(ctx context.Context, cartID string, ttl time.Duration, update func(tx *sqlx.Tx) error) error {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	// Строка корзины блокируется, чтобы оформление заказа не началось во время изменения
+	var checkedOut bool
+	err = tx.GetContext(ctx, &checkedOut, `
+	SELECT order_id IS NOT NULL
+	    OR COALESCE(checkout_started_at >= NOW() - INTERVAL '`+abandonedCheckoutTimeout+`', false)
+	FROM carts
+	WHERE id = $1 AND expires_at > NOW()
+	FOR UPDATE
+	`, cartID)
+	if err == sql.ErrNoRows {
+		err = ErrCartNotFound
+		return err
+	}
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при блокировке корзины %s", cartID)
+		return fmt.Errorf("ошибка при блокировке корзины: %w", err)
+	}
+	if checkedOut {
+		err = ErrCartCheckedOut
+		return err
+	}
+
+	if err = update(tx); err != nil {
+		if !errors.Is(err, ErrCartItemNotFound) {
+			r.logger.WithError(err).Errorf("Ошибка при изменении корзины %s", cartID)
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE carts SET updated_at = NOW(), expires_at = NOW() + make_interval(secs => $2) WHERE id = $1
+	`, cartID, int64(ttl/time.Second))
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при продлении корзины %s", cartID)
+		return fmt.Errorf("ошибка при продлении корзины: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// StartCartCheckout отмечает начало оформления заказа из корзины. Если заказ уже оформлен
+// или оформляется в другом запросе, возвращает ErrCartCheckedOut
+func (r *PostgresRepository) StartCartCheckout(ctx context.Context, cartID string) error {
+	query := `
+	UPDATE carts SET checkout_started_at = NOW()
+	WHERE id = $1 AND expires_at > NOW() AND order_id IS NULL
+	  AND (checkout_started_at IS NULL OR checkout_started_at < NOW() - INTERVAL '` + abandonedCheckoutTimeout + `')
+	`
+	result, err := r.db.ExecContext(ctx, query, cartID)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при начале оформления заказа из корзины %s", cartID)
+		return fmt.Errorf("ошибка при начале оформления заказа из корзины: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrCartCheckedOut
+	}
+	return nil
+}
+
+// FinishCartCheckout связывает корзину с оформленным из нее заказом
+func (r *PostgresRepository) FinishCartCheckout(ctx context.Context, cartID string, orderID int64) error {
+	query := `UPDATE carts SET order_id = $2, updated_at = NOW() WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, cartID, orderID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при завершении оформления заказа из корзины %s", cartID)
+		return fmt.Errorf("ошибка при завершении оформления заказа из корзины: %w", err)
+	}
+	return nil
+}
+
+// CancelCartCheckout снимает отметку об оформлении заказа, если заказ создать не удалось
+func (r *PostgresRepository) CancelCartCheckout(ctx context.Context, cartID string) error {
+	query := `UPDATE carts SET checkout_started_at = NULL WHERE id = $1 AND order_id IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, cartID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при отмене оформления заказа из корзины %s", cartID)
+		return fmt.Errorf("ошибка при отмене оформления заказа из корзины: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredCarts удаляет истекшие корзины и возвращает их количество
+func (r *PostgresRepository) DeleteExpiredCarts(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM carts WHERE expires_at <= NOW()`)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при удалении истекших корзин")
+		return 0, fmt.Errorf("ошибка при удалении истекших корзин: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при получении количества удаленных корзин: %w", err)
+	}
+	return deleted, nil
+}
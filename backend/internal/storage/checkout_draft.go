@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"pryanik_studio/internal/models"
+)
+
+// Ошибки работы с черновиками заказов
+var (
+	ErrCheckoutDraftNotFound  = errors.New("черновик заказа не найден")
+	ErrCheckoutDraftConverted = errors.New("заказ по черновику уже оформлен")
+)
+
+// checkoutDraftColumns колонки черновика заказа
+const checkoutDraftColumns = `id, email, name, phone, language, cart_id, items, created_at, updated_at,
+	reminder_sent_at, reminder_attempts, order_id, converted_at`
+
+// CreateCheckoutDraft сохраняет новый черновик заказа
+func (r *PostgresRepository) CreateCheckoutDraft(ctx context.Context, draft *models.CheckoutDraft) error {
+	query := `
+	INSERT INTO checkout_drafts (id, email, name, phone, language, cart_id, items, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+	RETURNING ` + checkoutDraftColumns
+	err := r.db.GetContext(ctx, draft, query,
+		draft.ID, draft.Email, draft.Name, draft.Phone, draft.Language, draft.CartID, draft.Items)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при создании черновика заказа")
+		return fmt.Errorf("ошибка при создании черновика заказа: %w", err)
+	}
+	return nil
+}
+
+// UpdateCheckoutDraft заменяет контакты и состав черновика и начинает отсчет времени
+// до напоминания заново. Черновик, по которому оформлен заказ, не изменяется
+func (r *PostgresRepository) UpdateCheckoutDraft(ctx context.Context, draft *models.CheckoutDraft) error {
+	query := `
+	UPDATE checkout_drafts
+	SET email = $2, name = $3, phone = $4, language = $5, cart_id = $6, items = $7, updated_at = NOW()
+	WHERE id = $1 AND converted_at IS NULL
+	RETURNING ` + checkoutDraftColumns
+	err := r.db.GetContext(ctx, draft, query,
+		draft.ID, draft.Email, draft.Name, draft.Phone, draft.Language, draft.CartID, draft.Items)
+	if err == sql.ErrNoRows {
+		if _, err := r.GetCheckoutDraft(ctx, draft.ID); err != nil {
+			return err
+		}
+		return ErrCheckoutDraftConverted
+	}
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при изменении черновика заказа %s", draft.ID)
+		return fmt.Errorf("ошибка при изменении черновика заказа: %w", err)
+	}
+	return nil
+}
+
+// GetCheckoutDraft возвращает черновик заказа по ID
+func (r *PostgresRepository) GetCheckoutDraft(ctx context.Context, id string) (models.CheckoutDraft, error) {
+	var draft models.CheckoutDraft
+
+	query := `SELECT ` + checkoutDraftColumns + ` FROM checkout_drafts WHERE id = $1`
+	if err := r.db.GetContext(ctx, &draft, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return draft, ErrCheckoutDraftNotFound
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении черновика заказа %s", id)
+		return draft, fmt.Errorf("ошибка при получении черновика заказа: %w", err)
+	}
+	return draft, nil
+}
+
+// CountCheckoutDrafts возвращает количество черновиков с адресом email, измененных за период period,
+// кроме черновика excludeID
+func (r *PostgresRepository) CountCheckoutDrafts(ctx context.Context, email, excludeID string, period time.Duration) (int, error) {
+	query := `
+	SELECT COUNT(*) FROM checkout_drafts
+	WHERE email = $1 AND id <> $2 AND updated_at >= NOW() - make_interval(secs => $3)
+	`
+	var count int
+	if err := r.db.GetContext(ctx, &count, query, email, excludeID, int64(period/time.Second)); err != nil {
+		r.logger.WithError(err).Error("Ошибка при подсчете черновиков заказа")
+		return 0, fmt.Errorf("ошибка при подсчете черновиков заказа: %w", err)
+	}
+	return count, nil
+}
+
+// ClaimCheckoutReminders выбирает до limit черновиков с товарами, которые не изменялись дольше idle,
+// но изменялись не раньше maxAge назад, и отмечает отправку напоминания по ним. Черновики
+// с оформленным заказом, отправленным напоминанием, maxAttempts неудачными попытками
+// отправки или адресом, отказавшимся от напоминаний, не выбираются. Черновики,
+// выбранные другим экземпляром сервера, пропускаются
+func (r *PostgresRepository) ClaimCheckoutReminders(ctx context.Context, idle, maxAge time.Duration, maxAttempts, limit int) ([]models.CheckoutDraft, error) {
+	query := `
+	UPDATE checkout_drafts SET reminder_sent_at = NOW(), reminder_attempts = reminder_attempts + 1
+	WHERE id IN (
+		SELECT d.id FROM checkout_drafts d
+		WHERE d.reminder_sent_at IS NULL AND d.converted_at IS NULL AND d.items IS NOT NULL
+		  AND d.reminder_attempts < $3
+		  AND d.updated_at < NOW() - make_interval(secs => $1)
+		  AND d.updated_at >= NOW() - make_interval(secs => $2)
+		  AND NOT EXISTS (SELECT 1 FROM reminder_unsubscribes u WHERE u.email = d.email)
+		ORDER BY d.updated_at
+		LIMIT $4
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + checkoutDraftColumns
+
+	drafts := []models.CheckoutDraft{}
+	err := r.db.SelectContext(ctx, &drafts, query,
+		int64(idle/time.Second), int64(maxAge/time.Second), maxAttempts, limit)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при выборе черновиков заказов для напоминаний")
+		return nil, fmt.Errorf("ошибка при выборе черновиков заказов для напоминаний: %w", err)
+	}
+	return drafts, nil
+}
+
+// ReleaseCheckoutReminder снимает отметку об отправке напоминания, если письмо отправить не удалось;
+// напоминание будет отправлено повторно, пока не исчерпаны попытки
+func (r *PostgresRepository) ReleaseCheckoutReminder(ctx context.Context, id string) error {
+	query := `UPDATE checkout_drafts SET reminder_sent_at = NULL WHERE id = $1 AND converted_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при снятии отметки о напоминании по черновику %s", id)
+		return fmt.Errorf("ошибка при снятии отметки о напоминании: %w", err)
+	}
+	return nil
+}
+
+// MarkCheckoutDraftConverted связывает с оформленным заказом черновик draftID.
+// Другие черновики с тем же email не трогаются: адрес в них не подтвержден, и чужой
+// заказ не должен отключать напоминания по ним
+func (r *PostgresRepository) MarkCheckoutDraftConverted(ctx context.Context, draftID string, orderID int64) error {
+	query := `UPDATE checkout_drafts SET order_id = $2, converted_at = NOW() WHERE id = $1 AND converted_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, draftID, orderID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при отметке черновика заказа ID=%d", orderID)
+		return fmt.Errorf("ошибка при отметке черновика заказа: %w", err)
+	}
+	return nil
+}
+
+// DeleteStaleCheckoutDrafts удаляет черновики без заказа, не изменявшиеся дольше retention,
+// и возвращает их количество. Черновики с заказом остаются для статистики
+func (r *PostgresRepository) DeleteStaleCheckoutDrafts(ctx context.Context, retention time.Duration) (int64, error) {
+	query := `DELETE FROM checkout_drafts WHERE converted_at IS NULL AND updated_at < NOW() - make_interval(secs => $1)`
+	result, err := r.db.ExecContext(ctx, query, int64(retention/time.Second))
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при удалении устаревших черновиков заказов")
+		return 0, fmt.Errorf("ошибка при удалении устаревших черновиков заказов: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при получении количества удаленных черновиков: %w", err)
+	}
+	return deleted, nil
+}
+
+// UnsubscribeFromReminders отключает напоминания о незавершенных заказах для email
+func (r *PostgresRepository) UnsubscribeFromReminders(ctx context.Context, email string) error {
+	query := `
+	INSERT INTO reminder_unsubscribes (email, created_at) VALUES ($1, NOW())
+	ON CONFLICT (email) DO NOTHING
+	`
+	if _, err := r.db.ExecContext(ctx, query, email); err != nil {
+		r.logger.WithError(err).Error("Ошибка при отказе от напоминаний")
+		return fmt.Errorf("ошибка при отказе от напоминаний: %w", err)
+	}
+	return nil
+}
+
+// GetReminderStats возвращает статистику черновиков, созданных за последние period
+func (r *PostgresRepository) GetReminderStats(ctx context.Context, period time.Duration) (models.ReminderStats, error) {
+	var stats models.ReminderStats
+
+	query := `
+	SELECT COUNT(*) AS drafts,
+	       COUNT(*) FILTER (WHERE reminder_sent_at IS NOT NULL) AS reminded,
+	       COUNT(*) FILTER (WHERE converted_at IS NOT NULL) AS converted,
+	       COUNT(*) FILTER (WHERE converted_at > reminder_sent_at) AS converted_after_reminder,
+	       (SELECT COUNT(*) FROM reminder_unsubscribes) AS unsubscribed
+	FROM checkout_drafts
+	WHERE created_at >= NOW() - make_interval(secs => $1)
+	`
+	if err := r.db.GetContext(ctx, &stats, query, int64(period/time.Second)); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении статистики напоминаний")
+		return stats, fmt.Errorf("ошибка при получении статистики напоминаний: %w", err)
+	}
+	return stats, nil
+}
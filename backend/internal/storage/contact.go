@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"pryanik_studio/internal/models"
+)
+
+// ErrContactMessageNotFound возвращается, если сообщение с формы обратной связи не найдено
+var ErrContactMessageNotFound = errors.New("сообщение не найдено")
+
+// contactMessageColumns колонки сообщения с формы обратной связи
+const contactMessageColumns = `id, name, email, phone, message, language, status, assigned_to,
+	read_at, answered_at, notified_at, customer_notified_at, admin_notified_at, notify_attempts,
+	created_at, updated_at`
+
+// CreateContactMessage сохраняет новое сообщение с формы обратной связи
+func (r *PostgresRepository) CreateContactMessage(ctx context.Context, message *models.ContactMessage) error {
+	query := `
+	INSERT INTO contact_messages (name, email, phone, message, language, status, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+	RETURNING ` + contactMessageColumns
+	err := r.db.GetContext(ctx, message, query,
+		message.Name,
+		message.Email,
+		message.Phone,
+		message.Message,
+		message.Language,
+		models.ContactStatusNew,
+	)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при сохранении сообщения с формы обратной связи")
+		return fmt.Errorf("ошибка при сохранении сообщения: %w", err)
+	}
+	return nil
+}
+
+// GetContactMessages возвращает страницу сообщений по фильтру, начиная с последних
+func (r *PostgresRepository) GetContactMessages(ctx context.Context, filter models.ContactMessageFilter) (models.ContactMessageList, error) {
+	result := models.ContactMessageList{
+		Items:    []models.ContactMessage{},
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}
+
+	var conditions []string
+	var args []interface{}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.AssignedTo != nil {
+		args = append(args, *filter.AssignedTo)
+		conditions = append(conditions, fmt.Sprintf("assigned_to = $%d", len(args)))
+	}
+	if filter.Search != "" {
+		args = append(args, "%"+filter.Search+"%")
+		conditions = append(conditions, fmt.Sprintf(
+			"(name ILIKE $%[1]d OR email ILIKE $%[1]d OR phone ILIKE $%[1]d OR message ILIKE $%[1]d)", len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var totalItems int
+	if err := r.db.GetContext(ctx, &totalItems, `SELECT COUNT(*) FROM contact_messages `+where, args...); err != nil {
+		r.logger.WithError(err).Error("Ошибка при подсчете сообщений")
+		return result, fmt.Errorf("ошибка при подсчете сообщений: %w", err)
+	}
+	result.TotalItems = totalItems
+	result.TotalPages = int(math.Ceil(float64(totalItems) / float64(filter.PageSize)))
+
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	query := fmt.Sprintf(`
+	SELECT %s FROM contact_messages
+	%s
+	ORDER BY created_at DESC, id DESC
+	LIMIT $%d OFFSET $%d
+	`, contactMessageColumns, where, len(args)-1, len(args))
+	if err := r.db.SelectContext(ctx, &result.Items, query, args...); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении сообщений")
+		return result, fmt.Errorf("ошибка при получении сообщений: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetContactMessage возвращает сообщение по ID вместе с внутренними заметками
+func (r *PostgresRepository) GetContactMessage(ctx context.Context, id int64) (models.ContactMessage, error) {
+	var message models.ContactMessage
+
+	query := `SELECT ` + contactMessageColumns + ` FROM contact_messages WHERE id = $1`
+	if err := r.db.GetContext(ctx, &message, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return message, ErrContactMessageNotFound
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении сообщения ID=%d", id)
+		return message, fmt.Errorf("ошибка при получении сообщения: %w", err)
+	}
+
+	message.Notes = []models.ContactNote{}
+	notesQuery := `
+	SELECT id, message_id, author, text, created_at FROM contact_message_notes
+	WHERE message_id = $1
+	ORDER BY created_at, id
+	`
+	if err := r.db.SelectContext(ctx, &message.Notes, notesQuery, id); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении заметок к сообщению ID=%d", id)
+		return message, fmt.Errorf("ошибка при получении заметок к сообщению: %w", err)
+	}
+
+	return message, nil
+}
+
+// UpdateContactMessage меняет статус сообщения и (или) ответственного. Пустой status оставляет статус
+// без изменений, nil assignedTo - ответственного без изменений. Время прочтения и ответа
+// запоминается при первом переходе в соответствующий статус
+func (r *PostgresRepository) UpdateContactMessage(ctx context.Context, id int64, status string, assignedTo *string) error {
+	query := `
+	UPDATE contact_messages SET
+		status = COALESCE(NULLIF($2, ''), status),
+		read_at = CASE WHEN $2 IN ($3, $4) THEN COALESCE(read_at, NOW()) ELSE read_at END,
+		answered_at = CASE WHEN $2 = $4 THEN COALESCE(answered_at, NOW()) ELSE answered_at END,
+		assigned_to = COALESCE($5::text, assigned_to),
+		updated_at = NOW()
+	WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query, id, status, models.ContactStatusRead, models.ContactStatusAnswered, assignedTo)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при изменении сообщения ID=%d", id)
+		return fmt.Errorf("ошибка при изменении сообщения: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при получении результата изменения сообщения: %w", err)
+	}
+	if updated == 0 {
+		return ErrContactMessageNotFound
+	}
+	return nil
+}
+
+// AddContactNote добавляет внутреннюю заметку к сообщению
+func (r *PostgresRepository) AddContactNote(ctx context.Context, note *models.ContactNote) error {
+	query := `
+	INSERT INTO contact_message_notes (message_id, author, text, created_at)
+	SELECT id, $2, $3, NOW() FROM contact_messages WHERE id = $1
+	RETURNING id, message_id, author, text, created_at
+	`
+	if err := r.db.GetContext(ctx, note, query, note.MessageID, note.Author, note.Text); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrContactMessageNotFound
+		}
+		r.logger.WithError(err).Errorf("Ошибка при добавлении заметки к сообщению ID=%d", note.MessageID)
+		return fmt.Errorf("ошибка при добавлении заметки: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, `UPDATE contact_messages SET updated_at = NOW() WHERE id = $1`, note.MessageID); err != nil {
+		r.logger.WithError(err).Warnf("Не удалось обновить время изменения сообщения ID=%d", note.MessageID)
+	}
+	return nil
+}
+
+// ClaimContactNotifications отмечает отправку уведомлений по сообщениям, о которых еще не сообщено
+// и попытки не исчерпаны, и возвращает их. Отмеченные сообщения не выбираются параллельными запусками
+func (r *PostgresRepository) ClaimContactNotifications(ctx context.Context, maxAttempts, limit int) ([]models.ContactMessage, error) {
+	query := `
+	UPDATE contact_messages SET notified_at = NOW(), notify_attempts = notify_attempts + 1
+	WHERE id IN (
+		SELECT id FROM contact_messages
+		WHERE notified_at IS NULL AND notify_attempts < $1
+		ORDER BY created_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + contactMessageColumns
+
+	messages := []models.ContactMessage{}
+	if err := r.db.SelectContext(ctx, &messages, query, maxAttempts, limit); err != nil {
+		r.logger.WithError(err).Error("Ошибка при выборе сообщений для уведомлений")
+		return nil, fmt.Errorf("ошибка при выборе сообщений для уведомлений: %w", err)
+	}
+	return messages, nil
+}
+
+// MarkContactCustomerNotified отмечает отправку подтверждения клиенту по сообщению
+func (r *PostgresRepository) MarkContactCustomerNotified(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE contact_messages SET customer_notified_at = NOW() WHERE id = $1`, id); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при отметке подтверждения клиенту по сообщению ID=%d", id)
+		return fmt.Errorf("ошибка при отметке подтверждения клиенту: %w", err)
+	}
+	return nil
+}
+
+// MarkContactAdminNotified отмечает отправку уведомления администратору по сообщению
+func (r *PostgresRepository) MarkContactAdminNotified(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE contact_messages SET admin_notified_at = NOW() WHERE id = $1`, id); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при отметке уведомления администратору по сообщению ID=%d", id)
+		return fmt.Errorf("ошибка при отметке уведомления администратору: %w", err)
+	}
+	return nil
+}
+
+// ReleaseContactNotification снимает отметку об отправке уведомления, если письмо отправить не удалось;
+// уведомление будет отправлено повторно, пока не исчерпаны попытки
+func (r *PostgresRepository) ReleaseContactNotification(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE contact_messages SET notified_at = NULL WHERE id = $1`, id); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при снятии отметки об уведомлении по сообщению ID=%d", id)
+		return fmt.Errorf("ошибка при снятии отметки об уведомлении: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"pryanik_studio/internal/models"
+)
+
+// GetExchangeRates возвращает все курсы валют
+func (r *PostgresRepository) GetExchangeRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	var rates []models.ExchangeRate
+
+	query := `
+	SELECT currency, rate::text AS rate, rounding_step, rounding_mode, source, updated_at
+	FROM exchange_rates
+	ORDER BY currency
+	`
+
+	if err := r.db.SelectContext(ctx, &rates, query); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении курсов валют")
+		return nil, fmt.Errorf("ошибка при получении курсов валют: %w", err)
+	}
+
+	return rates, nil
+}
+
+// SaveExchangeRate добавляет или обновляет курс валюты вместе с правилом округления
+func (r *PostgresRepository) SaveExchangeRate(ctx context.Context, rate *models.ExchangeRate) error {
+	rate.UpdatedAt = time.Now()
+
+	query := `
+	INSERT INTO exchange_rates (currency, rate, rounding_step, rounding_mode, source, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (currency) DO UPDATE
+	SET rate = EXCLUDED.rate,
+	    rounding_step = EXCLUDED.rounding_step,
+	    rounding_mode = EXCLUDED.rounding_mode,
+	    source = EXCLUDED.source,
+	    updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query, rate.Currency, rate.Rate, rate.RoundingStep, rate.RoundingMode, rate.Source, rate.UpdatedAt)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при сохранении курса валюты %s", rate.Currency)
+		return fmt.Errorf("ошибка при сохранении курса валюты: %w", err)
+	}
+
+	return nil
+}
+
+// ImportExchangeRates обновляет курсы валют, не затрагивая настроенные правила округления
+func (r *PostgresRepository) ImportExchangeRates(ctx context.Context, rates []models.ExchangeRate) error {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для импорта курсов валют")
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	// Добавляем отложенную функцию для отката транзакции в случае ошибки
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	query := `
+	INSERT INTO exchange_rates (currency, rate, source, updated_at)
+	VALUES ($1, $2, $3, NOW())
+	ON CONFLICT (currency) DO UPDATE
+	SET rate = EXCLUDED.rate,
+	    source = EXCLUDED.source,
+	    updated_at = EXCLUDED.updated_at
+	`
+
+	for _, rate := range rates {
+		if _, err = tx.ExecContext(ctx, query, rate.Currency, rate.Rate, rate.Source); err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при импорте курса валюты %s", rate.Currency)
+			return fmt.Errorf("ошибка при импорте курса валюты: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
+
+// GetDerivedPrices возвращает переводы товаров с базовой ценой, которые нужно пересчитать
+// в валюту языка перевода. Если productID равен 0, возвращаются все такие товары
+func (r *PostgresRepository) GetDerivedPrices(ctx context.Context, productID int64) ([]models.DerivedPrice, error) {
+	var prices []models.DerivedPrice
+
+	query := `
+	SELECT pt.product_id, pt.language, p.base_price, p.base_currency, l.default_currency AS target_currency
+	FROM product_translations pt
+	JOIN products p ON p.id = pt.product_id
+	JOIN languages l ON l.code = pt.language
+	WHERE p.base_price IS NOT NULL AND p.base_currency IS NOT NULL
+	  AND ($1 = 0 OR p.id = $1)
+	ORDER BY pt.product_id, pt.language
+	`
+
+	if err := r.db.SelectContext(ctx, &prices, query, productID); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении товаров с базовой ценой")
+		return nil, fmt.Errorf("ошибка при получении товаров с базовой ценой: %w", err)
+	}
+
+	for i := range prices {
+		prices[i].BasePrice.Currency = prices[i].BaseCurrency
+	}
+
+	return prices, nil
+}
+
+// UpdateDerivedPrices сохраняет рассчитанные цены в переводы товаров
+func (r *PostgresRepository) UpdateDerivedPrices(ctx context.Context, prices []models.DerivedPrice) error {
+	if len(prices) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для пересчета цен")
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	// Добавляем отложенную функцию для отката транзакции в случае ошибки
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	query := `
+	UPDATE product_translations
+	SET price = $1, currency = $2
+	WHERE product_id = $3 AND language = $4
+	`
+
+	for _, price := range prices {
+		if _, err = tx.ExecContext(ctx, query, price.Price, price.Price.Currency, price.ProductID, price.Language); err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при обновлении цены товара ID=%d, язык=%s", price.ProductID, price.Language)
+			return fmt.Errorf("ошибка при обновлении цены товара: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
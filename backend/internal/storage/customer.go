@@ -0,0 +1,263 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"pryanik_studio/internal/models"
+)
+
+// Ошибки работы с покупателями
+var (
+	ErrCustomerNotFound        = errors.New("покупатель не найден")
+	ErrLoginTokenInvalid       = errors.New("ссылка для входа недействительна или уже использована")
+	ErrCustomerAddressNotFound = errors.New("адрес не найден")
+)
+
+// customerColumns колонки покупателя
+const customerColumns = `id, email, name, phone, created_at, updated_at, last_login_at`
+
+// customerAddressColumns колонки адреса покупателя
+const customerAddressColumns = `id, customer_id, label, address, is_default, created_at, updated_at`
+
+// CreateLoginToken сохраняет хеш токена ссылки для входа, действующего ttl. Если для email
+// уже выдан токен меньше interval назад, новый токен не сохраняется и возвращается false
+func (r *PostgresRepository) CreateLoginToken(ctx context.Context, email, tokenHash string, ttl, interval time.Duration) (bool, error) {
+	query := `
+	INSERT INTO customer_login_tokens (token_hash, email, created_at, expires_at)
+	SELECT $1, $2, NOW(), NOW() + make_interval(secs => $3)
+	WHERE NOT EXISTS (
+		SELECT 1 FROM customer_login_tokens
+		WHERE email = $2 AND created_at > NOW() - make_interval(secs => $4)
+	)
+	`
+	result, err := r.db.ExecContext(ctx, query, tokenHash, email, int64(ttl/time.Second), int64(interval/time.Second))
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при сохранении токена для входа")
+		return false, fmt.Errorf("ошибка при сохранении токена для входа: %w", err)
+	}
+
+	created, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка при получении результата сохранения токена: %w", err)
+	}
+	return created > 0, nil
+}
+
+// DeleteLoginToken удаляет неотправленный токен ссылки для входа, чтобы он не задерживал повторный запрос
+func (r *PostgresRepository) DeleteLoginToken(ctx context.Context, tokenHash string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM customer_login_tokens WHERE token_hash = $1`, tokenHash); err != nil {
+		r.logger.WithError(err).Error("Ошибка при удалении токена для входа")
+		return fmt.Errorf("ошибка при удалении токена для входа: %w", err)
+	}
+	return nil
+}
+
+// ConsumeLoginToken погашает действующий токен ссылки для входа и возвращает покупателя
+// с email из токена; при первом входе учетная запись создается
+func (r *PostgresRepository) ConsumeLoginToken(ctx context.Context, tokenHash string) (models.Customer, error) {
+	var customer models.Customer
+
+	query := `
+	WITH token AS (
+		UPDATE customer_login_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING email
+	)
+	INSERT INTO customers (email, created_at, updated_at, last_login_at)
+	SELECT email, NOW(), NOW(), NOW() FROM token
+	ON CONFLICT (email) DO UPDATE SET last_login_at = NOW()
+	RETURNING ` + customerColumns
+	if err := r.db.GetContext(ctx, &customer, query, tokenHash); err != nil {
+		if err == sql.ErrNoRows {
+			return customer, ErrLoginTokenInvalid
+		}
+		r.logger.WithError(err).Error("Ошибка при входе по ссылке")
+		return customer, fmt.Errorf("ошибка при входе по ссылке: %w", err)
+	}
+	return customer, nil
+}
+
+// DeleteExpiredLoginTokens удаляет истекшие токены для входа и возвращает их количество
+func (r *PostgresRepository) DeleteExpiredLoginTokens(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM customer_login_tokens WHERE expires_at <= NOW()`)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при удалении истекших токенов для входа")
+		return 0, fmt.Errorf("ошибка при удалении истекших токенов для входа: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при получении количества удаленных токенов: %w", err)
+	}
+	return deleted, nil
+}
+
+// GetCustomer возвращает покупателя по ID
+func (r *PostgresRepository) GetCustomer(ctx context.Context, id int64) (models.Customer, error) {
+	var customer models.Customer
+
+	query := `SELECT ` + customerColumns + ` FROM customers WHERE id = $1`
+	if err := r.db.GetContext(ctx, &customer, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return customer, ErrCustomerNotFound
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении покупателя ID=%d", id)
+		return customer, fmt.Errorf("ошибка при получении покупателя: %w", err)
+	}
+	return customer, nil
+}
+
+// UpdateCustomer изменяет имя и телефон покупателя
+func (r *PostgresRepository) UpdateCustomer(ctx context.Context, id int64, name, phone string) (models.Customer, error) {
+	var customer models.Customer
+
+	query := `
+	UPDATE customers SET name = $2, phone = $3, updated_at = NOW()
+	WHERE id = $1
+	RETURNING ` + customerColumns
+	if err := r.db.GetContext(ctx, &customer, query, id, name, phone); err != nil {
+		if err == sql.ErrNoRows {
+			return customer, ErrCustomerNotFound
+		}
+		r.logger.WithError(err).Errorf("Ошибка при изменении покупателя ID=%d", id)
+		return customer, fmt.Errorf("ошибка при изменении покупателя: %w", err)
+	}
+	return customer, nil
+}
+
+// GetOrdersByEmail возвращает страницу заказов с email покупателя, начиная с последних
+func (r *PostgresRepository) GetOrdersByEmail(ctx context.Context, email string, page, pageSize int) (models.OrderList, error) {
+	result := models.OrderList{
+		Items:    []models.Order{},
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	var totalItems int
+	if err := r.db.GetContext(ctx, &totalItems, `SELECT COUNT(*) FROM orders WHERE LOWER(email) = $1`, email); err != nil {
+		r.logger.WithError(err).Error("Ошибка при подсчете заказов покупателя")
+		return result, fmt.Errorf("ошибка при подсчете заказов покупателя: %w", err)
+	}
+	result.TotalItems = totalItems
+	result.TotalPages = int(math.Ceil(float64(totalItems) / float64(pageSize)))
+
+	var ids []int64
+	query := `
+	SELECT id FROM orders
+	WHERE LOWER(email) = $1
+	ORDER BY created_at DESC, id DESC
+	LIMIT $2 OFFSET $3
+	`
+	if err := r.db.SelectContext(ctx, &ids, query, email, pageSize, (page-1)*pageSize); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении заказов покупателя")
+		return result, fmt.Errorf("ошибка при получении заказов покупателя: %w", err)
+	}
+
+	for _, id := range ids {
+		order, err := r.GetOrderByID(ctx, id)
+		if err != nil {
+			return result, err
+		}
+		result.Items = append(result.Items, order)
+	}
+
+	return result, nil
+}
+
+// GetCustomerAddresses возвращает адреса покупателя; адрес по умолчанию - первый
+func (r *PostgresRepository) GetCustomerAddresses(ctx context.Context, customerID int64) ([]models.CustomerAddress, error) {
+	addresses := []models.CustomerAddress{}
+
+	query := `
+	SELECT ` + customerAddressColumns + `
+	FROM customer_addresses
+	WHERE customer_id = $1
+	ORDER BY is_default DESC, id
+	`
+	if err := r.db.SelectContext(ctx, &addresses, query, customerID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении адресов покупателя ID=%d", customerID)
+		return nil, fmt.Errorf("ошибка при получении адресов покупателя: %w", err)
+	}
+	return addresses, nil
+}
+
+// CreateCustomerAddress сохраняет новый адрес покупателя. Первый адрес становится адресом по умолчанию
+func (r *PostgresRepository) CreateCustomerAddress(ctx context.Context, address *models.CustomerAddress) error {
+	return r.saveCustomerAddress(ctx, address, `
+	INSERT INTO customer_addresses (customer_id, label, address, is_default, created_at, updated_at)
+	VALUES ($1, $2, $3, $4 OR NOT EXISTS (SELECT 1 FROM customer_addresses WHERE customer_id = $1), NOW(), NOW())
+	RETURNING `+customerAddressColumns,
+		address.CustomerID, address.Label, address.Address, address.IsDefault)
+}
+
+// UpdateCustomerAddress изменяет адрес покупателя. Отметку адреса по умолчанию нельзя снять,
+// можно только отметить другой адрес
+func (r *PostgresRepository) UpdateCustomerAddress(ctx context.Context, address *models.CustomerAddress) error {
+	return r.saveCustomerAddress(ctx, address, `
+	UPDATE customer_addresses SET label = $3, address = $4, is_default = is_default OR $5, updated_at = NOW()
+	WHERE customer_id = $1 AND id = $2
+	RETURNING `+customerAddressColumns,
+		address.CustomerID, address.ID, address.Label, address.Address, address.IsDefault)
+}
+
+// saveCustomerAddress сохраняет адрес запросом query с аргументами args в транзакции; если адрес отмечен
+// как адрес по умолчанию, отметка снимается с остальных адресов покупателя
+func (r *PostgresRepository) saveCustomerAddress(ctx context.Context, address *models.CustomerAddress, query string, args ...interface{}) error {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	err = tx.GetContext(ctx, address, query, args...)
+	if err == sql.ErrNoRows {
+		err = ErrCustomerAddressNotFound
+		return err
+	}
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при сохранении адреса покупателя ID=%d", address.CustomerID)
+		return fmt.Errorf("ошибка при сохранении адреса покупателя: %w", err)
+	}
+
+	if address.IsDefault {
+		_, err = tx.ExecContext(ctx, `
+		UPDATE customer_addresses SET is_default = FALSE, updated_at = NOW()
+		WHERE customer_id = $1 AND id <> $2 AND is_default
+		`, address.CustomerID, address.ID)
+		if err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при смене адреса по умолчанию покупателя ID=%d", address.CustomerID)
+			return fmt.Errorf("ошибка при смене адреса по умолчанию: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// DeleteCustomerAddress удаляет адрес покупателя
+func (r *PostgresRepository) DeleteCustomerAddress(ctx context.Context, customerID, id int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM customer_addresses WHERE customer_id = $1 AND id = $2`, customerID, id)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при удалении адреса покупателя ID=%d", customerID)
+		return fmt.Errorf("ошибка при удалении адреса покупателя: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrCustomerAddressNotFound
+	}
+	return nil
+}
@@ -87,6 +87,29 @@ func MigrateDatabase(db *sqlx.DB, logger *logrus.Logger) error {
 
 	// Создание схемы для таблиц, если она не существует
 	schema := `
+	-- Языки
+	CREATE TABLE IF NOT EXISTS languages (
+		code VARCHAR(5) PRIMARY KEY,
+		name VARCHAR(100) NOT NULL,
+		default_currency VARCHAR(3) NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT true,
+		is_default BOOLEAN NOT NULL DEFAULT false,
+		fallback VARCHAR(5) REFERENCES languages(code) ON DELETE SET NULL,
+		sort_order INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	-- Курсы валют относительно опорной валюты и правила округления
+	CREATE TABLE IF NOT EXISTS exchange_rates (
+		currency VARCHAR(3) PRIMARY KEY,
+		rate NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+		rounding_step BIGINT NOT NULL DEFAULT 1 CHECK (rounding_step > 0),
+		rounding_mode VARCHAR(10) NOT NULL DEFAULT 'half_up',
+		source VARCHAR(20) NOT NULL DEFAULT 'manual',
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
 	-- Категории
 	CREATE TABLE IF NOT EXISTS categories (
 		id SERIAL PRIMARY KEY,
@@ -181,6 +204,571 @@ func MigrateDatabase(db *sqlx.DB, logger *logrus.Logger) error {
 		quantity INTEGER NOT NULL DEFAULT 1,
 		price DECIMAL(10, 2) NOT NULL
 	);
+
+	-- Опции товаров (размер, материал, отделка)
+	CREATE TABLE IF NOT EXISTS product_options (
+		id SERIAL PRIMARY KEY,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		code VARCHAR(50) NOT NULL,
+		sort_order INTEGER NOT NULL DEFAULT 0,
+		UNIQUE (product_id, code)
+	);
+
+	-- Переводы опций товаров
+	CREATE TABLE IF NOT EXISTS product_option_translations (
+		option_id INTEGER NOT NULL REFERENCES product_options(id) ON DELETE CASCADE,
+		language VARCHAR(5) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		PRIMARY KEY (option_id, language)
+	);
+
+	-- Значения опций товаров
+	CREATE TABLE IF NOT EXISTS product_option_values (
+		id SERIAL PRIMARY KEY,
+		option_id INTEGER NOT NULL REFERENCES product_options(id) ON DELETE CASCADE,
+		code VARCHAR(50) NOT NULL,
+		sort_order INTEGER NOT NULL DEFAULT 0,
+		UNIQUE (option_id, code)
+	);
+
+	-- Переводы значений опций
+	CREATE TABLE IF NOT EXISTS product_option_value_translations (
+		value_id INTEGER NOT NULL REFERENCES product_option_values(id) ON DELETE CASCADE,
+		language VARCHAR(5) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		PRIMARY KEY (value_id, language)
+	);
+
+	-- Варианты товаров: сочетание значений опций со своим SKU, ценой и остатком
+	CREATE TABLE IF NOT EXISTS product_variants (
+		id SERIAL PRIMARY KEY,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		sku VARCHAR(100) NOT NULL UNIQUE,
+		price_mode VARCHAR(10) NOT NULL DEFAULT 'delta',
+		price_value DECIMAL(10, 2) NOT NULL DEFAULT 0,
+		price_currency VARCHAR(3) NOT NULL,
+		stock INTEGER CHECK (stock >= 0),
+		enabled BOOLEAN NOT NULL DEFAULT true,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	-- Значения опций, из которых состоит вариант
+	CREATE TABLE IF NOT EXISTS product_variant_values (
+		variant_id INTEGER NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+		value_id INTEGER NOT NULL REFERENCES product_option_values(id) ON DELETE CASCADE,
+		PRIMARY KEY (variant_id, value_id)
+	);
+
+	-- Выбранный вариант в позиции заказа
+	ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES product_variants(id) ON DELETE SET NULL;
+	ALTER TABLE order_items ADD COLUMN IF NOT EXISTS sku VARCHAR(100);
+	ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_name VARCHAR(255);
+
+	-- Базовая цена товара, из которой по курсам рассчитываются цены переводов
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS base_price DECIMAL(10, 2);
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS base_currency VARCHAR(3);
+
+	-- Схема персонализации товара и значения персонализации в позициях заказа
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS personalization_schema JSONB;
+	ALTER TABLE order_items ADD COLUMN IF NOT EXISTS personalization JSONB;
+
+	-- Файлы, загруженные клиентами для персонализации
+	CREATE TABLE IF NOT EXISTS uploads (
+		id VARCHAR(32) PRIMARY KEY,
+		file_name VARCHAR(255) NOT NULL,
+		content_type VARCHAR(100) NOT NULL,
+		size BIGINT NOT NULL,
+		path VARCHAR(500) NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	-- Запросы на расчет изделий не из каталога
+	CREATE TABLE IF NOT EXISTS quote_requests (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		email VARCHAR(255) NOT NULL,
+		phone VARCHAR(50) NOT NULL,
+		description TEXT NOT NULL,
+		material VARCHAR(255) NOT NULL DEFAULT '',
+		quantity INTEGER NOT NULL CHECK (quantity > 0),
+		language VARCHAR(5) NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'new',
+		price DECIMAL(10, 2),
+		currency VARCHAR(3),
+		admin_comment TEXT NOT NULL DEFAULT '',
+		valid_until TIMESTAMP,
+		quoted_at TIMESTAMP,
+		order_id INTEGER REFERENCES orders(id) ON DELETE SET NULL,
+		accepted_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	-- Файлы моделей, приложенные к запросу на расчет
+	CREATE TABLE IF NOT EXISTS quote_request_files (
+		quote_request_id INTEGER NOT NULL REFERENCES quote_requests(id) ON DELETE CASCADE,
+		upload_id VARCHAR(32) NOT NULL REFERENCES uploads(id),
+		sort_order INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (quote_request_id, upload_id)
+	);
+
+	-- Материалы 3D-печати с параметрами расчета стоимости
+	CREATE TABLE IF NOT EXISTS print_materials (
+		code VARCHAR(50) PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		density NUMERIC(8, 4) NOT NULL CHECK (density > 0),
+		print_speed NUMERIC(10, 4) NOT NULL CHECK (print_speed > 0),
+		price_per_gram DECIMAL(10, 2) NOT NULL,
+		machine_hour_rate DECIMAL(10, 2) NOT NULL,
+		minimum_charge DECIMAL(10, 2) NOT NULL DEFAULT 0,
+		currency VARCHAR(3) NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT true,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	-- Материалы лазерной резки и гравировки с расценками
+	CREATE TABLE IF NOT EXISTS laser_materials (
+		code VARCHAR(50) PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		cut_price_per_meter DECIMAL(10, 2) NOT NULL,
+		engrave_price_per_cm2 DECIMAL(10, 2) NOT NULL,
+		sheet_price_per_cm2 DECIMAL(10, 2) NOT NULL,
+		minimum_charge DECIMAL(10, 2) NOT NULL DEFAULT 0,
+		currency VARCHAR(3) NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT true,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	-- Сохраненные автоматические расчеты стоимости, которые можно прикрепить к позиции заказа
+	CREATE TABLE IF NOT EXISTS estimates (
+		id VARCHAR(32) PRIMARY KEY,
+		kind VARCHAR(20) NOT NULL,
+		material VARCHAR(50) NOT NULL,
+		quantity INTEGER NOT NULL,
+		upload_id VARCHAR(32) REFERENCES uploads(id) ON DELETE SET NULL,
+		unit_price DECIMAL(10, 2) NOT NULL,
+		total DECIMAL(10, 2) NOT NULL,
+		currency VARCHAR(3) NOT NULL,
+		details JSONB NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMP NOT NULL
+	);
+
+	-- Расчет стоимости, прикрепленный к позиции заказа
+	ALTER TABLE order_items ADD COLUMN IF NOT EXISTS estimate_id VARCHAR(32) REFERENCES estimates(id) ON DELETE SET NULL;
+
+	-- Остаток товара без вариантов (NULL - не учитывается) и признак списания остатка позицией заказа
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS stock INTEGER CHECK (stock >= 0);
+	ALTER TABLE order_items ADD COLUMN IF NOT EXISTS reserved BOOLEAN NOT NULL DEFAULT false;
+
+	-- Материалы мастерской (фанера, акрил, филамент)
+	CREATE TABLE IF NOT EXISTS workshop_materials (
+		id SERIAL PRIMARY KEY,
+		code VARCHAR(50) NOT NULL UNIQUE,
+		name VARCHAR(255) NOT NULL,
+		unit VARCHAR(20) NOT NULL,
+		quantity NUMERIC(12, 3) NOT NULL DEFAULT 0,
+		reorder_threshold NUMERIC(12, 3) NOT NULL DEFAULT 0 CHECK (reorder_threshold >= 0),
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	-- Спецификации: расход материалов на единицу товара или варианта
+	CREATE TABLE IF NOT EXISTS bill_of_materials (
+		id SERIAL PRIMARY KEY,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+		material_id INTEGER NOT NULL REFERENCES workshop_materials(id) ON DELETE RESTRICT,
+		quantity NUMERIC(12, 3) NOT NULL CHECK (quantity > 0)
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS bill_of_materials_item_idx
+		ON bill_of_materials (product_id, COALESCE(variant_id, 0), material_id);
+
+	-- Движения материалов: списания на заказы и ручные корректировки
+	CREATE TABLE IF NOT EXISTS material_movements (
+		id SERIAL PRIMARY KEY,
+		material_id INTEGER NOT NULL REFERENCES workshop_materials(id) ON DELETE CASCADE,
+		quantity NUMERIC(12, 3) NOT NULL,
+		reason VARCHAR(20) NOT NULL,
+		order_id INTEGER REFERENCES orders(id) ON DELETE SET NULL,
+		comment TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS material_movements_material_idx ON material_movements (material_id, created_at);
+
+	-- Время списания материалов заказа (при запуске в производство)
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS materials_consumed_at TIMESTAMP;
+
+	-- Параметры изготовления товара каталога: вид работ и время на единицу (мин)
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS production_kind VARCHAR(50);
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS production_minutes INTEGER NOT NULL DEFAULT 0 CHECK (production_minutes >= 0);
+
+	-- Станки мастерской и виды работ, которые они выполняют
+	CREATE TABLE IF NOT EXISTS machines (
+		id SERIAL PRIMARY KEY,
+		code VARCHAR(50) NOT NULL UNIQUE,
+		name VARCHAR(255) NOT NULL,
+		capabilities TEXT[] NOT NULL DEFAULT '{}',
+		enabled BOOLEAN NOT NULL DEFAULT true,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	-- Производственные задания по позициям заказов
+	CREATE TABLE IF NOT EXISTS production_jobs (
+		id SERIAL PRIMARY KEY,
+		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		order_item_id INTEGER NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+		kind VARCHAR(50) NOT NULL,
+		title VARCHAR(500) NOT NULL,
+		quantity INTEGER NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'queued',
+		priority INTEGER NOT NULL DEFAULT 0,
+		due_date DATE,
+		estimated_minutes INTEGER NOT NULL CHECK (estimated_minutes > 0),
+		machine_id INTEGER REFERENCES machines(id) ON DELETE SET NULL,
+		machine_locked BOOLEAN NOT NULL DEFAULT false,
+		scheduled_start TIMESTAMP,
+		scheduled_end TIMESTAMP,
+		started_at TIMESTAMP,
+		finished_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+	-- По позиции заказа может быть только одно неотмененное задание
+	CREATE UNIQUE INDEX IF NOT EXISTS production_jobs_item_idx
+		ON production_jobs (order_item_id) WHERE status <> 'cancelled';
+	CREATE INDEX IF NOT EXISTS production_jobs_status_idx ON production_jobs (status);
+	CREATE INDEX IF NOT EXISTS production_jobs_order_idx ON production_jobs (order_id);
+
+	-- Промокоды: вид скидки, условия и лимиты использований
+	CREATE TABLE IF NOT EXISTS promo_codes (
+		id SERIAL PRIMARY KEY,
+		code VARCHAR(50) NOT NULL UNIQUE,
+		description VARCHAR(500) NOT NULL DEFAULT '',
+		kind VARCHAR(20) NOT NULL,
+		percent INTEGER NOT NULL DEFAULT 0 CHECK (percent BETWEEN 0 AND 100),
+		amounts JSONB,
+		free_product_id INTEGER REFERENCES products(id) ON DELETE SET NULL,
+		min_order_amounts JSONB,
+		category_ids INTEGER[] NOT NULL DEFAULT '{}',
+		starts_at TIMESTAMP,
+		ends_at TIMESTAMP,
+		max_uses INTEGER,
+		max_uses_per_email INTEGER,
+		enabled BOOLEAN NOT NULL DEFAULT true,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	-- Использования промокодов заказами
+	CREATE TABLE IF NOT EXISTS promo_redemptions (
+		id SERIAL PRIMARY KEY,
+		promo_id INTEGER NOT NULL REFERENCES promo_codes(id) ON DELETE CASCADE,
+		order_id INTEGER NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+		email VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS promo_redemptions_promo_idx ON promo_redemptions (promo_id, email);
+
+	-- Скидка заказа по промокоду и ее распределение по позициям
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS subtotal DECIMAL(10, 2) NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount DECIMAL(10, 2) NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS promo_code VARCHAR(50);
+	ALTER TABLE order_items ADD COLUMN IF NOT EXISTS discount DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+	-- Способы доставки; правила расчета стоимости по странам и городам хранятся в JSONB
+	CREATE TABLE IF NOT EXISTS shipping_methods (
+		id SERIAL PRIMARY KEY,
+		code VARCHAR(50) NOT NULL UNIQUE,
+		name VARCHAR(255) NOT NULL,
+		kind VARCHAR(20) NOT NULL,
+		description VARCHAR(1000) NOT NULL DEFAULT '',
+		pickup_address VARCHAR(500) NOT NULL DEFAULT '',
+		rules JSONB NOT NULL DEFAULT '[]',
+		sort_order INTEGER NOT NULL DEFAULT 0,
+		enabled BOOLEAN NOT NULL DEFAULT true,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	-- Вес и габариты товара в упаковке для расчета доставки
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS weight_grams INTEGER CHECK (weight_grams > 0);
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS length_mm INTEGER CHECK (length_mm > 0);
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS width_mm INTEGER CHECK (width_mm > 0);
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS height_mm INTEGER CHECK (height_mm > 0);
+
+	-- Доставка заказа
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_method VARCHAR(50);
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_method_name VARCHAR(255);
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_cost DECIMAL(10, 2) NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_address JSONB;
+
+	-- Онлайн-платежи по заказам
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS payment_status VARCHAR(20) NOT NULL DEFAULT 'unpaid';
+	CREATE TABLE IF NOT EXISTS payments (
+		id SERIAL PRIMARY KEY,
+		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		provider VARCHAR(20) NOT NULL,
+		provider_payment_id VARCHAR(255),
+		provider_reference VARCHAR(255),
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		amount DECIMAL(10, 2) NOT NULL,
+		refunded_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+		currency VARCHAR(3) NOT NULL,
+		confirmation_url TEXT,
+		failure_reason VARCHAR(500),
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		UNIQUE (provider, provider_payment_id)
+	);
+	CREATE INDEX IF NOT EXISTS payments_order_idx ON payments (order_id);
+	CREATE INDEX IF NOT EXISTS payments_reference_idx ON payments (provider, provider_reference);
+
+	-- Обработанные события вебхуков: повторно доставленные события пропускаются
+	CREATE TABLE IF NOT EXISTS payment_events (
+		provider VARCHAR(20) NOT NULL,
+		event_id VARCHAR(255) NOT NULL,
+		payment_id INTEGER REFERENCES payments(id) ON DELETE CASCADE,
+		type VARCHAR(20) NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (provider, event_id)
+	);
+
+	-- Счета по заказам: сквозная нумерация в пределах года без пропусков
+	CREATE TABLE IF NOT EXISTS invoice_counters (
+		year INTEGER PRIMARY KEY,
+		last_sequence INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS invoices (
+		id SERIAL PRIMARY KEY,
+		order_id INTEGER NOT NULL UNIQUE REFERENCES orders(id) ON DELETE RESTRICT,
+		number VARCHAR(50) NOT NULL UNIQUE,
+		year INTEGER NOT NULL,
+		sequence INTEGER NOT NULL,
+		seller JSONB NOT NULL,
+		issued_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		UNIQUE (year, sequence)
+	);
+
+	-- Ключи идемпотентности: повторный запрос с тем же ключом получает сохраненный ответ
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		scope VARCHAR(100) NOT NULL,
+		key VARCHAR(255) NOT NULL,
+		request_hash CHAR(64) NOT NULL,
+		status_code INTEGER,
+		content_type VARCHAR(100),
+		response BYTEA,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (scope, key)
+	);
+	CREATE INDEX IF NOT EXISTS idempotency_keys_expires_idx ON idempotency_keys (expires_at);
+
+	-- Снятые с публикации товары не показываются в каталоге и не принимаются в заказы;
+	-- минимальное количество для заказа задается для каждого товара
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS published BOOLEAN NOT NULL DEFAULT TRUE;
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS min_order_quantity INTEGER NOT NULL DEFAULT 1 CHECK (min_order_quantity >= 1);
+
+	-- Корзины покупателей; доступ к корзине дает токен из cookie, хранится его хеш
+	CREATE TABLE IF NOT EXISTS carts (
+		id VARCHAR(32) PRIMARY KEY,
+		token_hash VARCHAR(64) NOT NULL,
+		order_id INTEGER REFERENCES orders(id) ON DELETE SET NULL,
+		checkout_started_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS carts_expires_idx ON carts (expires_at);
+
+	-- Позиции корзины с ценой, которую покупатель видел при добавлении или изменении позиции
+	CREATE TABLE IF NOT EXISTS cart_items (
+		id SERIAL PRIMARY KEY,
+		cart_id VARCHAR(32) NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		variant_id INTEGER REFERENCES product_variants(id) ON DELETE SET NULL,
+		estimate_id VARCHAR(32) REFERENCES estimates(id) ON DELETE CASCADE,
+		personalization JSONB,
+		quantity INTEGER NOT NULL CHECK (quantity > 0),
+		price DECIMAL(10, 2) NOT NULL,
+		currency VARCHAR(3) NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS cart_items_cart_idx ON cart_items (cart_id);
+
+	-- Черновики заказов: контакты и состав корзины из незаполненной формы заказа.
+	-- По черновику, который долго не изменялся, покупателю отправляется напоминание
+	CREATE TABLE IF NOT EXISTS checkout_drafts (
+		id VARCHAR(32) PRIMARY KEY,
+		email VARCHAR(255) NOT NULL,
+		name VARCHAR(255) NOT NULL DEFAULT '',
+		phone VARCHAR(50) NOT NULL DEFAULT '',
+		language VARCHAR(10) NOT NULL,
+		cart_id VARCHAR(32) REFERENCES carts(id) ON DELETE SET NULL,
+		items JSONB,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		reminder_sent_at TIMESTAMP,
+		reminder_attempts INTEGER NOT NULL DEFAULT 0,
+		order_id INTEGER REFERENCES orders(id) ON DELETE SET NULL,
+		converted_at TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS checkout_drafts_email_idx ON checkout_drafts (email);
+	CREATE INDEX IF NOT EXISTS checkout_drafts_pending_idx ON checkout_drafts (updated_at)
+		WHERE reminder_sent_at IS NULL AND converted_at IS NULL;
+
+	-- Адреса, отказавшиеся от напоминаний о незавершенных заказах
+	CREATE TABLE IF NOT EXISTS reminder_unsubscribes (
+		email VARCHAR(255) PRIMARY KEY,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	-- Учетные записи покупателей; заказы связываются с ними по email
+	CREATE TABLE IF NOT EXISTS customers (
+		id SERIAL PRIMARY KEY,
+		email VARCHAR(255) NOT NULL UNIQUE,
+		name VARCHAR(255) NOT NULL DEFAULT '',
+		phone VARCHAR(50) NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		last_login_at TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS orders_email_idx ON orders (LOWER(email));
+
+	-- Одноразовые токены ссылок для входа; хранится хеш токена
+	CREATE TABLE IF NOT EXISTS customer_login_tokens (
+		token_hash VARCHAR(64) PRIMARY KEY,
+		email VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS customer_login_tokens_email_idx ON customer_login_tokens (email, created_at);
+
+	-- Сохраненные адреса доставки покупателей
+	CREATE TABLE IF NOT EXISTS customer_addresses (
+		id SERIAL PRIMARY KEY,
+		customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+		label VARCHAR(100) NOT NULL DEFAULT '',
+		address JSONB NOT NULL,
+		is_default BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS customer_addresses_customer_idx ON customer_addresses (customer_id);
+
+	-- Списки избранного: без входа доступ к списку дает токен из cookie (хранится его хеш),
+	-- у покупателя с личным кабинетом один список. Список можно открыть для просмотра по share_id
+	CREATE TABLE IF NOT EXISTS wishlists (
+		id VARCHAR(32) PRIMARY KEY,
+		token_hash VARCHAR(64) UNIQUE,
+		customer_id INTEGER UNIQUE REFERENCES customers(id) ON DELETE CASCADE,
+		share_id VARCHAR(32) UNIQUE,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMP,
+		CHECK ((token_hash IS NULL) <> (customer_id IS NULL))
+	);
+	CREATE INDEX IF NOT EXISTS wishlists_expires_idx ON wishlists (expires_at) WHERE expires_at IS NOT NULL;
+
+	-- Товары в списках избранного. Для подписки на снижение цены хранится цена перевода
+	-- price_language, о которой покупатель уже знает
+	CREATE TABLE IF NOT EXISTS wishlist_items (
+		wishlist_id VARCHAR(32) NOT NULL REFERENCES wishlists(id) ON DELETE CASCADE,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		language VARCHAR(10) NOT NULL,
+		notify_price_drop BOOLEAN NOT NULL DEFAULT FALSE,
+		price_language VARCHAR(10),
+		watched_price DECIMAL(10, 2),
+		currency VARCHAR(3),
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (wishlist_id, product_id)
+	);
+	CREATE INDEX IF NOT EXISTS wishlist_items_watch_idx ON wishlist_items (product_id) WHERE notify_price_drop;
+
+	-- Отзывы о товарах оставляют покупатели выполненных заказов по подписанной ссылке из письма;
+	-- отзыв публикуется после модерации. Средняя оценка и количество опубликованных отзывов
+	-- хранятся в товаре и пересчитываются при модерации
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS review_invited_at TIMESTAMP;
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS rating_avg DECIMAL(3, 2) NOT NULL DEFAULT 0;
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS rating_count INTEGER NOT NULL DEFAULT 0;
+
+	CREATE TABLE IF NOT EXISTS product_reviews (
+		id SERIAL PRIMARY KEY,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		author_name VARCHAR(100) NOT NULL,
+		rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+		text TEXT NOT NULL DEFAULT '',
+		language VARCHAR(10) NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		reply TEXT NOT NULL DEFAULT '',
+		replied_at TIMESTAMP,
+		moderated_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		UNIQUE (order_id, product_id)
+	);
+	CREATE INDEX IF NOT EXISTS product_reviews_product_idx ON product_reviews (product_id, status, created_at);
+	CREATE INDEX IF NOT EXISTS product_reviews_status_idx ON product_reviews (status, created_at);
+
+	-- Фотографии к отзывам из загруженных покупателем файлов
+	CREATE TABLE IF NOT EXISTS review_photos (
+		review_id INTEGER NOT NULL REFERENCES product_reviews(id) ON DELETE CASCADE,
+		upload_id VARCHAR(32) NOT NULL REFERENCES uploads(id),
+		sort_order INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (review_id, upload_id)
+	);
+
+	-- Сообщения с формы обратной связи сохраняются до отправки уведомления, поэтому не теряются
+	-- при ошибке почты; уведомления отправляются в фоне с повторными попытками
+	CREATE TABLE IF NOT EXISTS contact_messages (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		email VARCHAR(255) NOT NULL,
+		phone VARCHAR(50) NOT NULL,
+		message TEXT NOT NULL,
+		language VARCHAR(10) NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'new',
+		assigned_to VARCHAR(100) NOT NULL DEFAULT '',
+		read_at TIMESTAMP,
+		answered_at TIMESTAMP,
+		notified_at TIMESTAMP,
+		notify_attempts INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS contact_messages_status_idx ON contact_messages (status, created_at);
+	CREATE INDEX IF NOT EXISTS contact_messages_notify_idx ON contact_messages (created_at) WHERE notified_at IS NULL;
+
+	-- Внутренние заметки администраторов к сообщениям
+	CREATE TABLE IF NOT EXISTS contact_message_notes (
+		id SERIAL PRIMARY KEY,
+		message_id INTEGER NOT NULL REFERENCES contact_messages(id) ON DELETE CASCADE,
+		author VARCHAR(100) NOT NULL,
+		text TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS contact_message_notes_message_idx ON contact_message_notes (message_id, created_at);
+
+	-- Товары, изготавливаемые по расчету стоимости (3D-печать или лазерная обработка);
+	-- расчет прикрепляется только к позиции такого товара и используется в одном заказе
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS fabrication_kind VARCHAR(20) CHECK (fabrication_kind IN ('3d', 'laser'));
+	ALTER TABLE estimates ADD COLUMN IF NOT EXISTS order_id INTEGER REFERENCES orders(id) ON DELETE SET NULL;
+
+	-- Аномалии оплаты (повторная оплата, оплата отмененного заказа) требуют возврата вручную
+	ALTER TABLE payments ADD COLUMN IF NOT EXISTS anomaly VARCHAR(255);
+
+	-- Подтверждение клиенту и уведомление администратору по сообщению с формы обратной связи
+	-- отмечаются отдельно, чтобы повторная попытка не отправляла уже доставленное письмо
+	ALTER TABLE contact_messages ADD COLUMN IF NOT EXISTS customer_notified_at TIMESTAMP;
+	ALTER TABLE contact_messages ADD COLUMN IF NOT EXISTS admin_notified_at TIMESTAMP;
 	`
 
 	// Выполняем SQL запрос для создания таблиц
@@ -192,6 +780,27 @@ func MigrateDatabase(db *sqlx.DB, logger *logrus.Logger) error {
 
 	logger.Info("Миграции успешно выполнены")
 
+	// Заполняем реестр языков, если он пуст
+	var languagesCount int
+	if err = db.Get(&languagesCount, "SELECT COUNT(*) FROM languages"); err != nil {
+		logger.WithError(err).Error("Ошибка при проверке наличия языков")
+		return fmt.Errorf("ошибка при проверке наличия языков: %w", err)
+	}
+
+	if languagesCount == 0 {
+		_, err = db.Exec(`
+		INSERT INTO languages (code, name, default_currency, enabled, is_default, fallback, sort_order)
+		VALUES ('ru', 'Русский', 'RUB', true, true, NULL, 0),
+		       ('en', 'English', 'USD', true, false, 'ru', 1),
+		       ('es', 'Español', 'EUR', true, false, 'en', 2)
+		`)
+		if err != nil {
+			logger.WithError(err).Error("Ошибка при добавлении языков")
+			return fmt.Errorf("ошибка при добавлении языков: %w", err)
+		}
+		logger.Info("Реестр языков заполнен значениями по умолчанию")
+	}
+
 	// Проверка наличия колонки language в таблице orders
 	// Выполняем проверку и добавление колонки, если она не существует
 	var languageExists bool
@@ -218,6 +827,52 @@ func MigrateDatabase(db *sqlx.DB, logger *logrus.Logger) error {
 		logger.Info("Колонка language добавлена в таблицу orders")
 	}
 
+	// Проверка наличия колонки currency в таблице orders
+	var orderCurrencyExists bool
+	err = db.Get(&orderCurrencyExists, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM information_schema.columns
+			WHERE table_name = 'orders' AND column_name = 'currency'
+		)
+	`)
+
+	if err != nil {
+		logger.WithError(err).Error("Ошибка при проверке наличия колонки currency в таблице orders")
+		return fmt.Errorf("ошибка при проверке наличия колонки currency: %w", err)
+	}
+
+	// Валюта хранится в заказе и в каждой позиции; составной внешний ключ
+	// не позволяет добавить в заказ позицию в другой валюте
+	if !orderCurrencyExists {
+		_, err = db.Exec(`
+		ALTER TABLE orders ADD COLUMN currency VARCHAR(3);
+		ALTER TABLE order_items ADD COLUMN currency VARCHAR(3);
+
+		-- Для существующих заказов валюта определялась языком заказа
+		UPDATE orders o
+		SET currency = COALESCE(
+			(SELECT default_currency FROM languages WHERE code = o.language),
+			(SELECT default_currency FROM languages WHERE is_default),
+			'RUB'
+		);
+		UPDATE order_items oi
+		SET currency = (SELECT currency FROM orders WHERE id = oi.order_id);
+
+		ALTER TABLE orders ALTER COLUMN currency SET NOT NULL;
+		ALTER TABLE order_items ALTER COLUMN currency SET NOT NULL;
+
+		ALTER TABLE orders ADD CONSTRAINT orders_id_currency_key UNIQUE (id, currency);
+		ALTER TABLE order_items ADD CONSTRAINT order_items_order_currency_fkey
+			FOREIGN KEY (order_id, currency) REFERENCES orders (id, currency) ON DELETE CASCADE;
+		`)
+		if err != nil {
+			logger.WithError(err).Error("Ошибка при добавлении колонок currency в таблицы заказов")
+			return fmt.Errorf("ошибка при добавлении колонок currency: %w", err)
+		}
+		logger.Info("Колонки currency добавлены в таблицы orders и order_items")
+	}
+
 	// Проверяем, есть ли уже категории в базе данных
 	var count int
 	err = db.Get(&count, "SELECT COUNT(*) FROM categories")
@@ -259,12 +914,11 @@ func MigrateDatabase(db *sqlx.DB, logger *logrus.Logger) error {
         -- Копируем значение price из таблицы products в таблицу product_translations
         UPDATE product_translations pt
         SET price = (SELECT price FROM products WHERE id = pt.product_id),
-            currency = CASE 
-                WHEN pt.language = 'ru' THEN 'RUB'
-                WHEN pt.language = 'en' THEN 'USD'
-                WHEN pt.language = 'es' THEN 'EUR'
-                ELSE 'USD'
-            END;
+            currency = COALESCE(
+                (SELECT default_currency FROM languages WHERE code = pt.language),
+                (SELECT default_currency FROM languages WHERE is_default),
+                'USD'
+            );
         
         -- Делаем колонки NOT NULL
         ALTER TABLE product_translations ALTER COLUMN price SET NOT NULL;
@@ -296,134 +950,79 @@ func seedInitialData(db *sqlx.DB, logger *logrus.Logger) error {
 	}()
 
 	// Добавляем основные категории
-	engravingCategoryID := int64(0)
-	printingCategoryID := int64(0)
-
-	// Категория "Выжигание"
-	err = tx.QueryRow(`
-		INSERT INTO categories (created_at, updated_at) 
-		VALUES (NOW(), NOW()) 
-		RETURNING id
-	`).Scan(&engravingCategoryID)
-
+	engravingCategoryID, err := seedCategory(tx, nil, map[string]string{
+		"ru": "Выжигание",
+		"en": "Engraving",
+		"es": "Grabado",
+	})
 	if err != nil {
 		return fmt.Errorf("ошибка при добавлении категории 'Выжигание': %w", err)
 	}
 
-	// Добавляем переводы для категории "Выжигание"
-	_, err = tx.Exec(`
-		INSERT INTO category_translations (category_id, language, name) 
-		VALUES ($1, 'ru', 'Выжигание'),
-		       ($1, 'en', 'Engraving'),
-		       ($1, 'es', 'Grabado')
-	`, engravingCategoryID)
-
-	if err != nil {
-		return fmt.Errorf("ошибка при добавлении переводов для категории 'Выжигание': %w", err)
-	}
-
-	// Категория "3D Печать"
-	err = tx.QueryRow(`
-		INSERT INTO categories (created_at, updated_at) 
-		VALUES (NOW(), NOW()) 
-		RETURNING id
-	`).Scan(&printingCategoryID)
-
-	if err != nil {
+	if _, err = seedCategory(tx, nil, map[string]string{
+		"ru": "3D Печать",
+		"en": "3D Printing",
+		"es": "Impresión 3D",
+	}); err != nil {
 		return fmt.Errorf("ошибка при добавлении категории '3D Печать': %w", err)
 	}
 
-	// Добавляем переводы для категории "3D Печать"
-	_, err = tx.Exec(`
-		INSERT INTO category_translations (category_id, language, name) 
-		VALUES ($1, 'ru', '3D Печать'),
-		       ($1, 'en', '3D Printing'),
-		       ($1, 'es', 'Impresión 3D')
-	`, printingCategoryID)
-
-	if err != nil {
-		return fmt.Errorf("ошибка при добавлении переводов для категории '3D Печать': %w", err)
-	}
-
 	// Добавляем подкатегории для "Выжигание"
-
-	// Подкатегория "Дерево"
-	woodCategoryID := int64(0)
-	err = tx.QueryRow(`
-		INSERT INTO categories (parent_id, created_at, updated_at) 
-		VALUES ($1, NOW(), NOW()) 
-		RETURNING id
-	`, engravingCategoryID).Scan(&woodCategoryID)
-
-	if err != nil {
+	if _, err = seedCategory(tx, &engravingCategoryID, map[string]string{
+		"ru": "Дерево",
+		"en": "Wood",
+		"es": "Madera",
+	}); err != nil {
 		return fmt.Errorf("ошибка при добавлении подкатегории 'Дерево': %w", err)
 	}
 
-	// Добавляем переводы для подкатегории "Дерево"
-	_, err = tx.Exec(`
-		INSERT INTO category_translations (category_id, language, name) 
-		VALUES ($1, 'ru', 'Дерево'),
-		       ($1, 'en', 'Wood'),
-		       ($1, 'es', 'Madera')
-	`, woodCategoryID)
-
-	if err != nil {
-		return fmt.Errorf("ошибка при добавлении переводов для подкатегории 'Дерево': %w", err)
-	}
-
-	// Подкатегория "Металл"
-	metalCategoryID := int64(0)
-	err = tx.QueryRow(`
-		INSERT INTO categories (parent_id, created_at, updated_at) 
-		VALUES ($1, NOW(), NOW()) 
-		RETURNING id
-	`, engravingCategoryID).Scan(&metalCategoryID)
-
-	if err != nil {
+	if _, err = seedCategory(tx, &engravingCategoryID, map[string]string{
+		"ru": "Металл",
+		"en": "Metal",
+		"es": "Metal",
+	}); err != nil {
 		return fmt.Errorf("ошибка при добавлении подкатегории 'Металл': %w", err)
 	}
 
-	// Добавляем переводы для подкатегории "Металл"
-	_, err = tx.Exec(`
-		INSERT INTO category_translations (category_id, language, name) 
-		VALUES ($1, 'ru', 'Металл'),
-		       ($1, 'en', 'Metal'),
-		       ($1, 'es', 'Metal')
-	`, metalCategoryID)
+	if _, err = seedCategory(tx, &engravingCategoryID, map[string]string{
+		"ru": "Другое",
+		"en": "Other",
+		"es": "Otro",
+	}); err != nil {
+		return fmt.Errorf("ошибка при добавлении подкатегории 'Другое': %w", err)
+	}
 
-	if err != nil {
-		return fmt.Errorf("ошибка при добавлении переводов для подкатегории 'Металл': %w", err)
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
 	}
 
-	// Подкатегория "Другое"
-	otherCategoryID := int64(0)
-	err = tx.QueryRow(`
+	logger.Info("Начальные данные успешно добавлены")
+	return nil
+}
+
+// seedCategory добавляет категорию и ее переводы. Переводы добавляются только
+// для языков, зарегистрированных в таблице languages
+func seedCategory(tx *sqlx.Tx, parentID *int64, names map[string]string) (int64, error) {
+	var categoryID int64
+	err := tx.QueryRow(`
 		INSERT INTO categories (parent_id, created_at, updated_at) 
 		VALUES ($1, NOW(), NOW()) 
 		RETURNING id
-	`, engravingCategoryID).Scan(&otherCategoryID)
-
-	if err != nil {
-		return fmt.Errorf("ошибка при добавлении подкатегории 'Другое': %w", err)
-	}
-
-	// Добавляем переводы для подкатегории "Другое"
-	_, err = tx.Exec(`
-		INSERT INTO category_translations (category_id, language, name) 
-		VALUES ($1, 'ru', 'Другое'),
-		       ($1, 'en', 'Other'),
-		       ($1, 'es', 'Otro')
-	`, otherCategoryID)
-
+	`, parentID).Scan(&categoryID)
 	if err != nil {
-		return fmt.Errorf("ошибка при добавлении переводов для подкатегории 'Другое': %w", err)
+		return 0, err
 	}
 
-	// Фиксируем транзакцию
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	for lang, name := range names {
+		_, err = tx.Exec(`
+			INSERT INTO category_translations (category_id, language, name)
+			SELECT $1, code, $3 FROM languages WHERE code = $2
+		`, categoryID, lang, name)
+		if err != nil {
+			return 0, fmt.Errorf("ошибка при добавлении перевода для языка %s: %w", lang, err)
+		}
 	}
 
-	logger.Info("Начальные данные успешно добавлены")
-	return nil
+	return categoryID, nil
 }
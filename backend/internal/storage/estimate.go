@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"pryanik_studio/internal/models"
+)
+
+var (
+	// ErrEstimateNotFound возвращается, если сохраненный расчет стоимости не найден
+	ErrEstimateNotFound = errors.New("расчет стоимости не найден")
+
+	// ErrEstimateUsed возвращается, если расчет стоимости уже использован в другом заказе
+	ErrEstimateUsed = errors.New("расчет стоимости уже использован в заказе")
+)
+
+// CreateEstimate сохраняет расчет стоимости; ID и сроки задаются вызывающим кодом
+func (r *PostgresRepository) CreateEstimate(ctx context.Context, estimate *models.Estimate) error {
+	details, err := json.Marshal(estimate)
+	if err != nil {
+		return fmt.Errorf("ошибка при сериализации расчета стоимости: %w", err)
+	}
+
+	query := `
+	INSERT INTO estimates (id, kind, material, quantity, upload_id, unit_price, total, currency,
+	                       details, created_at, expires_at)
+	VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err = r.db.ExecContext(ctx, query,
+		estimate.ID,
+		estimate.Kind,
+		estimate.Material,
+		estimate.Quantity,
+		estimate.UploadID,
+		estimate.UnitPrice,
+		estimate.Total,
+		estimate.Currency,
+		details,
+		estimate.CreatedAt,
+		estimate.ExpiresAt,
+	)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при сохранении расчета стоимости")
+		return fmt.Errorf("ошибка при сохранении расчета стоимости: %w", err)
+	}
+
+	return nil
+}
+
+// GetEstimate возвращает сохраненный расчет стоимости по ID
+func (r *PostgresRepository) GetEstimate(ctx context.Context, id string) (models.Estimate, error) {
+	var estimate models.Estimate
+	var details []byte
+	var orderID sql.NullInt64
+
+	query := `SELECT details, order_id FROM estimates WHERE id = $1`
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&details, &orderID); err != nil {
+		if err == sql.ErrNoRows {
+			return estimate, ErrEstimateNotFound
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении расчета стоимости ID=%s", id)
+		return estimate, fmt.Errorf("ошибка при получении расчета стоимости: %w", err)
+	}
+
+	if err := json.Unmarshal(details, &estimate); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при разборе расчета стоимости ID=%s", id)
+		return estimate, fmt.Errorf("ошибка при разборе расчета стоимости: %w", err)
+	}
+
+	if orderID.Valid {
+		estimate.OrderID = &orderID.Int64
+	}
+
+	// Суммы сериализуются без валюты, она хранится в поле расчета
+	estimate.UnitPrice.Currency = estimate.Currency
+	estimate.Total.Currency = estimate.Currency
+	for i := range estimate.Lines {
+		estimate.Lines[i].Amount.Currency = estimate.Currency
+	}
+
+	return estimate, nil
+}
+
+// claimEstimates связывает прикрепленные к позициям расчеты стоимости с заказом в рамках транзакции.
+// Расчет используется только в одном заказе: если он уже связан с заказом, возвращает ErrEstimateUsed
+func claimEstimates(ctx context.Context, tx *sqlx.Tx, orderID int64, items []models.OrderItem) error {
+	query := `UPDATE estimates SET order_id = $1 WHERE id = $2 AND order_id IS NULL`
+	for _, item := range items {
+		if item.EstimateID == nil {
+			continue
+		}
+		result, err := tx.ExecContext(ctx, query, orderID, *item.EstimateID)
+		if err != nil {
+			return fmt.Errorf("ошибка при связывании расчета стоимости с заказом: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return fmt.Errorf("расчет ID=%s: %w", *item.EstimateID, ErrEstimateUsed)
+		}
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"pryanik_studio/internal/models"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
@@ -18,16 +19,23 @@ func (r *PostgresRepository) GetGalleryItems(ctx context.Context, filter models.
 	var result models.GalleryList
 
 	// Базовый запрос для выборки элементов
+	// Перевод выбираем по цепочке отката: первый доступный язык из цепочки
 	query := `
 	SELECT gi.id, gi.category_id, gi.thumbnail, gi.full_image, gi.created_at, gi.updated_at,
-	       git.title, git.description
+	       git.language, git.title, git.description
 	FROM gallery_items gi
-	JOIN gallery_item_translations git ON gi.id = git.gallery_item_id
-	WHERE git.language = $1
+	JOIN LATERAL (
+		SELECT t.language, t.title, t.description
+		FROM gallery_item_translations t
+		WHERE t.gallery_item_id = gi.id AND t.language = ANY($1::text[])
+		ORDER BY array_position($1::text[], t.language::text)
+		LIMIT 1
+	) git ON true
+	WHERE TRUE
 	`
 
 	// Добавляем условия фильтрации
-	args := []interface{}{filter.Language}
+	args := []interface{}{pq.Array(r.languageChain(filter.Language))}
 
 	if filter.CategoryID != nil {
 		query += " AND gi.category_id = $2"
@@ -35,7 +43,7 @@ func (r *PostgresRepository) GetGalleryItems(ctx context.Context, filter models.
 	}
 
 	// Добавляем сортировку
-	query += " ORDER BY gi.created_at DESC"
+	query += " ORDER BY gi.created_at DESC, gi.id DESC"
 
 	// Логируем выполняемый запрос
 	r.logger.WithFields(logrus.Fields{
@@ -51,6 +59,7 @@ func (r *PostgresRepository) GetGalleryItems(ctx context.Context, filter models.
 		FullImage   string       `db:"full_image"`
 		CreatedAt   sql.NullTime `db:"created_at"`
 		UpdatedAt   sql.NullTime `db:"updated_at"`
+		Language    string       `db:"language"`
 		Title       string       `db:"title"`
 		Description string       `db:"description"`
 	}
@@ -81,8 +90,9 @@ func (r *PostgresRepository) GetGalleryItems(ctx context.Context, filter models.
 			CreatedAt:  item.CreatedAt.Time,
 			UpdatedAt:  item.UpdatedAt.Time,
 			// Прямой доступ к полям перевода
-			Title:       item.Title,
-			Description: item.Description,
+			Title:          item.Title,
+			Description:    item.Description,
+			FallbackFields: markFallback(nil, filter.Language, item.Language, "title", "description"),
 		}
 
 		result.Items = append(result.Items, galleryItem)
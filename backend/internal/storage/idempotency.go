@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"pryanik_studio/internal/models"
+)
+
+// idempotencyColumns колонки ключа идемпотентности
+const idempotencyColumns = `
+	scope, key, request_hash, status_code, COALESCE(content_type, '') AS content_type,
+	response, created_at, expires_at
+`
+
+// abandonedRequestTimeout время, после которого незавершенный запрос считается прерванным
+// (например, из-за перезапуска сервера) и может быть выполнен повторно с тем же ключом.
+// Обработчик продолжает работу и после таймаута записи ответа: оформление заказа отправляет
+// письма и формирует счет, поэтому запас взят с большим превышением их худшего времени.
+// До истечения этого времени повторный запрос получает ответ 409
+const abandonedRequestTimeout = "15 minutes"
+
+// ReserveIdempotencyKey закрепляет ключ за запросом с хешем requestHash на время ttl.
+// Если ключ свободен, истек или запрос с тем же хешем был прерван, ключ закрепляется
+// (второй результат - true) и запрос нужно выполнить. Иначе возвращается существующая запись:
+// с сохраненным ответом, еще выполняющаяся или с другим хешем запроса
+func (r *PostgresRepository) ReserveIdempotencyKey(ctx context.Context, scope, key, requestHash string, ttl time.Duration) (models.IdempotencyRecord, bool, error) {
+	var record models.IdempotencyRecord
+
+	reserve := `
+	INSERT INTO idempotency_keys (scope, key, request_hash, created_at, expires_at)
+	VALUES ($1, $2, $3, NOW(), NOW() + make_interval(secs => $4))
+	ON CONFLICT (scope, key) DO UPDATE
+	SET request_hash = EXCLUDED.request_hash, status_code = NULL, content_type = NULL, response = NULL,
+		created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+	WHERE idempotency_keys.expires_at <= NOW()
+	   OR (idempotency_keys.status_code IS NULL AND idempotency_keys.request_hash = EXCLUDED.request_hash
+		   AND idempotency_keys.created_at < NOW() - INTERVAL '` + abandonedRequestTimeout + `')
+	RETURNING ` + idempotencyColumns
+	existing := `SELECT ` + idempotencyColumns + ` FROM idempotency_keys WHERE scope = $1 AND key = $2`
+
+	// Запись может быть удалена между попытками закрепления и чтения - тогда пробуем еще раз
+	for attempt := 0; attempt < 2; attempt++ {
+		err := r.db.GetContext(ctx, &record, reserve, scope, key, requestHash, int64(ttl/time.Second))
+		if err == nil {
+			return record, true, nil
+		}
+		if err != sql.ErrNoRows {
+			r.logger.WithError(err).Errorf("Ошибка при закреплении ключа идемпотентности %s", scope)
+			return record, false, fmt.Errorf("ошибка при закреплении ключа идемпотентности: %w", err)
+		}
+
+		err = r.db.GetContext(ctx, &record, existing, scope, key)
+		if err == nil {
+			return record, false, nil
+		}
+		if err != sql.ErrNoRows {
+			r.logger.WithError(err).Errorf("Ошибка при получении ключа идемпотентности %s", scope)
+			return record, false, fmt.Errorf("ошибка при получении ключа идемпотентности: %w", err)
+		}
+	}
+
+	return record, false, fmt.Errorf("не удалось закрепить ключ идемпотентности")
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос, выполненный с ключом идемпотентности
+func (r *PostgresRepository) CompleteIdempotencyKey(ctx context.Context, scope, key string, statusCode int, contentType string, response []byte) error {
+	query := `
+	UPDATE idempotency_keys
+	SET status_code = $3, content_type = $4, response = $5
+	WHERE scope = $1 AND key = $2
+	`
+	if _, err := r.db.ExecContext(ctx, query, scope, key, statusCode, contentType, response); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при сохранении ответа для ключа идемпотентности %s", scope)
+		return fmt.Errorf("ошибка при сохранении ответа для ключа идемпотентности: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ, чтобы запрос можно было повторить
+// (например, после ошибки сервера)
+func (r *PostgresRepository) ReleaseIdempotencyKey(ctx context.Context, scope, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND status_code IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, scope, key); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при освобождении ключа идемпотентности %s", scope)
+		return fmt.Errorf("ошибка при освобождении ключа идемпотентности: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys удаляет истекшие ключи и возвращает их количество
+func (r *PostgresRepository) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= NOW()`)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при удалении истекших ключей идемпотентности")
+		return 0, fmt.Errorf("ошибка при удалении истекших ключей идемпотентности: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при получении количества удаленных ключей: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+
+	"pryanik_studio/internal/models"
+)
+
+var (
+	// ErrInsufficientStock возвращается, если товара или варианта недостаточно на складе
+	ErrInsufficientStock = errors.New("недостаточно товара на складе")
+
+	// ErrProductNotFound возвращается, если товар не найден
+	ErrProductNotFound = errors.New("товар не найден")
+)
+
+// stockColumns колонки наличия товара; требуют соединения stockJoin.
+// Для товара с включенными вариантами наличие считается по вариантам, иначе - по остатку товара
+const stockColumns = `
+           CASE WHEN sv.variants > 0 THEN sv.in_stock ELSE p.stock IS NULL OR p.stock > 0 END AS in_stock,
+           CASE WHEN sv.variants > 0 THEN sv.available ELSE p.stock END AS available_quantity`
+
+// stockJoin соединение с агрегированными остатками включенных вариантов товара
+const stockJoin = `
+    LEFT JOIN LATERAL (
+        SELECT COUNT(*) AS variants,
+               bool_or(v.stock IS NULL OR v.stock > 0) AS in_stock,
+               CASE WHEN bool_and(v.stock IS NOT NULL) THEN SUM(v.stock) END AS available
+        FROM product_variants v
+        WHERE v.product_id = p.id AND v.enabled
+    ) sv ON true
+    `
+
+// SetProductStock задает остаток товара без вариантов; nil отключает учет остатка
+func (r *PostgresRepository) SetProductStock(ctx context.Context, productID int64, stock *int) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE products SET stock = $1, updated_at = NOW() WHERE id = $2`, stock, productID)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при изменении остатка товара ID=%d", productID)
+		return fmt.Errorf("ошибка при изменении остатка товара: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrProductNotFound
+	}
+
+	return nil
+}
+
+// reserveStock списывает остатки товаров и вариантов позиций заказа в рамках транзакции.
+// Строки блокируются в порядке ID, чтобы одновременные заказы не блокировали друг друга взаимно.
+// Позициям с учитываемым остатком проставляются Reserved и StockLeft
+func reserveStock(ctx context.Context, tx *sqlx.Tx, items []models.OrderItem) error {
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		x, y := items[order[a]], items[order[b]]
+		if (x.VariantID == nil) != (y.VariantID == nil) {
+			return x.VariantID == nil
+		}
+		if x.VariantID != nil {
+			return *x.VariantID < *y.VariantID
+		}
+		return x.ProductID < y.ProductID
+	})
+
+	for _, i := range order {
+		item := &items[i]
+
+		table, id := "products", item.ProductID
+		if item.VariantID != nil {
+			table, id = "product_variants", *item.VariantID
+		}
+
+		var stock sql.NullInt64
+		err := tx.QueryRowContext(ctx, `SELECT stock FROM `+table+` WHERE id = $1 FOR UPDATE`, id).Scan(&stock)
+		if err == sql.ErrNoRows || (err == nil && !stock.Valid) {
+			// Остаток не учитывается; отсутствие товара проверит внешний ключ позиции
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка при блокировке остатка: %w", err)
+		}
+
+		// Позиции с одним и тем же товаром списываются по очереди, остаток уже уменьшен предыдущими
+		if stock.Int64 < int64(item.Quantity) {
+			return fmt.Errorf("%w: товар ID=%d, остаток %d, требуется %d",
+				ErrInsufficientStock, item.ProductID, stock.Int64, item.Quantity)
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE `+table+` SET stock = stock - $1 WHERE id = $2`, item.Quantity, id); err != nil {
+			return fmt.Errorf("ошибка при списании остатка: %w", err)
+		}
+
+		left := int(stock.Int64) - item.Quantity
+		item.Reserved = true
+		item.StockLeft = &left
+	}
+
+	return nil
+}
+
+// releaseStock возвращает на склад остатки, списанные позициями заказа
+func releaseStock(ctx context.Context, tx *sqlx.Tx, orderID int64) error {
+	var items []struct {
+		ProductID int64  `db:"product_id"`
+		VariantID *int64 `db:"variant_id"`
+		Quantity  int    `db:"quantity"`
+	}
+
+	query := `
+	SELECT product_id, variant_id, quantity FROM order_items
+	WHERE order_id = $1 AND reserved
+	  -- Вариант удален после заказа: остаток товара не трогаем. Позиции без варианта,
+	  -- созданные до сохранения NULL, хранят пустой SKU
+	  AND NOT (variant_id IS NULL AND COALESCE(sku, '') <> '')
+	ORDER BY variant_id NULLS FIRST, product_id
+	`
+	if err := tx.SelectContext(ctx, &items, query, orderID); err != nil {
+		return fmt.Errorf("ошибка при получении списанных остатков: %w", err)
+	}
+
+	for _, item := range items {
+		table, id := "products", item.ProductID
+		if item.VariantID != nil {
+			table, id = "product_variants", *item.VariantID
+		}
+
+		// Если учет остатка с тех пор отключен, возвращать нечего
+		query := `UPDATE ` + table + ` SET stock = stock + $1 WHERE id = $2 AND stock IS NOT NULL`
+		if _, err := tx.ExecContext(ctx, query, item.Quantity, id); err != nil {
+			return fmt.Errorf("ошибка при возврате остатка: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE order_items SET reserved = false WHERE order_id = $1`, orderID); err != nil {
+		return fmt.Errorf("ошибка при возврате остатка: %w", err)
+	}
+
+	return nil
+}
+
+// nullableInt преобразует целое из базы данных в указатель (nil для NULL)
+func nullableInt(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	result := int(value.Int64)
+	return &result
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+
+	"pryanik_studio/internal/models"
+)
+
+// testRepository подключается к тестовой базе из TEST_DATABASE_DSN и выполняет миграции.
+// Без переменной тест пропускается
+func testRepository(t *testing.T) (*PostgresRepository, *sqlx.DB) {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN не задана")
+	}
+
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatalf("подключение к тестовой базе: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	if err := MigrateDatabase(db, logger); err != nil {
+		t.Fatalf("миграции: %v", err)
+	}
+	return NewPostgresRepository(db, logger), db
+}
+
+func TestCancelOrderReleasesProductStock(t *testing.T) {
+	repo, db := testRepository(t)
+	ctx := context.Background()
+
+	var productID int64
+	err := db.QueryRowContext(ctx, `
+	INSERT INTO products (category_id, stock) SELECT id, 5 FROM categories ORDER BY id LIMIT 1
+	RETURNING id`).Scan(&productID)
+	if err != nil {
+		t.Fatalf("создание товара: %v", err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM products WHERE id = $1`, productID) })
+
+	price := models.NewMoney(100000, "RUB")
+	order := &models.Order{
+		Name:      "Тест",
+		Email:     "test@example.com",
+		Phone:     "+70000000000",
+		TotalCost: models.NewMoney(200000, "RUB"),
+		Subtotal:  models.NewMoney(200000, "RUB"),
+		Currency:  "RUB",
+		Items: []models.OrderItem{
+			{ProductID: productID, Quantity: 2, Price: price, Currency: "RUB"},
+		},
+	}
+	orderID, err := repo.CreateOrder(ctx, order)
+	if err != nil {
+		t.Fatalf("создание заказа: %v", err)
+	}
+	t.Cleanup(func() { db.Exec(`DELETE FROM orders WHERE id = $1`, orderID) })
+
+	stock := func() int {
+		var value int
+		if err := db.GetContext(ctx, &value, `SELECT stock FROM products WHERE id = $1`, productID); err != nil {
+			t.Fatalf("получение остатка: %v", err)
+		}
+		return value
+	}
+	if got := stock(); got != 3 {
+		t.Fatalf("остаток после заказа = %d, ожидалось 3", got)
+	}
+
+	if err := repo.UpdateOrderStatus(ctx, orderID, models.OrderStatusCancelled); err != nil {
+		t.Fatalf("отмена заказа: %v", err)
+	}
+	if got := stock(); got != 5 {
+		t.Errorf("остаток после отмены = %d, ожидалось 5", got)
+	}
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"pryanik_studio/internal/models"
+)
+
+// ErrInvoiceNotFound возвращается, если по заказу еще не выставлен счет
+var ErrInvoiceNotFound = errors.New("счет не найден")
+
+// invoiceColumns колонки счета
+const invoiceColumns = `id, order_id, number, year, sequence, seller, issued_at`
+
+// IssueInvoice выставляет счет по заказу: присваивает следующий номер года и сохраняет реквизиты
+// продавца. Если счет по заказу уже выставлен, возвращается он без изменений. Номер имеет вид
+// «<prefix><год>-<порядковый номер>»; счетчик блокируется до конца транзакции, поэтому номера
+// идут подряд без пропусков. По отмененному заказу новый счет не выставляется
+func (r *PostgresRepository) IssueInvoice(ctx context.Context, orderID int64, prefix string, seller models.CompanyRequisites) (models.Invoice, error) {
+	var invoice models.Invoice
+
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для выставления счета")
+		return invoice, fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	// Блокировка заказа не дает выставить по нему два счета одновременно
+	var status string
+	if err = tx.GetContext(ctx, &status, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, orderID); err != nil {
+		if err == sql.ErrNoRows {
+			err = fmt.Errorf("%w: ID=%d", ErrOrderNotFound, orderID)
+			return invoice, err
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении заказа ID=%d", orderID)
+		return invoice, fmt.Errorf("ошибка при получении заказа: %w", err)
+	}
+
+	query := `SELECT ` + invoiceColumns + ` FROM invoices WHERE order_id = $1`
+	err = tx.GetContext(ctx, &invoice, query, orderID)
+	if err == nil {
+		if err = tx.Commit(); err != nil {
+			return invoice, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+		}
+		return invoice, nil
+	}
+	if err != sql.ErrNoRows {
+		r.logger.WithError(err).Errorf("Ошибка при получении счета заказа ID=%d", orderID)
+		return invoice, fmt.Errorf("ошибка при получении счета: %w", err)
+	}
+
+	if status == models.OrderStatusCancelled {
+		err = ErrOrderStatus
+		return invoice, err
+	}
+
+	issuedAt := time.Now()
+	year := issuedAt.Year()
+
+	var sequence int
+	query = `
+	INSERT INTO invoice_counters (year, last_sequence) VALUES ($1, 1)
+	ON CONFLICT (year) DO UPDATE SET last_sequence = invoice_counters.last_sequence + 1
+	RETURNING last_sequence
+	`
+	if err = tx.GetContext(ctx, &sequence, query, year); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении номера счета за %d год", year)
+		return invoice, fmt.Errorf("ошибка при получении номера счета: %w", err)
+	}
+
+	number := fmt.Sprintf("%s%d-%05d", prefix, year, sequence)
+	query = `
+	INSERT INTO invoices (order_id, number, year, sequence, seller, issued_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING ` + invoiceColumns
+	if err = tx.GetContext(ctx, &invoice, query, orderID, number, year, sequence, seller, issuedAt); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при выставлении счета по заказу ID=%d", orderID)
+		return invoice, fmt.Errorf("ошибка при выставлении счета: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции выставления счета")
+		return invoice, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	r.logger.Infof("Выставлен счет %s по заказу ID=%d", invoice.Number, orderID)
+	return invoice, nil
+}
+
+// GetOrderInvoice возвращает счет, выставленный по заказу
+func (r *PostgresRepository) GetOrderInvoice(ctx context.Context, orderID int64) (models.Invoice, error) {
+	var invoice models.Invoice
+
+	query := `SELECT ` + invoiceColumns + ` FROM invoices WHERE order_id = $1`
+	if err := r.db.GetContext(ctx, &invoice, query, orderID); err != nil {
+		if err == sql.ErrNoRows {
+			return invoice, fmt.Errorf("%w: заказ ID=%d", ErrInvoiceNotFound, orderID)
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении счета заказа ID=%d", orderID)
+		return invoice, fmt.Errorf("ошибка при получении счета: %w", err)
+	}
+
+	return invoice, nil
+}
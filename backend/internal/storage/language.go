@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"pryanik_studio/internal/models"
+)
+
+// ErrLanguageNotFound возвращается, если язык не зарегистрирован
+var ErrLanguageNotFound = errors.New("язык не найден")
+
+// GetLanguages возвращает все зарегистрированные языки, включая отключенные
+func (r *PostgresRepository) GetLanguages(ctx context.Context) ([]models.Language, error) {
+	var languages []models.Language
+
+	query := `
+	SELECT code, name, default_currency, enabled, is_default, fallback, sort_order, created_at, updated_at
+	FROM languages
+	ORDER BY sort_order, code
+	`
+
+	if err := r.db.SelectContext(ctx, &languages, query); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении списка языков")
+		return nil, fmt.Errorf("ошибка при получении списка языков: %w", err)
+	}
+
+	return languages, nil
+}
+
+// CreateLanguage добавляет новый язык
+func (r *PostgresRepository) CreateLanguage(ctx context.Context, language *models.Language) error {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для добавления языка")
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	// Добавляем отложенную функцию для отката транзакции в случае ошибки
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	now := time.Now()
+	language.CreatedAt = now
+	language.UpdatedAt = now
+
+	// Язык по умолчанию может быть только один
+	if language.IsDefault {
+		if _, err = tx.ExecContext(ctx, "UPDATE languages SET is_default = false, updated_at = NOW() WHERE is_default"); err != nil {
+			return fmt.Errorf("ошибка при сбросе языка по умолчанию: %w", err)
+		}
+	}
+
+	query := `
+	INSERT INTO languages (code, name, default_currency, enabled, is_default, fallback, sort_order, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err = tx.ExecContext(
+		ctx,
+		query,
+		language.Code,
+		language.Name,
+		language.DefaultCurrency,
+		language.Enabled,
+		language.IsDefault,
+		language.Fallback,
+		language.SortOrder,
+		language.CreatedAt,
+		language.UpdatedAt,
+	)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при добавлении языка %s", language.Code)
+		return fmt.Errorf("ошибка при добавлении языка: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateLanguage обновляет существующий язык
+func (r *PostgresRepository) UpdateLanguage(ctx context.Context, language *models.Language) error {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для обновления языка")
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	// Добавляем отложенную функцию для отката транзакции в случае ошибки
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	// Язык по умолчанию может быть только один
+	if language.IsDefault {
+		if _, err = tx.ExecContext(ctx, "UPDATE languages SET is_default = false, updated_at = NOW() WHERE is_default AND code <> $1", language.Code); err != nil {
+			return fmt.Errorf("ошибка при сбросе языка по умолчанию: %w", err)
+		}
+	}
+
+	query := `
+	UPDATE languages
+	SET name = $1,
+	    default_currency = $2,
+	    enabled = $3,
+	    is_default = $4,
+	    fallback = $5,
+	    sort_order = $6,
+	    updated_at = NOW()
+	WHERE code = $7
+	`
+
+	var res sql.Result
+	res, err = tx.ExecContext(
+		ctx,
+		query,
+		language.Name,
+		language.DefaultCurrency,
+		language.Enabled,
+		language.IsDefault,
+		language.Fallback,
+		language.SortOrder,
+		language.Code,
+	)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при обновлении языка %s", language.Code)
+		return fmt.Errorf("ошибка при обновлении языка: %w", err)
+	}
+
+	var affected int64
+	if affected, err = res.RowsAffected(); err != nil {
+		return fmt.Errorf("ошибка при обновлении языка: %w", err)
+	}
+	if affected == 0 {
+		err = ErrLanguageNotFound
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
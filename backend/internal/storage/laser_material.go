@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"pryanik_studio/internal/models"
+)
+
+// ErrLaserMaterialNotFound возвращается, если материал лазерной обработки не найден
+var ErrLaserMaterialNotFound = errors.New("материал не найден")
+
+// laserMaterialColumns колонки, которые выбираются для материала лазерной обработки
+const laserMaterialColumns = `
+	code, name, cut_price_per_meter, engrave_price_per_cm2, sheet_price_per_cm2, minimum_charge,
+	currency, enabled, created_at, updated_at
+`
+
+// GetLaserMaterials возвращает материалы лазерной обработки, отсортированные по названию
+func (r *PostgresRepository) GetLaserMaterials(ctx context.Context, onlyEnabled bool) ([]models.LaserMaterial, error) {
+	query := `SELECT ` + laserMaterialColumns + ` FROM laser_materials
+	WHERE enabled OR NOT $1
+	ORDER BY name, code`
+
+	var materials []models.LaserMaterial
+	if err := r.db.SelectContext(ctx, &materials, query, onlyEnabled); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении материалов лазерной обработки")
+		return nil, fmt.Errorf("ошибка при получении материалов лазерной обработки: %w", err)
+	}
+
+	for i := range materials {
+		setLaserMaterialCurrency(&materials[i])
+	}
+
+	return materials, nil
+}
+
+// GetLaserMaterial возвращает материал лазерной обработки по коду
+func (r *PostgresRepository) GetLaserMaterial(ctx context.Context, code string) (models.LaserMaterial, error) {
+	var material models.LaserMaterial
+
+	query := `SELECT ` + laserMaterialColumns + ` FROM laser_materials WHERE code = $1`
+	if err := r.db.GetContext(ctx, &material, query, code); err != nil {
+		if err == sql.ErrNoRows {
+			return material, ErrLaserMaterialNotFound
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении материала лазерной обработки %s", code)
+		return material, fmt.Errorf("ошибка при получении материала лазерной обработки: %w", err)
+	}
+
+	setLaserMaterialCurrency(&material)
+	return material, nil
+}
+
+// SaveLaserMaterial создает материал лазерной обработки или обновляет существующий с тем же кодом
+func (r *PostgresRepository) SaveLaserMaterial(ctx context.Context, material *models.LaserMaterial) error {
+	query := `
+	INSERT INTO laser_materials (code, name, cut_price_per_meter, engrave_price_per_cm2, sheet_price_per_cm2,
+	                             minimum_charge, currency, enabled, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+	ON CONFLICT (code) DO UPDATE
+	SET name = EXCLUDED.name,
+	    cut_price_per_meter = EXCLUDED.cut_price_per_meter,
+	    engrave_price_per_cm2 = EXCLUDED.engrave_price_per_cm2,
+	    sheet_price_per_cm2 = EXCLUDED.sheet_price_per_cm2,
+	    minimum_charge = EXCLUDED.minimum_charge,
+	    currency = EXCLUDED.currency,
+	    enabled = EXCLUDED.enabled,
+	    updated_at = NOW()
+	RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		material.Code,
+		material.Name,
+		material.CutPricePerMeter,
+		material.EngravePricePerCM2,
+		material.SheetPricePerCM2,
+		material.MinimumCharge,
+		material.Currency,
+		material.Enabled,
+	).Scan(&material.CreatedAt, &material.UpdatedAt)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при сохранении материала лазерной обработки %s", material.Code)
+		return fmt.Errorf("ошибка при сохранении материала лазерной обработки: %w", err)
+	}
+
+	return nil
+}
+
+// setLaserMaterialCurrency проставляет валюту материала в его ценах
+func setLaserMaterialCurrency(material *models.LaserMaterial) {
+	material.CutPricePerMeter.Currency = material.Currency
+	material.EngravePricePerCM2.Currency = material.Currency
+	material.SheetPricePerCM2.Currency = material.Currency
+	material.MinimumCharge.Currency = material.Currency
+}
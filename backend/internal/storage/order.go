@@ -3,14 +3,24 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 
 	"pryanik_studio/internal/models"
 )
 
+var (
+	// ErrOrderNotFound возвращается, если заказ не найден
+	ErrOrderNotFound = errors.New("заказ не найден")
+
+	// ErrOrderStatus возвращается, если переход в запрошенный статус заказа недопустим
+	ErrOrderStatus = errors.New("недопустимое изменение статуса заказа")
+)
+
 // CreateOrder создает новый заказ в базе данных
 func (r *PostgresRepository) CreateOrder(ctx context.Context, order *models.Order) (int64, error) {
 	// Начинаем транзакцию
@@ -29,6 +39,22 @@ func (r *PostgresRepository) CreateOrder(ctx context.Context, order *models.Orde
 		}
 	}()
 
+	orderID, err := r.insertOrder(ctx, tx, order)
+	if err != nil {
+		return 0, err
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return 0, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return orderID, nil
+}
+
+// insertOrder добавляет заказ и его товары в рамках транзакции
+func (r *PostgresRepository) insertOrder(ctx context.Context, tx *sqlx.Tx, order *models.Order) (int64, error) {
 	// Устанавливаем язык по умолчанию, если он не задан
 	if order.Language == "" {
 		order.Language = "ru"
@@ -50,13 +76,16 @@ func (r *PostgresRepository) CreateOrder(ctx context.Context, order *models.Orde
 
 	// Вставляем заказ
 	query := `
-	INSERT INTO orders (name, email, phone, comment, status, total_cost, language, created_at, updated_at)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	INSERT INTO orders (name, email, phone, comment, status, total_cost, currency, language, created_at, updated_at,
+	                    subtotal, discount, promo_code, shipping_method, shipping_method_name, shipping_cost,
+	                    shipping_address)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), NULLIF($14, ''), NULLIF($15, ''),
+	        $16, $17)
 	RETURNING id
 	`
 
 	var orderID int64
-	err = tx.QueryRowContext(
+	err := tx.QueryRowContext(
 		ctx,
 		query,
 		order.Name,
@@ -65,9 +94,17 @@ func (r *PostgresRepository) CreateOrder(ctx context.Context, order *models.Orde
 		order.Comment,
 		order.Status,
 		order.TotalCost,
+		order.Currency,
 		order.Language,
 		order.CreatedAt,
 		order.UpdatedAt,
+		order.Subtotal,
+		order.Discount,
+		order.PromoCode,
+		order.ShippingMethod,
+		order.ShippingMethodName,
+		order.ShippingCost,
+		order.ShippingAddress,
 	).Scan(&orderID)
 
 	if err != nil {
@@ -75,22 +112,62 @@ func (r *PostgresRepository) CreateOrder(ctx context.Context, order *models.Orde
 		return 0, fmt.Errorf("ошибка при создании заказа: %w", err)
 	}
 
+	// Использование промокода фиксируется в той же транзакции, что и заказ
+	if order.PromoCode != "" {
+		if err := redeemPromo(ctx, tx, orderID, order.PromoCode, order.Email); err != nil {
+			if !errors.Is(err, ErrPromoLimit) && !errors.Is(err, ErrPromoNotFound) {
+				r.logger.WithError(err).Error("Ошибка при применении промокода")
+			}
+			return 0, err
+		}
+	}
+
 	// Вставляем товары заказа, если они есть
 	if len(order.Items) > 0 {
+		// Списываем остатки до вставки позиций, чтобы сохранить признак списания
+		if err := reserveStock(ctx, tx, order.Items); err != nil {
+			if !errors.Is(err, ErrInsufficientStock) {
+				r.logger.WithError(err).Error("Ошибка при списании остатков заказа")
+			}
+			return 0, err
+		}
+
+		// Расчеты стоимости одноразовые: связываем их с заказом до вставки позиций
+		if err := claimEstimates(ctx, tx, orderID, order.Items); err != nil {
+			if !errors.Is(err, ErrEstimateUsed) {
+				r.logger.WithError(err).Error("Ошибка при связывании расчетов стоимости с заказом")
+			}
+			return 0, err
+		}
+
 		// SQL-запрос для вставки товаров заказа
 		itemsQuery := `
-		INSERT INTO order_items (order_id, product_id, quantity, price)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO order_items (order_id, product_id, variant_id, sku, variant_name, personalization, estimate_id,
+		                         reserved, quantity, price, currency, discount)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, $9, $10, $11, $12)
 		`
 
 		for _, item := range order.Items {
+			// Позиция должна быть в валюте заказа
+			if item.Currency != order.Currency {
+				return 0, fmt.Errorf("%w: позиция в %s, заказ в %s", models.ErrCurrencyMismatch, item.Currency, order.Currency)
+			}
+
 			_, err = tx.ExecContext(
 				ctx,
 				itemsQuery,
 				orderID,
 				item.ProductID,
+				item.VariantID,
+				item.SKU,
+				item.VariantName,
+				item.Personalization,
+				item.EstimateID,
+				item.Reserved,
 				item.Quantity,
 				item.Price,
+				item.Currency,
+				item.Discount,
 			)
 
 			if err != nil {
@@ -100,12 +177,6 @@ func (r *PostgresRepository) CreateOrder(ctx context.Context, order *models.Orde
 		}
 	}
 
-	// Фиксируем транзакцию
-	if err = tx.Commit(); err != nil {
-		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
-		return 0, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
-	}
-
 	return orderID, nil
 }
 
@@ -115,7 +186,11 @@ func (r *PostgresRepository) GetOrderByID(ctx context.Context, id int64) (models
 
 	// Получаем основную информацию о заказе
 	query := `
-	SELECT id, name, email, phone, comment, status, total_cost, language, created_at, updated_at
+	SELECT id, name, email, phone, comment, status, total_cost, currency, language, created_at, updated_at,
+	       subtotal, discount, COALESCE(promo_code, '') AS promo_code,
+	       COALESCE(shipping_method, '') AS shipping_method,
+	       COALESCE(shipping_method_name, '') AS shipping_method_name,
+	       shipping_cost, shipping_address, payment_status
 	FROM orders
 	WHERE id = $1
 	`
@@ -127,16 +202,26 @@ func (r *PostgresRepository) GetOrderByID(ctx context.Context, id int64) (models
 		Phone     string         `db:"phone"`
 		Comment   string         `db:"comment"`
 		Status    string         `db:"status"`
-		TotalCost float64        `db:"total_cost"`
+		TotalCost models.Money   `db:"total_cost"`
+		Currency  string         `db:"currency"`
 		Language  sql.NullString `db:"language"`
 		CreatedAt sql.NullTime   `db:"created_at"`
 		UpdatedAt sql.NullTime   `db:"updated_at"`
+		Subtotal  models.Money   `db:"subtotal"`
+		Discount  models.Money   `db:"discount"`
+		PromoCode string         `db:"promo_code"`
+
+		ShippingMethod     string          `db:"shipping_method"`
+		ShippingMethodName string          `db:"shipping_method_name"`
+		ShippingCost       models.Money    `db:"shipping_cost"`
+		ShippingAddress    *models.Address `db:"shipping_address"`
+		PaymentStatus      string          `db:"payment_status"`
 	}
 
 	err := r.db.GetContext(ctx, &result, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return order, fmt.Errorf("заказ с ID=%d не найден", id)
+			return order, fmt.Errorf("%w: ID=%d", ErrOrderNotFound, id)
 		}
 		r.logger.WithError(err).Errorf("Ошибка при получении заказа ID=%d", id)
 		return order, fmt.Errorf("ошибка при получении заказа: %w", err)
@@ -149,7 +234,16 @@ func (r *PostgresRepository) GetOrderByID(ctx context.Context, id int64) (models
 	order.Phone = result.Phone
 	order.Comment = result.Comment
 	order.Status = result.Status
-	order.TotalCost = result.TotalCost
+	order.TotalCost = models.NewMoney(result.TotalCost.Amount, result.Currency)
+	order.Currency = result.Currency
+	order.Subtotal = models.NewMoney(result.Subtotal.Amount, result.Currency)
+	order.Discount = models.NewMoney(result.Discount.Amount, result.Currency)
+	order.PromoCode = result.PromoCode
+	order.ShippingMethod = result.ShippingMethod
+	order.ShippingMethodName = result.ShippingMethodName
+	order.ShippingCost = models.NewMoney(result.ShippingCost.Amount, result.Currency)
+	order.ShippingAddress = result.ShippingAddress
+	order.PaymentStatus = result.PaymentStatus
 
 	// Устанавливаем язык, учитывая возможность NULL значения
 	if result.Language.Valid {
@@ -168,10 +262,16 @@ func (r *PostgresRepository) GetOrderByID(ctx context.Context, id int64) (models
 
 	// Получаем товары заказа
 	itemsQuery := `
-	SELECT oi.id, oi.order_id, oi.product_id, oi.quantity, oi.price,
+	SELECT oi.id, oi.order_id, oi.product_id, oi.variant_id, oi.sku, oi.variant_name,
+	       oi.personalization, oi.estimate_id, oi.quantity, oi.price, oi.currency, oi.discount,
 	       pt.name as product_name
 	FROM order_items oi
-	LEFT JOIN product_translations pt ON oi.product_id = pt.product_id AND pt.language = $2
+	LEFT JOIN LATERAL (
+		SELECT t.name FROM product_translations t
+		WHERE t.product_id = oi.product_id AND t.language = ANY($2::text[])
+		ORDER BY array_position($2::text[], t.language::text)
+		LIMIT 1
+	) pt ON true
 	WHERE oi.order_id = $1
 	ORDER BY oi.id
 	`
@@ -180,12 +280,20 @@ func (r *PostgresRepository) GetOrderByID(ctx context.Context, id int64) (models
 		ID          int64          `db:"id"`
 		OrderID     int64          `db:"order_id"`
 		ProductID   int64          `db:"product_id"`
-		Quantity    int            `db:"quantity"`
-		Price       float64        `db:"price"`
-		ProductName sql.NullString `db:"product_name"`
+		VariantID   *int64         `db:"variant_id"`
+		SKU         sql.NullString `db:"sku"`
+		VariantName sql.NullString `db:"variant_name"`
+
+		Personalization models.Personalization `db:"personalization"`
+		EstimateID      *string                `db:"estimate_id"`
+		Quantity        int                    `db:"quantity"`
+		Price           models.Money           `db:"price"`
+		Currency        string                 `db:"currency"`
+		Discount        models.Money           `db:"discount"`
+		ProductName     sql.NullString         `db:"product_name"`
 	}
 
-	err = r.db.SelectContext(ctx, &items, itemsQuery, id, order.Language)
+	err = r.db.SelectContext(ctx, &items, itemsQuery, id, pq.Array(r.languageChain(order.Language)))
 	if err != nil {
 		r.logger.WithError(err).Errorf("Ошибка при получении товаров заказа ID=%d", id)
 		return order, fmt.Errorf("ошибка при получении товаров заказа: %w", err)
@@ -198,14 +306,24 @@ func (r *PostgresRepository) GetOrderByID(ctx context.Context, id int64) (models
 			ID:        item.ID,
 			OrderID:   item.OrderID,
 			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			SKU:       item.SKU.String,
 			Quantity:  item.Quantity,
-			Price:     item.Price,
+			Price:     models.NewMoney(item.Price.Amount, item.Currency),
+			Currency:  item.Currency,
+			Discount:  models.NewMoney(item.Discount.Amount, item.Currency),
+
+			Personalization: item.Personalization,
+			EstimateID:      item.EstimateID,
 		}
 
 		// Устанавливаем наименование товара, если оно доступно
 		if item.ProductName.Valid {
 			orderItem.ProductName = item.ProductName.String
 		}
+		if item.VariantName.Valid {
+			orderItem.VariantName = item.VariantName.String
+		}
 
 		// Получаем изображение товара
 		var image string
@@ -244,3 +362,78 @@ func (r *PostgresRepository) GetOrderByID(ctx context.Context, id int64) (models
 
 	return order, nil
 }
+
+// UpdateOrderStatus изменяет статус заказа. При отмене списанные остатки возвращаются на склад;
+// отмененный заказ нельзя вернуть в работу, так как остатки могли быть уже проданы,
+// а его производственные задания отменяются. При запуске в производство (или сразу при выполнении)
+// списываются материалы мастерской; при отмене они не возвращаются - материал уже мог быть раскроен
+func (r *PostgresRepository) UpdateOrderStatus(ctx context.Context, id int64, status string) error {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для изменения статуса заказа")
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	var current string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, id).Scan(&current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrOrderNotFound
+			return err
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении заказа ID=%d", id)
+		return fmt.Errorf("ошибка при получении заказа: %w", err)
+	}
+
+	if err = setOrderStatus(ctx, tx, id, current, status); err != nil {
+		if !errors.Is(err, ErrOrderStatus) {
+			r.logger.WithError(err).Errorf("Ошибка при изменении статуса заказа ID=%d", id)
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
+
+// setOrderStatus меняет статус заказа, заблокированного в транзакции: при отмене возвращает остатки
+// и отменяет производственные задания, при передаче в производство списывает материалы
+func setOrderStatus(ctx context.Context, tx *sqlx.Tx, id int64, current, status string) error {
+	if current == models.OrderStatusCancelled && status != models.OrderStatusCancelled {
+		return ErrOrderStatus
+	}
+
+	if status == models.OrderStatusCancelled && current != models.OrderStatusCancelled {
+		if err := releaseStock(ctx, tx, id); err != nil {
+			return err
+		}
+		if err := cancelOrderJobs(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+
+	switch status {
+	case models.OrderStatusInProduction, models.OrderStatusReady, models.OrderStatusCompleted:
+		if err := consumeMaterials(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2`, status, id); err != nil {
+		return fmt.Errorf("ошибка при изменении статуса заказа: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,395 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"pryanik_studio/internal/models"
+)
+
+var (
+	// ErrPaymentNotFound возвращается, если платеж не найден
+	ErrPaymentNotFound = errors.New("платеж не найден")
+
+	// ErrOrderPaid возвращается при попытке оплатить уже оплаченный заказ
+	ErrOrderPaid = errors.New("заказ уже оплачен")
+
+	// ErrNothingToPay возвращается, если сумма заказа нулевая
+	ErrNothingToPay = errors.New("заказ не требует оплаты")
+)
+
+// paymentColumns колонки платежа
+const paymentColumns = `
+	id, order_id, provider, COALESCE(provider_payment_id, '') AS provider_payment_id,
+	COALESCE(provider_reference, '') AS provider_reference, status, amount, refunded_amount, currency,
+	COALESCE(confirmation_url, '') AS confirmation_url, COALESCE(failure_reason, '') AS failure_reason,
+	COALESCE(anomaly, '') AS anomaly, created_at, updated_at
+`
+
+// pendingPaymentTTL время, в течение которого ожидающий платеж можно повторно предложить
+// покупателю вместо создания нового (меньше срока действия ключа идемпотентности провайдера)
+const pendingPaymentTTL = "23 hours"
+
+// StartPayment резервирует ожидающий платеж на сумму заказа под блокировкой строки заказа.
+// Если у заказа уже есть ожидающий платеж у того же провайдера на ту же сумму, возвращается он,
+// даже если платеж у провайдера для него еще не создан: платеж у провайдера создается
+// вызывающим кодом после резервирования с ключом идемпотентности по ID платежа,
+// поэтому параллельные запросы получают один и тот же платеж
+func (r *PostgresRepository) StartPayment(ctx context.Context, orderID int64, provider string) (models.Payment, error) {
+	var payment models.Payment
+
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для создания платежа")
+		return payment, fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	var order struct {
+		Status        string       `db:"status"`
+		PaymentStatus string       `db:"payment_status"`
+		TotalCost     models.Money `db:"total_cost"`
+		Currency      string       `db:"currency"`
+	}
+	query := `SELECT status, payment_status, total_cost, currency FROM orders WHERE id = $1 FOR UPDATE`
+	if err = tx.GetContext(ctx, &order, query, orderID); err != nil {
+		if err == sql.ErrNoRows {
+			err = fmt.Errorf("%w: ID=%d", ErrOrderNotFound, orderID)
+			return payment, err
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении заказа ID=%d", orderID)
+		return payment, fmt.Errorf("ошибка при получении заказа: %w", err)
+	}
+
+	switch {
+	case order.Status == models.OrderStatusCancelled:
+		err = ErrOrderStatus
+		return payment, err
+	case order.PaymentStatus == models.OrderPaymentPaid || order.PaymentStatus == models.OrderPaymentPartiallyRefunded ||
+		order.PaymentStatus == models.OrderPaymentRefunded:
+		err = ErrOrderPaid
+		return payment, err
+	case order.TotalCost.IsZero():
+		err = ErrNothingToPay
+		return payment, err
+	}
+
+	query = `
+	SELECT ` + paymentColumns + `
+	FROM payments
+	WHERE order_id = $1 AND provider = $2 AND status = 'pending' AND amount = $3
+	  AND created_at > NOW() - INTERVAL '` + pendingPaymentTTL + `'
+	ORDER BY id DESC
+	LIMIT 1
+	`
+	err = tx.GetContext(ctx, &payment, query, orderID, provider, order.TotalCost)
+	if err == nil {
+		if err = tx.Commit(); err != nil {
+			return payment, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+		}
+		return paymentWithCurrency(payment), nil
+	}
+	if err != sql.ErrNoRows {
+		r.logger.WithError(err).Errorf("Ошибка при поиске ожидающего платежа заказа ID=%d", orderID)
+		return payment, fmt.Errorf("ошибка при поиске ожидающего платежа: %w", err)
+	}
+
+	query = `
+	INSERT INTO payments (order_id, provider, status, amount, currency, created_at, updated_at)
+	VALUES ($1, $2, 'pending', $3, $4, NOW(), NOW())
+	RETURNING ` + paymentColumns
+	if err = tx.GetContext(ctx, &payment, query, orderID, provider, order.TotalCost, order.Currency); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при создании платежа заказа ID=%d", orderID)
+		return payment, fmt.Errorf("ошибка при создании платежа: %w", err)
+	}
+
+	query = `UPDATE orders SET payment_status = 'pending', updated_at = NOW() WHERE id = $1`
+	if _, err = tx.ExecContext(ctx, query, orderID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при изменении статуса оплаты заказа ID=%d", orderID)
+		return payment, fmt.Errorf("ошибка при изменении статуса оплаты заказа: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return payment, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return paymentWithCurrency(payment), nil
+}
+
+// SetPaymentCreated сохраняет идентификатор платежа у провайдера и страницу оплаты
+func (r *PostgresRepository) SetPaymentCreated(ctx context.Context, id int64, providerPaymentID, confirmationURL string) error {
+	query := `
+	UPDATE payments SET provider_payment_id = $1, confirmation_url = $2, updated_at = NOW()
+	WHERE id = $3
+	`
+	if _, err := r.db.ExecContext(ctx, query, providerPaymentID, confirmationURL, id); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при сохранении данных провайдера для платежа ID=%d", id)
+		return fmt.Errorf("ошибка при сохранении данных провайдера для платежа: %w", err)
+	}
+	return nil
+}
+
+// FailPayment отмечает ожидающий платеж неуспешным, например если провайдер не смог его создать
+func (r *PostgresRepository) FailPayment(ctx context.Context, id int64, reason string) error {
+	query := `
+	WITH failed AS (
+		UPDATE payments SET status = 'failed', failure_reason = $2, updated_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING order_id
+	)
+	UPDATE orders SET payment_status = 'failed', updated_at = NOW()
+	WHERE id IN (SELECT order_id FROM failed) AND payment_status = 'pending'
+	`
+	if _, err := r.db.ExecContext(ctx, query, id, reason); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при отметке неуспешного платежа ID=%d", id)
+		return fmt.Errorf("ошибка при отметке неуспешного платежа: %w", err)
+	}
+	return nil
+}
+
+// ApplyPaymentEvent применяет событие провайдера к платежу и заказу по правилам paymentTransition.
+// Событие с уже обработанным ID пропускается (Applied - false)
+func (r *PostgresRepository) ApplyPaymentEvent(ctx context.Context, provider string, event models.PaymentEvent) (models.PaymentEventResult, error) {
+	var result models.PaymentEventResult
+	var payment models.Payment
+
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для обработки события платежа")
+		return result, fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	var ref struct {
+		ID      int64 `db:"id"`
+		OrderID int64 `db:"order_id"`
+	}
+	query := `
+	SELECT id, order_id FROM payments
+	WHERE provider = $1 AND (provider_payment_id = $2 OR provider_reference = $2)
+	ORDER BY id DESC
+	LIMIT 1
+	`
+	if err = tx.GetContext(ctx, &ref, query, provider, event.ProviderPaymentID); err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrPaymentNotFound
+			return result, err
+		}
+		r.logger.WithError(err).Errorf("Ошибка при поиске платежа %s", event.ProviderPaymentID)
+		return result, fmt.Errorf("ошибка при поиске платежа: %w", err)
+	}
+
+	// Блокируем заказ, затем платеж - в том же порядке, что и при изменении статуса заказа
+	var order paymentOrderState
+	query = `SELECT status, payment_status FROM orders WHERE id = $1 FOR UPDATE`
+	if err = tx.GetContext(ctx, &order, query, ref.OrderID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении заказа ID=%d", ref.OrderID)
+		return result, fmt.Errorf("ошибка при получении заказа: %w", err)
+	}
+	query = `SELECT ` + paymentColumns + ` FROM payments WHERE id = $1 FOR UPDATE`
+	if err = tx.GetContext(ctx, &payment, query, ref.ID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении платежа ID=%d", ref.ID)
+		return result, fmt.Errorf("ошибка при получении платежа: %w", err)
+	}
+	payment = paymentWithCurrency(payment)
+
+	query = `
+	INSERT INTO payment_events (provider, event_id, payment_id, type, created_at)
+	VALUES ($1, $2, $3, $4, NOW())
+	ON CONFLICT (provider, event_id) DO NOTHING
+	`
+	inserted, err := tx.ExecContext(ctx, query, provider, event.ID, payment.ID, event.Type)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при сохранении события платежа %s", event.ID)
+		return result, fmt.Errorf("ошибка при сохранении события платежа: %w", err)
+	}
+	affected, _ := inserted.RowsAffected()
+	updated, result := paymentEvent(order, payment, event, affected == 0)
+	if !result.Applied {
+		err = tx.Commit()
+		return result, err
+	}
+	payment = result.Payment
+
+	query = `
+	UPDATE payments
+	SET status = $1, provider_reference = NULLIF($2, ''), refunded_amount = $3, failure_reason = NULLIF($4, ''),
+	    anomaly = NULLIF($5, ''), updated_at = NOW()
+	WHERE id = $6
+	RETURNING updated_at
+	`
+	err = tx.QueryRowContext(ctx, query,
+		payment.Status,
+		payment.ProviderReference,
+		payment.RefundedAmount,
+		payment.FailureReason,
+		payment.Anomaly,
+		payment.ID,
+	).Scan(&payment.UpdatedAt)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при изменении платежа ID=%d", payment.ID)
+		return result, fmt.Errorf("ошибка при изменении платежа: %w", err)
+	}
+
+	if updated.PaymentStatus != order.PaymentStatus {
+		query = `UPDATE orders SET payment_status = $1, updated_at = NOW() WHERE id = $2`
+		if _, err = tx.ExecContext(ctx, query, updated.PaymentStatus, payment.OrderID); err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при изменении статуса оплаты заказа ID=%d", payment.OrderID)
+			return result, fmt.Errorf("ошибка при изменении статуса оплаты заказа: %w", err)
+		}
+	}
+	if updated.Status != order.Status {
+		if err = setOrderStatus(ctx, tx, payment.OrderID, order.Status, updated.Status); err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при изменении статуса заказа ID=%d", payment.OrderID)
+			return result, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return result, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	result.Payment = payment
+	return result, nil
+}
+
+// paymentEvent применяет событие провайдера к платежу и определяет результат обработки.
+// Повторно доставленное событие (duplicate) ничего не меняет; аномалия возвращается в результате
+// только при первом обнаружении, чтобы администратор получил одно уведомление
+func paymentEvent(order paymentOrderState, payment models.Payment, event models.PaymentEvent, duplicate bool) (paymentOrderState, models.PaymentEventResult) {
+	if duplicate {
+		return order, models.PaymentEventResult{Payment: payment}
+	}
+
+	updated, changed := paymentTransition(order, payment, event)
+	result := models.PaymentEventResult{Payment: changed, Applied: true}
+	if changed.Anomaly != payment.Anomaly {
+		result.Anomaly = changed.Anomaly
+	}
+	return updated, result
+}
+
+// paymentOrderState статусы заказа, которые меняются событиями платежа
+type paymentOrderState struct {
+	Status        string `db:"status"`
+	PaymentStatus string `db:"payment_status"`
+}
+
+// paymentTransition возвращает новые статусы заказа и платежа после события провайдера.
+// Успешная оплата переводит новый заказ в обработку, полный возврат отменяет заказ, если он еще
+// не выполнен. Успешная оплата заказа, уже оплаченного другим платежом, или отмененного заказа
+// сохраняется (деньги получены), но отмечается аномалией: ее нужно разобрать и вернуть деньги
+func paymentTransition(order paymentOrderState, payment models.Payment, event models.PaymentEvent) (paymentOrderState, models.Payment) {
+	switch event.Type {
+	case models.PaymentEventSucceeded:
+		if payment.Status != models.PaymentStatusPending && payment.Status != models.PaymentStatusFailed {
+			break
+		}
+		payment.Status = models.PaymentStatusSucceeded
+		payment.FailureReason = ""
+		if event.Reference != "" {
+			payment.ProviderReference = event.Reference
+		}
+
+		switch {
+		case order.PaymentStatus == models.OrderPaymentPaid || order.PaymentStatus == models.OrderPaymentPartiallyRefunded ||
+			order.PaymentStatus == models.OrderPaymentRefunded:
+			// Статус оплаты заказа относится к первому платежу и не меняется
+			payment.Anomaly = models.PaymentAnomalyDuplicate
+		case order.Status == models.OrderStatusCancelled:
+			payment.Anomaly = models.PaymentAnomalyCancelled
+			order.PaymentStatus = models.OrderPaymentPaid
+		default:
+			order.PaymentStatus = models.OrderPaymentPaid
+			if order.Status == models.OrderStatusNew {
+				order.Status = models.OrderStatusProcessing
+			}
+		}
+	case models.PaymentEventFailed:
+		if payment.Status == models.PaymentStatusPending {
+			payment.Status = models.PaymentStatusFailed
+			payment.FailureReason = event.Reason
+			if order.PaymentStatus == models.OrderPaymentPending {
+				order.PaymentStatus = models.OrderPaymentFailed
+			}
+		}
+	case models.PaymentEventRefunded:
+		// Провайдер сообщает общую сумму возвратов, поэтому повторы и события не по порядку безопасны
+		if payment.Status == models.PaymentStatusSucceeded && event.RefundedAmount.Amount > payment.RefundedAmount.Amount {
+			payment.RefundedAmount = models.NewMoney(min(event.RefundedAmount.Amount, payment.Amount.Amount), payment.Currency)
+			refunded := payment.RefundedAmount.Amount == payment.Amount.Amount
+			if refunded {
+				payment.Status = models.PaymentStatusRefunded
+			}
+			// Возврат повторной оплаты не меняет статус оплаты заказа, который относится к первому платежу
+			if payment.Anomaly != models.PaymentAnomalyDuplicate {
+				order.PaymentStatus = models.OrderPaymentPartiallyRefunded
+				if refunded {
+					order.PaymentStatus = models.OrderPaymentRefunded
+					if order.Status != models.OrderStatusCompleted {
+						order.Status = models.OrderStatusCancelled
+					}
+				}
+			}
+		}
+	}
+
+	return order, payment
+}
+
+// GetPayment возвращает платеж по ID
+func (r *PostgresRepository) GetPayment(ctx context.Context, id int64) (models.Payment, error) {
+	var payment models.Payment
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE id = $1`
+	if err := r.db.GetContext(ctx, &payment, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return payment, ErrPaymentNotFound
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении платежа ID=%d", id)
+		return payment, fmt.Errorf("ошибка при получении платежа: %w", err)
+	}
+	return paymentWithCurrency(payment), nil
+}
+
+// GetOrderPayments возвращает платежи заказа, новые первыми
+func (r *PostgresRepository) GetOrderPayments(ctx context.Context, orderID int64) ([]models.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE order_id = $1 ORDER BY id DESC`
+
+	var payments []models.Payment
+	if err := r.db.SelectContext(ctx, &payments, query, orderID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении платежей заказа ID=%d", orderID)
+		return nil, fmt.Errorf("ошибка при получении платежей заказа: %w", err)
+	}
+
+	for i := range payments {
+		payments[i] = paymentWithCurrency(payments[i])
+	}
+	return payments, nil
+}
+
+// paymentWithCurrency заполняет валюту сумм платежа, которая хранится в отдельной колонке
+func paymentWithCurrency(payment models.Payment) models.Payment {
+	payment.Amount = models.NewMoney(payment.Amount.Amount, payment.Currency)
+	payment.RefundedAmount = models.NewMoney(payment.RefundedAmount.Amount, payment.Currency)
+	return payment
+}
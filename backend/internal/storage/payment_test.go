@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"testing"
+
+	"pryanik_studio/internal/models"
+)
+
+func TestPaymentTransition(t *testing.T) {
+	amount := models.NewMoney(10000, "USD")
+	payment := func(status string, refunded int64, anomaly string) models.Payment {
+		return models.Payment{
+			ID:             1,
+			OrderID:        1,
+			Status:         status,
+			Amount:         amount,
+			RefundedAmount: models.NewMoney(refunded, "USD"),
+			Currency:       "USD",
+			Anomaly:        anomaly,
+		}
+	}
+	succeeded := models.PaymentEvent{ID: "evt", Type: models.PaymentEventSucceeded, Reference: "pi_1"}
+	failed := models.PaymentEvent{ID: "evt", Type: models.PaymentEventFailed, Reason: "Оплата не прошла"}
+	refunded := func(total int64) models.PaymentEvent {
+		return models.PaymentEvent{ID: "evt", Type: models.PaymentEventRefunded, RefundedAmount: models.NewMoney(total, "USD")}
+	}
+
+	tests := []struct {
+		name        string
+		order       paymentOrderState
+		payment     models.Payment
+		event       models.PaymentEvent
+		wantOrder   paymentOrderState
+		wantStatus  string
+		wantRefund  int64
+		wantAnomaly string
+	}{
+		{
+			name:       "оплата нового заказа",
+			order:      paymentOrderState{models.OrderStatusNew, models.OrderPaymentPending},
+			payment:    payment(models.PaymentStatusPending, 0, ""),
+			event:      succeeded,
+			wantOrder:  paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPaid},
+			wantStatus: models.PaymentStatusSucceeded,
+		},
+		{
+			name:       "оплата после неудачной попытки",
+			order:      paymentOrderState{models.OrderStatusNew, models.OrderPaymentFailed},
+			payment:    payment(models.PaymentStatusFailed, 0, ""),
+			event:      succeeded,
+			wantOrder:  paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPaid},
+			wantStatus: models.PaymentStatusSucceeded,
+		},
+		{
+			name:       "оплата заказа в работе не меняет его статус",
+			order:      paymentOrderState{models.OrderStatusInProduction, models.OrderPaymentPending},
+			payment:    payment(models.PaymentStatusPending, 0, ""),
+			event:      succeeded,
+			wantOrder:  paymentOrderState{models.OrderStatusInProduction, models.OrderPaymentPaid},
+			wantStatus: models.PaymentStatusSucceeded,
+		},
+		{
+			name:        "повторная оплата уже оплаченного заказа",
+			order:       paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPaid},
+			payment:     payment(models.PaymentStatusPending, 0, ""),
+			event:       succeeded,
+			wantOrder:   paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPaid},
+			wantStatus:  models.PaymentStatusSucceeded,
+			wantAnomaly: models.PaymentAnomalyDuplicate,
+		},
+		{
+			name:        "оплата отмененного заказа",
+			order:       paymentOrderState{models.OrderStatusCancelled, models.OrderPaymentPending},
+			payment:     payment(models.PaymentStatusPending, 0, ""),
+			event:       succeeded,
+			wantOrder:   paymentOrderState{models.OrderStatusCancelled, models.OrderPaymentPaid},
+			wantStatus:  models.PaymentStatusSucceeded,
+			wantAnomaly: models.PaymentAnomalyCancelled,
+		},
+		{
+			name:       "повтор успешной оплаты ничего не меняет",
+			order:      paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPaid},
+			payment:    payment(models.PaymentStatusSucceeded, 0, ""),
+			event:      succeeded,
+			wantOrder:  paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPaid},
+			wantStatus: models.PaymentStatusSucceeded,
+		},
+		{
+			name:       "неудачная оплата",
+			order:      paymentOrderState{models.OrderStatusNew, models.OrderPaymentPending},
+			payment:    payment(models.PaymentStatusPending, 0, ""),
+			event:      failed,
+			wantOrder:  paymentOrderState{models.OrderStatusNew, models.OrderPaymentFailed},
+			wantStatus: models.PaymentStatusFailed,
+		},
+		{
+			name:       "неудача после оплаты игнорируется",
+			order:      paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPaid},
+			payment:    payment(models.PaymentStatusSucceeded, 0, ""),
+			event:      failed,
+			wantOrder:  paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPaid},
+			wantStatus: models.PaymentStatusSucceeded,
+		},
+		{
+			name:       "частичный возврат",
+			order:      paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPaid},
+			payment:    payment(models.PaymentStatusSucceeded, 0, ""),
+			event:      refunded(4000),
+			wantOrder:  paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPartiallyRefunded},
+			wantStatus: models.PaymentStatusSucceeded,
+			wantRefund: 4000,
+		},
+		{
+			name:       "полный возврат отменяет заказ",
+			order:      paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPartiallyRefunded},
+			payment:    payment(models.PaymentStatusSucceeded, 4000, ""),
+			event:      refunded(10000),
+			wantOrder:  paymentOrderState{models.OrderStatusCancelled, models.OrderPaymentRefunded},
+			wantStatus: models.PaymentStatusRefunded,
+			wantRefund: 10000,
+		},
+		{
+			name:       "полный возврат не отменяет выполненный заказ",
+			order:      paymentOrderState{models.OrderStatusCompleted, models.OrderPaymentPaid},
+			payment:    payment(models.PaymentStatusSucceeded, 0, ""),
+			event:      refunded(10000),
+			wantOrder:  paymentOrderState{models.OrderStatusCompleted, models.OrderPaymentRefunded},
+			wantStatus: models.PaymentStatusRefunded,
+			wantRefund: 10000,
+		},
+		{
+			name:       "устаревшая сумма возврата игнорируется",
+			order:      paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPartiallyRefunded},
+			payment:    payment(models.PaymentStatusSucceeded, 6000, ""),
+			event:      refunded(4000),
+			wantOrder:  paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPartiallyRefunded},
+			wantStatus: models.PaymentStatusSucceeded,
+			wantRefund: 6000,
+		},
+		{
+			name:       "возврат больше суммы платежа ограничивается ею",
+			order:      paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPaid},
+			payment:    payment(models.PaymentStatusSucceeded, 0, ""),
+			event:      refunded(20000),
+			wantOrder:  paymentOrderState{models.OrderStatusCancelled, models.OrderPaymentRefunded},
+			wantStatus: models.PaymentStatusRefunded,
+			wantRefund: 10000,
+		},
+		{
+			name:        "возврат повторной оплаты не меняет заказ",
+			order:       paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPaid},
+			payment:     payment(models.PaymentStatusSucceeded, 0, models.PaymentAnomalyDuplicate),
+			event:       refunded(10000),
+			wantOrder:   paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPaid},
+			wantStatus:  models.PaymentStatusRefunded,
+			wantRefund:  10000,
+			wantAnomaly: models.PaymentAnomalyDuplicate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, updated := paymentTransition(tt.order, tt.payment, tt.event)
+			if order != tt.wantOrder {
+				t.Errorf("заказ %+v, ожидалось %+v", order, tt.wantOrder)
+			}
+			if updated.Status != tt.wantStatus {
+				t.Errorf("статус платежа %s, ожидался %s", updated.Status, tt.wantStatus)
+			}
+			if updated.RefundedAmount.Amount != tt.wantRefund {
+				t.Errorf("возвращено %d, ожидалось %d", updated.RefundedAmount.Amount, tt.wantRefund)
+			}
+			if updated.Anomaly != tt.wantAnomaly {
+				t.Errorf("аномалия %q, ожидалась %q", updated.Anomaly, tt.wantAnomaly)
+			}
+		})
+	}
+}
+
+func TestPaymentEvent(t *testing.T) {
+	amount := models.NewMoney(10000, "USD")
+	pending := models.Payment{ID: 1, OrderID: 1, Status: models.PaymentStatusPending, Amount: amount, Currency: "USD"}
+	succeeded := models.PaymentEvent{ID: "evt", Type: models.PaymentEventSucceeded, Reference: "pi_1"}
+	paidOrder := paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPaid}
+	newOrder := paymentOrderState{models.OrderStatusNew, models.OrderPaymentPending}
+
+	duplicatePayment := pending
+	duplicatePayment.Status = models.PaymentStatusSucceeded
+	duplicatePayment.Anomaly = models.PaymentAnomalyDuplicate
+
+	tests := []struct {
+		name        string
+		order       paymentOrderState
+		payment     models.Payment
+		event       models.PaymentEvent
+		duplicate   bool
+		wantOrder   paymentOrderState
+		wantApplied bool
+		wantStatus  string
+		wantAnomaly string
+	}{
+		{
+			name:        "новое событие применяется",
+			order:       newOrder,
+			payment:     pending,
+			event:       succeeded,
+			wantOrder:   paymentOrderState{models.OrderStatusProcessing, models.OrderPaymentPaid},
+			wantApplied: true,
+			wantStatus:  models.PaymentStatusSucceeded,
+		},
+		{
+			name:       "повторная доставка события ничего не меняет",
+			order:      newOrder,
+			payment:    pending,
+			event:      succeeded,
+			duplicate:  true,
+			wantOrder:  newOrder,
+			wantStatus: models.PaymentStatusPending,
+		},
+		{
+			name:        "аномалия возвращается при первом обнаружении",
+			order:       paidOrder,
+			payment:     pending,
+			event:       succeeded,
+			wantOrder:   paidOrder,
+			wantApplied: true,
+			wantStatus:  models.PaymentStatusSucceeded,
+			wantAnomaly: models.PaymentAnomalyDuplicate,
+		},
+		{
+			name:       "повторная доставка аномального события не дает второго уведомления",
+			order:      paidOrder,
+			payment:    pending,
+			event:      succeeded,
+			duplicate:  true,
+			wantOrder:  paidOrder,
+			wantStatus: models.PaymentStatusPending,
+		},
+		{
+			name:        "новое событие по платежу с аномалией не дает второго уведомления",
+			order:       paidOrder,
+			payment:     duplicatePayment,
+			event:       models.PaymentEvent{ID: "evt_2", Type: models.PaymentEventSucceeded, Reference: "pi_1"},
+			wantOrder:   paidOrder,
+			wantApplied: true,
+			wantStatus:  models.PaymentStatusSucceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, result := paymentEvent(tt.order, tt.payment, tt.event, tt.duplicate)
+			if order != tt.wantOrder {
+				t.Errorf("заказ %+v, ожидалось %+v", order, tt.wantOrder)
+			}
+			if result.Applied != tt.wantApplied {
+				t.Errorf("применено %v, ожидалось %v", result.Applied, tt.wantApplied)
+			}
+			if result.Payment.Status != tt.wantStatus {
+				t.Errorf("статус платежа %s, ожидался %s", result.Payment.Status, tt.wantStatus)
+			}
+			if result.Anomaly != tt.wantAnomaly {
+				t.Errorf("аномалия %q, ожидалась %q", result.Anomaly, tt.wantAnomaly)
+			}
+		})
+	}
+}
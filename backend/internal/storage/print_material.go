@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"pryanik_studio/internal/models"
+)
+
+// ErrPrintMaterialNotFound возвращается, если материал печати не найден
+var ErrPrintMaterialNotFound = errors.New("материал не найден")
+
+// printMaterialColumns колонки, которые выбираются для материала печати
+const printMaterialColumns = `
+	code, name, density, print_speed, price_per_gram, machine_hour_rate, minimum_charge,
+	currency, enabled, created_at, updated_at
+`
+
+// GetPrintMaterials возвращает материалы печати, отсортированные по названию
+func (r *PostgresRepository) GetPrintMaterials(ctx context.Context, onlyEnabled bool) ([]models.PrintMaterial, error) {
+	query := `SELECT ` + printMaterialColumns + ` FROM print_materials
+	WHERE enabled OR NOT $1
+	ORDER BY name, code`
+
+	var materials []models.PrintMaterial
+	if err := r.db.SelectContext(ctx, &materials, query, onlyEnabled); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении материалов печати")
+		return nil, fmt.Errorf("ошибка при получении материалов печати: %w", err)
+	}
+
+	for i := range materials {
+		setPrintMaterialCurrency(&materials[i])
+	}
+
+	return materials, nil
+}
+
+// GetPrintMaterial возвращает материал печати по коду
+func (r *PostgresRepository) GetPrintMaterial(ctx context.Context, code string) (models.PrintMaterial, error) {
+	var material models.PrintMaterial
+
+	query := `SELECT ` + printMaterialColumns + ` FROM print_materials WHERE code = $1`
+	if err := r.db.GetContext(ctx, &material, query, code); err != nil {
+		if err == sql.ErrNoRows {
+			return material, ErrPrintMaterialNotFound
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении материала печати %s", code)
+		return material, fmt.Errorf("ошибка при получении материала печати: %w", err)
+	}
+
+	setPrintMaterialCurrency(&material)
+	return material, nil
+}
+
+// SavePrintMaterial создает материал печати или обновляет существующий с тем же кодом
+func (r *PostgresRepository) SavePrintMaterial(ctx context.Context, material *models.PrintMaterial) error {
+	query := `
+	INSERT INTO print_materials (code, name, density, print_speed, price_per_gram, machine_hour_rate,
+	                             minimum_charge, currency, enabled, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+	ON CONFLICT (code) DO UPDATE
+	SET name = EXCLUDED.name,
+	    density = EXCLUDED.density,
+	    print_speed = EXCLUDED.print_speed,
+	    price_per_gram = EXCLUDED.price_per_gram,
+	    machine_hour_rate = EXCLUDED.machine_hour_rate,
+	    minimum_charge = EXCLUDED.minimum_charge,
+	    currency = EXCLUDED.currency,
+	    enabled = EXCLUDED.enabled,
+	    updated_at = NOW()
+	RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		material.Code,
+		material.Name,
+		material.Density,
+		material.PrintSpeed,
+		material.PricePerGram,
+		material.MachineHourRate,
+		material.MinimumCharge,
+		material.Currency,
+		material.Enabled,
+	).Scan(&material.CreatedAt, &material.UpdatedAt)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при сохранении материала печати %s", material.Code)
+		return fmt.Errorf("ошибка при сохранении материала печати: %w", err)
+	}
+
+	return nil
+}
+
+// setPrintMaterialCurrency проставляет валюту материала в его ценах
+func setPrintMaterialCurrency(material *models.PrintMaterial) {
+	material.PricePerGram.Currency = material.Currency
+	material.MachineHourRate.Currency = material.Currency
+	material.MinimumCharge.Currency = material.Currency
+}
@@ -10,6 +10,7 @@ import (
 	"pryanik_studio/internal/models"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // GetProducts возвращает список товаров с пагинацией и фильтрацией
@@ -18,65 +19,116 @@ func (r *PostgresRepository) GetProducts(ctx context.Context, filter models.Prod
 	result.Page = filter.Page
 	result.PageSize = filter.PageSize
 
-	// Базовый запрос для подсчета общего количества товаров
-	countQuery := `
-    SELECT COUNT(*) 
+	// Перевод выбираем по цепочке отката: первый доступный язык из цепочки
+	from := `
     FROM products p
-    JOIN product_translations pt ON p.id = pt.product_id
-    WHERE pt.language = $1
+    JOIN LATERAL (
+        SELECT t.language, t.name, t.description, t.price, t.currency
+        FROM product_translations t
+        WHERE t.product_id = p.id AND t.language = ANY($1::text[])
+        ORDER BY array_position($1::text[], t.language::text)
+        LIMIT 1
+    ) pt ON true
     `
 
+	// Базовый запрос для подсчета общего количества товаров; снятые с публикации товары не показываются
+	countQuery := "SELECT COUNT(*)" + from + " WHERE p.published"
+
 	// Базовый запрос для выборки товаров
+	// Соединения и условие WHERE добавляются ниже, чтобы при необходимости подключить популярность
 	query := `
     SELECT p.id, p.category_id, p.subcategory_id, p.created_at, p.updated_at,
-           pt.name, pt.description, pt.price, pt.currency
-    FROM products p
-    JOIN product_translations pt ON p.id = pt.product_id
-    WHERE pt.language = $1
-    `
+           p.base_price, p.base_currency, p.min_order_quantity, p.rating_avg, p.rating_count, ` + stockColumns + `,
+           pt.language, pt.name, pt.description, pt.price, pt.currency` + from + stockJoin
+	where := " WHERE p.published"
 
 	// Добавляем условия фильтрации
-	args := []interface{}{filter.Language}
+	chain := r.languageChain(filter.Language)
+	args := []interface{}{pq.Array(chain)}
 	argCount := 1
 
 	if filter.CategoryID != nil {
 		argCount++
-		query += fmt.Sprintf(" AND p.category_id = $%d", argCount)
+		where += fmt.Sprintf(" AND p.category_id = $%d", argCount)
 		countQuery += fmt.Sprintf(" AND p.category_id = $%d", argCount)
 		args = append(args, *filter.CategoryID)
 	}
 
 	if filter.SubcategoryID != nil {
 		argCount++
-		query += fmt.Sprintf(" AND p.subcategory_id = $%d", argCount)
+		where += fmt.Sprintf(" AND p.subcategory_id = $%d", argCount)
 		countQuery += fmt.Sprintf(" AND p.subcategory_id = $%d", argCount)
 		args = append(args, *filter.SubcategoryID)
 	}
 
-	if filter.Search != nil && *filter.Search != "" {
+	hasSearch := filter.Search != nil && *filter.Search != ""
+	searchArg := 0
+	if hasSearch {
 		argCount++
+		searchArg = argCount
 		searchTerm := "%" + *filter.Search + "%"
-		query += fmt.Sprintf(" AND (pt.name ILIKE $%d OR pt.description ILIKE $%d)", argCount, argCount)
+		where += fmt.Sprintf(" AND (pt.name ILIKE $%d OR pt.description ILIKE $%d)", argCount, argCount)
 		countQuery += fmt.Sprintf(" AND (pt.name ILIKE $%d OR pt.description ILIKE $%d)", argCount, argCount)
 		args = append(args, searchTerm)
 	}
 
-	// Добавляем сортировку по цене, если указана
-	// Изменяем для сортировки по цене из product_translations, а не из products
-	if filter.SortByPrice != nil {
-		sortDirection := "ASC"
-		if *filter.SortByPrice == "desc" {
-			sortDirection = "DESC"
+	// Получаем общее количество товаров до добавления параметров сортировки
+	countArgs := append([]interface{}(nil), args...)
+
+	// Популярность считаем по количеству проданных единиц за скользящее окно
+	if filter.Sort == models.ProductSortPopularity {
+		argCount++
+		query += fmt.Sprintf(`
+    LEFT JOIN (
+        SELECT oi.product_id, SUM(oi.quantity) AS sold
+        FROM order_items oi
+        JOIN orders o ON o.id = oi.order_id
+        WHERE o.created_at >= $%d AND o.status <> 'cancelled'
+        GROUP BY oi.product_id
+    ) pop ON pop.product_id = p.id
+    `, argCount)
+		args = append(args, time.Now().Add(-filter.PopularityWindow))
+	}
+
+	query += where
+
+	// Добавляем сортировку; последним ключом всегда идет p.id, чтобы порядок был детерминированным
+	switch filter.Sort {
+	case models.ProductSortNewest:
+		query += " ORDER BY p.created_at DESC, p.id DESC"
+	case models.ProductSortOldest:
+		query += " ORDER BY p.created_at ASC, p.id ASC"
+	case models.ProductSortName:
+		query += " ORDER BY pt.name ASC, p.id ASC"
+	case models.ProductSortPrice:
+		query += " ORDER BY pt.price ASC, p.id ASC"
+	case models.ProductSortPriceDesc:
+		query += " ORDER BY pt.price DESC, p.id DESC"
+	case models.ProductSortPopularity:
+		query += " ORDER BY COALESCE(pop.sold, 0) DESC, p.id DESC"
+	case models.ProductSortRelevance:
+		if hasSearch {
+			// Точное совпадение названия, затем начало названия, затем вхождение в название, затем описание
+			argCount += 2
+			query += fmt.Sprintf(`
+    ORDER BY CASE
+        WHEN pt.name ILIKE $%d THEN 0
+        WHEN pt.name ILIKE $%d THEN 1
+        WHEN pt.name ILIKE $%d THEN 2
+        ELSE 3
+    END, p.id DESC`, argCount-1, argCount, searchArg)
+			args = append(args, *filter.Search, *filter.Search+"%")
+		} else {
+			query += " ORDER BY p.id DESC"
 		}
-		query += fmt.Sprintf(" ORDER BY pt.price %s", sortDirection) // Сортируем по pt.price вместо p.price
-	} else {
+	default:
 		// По умолчанию сортируем по ID
 		query += " ORDER BY p.id DESC"
 	}
 
 	// Получаем общее количество товаров
 	var totalItems int
-	err := r.db.GetContext(ctx, &totalItems, countQuery, args...)
+	err := r.db.GetContext(ctx, &totalItems, countQuery, countArgs...)
 	if err != nil {
 		r.logger.WithError(err).Error("Ошибка при получении общего количества товаров")
 		return result, fmt.Errorf("ошибка при получении общего количества товаров: %w", err)
@@ -92,15 +144,24 @@ func (r *PostgresRepository) GetProducts(ctx context.Context, filter models.Prod
 
 	// Запрос товаров
 	var products []struct {
-		ID            int64         `db:"id"`
-		CategoryID    int64         `db:"category_id"`
-		SubcategoryID sql.NullInt64 `db:"subcategory_id"`
-		CreatedAt     sql.NullTime  `db:"created_at"`
-		UpdatedAt     sql.NullTime  `db:"updated_at"`
-		Name          string        `db:"name"`
-		Description   string        `db:"description"`
-		Price         float64       `db:"price"`
-		Currency      string        `db:"currency"`
+		ID            int64          `db:"id"`
+		CategoryID    int64          `db:"category_id"`
+		SubcategoryID sql.NullInt64  `db:"subcategory_id"`
+		CreatedAt     sql.NullTime   `db:"created_at"`
+		UpdatedAt     sql.NullTime   `db:"updated_at"`
+		Language      string         `db:"language"`
+		Name          string         `db:"name"`
+		Description   string         `db:"description"`
+		Price         models.Money   `db:"price"`
+		Currency      string         `db:"currency"`
+		BasePrice     *models.Money  `db:"base_price"`
+		BaseCurrency  sql.NullString `db:"base_currency"`
+
+		InStock           bool          `db:"in_stock"`
+		AvailableQuantity sql.NullInt64 `db:"available_quantity"`
+		MinOrderQuantity  int           `db:"min_order_quantity"`
+		RatingAvg         float64       `db:"rating_avg"`
+		RatingCount       int           `db:"rating_count"`
 	}
 
 	err = r.db.SelectContext(ctx, &products, query, args...)
@@ -119,8 +180,16 @@ func (r *PostgresRepository) GetProducts(ctx context.Context, filter models.Prod
 			UpdatedAt:   p.UpdatedAt.Time,
 			Name:        p.Name,
 			Description: p.Description,
-			Price:       p.Price,
+			Price:       models.NewMoney(p.Price.Amount, p.Currency),
 			Currency:    p.Currency,
+			BasePrice:   basePrice(p.BasePrice, p.BaseCurrency),
+
+			InStock:           p.InStock,
+			AvailableQuantity: nullableInt(p.AvailableQuantity),
+			MinOrderQuantity:  p.MinOrderQuantity,
+			RatingAvg:         p.RatingAvg,
+			RatingCount:       p.RatingCount,
+			Published:         true,
 		}
 
 		if p.SubcategoryID.Valid {
@@ -145,29 +214,16 @@ func (r *PostgresRepository) GetProducts(ctx context.Context, filter models.Prod
 			product.Images = []string{"/default-product-image.jpg"}
 		}
 
-		// Получаем характеристики товара
-		var characteristics []struct {
-			Key   string `db:"key"`
-			Value string `db:"value"`
-		}
+		product.FallbackFields = markFallback(nil, filter.Language, p.Language, "name", "description", "price", "currency")
 
-		query = `
-        SELECT key, value 
-        FROM product_characteristics 
-        WHERE product_id = $1 AND language = $2
-        `
-
-		err = r.db.SelectContext(ctx, &characteristics, query, p.ID, filter.Language)
+		// Получаем характеристики товара
+		characteristics, charLanguage, err := r.getCharacteristics(ctx, p.ID, chain)
 		if err != nil {
 			r.logger.WithError(err).Errorf("Ошибка при получении характеристик товара ID=%d", p.ID)
 		}
-
-		// Если есть характеристики, добавляем их
 		if len(characteristics) > 0 {
-			product.Characteristics = make(map[string]string)
-			for _, c := range characteristics {
-				product.Characteristics[c.Key] = c.Value
-			}
+			product.Characteristics = characteristics
+			product.FallbackFields = markFallback(product.FallbackFields, filter.Language, charLanguage, "characteristics")
 		}
 
 		result.Items = append(result.Items, product)
@@ -180,31 +236,53 @@ func (r *PostgresRepository) GetProducts(ctx context.Context, filter models.Prod
 func (r *PostgresRepository) GetProductByID(ctx context.Context, id int64, language string) (models.ProductDetail, error) {
 	var result models.ProductDetail
 
-	// Получаем основную информацию о товаре
+	// Получаем основную информацию о товаре, перевод выбираем по цепочке отката
 	query := `
     SELECT p.id, p.category_id, p.subcategory_id, p.created_at, p.updated_at,
-           pt.name, pt.description, pt.price, pt.currency
+           p.base_price, p.base_currency, p.personalization_schema, p.published, p.min_order_quantity,
+           COALESCE(p.fabrication_kind, '') AS fabrication_kind, p.rating_avg, p.rating_count, ` + stockColumns + `,
+           pt.language, pt.name, pt.description, pt.price, pt.currency
     FROM products p
-    JOIN product_translations pt ON p.id = pt.product_id
-    WHERE p.id = $1 AND pt.language = $2
+    JOIN LATERAL (
+        SELECT t.language, t.name, t.description, t.price, t.currency
+        FROM product_translations t
+        WHERE t.product_id = p.id AND t.language = ANY($2::text[])
+        ORDER BY array_position($2::text[], t.language::text)
+        LIMIT 1
+    ) pt ON true` + stockJoin + `
+    WHERE p.id = $1
 	`
 
 	var product struct {
-		ID            int64         `db:"id"`
-		CategoryID    int64         `db:"category_id"`
-		SubcategoryID sql.NullInt64 `db:"subcategory_id"`
-		CreatedAt     sql.NullTime  `db:"created_at"`
-		UpdatedAt     sql.NullTime  `db:"updated_at"`
-		Name          string        `db:"name"`
-		Description   string        `db:"description"`
-		Price         float64       `db:"price"`
-		Currency      string        `db:"currency"`
+		ID            int64          `db:"id"`
+		CategoryID    int64          `db:"category_id"`
+		SubcategoryID sql.NullInt64  `db:"subcategory_id"`
+		CreatedAt     sql.NullTime   `db:"created_at"`
+		UpdatedAt     sql.NullTime   `db:"updated_at"`
+		Language      string         `db:"language"`
+		Name          string         `db:"name"`
+		Description   string         `db:"description"`
+		Price         models.Money   `db:"price"`
+		Currency      string         `db:"currency"`
+		BasePrice     *models.Money  `db:"base_price"`
+		BaseCurrency  sql.NullString `db:"base_currency"`
+
+		InStock           bool          `db:"in_stock"`
+		AvailableQuantity sql.NullInt64 `db:"available_quantity"`
+		MinOrderQuantity  int           `db:"min_order_quantity"`
+		RatingAvg         float64       `db:"rating_avg"`
+		RatingCount       int           `db:"rating_count"`
+		Published         bool          `db:"published"`
+		FabricationKind   string        `db:"fabrication_kind"`
+
+		Personalization models.PersonalizationSchema `db:"personalization_schema"`
 	}
 
-	err := r.db.GetContext(ctx, &product, query, id, language)
+	chain := r.languageChain(language)
+	err := r.db.GetContext(ctx, &product, query, id, pq.Array(chain))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return result, fmt.Errorf("товар с ID=%d не найден", id)
+			return result, fmt.Errorf("товар с ID=%d: %w", id, ErrProductNotFound)
 		}
 		r.logger.WithError(err).Errorf("Ошибка при получении товара ID=%d", id)
 		return result, fmt.Errorf("ошибка при получении товара: %w", err)
@@ -221,32 +299,27 @@ func (r *PostgresRepository) GetProductByID(ctx context.Context, id int64, langu
 	result.UpdatedAt = product.UpdatedAt.Time
 	result.Name = product.Name
 	result.Description = product.Description
-	result.Price = product.Price
+	result.Price = models.NewMoney(product.Price.Amount, product.Currency)
 	result.Currency = product.Currency
+	result.BasePrice = basePrice(product.BasePrice, product.BaseCurrency)
+	result.Personalization = product.Personalization
+	result.InStock = product.InStock
+	result.AvailableQuantity = nullableInt(product.AvailableQuantity)
+	result.MinOrderQuantity = product.MinOrderQuantity
+	result.RatingAvg = product.RatingAvg
+	result.RatingCount = product.RatingCount
+	result.Published = product.Published
+	result.FabricationKind = product.FabricationKind
+	result.FallbackFields = markFallback(nil, language, product.Language, "name", "description", "price", "currency")
 
 	// Получаем характеристики товара
-	var characteristics []struct {
-		Key   string `db:"key"`
-		Value string `db:"value"`
-	}
-
-	query = `
-    SELECT key, value 
-    FROM product_characteristics 
-    WHERE product_id = $1 AND language = $2
-    `
-
-	err = r.db.SelectContext(ctx, &characteristics, query, id, language)
+	characteristics, charLanguage, err := r.getCharacteristics(ctx, id, chain)
 	if err != nil {
 		r.logger.WithError(err).Errorf("Ошибка при получении характеристик товара ID=%d", id)
 	}
-
-	// Если есть характеристики, добавляем их
 	if len(characteristics) > 0 {
-		result.Characteristics = make(map[string]string)
-		for _, c := range characteristics {
-			result.Characteristics[c.Key] = c.Value
-		}
+		result.Characteristics = characteristics
+		result.FallbackFields = markFallback(result.FallbackFields, language, charLanguage, "characteristics")
 	}
 
 	// Получаем изображения товара
@@ -276,9 +349,54 @@ func (r *PostgresRepository) GetProductByID(ctx context.Context, id int64, langu
 		result.RelatedProducts = relatedProducts
 	}
 
+	// Получаем опции и включенные варианты товара
+	options, variants, err := r.GetProductVariants(ctx, id, language, true)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении вариантов товара ID=%d", id)
+		return result, fmt.Errorf("ошибка при получении вариантов товара: %w", err)
+	}
+	result.Options = options
+	result.Variants = variants
+
 	return result, nil
 }
 
+// getCharacteristics возвращает характеристики товара на первом языке цепочки, для которого они заданы,
+// а также этот язык
+func (r *PostgresRepository) getCharacteristics(ctx context.Context, productID int64, chain []string) (map[string]string, string, error) {
+	var characteristics []struct {
+		Language string `db:"language"`
+		Key      string `db:"key"`
+		Value    string `db:"value"`
+	}
+
+	query := `
+    SELECT language, key, value
+    FROM product_characteristics
+    WHERE product_id = $1 AND language = (
+        SELECT c.language FROM product_characteristics c
+        WHERE c.product_id = $1 AND c.language = ANY($2::text[])
+        ORDER BY array_position($2::text[], c.language::text)
+        LIMIT 1
+    )
+    `
+
+	if err := r.db.SelectContext(ctx, &characteristics, query, productID, pq.Array(chain)); err != nil {
+		return nil, "", err
+	}
+
+	if len(characteristics) == 0 {
+		return nil, "", nil
+	}
+
+	result := make(map[string]string, len(characteristics))
+	for _, c := range characteristics {
+		result[c.Key] = c.Value
+	}
+
+	return result, characteristics[0].Language, nil
+}
+
 // GetRelatedProducts возвращает список связанных товаров
 func (r *PostgresRepository) GetRelatedProducts(ctx context.Context, productID int64, limit int, language string) ([]models.Product, error) {
 	var result []models.Product
@@ -294,27 +412,43 @@ func (r *PostgresRepository) GetRelatedProducts(ctx context.Context, productID i
 	// Получаем товары из той же категории, кроме текущего
 	query = `
     SELECT p.id, p.category_id, p.subcategory_id, p.created_at, p.updated_at,
-           pt.name, pt.description, pt.price, pt.currency
+           p.base_price, p.base_currency, p.min_order_quantity, p.rating_avg, p.rating_count, ` + stockColumns + `,
+           pt.language, pt.name, pt.description, pt.price, pt.currency
     FROM products p
-    JOIN product_translations pt ON p.id = pt.product_id
-    WHERE p.category_id = $1 AND p.id != $2 AND pt.language = $3
+    JOIN LATERAL (
+        SELECT t.language, t.name, t.description, t.price, t.currency
+        FROM product_translations t
+        WHERE t.product_id = p.id AND t.language = ANY($3::text[])
+        ORDER BY array_position($3::text[], t.language::text)
+        LIMIT 1
+    ) pt ON true` + stockJoin + `
+    WHERE p.category_id = $1 AND p.id != $2 AND p.published
     ORDER BY RANDOM()
     LIMIT $4
     `
 
 	var products []struct {
-		ID            int64         `db:"id"`
-		CategoryID    int64         `db:"category_id"`
-		SubcategoryID sql.NullInt64 `db:"subcategory_id"`
-		CreatedAt     sql.NullTime  `db:"created_at"`
-		UpdatedAt     sql.NullTime  `db:"updated_at"`
-		Name          string        `db:"name"`
-		Description   string        `db:"description"`
-		Price         float64       `db:"price"`
-		Currency      string        `db:"currency"`
+		ID            int64          `db:"id"`
+		CategoryID    int64          `db:"category_id"`
+		SubcategoryID sql.NullInt64  `db:"subcategory_id"`
+		CreatedAt     sql.NullTime   `db:"created_at"`
+		UpdatedAt     sql.NullTime   `db:"updated_at"`
+		Language      string         `db:"language"`
+		Name          string         `db:"name"`
+		Description   string         `db:"description"`
+		Price         models.Money   `db:"price"`
+		Currency      string         `db:"currency"`
+		BasePrice     *models.Money  `db:"base_price"`
+		BaseCurrency  sql.NullString `db:"base_currency"`
+
+		InStock           bool          `db:"in_stock"`
+		AvailableQuantity sql.NullInt64 `db:"available_quantity"`
+		MinOrderQuantity  int           `db:"min_order_quantity"`
+		RatingAvg         float64       `db:"rating_avg"`
+		RatingCount       int           `db:"rating_count"`
 	}
 
-	err = r.db.SelectContext(ctx, &products, query, categoryID, productID, language, limit)
+	err = r.db.SelectContext(ctx, &products, query, categoryID, productID, pq.Array(r.languageChain(language)), limit)
 	if err != nil {
 		return result, fmt.Errorf("ошибка при получении связанных товаров: %w", err)
 	}
@@ -328,13 +462,22 @@ func (r *PostgresRepository) GetRelatedProducts(ctx context.Context, productID i
 			UpdatedAt:   p.UpdatedAt.Time,
 			Name:        p.Name,
 			Description: p.Description,
-			Price:       p.Price,
+			Price:       models.NewMoney(p.Price.Amount, p.Currency),
 			Currency:    p.Currency,
+			BasePrice:   basePrice(p.BasePrice, p.BaseCurrency),
+
+			InStock:           p.InStock,
+			AvailableQuantity: nullableInt(p.AvailableQuantity),
+			MinOrderQuantity:  p.MinOrderQuantity,
+			RatingAvg:         p.RatingAvg,
+			RatingCount:       p.RatingCount,
+			Published:         true,
 		}
 
 		if p.SubcategoryID.Valid {
 			product.SubcategoryID = &p.SubcategoryID.Int64
 		}
+		product.FallbackFields = markFallback(nil, language, p.Language, "name", "description", "price", "currency")
 
 		// Получаем основное изображение товара
 		var image string
@@ -378,11 +521,17 @@ func (r *PostgresRepository) GetCategories(ctx context.Context, language string)
 
 	// Получаем все категории верхнего уровня
 	query := `
-    SELECT c.id, c.parent_id, c.created_at, c.updated_at, ct.name
+    SELECT c.id, c.parent_id, c.created_at, c.updated_at, ct.language, ct.name
     FROM categories c
-    JOIN category_translations ct ON c.id = ct.category_id
-    WHERE c.parent_id IS NULL AND ct.language = $1
-    ORDER BY ct.name
+    JOIN LATERAL (
+        SELECT t.language, t.name
+        FROM category_translations t
+        WHERE t.category_id = c.id AND t.language = ANY($1::text[])
+        ORDER BY array_position($1::text[], t.language::text)
+        LIMIT 1
+    ) ct ON true
+    WHERE c.parent_id IS NULL
+    ORDER BY ct.name, c.id
     `
 
 	var categories []struct {
@@ -390,10 +539,11 @@ func (r *PostgresRepository) GetCategories(ctx context.Context, language string)
 		ParentID  sql.NullInt64 `db:"parent_id"`
 		CreatedAt sql.NullTime  `db:"created_at"`
 		UpdatedAt sql.NullTime  `db:"updated_at"`
+		Language  string        `db:"language"`
 		Name      string        `db:"name"`
 	}
 
-	err := r.db.SelectContext(ctx, &categories, query, language)
+	err := r.db.SelectContext(ctx, &categories, query, pq.Array(r.languageChain(language)))
 	if err != nil {
 		r.logger.WithError(err).Error("Ошибка при получении списка категорий")
 		return result, fmt.Errorf("ошибка при получении списка категорий: %w", err)
@@ -406,7 +556,8 @@ func (r *PostgresRepository) GetCategories(ctx context.Context, language string)
 			CreatedAt: c.CreatedAt.Time,
 			UpdatedAt: c.UpdatedAt.Time,
 			// Прямое присвоение имени
-			Name: c.Name,
+			Name:           c.Name,
+			FallbackFields: markFallback(nil, language, c.Language, "name"),
 		}
 
 		// Получаем подкатегории для текущей категории
@@ -429,11 +580,17 @@ func (r *PostgresRepository) getSubcategories(ctx context.Context, parentID int6
 
 	// Получаем все подкатегории для указанной категории
 	query := `
-    SELECT c.id, c.parent_id, c.created_at, c.updated_at, ct.name
+    SELECT c.id, c.parent_id, c.created_at, c.updated_at, ct.language, ct.name
     FROM categories c
-    JOIN category_translations ct ON c.id = ct.category_id
-    WHERE c.parent_id = $1 AND ct.language = $2
-    ORDER BY ct.name
+    JOIN LATERAL (
+        SELECT t.language, t.name
+        FROM category_translations t
+        WHERE t.category_id = c.id AND t.language = ANY($2::text[])
+        ORDER BY array_position($2::text[], t.language::text)
+        LIMIT 1
+    ) ct ON true
+    WHERE c.parent_id = $1
+    ORDER BY ct.name, c.id
     `
 
 	var subcategories []struct {
@@ -441,10 +598,11 @@ func (r *PostgresRepository) getSubcategories(ctx context.Context, parentID int6
 		ParentID  int64        `db:"parent_id"`
 		CreatedAt sql.NullTime `db:"created_at"`
 		UpdatedAt sql.NullTime `db:"updated_at"`
+		Language  string       `db:"language"`
 		Name      string       `db:"name"`
 	}
 
-	err := r.db.SelectContext(ctx, &subcategories, query, parentID, language)
+	err := r.db.SelectContext(ctx, &subcategories, query, parentID, pq.Array(r.languageChain(language)))
 	if err != nil {
 		return result, fmt.Errorf("ошибка при получении подкатегорий: %w", err)
 	}
@@ -457,7 +615,8 @@ func (r *PostgresRepository) getSubcategories(ctx context.Context, parentID int6
 			CreatedAt: c.CreatedAt.Time,
 			UpdatedAt: c.UpdatedAt.Time,
 			// Прямое присвоение имени
-			Name: c.Name,
+			Name:           c.Name,
+			FallbackFields: markFallback(nil, language, c.Language, "name"),
 		}
 
 		result = append(result, category)
@@ -495,17 +654,22 @@ func (r *PostgresRepository) CreateProduct(ctx context.Context, product *models.
 
 	// Вставляем товар (без поля price)
 	query := `
-    INSERT INTO products (category_id, subcategory_id, created_at, updated_at)
-    VALUES ($1, $2, $3, $4)
+    INSERT INTO products (category_id, subcategory_id, base_price, base_currency, personalization_schema, created_at, updated_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7)
     RETURNING id
     `
 
+	basePrice, baseCurrency := basePriceColumns(product.BasePrice)
+
 	var productID int64
 	err = tx.QueryRowContext(
 		ctx,
 		query,
 		product.CategoryID,
 		product.SubcategoryID,
+		basePrice,
+		baseCurrency,
+		product.Personalization,
 		product.CreatedAt,
 		product.UpdatedAt,
 	).Scan(&productID)
@@ -662,6 +826,27 @@ func (r *PostgresRepository) UpdateProduct(ctx context.Context, product *models.
 		return fmt.Errorf("ошибка при обновлении основной информации товара: %w", err)
 	}
 
+	// Обновляем базовую цену, если она предоставлена; сумма без валюты отключает пересчет
+	if product.BasePrice != nil {
+		basePrice, baseCurrency := basePriceColumns(product.BasePrice)
+		query = `UPDATE products SET base_price = $1, base_currency = $2 WHERE id = $3`
+		_, err = tx.ExecContext(ctx, query, basePrice, baseCurrency, product.ID)
+		if err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при обновлении базовой цены товара ID=%d", product.ID)
+			return fmt.Errorf("ошибка при обновлении базовой цены товара: %w", err)
+		}
+	}
+
+	// Обновляем схему персонализации, если она предоставлена; пустой список удаляет персонализацию
+	if product.Personalization != nil {
+		query = `UPDATE products SET personalization_schema = $1 WHERE id = $2`
+		_, err = tx.ExecContext(ctx, query, product.Personalization, product.ID)
+		if err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при обновлении персонализации товара ID=%d", product.ID)
+			return fmt.Errorf("ошибка при обновлении персонализации товара: %w", err)
+		}
+	}
+
 	// Обновляем переводы товара, если они предоставлены
 	if product.Translations != nil && len(product.Translations) > 0 {
 		for lang, translation := range product.Translations {
@@ -678,10 +863,10 @@ func (r *PostgresRepository) UpdateProduct(ctx context.Context, product *models.
 			if translationExists {
 				// Сначала получаем текущие значения перевода
 				var currentTranslation struct {
-					Name        string  `db:"name"`
-					Description string  `db:"description"`
-					Price       float64 `db:"price"`
-					Currency    string  `db:"currency"`
+					Name        string       `db:"name"`
+					Description string       `db:"description"`
+					Price       models.Money `db:"price"`
+					Currency    string       `db:"currency"`
 				}
 
 				query = `
@@ -708,7 +893,7 @@ func (r *PostgresRepository) UpdateProduct(ctx context.Context, product *models.
 				}
 
 				price := currentTranslation.Price
-				if translation.Price > 0 { // Используем > 0 вместо != 0, чтобы избежать отрицательных цен
+				if translation.Price.Amount > 0 { // Используем > 0 вместо != 0, чтобы избежать отрицательных цен
 					price = translation.Price
 				}
 
@@ -844,3 +1029,42 @@ func (r *PostgresRepository) UpdateProduct(ctx context.Context, product *models.
 
 	return nil
 }
+
+// basePrice собирает базовую цену товара из колонок base_price и base_currency.
+// Возвращает nil, если товар не использует пересчет из базовой цены
+func basePrice(price *models.Money, currency sql.NullString) *models.Money {
+	if price == nil || !currency.Valid {
+		return nil
+	}
+	money := models.NewMoney(price.Amount, currency.String)
+	return &money
+}
+
+// basePriceColumns возвращает значения колонок base_price и base_currency.
+// Сумма без валюты (или nil) сохраняется как NULL
+func basePriceColumns(price *models.Money) (interface{}, interface{}) {
+	if price == nil || price.Currency == "" {
+		return nil, nil
+	}
+	return *price, price.Currency
+}
+
+// SetProductOrderSettings задает публикацию товара, минимальное количество для заказа
+// и вид изготовления по расчету стоимости
+func (r *PostgresRepository) SetProductOrderSettings(ctx context.Context, productID int64, settings models.ProductOrderSettings) error {
+	query := `
+	UPDATE products SET published = $1, min_order_quantity = $2, fabrication_kind = NULLIF($3, ''), updated_at = NOW()
+	WHERE id = $4
+	`
+	result, err := r.db.ExecContext(ctx, query, *settings.Published, settings.MinOrderQuantity, settings.FabricationKind, productID)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при изменении настроек заказа товара ID=%d", productID)
+		return fmt.Errorf("ошибка при изменении настроек заказа товара: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrProductNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,475 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"pryanik_studio/internal/models"
+)
+
+var (
+	// ErrMachineNotFound возвращается, если станок не найден
+	ErrMachineNotFound = errors.New("станок не найден")
+
+	// ErrJobNotFound возвращается, если производственное задание не найдено
+	ErrJobNotFound = errors.New("производственное задание не найдено")
+
+	// ErrJobStatus возвращается при недопустимом изменении статуса задания
+	ErrJobStatus = errors.New("недопустимое изменение статуса задания")
+)
+
+// productionJobColumns колонки, которые выбираются для производственного задания
+const productionJobColumns = `
+	j.id, j.order_id, j.order_item_id, j.kind, j.title, j.quantity, j.status, j.priority, j.due_date,
+	j.estimated_minutes, j.machine_id, j.machine_locked, j.scheduled_start, j.scheduled_end,
+	j.started_at, j.finished_at, j.created_at, j.updated_at
+`
+
+// machineRow строка станка; виды работ хранятся в массиве PostgreSQL
+type machineRow struct {
+	models.Machine
+	Capabilities pq.StringArray `db:"capabilities"`
+}
+
+// GetMachines возвращает станки мастерской, отсортированные по названию
+func (r *PostgresRepository) GetMachines(ctx context.Context) ([]models.Machine, error) {
+	query := `
+	SELECT id, code, name, capabilities, enabled, created_at, updated_at
+	FROM machines
+	ORDER BY name, code
+	`
+
+	var rows []machineRow
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении станков")
+		return nil, fmt.Errorf("ошибка при получении станков: %w", err)
+	}
+
+	machines := make([]models.Machine, 0, len(rows))
+	for _, row := range rows {
+		machine := row.Machine
+		machine.Capabilities = []string(row.Capabilities)
+		machines = append(machines, machine)
+	}
+
+	return machines, nil
+}
+
+// SaveMachine создает станок или обновляет существующий с тем же кодом
+func (r *PostgresRepository) SaveMachine(ctx context.Context, machine *models.Machine) error {
+	query := `
+	INSERT INTO machines (code, name, capabilities, enabled, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, NOW(), NOW())
+	ON CONFLICT (code) DO UPDATE
+	SET name = EXCLUDED.name,
+	    capabilities = EXCLUDED.capabilities,
+	    enabled = EXCLUDED.enabled,
+	    updated_at = NOW()
+	RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		machine.Code,
+		machine.Name,
+		pq.Array(machine.Capabilities),
+		machine.Enabled,
+	).Scan(&machine.ID, &machine.CreatedAt, &machine.UpdatedAt)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при сохранении станка %s", machine.Code)
+		return fmt.Errorf("ошибка при сохранении станка: %w", err)
+	}
+
+	return nil
+}
+
+// GetProductionSettings возвращает параметры изготовления товаров; товары без вида работ
+// (продаются со склада) в результат не попадают
+func (r *PostgresRepository) GetProductionSettings(ctx context.Context, productIDs []int64) (map[int64]models.ProductionSettings, error) {
+	query := `
+	SELECT id, production_kind, production_minutes
+	FROM products
+	WHERE id = ANY($1) AND production_kind IS NOT NULL
+	`
+
+	var rows []struct {
+		ID int64 `db:"id"`
+		models.ProductionSettings
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, pq.Array(productIDs)); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении параметров изготовления товаров")
+		return nil, fmt.Errorf("ошибка при получении параметров изготовления товаров: %w", err)
+	}
+
+	settings := make(map[int64]models.ProductionSettings, len(rows))
+	for _, row := range rows {
+		settings[row.ID] = row.ProductionSettings
+	}
+
+	return settings, nil
+}
+
+// SetProductionSettings задает параметры изготовления товара; пустой вид работ их сбрасывает
+func (r *PostgresRepository) SetProductionSettings(ctx context.Context, productID int64, settings models.ProductionSettings) error {
+	query := `
+	UPDATE products SET production_kind = NULLIF($1, ''), production_minutes = $2, updated_at = NOW()
+	WHERE id = $3
+	`
+	result, err := r.db.ExecContext(ctx, query, settings.Kind, settings.Minutes, productID)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при изменении параметров изготовления товара ID=%d", productID)
+		return fmt.Errorf("ошибка при изменении параметров изготовления товара: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrProductNotFound
+	}
+
+	return nil
+}
+
+// CreateProductionJobs создает задания; задания по позициям, для которых уже есть
+// неотмененное задание, пропускаются. Возвращает созданные задания
+func (r *PostgresRepository) CreateProductionJobs(ctx context.Context, jobs []models.ProductionJob) ([]models.ProductionJob, error) {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для создания заданий")
+		return nil, fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	query := `
+	INSERT INTO production_jobs AS j (order_id, order_item_id, kind, title, quantity, status,
+	                                  priority, due_date, estimated_minutes, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+	ON CONFLICT (order_item_id) WHERE status <> 'cancelled' DO NOTHING
+	RETURNING ` + productionJobColumns
+
+	created := make([]models.ProductionJob, 0, len(jobs))
+	for _, job := range jobs {
+		var inserted models.ProductionJob
+		err = tx.GetContext(ctx, &inserted, query,
+			job.OrderID,
+			job.OrderItemID,
+			job.Kind,
+			job.Title,
+			job.Quantity,
+			models.JobStatusQueued,
+			job.Priority,
+			job.DueDate,
+			job.EstimatedMinutes,
+		)
+		if err == sql.ErrNoRows {
+			err = nil
+			continue
+		}
+		if err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при создании задания по позиции ID=%d", job.OrderItemID)
+			return nil, fmt.Errorf("ошибка при создании задания: %w", err)
+		}
+		created = append(created, inserted)
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return nil, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetProductionJobs возвращает производственные задания по фильтру, отсортированные по плану
+func (r *PostgresRepository) GetProductionJobs(ctx context.Context, filter models.ProductionJobFilter) ([]models.ProductionJob, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.OrderID != nil {
+		args = append(args, *filter.OrderID)
+		conditions = append(conditions, fmt.Sprintf("j.order_id = $%d", len(args)))
+	}
+
+	active := fmt.Sprintf("j.status IN ('%s', '%s', '%s')",
+		models.JobStatusQueued, models.JobStatusScheduled, models.JobStatusInProgress)
+	switch {
+	case filter.ActiveOnly:
+		conditions = append(conditions, active)
+	case filter.From != nil && filter.To != nil:
+		// Ожидающие и выполняемые задания показываются всегда, остальные - если попадают в период
+		args = append(args, *filter.From, *filter.To)
+		from, to := len(args)-1, len(args)
+		conditions = append(conditions, fmt.Sprintf(`(
+			j.status IN ('%s', '%s')
+			OR (j.status = '%s' AND j.scheduled_start < $%d AND j.scheduled_end > $%d)
+			OR (j.status = '%s' AND j.finished_at >= $%d AND j.started_at < $%d)
+		)`,
+			models.JobStatusQueued, models.JobStatusInProgress,
+			models.JobStatusScheduled, to, from,
+			models.JobStatusDone, from, to,
+		))
+	}
+
+	query := `SELECT ` + productionJobColumns + ` FROM production_jobs j`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY COALESCE(j.started_at, j.scheduled_start) NULLS LAST, j.priority DESC, j.id`
+
+	jobs := []models.ProductionJob{}
+	if err := r.db.SelectContext(ctx, &jobs, query, args...); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении производственных заданий")
+		return nil, fmt.Errorf("ошибка при получении производственных заданий: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// ApplySchedule сохраняет план производства. Меняются только задания, которые все еще
+// ожидают выполнения: задание, запущенное после составления плана, не затрагивается.
+// Возвращает количество обновленных заданий
+func (r *PostgresRepository) ApplySchedule(ctx context.Context, slots []models.JobSlot) (int, error) {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для сохранения плана")
+		return 0, fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	query := `
+	UPDATE production_jobs
+	SET machine_id = $2, scheduled_start = $3, scheduled_end = $4,
+	    status = CASE WHEN $2::integer IS NULL THEN $5 ELSE $6 END,
+	    updated_at = NOW()
+	WHERE id = $1 AND status IN ($5, $6)
+	`
+
+	updated := 0
+	for _, slot := range slots {
+		var result sql.Result
+		result, err = tx.ExecContext(ctx, query,
+			slot.JobID, slot.MachineID, slot.Start, slot.End,
+			models.JobStatusQueued, models.JobStatusScheduled,
+		)
+		if err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при планировании задания ID=%d", slot.JobID)
+			return 0, fmt.Errorf("ошибка при сохранении плана: %w", err)
+		}
+		if affected, affectedErr := result.RowsAffected(); affectedErr == nil {
+			updated += int(affected)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return 0, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return updated, nil
+}
+
+// UpdateProductionJob изменяет задание и обновляет статус заказа по его заданиям:
+// запуск первого задания переводит заказ в производство, выполнение всех заданий -
+// в статус «изготовлен». Возвращает измененное задание и статус заказа
+func (r *PostgresRepository) UpdateProductionJob(ctx context.Context, id int64, update models.JobUpdate) (models.ProductionJob, string, error) {
+	var job models.ProductionJob
+
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для изменения задания")
+		return job, "", fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	// Заказ блокируется раньше задания - в том же порядке, что и при изменении статуса заказа
+	var orderStatus string
+	query := `
+	SELECT o.status FROM orders o
+	WHERE o.id = (SELECT order_id FROM production_jobs WHERE id = $1)
+	FOR UPDATE
+	`
+	if err = tx.GetContext(ctx, &orderStatus, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrJobNotFound
+			return job, "", err
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении заказа задания ID=%d", id)
+		return job, "", fmt.Errorf("ошибка при получении заказа: %w", err)
+	}
+
+	query = `SELECT ` + productionJobColumns + ` FROM production_jobs j WHERE j.id = $1 FOR UPDATE`
+	if err = tx.GetContext(ctx, &job, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrJobNotFound
+			return job, "", err
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении задания ID=%d", id)
+		return job, "", fmt.Errorf("ошибка при получении задания: %w", err)
+	}
+
+	if job.Status == models.JobStatusCancelled || orderStatus == models.OrderStatusCancelled {
+		err = ErrJobStatus
+		return job, "", err
+	}
+
+	if update.MachineID != nil {
+		if *update.MachineID == 0 {
+			job.MachineLocked = false
+		} else {
+			var exists bool
+			if err = tx.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM machines WHERE id = $1)`, *update.MachineID); err != nil {
+				return job, "", fmt.Errorf("ошибка при получении станка: %w", err)
+			}
+			if !exists {
+				err = ErrMachineNotFound
+				return job, "", err
+			}
+			job.MachineID = update.MachineID
+			job.MachineLocked = true
+		}
+	}
+	if update.Priority != nil {
+		job.Priority = *update.Priority
+	}
+	if update.DueDate != nil {
+		job.DueDate = update.DueDate
+	}
+	if update.ClearDueDate {
+		job.DueDate = nil
+	}
+	if update.EstimatedMinutes != nil {
+		job.EstimatedMinutes = *update.EstimatedMinutes
+	}
+
+	if update.Status != nil && *update.Status != job.Status {
+		switch *update.Status {
+		case models.JobStatusInProgress:
+			if job.StartedAt == nil {
+				err = tx.GetContext(ctx, &job.StartedAt, `SELECT NOW()::timestamp`)
+			}
+			job.FinishedAt = nil
+		case models.JobStatusDone:
+			err = tx.GetContext(ctx, &job.FinishedAt, `SELECT NOW()::timestamp`)
+		case models.JobStatusQueued:
+			// Приостановленное задание возвращается в очередь и планируется заново
+			job.StartedAt = nil
+			job.FinishedAt = nil
+		}
+		if err != nil {
+			return job, "", fmt.Errorf("ошибка при изменении статуса задания: %w", err)
+		}
+		job.Status = *update.Status
+	}
+
+	query = `
+	UPDATE production_jobs
+	SET status = $2, machine_id = $3, machine_locked = $4, priority = $5, due_date = $6,
+	    estimated_minutes = $7, started_at = $8, finished_at = $9, updated_at = NOW()
+	WHERE id = $1
+	RETURNING updated_at
+	`
+	err = tx.GetContext(ctx, &job.UpdatedAt, query,
+		job.ID, job.Status, job.MachineID, job.MachineLocked, job.Priority, job.DueDate,
+		job.EstimatedMinutes, job.StartedAt, job.FinishedAt,
+	)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при изменении задания ID=%d", id)
+		return job, "", fmt.Errorf("ошибка при изменении задания: %w", err)
+	}
+
+	if orderStatus, err = syncOrderWithJobs(ctx, tx, job.OrderID, orderStatus); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при обновлении статуса заказа ID=%d по заданиям", job.OrderID)
+		return job, "", err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return job, "", fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return job, orderStatus, nil
+}
+
+// syncOrderWithJobs переводит заказ в производство, когда начато выполнение его заданий,
+// и в статус «изготовлен», когда все неотмененные задания выполнены. Заказ уже заблокирован
+func syncOrderWithJobs(ctx context.Context, tx *sqlx.Tx, orderID int64, current string) (string, error) {
+	var counts struct {
+		Active  int `db:"active"`
+		Started int `db:"started"`
+		Done    int `db:"done"`
+	}
+	query := `
+	SELECT COUNT(*) FILTER (WHERE status IN ($2, $3, $4)) AS active,
+	       COUNT(*) FILTER (WHERE status = $4) AS started,
+	       COUNT(*) FILTER (WHERE status = $5) AS done
+	FROM production_jobs
+	WHERE order_id = $1
+	`
+	err := tx.GetContext(ctx, &counts, query, orderID,
+		models.JobStatusQueued, models.JobStatusScheduled, models.JobStatusInProgress, models.JobStatusDone)
+	if err != nil {
+		return current, fmt.Errorf("ошибка при подсчете заданий заказа: %w", err)
+	}
+
+	status := current
+	switch {
+	case counts.Active == 0 && counts.Done > 0:
+		if current == models.OrderStatusNew || current == models.OrderStatusProcessing || current == models.OrderStatusInProduction {
+			status = models.OrderStatusReady
+		}
+	case counts.Started > 0 || counts.Done > 0:
+		if current == models.OrderStatusNew || current == models.OrderStatusProcessing {
+			status = models.OrderStatusInProduction
+		}
+	}
+	if status == current {
+		return current, nil
+	}
+
+	if err := consumeMaterials(ctx, tx, orderID); err != nil {
+		return current, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2`, status, orderID); err != nil {
+		return current, fmt.Errorf("ошибка при изменении статуса заказа: %w", err)
+	}
+
+	return status, nil
+}
+
+// cancelOrderJobs отменяет незавершенные задания заказа
+func cancelOrderJobs(ctx context.Context, tx *sqlx.Tx, orderID int64) error {
+	query := `
+	UPDATE production_jobs SET status = $2, updated_at = NOW()
+	WHERE order_id = $1 AND status IN ($3, $4, $5)
+	`
+	_, err := tx.ExecContext(ctx, query, orderID, models.JobStatusCancelled,
+		models.JobStatusQueued, models.JobStatusScheduled, models.JobStatusInProgress)
+	if err != nil {
+		return fmt.Errorf("ошибка при отмене заданий заказа: %w", err)
+	}
+	return nil
+}
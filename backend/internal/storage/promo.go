@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"pryanik_studio/internal/models"
+)
+
+var (
+	// ErrPromoNotFound возвращается, если промокод не найден
+	ErrPromoNotFound = errors.New("промокод не найден")
+
+	// ErrPromoLimit возвращается, если исчерпан лимит использований промокода
+	ErrPromoLimit = errors.New("промокод больше нельзя использовать")
+)
+
+// promoColumns колонки промокода с количеством использований в неотмененных заказах
+const promoColumns = `
+	p.id, p.code, p.description, p.kind, p.percent, p.amounts, p.free_product_id, p.min_order_amounts,
+	p.category_ids, p.starts_at, p.ends_at, p.max_uses, p.max_uses_per_email, p.enabled,
+	p.created_at, p.updated_at,
+	(SELECT COUNT(*) FROM promo_redemptions pr JOIN orders o ON o.id = pr.order_id
+	 WHERE pr.promo_id = p.id AND o.status <> 'cancelled') AS uses
+`
+
+// promoRow строка промокода; категории хранятся в массиве PostgreSQL
+type promoRow struct {
+	models.PromoCode
+	CategoryIDs pq.Int64Array `db:"category_ids"`
+}
+
+func (row promoRow) promo() models.PromoCode {
+	promo := row.PromoCode
+	promo.CategoryIDs = []int64(row.CategoryIDs)
+	return promo
+}
+
+// GetPromoCodes возвращает все промокоды, новые первыми
+func (r *PostgresRepository) GetPromoCodes(ctx context.Context) ([]models.PromoCode, error) {
+	query := `SELECT ` + promoColumns + ` FROM promo_codes p ORDER BY p.created_at DESC, p.id DESC`
+
+	var rows []promoRow
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении промокодов")
+		return nil, fmt.Errorf("ошибка при получении промокодов: %w", err)
+	}
+
+	promos := make([]models.PromoCode, 0, len(rows))
+	for _, row := range rows {
+		promos = append(promos, row.promo())
+	}
+
+	return promos, nil
+}
+
+// GetPromoCode возвращает промокод по коду
+func (r *PostgresRepository) GetPromoCode(ctx context.Context, code string) (models.PromoCode, error) {
+	query := `SELECT ` + promoColumns + ` FROM promo_codes p WHERE p.code = $1`
+
+	var row promoRow
+	if err := r.db.GetContext(ctx, &row, query, code); err != nil {
+		if err == sql.ErrNoRows {
+			return models.PromoCode{}, ErrPromoNotFound
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении промокода %s", code)
+		return models.PromoCode{}, fmt.Errorf("ошибка при получении промокода: %w", err)
+	}
+
+	return row.promo(), nil
+}
+
+// SavePromoCode создает промокод или обновляет существующий с тем же кодом
+func (r *PostgresRepository) SavePromoCode(ctx context.Context, promo *models.PromoCode) error {
+	query := `
+	INSERT INTO promo_codes (code, description, kind, percent, amounts, free_product_id, min_order_amounts,
+	                         category_ids, starts_at, ends_at, max_uses, max_uses_per_email, enabled,
+	                         created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())
+	ON CONFLICT (code) DO UPDATE
+	SET description = EXCLUDED.description,
+	    kind = EXCLUDED.kind,
+	    percent = EXCLUDED.percent,
+	    amounts = EXCLUDED.amounts,
+	    free_product_id = EXCLUDED.free_product_id,
+	    min_order_amounts = EXCLUDED.min_order_amounts,
+	    category_ids = EXCLUDED.category_ids,
+	    starts_at = EXCLUDED.starts_at,
+	    ends_at = EXCLUDED.ends_at,
+	    max_uses = EXCLUDED.max_uses,
+	    max_uses_per_email = EXCLUDED.max_uses_per_email,
+	    enabled = EXCLUDED.enabled,
+	    updated_at = NOW()
+	RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		promo.Code,
+		promo.Description,
+		promo.Kind,
+		promo.Percent,
+		promo.Amounts,
+		promo.FreeProductID,
+		promo.MinOrderAmounts,
+		pq.Array(promo.CategoryIDs),
+		promo.StartsAt,
+		promo.EndsAt,
+		promo.MaxUses,
+		promo.MaxUsesPerEmail,
+		promo.Enabled,
+	).Scan(&promo.ID, &promo.CreatedAt, &promo.UpdatedAt)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при сохранении промокода %s", promo.Code)
+		return fmt.Errorf("ошибка при сохранении промокода: %w", err)
+	}
+
+	return nil
+}
+
+// CheckPromoUsage проверяет лимиты использований промокода без блокировки;
+// окончательная проверка выполняется при создании заказа
+func (r *PostgresRepository) CheckPromoUsage(ctx context.Context, promo models.PromoCode, email string) error {
+	if err := checkPromoUsage(ctx, r.db.(*sqlx.DB), promo, email); err != nil {
+		if !errors.Is(err, ErrPromoLimit) {
+			r.logger.WithError(err).Errorf("Ошибка при проверке использований промокода %s", promo.Code)
+		}
+		return err
+	}
+	return nil
+}
+
+// redeemPromo фиксирует использование промокода заказом. Строка промокода блокируется,
+// чтобы одновременные заказы не превысили лимит
+func redeemPromo(ctx context.Context, tx *sqlx.Tx, orderID int64, code, email string) error {
+	var row promoRow
+	query := `SELECT ` + promoColumns + ` FROM promo_codes p WHERE p.code = $1 FOR UPDATE`
+	if err := tx.GetContext(ctx, &row, query, code); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrPromoNotFound
+		}
+		return fmt.Errorf("ошибка при получении промокода: %w", err)
+	}
+
+	if err := checkPromoUsage(ctx, tx, row.promo(), email); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO promo_redemptions (promo_id, order_id, email, created_at) VALUES ($1, $2, $3, NOW())`
+	if _, err := tx.ExecContext(ctx, query, row.ID, orderID, normalizeEmail(email)); err != nil {
+		return fmt.Errorf("ошибка при сохранении использования промокода: %w", err)
+	}
+
+	return nil
+}
+
+// checkPromoUsage сравнивает количество использований промокода с лимитами
+func checkPromoUsage(ctx context.Context, db sqlx.QueryerContext, promo models.PromoCode, email string) error {
+	if promo.MaxUses != nil && promo.Uses >= *promo.MaxUses {
+		return ErrPromoLimit
+	}
+	if promo.MaxUsesPerEmail == nil || email == "" {
+		return nil
+	}
+
+	var uses int
+	query := `
+	SELECT COUNT(*) FROM promo_redemptions pr JOIN orders o ON o.id = pr.order_id
+	WHERE pr.promo_id = $1 AND pr.email = $2 AND o.status <> 'cancelled'
+	`
+	if err := sqlx.GetContext(ctx, db, &uses, query, promo.ID, normalizeEmail(email)); err != nil {
+		return fmt.Errorf("ошибка при подсчете использований промокода: %w", err)
+	}
+	if uses >= *promo.MaxUsesPerEmail {
+		return ErrPromoLimit
+	}
+
+	return nil
+}
+
+// normalizeEmail приводит email к виду, по которому считаются использования промокода
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
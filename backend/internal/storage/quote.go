@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"pryanik_studio/internal/models"
+)
+
+var (
+	// ErrQuoteNotFound возвращается, если запрос на расчет не найден
+	ErrQuoteNotFound = errors.New("запрос на расчет не найден")
+
+	// ErrQuoteStatus возвращается, если действие недоступно в текущем статусе запроса
+	ErrQuoteStatus = errors.New("действие недоступно в текущем статусе запроса на расчет")
+
+	// ErrQuoteExpired возвращается при попытке принять предложение после окончания срока действия
+	ErrQuoteExpired = errors.New("срок действия предложения истек")
+)
+
+// quoteRow строка таблицы quote_requests
+type quoteRow struct {
+	models.QuoteRequest
+	Price    *models.Money  `db:"price"`
+	Currency sql.NullString `db:"currency"`
+}
+
+// toModel преобразует строку таблицы в модель запроса на расчет
+func (row quoteRow) toModel() models.QuoteRequest {
+	quote := row.QuoteRequest
+	if row.Price != nil && row.Currency.Valid {
+		price := models.NewMoney(row.Price.Amount, row.Currency.String)
+		quote.Price = &price
+	}
+	return quote
+}
+
+// quoteColumns колонки, которые выбираются для запроса на расчет
+const quoteColumns = `
+	id, name, email, phone, description, material, quantity, language, status,
+	price, currency, admin_comment, valid_until, quoted_at, order_id, accepted_at,
+	created_at, updated_at
+`
+
+// CreateQuoteRequest сохраняет запрос на расчет вместе со ссылками на загруженные файлы
+func (r *PostgresRepository) CreateQuoteRequest(ctx context.Context, quote *models.QuoteRequest) (int64, error) {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для создания запроса на расчет")
+		return 0, fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	// Добавляем отложенную функцию для отката транзакции в случае ошибки
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	now := time.Now()
+	quote.Status = models.QuoteStatusNew
+	quote.CreatedAt = now
+	quote.UpdatedAt = now
+
+	query := `
+	INSERT INTO quote_requests (name, email, phone, description, material, quantity, language, status, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING id
+	`
+
+	err = tx.QueryRowContext(ctx, query,
+		quote.Name,
+		quote.Email,
+		quote.Phone,
+		quote.Description,
+		quote.Material,
+		quote.Quantity,
+		quote.Language,
+		quote.Status,
+		quote.CreatedAt,
+		quote.UpdatedAt,
+	).Scan(&quote.ID)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при создании запроса на расчет")
+		return 0, fmt.Errorf("ошибка при создании запроса на расчет: %w", err)
+	}
+
+	for i, file := range quote.Files {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO quote_request_files (quote_request_id, upload_id, sort_order) VALUES ($1, $2, $3)`,
+			quote.ID, file.ID, i,
+		)
+		if err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при добавлении файла %s к запросу на расчет", file.ID)
+			return 0, fmt.Errorf("ошибка при добавлении файла к запросу на расчет: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return 0, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return quote.ID, nil
+}
+
+// GetQuoteRequests возвращает запросы на расчет, новые сверху; пустой статус - все запросы
+func (r *PostgresRepository) GetQuoteRequests(ctx context.Context, status string) ([]models.QuoteRequest, error) {
+	query := `SELECT ` + quoteColumns + ` FROM quote_requests
+	WHERE $1 = '' OR status = $1
+	ORDER BY created_at DESC, id DESC`
+
+	var rows []quoteRow
+	if err := r.db.SelectContext(ctx, &rows, query, status); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении запросов на расчет")
+		return nil, fmt.Errorf("ошибка при получении запросов на расчет: %w", err)
+	}
+
+	quotes := make([]models.QuoteRequest, 0, len(rows))
+	for _, row := range rows {
+		quotes = append(quotes, row.toModel())
+	}
+
+	return quotes, nil
+}
+
+// GetQuoteRequest возвращает запрос на расчет по ID вместе с файлами
+func (r *PostgresRepository) GetQuoteRequest(ctx context.Context, id int64) (models.QuoteRequest, error) {
+	var row quoteRow
+
+	query := `SELECT ` + quoteColumns + ` FROM quote_requests WHERE id = $1`
+	if err := r.db.GetContext(ctx, &row, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return models.QuoteRequest{}, ErrQuoteNotFound
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении запроса на расчет ID=%d", id)
+		return models.QuoteRequest{}, fmt.Errorf("ошибка при получении запроса на расчет: %w", err)
+	}
+
+	quote := row.toModel()
+
+	filesQuery := `
+	SELECT u.id, u.file_name, u.content_type, u.size, u.path, u.created_at
+	FROM quote_request_files f
+	JOIN uploads u ON u.id = f.upload_id
+	WHERE f.quote_request_id = $1
+	ORDER BY f.sort_order
+	`
+	if err := r.db.SelectContext(ctx, &quote.Files, filesQuery, id); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении файлов запроса на расчет ID=%d", id)
+		return quote, fmt.Errorf("ошибка при получении файлов запроса на расчет: %w", err)
+	}
+
+	return quote, nil
+}
+
+// SetQuote сохраняет предложение администратора. Повторное предложение заменяет предыдущее,
+// пока клиент его не принял
+func (r *PostgresRepository) SetQuote(ctx context.Context, id int64, price models.Money, comment string, validUntil time.Time) error {
+	query := `
+	UPDATE quote_requests
+	SET status = $1, price = $2, currency = $3, admin_comment = $4, valid_until = $5,
+	    quoted_at = NOW(), updated_at = NOW()
+	WHERE id = $6 AND status IN ($7, $1)
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		models.QuoteStatusQuoted, price, price.Currency, comment, validUntil, id, models.QuoteStatusNew,
+	)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при сохранении предложения по запросу ID=%d", id)
+		return fmt.Errorf("ошибка при сохранении предложения: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении предложения: %w", err)
+	}
+	if affected == 0 {
+		// Отличаем отсутствующий запрос от запроса в неподходящем статусе
+		if _, err := r.GetQuoteRequest(ctx, id); err != nil {
+			return err
+		}
+		return ErrQuoteStatus
+	}
+
+	return nil
+}
+
+// AcceptQuote принимает предложение и в той же транзакции создает по нему заказ.
+// Возвращает ID созданного заказа
+func (r *PostgresRepository) AcceptQuote(ctx context.Context, id int64, order *models.Order) (int64, error) {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для принятия предложения")
+		return 0, fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	// Добавляем отложенную функцию для отката транзакции в случае ошибки
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	// Блокируем запрос, чтобы предложение нельзя было принять дважды
+	var current struct {
+		Status     string       `db:"status"`
+		ValidUntil sql.NullTime `db:"valid_until"`
+	}
+	err = tx.GetContext(ctx, &current, `SELECT status, valid_until FROM quote_requests WHERE id = $1 FOR UPDATE`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrQuoteNotFound
+			return 0, err
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении запроса на расчет ID=%d", id)
+		return 0, fmt.Errorf("ошибка при получении запроса на расчет: %w", err)
+	}
+
+	if current.Status != models.QuoteStatusQuoted {
+		err = ErrQuoteStatus
+		return 0, err
+	}
+	if current.ValidUntil.Valid && time.Now().After(current.ValidUntil.Time) {
+		err = ErrQuoteExpired
+		return 0, err
+	}
+
+	orderID, err := r.insertOrder(ctx, tx, order)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE quote_requests
+	SET status = $1, order_id = $2, accepted_at = NOW(), updated_at = NOW()
+	WHERE id = $3
+	`, models.QuoteStatusAccepted, orderID, id)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при обновлении запроса на расчет ID=%d", id)
+		return 0, fmt.Errorf("ошибка при обновлении запроса на расчет: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return 0, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return orderID, nil
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
@@ -32,6 +33,72 @@ type Repository interface {
 
 	// Интерфейсы для работы с заказами
 	OrderRepository
+
+	// Интерфейсы для работы с переводами
+	TranslationRepository
+
+	// Интерфейсы для работы с языками
+	LanguageRepository
+
+	// Интерфейсы для работы с курсами валют
+	ExchangeRateRepository
+
+	// Интерфейсы для работы с вариантами товаров
+	VariantRepository
+
+	// Интерфейсы для работы с загруженными файлами
+	UploadRepository
+
+	// Интерфейсы для работы с запросами на расчет
+	QuoteRepository
+
+	// Интерфейсы для работы с материалами печати
+	PrintMaterialRepository
+
+	// Интерфейсы для работы с материалами лазерной обработки
+	LaserMaterialRepository
+
+	// Интерфейсы для работы с сохраненными расчетами стоимости
+	EstimateRepository
+
+	// Интерфейсы для работы с материалами мастерской
+	WorkshopMaterialRepository
+
+	// Интерфейсы для работы с производством
+	ProductionRepository
+
+	// Интерфейсы для работы с промокодами
+	PromoRepository
+
+	// Интерфейсы для работы с доставкой
+	ShippingRepository
+
+	// Интерфейсы для работы с онлайн-платежами
+	PaymentRepository
+
+	// Интерфейсы для работы со счетами
+	InvoiceRepository
+
+	// Интерфейсы для работы с ключами идемпотентности
+	IdempotencyRepository
+
+	// Интерфейсы для работы с корзинами
+	CartRepository
+
+	// Интерфейсы для работы с черновиками заказов и напоминаниями
+	CheckoutDraftRepository
+
+	// Интерфейсы для работы с учетными записями покупателей
+	CustomerRepository
+
+	// Интерфейсы для работы со списками избранного
+	WishlistRepository
+
+	// Интерфейсы для работы с отзывами о товарах
+	ReviewRepository
+
+	// Интерфейсы для работы с сообщениями с формы обратной связи
+	ContactRepository
 }
 
 // ProductRepository интерфейс для работы с товарами
@@ -42,6 +109,8 @@ type ProductRepository interface {
 	GetCategories(ctx context.Context, language string) ([]models.Category, error)
 	CreateProduct(ctx context.Context, product *models.Product) (int64, error)
 	UpdateProduct(ctx context.Context, product *models.Product) error
+	SetProductStock(ctx context.Context, productID int64, stock *int) error
+	SetProductOrderSettings(ctx context.Context, productID int64, settings models.ProductOrderSettings) error
 }
 
 // GalleryRepository интерфейс для работы с галереей
@@ -55,12 +124,228 @@ type GalleryRepository interface {
 type OrderRepository interface {
 	CreateOrder(ctx context.Context, order *models.Order) (int64, error)
 	GetOrderByID(ctx context.Context, id int64) (models.Order, error)
+	UpdateOrderStatus(ctx context.Context, id int64, status string) error
+}
+
+// TranslationRepository интерфейс для работы с переводами
+type TranslationRepository interface {
+	GetMissingTranslations(ctx context.Context, language string) (models.MissingTranslationsReport, error)
+}
+
+// LanguageRepository интерфейс для работы с языками
+type LanguageRepository interface {
+	GetLanguages(ctx context.Context) ([]models.Language, error)
+	CreateLanguage(ctx context.Context, language *models.Language) error
+	UpdateLanguage(ctx context.Context, language *models.Language) error
+}
+
+// ExchangeRateRepository интерфейс для работы с курсами валют и рассчитанными ценами
+type ExchangeRateRepository interface {
+	GetExchangeRates(ctx context.Context) ([]models.ExchangeRate, error)
+	SaveExchangeRate(ctx context.Context, rate *models.ExchangeRate) error
+	ImportExchangeRates(ctx context.Context, rates []models.ExchangeRate) error
+	GetDerivedPrices(ctx context.Context, productID int64) ([]models.DerivedPrice, error)
+	UpdateDerivedPrices(ctx context.Context, prices []models.DerivedPrice) error
+}
+
+// VariantRepository интерфейс для работы с опциями и вариантами товаров
+type VariantRepository interface {
+	GetProductVariants(ctx context.Context, productID int64, language string, onlyEnabled bool) ([]models.ProductOption, []models.ProductVariant, error)
+	SaveProductVariants(ctx context.Context, productID int64, request models.ProductVariantsRequest) error
+}
+
+// UploadRepository интерфейс для работы с файлами, загруженными клиентами
+type UploadRepository interface {
+	CreateUpload(ctx context.Context, upload *models.Upload) error
+	GetUpload(ctx context.Context, id string) (models.Upload, error)
+	DeleteExpiredUploads(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+// QuoteRepository интерфейс для работы с запросами на расчет
+type QuoteRepository interface {
+	CreateQuoteRequest(ctx context.Context, quote *models.QuoteRequest) (int64, error)
+	GetQuoteRequests(ctx context.Context, status string) ([]models.QuoteRequest, error)
+	GetQuoteRequest(ctx context.Context, id int64) (models.QuoteRequest, error)
+	SetQuote(ctx context.Context, id int64, price models.Money, comment string, validUntil time.Time) error
+	AcceptQuote(ctx context.Context, id int64, order *models.Order) (int64, error)
+}
+
+// PrintMaterialRepository интерфейс для работы с материалами 3D-печати
+type PrintMaterialRepository interface {
+	GetPrintMaterials(ctx context.Context, onlyEnabled bool) ([]models.PrintMaterial, error)
+	GetPrintMaterial(ctx context.Context, code string) (models.PrintMaterial, error)
+	SavePrintMaterial(ctx context.Context, material *models.PrintMaterial) error
+}
+
+// LaserMaterialRepository интерфейс для работы с материалами лазерной обработки
+type LaserMaterialRepository interface {
+	GetLaserMaterials(ctx context.Context, onlyEnabled bool) ([]models.LaserMaterial, error)
+	GetLaserMaterial(ctx context.Context, code string) (models.LaserMaterial, error)
+	SaveLaserMaterial(ctx context.Context, material *models.LaserMaterial) error
+}
+
+// EstimateRepository интерфейс для работы с сохраненными расчетами стоимости
+type EstimateRepository interface {
+	CreateEstimate(ctx context.Context, estimate *models.Estimate) error
+	GetEstimate(ctx context.Context, id string) (models.Estimate, error)
+}
+
+// WorkshopMaterialRepository интерфейс для работы с материалами мастерской и спецификациями товаров
+type WorkshopMaterialRepository interface {
+	GetWorkshopMaterials(ctx context.Context) ([]models.WorkshopMaterial, error)
+	SaveWorkshopMaterial(ctx context.Context, material *models.WorkshopMaterial) error
+	AdjustWorkshopMaterial(ctx context.Context, id int64, delta float64, comment string) (models.WorkshopMaterial, error)
+	GetMaterialMovements(ctx context.Context, materialID int64, limit int) ([]models.MaterialMovement, error)
+	GetBOM(ctx context.Context, productID int64) ([]models.BOMItem, error)
+	SaveBOM(ctx context.Context, productID int64, items []models.BOMItem) error
+	GetMaterialShortages(ctx context.Context) ([]models.MaterialShortage, error)
+}
+
+// ProductionRepository интерфейс для работы со станками и производственными заданиями
+type ProductionRepository interface {
+	GetMachines(ctx context.Context) ([]models.Machine, error)
+	SaveMachine(ctx context.Context, machine *models.Machine) error
+	GetProductionSettings(ctx context.Context, productIDs []int64) (map[int64]models.ProductionSettings, error)
+	SetProductionSettings(ctx context.Context, productID int64, settings models.ProductionSettings) error
+	CreateProductionJobs(ctx context.Context, jobs []models.ProductionJob) ([]models.ProductionJob, error)
+	GetProductionJobs(ctx context.Context, filter models.ProductionJobFilter) ([]models.ProductionJob, error)
+	ApplySchedule(ctx context.Context, slots []models.JobSlot) (int, error)
+	UpdateProductionJob(ctx context.Context, id int64, update models.JobUpdate) (models.ProductionJob, string, error)
+}
+
+// PromoRepository интерфейс для работы с промокодами
+type PromoRepository interface {
+	GetPromoCodes(ctx context.Context) ([]models.PromoCode, error)
+	GetPromoCode(ctx context.Context, code string) (models.PromoCode, error)
+	SavePromoCode(ctx context.Context, promo *models.PromoCode) error
+	CheckPromoUsage(ctx context.Context, promo models.PromoCode, email string) error
+}
+
+// ShippingRepository интерфейс для работы со способами доставки и весом товаров
+type ShippingRepository interface {
+	GetShippingMethods(ctx context.Context, onlyEnabled bool) ([]models.ShippingMethod, error)
+	GetShippingMethod(ctx context.Context, code string) (models.ShippingMethod, error)
+	SaveShippingMethod(ctx context.Context, method *models.ShippingMethod) error
+	GetProductDimensions(ctx context.Context, productIDs []int64) (map[int64]models.ProductDimensions, error)
+	SetProductDimensions(ctx context.Context, productID int64, dimensions models.ProductDimensions) error
+}
+
+// PaymentRepository интерфейс для работы с онлайн-платежами по заказам
+type PaymentRepository interface {
+	StartPayment(ctx context.Context, orderID int64, provider string) (models.Payment, error)
+	SetPaymentCreated(ctx context.Context, id int64, providerPaymentID, confirmationURL string) error
+	FailPayment(ctx context.Context, id int64, reason string) error
+	ApplyPaymentEvent(ctx context.Context, provider string, event models.PaymentEvent) (models.PaymentEventResult, error)
+	GetPayment(ctx context.Context, id int64) (models.Payment, error)
+	GetOrderPayments(ctx context.Context, orderID int64) ([]models.Payment, error)
+}
+
+// InvoiceRepository интерфейс для работы со счетами по заказам
+type InvoiceRepository interface {
+	IssueInvoice(ctx context.Context, orderID int64, prefix string, seller models.CompanyRequisites) (models.Invoice, error)
+	GetOrderInvoice(ctx context.Context, orderID int64) (models.Invoice, error)
+}
+
+// IdempotencyRepository интерфейс для работы с ключами идемпотентности запросов
+type IdempotencyRepository interface {
+	ReserveIdempotencyKey(ctx context.Context, scope, key, requestHash string, ttl time.Duration) (models.IdempotencyRecord, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, scope, key string, statusCode int, contentType string, response []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, scope, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+}
+
+// CartRepository интерфейс для работы с корзинами покупателей
+type CartRepository interface {
+	CreateCart(ctx context.Context, id, tokenHash string, ttl time.Duration) (models.Cart, error)
+	GetCart(ctx context.Context, id string) (models.Cart, error)
+	AddCartItem(ctx context.Context, cartID string, item *models.CartItem, ttl time.Duration) error
+	UpdateCartItem(ctx context.Context, cartID string, itemID int64, quantity int, price models.Money, ttl time.Duration) error
+	DeleteCartItem(ctx context.Context, cartID string, itemID int64, ttl time.Duration) error
+	StartCartCheckout(ctx context.Context, cartID string) error
+	FinishCartCheckout(ctx context.Context, cartID string, orderID int64) error
+	CancelCartCheckout(ctx context.Context, cartID string) error
+	DeleteExpiredCarts(ctx context.Context) (int64, error)
+}
+
+// CheckoutDraftRepository интерфейс для работы с черновиками заказов и напоминаниями о них
+type CheckoutDraftRepository interface {
+	CreateCheckoutDraft(ctx context.Context, draft *models.CheckoutDraft) error
+	UpdateCheckoutDraft(ctx context.Context, draft *models.CheckoutDraft) error
+	GetCheckoutDraft(ctx context.Context, id string) (models.CheckoutDraft, error)
+	ClaimCheckoutReminders(ctx context.Context, idle, maxAge time.Duration, maxAttempts, limit int) ([]models.CheckoutDraft, error)
+	ReleaseCheckoutReminder(ctx context.Context, id string) error
+	CountCheckoutDrafts(ctx context.Context, email, excludeID string, period time.Duration) (int, error)
+	MarkCheckoutDraftConverted(ctx context.Context, draftID string, orderID int64) error
+	DeleteStaleCheckoutDrafts(ctx context.Context, retention time.Duration) (int64, error)
+	UnsubscribeFromReminders(ctx context.Context, email string) error
+	GetReminderStats(ctx context.Context, period time.Duration) (models.ReminderStats, error)
+}
+
+// CustomerRepository интерфейс для работы с учетными записями покупателей
+type CustomerRepository interface {
+	CreateLoginToken(ctx context.Context, email, tokenHash string, ttl, interval time.Duration) (bool, error)
+	DeleteLoginToken(ctx context.Context, tokenHash string) error
+	ConsumeLoginToken(ctx context.Context, tokenHash string) (models.Customer, error)
+	DeleteExpiredLoginTokens(ctx context.Context) (int64, error)
+	GetCustomer(ctx context.Context, id int64) (models.Customer, error)
+	UpdateCustomer(ctx context.Context, id int64, name, phone string) (models.Customer, error)
+	GetOrdersByEmail(ctx context.Context, email string, page, pageSize int) (models.OrderList, error)
+	GetCustomerAddresses(ctx context.Context, customerID int64) ([]models.CustomerAddress, error)
+	CreateCustomerAddress(ctx context.Context, address *models.CustomerAddress) error
+	UpdateCustomerAddress(ctx context.Context, address *models.CustomerAddress) error
+	DeleteCustomerAddress(ctx context.Context, customerID, id int64) error
+}
+
+// WishlistRepository интерфейс для работы со списками избранного
+type WishlistRepository interface {
+	CreateWishlist(ctx context.Context, id, tokenHash string, ttl time.Duration) (models.Wishlist, error)
+	GetCustomerWishlist(ctx context.Context, customerID int64, id string) (models.Wishlist, error)
+	GetWishlist(ctx context.Context, id string) (models.Wishlist, error)
+	GetWishlistByToken(ctx context.Context, tokenHash string) (models.Wishlist, error)
+	GetWishlistByShareID(ctx context.Context, shareID string) (models.Wishlist, error)
+	AddWishlistItem(ctx context.Context, wishlistID string, productID int64, language string, notify bool, ttl time.Duration) error
+	UpdateWishlistItem(ctx context.Context, wishlistID string, productID int64, language string, notify bool, ttl time.Duration) error
+	DeleteWishlistItem(ctx context.Context, wishlistID string, productID int64, ttl time.Duration) error
+	MergeWishlists(ctx context.Context, fromID, toID string, maxItems int) error
+	SetWishlistShareID(ctx context.Context, wishlistID string, shareID *string) error
+	DeleteExpiredWishlists(ctx context.Context) (int64, error)
+	RebaseWatchedPrices(ctx context.Context) error
+	ClaimPriceDrops(ctx context.Context, limit int) ([]models.PriceDrop, error)
+	ReleasePriceDrop(ctx context.Context, drop models.PriceDrop) error
+}
+
+// ReviewRepository интерфейс для работы с отзывами о товарах и их модерацией
+type ReviewRepository interface {
+	MarkReviewInvitation(ctx context.Context, orderID int64) (bool, error)
+	ReleaseReviewInvitation(ctx context.Context, orderID int64) error
+	CreateReview(ctx context.Context, review *models.Review) error
+	GetReviewedProducts(ctx context.Context, orderID int64) ([]int64, error)
+	GetProductReviews(ctx context.Context, productID int64, page, pageSize int) (models.ReviewList, error)
+	GetReviews(ctx context.Context, status, language string, page, pageSize int) (models.ReviewList, error)
+	ModerateReview(ctx context.Context, id int64, status string, reply *string) (models.Review, error)
+	GetReviewPhoto(ctx context.Context, reviewID int64, uploadID string) (models.Upload, error)
+}
+
+// ContactRepository интерфейс для работы с сообщениями с формы обратной связи и уведомлениями о них
+type ContactRepository interface {
+	CreateContactMessage(ctx context.Context, message *models.ContactMessage) error
+	GetContactMessages(ctx context.Context, filter models.ContactMessageFilter) (models.ContactMessageList, error)
+	GetContactMessage(ctx context.Context, id int64) (models.ContactMessage, error)
+	UpdateContactMessage(ctx context.Context, id int64, status string, assignedTo *string) error
+	AddContactNote(ctx context.Context, note *models.ContactNote) error
+	ClaimContactNotifications(ctx context.Context, maxAttempts, limit int) ([]models.ContactMessage, error)
+	MarkContactCustomerNotified(ctx context.Context, id int64) error
+	MarkContactAdminNotified(ctx context.Context, id int64) error
+	ReleaseContactNotification(ctx context.Context, id int64) error
 }
 
 // PostgresRepository реализация Repository для PostgreSQL
 type PostgresRepository struct {
 	db     DatabaseConnection
 	logger *logrus.Logger
+
+	// Цепочка языков для поиска перевода при его отсутствии на запрошенном языке
+	fallbackChain func(language string) []string
 }
 
 // NewPostgresRepository создает новый PostgresRepository
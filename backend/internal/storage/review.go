@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"pryanik_studio/internal/models"
+)
+
+// Ошибки работы с отзывами
+var (
+	ErrReviewNotFound = errors.New("отзыв не найден")
+	ErrReviewExists   = errors.New("отзыв о товаре по этому заказу уже оставлен")
+)
+
+// reviewColumns колонки отзыва
+const reviewColumns = `pr.id, pr.product_id, pr.order_id, pr.author_name, pr.rating, pr.text, pr.language, pr.status,
+	pr.reply, pr.replied_at, pr.moderated_at, pr.created_at, pr.updated_at`
+
+// MarkReviewInvitation отмечает отправку приглашения оставить отзыв о выполненном заказе.
+// Возвращает false, если заказ не выполнен или приглашение уже отправлялось
+func (r *PostgresRepository) MarkReviewInvitation(ctx context.Context, orderID int64) (bool, error) {
+	query := `
+	UPDATE orders SET review_invited_at = NOW()
+	WHERE id = $1 AND status = $2 AND review_invited_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, orderID, models.OrderStatusCompleted)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при отметке приглашения к отзыву по заказу ID=%d", orderID)
+		return false, fmt.Errorf("ошибка при отметке приглашения к отзыву: %w", err)
+	}
+
+	marked, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка при получении результата отметки приглашения: %w", err)
+	}
+	return marked > 0, nil
+}
+
+// ReleaseReviewInvitation снимает отметку о приглашении к отзыву, если письмо не удалось отправить
+func (r *PostgresRepository) ReleaseReviewInvitation(ctx context.Context, orderID int64) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE orders SET review_invited_at = NULL WHERE id = $1`, orderID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при снятии отметки приглашения к отзыву по заказу ID=%d", orderID)
+		return fmt.Errorf("ошибка при снятии отметки приглашения к отзыву: %w", err)
+	}
+	return nil
+}
+
+// CreateReview сохраняет отзыв на модерацию вместе с фотографиями.
+// По каждому товару заказа можно оставить один отзыв
+func (r *PostgresRepository) CreateReview(ctx context.Context, review *models.Review) error {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для создания отзыва")
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	query := `
+	INSERT INTO product_reviews (product_id, order_id, author_name, rating, text, language, status, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+	ON CONFLICT (order_id, product_id) DO NOTHING
+	RETURNING id, status, created_at, updated_at
+	`
+	err = tx.QueryRowContext(ctx, query,
+		review.ProductID,
+		review.OrderID,
+		review.AuthorName,
+		review.Rating,
+		review.Text,
+		review.Language,
+		models.ReviewStatusPending,
+	).Scan(&review.ID, &review.Status, &review.CreatedAt, &review.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrReviewExists
+			return err
+		}
+		r.logger.WithError(err).Errorf("Ошибка при сохранении отзыва о товаре ID=%d по заказу ID=%d", review.ProductID, review.OrderID)
+		return fmt.Errorf("ошибка при сохранении отзыва: %w", err)
+	}
+
+	for i, uploadID := range review.PhotoIDs {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO review_photos (review_id, upload_id, sort_order) VALUES ($1, $2, $3)`,
+			review.ID, uploadID, i)
+		if err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при сохранении фотографии к отзыву ID=%d", review.ID)
+			return fmt.Errorf("ошибка при сохранении фотографий отзыва: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// GetReviewedProducts возвращает ID товаров заказа, о которых уже оставлены отзывы
+func (r *PostgresRepository) GetReviewedProducts(ctx context.Context, orderID int64) ([]int64, error) {
+	ids := []int64{}
+	if err := r.db.SelectContext(ctx, &ids, `SELECT product_id FROM product_reviews WHERE order_id = $1`, orderID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении отзывов по заказу ID=%d", orderID)
+		return nil, fmt.Errorf("ошибка при получении отзывов по заказу: %w", err)
+	}
+	return ids, nil
+}
+
+// GetProductReviews возвращает страницу опубликованных отзывов о товаре, начиная с последних
+func (r *PostgresRepository) GetProductReviews(ctx context.Context, productID int64, page, pageSize int) (models.ReviewList, error) {
+	return r.getReviews(ctx, `pr.product_id = $1 AND pr.status = $2`, []interface{}{productID, models.ReviewStatusApproved}, nil, page, pageSize)
+}
+
+// GetReviews возвращает страницу отзывов со статусом status (все отзывы, если статус пустой)
+// с названиями товаров на языке language; новые отзывы идут первыми
+func (r *PostgresRepository) GetReviews(ctx context.Context, status, language string, page, pageSize int) (models.ReviewList, error) {
+	where, args := `TRUE`, []interface{}{}
+	if status != "" {
+		where, args = `pr.status = $1`, []interface{}{status}
+	}
+	return r.getReviews(ctx, where, args, r.languageChain(language), page, pageSize)
+}
+
+// getReviews возвращает страницу отзывов по условию where; если задана цепочка языков chain,
+// заполняются названия товаров
+func (r *PostgresRepository) getReviews(ctx context.Context, where string, args []interface{}, chain []string, page, pageSize int) (models.ReviewList, error) {
+	result := models.ReviewList{
+		Items:    []models.Review{},
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	var totalItems int
+	if err := r.db.GetContext(ctx, &totalItems, `SELECT COUNT(*) FROM product_reviews pr WHERE `+where, args...); err != nil {
+		r.logger.WithError(err).Error("Ошибка при подсчете отзывов")
+		return result, fmt.Errorf("ошибка при подсчете отзывов: %w", err)
+	}
+	result.TotalItems = totalItems
+	result.TotalPages = int(math.Ceil(float64(totalItems) / float64(pageSize)))
+
+	productName := `'' AS product_name`
+	if chain != nil {
+		args = append(args, pq.Array(chain))
+		productName = fmt.Sprintf(`COALESCE((
+			SELECT t.name FROM product_translations t
+			WHERE t.product_id = pr.product_id AND t.language = ANY($%[1]d::text[])
+			ORDER BY array_position($%[1]d::text[], t.language::text)
+			LIMIT 1
+		), '') AS product_name`, len(args))
+	}
+	args = append(args, pageSize, (page-1)*pageSize)
+
+	query := fmt.Sprintf(`
+	SELECT %s, %s
+	FROM product_reviews pr
+	WHERE %s
+	ORDER BY pr.created_at DESC, pr.id DESC
+	LIMIT $%d OFFSET $%d
+	`, reviewColumns, productName, where, len(args)-1, len(args))
+	if err := r.db.SelectContext(ctx, &result.Items, query, args...); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении отзывов")
+		return result, fmt.Errorf("ошибка при получении отзывов: %w", err)
+	}
+
+	return result, r.loadReviewPhotos(ctx, result.Items)
+}
+
+// loadReviewPhotos заполняет ID загруженных файлов с фотографиями отзывов
+func (r *PostgresRepository) loadReviewPhotos(ctx context.Context, reviews []models.Review) error {
+	if len(reviews) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(reviews))
+	byID := make(map[int64]*models.Review, len(reviews))
+	for i := range reviews {
+		reviews[i].PhotoIDs = []string{}
+		ids[i] = reviews[i].ID
+		byID[reviews[i].ID] = &reviews[i]
+	}
+
+	var photos []struct {
+		ReviewID int64  `db:"review_id"`
+		UploadID string `db:"upload_id"`
+	}
+	query := `
+	SELECT review_id, upload_id FROM review_photos
+	WHERE review_id = ANY($1)
+	ORDER BY review_id, sort_order
+	`
+	if err := r.db.SelectContext(ctx, &photos, query, pq.Array(ids)); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении фотографий отзывов")
+		return fmt.Errorf("ошибка при получении фотографий отзывов: %w", err)
+	}
+
+	for _, photo := range photos {
+		review := byID[photo.ReviewID]
+		review.PhotoIDs = append(review.PhotoIDs, photo.UploadID)
+	}
+	return nil
+}
+
+// ModerateReview меняет статус отзыва и (или) ответ магазина. Пустой status оставляет статус без
+// изменений, nil reply - ответ без изменений, пустой ответ удаляет его. Средняя оценка и количество
+// опубликованных отзывов товара пересчитываются в той же транзакции
+func (r *PostgresRepository) ModerateReview(ctx context.Context, id int64, status string, reply *string) (models.Review, error) {
+	var review models.Review
+
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для модерации отзыва")
+		return review, fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	query := `
+	UPDATE product_reviews pr SET
+		status = COALESCE(NULLIF($2, ''), status),
+		moderated_at = CASE WHEN $2 = '' THEN moderated_at ELSE NOW() END,
+		reply = COALESCE($3::text, reply),
+		replied_at = CASE WHEN $3::text IS NULL THEN replied_at WHEN $3::text = '' THEN NULL ELSE NOW() END,
+		updated_at = NOW()
+	WHERE id = $1
+	RETURNING ` + reviewColumns + `, '' AS product_name`
+	if err = tx.GetContext(ctx, &review, query, id, status, reply); err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrReviewNotFound
+			return review, err
+		}
+		r.logger.WithError(err).Errorf("Ошибка при модерации отзыва ID=%d", id)
+		return review, fmt.Errorf("ошибка при модерации отзыва: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	UPDATE products SET
+		rating_avg = COALESCE((
+			SELECT ROUND(AVG(rating), 2) FROM product_reviews WHERE product_id = $1 AND status = $2
+		), 0),
+		rating_count = (SELECT COUNT(*) FROM product_reviews WHERE product_id = $1 AND status = $2)
+	WHERE id = $1
+	`, review.ProductID, models.ReviewStatusApproved)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при пересчете рейтинга товара ID=%d", review.ProductID)
+		return review, fmt.Errorf("ошибка при пересчете рейтинга товара: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return review, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	reviews := []models.Review{review}
+	if err := r.loadReviewPhotos(ctx, reviews); err != nil {
+		return review, err
+	}
+	return reviews[0], nil
+}
+
+// GetReviewPhoto возвращает файл фотографии опубликованного отзыва
+func (r *PostgresRepository) GetReviewPhoto(ctx context.Context, reviewID int64, uploadID string) (models.Upload, error) {
+	var upload models.Upload
+
+	query := `
+	SELECT u.id, u.file_name, u.content_type, u.size, u.path, u.created_at
+	FROM review_photos rp
+	JOIN product_reviews pr ON pr.id = rp.review_id
+	JOIN uploads u ON u.id = rp.upload_id
+	WHERE rp.review_id = $1 AND rp.upload_id = $2 AND pr.status = $3
+	`
+	if err := r.db.GetContext(ctx, &upload, query, reviewID, uploadID, models.ReviewStatusApproved); err != nil {
+		if err == sql.ErrNoRows {
+			return upload, ErrUploadNotFound
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении фотографии %s к отзыву ID=%d", uploadID, reviewID)
+		return upload, fmt.Errorf("ошибка при получении фотографии отзыва: %w", err)
+	}
+	return upload, nil
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"pryanik_studio/internal/models"
+)
+
+// ErrShippingMethodNotFound возвращается, если способ доставки не найден
+var ErrShippingMethodNotFound = errors.New("способ доставки не найден")
+
+// shippingMethodColumns колонки способа доставки
+const shippingMethodColumns = `
+	id, code, name, kind, description, pickup_address, rules, sort_order, enabled, created_at, updated_at
+`
+
+// GetShippingMethods возвращает способы доставки в порядке отображения;
+// onlyEnabled - только включенные (для покупателей)
+func (r *PostgresRepository) GetShippingMethods(ctx context.Context, onlyEnabled bool) ([]models.ShippingMethod, error) {
+	query := `
+	SELECT ` + shippingMethodColumns + `
+	FROM shipping_methods
+	WHERE enabled OR NOT $1
+	ORDER BY sort_order, name, code
+	`
+
+	methods := []models.ShippingMethod{}
+	if err := r.db.SelectContext(ctx, &methods, query, onlyEnabled); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении способов доставки")
+		return nil, fmt.Errorf("ошибка при получении способов доставки: %w", err)
+	}
+
+	return methods, nil
+}
+
+// GetShippingMethod возвращает способ доставки по коду
+func (r *PostgresRepository) GetShippingMethod(ctx context.Context, code string) (models.ShippingMethod, error) {
+	query := `SELECT ` + shippingMethodColumns + ` FROM shipping_methods WHERE code = $1`
+
+	var method models.ShippingMethod
+	if err := r.db.GetContext(ctx, &method, query, code); err != nil {
+		if err == sql.ErrNoRows {
+			return models.ShippingMethod{}, ErrShippingMethodNotFound
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении способа доставки %s", code)
+		return models.ShippingMethod{}, fmt.Errorf("ошибка при получении способа доставки: %w", err)
+	}
+
+	return method, nil
+}
+
+// SaveShippingMethod создает способ доставки или обновляет существующий с тем же кодом
+func (r *PostgresRepository) SaveShippingMethod(ctx context.Context, method *models.ShippingMethod) error {
+	query := `
+	INSERT INTO shipping_methods (code, name, kind, description, pickup_address, rules, sort_order, enabled,
+	                              created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+	ON CONFLICT (code) DO UPDATE
+	SET name = EXCLUDED.name,
+	    kind = EXCLUDED.kind,
+	    description = EXCLUDED.description,
+	    pickup_address = EXCLUDED.pickup_address,
+	    rules = EXCLUDED.rules,
+	    sort_order = EXCLUDED.sort_order,
+	    enabled = EXCLUDED.enabled,
+	    updated_at = NOW()
+	RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		method.Code,
+		method.Name,
+		method.Kind,
+		method.Description,
+		method.PickupAddress,
+		method.Rules,
+		method.SortOrder,
+		method.Enabled,
+	).Scan(&method.ID, &method.CreatedAt, &method.UpdatedAt)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при сохранении способа доставки %s", method.Code)
+		return fmt.Errorf("ошибка при сохранении способа доставки: %w", err)
+	}
+
+	return nil
+}
+
+// GetProductDimensions возвращает вес и габариты товаров; для товаров без данных значения пустые
+func (r *PostgresRepository) GetProductDimensions(ctx context.Context, productIDs []int64) (map[int64]models.ProductDimensions, error) {
+	query := `
+	SELECT id, weight_grams, length_mm, width_mm, height_mm
+	FROM products
+	WHERE id = ANY($1)
+	`
+
+	var rows []struct {
+		ID int64 `db:"id"`
+		models.ProductDimensions
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, pq.Array(productIDs)); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении веса и габаритов товаров")
+		return nil, fmt.Errorf("ошибка при получении веса и габаритов товаров: %w", err)
+	}
+
+	dimensions := make(map[int64]models.ProductDimensions, len(rows))
+	for _, row := range rows {
+		dimensions[row.ID] = row.ProductDimensions
+	}
+
+	return dimensions, nil
+}
+
+// SetProductDimensions задает вес и габариты товара; пустые значения сбрасывают их
+func (r *PostgresRepository) SetProductDimensions(ctx context.Context, productID int64, dimensions models.ProductDimensions) error {
+	query := `
+	UPDATE products SET weight_grams = $1, length_mm = $2, width_mm = $3, height_mm = $4, updated_at = NOW()
+	WHERE id = $5
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		dimensions.WeightGrams,
+		dimensions.LengthMM,
+		dimensions.WidthMM,
+		dimensions.HeightMM,
+		productID,
+	)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при изменении веса и габаритов товара ID=%d", productID)
+		return fmt.Errorf("ошибка при изменении веса и габаритов товара: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrProductNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"pryanik_studio/internal/models"
+)
+
+// SetFallbackChain задает функцию, возвращающую цепочку языков для поиска перевода.
+// Если функция не задана, переводы ищутся только на запрошенном языке
+func (r *PostgresRepository) SetFallbackChain(chain func(language string) []string) {
+	r.fallbackChain = chain
+}
+
+// languageChain возвращает цепочку языков для поиска перевода, начиная с запрошенного
+func (r *PostgresRepository) languageChain(language string) []string {
+	if r.fallbackChain == nil {
+		return []string{language}
+	}
+	return r.fallbackChain(language)
+}
+
+// markFallback отмечает поля, значения которых взяты не из запрошенного языка
+func markFallback(fields map[string]string, requested, used string, names ...string) map[string]string {
+	if used == "" || used == requested {
+		return fields
+	}
+	if fields == nil {
+		fields = make(map[string]string, len(names))
+	}
+	for _, name := range names {
+		fields[name] = used
+	}
+	return fields
+}
+
+// GetMissingTranslations возвращает товары, элементы галереи и категории без перевода на указанный язык
+func (r *PostgresRepository) GetMissingTranslations(ctx context.Context, language string) (models.MissingTranslationsReport, error) {
+	report := models.MissingTranslationsReport{
+		Language:     language,
+		Products:     []models.MissingTranslation{},
+		GalleryItems: []models.MissingTranslation{},
+		Categories:   []models.MissingTranslation{},
+	}
+
+	// Название берем по цепочке отката запрошенного языка, чтобы администратор мог узнать сущность
+	chain := pq.Array(r.languageChain(language))
+
+	query := `
+	SELECT p.id, COALESCE((
+		SELECT t.name FROM product_translations t
+		WHERE t.product_id = p.id
+		ORDER BY array_position($2::text[], t.language::text) NULLS LAST, t.language
+		LIMIT 1
+	), '') AS name
+	FROM products p
+	WHERE NOT EXISTS (
+		SELECT 1 FROM product_translations t WHERE t.product_id = p.id AND t.language = $1
+	)
+	ORDER BY p.id
+	`
+	if err := r.db.SelectContext(ctx, &report.Products, query, language, chain); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при поиске товаров без перевода на язык %s", language)
+		return report, fmt.Errorf("ошибка при поиске товаров без перевода: %w", err)
+	}
+
+	query = `
+	SELECT gi.id, COALESCE((
+		SELECT t.title FROM gallery_item_translations t
+		WHERE t.gallery_item_id = gi.id
+		ORDER BY array_position($2::text[], t.language::text) NULLS LAST, t.language
+		LIMIT 1
+	), '') AS name
+	FROM gallery_items gi
+	WHERE NOT EXISTS (
+		SELECT 1 FROM gallery_item_translations t WHERE t.gallery_item_id = gi.id AND t.language = $1
+	)
+	ORDER BY gi.id
+	`
+	if err := r.db.SelectContext(ctx, &report.GalleryItems, query, language, chain); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при поиске элементов галереи без перевода на язык %s", language)
+		return report, fmt.Errorf("ошибка при поиске элементов галереи без перевода: %w", err)
+	}
+
+	query = `
+	SELECT c.id, COALESCE((
+		SELECT t.name FROM category_translations t
+		WHERE t.category_id = c.id
+		ORDER BY array_position($2::text[], t.language::text) NULLS LAST, t.language
+		LIMIT 1
+	), '') AS name
+	FROM categories c
+	WHERE NOT EXISTS (
+		SELECT 1 FROM category_translations t WHERE t.category_id = c.id AND t.language = $1
+	)
+	ORDER BY c.id
+	`
+	if err := r.db.SelectContext(ctx, &report.Categories, query, language, chain); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при поиске категорий без перевода на язык %s", language)
+		return report, fmt.Errorf("ошибка при поиске категорий без перевода: %w", err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"pryanik_studio/internal/models"
+)
+
+// ErrUploadNotFound возвращается, если загруженный файл не найден
+var ErrUploadNotFound = errors.New("файл не найден")
+
+// CreateUpload сохраняет сведения о загруженном файле
+func (r *PostgresRepository) CreateUpload(ctx context.Context, upload *models.Upload) error {
+	upload.CreatedAt = time.Now()
+
+	query := `
+	INSERT INTO uploads (id, file_name, content_type, size, path, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		upload.ID,
+		upload.FileName,
+		upload.ContentType,
+		upload.Size,
+		upload.Path,
+		upload.CreatedAt,
+	)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при сохранении сведений о загруженном файле")
+		return fmt.Errorf("ошибка при сохранении сведений о файле: %w", err)
+	}
+
+	return nil
+}
+
+// GetUpload возвращает сведения о загруженном файле по ID
+func (r *PostgresRepository) GetUpload(ctx context.Context, id string) (models.Upload, error) {
+	var upload models.Upload
+
+	query := `SELECT id, file_name, content_type, size, path, created_at FROM uploads WHERE id = $1`
+	if err := r.db.GetContext(ctx, &upload, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return upload, ErrUploadNotFound
+		}
+		r.logger.WithError(err).Errorf("Ошибка при получении файла ID=%s", id)
+		return upload, fmt.Errorf("ошибка при получении файла: %w", err)
+	}
+
+	return upload, nil
+}
+
+// DeleteExpiredUploads удаляет файлы старше retention, на которые не ссылаются заказы, корзины,
+// запросы на расчет, действующие или использованные расчеты стоимости и отзывы, и возвращает их количество
+func (r *PostgresRepository) DeleteExpiredUploads(ctx context.Context, retention time.Duration) (int64, error) {
+	query := `
+	DELETE FROM uploads u
+	WHERE u.created_at < NOW() - make_interval(secs => $1)
+	  AND NOT EXISTS (SELECT 1 FROM quote_request_files f WHERE f.upload_id = u.id)
+	  AND NOT EXISTS (SELECT 1 FROM review_photos p WHERE p.upload_id = u.id)
+	  AND NOT EXISTS (
+		SELECT 1 FROM estimates e
+		WHERE e.upload_id = u.id AND (e.order_id IS NOT NULL OR e.expires_at > NOW())
+	  )
+	  -- Файлы персонализации хранятся в значениях позиций по ID загрузки
+	  AND NOT EXISTS (
+		SELECT 1 FROM order_items i
+		WHERE i.personalization @> jsonb_build_array(jsonb_build_object('value', u.id))
+	  )
+	  AND NOT EXISTS (
+		SELECT 1 FROM cart_items i
+		WHERE i.personalization @> jsonb_build_array(jsonb_build_object('value', u.id))
+	  )
+	RETURNING u.path
+	`
+
+	var paths []string
+	if err := r.db.SelectContext(ctx, &paths, query, int64(retention/time.Second)); err != nil {
+		r.logger.WithError(err).Error("Ошибка при удалении неиспользуемых файлов")
+		return 0, fmt.Errorf("ошибка при удалении неиспользуемых файлов: %w", err)
+	}
+
+	// Сведения о файлах уже удалены, поэтому ошибка удаления файла с диска только записывается в лог
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			r.logger.WithError(err).Warnf("Не удалось удалить файл %s", path)
+		}
+	}
+
+	return int64(len(paths)), nil
+}
@@ -0,0 +1,355 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"pryanik_studio/internal/models"
+)
+
+// ErrSKUConflict возвращается, если SKU варианта уже используется другим товаром
+var ErrSKUConflict = errors.New("SKU уже используется другим товаром")
+
+// GetProductVariants возвращает опции и варианты товара. Названия опций и значений
+// выбираются по цепочке отката языка. Если onlyEnabled, отключенные варианты не возвращаются
+func (r *PostgresRepository) GetProductVariants(ctx context.Context, productID int64, language string, onlyEnabled bool) ([]models.ProductOption, []models.ProductVariant, error) {
+	chain := pq.Array(r.languageChain(language))
+
+	// Получаем опции товара
+	var options []struct {
+		models.ProductOption
+		Language sql.NullString `db:"language"`
+	}
+
+	query := `
+	SELECT o.id, o.product_id, o.code, o.sort_order, COALESCE(ot.name, o.code) AS name, ot.language
+	FROM product_options o
+	LEFT JOIN LATERAL (
+		SELECT t.language, t.name FROM product_option_translations t
+		WHERE t.option_id = o.id AND t.language = ANY($2::text[])
+		ORDER BY array_position($2::text[], t.language::text)
+		LIMIT 1
+	) ot ON true
+	WHERE o.product_id = $1
+	ORDER BY o.sort_order, o.id
+	`
+
+	if err := r.db.SelectContext(ctx, &options, query, productID, chain); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении опций товара ID=%d", productID)
+		return nil, nil, fmt.Errorf("ошибка при получении опций товара: %w", err)
+	}
+
+	// Получаем значения опций
+	var values []struct {
+		models.ProductOptionValue
+		Language sql.NullString `db:"language"`
+	}
+
+	query = `
+	SELECT v.id, v.option_id, v.code, v.sort_order, COALESCE(vt.name, v.code) AS name, vt.language
+	FROM product_option_values v
+	JOIN product_options o ON o.id = v.option_id
+	LEFT JOIN LATERAL (
+		SELECT t.language, t.name FROM product_option_value_translations t
+		WHERE t.value_id = v.id AND t.language = ANY($2::text[])
+		ORDER BY array_position($2::text[], t.language::text)
+		LIMIT 1
+	) vt ON true
+	WHERE o.product_id = $1
+	ORDER BY v.sort_order, v.id
+	`
+
+	if err := r.db.SelectContext(ctx, &values, query, productID, chain); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении значений опций товара ID=%d", productID)
+		return nil, nil, fmt.Errorf("ошибка при получении значений опций товара: %w", err)
+	}
+
+	// Собираем опции со значениями и справочник кодов для вариантов
+	result := make([]models.ProductOption, 0, len(options))
+	optionIndex := make(map[int64]int, len(options))
+	for _, o := range options {
+		option := o.ProductOption
+		option.Values = []models.ProductOptionValue{}
+		option.FallbackFields = markFallback(nil, language, o.Language.String, "name")
+		optionIndex[option.ID] = len(result)
+		result = append(result, option)
+	}
+
+	type valueCodes struct{ option, value string }
+	codesByValue := make(map[int64]valueCodes, len(values))
+	for _, v := range values {
+		i, ok := optionIndex[v.OptionID]
+		if !ok {
+			continue
+		}
+		value := v.ProductOptionValue
+		value.FallbackFields = markFallback(nil, language, v.Language.String, "name")
+		result[i].Values = append(result[i].Values, value)
+		codesByValue[value.ID] = valueCodes{option: result[i].Code, value: value.Code}
+	}
+
+	// Получаем варианты товара
+	var variants []struct {
+		ID            int64         `db:"id"`
+		ProductID     int64         `db:"product_id"`
+		SKU           string        `db:"sku"`
+		PriceMode     string        `db:"price_mode"`
+		PriceValue    models.Money  `db:"price_value"`
+		PriceCurrency string        `db:"price_currency"`
+		Stock         sql.NullInt64 `db:"stock"`
+		Enabled       bool          `db:"enabled"`
+		CreatedAt     sql.NullTime  `db:"created_at"`
+		UpdatedAt     sql.NullTime  `db:"updated_at"`
+	}
+
+	query = `
+	SELECT id, product_id, sku, price_mode, price_value, price_currency, stock, enabled, created_at, updated_at
+	FROM product_variants
+	WHERE product_id = $1 AND (enabled OR NOT $2)
+	ORDER BY id
+	`
+
+	if err := r.db.SelectContext(ctx, &variants, query, productID, onlyEnabled); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении вариантов товара ID=%d", productID)
+		return nil, nil, fmt.Errorf("ошибка при получении вариантов товара: %w", err)
+	}
+
+	// Получаем значения опций, из которых состоят варианты
+	var variantValues []struct {
+		VariantID int64 `db:"variant_id"`
+		ValueID   int64 `db:"value_id"`
+	}
+
+	query = `
+	SELECT vv.variant_id, vv.value_id
+	FROM product_variant_values vv
+	JOIN product_variants pv ON pv.id = vv.variant_id
+	WHERE pv.product_id = $1
+	ORDER BY vv.variant_id, vv.value_id
+	`
+
+	if err := r.db.SelectContext(ctx, &variantValues, query, productID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении состава вариантов товара ID=%d", productID)
+		return nil, nil, fmt.Errorf("ошибка при получении состава вариантов товара: %w", err)
+	}
+
+	valuesByVariant := make(map[int64][]int64)
+	for _, vv := range variantValues {
+		valuesByVariant[vv.VariantID] = append(valuesByVariant[vv.VariantID], vv.ValueID)
+	}
+
+	resultVariants := make([]models.ProductVariant, 0, len(variants))
+	for _, v := range variants {
+		variant := models.ProductVariant{
+			ID:            v.ID,
+			ProductID:     v.ProductID,
+			SKU:           v.SKU,
+			PriceMode:     v.PriceMode,
+			PriceValue:    models.NewMoney(v.PriceValue.Amount, v.PriceCurrency),
+			PriceCurrency: v.PriceCurrency,
+			Enabled:       v.Enabled,
+			CreatedAt:     v.CreatedAt.Time,
+			UpdatedAt:     v.UpdatedAt.Time,
+			Options:       make(map[string]string),
+			ValueIDs:      []int64{},
+		}
+
+		if v.Stock.Valid {
+			stock := int(v.Stock.Int64)
+			variant.Stock = &stock
+		}
+
+		for _, valueID := range valuesByVariant[v.ID] {
+			codes, ok := codesByValue[valueID]
+			if !ok {
+				continue
+			}
+			variant.Options[codes.option] = codes.value
+			variant.ValueIDs = append(variant.ValueIDs, valueID)
+		}
+
+		resultVariants = append(resultVariants, variant)
+	}
+
+	return result, resultVariants, nil
+}
+
+// SaveProductVariants заменяет опции и варианты товара. Опции и значения сопоставляются
+// по коду, варианты - по SKU, поэтому идентификаторы существующих вариантов сохраняются.
+// Отсутствующие в запросе опции, значения и варианты удаляются
+func (r *PostgresRepository) SaveProductVariants(ctx context.Context, productID int64, request models.ProductVariantsRequest) error {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для сохранения вариантов товара")
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	// Добавляем отложенную функцию для отката транзакции в случае ошибки
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	// Идентификаторы значений: код опции -> код значения -> ID
+	valueIDs := make(map[string]map[string]int64, len(request.Options))
+	optionCodes := make([]string, 0, len(request.Options))
+
+	for i, option := range request.Options {
+		var optionID int64
+		query := `
+		INSERT INTO product_options (product_id, code, sort_order)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (product_id, code) DO UPDATE SET sort_order = EXCLUDED.sort_order
+		RETURNING id
+		`
+		if err = tx.GetContext(ctx, &optionID, query, productID, option.Code, i); err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при сохранении опции %s товара ID=%d", option.Code, productID)
+			return fmt.Errorf("ошибка при сохранении опции товара: %w", err)
+		}
+
+		if err = replaceTranslations(ctx, tx, "product_option_translations", "option_id", optionID, option.Translations); err != nil {
+			return err
+		}
+
+		valueIDs[option.Code] = make(map[string]int64, len(option.Values))
+		valueCodes := make([]string, 0, len(option.Values))
+
+		for j, value := range option.Values {
+			var valueID int64
+			query = `
+			INSERT INTO product_option_values (option_id, code, sort_order)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (option_id, code) DO UPDATE SET sort_order = EXCLUDED.sort_order
+			RETURNING id
+			`
+			if err = tx.GetContext(ctx, &valueID, query, optionID, value.Code, j); err != nil {
+				r.logger.WithError(err).Errorf("Ошибка при сохранении значения %s опции %s", value.Code, option.Code)
+				return fmt.Errorf("ошибка при сохранении значения опции: %w", err)
+			}
+
+			if err = replaceTranslations(ctx, tx, "product_option_value_translations", "value_id", valueID, value.Translations); err != nil {
+				return err
+			}
+
+			valueIDs[option.Code][value.Code] = valueID
+			valueCodes = append(valueCodes, value.Code)
+		}
+
+		// Удаляем значения опции, которых нет в запросе
+		query = `DELETE FROM product_option_values WHERE option_id = $1 AND NOT (code = ANY($2::text[]))`
+		if _, err = tx.ExecContext(ctx, query, optionID, pq.Array(valueCodes)); err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при удалении значений опции %s", option.Code)
+			return fmt.Errorf("ошибка при удалении значений опции: %w", err)
+		}
+
+		optionCodes = append(optionCodes, option.Code)
+	}
+
+	// Удаляем опции, которых нет в запросе
+	query := `DELETE FROM product_options WHERE product_id = $1 AND NOT (code = ANY($2::text[]))`
+	if _, err = tx.ExecContext(ctx, query, productID, pq.Array(optionCodes)); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при удалении опций товара ID=%d", productID)
+		return fmt.Errorf("ошибка при удалении опций товара: %w", err)
+	}
+
+	skus := make([]string, 0, len(request.Variants))
+	for _, variant := range request.Variants {
+		enabled := true
+		if variant.Enabled != nil {
+			enabled = *variant.Enabled
+		}
+
+		// SKU уникален среди всех товаров: чужой вариант не перезаписываем
+		var variantID int64
+		query = `
+		INSERT INTO product_variants (product_id, sku, price_mode, price_value, price_currency, stock, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		ON CONFLICT (sku) DO UPDATE
+		SET price_mode = EXCLUDED.price_mode,
+		    price_value = EXCLUDED.price_value,
+		    price_currency = EXCLUDED.price_currency,
+		    stock = EXCLUDED.stock,
+		    enabled = EXCLUDED.enabled,
+		    updated_at = NOW()
+		WHERE product_variants.product_id = EXCLUDED.product_id
+		RETURNING id
+		`
+		err = tx.GetContext(ctx, &variantID, query,
+			productID,
+			variant.SKU,
+			variant.PriceMode,
+			*variant.Price,
+			variant.Currency,
+			variant.Stock,
+			enabled,
+		)
+		if err == sql.ErrNoRows {
+			err = fmt.Errorf("%w: %s", ErrSKUConflict, variant.SKU)
+			return err
+		}
+		if err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при сохранении варианта %s товара ID=%d", variant.SKU, productID)
+			return fmt.Errorf("ошибка при сохранении варианта товара: %w", err)
+		}
+
+		// Перезаписываем состав варианта
+		if _, err = tx.ExecContext(ctx, `DELETE FROM product_variant_values WHERE variant_id = $1`, variantID); err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при очистке состава варианта %s", variant.SKU)
+			return fmt.Errorf("ошибка при очистке состава варианта: %w", err)
+		}
+
+		for optionCode, valueCode := range variant.Options {
+			valueID, ok := valueIDs[optionCode][valueCode]
+			if !ok {
+				err = fmt.Errorf("вариант %s ссылается на неизвестное значение %s.%s", variant.SKU, optionCode, valueCode)
+				return err
+			}
+			query = `INSERT INTO product_variant_values (variant_id, value_id) VALUES ($1, $2)`
+			if _, err = tx.ExecContext(ctx, query, variantID, valueID); err != nil {
+				r.logger.WithError(err).Errorf("Ошибка при сохранении состава варианта %s", variant.SKU)
+				return fmt.Errorf("ошибка при сохранении состава варианта: %w", err)
+			}
+		}
+
+		skus = append(skus, variant.SKU)
+	}
+
+	// Удаляем варианты, которых нет в запросе; в заказах остаются SKU и название варианта
+	query = `DELETE FROM product_variants WHERE product_id = $1 AND NOT (sku = ANY($2::text[]))`
+	if _, err = tx.ExecContext(ctx, query, productID, pq.Array(skus)); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при удалении вариантов товара ID=%d", productID)
+		return fmt.Errorf("ошибка при удалении вариантов товара: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
+
+// replaceTranslations заменяет переводы названия сущности в таблице переводов
+func replaceTranslations(ctx context.Context, tx *sqlx.Tx, table, idColumn string, id int64, names map[string]string) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE %s = $1`, table, idColumn)
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("ошибка при удалении переводов: %w", err)
+	}
+
+	query = fmt.Sprintf(`INSERT INTO %s (%s, language, name) VALUES ($1, $2, $3)`, table, idColumn)
+	for language, name := range names {
+		if _, err := tx.ExecContext(ctx, query, id, language, name); err != nil {
+			return fmt.Errorf("ошибка при добавлении перевода: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,373 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"pryanik_studio/internal/models"
+)
+
+// Ошибки работы со списками избранного
+var (
+	ErrWishlistNotFound     = errors.New("список избранного не найден")
+	ErrWishlistItemNotFound = errors.New("товара нет в списке избранного")
+)
+
+// wishlistColumns колонки списка избранного
+const wishlistColumns = `id, token_hash, customer_id, share_id, created_at, updated_at, expires_at`
+
+// CreateWishlist создает пустой список избранного без личного кабинета, действующий ttl с последнего изменения
+func (r *PostgresRepository) CreateWishlist(ctx context.Context, id, tokenHash string, ttl time.Duration) (models.Wishlist, error) {
+	var wishlist models.Wishlist
+
+	query := `
+	INSERT INTO wishlists (id, token_hash, created_at, updated_at, expires_at)
+	VALUES ($1, $2, NOW(), NOW(), NOW() + make_interval(secs => $3))
+	RETURNING ` + wishlistColumns
+	if err := r.db.GetContext(ctx, &wishlist, query, id, tokenHash, int64(ttl/time.Second)); err != nil {
+		r.logger.WithError(err).Error("Ошибка при создании списка избранного")
+		return wishlist, fmt.Errorf("ошибка при создании списка избранного: %w", err)
+	}
+
+	wishlist.Items = []models.WishlistItem{}
+	return wishlist, nil
+}
+
+// GetCustomerWishlist возвращает список избранного покупателя; если списка еще нет, он создается с ID id
+func (r *PostgresRepository) GetCustomerWishlist(ctx context.Context, customerID int64, id string) (models.Wishlist, error) {
+	var wishlist models.Wishlist
+
+	query := `
+	INSERT INTO wishlists (id, customer_id, created_at, updated_at)
+	VALUES ($1, $2, NOW(), NOW())
+	ON CONFLICT (customer_id) DO UPDATE SET customer_id = EXCLUDED.customer_id
+	RETURNING ` + wishlistColumns
+	if err := r.db.GetContext(ctx, &wishlist, query, id, customerID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении списка избранного покупателя ID=%d", customerID)
+		return wishlist, fmt.Errorf("ошибка при получении списка избранного: %w", err)
+	}
+
+	return wishlist, r.loadWishlistItems(ctx, &wishlist)
+}
+
+// GetWishlist возвращает действующий список избранного по ID
+func (r *PostgresRepository) GetWishlist(ctx context.Context, id string) (models.Wishlist, error) {
+	return r.getWishlist(ctx, `id = $1`, id)
+}
+
+// GetWishlistByToken возвращает действующий список избранного по хешу токена из cookie
+func (r *PostgresRepository) GetWishlistByToken(ctx context.Context, tokenHash string) (models.Wishlist, error) {
+	return r.getWishlist(ctx, `token_hash = $1`, tokenHash)
+}
+
+// GetWishlistByShareID возвращает действующий список избранного, открытый по публичной ссылке
+func (r *PostgresRepository) GetWishlistByShareID(ctx context.Context, shareID string) (models.Wishlist, error) {
+	return r.getWishlist(ctx, `share_id = $1`, shareID)
+}
+
+// getWishlist возвращает действующий список избранного по условию condition с товарами в порядке добавления
+func (r *PostgresRepository) getWishlist(ctx context.Context, condition string, arg interface{}) (models.Wishlist, error) {
+	var wishlist models.Wishlist
+
+	query := `
+	SELECT ` + wishlistColumns + ` FROM wishlists
+	WHERE ` + condition + ` AND (expires_at IS NULL OR expires_at > NOW())
+	`
+	if err := r.db.GetContext(ctx, &wishlist, query, arg); err != nil {
+		if err == sql.ErrNoRows {
+			return wishlist, ErrWishlistNotFound
+		}
+		r.logger.WithError(err).Error("Ошибка при получении списка избранного")
+		return wishlist, fmt.Errorf("ошибка при получении списка избранного: %w", err)
+	}
+
+	return wishlist, r.loadWishlistItems(ctx, &wishlist)
+}
+
+// loadWishlistItems загружает товары списка избранного в порядке добавления
+func (r *PostgresRepository) loadWishlistItems(ctx context.Context, wishlist *models.Wishlist) error {
+	query := `
+	SELECT wishlist_id, product_id, language, notify_price_drop, created_at
+	FROM wishlist_items
+	WHERE wishlist_id = $1
+	ORDER BY created_at, product_id
+	`
+	wishlist.Items = []models.WishlistItem{}
+	if err := r.db.SelectContext(ctx, &wishlist.Items, query, wishlist.ID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении товаров списка избранного %s", wishlist.ID)
+		return fmt.Errorf("ошибка при получении товаров списка избранного: %w", err)
+	}
+	return nil
+}
+
+// AddWishlistItem добавляет товар в список избранного или изменяет подписку на снижение цены,
+// если товар уже есть в списке
+func (r *PostgresRepository) AddWishlistItem(ctx context.Context, wishlistID string, productID int64, language string, notify bool, ttl time.Duration) error {
+	return r.updateWishlist(ctx, wishlistID, ttl, func(tx *sqlx.Tx) error {
+		query := `
+		INSERT INTO wishlist_items (wishlist_id, product_id, language, notify_price_drop, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (wishlist_id, product_id) DO UPDATE
+		SET language = EXCLUDED.language, notify_price_drop = EXCLUDED.notify_price_drop
+		`
+		if _, err := tx.ExecContext(ctx, query, wishlistID, productID, language, notify); err != nil {
+			return fmt.Errorf("ошибка при добавлении товара в список избранного: %w", err)
+		}
+		return r.watchPrice(ctx, tx, wishlistID, productID, language)
+	})
+}
+
+// UpdateWishlistItem изменяет подписку на снижение цены товара из списка избранного
+func (r *PostgresRepository) UpdateWishlistItem(ctx context.Context, wishlistID string, productID int64, language string, notify bool, ttl time.Duration) error {
+	return r.updateWishlist(ctx, wishlistID, ttl, func(tx *sqlx.Tx) error {
+		query := `
+		UPDATE wishlist_items SET language = $3, notify_price_drop = $4
+		WHERE wishlist_id = $1 AND product_id = $2
+		`
+		result, err := tx.ExecContext(ctx, query, wishlistID, productID, language, notify)
+		if err != nil {
+			return fmt.Errorf("ошибка при изменении товара в списке избранного: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return ErrWishlistItemNotFound
+		}
+		return r.watchPrice(ctx, tx, wishlistID, productID, language)
+	})
+}
+
+// watchPrice запоминает текущую цену товара из списка избранного: уведомление о снижении цены
+// отправляется, когда цена станет ниже запомненной. Цена берется из перевода по цепочке отката языка
+func (r *PostgresRepository) watchPrice(ctx context.Context, tx *sqlx.Tx, wishlistID string, productID int64, language string) error {
+	query := `
+	UPDATE wishlist_items SET (price_language, watched_price, currency) = (
+		SELECT t.language, t.price, t.currency
+		FROM product_translations t
+		WHERE t.product_id = $2 AND t.language = ANY($3::text[])
+		ORDER BY array_position($3::text[], t.language::text)
+		LIMIT 1
+	)
+	WHERE wishlist_id = $1 AND product_id = $2
+	`
+	if _, err := tx.ExecContext(ctx, query, wishlistID, productID, pq.Array(r.languageChain(language))); err != nil {
+		return fmt.Errorf("ошибка при сохранении цены товара из списка избранного: %w", err)
+	}
+	return nil
+}
+
+// DeleteWishlistItem удаляет товар из списка избранного
+func (r *PostgresRepository) DeleteWishlistItem(ctx context.Context, wishlistID string, productID int64, ttl time.Duration) error {
+	return r.updateWishlist(ctx, wishlistID, ttl, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM wishlist_items WHERE wishlist_id = $1 AND product_id = $2`, wishlistID, productID)
+		if err != nil {
+			return fmt.Errorf("ошибка при удалении товара из списка избранного: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return ErrWishlistItemNotFound
+		}
+		return nil
+	})
+}
+
+// updateWishlist выполняет изменение списка избранного в транзакции и отмечает время изменения.
+// Срок хранения списка без личного кабинета продлевается на ttl
+func (r *PostgresRepository) updateWishlist(ctx context.Context, wishlistID string, ttl time.Duration, update func(tx *sqlx.Tx) error) error {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, `
+	UPDATE wishlists SET updated_at = NOW(),
+		expires_at = CASE WHEN expires_at IS NULL THEN NULL ELSE NOW() + make_interval(secs => $2) END
+	WHERE id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+	`, wishlistID, int64(ttl/time.Second))
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при продлении списка избранного %s", wishlistID)
+		return fmt.Errorf("ошибка при продлении списка избранного: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		err = ErrWishlistNotFound
+		return err
+	}
+
+	if err = update(tx); err != nil {
+		if !errors.Is(err, ErrWishlistItemNotFound) {
+			r.logger.WithError(err).Errorf("Ошибка при изменении списка избранного %s", wishlistID)
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// MergeWishlists переносит товары из списка fromID в список toID и удаляет список fromID.
+// Если товар есть в обоих списках, подписка на снижение цены сохраняется, если она была в любом из них.
+// Новые для toID товары переносятся в порядке добавления, пока в списке не больше maxItems товаров;
+// остальные пропускаются
+func (r *PostgresRepository) MergeWishlists(ctx context.Context, fromID, toID string, maxItems int) error {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	// Блокируем список toID, чтобы параллельное объединение не превысило ограничение
+	if _, err = tx.ExecContext(ctx, `UPDATE wishlists SET updated_at = NOW() WHERE id = $1`, toID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при изменении списка избранного %s", toID)
+		return fmt.Errorf("ошибка при объединении списков избранного: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO wishlist_items (wishlist_id, product_id, language, notify_price_drop, price_language, watched_price, currency, created_at)
+	SELECT $2, f.product_id, f.language, f.notify_price_drop, f.price_language, f.watched_price, f.currency, f.created_at
+	FROM wishlist_items f
+	WHERE f.wishlist_id = $1
+	  AND (
+		EXISTS (SELECT 1 FROM wishlist_items t WHERE t.wishlist_id = $2 AND t.product_id = f.product_id)
+		OR f.product_id IN (
+			SELECT n.product_id FROM wishlist_items n
+			WHERE n.wishlist_id = $1
+			  AND NOT EXISTS (SELECT 1 FROM wishlist_items t WHERE t.wishlist_id = $2 AND t.product_id = n.product_id)
+			ORDER BY n.created_at, n.product_id
+			LIMIT GREATEST($3 - (SELECT COUNT(*) FROM wishlist_items WHERE wishlist_id = $2), 0)
+		)
+	  )
+	ON CONFLICT (wishlist_id, product_id) DO UPDATE
+	SET notify_price_drop = TRUE, price_language = EXCLUDED.price_language,
+		watched_price = EXCLUDED.watched_price, currency = EXCLUDED.currency
+	WHERE NOT wishlist_items.notify_price_drop AND EXCLUDED.notify_price_drop
+	`, fromID, toID, maxItems)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при переносе товаров из списка избранного %s в %s", fromID, toID)
+		return fmt.Errorf("ошибка при объединении списков избранного: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM wishlists WHERE id = $1`, fromID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при удалении списка избранного %s", fromID)
+		return fmt.Errorf("ошибка при объединении списков избранного: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// SetWishlistShareID открывает список избранного по публичной ссылке shareID или закрывает его, если shareID nil
+func (r *PostgresRepository) SetWishlistShareID(ctx context.Context, wishlistID string, shareID *string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE wishlists SET share_id = $2 WHERE id = $1`, wishlistID, shareID)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при изменении публичной ссылки на список избранного %s", wishlistID)
+		return fmt.Errorf("ошибка при изменении публичной ссылки на список избранного: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrWishlistNotFound
+	}
+	return nil
+}
+
+// DeleteExpiredWishlists удаляет истекшие списки избранного без личного кабинета и возвращает их количество
+func (r *PostgresRepository) DeleteExpiredWishlists(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM wishlists WHERE expires_at <= NOW()`)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при удалении истекших списков избранного")
+		return 0, fmt.Errorf("ошибка при удалении истекших списков избранного: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при получении количества удаленных списков избранного: %w", err)
+	}
+	return deleted, nil
+}
+
+// RebaseWatchedPrices запоминает новую цену товаров с подпиской на снижение цены, если цена выросла
+// или изменилась валюта перевода: следующее уведомление отправляется при снижении от новой цены
+func (r *PostgresRepository) RebaseWatchedPrices(ctx context.Context) error {
+	query := `
+	UPDATE wishlist_items wi SET watched_price = t.price, currency = t.currency
+	FROM product_translations t
+	WHERE wi.notify_price_drop
+	  AND t.product_id = wi.product_id AND t.language = wi.price_language
+	  AND (wi.watched_price IS NULL OR t.currency <> wi.currency OR t.price > wi.watched_price)
+	`
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		r.logger.WithError(err).Error("Ошибка при обновлении отслеживаемых цен")
+		return fmt.Errorf("ошибка при обновлении отслеживаемых цен: %w", err)
+	}
+	return nil
+}
+
+// ClaimPriceDrops выбирает до limit товаров из списков избранного покупателей, цена которых
+// стала ниже запомненной, и запоминает новую цену. Товары, снятые с продажи, и товары,
+// выбранные другим экземпляром сервера, пропускаются
+func (r *PostgresRepository) ClaimPriceDrops(ctx context.Context, limit int) ([]models.PriceDrop, error) {
+	query := `
+	UPDATE wishlist_items wi SET watched_price = d.new_price
+	FROM (
+		SELECT i.wishlist_id, i.product_id, i.watched_price AS old_price, t.price AS new_price,
+		       c.id AS customer_id, c.email, c.name
+		FROM wishlist_items i
+		JOIN wishlists w ON w.id = i.wishlist_id
+		JOIN customers c ON c.id = w.customer_id
+		JOIN products p ON p.id = i.product_id AND p.published
+		JOIN product_translations t
+		  ON t.product_id = i.product_id AND t.language = i.price_language AND t.currency = i.currency
+		WHERE i.notify_price_drop AND t.price < i.watched_price
+		ORDER BY i.wishlist_id, i.product_id
+		LIMIT $1
+		FOR UPDATE OF i SKIP LOCKED
+	) d
+	WHERE wi.wishlist_id = d.wishlist_id AND wi.product_id = d.product_id
+	RETURNING wi.wishlist_id, wi.product_id, d.customer_id, d.email, d.name, wi.language, wi.price_language,
+	          d.old_price, d.new_price, wi.currency
+	`
+
+	drops := []models.PriceDrop{}
+	if err := r.db.SelectContext(ctx, &drops, query, limit); err != nil {
+		r.logger.WithError(err).Error("Ошибка при выборе снижений цен для уведомлений")
+		return nil, fmt.Errorf("ошибка при выборе снижений цен для уведомлений: %w", err)
+	}
+	for i := range drops {
+		drop := &drops[i]
+		drop.OldPrice = models.NewMoney(drop.OldPrice.Amount, drop.Currency)
+		drop.NewPrice = models.NewMoney(drop.NewPrice.Amount, drop.Currency)
+	}
+	return drops, nil
+}
+
+// ReleasePriceDrop возвращает запомненную цену товара, если уведомление о снижении отправить не удалось;
+// уведомление будет отправлено при следующей проверке
+func (r *PostgresRepository) ReleasePriceDrop(ctx context.Context, drop models.PriceDrop) error {
+	query := `
+	UPDATE wishlist_items SET watched_price = $3
+	WHERE wishlist_id = $1 AND product_id = $2 AND watched_price = $4
+	`
+	if _, err := r.db.ExecContext(ctx, query, drop.WishlistID, drop.ProductID, drop.OldPrice, drop.NewPrice); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при возврате цены товара ID=%d в списке избранного %s", drop.ProductID, drop.WishlistID)
+		return fmt.Errorf("ошибка при возврате отслеживаемой цены: %w", err)
+	}
+	return nil
+}
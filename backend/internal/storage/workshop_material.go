@@ -0,0 +1,369 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"pryanik_studio/internal/models"
+)
+
+var (
+	// ErrWorkshopMaterialNotFound возвращается, если материал мастерской не найден
+	ErrWorkshopMaterialNotFound = errors.New("материал мастерской не найден")
+
+	// ErrBOMVariant возвращается, если вариант в спецификации не принадлежит товару
+	ErrBOMVariant = errors.New("вариант не принадлежит товару")
+)
+
+// workshopMaterialColumns колонки, которые выбираются для материала мастерской
+const workshopMaterialColumns = `
+	m.id, m.code, m.name, m.unit, m.quantity, m.reorder_threshold, m.created_at, m.updated_at
+`
+
+// orderMaterialNeeds потребность позиций заказов в материалах по спецификациям.
+// Для позиции с вариантом берется спецификация варианта, а если ее нет - спецификация товара
+const orderMaterialNeeds = `
+	SELECT oi.order_id, b.material_id, b.quantity * oi.quantity AS quantity
+	FROM order_items oi
+	JOIN bill_of_materials b ON b.product_id = oi.product_id AND (
+		b.variant_id = oi.variant_id OR (
+			b.variant_id IS NULL AND NOT EXISTS (
+				SELECT 1 FROM bill_of_materials vb
+				WHERE vb.product_id = oi.product_id AND vb.variant_id = oi.variant_id
+			)
+		)
+	)
+`
+
+// GetWorkshopMaterials возвращает материалы мастерской, отсортированные по названию
+func (r *PostgresRepository) GetWorkshopMaterials(ctx context.Context) ([]models.WorkshopMaterial, error) {
+	query := `SELECT ` + workshopMaterialColumns + ` FROM workshop_materials m ORDER BY m.name, m.code`
+
+	var materials []models.WorkshopMaterial
+	if err := r.db.SelectContext(ctx, &materials, query); err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении материалов мастерской")
+		return nil, fmt.Errorf("ошибка при получении материалов мастерской: %w", err)
+	}
+
+	return materials, nil
+}
+
+// SaveWorkshopMaterial создает материал мастерской или обновляет существующий с тем же кодом.
+// Остаток задается только при создании и записывается начальной корректировкой
+func (r *PostgresRepository) SaveWorkshopMaterial(ctx context.Context, material *models.WorkshopMaterial) error {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для сохранения материала мастерской")
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	query := `
+	INSERT INTO workshop_materials (code, name, unit, quantity, reorder_threshold, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+	ON CONFLICT (code) DO UPDATE
+	SET name = EXCLUDED.name,
+	    unit = EXCLUDED.unit,
+	    reorder_threshold = EXCLUDED.reorder_threshold,
+	    updated_at = NOW()
+	RETURNING id, quantity, created_at, updated_at, xmax = 0 AS inserted
+	`
+
+	var inserted bool
+	err = tx.QueryRowContext(ctx, query,
+		material.Code,
+		material.Name,
+		material.Unit,
+		material.Quantity,
+		material.ReorderThreshold,
+	).Scan(&material.ID, &material.Quantity, &material.CreatedAt, &material.UpdatedAt, &inserted)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при сохранении материала мастерской %s", material.Code)
+		return fmt.Errorf("ошибка при сохранении материала мастерской: %w", err)
+	}
+
+	if inserted && material.Quantity != 0 {
+		if err = insertMaterialMovement(ctx, tx, material.ID, material.Quantity,
+			models.MaterialMovementAdjustment, nil, "Начальный остаток"); err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при записи начального остатка материала %s", material.Code)
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
+
+// AdjustWorkshopMaterial изменяет остаток материала на delta и записывает корректировку
+func (r *PostgresRepository) AdjustWorkshopMaterial(ctx context.Context, id int64, delta float64, comment string) (models.WorkshopMaterial, error) {
+	var material models.WorkshopMaterial
+
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для корректировки материала")
+		return material, fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	query := `
+	UPDATE workshop_materials m SET quantity = quantity + $1, updated_at = NOW()
+	WHERE id = $2
+	RETURNING ` + workshopMaterialColumns
+	if err = tx.GetContext(ctx, &material, query, delta, id); err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrWorkshopMaterialNotFound
+			return material, err
+		}
+		r.logger.WithError(err).Errorf("Ошибка при корректировке материала ID=%d", id)
+		return material, fmt.Errorf("ошибка при корректировке материала: %w", err)
+	}
+
+	if err = insertMaterialMovement(ctx, tx, id, delta, models.MaterialMovementAdjustment, nil, comment); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при записи корректировки материала ID=%d", id)
+		return material, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return material, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return material, nil
+}
+
+// GetMaterialMovements возвращает последние движения материала, новые первыми
+func (r *PostgresRepository) GetMaterialMovements(ctx context.Context, materialID int64, limit int) ([]models.MaterialMovement, error) {
+	var exists bool
+	if err := r.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM workshop_materials WHERE id = $1)`, materialID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении материала ID=%d", materialID)
+		return nil, fmt.Errorf("ошибка при получении материала: %w", err)
+	}
+	if !exists {
+		return nil, ErrWorkshopMaterialNotFound
+	}
+
+	query := `
+	SELECT id, material_id, quantity, reason, order_id, comment, created_at
+	FROM material_movements
+	WHERE material_id = $1
+	ORDER BY created_at DESC, id DESC
+	LIMIT $2
+	`
+
+	movements := []models.MaterialMovement{}
+	if err := r.db.SelectContext(ctx, &movements, query, materialID, limit); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении движений материала ID=%d", materialID)
+		return nil, fmt.Errorf("ошибка при получении движений материала: %w", err)
+	}
+
+	return movements, nil
+}
+
+// GetBOM возвращает спецификацию товара: сначала строки товара, затем строки вариантов
+func (r *PostgresRepository) GetBOM(ctx context.Context, productID int64) ([]models.BOMItem, error) {
+	query := `
+	SELECT b.variant_id, b.material_id, m.code AS material_code, m.name AS material_name, m.unit, b.quantity
+	FROM bill_of_materials b
+	JOIN workshop_materials m ON m.id = b.material_id
+	WHERE b.product_id = $1
+	ORDER BY b.variant_id NULLS FIRST, m.name, b.id
+	`
+
+	items := []models.BOMItem{}
+	if err := r.db.SelectContext(ctx, &items, query, productID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при получении спецификации товара ID=%d", productID)
+		return nil, fmt.Errorf("ошибка при получении спецификации товара: %w", err)
+	}
+
+	return items, nil
+}
+
+// SaveBOM заменяет спецификацию товара
+func (r *PostgresRepository) SaveBOM(ctx context.Context, productID int64, items []models.BOMItem) error {
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при начале транзакции для сохранения спецификации")
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.logger.WithError(rollbackErr).Error("Ошибка при откате транзакции")
+			}
+		}
+	}()
+
+	var exists bool
+	if err = tx.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM products WHERE id = $1)`, productID); err != nil {
+		return fmt.Errorf("ошибка при получении товара: %w", err)
+	}
+	if !exists {
+		err = ErrProductNotFound
+		return err
+	}
+
+	var variantIDs, materialIDs []int64
+	if err = tx.SelectContext(ctx, &variantIDs, `SELECT id FROM product_variants WHERE product_id = $1`, productID); err != nil {
+		return fmt.Errorf("ошибка при получении вариантов товара: %w", err)
+	}
+	if err = tx.SelectContext(ctx, &materialIDs, `SELECT id FROM workshop_materials`); err != nil {
+		return fmt.Errorf("ошибка при получении материалов мастерской: %w", err)
+	}
+
+	for _, item := range items {
+		if item.VariantID != nil && !containsID(variantIDs, *item.VariantID) {
+			err = fmt.Errorf("%w: вариант ID=%d", ErrBOMVariant, *item.VariantID)
+			return err
+		}
+		if !containsID(materialIDs, item.MaterialID) {
+			err = fmt.Errorf("%w: ID=%d", ErrWorkshopMaterialNotFound, item.MaterialID)
+			return err
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM bill_of_materials WHERE product_id = $1`, productID); err != nil {
+		r.logger.WithError(err).Errorf("Ошибка при удалении спецификации товара ID=%d", productID)
+		return fmt.Errorf("ошибка при удалении спецификации: %w", err)
+	}
+
+	for _, item := range items {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO bill_of_materials (product_id, variant_id, material_id, quantity) VALUES ($1, $2, $3, $4)`,
+			productID, item.VariantID, item.MaterialID, item.Quantity,
+		)
+		if err != nil {
+			r.logger.WithError(err).Errorf("Ошибка при сохранении спецификации товара ID=%d", productID)
+			return fmt.Errorf("ошибка при сохранении спецификации: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.logger.WithError(err).Error("Ошибка при фиксации транзакции")
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
+
+// GetMaterialShortages возвращает материалы, которых не хватит на открытые заказы
+// или остаток которых после их выполнения опустится ниже порога дозаказа
+func (r *PostgresRepository) GetMaterialShortages(ctx context.Context) ([]models.MaterialShortage, error) {
+	query := `
+	SELECT ` + workshopMaterialColumns + `,
+	       s.demand, s.projected,
+	       GREATEST(-s.projected, 0) AS shortage,
+	       GREATEST(m.reorder_threshold - s.projected, 0) AS reorder_quantity
+	FROM workshop_materials m
+	LEFT JOIN (
+		SELECT n.material_id, SUM(n.quantity) AS demand
+		FROM (` + orderMaterialNeeds + `) n
+		JOIN orders o ON o.id = n.order_id
+		WHERE o.status NOT IN ($1, $2) AND o.materials_consumed_at IS NULL
+		GROUP BY n.material_id
+	) d ON d.material_id = m.id
+	CROSS JOIN LATERAL (
+		SELECT COALESCE(d.demand, 0) AS demand, m.quantity - COALESCE(d.demand, 0) AS projected
+	) s
+	WHERE s.projected < m.reorder_threshold OR s.projected < 0
+	ORDER BY shortage DESC, reorder_quantity DESC, m.name
+	`
+
+	shortages := []models.MaterialShortage{}
+	err := r.db.SelectContext(ctx, &shortages, query, models.OrderStatusCompleted, models.OrderStatusCancelled)
+	if err != nil {
+		r.logger.WithError(err).Error("Ошибка при получении отчета о нехватке материалов")
+		return nil, fmt.Errorf("ошибка при получении отчета о нехватке материалов: %w", err)
+	}
+
+	return shortages, nil
+}
+
+// consumeMaterials списывает материалы заказа по спецификациям, если они еще не списаны.
+// Остаток может уйти в минус: производство не останавливается, нехватка видна в отчете
+func consumeMaterials(ctx context.Context, tx *sqlx.Tx, orderID int64) error {
+	var consumed bool
+	query := `SELECT materials_consumed_at IS NOT NULL FROM orders WHERE id = $1`
+	if err := tx.GetContext(ctx, &consumed, query, orderID); err != nil {
+		return fmt.Errorf("ошибка при проверке списания материалов: %w", err)
+	}
+	if consumed {
+		return nil
+	}
+
+	var needs []struct {
+		MaterialID int64   `db:"material_id"`
+		Quantity   float64 `db:"quantity"`
+	}
+	query = `
+	SELECT n.material_id, SUM(n.quantity) AS quantity
+	FROM (` + orderMaterialNeeds + `) n
+	WHERE n.order_id = $1
+	GROUP BY n.material_id
+	ORDER BY n.material_id
+	`
+	if err := tx.SelectContext(ctx, &needs, query, orderID); err != nil {
+		return fmt.Errorf("ошибка при расчете потребности в материалах: %w", err)
+	}
+
+	for _, need := range needs {
+		query := `UPDATE workshop_materials SET quantity = quantity - $1, updated_at = NOW() WHERE id = $2`
+		if _, err := tx.ExecContext(ctx, query, need.Quantity, need.MaterialID); err != nil {
+			return fmt.Errorf("ошибка при списании материала: %w", err)
+		}
+		if err := insertMaterialMovement(ctx, tx, need.MaterialID, -need.Quantity,
+			models.MaterialMovementConsumption, &orderID, ""); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE orders SET materials_consumed_at = NOW() WHERE id = $1`, orderID); err != nil {
+		return fmt.Errorf("ошибка при списании материалов: %w", err)
+	}
+
+	return nil
+}
+
+// insertMaterialMovement записывает движение материала
+func insertMaterialMovement(ctx context.Context, tx *sqlx.Tx, materialID int64, quantity float64, reason string, orderID *int64, comment string) error {
+	query := `
+	INSERT INTO material_movements (material_id, quantity, reason, order_id, comment, created_at)
+	VALUES ($1, $2, $3, $4, $5, NOW())
+	`
+	if _, err := tx.ExecContext(ctx, query, materialID, quantity, reason, orderID, comment); err != nil {
+		return fmt.Errorf("ошибка при записи движения материала: %w", err)
+	}
+	return nil
+}
+
+// containsID проверяет, содержится ли ID в списке
+func containsID(ids []int64, id int64) bool {
+	for _, value := range ids {
+		if value == id {
+			return true
+		}
+	}
+	return false
+}
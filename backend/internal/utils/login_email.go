@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+
+	gomail "gopkg.in/gomail.v2"
+
+	"pryanik_studio/internal/models"
+)
+
+// SendLoginLink отправляет покупателю ссылку для входа в личный кабинет
+func (s *GomailSender) SendLoginLink(link *models.LoginLink) error {
+	return s.sendEmails([]*gomail.Message{
+		s.newMessage(link.Email, loginLinkEmail(link)),
+	})
+}
+
+// SendLoginLink отправляет покупателю ссылку для входа в личный кабинет
+func (s *SendGridSender) SendLoginLink(link *models.LoginLink) error {
+	content := loginLinkEmail(link)
+	if err := s.service.SendEmail(link.Email, content.Subject, content.HTML, content.Text); err != nil {
+		s.logger.WithError(err).Error("Ошибка отправки ссылки для входа")
+		return err
+	}
+
+	s.logger.Info("Ссылка для входа отправлена")
+	return nil
+}
+
+// loginLinkEmail письмо покупателю с одноразовой ссылкой для входа
+func loginLinkEmail(link *models.LoginLink) emailContent {
+	url := html.EscapeString(link.URL)
+
+	switch link.Language {
+	case "en":
+		expires := link.ExpiresAt.Format("01/02/2006 15:04")
+		return emailContent{
+			Subject: "Sign in to Prianik Studio",
+			HTML: fmt.Sprintf(`
+<h2>Sign in to your account</h2>
+<p><a href="%s">Sign in</a></p>
+<p>The link can be used once and is valid until %s. If you did not request it, just ignore this email.</p>
+<p>Best regards,<br><strong>Prianik Studio Team</strong></p>
+	`, url, expires),
+			Text: fmt.Sprintf("Sign in to your account: %s\nThe link can be used once and is valid until %s. If you did not request it, just ignore this email.",
+				link.URL, expires),
+		}
+	case "es":
+		expires := link.ExpiresAt.Format("02/01/2006 15:04")
+		return emailContent{
+			Subject: "Iniciar sesión en Prianik Studio",
+			HTML: fmt.Sprintf(`
+<h2>Inicie sesión en su cuenta</h2>
+<p><a href="%s">Iniciar sesión</a></p>
+<p>El enlace se puede usar una sola vez y es válido hasta el %s. Si no lo ha solicitado, ignore este correo.</p>
+<p>Atentamente,<br><strong>Equipo de Prianik Studio</strong></p>
+	`, url, expires),
+			Text: fmt.Sprintf("Inicie sesión en su cuenta: %s\nEl enlace se puede usar una sola vez y es válido hasta el %s. Si no lo ha solicitado, ignore este correo.",
+				link.URL, expires),
+		}
+	default:
+		expires := link.ExpiresAt.Format("02.01.2006 15:04")
+		return emailContent{
+			Subject: "Вход в личный кабинет Prianik Studio",
+			HTML: fmt.Sprintf(`
+<h2>Вход в личный кабинет</h2>
+<p><a href="%s">Войти</a></p>
+<p>Ссылка одноразовая и действует до %s. Если вы не запрашивали вход, просто проигнорируйте это письмо.</p>
+<p>С уважением,<br><strong>Команда Prianik Studio</strong></p>
+	`, url, expires),
+			Text: fmt.Sprintf("Вход в личный кабинет: %s\nСсылка одноразовая и действует до %s. Если вы не запрашивали вход, просто проигнорируйте это письмо.",
+				link.URL, expires),
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+
+	gomail "gopkg.in/gomail.v2"
+
+	"pryanik_studio/internal/models"
+)
+
+// SendPaymentAnomalyAlert отправляет компании уведомление об аномалии оплаты
+func (s *GomailSender) SendPaymentAnomalyAlert(payment *models.Payment) error {
+	return s.sendEmails([]*gomail.Message{
+		s.newMessage(s.config.CompanyEmail, paymentAnomalyEmail(payment)),
+	})
+}
+
+// SendPaymentAnomalyAlert отправляет компании уведомление об аномалии оплаты
+func (s *SendGridSender) SendPaymentAnomalyAlert(payment *models.Payment) error {
+	content := paymentAnomalyEmail(payment)
+	if err := s.service.SendEmail(s.config.CompanyEmail, content.Subject, content.HTML, content.Text); err != nil {
+		s.logger.WithError(err).Error("Ошибка отправки уведомления об аномалии оплаты")
+		return err
+	}
+
+	s.logger.WithField("payment_id", payment.ID).Info("Уведомление об аномалии оплаты отправлено")
+	return nil
+}
+
+// paymentAnomalyEmail письмо компании о платеже, который получен, но заказом не ожидался
+func paymentAnomalyEmail(payment *models.Payment) emailContent {
+	reference := payment.ProviderReference
+	if reference == "" {
+		reference = payment.ProviderPaymentID
+	}
+
+	return emailContent{
+		Subject: fmt.Sprintf("Аномалия оплаты заказа №%d", payment.OrderID),
+		HTML: fmt.Sprintf(`
+<h2>Аномалия оплаты заказа №%d</h2>
+<p><strong>%s.</strong> Деньги получены, проверьте заказ и при необходимости выполните возврат.</p>
+<p><strong>Платеж:</strong> ID %d (%s, %s)</p>
+<p><strong>Сумма:</strong> %s</p>
+	`, payment.OrderID, html.EscapeString(payment.Anomaly), payment.ID,
+			html.EscapeString(payment.Provider), html.EscapeString(reference), FormatCurrency(payment.Amount)),
+		Text: fmt.Sprintf("%s (заказ №%d).\nДеньги получены, проверьте заказ и при необходимости выполните возврат.\n"+
+			"Платеж: ID %d (%s, %s)\nСумма: %s\n",
+			payment.Anomaly, payment.OrderID, payment.ID, payment.Provider, reference, FormatCurrency(payment.Amount)),
+	}
+}
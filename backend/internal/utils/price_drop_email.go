@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	gomail "gopkg.in/gomail.v2"
+
+	"pryanik_studio/internal/models"
+)
+
+// SendPriceDropAlert отправляет покупателю уведомление о снижении цен на товары из избранного
+func (s *GomailSender) SendPriceDropAlert(alert *models.PriceDropAlert) error {
+	return s.sendEmails([]*gomail.Message{s.newMessage(alert.Email, priceDropEmail(alert))})
+}
+
+// SendPriceDropAlert отправляет покупателю уведомление о снижении цен на товары из избранного
+func (s *SendGridSender) SendPriceDropAlert(alert *models.PriceDropAlert) error {
+	content := priceDropEmail(alert)
+	if err := s.service.SendEmail(alert.Email, content.Subject, content.HTML, content.Text); err != nil {
+		s.logger.WithError(err).Error("Ошибка отправки уведомления о снижении цен")
+		return err
+	}
+
+	s.logger.WithField("items", len(alert.Items)).Info("Уведомление о снижении цен отправлено")
+	return nil
+}
+
+// priceDropEmail письмо покупателю с подешевевшими товарами из избранного, старыми и новыми ценами
+// и ссылкой на список избранного, где можно отключить уведомления
+func priceDropEmail(alert *models.PriceDropAlert) emailContent {
+	var rows, lines strings.Builder
+	for _, item := range alert.Items {
+		oldPrice, newPrice := FormatCurrency(item.OldPrice), FormatCurrency(item.NewPrice)
+		rows.WriteString(fmt.Sprintf(`<li><a href="%s">%s</a>: <s>%s</s> <strong>%s</strong></li>`,
+			html.EscapeString(item.URL), html.EscapeString(item.Name), oldPrice, newPrice))
+		lines.WriteString(fmt.Sprintf("- %s: %s -> %s (%s)\n", item.Name, oldPrice, newPrice, item.URL))
+	}
+	wishlist := html.EscapeString(alert.WishlistURL)
+
+	switch alert.Language {
+	case "en":
+		greeting := "Hello"
+		if alert.Name != "" {
+			greeting = "Dear " + alert.Name
+		}
+		return emailContent{
+			Subject: "Prices have dropped on your wishlist items",
+			HTML: fmt.Sprintf(`
+<h2>Good news from your wishlist</h2>
+<p>%s,</p>
+<p>The following items from your wishlist are now cheaper:</p>
+<ul>%s</ul>
+<p>Best regards,<br><strong>Prianik Studio Team</strong></p>
+<p><small>You can turn off price alerts in <a href="%s">your wishlist</a></small></p>
+	`, html.EscapeString(greeting), rows.String(), wishlist),
+			Text: fmt.Sprintf("%s,\nthe following items from your wishlist are now cheaper:\n%s\nYou can turn off price alerts in your wishlist: %s",
+				greeting, lines.String(), alert.WishlistURL),
+		}
+	case "es":
+		greeting := "Hola"
+		if alert.Name != "" {
+			greeting = "Estimado/a " + alert.Name
+		}
+		return emailContent{
+			Subject: "Han bajado los precios de su lista de deseos",
+			HTML: fmt.Sprintf(`
+<h2>Buenas noticias de su lista de deseos</h2>
+<p>%s,</p>
+<p>Los siguientes productos de su lista de deseos ahora son más baratos:</p>
+<ul>%s</ul>
+<p>Atentamente,<br><strong>Equipo de Prianik Studio</strong></p>
+<p><small>Puede desactivar los avisos de precios en <a href="%s">su lista de deseos</a></small></p>
+	`, html.EscapeString(greeting), rows.String(), wishlist),
+			Text: fmt.Sprintf("%s,\nlos siguientes productos de su lista de deseos ahora son más baratos:\n%s\nPuede desactivar los avisos de precios en su lista de deseos: %s",
+				greeting, lines.String(), alert.WishlistURL),
+		}
+	default:
+		greeting := "Здравствуйте"
+		if alert.Name != "" {
+			greeting = "Уважаемый(ая) " + alert.Name
+		}
+		return emailContent{
+			Subject: "Товары из избранного подешевели",
+			HTML: fmt.Sprintf(`
+<h2>Хорошие новости из вашего избранного</h2>
+<p>%s,</p>
+<p>Эти товары из вашего списка избранного подешевели:</p>
+<ul>%s</ul>
+<p>С уважением,<br><strong>Команда Prianik Studio</strong></p>
+<p><small>Отключить уведомления о снижении цен можно в <a href="%s">списке избранного</a></small></p>
+	`, html.EscapeString(greeting), rows.String(), wishlist),
+			Text: fmt.Sprintf("%s,\nэти товары из вашего списка избранного подешевели:\n%s\nОтключить уведомления о снижении цен можно в списке избранного: %s",
+				greeting, lines.String(), alert.WishlistURL),
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+
+	gomail "gopkg.in/gomail.v2"
+
+	"pryanik_studio/internal/models"
+)
+
+// emailContent содержит тему и тело письма в HTML и текстовом виде
+type emailContent struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// SendQuoteRequest отправляет клиенту подтверждение получения запроса на расчет, а компании - уведомление
+func (s *GomailSender) SendQuoteRequest(quote *models.QuoteRequest) error {
+	return s.sendEmails([]*gomail.Message{
+		s.newMessage(quote.Email, quoteRequestCustomerEmail(quote)),
+		s.newMessage(s.config.CompanyEmail, quoteRequestAdminEmail(quote)),
+	})
+}
+
+// SendQuote отправляет клиенту предложение по запросу на расчет со ссылкой для принятия
+func (s *GomailSender) SendQuote(quote *models.QuoteRequest, acceptURL string) error {
+	return s.sendEmails([]*gomail.Message{
+		s.newMessage(quote.Email, quoteOfferEmail(quote, acceptURL)),
+	})
+}
+
+// newMessage создает письмо из подготовленного содержимого
+func (s *GomailSender) newMessage(to string, content emailContent) *gomail.Message {
+	msg := gomail.NewMessage()
+	msg.SetHeader("From", fmt.Sprintf("%s <%s>", s.config.MailFromName, s.config.MailFrom))
+	msg.SetHeader("To", to)
+	msg.SetHeader("Subject", content.Subject)
+	msg.SetBody("text/plain", content.Text)
+	msg.AddAlternative("text/html", content.HTML)
+	return msg
+}
+
+// SendQuoteRequest отправляет клиенту подтверждение получения запроса на расчет, а компании - уведомление
+func (s *SendGridSender) SendQuoteRequest(quote *models.QuoteRequest) error {
+	customer := quoteRequestCustomerEmail(quote)
+	if err := s.service.SendEmail(quote.Email, customer.Subject, customer.HTML, customer.Text); err != nil {
+		s.logger.WithError(err).Error("Ошибка отправки подтверждения запроса на расчет клиенту")
+		return err
+	}
+
+	admin := quoteRequestAdminEmail(quote)
+	if err := s.service.SendEmail(s.config.CompanyEmail, admin.Subject, admin.HTML, admin.Text); err != nil {
+		s.logger.WithError(err).Error("Ошибка отправки уведомления о запросе на расчет администратору")
+		// Не возвращаем ошибку, так как клиенту подтверждение уже отправлено
+	}
+
+	s.logger.WithField("quote_id", quote.ID).Info("Уведомления о запросе на расчет отправлены")
+	return nil
+}
+
+// SendQuote отправляет клиенту предложение по запросу на расчет со ссылкой для принятия
+func (s *SendGridSender) SendQuote(quote *models.QuoteRequest, acceptURL string) error {
+	content := quoteOfferEmail(quote, acceptURL)
+	if err := s.service.SendEmail(quote.Email, content.Subject, content.HTML, content.Text); err != nil {
+		s.logger.WithError(err).Error("Ошибка отправки предложения по запросу на расчет")
+		return err
+	}
+
+	s.logger.WithField("quote_id", quote.ID).Info("Предложение по запросу на расчет отправлено")
+	return nil
+}
+
+// quoteRequestCustomerEmail письмо клиенту о получении запроса на расчет
+func quoteRequestCustomerEmail(quote *models.QuoteRequest) emailContent {
+	name := html.EscapeString(quote.Name)
+
+	switch quote.Language {
+	case "en":
+		return emailContent{
+			Subject: fmt.Sprintf("Quote request #%d received", quote.ID),
+			HTML: fmt.Sprintf(`
+<h2>Your quote request #%d has been received</h2>
+<p>Dear %s,</p>
+<p>We will review your files and send you a price within a few working days.</p>
+<p>Best regards,<br><strong>Prianik Studio Team</strong></p>
+	`, quote.ID, name),
+			Text: fmt.Sprintf("Dear %s, we have received your quote request #%d and will send you a price within a few working days.", quote.Name, quote.ID),
+		}
+	case "es":
+		return emailContent{
+			Subject: fmt.Sprintf("Solicitud de presupuesto #%d recibida", quote.ID),
+			HTML: fmt.Sprintf(`
+<h2>Hemos recibido su solicitud de presupuesto #%d</h2>
+<p>Estimado/a %s,</p>
+<p>Revisaremos sus archivos y le enviaremos un precio en unos días hábiles.</p>
+<p>Atentamente,<br><strong>Equipo de Prianik Studio</strong></p>
+	`, quote.ID, name),
+			Text: fmt.Sprintf("Estimado/a %s, hemos recibido su solicitud de presupuesto #%d y le enviaremos un precio en unos días hábiles.", quote.Name, quote.ID),
+		}
+	default:
+		return emailContent{
+			Subject: fmt.Sprintf("Запрос на расчет №%d получен", quote.ID),
+			HTML: fmt.Sprintf(`
+<h2>Ваш запрос на расчет №%d получен</h2>
+<p>Уважаемый(ая) %s,</p>
+<p>Мы изучим ваши файлы и пришлем стоимость изготовления в течение нескольких рабочих дней.</p>
+<p>С уважением,<br><strong>Команда Prianik Studio</strong></p>
+	`, quote.ID, name),
+			Text: fmt.Sprintf("Уважаемый(ая) %s, мы получили ваш запрос на расчет №%d и пришлем стоимость в течение нескольких рабочих дней.", quote.Name, quote.ID),
+		}
+	}
+}
+
+// quoteRequestAdminEmail уведомление компании о новом запросе на расчет
+func quoteRequestAdminEmail(quote *models.QuoteRequest) emailContent {
+	files := ""
+	for _, file := range quote.Files {
+		files += fmt.Sprintf("<li>%s (ID: %s)</li>", html.EscapeString(file.FileName), file.ID)
+	}
+
+	return emailContent{
+		Subject: fmt.Sprintf("Новый запрос на расчет №%d", quote.ID),
+		HTML: fmt.Sprintf(`
+<h2>Новый запрос на расчет №%d</h2>
+<p><strong>Имя:</strong> %s</p>
+<p><strong>Email:</strong> %s</p>
+<p><strong>Телефон:</strong> %s</p>
+<p><strong>Материал:</strong> %s</p>
+<p><strong>Количество:</strong> %d</p>
+<p><strong>Описание:</strong> %s</p>
+<h3>Файлы:</h3>
+<ul>%s</ul>
+	`, quote.ID,
+			html.EscapeString(quote.Name),
+			html.EscapeString(quote.Email),
+			html.EscapeString(quote.Phone),
+			html.EscapeString(quote.Material),
+			quote.Quantity,
+			html.EscapeString(quote.Description),
+			files),
+		Text: fmt.Sprintf("Новый запрос на расчет №%d от %s (%s): %d шт., материал: %s",
+			quote.ID, quote.Name, quote.Email, quote.Quantity, quote.Material),
+	}
+}
+
+// quoteOfferEmail письмо клиенту с предложением и ссылкой для его принятия
+func quoteOfferEmail(quote *models.QuoteRequest, acceptURL string) emailContent {
+	name := html.EscapeString(quote.Name)
+	comment := html.EscapeString(quote.AdminComment)
+	link := html.EscapeString(acceptURL)
+
+	price := ""
+	if quote.Price != nil {
+		price = FormatCurrency(*quote.Price)
+	}
+	validUntil := ""
+	if quote.ValidUntil != nil {
+		validUntil = quote.ValidUntil.Format("02.01.2006")
+	}
+
+	switch quote.Language {
+	case "en":
+		if quote.ValidUntil != nil {
+			validUntil = quote.ValidUntil.Format("01/02/2006")
+		}
+		return emailContent{
+			Subject: fmt.Sprintf("Quote #%d is ready", quote.ID),
+			HTML: fmt.Sprintf(`
+<h2>Your quote #%d is ready</h2>
+<p>Dear %s,</p>
+<p><strong>Price for %d pcs:</strong> %s</p>
+<p>%s</p>
+<p>The offer is valid until %s. <a href="%s">Accept the quote</a></p>
+<p>Best regards,<br><strong>Prianik Studio Team</strong></p>
+	`, quote.ID, name, quote.Quantity, price, comment, validUntil, link),
+			Text: fmt.Sprintf("Dear %s, your quote #%d is ready: %s for %d pcs. Valid until %s. Accept: %s",
+				quote.Name, quote.ID, price, quote.Quantity, validUntil, acceptURL),
+		}
+	case "es":
+		return emailContent{
+			Subject: fmt.Sprintf("Presupuesto #%d listo", quote.ID),
+			HTML: fmt.Sprintf(`
+<h2>Su presupuesto #%d está listo</h2>
+<p>Estimado/a %s,</p>
+<p><strong>Precio por %d uds:</strong> %s</p>
+<p>%s</p>
+<p>La oferta es válida hasta el %s. <a href="%s">Aceptar el presupuesto</a></p>
+<p>Atentamente,<br><strong>Equipo de Prianik Studio</strong></p>
+	`, quote.ID, name, quote.Quantity, price, comment, validUntil, link),
+			Text: fmt.Sprintf("Estimado/a %s, su presupuesto #%d está listo: %s por %d uds. Válido hasta el %s. Aceptar: %s",
+				quote.Name, quote.ID, price, quote.Quantity, validUntil, acceptURL),
+		}
+	default:
+		return emailContent{
+			Subject: fmt.Sprintf("Расчет №%d готов", quote.ID),
+			HTML: fmt.Sprintf(`
+<h2>Расчет по вашему запросу №%d готов</h2>
+<p>Уважаемый(ая) %s,</p>
+<p><strong>Стоимость за %d шт.:</strong> %s</p>
+<p>%s</p>
+<p>Предложение действует до %s. <a href="%s">Принять предложение</a></p>
+<p>С уважением,<br><strong>Команда Prianik Studio</strong></p>
+	`, quote.ID, name, quote.Quantity, price, comment, validUntil, link),
+			Text: fmt.Sprintf("Уважаемый(ая) %s, расчет по запросу №%d готов: %s за %d шт. Предложение действует до %s. Принять: %s",
+				quote.Name, quote.ID, price, quote.Quantity, validUntil, acceptURL),
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	gomail "gopkg.in/gomail.v2"
+
+	"pryanik_studio/internal/models"
+)
+
+// SendCheckoutReminder отправляет покупателю напоминание о незавершенном заказе
+func (s *GomailSender) SendCheckoutReminder(reminder *models.CheckoutReminder) error {
+	msg := s.newMessage(reminder.Email, checkoutReminderEmail(reminder))
+	msg.SetHeader("List-Unsubscribe", "<"+reminder.UnsubscribeURL+">")
+	return s.sendEmails([]*gomail.Message{msg})
+}
+
+// SendCheckoutReminder отправляет покупателю напоминание о незавершенном заказе
+func (s *SendGridSender) SendCheckoutReminder(reminder *models.CheckoutReminder) error {
+	content := checkoutReminderEmail(reminder)
+	if err := s.service.SendEmail(reminder.Email, content.Subject, content.HTML, content.Text); err != nil {
+		s.logger.WithError(err).Error("Ошибка отправки напоминания о незавершенном заказе")
+		return err
+	}
+
+	s.logger.WithField("items", len(reminder.Items)).Info("Напоминание о незавершенном заказе отправлено")
+	return nil
+}
+
+// checkoutReminderEmail письмо покупателю с составом брошенной корзины, ссылкой
+// для ее восстановления и ссылкой для отказа от напоминаний
+func checkoutReminderEmail(reminder *models.CheckoutReminder) emailContent {
+	var rows, lines strings.Builder
+	for _, item := range reminder.Items {
+		rows.WriteString(fmt.Sprintf("<li>%s × %d</li>", html.EscapeString(item.Name), item.Quantity))
+		lines.WriteString(fmt.Sprintf("- %s × %d\n", item.Name, item.Quantity))
+	}
+	restore := html.EscapeString(reminder.RestoreURL)
+	unsubscribe := html.EscapeString(reminder.UnsubscribeURL)
+
+	switch reminder.Language {
+	case "en":
+		greeting := "Hello"
+		return emailContent{
+			Subject: "You have not finished your order",
+			HTML: fmt.Sprintf(`
+<h2>Your cart is waiting for you</h2>
+<p>%s,</p>
+<p>You started placing an order but did not finish it:</p>
+<ul>%s</ul>
+<p><a href="%s">Return to your order</a></p>
+<p>Best regards,<br><strong>Prianik Studio Team</strong></p>
+<p><small>Do not want these reminders? <a href="%s">Unsubscribe</a></small></p>
+	`, greeting, rows.String(), restore, unsubscribe),
+			Text: fmt.Sprintf("%s,\nyou started placing an order but did not finish it:\n%s\nReturn to your order: %s\n\nUnsubscribe from reminders: %s",
+				greeting, lines.String(), reminder.RestoreURL, reminder.UnsubscribeURL),
+		}
+	case "es":
+		greeting := "Hola"
+		return emailContent{
+			Subject: "No ha terminado su pedido",
+			HTML: fmt.Sprintf(`
+<h2>Su carrito le está esperando</h2>
+<p>%s,</p>
+<p>Empezó a realizar un pedido, pero no lo terminó:</p>
+<ul>%s</ul>
+<p><a href="%s">Volver a su pedido</a></p>
+<p>Atentamente,<br><strong>Equipo de Prianik Studio</strong></p>
+<p><small>¿No desea recibir estos recordatorios? <a href="%s">Darse de baja</a></small></p>
+	`, greeting, rows.String(), restore, unsubscribe),
+			Text: fmt.Sprintf("%s,\nempezó a realizar un pedido, pero no lo terminó:\n%s\nVolver a su pedido: %s\n\nDarse de baja de los recordatorios: %s",
+				greeting, lines.String(), reminder.RestoreURL, reminder.UnsubscribeURL),
+		}
+	default:
+		greeting := "Здравствуйте"
+		return emailContent{
+			Subject: "Вы не завершили оформление заказа",
+			HTML: fmt.Sprintf(`
+<h2>Ваша корзина ждет вас</h2>
+<p>%s,</p>
+<p>Вы начали оформлять заказ, но не завершили его:</p>
+<ul>%s</ul>
+<p><a href="%s">Вернуться к заказу</a></p>
+<p>С уважением,<br><strong>Команда Prianik Studio</strong></p>
+<p><small>Не хотите получать такие напоминания? <a href="%s">Отписаться</a></small></p>
+	`, greeting, rows.String(), restore, unsubscribe),
+			Text: fmt.Sprintf("%s,\nвы начали оформлять заказ, но не завершили его:\n%s\nВернуться к заказу: %s\n\nОтписаться от напоминаний: %s",
+				greeting, lines.String(), reminder.RestoreURL, reminder.UnsubscribeURL),
+		}
+	}
+}
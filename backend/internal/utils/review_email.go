@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	gomail "gopkg.in/gomail.v2"
+
+	"pryanik_studio/internal/models"
+)
+
+// SendReviewInvitation отправляет покупателю приглашение оставить отзыв о товарах выполненного заказа
+func (s *GomailSender) SendReviewInvitation(invitation *models.ReviewInvitation) error {
+	return s.sendEmails([]*gomail.Message{s.newMessage(invitation.Email, reviewInvitationEmail(invitation))})
+}
+
+// SendReviewInvitation отправляет покупателю приглашение оставить отзыв о товарах выполненного заказа
+func (s *SendGridSender) SendReviewInvitation(invitation *models.ReviewInvitation) error {
+	content := reviewInvitationEmail(invitation)
+	if err := s.service.SendEmail(invitation.Email, content.Subject, content.HTML, content.Text); err != nil {
+		s.logger.WithError(err).Error("Ошибка отправки приглашения оставить отзыв")
+		return err
+	}
+
+	s.logger.WithField("order_id", invitation.OrderID).Info("Приглашение оставить отзыв отправлено")
+	return nil
+}
+
+// reviewInvitationEmail письмо покупателю со списком товаров заказа и ссылкой на страницу отзыва
+func reviewInvitationEmail(invitation *models.ReviewInvitation) emailContent {
+	var rows, lines strings.Builder
+	for _, name := range invitation.Products {
+		rows.WriteString("<li>" + html.EscapeString(name) + "</li>")
+		lines.WriteString("- " + name + "\n")
+	}
+	link := html.EscapeString(invitation.URL)
+	name := html.EscapeString(invitation.Name)
+
+	switch invitation.Language {
+	case "en":
+		return emailContent{
+			Subject: fmt.Sprintf("How do you like your order #%d?", invitation.OrderID),
+			HTML: fmt.Sprintf(`
+<h2>Share your impressions</h2>
+<p>Dear %s,</p>
+<p>Your order #%d has been completed. We would be grateful if you rated the items and told us what you think:</p>
+<ul>%s</ul>
+<p><a href="%s">Leave a review</a></p>
+<p>Best regards,<br><strong>Prianik Studio Team</strong></p>
+	`, name, invitation.OrderID, rows.String(), link),
+			Text: fmt.Sprintf("Dear %s,\nyour order #%d has been completed. Please rate the items:\n%s\nLeave a review: %s",
+				invitation.Name, invitation.OrderID, lines.String(), invitation.URL),
+		}
+	case "es":
+		return emailContent{
+			Subject: fmt.Sprintf("¿Qué le ha parecido su pedido #%d?", invitation.OrderID),
+			HTML: fmt.Sprintf(`
+<h2>Comparta su opinión</h2>
+<p>Estimado/a %s,</p>
+<p>Su pedido #%d ha sido completado. Le agradeceríamos que valorara los productos y nos contara su opinión:</p>
+<ul>%s</ul>
+<p><a href="%s">Dejar una reseña</a></p>
+<p>Atentamente,<br><strong>Equipo de Prianik Studio</strong></p>
+	`, name, invitation.OrderID, rows.String(), link),
+			Text: fmt.Sprintf("Estimado/a %s,\nsu pedido #%d ha sido completado. Valore los productos:\n%s\nDejar una reseña: %s",
+				invitation.Name, invitation.OrderID, lines.String(), invitation.URL),
+		}
+	default:
+		return emailContent{
+			Subject: fmt.Sprintf("Как вам заказ №%d?", invitation.OrderID),
+			HTML: fmt.Sprintf(`
+<h2>Поделитесь впечатлениями</h2>
+<p>Уважаемый(ая) %s,</p>
+<p>Ваш заказ №%d выполнен. Будем благодарны, если вы оцените товары и расскажете о них:</p>
+<ul>%s</ul>
+<p><a href="%s">Оставить отзыв</a></p>
+<p>С уважением,<br><strong>Команда Prianik Studio</strong></p>
+	`, name, invitation.OrderID, rows.String(), link),
+			Text: fmt.Sprintf("Уважаемый(ая) %s,\nваш заказ №%d выполнен. Оцените, пожалуйста, товары:\n%s\nОставить отзыв: %s",
+				invitation.Name, invitation.OrderID, lines.String(), invitation.URL),
+		}
+	}
+}
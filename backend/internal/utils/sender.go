@@ -3,6 +3,9 @@ package utils
 import (
 	"crypto/tls"
 	"fmt"
+	"html"
+	"io"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	gomail "gopkg.in/gomail.v2"
@@ -13,8 +16,24 @@ import (
 
 // Sender интерфейс для отправки электронных писем
 type Sender interface {
-	SendOrderConfirmation(order *models.Order) error
-	SendContactForm(form *models.ContactFormRequest) error
+	SendOrderConfirmation(order *models.Order, attachments ...Attachment) error
+	SendContactConfirmation(form *models.ContactFormRequest) error
+	SendContactNotification(form *models.ContactFormRequest) error
+	SendQuoteRequest(quote *models.QuoteRequest) error
+	SendQuote(quote *models.QuoteRequest, acceptURL string) error
+	SendLowStockAlert(items []models.LowStockItem) error
+	SendCheckoutReminder(reminder *models.CheckoutReminder) error
+	SendLoginLink(link *models.LoginLink) error
+	SendPriceDropAlert(alert *models.PriceDropAlert) error
+	SendReviewInvitation(invitation *models.ReviewInvitation) error
+	SendPaymentAnomalyAlert(payment *models.Payment) error
+}
+
+// Attachment вложение письма (например, PDF-счет к подтверждению заказа)
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
 }
 
 // GomailSender реализация Sender с использованием gomail
@@ -55,35 +74,127 @@ func (t *EmailTemplate) GetBody(lang string) string {
 	return t.Body["ru"]
 }
 
-// FormatCurrency форматирует валюту в зависимости от языка
-func FormatCurrency(price float64, currency string, lang string) string {
-	// Если валюта не указана, определяем её по языку
-	if currency == "" {
-		switch lang {
-		case "en":
-			currency = "USD"
-		case "es":
-			currency = "EUR"
-		default:
-			currency = "RUB"
-		}
-	}
-
+// FormatCurrency форматирует сумму с символом ее валюты
+func FormatCurrency(price models.Money) string {
 	// Форматируем отображение в зависимости от валюты
-	switch currency {
+	switch price.Currency {
 	case "USD":
-		return fmt.Sprintf("$%.2f", price)
+		return "$" + price.String()
 	case "EUR":
-		return fmt.Sprintf("€%.2f", price)
+		return "€" + price.String()
 	case "RUB":
-		return fmt.Sprintf("%.2f ₽", price)
+		return price.String() + " ₽"
 	default:
-		return fmt.Sprintf("%.2f %s", price, currency)
+		return price.String() + " " + price.Currency
+	}
+}
+
+// PersonalizationHTML возвращает значения персонализации позиции заказа для HTML-письма.
+// В письме администратору для файлов дополнительно указывается ID загрузки
+func PersonalizationHTML(item models.OrderItem, forAdmin bool) string {
+	if len(item.Personalization) == 0 {
+		return ""
+	}
+
+	result := "<ul>"
+	for _, value := range item.Personalization {
+		result += fmt.Sprintf("<li>%s: %s</li>",
+			html.EscapeString(value.Label),
+			html.EscapeString(personalizationValue(value, forAdmin)),
+		)
+	}
+	return result + "</ul>"
+}
+
+// PersonalizationText возвращает значения персонализации позиции заказа для текстового письма
+func PersonalizationText(item models.OrderItem) string {
+	result := ""
+	for _, value := range item.Personalization {
+		result += fmt.Sprintf("    %s: %s\n", value.Label, personalizationValue(value, false))
+	}
+	return result
+}
+
+// personalizationValue возвращает отображаемое значение поля персонализации
+func personalizationValue(value models.PersonalizationValue, forAdmin bool) string {
+	if value.Type != models.PersonalizationFile {
+		return value.Value
+	}
+	if forAdmin {
+		return fmt.Sprintf("%s (ID: %s)", value.FileName, value.Value)
+	}
+	return value.FileName
+}
+
+// deliveryLabels подписи строк скидки и доставки в письмах о заказе
+var deliveryLabels = map[string]map[string]string{
+	"ru": {"discount": "Скидка", "shipping": "Доставка", "address": "Адрес доставки", "free": "бесплатно"},
+	"en": {"discount": "Discount", "shipping": "Shipping", "address": "Shipping address", "free": "free"},
+	"es": {"discount": "Descuento", "shipping": "Envío", "address": "Dirección de envío", "free": "gratis"},
+}
+
+// FormatAddress возвращает адрес доставки одной строкой
+func FormatAddress(address models.Address) string {
+	var parts []string
+	for _, part := range []string{
+		address.Street, address.Apartment, address.City, address.Region, address.PostalCode, address.Country,
+	} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// deliveryLines возвращает строки скидки, способа доставки и адреса заказа в виде пар «подпись - значение»
+func deliveryLines(order *models.Order, lang string) [][2]string {
+	labels, ok := deliveryLabels[lang]
+	if !ok {
+		labels = deliveryLabels["ru"]
+	}
+
+	var lines [][2]string
+	if !order.Discount.IsZero() {
+		discount := "-" + FormatCurrency(order.Discount)
+		if order.PromoCode != "" {
+			discount += " (" + order.PromoCode + ")"
+		}
+		lines = append(lines, [2]string{labels["discount"], discount})
+	}
+	if order.ShippingMethod != "" {
+		cost := labels["free"]
+		if !order.ShippingCost.IsZero() {
+			cost = FormatCurrency(order.ShippingCost)
+		}
+		lines = append(lines, [2]string{labels["shipping"], order.ShippingMethodName + " - " + cost})
+	}
+	if order.ShippingAddress != nil {
+		lines = append(lines, [2]string{labels["address"], FormatAddress(*order.ShippingAddress)})
 	}
+	return lines
 }
 
-// SendOrderConfirmation отправляет уведомление о заказе клиенту и компании
-func (s *GomailSender) SendOrderConfirmation(order *models.Order) error {
+// DeliveryHTML возвращает скидку, способ доставки и адрес заказа для HTML-письма
+func DeliveryHTML(order *models.Order, lang string) string {
+	result := ""
+	for _, line := range deliveryLines(order, lang) {
+		result += fmt.Sprintf("<p><strong>%s:</strong> %s</p>", line[0], html.EscapeString(line[1]))
+	}
+	return result
+}
+
+// DeliveryText возвращает скидку, способ доставки и адрес заказа для текстового письма
+func DeliveryText(order *models.Order, lang string) string {
+	result := ""
+	for _, line := range deliveryLines(order, lang) {
+		result += fmt.Sprintf("%s: %s\n", line[0], line[1])
+	}
+	return result
+}
+
+// SendOrderConfirmation отправляет уведомление о заказе клиенту и компании; вложения
+// добавляются к обоим письмам
+func (s *GomailSender) SendOrderConfirmation(order *models.Order, attachments ...Attachment) error {
 	// Определяем язык клиента (по умолчанию русский)
 	lang := order.Language
 	if lang == "" {
@@ -91,7 +202,7 @@ func (s *GomailSender) SendOrderConfirmation(order *models.Order) error {
 	}
 
 	// Создаем шаблоны для клиента
-	customerTemplate := createOrderCustomerTemplate(order, lang)
+	customerTemplate := createOrderCustomerTemplate(order)
 
 	// Создаем сообщение для клиента
 	customerMsg := gomail.NewMessage()
@@ -110,13 +221,29 @@ func (s *GomailSender) SendOrderConfirmation(order *models.Order) error {
 	ownerMsg.SetHeader("Subject", ownerTemplate.GetSubject(lang))
 	ownerMsg.SetBody("text/html", ownerTemplate.GetBody(lang))
 
+	for _, attachment := range attachments {
+		attach(customerMsg, attachment)
+		attach(ownerMsg, attachment)
+	}
+
 	// Объединяем сообщения и отправляем их
 	allMessages := []*gomail.Message{customerMsg, ownerMsg}
 	return s.sendEmails(allMessages)
 }
 
-// SendContactForm отправляет уведомление о новом сообщении с формы обратной связи
-func (s *GomailSender) SendContactForm(form *models.ContactFormRequest) error {
+// attach добавляет вложение к сообщению
+func attach(msg *gomail.Message, attachment Attachment) {
+	msg.Attach(attachment.Name,
+		gomail.SetHeader(map[string][]string{"Content-Type": {attachment.ContentType}}),
+		gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(attachment.Data)
+			return err
+		}),
+	)
+}
+
+// SendContactConfirmation отправляет клиенту подтверждение получения сообщения с формы обратной связи
+func (s *GomailSender) SendContactConfirmation(form *models.ContactFormRequest) error {
 	// Определяем язык клиента (по умолчанию русский)
 	lang := form.Language
 	if lang == "" {
@@ -133,7 +260,18 @@ func (s *GomailSender) SendContactForm(form *models.ContactFormRequest) error {
 	customerMsg.SetHeader("Subject", customerTemplate.GetSubject(lang))
 	customerMsg.SetBody("text/html", customerTemplate.GetBody(lang))
 
-	// Создаем шаблон для владельца на том же языке, что и клиент
+	return s.sendEmails([]*gomail.Message{customerMsg})
+}
+
+// SendContactNotification отправляет владельцу уведомление о новом сообщении с формы обратной связи
+func (s *GomailSender) SendContactNotification(form *models.ContactFormRequest) error {
+	// Уведомление на том же языке, что и сообщение клиента (по умолчанию русский)
+	lang := form.Language
+	if lang == "" {
+		lang = "ru"
+	}
+
+	// Создаем шаблон для владельца
 	ownerTemplate := createContactOwnerTemplate(form, lang)
 
 	// Создаем сообщение для владельца
@@ -143,13 +281,11 @@ func (s *GomailSender) SendContactForm(form *models.ContactFormRequest) error {
 	ownerMsg.SetHeader("Subject", ownerTemplate.GetSubject(lang))
 	ownerMsg.SetBody("text/html", ownerTemplate.GetBody(lang))
 
-	// Объединяем сообщения и отправляем их
-	allMessages := []*gomail.Message{customerMsg, ownerMsg}
-	return s.sendEmails(allMessages)
+	return s.sendEmails([]*gomail.Message{ownerMsg})
 }
 
 // createOrderCustomerTemplate создает шаблоны письма для клиента о заказе
-func createOrderCustomerTemplate(order *models.Order, lang string) *EmailTemplate {
+func createOrderCustomerTemplate(order *models.Order) *EmailTemplate {
 	template := &EmailTemplate{
 		Subject: map[string]string{
 			"ru": "Подтверждение заказа №" + fmt.Sprintf("%d", order.ID),
@@ -159,9 +295,8 @@ func createOrderCustomerTemplate(order *models.Order, lang string) *EmailTemplat
 		Body: make(map[string]string),
 	}
 
-	// Определяем валюту и форматируем общую стоимость
-	currency := GetCurrencyByLanguage(lang)
-	formattedTotalCost := FormatCurrency(order.TotalCost, currency, lang)
+	// Форматируем общую стоимость
+	formattedTotalCost := FormatCurrency(order.TotalCost)
 
 	// Формируем тело письма на русском
 	ruBody := fmt.Sprintf(`
@@ -213,13 +348,14 @@ func createOrderCustomerTemplate(order *models.Order, lang string) *EmailTemplat
 		// Русский
 		ruItemsSection := "<h3>Товары:</h3><ul>"
 		for _, item := range order.Items {
-			formattedPrice := FormatCurrency(item.Price, currency, "ru")
-			formattedTotal := FormatCurrency(float64(item.Quantity)*item.Price, currency, "ru")
-			ruItemsSection += fmt.Sprintf("<li>%s - %d шт. x %s = %s</li>",
-				item.ProductName,
+			formattedPrice := FormatCurrency(item.Price)
+			formattedTotal := FormatCurrency(item.Price.Mul(item.Quantity))
+			ruItemsSection += fmt.Sprintf("<li>%s - %d шт. x %s = %s%s</li>",
+				item.DisplayName(),
 				item.Quantity,
 				formattedPrice,
 				formattedTotal,
+				PersonalizationHTML(item, false),
 			)
 		}
 		ruItemsSection += "</ul>"
@@ -228,13 +364,14 @@ func createOrderCustomerTemplate(order *models.Order, lang string) *EmailTemplat
 		// Английский
 		enItemsSection := "<h3>Products:</h3><ul>"
 		for _, item := range order.Items {
-			formattedPrice := FormatCurrency(item.Price, currency, "en")
-			formattedTotal := FormatCurrency(float64(item.Quantity)*item.Price, currency, "en")
-			enItemsSection += fmt.Sprintf("<li>%s - %d pcs x %s = %s</li>",
-				item.ProductName,
+			formattedPrice := FormatCurrency(item.Price)
+			formattedTotal := FormatCurrency(item.Price.Mul(item.Quantity))
+			enItemsSection += fmt.Sprintf("<li>%s - %d pcs x %s = %s%s</li>",
+				item.DisplayName(),
 				item.Quantity,
 				formattedPrice,
 				formattedTotal,
+				PersonalizationHTML(item, false),
 			)
 		}
 		enItemsSection += "</ul>"
@@ -243,19 +380,25 @@ func createOrderCustomerTemplate(order *models.Order, lang string) *EmailTemplat
 		// Испанский
 		esItemsSection := "<h3>Productos:</h3><ul>"
 		for _, item := range order.Items {
-			formattedPrice := FormatCurrency(item.Price, currency, "es")
-			formattedTotal := FormatCurrency(float64(item.Quantity)*item.Price, currency, "es")
-			esItemsSection += fmt.Sprintf("<li>%s - %d uds x %s = %s</li>",
-				item.ProductName,
+			formattedPrice := FormatCurrency(item.Price)
+			formattedTotal := FormatCurrency(item.Price.Mul(item.Quantity))
+			esItemsSection += fmt.Sprintf("<li>%s - %d uds x %s = %s%s</li>",
+				item.DisplayName(),
 				item.Quantity,
 				formattedPrice,
 				formattedTotal,
+				PersonalizationHTML(item, false),
 			)
 		}
 		esItemsSection += "</ul>"
 		esBody += esItemsSection
 	}
 
+	// Добавляем скидку и доставку
+	ruBody += DeliveryHTML(order, "ru")
+	enBody += DeliveryHTML(order, "en")
+	esBody += DeliveryHTML(order, "es")
+
 	// Завершаем тело письма
 	ruBody += `
 		<p>Если у вас возникнут вопросы, пожалуйста, свяжитесь с нами по электронной почте или телефону.</p>
@@ -279,18 +422,6 @@ func createOrderCustomerTemplate(order *models.Order, lang string) *EmailTemplat
 	return template
 }
 
-// GetCurrencyByLanguage возвращает валюту по умолчанию для языка
-func GetCurrencyByLanguage(lang string) string {
-	switch lang {
-	case "en":
-		return "USD"
-	case "es":
-		return "EUR"
-	default:
-		return "RUB"
-	}
-}
-
 // createOrderOwnerTemplate создает шаблон письма для владельца о заказе
 func createOrderOwnerTemplate(order *models.Order, lang string) *EmailTemplate {
 	template := &EmailTemplate{
@@ -302,11 +433,8 @@ func createOrderOwnerTemplate(order *models.Order, lang string) *EmailTemplate {
 		Body: make(map[string]string),
 	}
 
-	// Определяем валюту по языку
-	currency := GetCurrencyByLanguage(lang)
-
 	// Форматируем общую стоимость в валюте
-	formattedTotalCost := FormatCurrency(order.TotalCost, currency, lang)
+	formattedTotalCost := FormatCurrency(order.TotalCost)
 
 	// Форматируем тело письма в зависимости от языка
 	switch lang {
@@ -377,15 +505,16 @@ func createOrderOwnerTemplate(order *models.Order, lang string) *EmailTemplate {
 		if _, ok := template.Body["ru"]; ok && lang == "ru" {
 			ruItemsSection := "<h3>Товары:</h3><ul>"
 			for _, item := range order.Items {
-				formattedPrice := FormatCurrency(item.Price, currency, lang)
-				formattedTotal := FormatCurrency(float64(item.Quantity)*item.Price, currency, lang)
+				formattedPrice := FormatCurrency(item.Price)
+				formattedTotal := FormatCurrency(item.Price.Mul(item.Quantity))
 
-				ruItemsSection += fmt.Sprintf("<li>%s (ID: %d) - %d шт. x %s = %s</li>",
-					item.ProductName,
+				ruItemsSection += fmt.Sprintf("<li>%s (ID: %d) - %d шт. x %s = %s%s</li>",
+					item.DisplayName(),
 					item.ProductID,
 					item.Quantity,
 					formattedPrice,
 					formattedTotal,
+					PersonalizationHTML(item, true),
 				)
 			}
 			ruItemsSection += "</ul>"
@@ -395,15 +524,16 @@ func createOrderOwnerTemplate(order *models.Order, lang string) *EmailTemplate {
 		if _, ok := template.Body["en"]; ok && lang == "en" {
 			enItemsSection := "<h3>Products:</h3><ul>"
 			for _, item := range order.Items {
-				formattedPrice := FormatCurrency(item.Price, currency, lang)
-				formattedTotal := FormatCurrency(float64(item.Quantity)*item.Price, currency, lang)
+				formattedPrice := FormatCurrency(item.Price)
+				formattedTotal := FormatCurrency(item.Price.Mul(item.Quantity))
 
-				enItemsSection += fmt.Sprintf("<li>%s (ID: %d) - %d pcs x %s = %s</li>",
-					item.ProductName,
+				enItemsSection += fmt.Sprintf("<li>%s (ID: %d) - %d pcs x %s = %s%s</li>",
+					item.DisplayName(),
 					item.ProductID,
 					item.Quantity,
 					formattedPrice,
 					formattedTotal,
+					PersonalizationHTML(item, true),
 				)
 			}
 			enItemsSection += "</ul>"
@@ -413,15 +543,16 @@ func createOrderOwnerTemplate(order *models.Order, lang string) *EmailTemplate {
 		if _, ok := template.Body["es"]; ok && lang == "es" {
 			esItemsSection := "<h3>Productos:</h3><ul>"
 			for _, item := range order.Items {
-				formattedPrice := FormatCurrency(item.Price, currency, lang)
-				formattedTotal := FormatCurrency(float64(item.Quantity)*item.Price, currency, lang)
+				formattedPrice := FormatCurrency(item.Price)
+				formattedTotal := FormatCurrency(item.Price.Mul(item.Quantity))
 
-				esItemsSection += fmt.Sprintf("<li>%s (ID: %d) - %d uds x %s = %s</li>",
-					item.ProductName,
+				esItemsSection += fmt.Sprintf("<li>%s (ID: %d) - %d uds x %s = %s%s</li>",
+					item.DisplayName(),
 					item.ProductID,
 					item.Quantity,
 					formattedPrice,
 					formattedTotal,
+					PersonalizationHTML(item, true),
 				)
 			}
 			esItemsSection += "</ul>"
@@ -429,6 +560,11 @@ func createOrderOwnerTemplate(order *models.Order, lang string) *EmailTemplate {
 		}
 	}
 
+	// Добавляем скидку и доставку
+	if _, ok := template.Body[lang]; ok {
+		template.Body[lang] += DeliveryHTML(order, lang)
+	}
+
 	return template
 }
 
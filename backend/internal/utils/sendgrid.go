@@ -1,11 +1,13 @@
 package utils
 
 import (
+	"encoding/base64"
 	"fmt"
 	"pryanik_studio/internal/config"
 	"pryanik_studio/internal/models"
 	"pryanik_studio/internal/services/email"
 
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
 	"github.com/sirupsen/logrus"
 )
 
@@ -29,7 +31,7 @@ func NewSendGridSender(config config.EmailConfig, logger *logrus.Logger) *SendGr
 	}
 }
 
-func (s *SendGridSender) SendOrderConfirmation(order *models.Order) error {
+func (s *SendGridSender) SendOrderConfirmation(order *models.Order, attachments ...Attachment) error {
 	// Определяем язык клиента (по умолчанию русский)
 	lang := order.Language
 	if lang == "" {
@@ -41,7 +43,8 @@ func (s *SendGridSender) SendOrderConfirmation(order *models.Order) error {
 	clientText := s.generateOrderClientText(order, lang)
 	clientSubject := s.getOrderSubject(lang, order.ID)
 
-	if err := s.service.SendEmail(order.Email, clientSubject, clientHTML, clientText); err != nil {
+	files := sendGridAttachments(attachments)
+	if err := s.service.SendEmail(order.Email, clientSubject, clientHTML, clientText, files...); err != nil {
 		s.logger.WithError(err).Error("Ошибка отправки письма клиенту")
 		return err
 	}
@@ -51,7 +54,7 @@ func (s *SendGridSender) SendOrderConfirmation(order *models.Order) error {
 	adminText := s.generateOrderAdminText(order, lang)
 	adminSubject := s.getAdminOrderSubject(lang, order.ID)
 
-	if err := s.service.SendEmail(s.config.CompanyEmail, adminSubject, adminHTML, adminText); err != nil {
+	if err := s.service.SendEmail(s.config.CompanyEmail, adminSubject, adminHTML, adminText, files...); err != nil {
 		s.logger.WithError(err).Error("Ошибка отправки письма администратору")
 		// Не возвращаем ошибку, так как клиенту письмо уже отправлено
 	}
@@ -60,7 +63,20 @@ func (s *SendGridSender) SendOrderConfirmation(order *models.Order) error {
 	return nil
 }
 
-func (s *SendGridSender) SendContactForm(form *models.ContactFormRequest) error {
+// sendGridAttachments преобразует вложения в формат SendGrid (содержимое в base64)
+func sendGridAttachments(attachments []Attachment) []*mail.Attachment {
+	files := make([]*mail.Attachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		files = append(files, mail.NewAttachment().
+			SetFilename(attachment.Name).
+			SetType(attachment.ContentType).
+			SetDisposition("attachment").
+			SetContent(base64.StdEncoding.EncodeToString(attachment.Data)))
+	}
+	return files
+}
+
+func (s *SendGridSender) SendContactConfirmation(form *models.ContactFormRequest) error {
 	// Определяем язык клиента (по умолчанию русский)
 	lang := form.Language
 	if lang == "" {
@@ -77,32 +93,28 @@ func (s *SendGridSender) SendContactForm(form *models.ContactFormRequest) error
 		return err
 	}
 
+	s.logger.WithField("email", form.Email).Info("Подтверждение по форме обратной связи отправлено")
+	return nil
+}
+
+func (s *SendGridSender) SendContactNotification(form *models.ContactFormRequest) error {
 	// Уведомление администратору (на том же языке, что и клиент)
+	lang := form.Language
+	if lang == "" {
+		lang = "ru"
+	}
+
 	adminHTML := s.generateContactAdminHTML(form, lang)
 	adminText := s.generateContactAdminText(form, lang)
 	adminSubject := s.getAdminContactSubject(lang)
 
 	if err := s.service.SendEmail(s.config.CompanyEmail, adminSubject, adminHTML, adminText); err != nil {
 		s.logger.WithError(err).Error("Ошибка отправки уведомления администратору")
-		// Не возвращаем ошибку, так как клиенту подтверждение уже отправлено
+		return err
 	}
-
-	s.logger.WithField("email", form.Email).Info("Форма обратной связи обработана")
 	return nil
 }
 
-// Функция для форматирования валюты в зависимости от языка
-func (s *SendGridSender) formatCurrency(price float64, lang string) string {
-	switch lang {
-	case "en":
-		return fmt.Sprintf("$%.2f", price)
-	case "es":
-		return fmt.Sprintf("€%.2f", price)
-	default:
-		return fmt.Sprintf("%.2f ₽", price)
-	}
-}
-
 // Генерация HTML для заказа клиенту с локализацией
 func (s *SendGridSender) generateOrderClientHTML(order *models.Order, lang string) string {
 	var title, greeting, message, orderInfoTitle, orderNumber, totalAmount, orderDate, itemsTitle, questionsText, regards string
@@ -147,24 +159,24 @@ func (s *SendGridSender) generateOrderClientHTML(order *models.Order, lang strin
 	if len(order.Items) > 0 {
 		itemsHTML = fmt.Sprintf("<h3>%s</h3><ul>", itemsTitle)
 		for _, item := range order.Items {
-			total := item.Price * float64(item.Quantity)
-			formattedPrice := s.formatCurrency(item.Price, lang)
-			formattedTotal := s.formatCurrency(total, lang)
+			formattedPrice := FormatCurrency(item.Price)
+			formattedTotal := FormatCurrency(item.Price.Mul(item.Quantity))
 
 			var itemText string
 			switch lang {
 			case "en":
-				itemText = fmt.Sprintf("%s - %d pcs × %s = %s", item.ProductName, item.Quantity, formattedPrice, formattedTotal)
+				itemText = fmt.Sprintf("%s - %d pcs × %s = %s", item.DisplayName(), item.Quantity, formattedPrice, formattedTotal)
 			case "es":
-				itemText = fmt.Sprintf("%s - %d uds × %s = %s", item.ProductName, item.Quantity, formattedPrice, formattedTotal)
+				itemText = fmt.Sprintf("%s - %d uds × %s = %s", item.DisplayName(), item.Quantity, formattedPrice, formattedTotal)
 			default:
-				itemText = fmt.Sprintf("%s - %d шт. × %s = %s", item.ProductName, item.Quantity, formattedPrice, formattedTotal)
+				itemText = fmt.Sprintf("%s - %d шт. × %s = %s", item.DisplayName(), item.Quantity, formattedPrice, formattedTotal)
 			}
 
-			itemsHTML += fmt.Sprintf("<li>%s</li>", itemText)
+			itemsHTML += fmt.Sprintf("<li>%s%s</li>", itemText, PersonalizationHTML(item, false))
 		}
 		itemsHTML += "</ul>"
 	}
+	itemsHTML += DeliveryHTML(order, lang)
 
 	dateFormat := order.CreatedAt.Format("02.01.2006 15:04")
 	if lang == "en" {
@@ -205,7 +217,7 @@ func (s *SendGridSender) generateOrderClientHTML(order *models.Order, lang strin
 </body>
 </html>
 	`, title, title, greeting, message, orderInfoTitle, orderNumber, order.ID,
-		totalAmount, s.formatCurrency(order.TotalCost, lang), orderDate, dateFormat,
+		totalAmount, FormatCurrency(order.TotalCost), orderDate, dateFormat,
 		itemsHTML, questionsText, s.config.CompanyEmail, regards)
 }
 
@@ -250,23 +262,25 @@ func (s *SendGridSender) generateOrderClientText(order *models.Order, lang strin
 	if len(order.Items) > 0 {
 		itemsText = fmt.Sprintf("\n%s\n", itemsTitle)
 		for _, item := range order.Items {
-			total := item.Price * float64(item.Quantity)
-			formattedPrice := s.formatCurrency(item.Price, lang)
-			formattedTotal := s.formatCurrency(total, lang)
+			formattedPrice := FormatCurrency(item.Price)
+			formattedTotal := FormatCurrency(item.Price.Mul(item.Quantity))
 
 			var itemText string
 			switch lang {
 			case "en":
-				itemText = fmt.Sprintf("- %s - %d pcs × %s = %s", item.ProductName, item.Quantity, formattedPrice, formattedTotal)
+				itemText = fmt.Sprintf("- %s - %d pcs × %s = %s", item.DisplayName(), item.Quantity, formattedPrice, formattedTotal)
 			case "es":
-				itemText = fmt.Sprintf("- %s - %d uds × %s = %s", item.ProductName, item.Quantity, formattedPrice, formattedTotal)
+				itemText = fmt.Sprintf("- %s - %d uds × %s = %s", item.DisplayName(), item.Quantity, formattedPrice, formattedTotal)
 			default:
-				itemText = fmt.Sprintf("- %s - %d шт. × %s = %s", item.ProductName, item.Quantity, formattedPrice, formattedTotal)
+				itemText = fmt.Sprintf("- %s - %d шт. × %s = %s", item.DisplayName(), item.Quantity, formattedPrice, formattedTotal)
 			}
 
-			itemsText += itemText + "\n"
+			itemsText += itemText + "\n" + PersonalizationText(item)
 		}
 	}
+	if delivery := DeliveryText(order, lang); delivery != "" {
+		itemsText += "\n" + delivery
+	}
 
 	dateFormat := order.CreatedAt.Format("02.01.2006 15:04")
 	if lang == "en" {
@@ -290,7 +304,7 @@ func (s *SendGridSender) generateOrderClientText(order *models.Order, lang strin
 
 %s
 	`, title, greeting, message, orderNumber, order.ID, totalAmount,
-		s.formatCurrency(order.TotalCost, lang), orderDate, dateFormat,
+		FormatCurrency(order.TotalCost), orderDate, dateFormat,
 		itemsText, questionsText, s.config.CompanyEmail, regards)
 }
 
@@ -327,15 +341,16 @@ func (s *SendGridSender) generateOrderAdminHTML(order *models.Order, lang string
 
 		itemsHTML = fmt.Sprintf("<h3>%s</h3><ul>", itemsTitle)
 		for _, item := range order.Items {
-			total := item.Price * float64(item.Quantity)
-			formattedPrice := s.formatCurrency(item.Price, lang)
-			formattedTotal := s.formatCurrency(total, lang)
+			formattedPrice := FormatCurrency(item.Price)
+			formattedTotal := FormatCurrency(item.Price.Mul(item.Quantity))
 
-			itemsHTML += fmt.Sprintf(`<li>%s (ID: %d) - %d × %s = %s</li>`,
-				item.ProductName, item.ProductID, item.Quantity, formattedPrice, formattedTotal)
+			itemsHTML += fmt.Sprintf(`<li>%s (ID: %d) - %d × %s = %s%s</li>`,
+				item.DisplayName(), item.ProductID, item.Quantity, formattedPrice, formattedTotal,
+				PersonalizationHTML(item, true))
 		}
 		itemsHTML += "</ul>"
 	}
+	itemsHTML += DeliveryHTML(order, lang)
 
 	return fmt.Sprintf(`
 <h2>%s</h2>
@@ -350,13 +365,13 @@ func (s *SendGridSender) generateOrderAdminHTML(order *models.Order, lang string
 <p><strong>Дата заказа:</strong> %s</p>
 %s
 	`, title, customerInfo, order.Name, order.Email, order.Phone, order.Comment,
-		orderInfo, s.formatCurrency(order.TotalCost, lang),
+		orderInfo, FormatCurrency(order.TotalCost),
 		order.CreatedAt.Format("02.01.2006 15:04"), itemsHTML)
 }
 
 func (s *SendGridSender) generateOrderAdminText(order *models.Order, lang string) string {
-	return fmt.Sprintf("Новый заказ №%d от %s (%s) на сумму %s",
-		order.ID, order.Name, order.Email, s.formatCurrency(order.TotalCost, lang))
+	return fmt.Sprintf("Новый заказ №%d от %s (%s) на сумму %s\n%s",
+		order.ID, order.Name, order.Email, FormatCurrency(order.TotalCost), DeliveryText(order, lang))
 }
 
 // Контактная форма клиенту с локализацией
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	gomail "gopkg.in/gomail.v2"
+
+	"pryanik_studio/internal/models"
+)
+
+// SendLowStockAlert отправляет компании уведомление о товарах с низким остатком
+func (s *GomailSender) SendLowStockAlert(items []models.LowStockItem) error {
+	return s.sendEmails([]*gomail.Message{
+		s.newMessage(s.config.CompanyEmail, lowStockEmail(items)),
+	})
+}
+
+// SendLowStockAlert отправляет компании уведомление о товарах с низким остатком
+func (s *SendGridSender) SendLowStockAlert(items []models.LowStockItem) error {
+	content := lowStockEmail(items)
+	if err := s.service.SendEmail(s.config.CompanyEmail, content.Subject, content.HTML, content.Text); err != nil {
+		s.logger.WithError(err).Error("Ошибка отправки уведомления о низком остатке")
+		return err
+	}
+
+	s.logger.WithField("items", len(items)).Info("Уведомление о низком остатке отправлено")
+	return nil
+}
+
+// lowStockEmail письмо компании о товарах, остаток которых опустился до порога
+func lowStockEmail(items []models.LowStockItem) emailContent {
+	var rows, lines strings.Builder
+	for _, item := range items {
+		sku := item.SKU
+		if sku == "" {
+			sku = "—"
+		}
+		rows.WriteString(fmt.Sprintf("<tr><td>%d</td><td>%s</td><td>%s</td><td>%d</td></tr>",
+			item.ProductID, html.EscapeString(item.Name), html.EscapeString(sku), item.Remaining))
+		lines.WriteString(fmt.Sprintf("- %s (ID товара %d, SKU %s): осталось %d\n",
+			item.Name, item.ProductID, sku, item.Remaining))
+	}
+
+	return emailContent{
+		Subject: "Низкий остаток товаров на складе",
+		HTML: fmt.Sprintf(`
+<h2>Низкий остаток товаров на складе</h2>
+<p>После последнего заказа остаток следующих товаров опустился до порога:</p>
+<table border="1" cellpadding="5" cellspacing="0">
+<tr><th>ID товара</th><th>Товар</th><th>SKU</th><th>Остаток</th></tr>
+%s
+</table>
+	`, rows.String()),
+		Text: "Остаток следующих товаров опустился до порога:\n" + lines.String(),
+	}
+}
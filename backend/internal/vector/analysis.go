@@ -0,0 +1,121 @@
+package vector
+
+import (
+	"math"
+
+	"pryanik_studio/internal/models"
+)
+
+// closeTolerance расстояние между концами ломаной, при котором она считается замкнутой (0,01 мм)
+const closeTolerance = 0.01
+
+// contour замкнутый контур с габаритами для проверки вложенности
+type contour struct {
+	points   []Point
+	area     float64
+	min, max Point
+}
+
+// Analyze рассчитывает длину реза, площадь гравировки и габариты макета.
+// Длина реза - суммарная длина всех контуров. Площадь гравировки - площадь, ограниченная
+// замкнутыми контурами, по правилу even-odd: отверстия (например, внутренность буквы «О») вычитаются
+func Analyze(d *Drawing) models.VectorAnalysis {
+	analysis := models.VectorAnalysis{
+		Paths:       len(d.Paths),
+		Unsupported: d.Unsupported,
+	}
+	if len(d.Paths) == 0 {
+		return analysis
+	}
+
+	min := d.Paths[0].Points[0]
+	max := min
+
+	var contours []contour
+	for _, path := range d.Paths {
+		points := path.Points
+		closed := path.Closed || (len(points) > 2 && distance(points[0], points[len(points)-1]) <= closeTolerance)
+
+		c := contour{points: points, min: points[0], max: points[0]}
+		for i, p := range points {
+			if i > 0 {
+				analysis.CutLength += distance(points[i-1], p)
+			}
+			c.min = Point{math.Min(c.min.X, p.X), math.Min(c.min.Y, p.Y)}
+			c.max = Point{math.Max(c.max.X, p.X), math.Max(c.max.Y, p.Y)}
+		}
+		min = Point{math.Min(min.X, c.min.X), math.Min(min.Y, c.min.Y)}
+		max = Point{math.Max(max.X, c.max.X), math.Max(max.Y, c.max.Y)}
+
+		if !closed {
+			continue
+		}
+		if path.Closed {
+			analysis.CutLength += distance(points[len(points)-1], points[0])
+		}
+		analysis.ClosedPaths++
+		if c.area = polygonArea(points); c.area > 0 {
+			contours = append(contours, c)
+		}
+	}
+
+	// Контур, вложенный в нечетное количество других, - отверстие
+	var area float64
+	for i, inner := range contours {
+		depth := 0
+		for j, outer := range contours {
+			if i == j || !encloses(outer, inner, j < i) {
+				continue
+			}
+			depth++
+		}
+		if depth%2 == 0 {
+			area += inner.area
+		} else {
+			area -= inner.area
+		}
+	}
+
+	analysis.EngraveArea = math.Max(0, area)
+	analysis.BoundingBox = models.BoundingBox{
+		Min:  models.Vec3{X: min.X, Y: min.Y},
+		Max:  models.Vec3{X: max.X, Y: max.Y},
+		Size: models.Vec3{X: max.X - min.X, Y: max.Y - min.Y},
+	}
+
+	return analysis
+}
+
+// encloses проверяет, лежит ли контур inner внутри контура outer.
+// Из двух совпадающих контуров внешним считается идущий раньше (earlier)
+func encloses(outer, inner contour, earlier bool) bool {
+	if outer.area < inner.area || (outer.area == inner.area && !earlier) {
+		return false
+	}
+	if inner.min.X < outer.min.X || inner.min.Y < outer.min.Y ||
+		inner.max.X > outer.max.X || inner.max.Y > outer.max.Y {
+		return false
+	}
+	return containsPoint(outer.points, inner.points[0])
+}
+
+// containsPoint проверяет попадание точки в многоугольник методом трассировки луча
+func containsPoint(polygon []Point, p Point) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// polygonArea возвращает площадь многоугольника (формула шнурования)
+func polygonArea(points []Point) float64 {
+	var sum float64
+	for i, j := 0, len(points)-1; i < len(points); j, i = i, i+1 {
+		sum += points[j].X*points[i].Y - points[i].X*points[j].Y
+	}
+	return math.Abs(sum) / 2
+}
@@ -0,0 +1,173 @@
+package vector
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// MaxPoints максимальное количество точек макета после разбиения кривых на отрезки
+const MaxPoints = 2_000_000
+
+// MaxPaths максимальное количество контуров в макете
+const MaxPaths = 20_000
+
+var (
+	// ErrInvalidDrawing возвращается, если файл макета поврежден или имеет неизвестный формат
+	ErrInvalidDrawing = errors.New("некорректный файл макета")
+
+	// ErrDrawingTooLarge возвращается, если макет содержит слишком много контуров или точек
+	ErrDrawingTooLarge = errors.New("макет содержит слишком много элементов")
+)
+
+// curveSegments количество отрезков, на которые разбивается кривая Безье
+const curveSegments = 16
+
+// arcStep максимальный угол отрезка при разбиении дуг и окружностей (5°)
+const arcStep = math.Pi / 36
+
+// Point точка макета
+type Point struct {
+	X, Y float64
+}
+
+// Path контур макета - ломаная в миллиметрах
+type Path struct {
+	Points []Point
+	Closed bool
+}
+
+// Drawing векторный макет, все кривые которого разбиты на отрезки
+type Drawing struct {
+	Paths []Path
+
+	// Количество элементов, которые не учитываются в расчете (текст, растровые изображения, блоки)
+	Unsupported int
+}
+
+// builder собирает контуры макета, ограничивая их количество
+type builder struct {
+	drawing Drawing
+	current Path
+	points  int
+}
+
+// moveTo начинает новый контур
+func (b *builder) moveTo(p Point) error {
+	if err := b.flush(); err != nil {
+		return err
+	}
+	return b.add(p)
+}
+
+// lineTo добавляет точку к текущему контуру
+func (b *builder) lineTo(p Point) error {
+	return b.add(p)
+}
+
+// closePath замыкает текущий контур
+func (b *builder) closePath() error {
+	b.current.Closed = true
+	return b.flush()
+}
+
+// flush завершает текущий контур; контуры из одной точки отбрасываются
+func (b *builder) flush() error {
+	if len(b.current.Points) >= 2 {
+		if len(b.drawing.Paths) >= MaxPaths {
+			return ErrDrawingTooLarge
+		}
+		b.drawing.Paths = append(b.drawing.Paths, b.current)
+	}
+	b.current = Path{}
+	return nil
+}
+
+func (b *builder) add(p Point) error {
+	if math.IsNaN(p.X) || math.IsNaN(p.Y) || math.IsInf(p.X, 0) || math.IsInf(p.Y, 0) {
+		return fmt.Errorf("%w: некорректные координаты", ErrInvalidDrawing)
+	}
+	b.points++
+	if b.points > MaxPoints {
+		return ErrDrawingTooLarge
+	}
+	b.current.Points = append(b.current.Points, p)
+	return nil
+}
+
+// result завершает построение и возвращает макет
+func (b *builder) result() (*Drawing, error) {
+	if err := b.flush(); err != nil {
+		return nil, err
+	}
+	if len(b.drawing.Paths) == 0 {
+		return nil, fmt.Errorf("%w: макет не содержит контуров", ErrInvalidDrawing)
+	}
+	return &b.drawing, nil
+}
+
+// ellipse разбивает дугу эллипса на точки, как ellipsePoints, предварительно проверяя,
+// что они уместятся в оставшийся лимит точек макета. Углы дуг из файла не ограничены,
+// поэтому точки нельзя выделять до проверки
+func (b *builder) ellipse(center Point, rx, ry, rotation, start, sweep float64) ([]Point, error) {
+	if math.IsNaN(sweep) || math.IsInf(sweep, 0) || math.IsNaN(start) || math.IsInf(start, 0) {
+		return nil, fmt.Errorf("%w: некорректные углы дуги", ErrInvalidDrawing)
+	}
+	if arcSegments(sweep)+1 > MaxPoints-b.points {
+		return nil, ErrDrawingTooLarge
+	}
+	return ellipsePoints(center, rx, ry, rotation, start, sweep), nil
+}
+
+// arcSegments возвращает количество отрезков, на которые разбивается дуга с углом sweep
+func arcSegments(sweep float64) int {
+	segments := math.Ceil(math.Abs(sweep) / arcStep)
+	if segments < 1 {
+		return 1
+	}
+	if segments > MaxPoints {
+		return MaxPoints
+	}
+	return int(segments)
+}
+
+// ellipsePoints разбивает дугу эллипса на точки, включая начальную и конечную.
+// Углы задаются в радианах, sweep положителен для обхода против часовой стрелки.
+// Дуги с углом из файла разбиваются через builder.ellipse
+func ellipsePoints(center Point, rx, ry, rotation, start, sweep float64) []Point {
+	segments := arcSegments(sweep)
+
+	cos, sin := math.Cos(rotation), math.Sin(rotation)
+	points := make([]Point, 0, segments+1)
+	for i := 0; i <= segments; i++ {
+		angle := start + sweep*float64(i)/float64(segments)
+		x, y := rx*math.Cos(angle), ry*math.Sin(angle)
+		points = append(points, Point{
+			X: center.X + x*cos - y*sin,
+			Y: center.Y + x*sin + y*cos,
+		})
+	}
+	return points
+}
+
+// cubicPoint возвращает точку кубической кривой Безье
+func cubicPoint(p0, p1, p2, p3 Point, t float64) Point {
+	u := 1 - t
+	return Point{
+		X: u*u*u*p0.X + 3*u*u*t*p1.X + 3*u*t*t*p2.X + t*t*t*p3.X,
+		Y: u*u*u*p0.Y + 3*u*u*t*p1.Y + 3*u*t*t*p2.Y + t*t*t*p3.Y,
+	}
+}
+
+// quadPoint возвращает точку квадратичной кривой Безье
+func quadPoint(p0, p1, p2 Point, t float64) Point {
+	u := 1 - t
+	return Point{
+		X: u*u*p0.X + 2*u*t*p1.X + t*t*p2.X,
+		Y: u*u*p0.Y + 2*u*t*p1.Y + t*t*p2.Y,
+	}
+}
+
+func distance(a, b Point) float64 {
+	return math.Hypot(b.X-a.X, b.Y-a.Y)
+}
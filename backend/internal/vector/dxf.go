@@ -0,0 +1,318 @@
+package vector
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// dxfUnits размеры единиц чертежа ($INSUNITS) в миллиметрах; без единиц принимаются миллиметры
+var dxfUnits = map[int]float64{
+	0: 1,
+	1: 25.4,
+	2: 304.8,
+	4: 1,
+	5: 10,
+	6: 1000,
+}
+
+// dxfUnsupportedEntities видимые объекты, которые не учитываются в расчете
+var dxfUnsupportedEntities = map[string]bool{
+	"TEXT":      true,
+	"MTEXT":     true,
+	"INSERT":    true,
+	"HATCH":     true,
+	"IMAGE":     true,
+	"DIMENSION": true,
+}
+
+// dxfPair пара «код группы - значение» DXF
+type dxfPair struct {
+	code  int
+	value string
+}
+
+// dxfEntity объект чертежа с его группами
+type dxfEntity struct {
+	kind  string
+	pairs []dxfPair
+}
+
+// float возвращает первое значение группы с указанным кодом
+func (e dxfEntity) float(code int, fallback float64) float64 {
+	for _, pair := range e.pairs {
+		if pair.code == code {
+			if value, err := strconv.ParseFloat(pair.value, 64); err == nil {
+				return value
+			}
+		}
+	}
+	return fallback
+}
+
+func (e dxfEntity) int(code int) int {
+	return int(e.float(code, 0))
+}
+
+// ParseDXF разбирает текстовый DXF: LINE, LWPOLYLINE, POLYLINE, CIRCLE, ARC, ELLIPSE и SPLINE
+// из раздела ENTITIES. Координаты переводятся в миллиметры согласно $INSUNITS.
+// Сплайны приближаются ломаной по определяющим или контрольным точкам
+func ParseDXF(data []byte) (*Drawing, error) {
+	if bytes.HasPrefix(data, []byte("AutoCAD Binary DXF")) {
+		return nil, fmt.Errorf("%w: двоичный DXF не поддерживается", ErrInvalidDrawing)
+	}
+
+	pairs, err := readDXFPairs(data)
+	if err != nil {
+		return nil, err
+	}
+
+	units := 0
+	var entities []dxfEntity
+	section := ""
+	for i := 0; i < len(pairs); i++ {
+		pair := pairs[i]
+		switch {
+		case pair.code == 0 && pair.value == "SECTION" && i+1 < len(pairs) && pairs[i+1].code == 2:
+			section = pairs[i+1].value
+			i++
+		case pair.code == 0 && pair.value == "ENDSEC":
+			section = ""
+		case section == "HEADER" && pair.code == 9 && pair.value == "$INSUNITS" && i+1 < len(pairs):
+			units, _ = strconv.Atoi(pairs[i+1].value)
+			i++
+		case section == "ENTITIES" && pair.code == 0:
+			entities = append(entities, dxfEntity{kind: pair.value})
+		case section == "ENTITIES" && len(entities) > 0:
+			last := &entities[len(entities)-1]
+			last.pairs = append(last.pairs, pair)
+		}
+	}
+
+	scale, ok := dxfUnits[units]
+	if !ok {
+		scale = 1
+	}
+
+	b := &builder{}
+	if err := dxfEntities(b, entities); err != nil {
+		return nil, err
+	}
+	drawing, err := b.result()
+	if err != nil {
+		return nil, err
+	}
+
+	if scale != 1 {
+		for _, path := range drawing.Paths {
+			for i := range path.Points {
+				path.Points[i].X *= scale
+				path.Points[i].Y *= scale
+			}
+		}
+	}
+
+	return drawing, nil
+}
+
+// readDXFPairs читает пары «код группы - значение»
+func readDXFPairs(data []byte) ([]dxfPair, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	// Последняя строка файла может быть пустой
+	if len(lines)%2 == 1 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines)%2 != 0 {
+		return nil, fmt.Errorf("%w: нечетное количество строк DXF", ErrInvalidDrawing)
+	}
+
+	pairs := make([]dxfPair, 0, len(lines)/2)
+	for i := 0; i < len(lines); i += 2 {
+		code, err := strconv.Atoi(strings.TrimSpace(lines[i]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: некорректный код группы в строке %d", ErrInvalidDrawing, i+1)
+		}
+		pairs = append(pairs, dxfPair{code: code, value: strings.TrimSpace(lines[i+1])})
+	}
+	return pairs, nil
+}
+
+// dxfEntities добавляет к макету контуры объектов чертежа
+func dxfEntities(b *builder, entities []dxfEntity) error {
+	for i := 0; i < len(entities); i++ {
+		entity := entities[i]
+
+		var err error
+		switch entity.kind {
+		case "LINE":
+			err = addPolygon(b, identity, []Point{
+				{entity.float(10, 0), entity.float(20, 0)},
+				{entity.float(11, 0), entity.float(21, 0)},
+			}, false)
+		case "LWPOLYLINE":
+			var vertices []Point
+			var bulges []float64
+			for _, pair := range entity.pairs {
+				value, _ := strconv.ParseFloat(pair.value, 64)
+				switch pair.code {
+				case 10:
+					vertices = append(vertices, Point{X: value})
+					bulges = append(bulges, 0)
+				case 20:
+					if len(vertices) > 0 {
+						vertices[len(vertices)-1].Y = value
+					}
+				case 42:
+					if len(bulges) > 0 {
+						bulges[len(bulges)-1] = value
+					}
+				}
+			}
+			err = addBulgePolyline(b, vertices, bulges, entity.int(70)&1 != 0)
+		case "POLYLINE":
+			// Вершины POLYLINE идут отдельными объектами VERTEX до SEQEND
+			var vertices []Point
+			var bulges []float64
+			for i+1 < len(entities) && entities[i+1].kind == "VERTEX" {
+				i++
+				vertex := entities[i]
+				vertices = append(vertices, Point{vertex.float(10, 0), vertex.float(20, 0)})
+				bulges = append(bulges, vertex.float(42, 0))
+			}
+			// Полигональные сетки (флаги 16 и 64) - трехмерные объекты
+			if flags := entity.int(70); flags&(16|64) != 0 {
+				b.drawing.Unsupported++
+			} else {
+				err = addBulgePolyline(b, vertices, bulges, flags&1 != 0)
+			}
+		case "CIRCLE":
+			r := entity.float(40, 0)
+			if r > 0 {
+				center := Point{entity.float(10, 0), entity.float(20, 0)}
+				var points []Point
+				if points, err = b.ellipse(center, r, r, 0, 0, 2*math.Pi); err == nil {
+					err = addPolygon(b, identity, points, true)
+				}
+			}
+		case "ARC":
+			r := entity.float(40, 0)
+			if r > 0 {
+				center := Point{entity.float(10, 0), entity.float(20, 0)}
+				start := entity.float(50, 0) * math.Pi / 180
+				end := entity.float(51, 360) * math.Pi / 180
+				sweep := math.Mod(end-start, 2*math.Pi)
+				if sweep <= 0 {
+					sweep += 2 * math.Pi
+				}
+				var points []Point
+				if points, err = b.ellipse(center, r, r, 0, start, sweep); err == nil {
+					err = addPolygon(b, identity, points, false)
+				}
+			}
+		case "ELLIPSE":
+			center := Point{entity.float(10, 0), entity.float(20, 0)}
+			major := Point{entity.float(11, 0), entity.float(21, 0)}
+			rx := math.Hypot(major.X, major.Y)
+			ry := rx * entity.float(40, 1)
+			if rx > 0 && ry > 0 {
+				start := entity.float(41, 0)
+				end := entity.float(42, 2*math.Pi)
+				// Как и у ARC, угол дуги приводится к (0, 2π]: параметры из файла не ограничены
+				sweep := math.Mod(end-start, 2*math.Pi)
+				if sweep <= 0 {
+					sweep += 2 * math.Pi
+				}
+				full := math.Abs(sweep-2*math.Pi) < 1e-9
+				var points []Point
+				if points, err = b.ellipse(center, rx, ry, math.Atan2(major.Y, major.X), start, sweep); err == nil {
+					err = addPolygon(b, identity, points, full)
+				}
+			}
+		case "SPLINE":
+			// Определяющие точки (11/21) лежат на кривой, контрольные (10/20) - рядом с ней
+			points := dxfPoints(entity, 11, 21)
+			if len(points) < 2 {
+				points = dxfPoints(entity, 10, 20)
+			}
+			err = addPolygon(b, identity, points, entity.int(70)&1 != 0)
+		default:
+			if dxfUnsupportedEntities[entity.kind] {
+				b.drawing.Unsupported++
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dxfPoints возвращает точки объекта, заданные повторяющимися группами координат
+func dxfPoints(entity dxfEntity, xCode, yCode int) []Point {
+	var points []Point
+	for _, pair := range entity.pairs {
+		value, _ := strconv.ParseFloat(pair.value, 64)
+		switch pair.code {
+		case xCode:
+			points = append(points, Point{X: value})
+		case yCode:
+			if len(points) > 0 {
+				points[len(points)-1].Y = value
+			}
+		}
+	}
+	return points
+}
+
+// addBulgePolyline добавляет ломаную DXF; ненулевая выпуклость (bulge) вершины означает дугу
+// до следующей вершины с центральным углом 4*atan(bulge)
+func addBulgePolyline(b *builder, vertices []Point, bulges []float64, closed bool) error {
+	if len(vertices) < 2 {
+		return nil
+	}
+
+	points := []Point{vertices[0]}
+	segments := len(vertices) - 1
+	if closed {
+		segments++
+	}
+	for i := 0; i < segments; i++ {
+		from, to := vertices[i], vertices[(i+1)%len(vertices)]
+		if bulges[i] == 0 {
+			points = append(points, to)
+			continue
+		}
+		points = append(points, bulgePoints(from, to, bulges[i])...)
+	}
+
+	// Последняя точка замкнутой ломаной совпадает с первой
+	if closed {
+		points = points[:len(points)-1]
+	}
+	return addPolygon(b, identity, points, closed)
+}
+
+// bulgePoints разбивает дугу сегмента ломаной на точки, не включая начальную
+func bulgePoints(from, to Point, bulge float64) []Point {
+	chord := distance(from, to)
+	if chord == 0 {
+		return nil
+	}
+
+	angle := 4 * math.Atan(bulge)
+	// Центр лежит на перпендикуляре к середине хорды
+	offset := chord / 2 / math.Tan(angle/2)
+	center := Point{
+		X: (from.X+to.X)/2 - (to.Y-from.Y)/chord*offset,
+		Y: (from.Y+to.Y)/2 + (to.X-from.X)/chord*offset,
+	}
+	radius := distance(center, from)
+	start := math.Atan2(from.Y-center.Y, from.X-center.X)
+
+	points := ellipsePoints(center, radius, radius, 0, start, angle)[1:]
+	points[len(points)-1] = to
+	return points
+}
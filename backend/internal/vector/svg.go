@@ -0,0 +1,658 @@
+package vector
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pixelMM размер пикселя SVG (1/96 дюйма) в миллиметрах
+const pixelMM = 25.4 / 96
+
+// svgUnits размеры единиц длины SVG в миллиметрах
+var svgUnits = map[string]float64{
+	"":   pixelMM,
+	"px": pixelMM,
+	"mm": 1,
+	"cm": 10,
+	"in": 25.4,
+	"pt": 25.4 / 72,
+	"pc": 25.4 / 6,
+}
+
+// svgSkippedElements элементы, содержимое которых не выводится на макет
+var svgSkippedElements = map[string]bool{
+	"defs":     true,
+	"clipPath": true,
+	"mask":     true,
+	"symbol":   true,
+	"marker":   true,
+	"pattern":  true,
+	"metadata": true,
+	"title":    true,
+	"desc":     true,
+	"style":    true,
+	"script":   true,
+}
+
+// svgUnsupportedElements видимые элементы, которые не учитываются в расчете
+var svgUnsupportedElements = map[string]bool{
+	"text":  true,
+	"image": true,
+	"use":   true,
+}
+
+// transformPattern одна функция атрибута transform, например translate(10, 20)
+var transformPattern = regexp.MustCompile(`([a-zA-Z]+)\s*\(([^)]*)\)`)
+
+// matrix аффинное преобразование SVG: x' = a*x + c*y + e, y' = b*x + d*y + f
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul возвращает преобразование, которое сначала применяет n, затем m
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+func (m matrix) apply(p Point) Point {
+	return Point{
+		X: m[0]*p.X + m[2]*p.Y + m[4],
+		Y: m[1]*p.X + m[3]*p.Y + m[5],
+	}
+}
+
+// ParseSVG разбирает SVG и переводит контуры фигур (path, rect, circle, ellipse, line,
+// polyline, polygon) в миллиметры с учетом атрибутов width, height, viewBox и transform.
+// Текст и растровые изображения не учитываются и подсчитываются в Drawing.Unsupported
+func ParseSVG(data []byte) (*Drawing, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	b := &builder{}
+	var stack []matrix
+	root := true
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDrawing, err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			parent := identity
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+
+			// Корневой элемент задает размеры макета в единицах длины
+			if root {
+				if element.Name.Local != "svg" {
+					return nil, fmt.Errorf("%w: корневой элемент не svg", ErrInvalidDrawing)
+				}
+				parent = svgViewport(element)
+				root = false
+			}
+
+			name := element.Name.Local
+			if svgSkippedElements[name] || svgAttr(element, "display") == "none" {
+				if err := decoder.Skip(); err != nil {
+					return nil, fmt.Errorf("%w: %v", ErrInvalidDrawing, err)
+				}
+				continue
+			}
+			if svgUnsupportedElements[name] {
+				b.drawing.Unsupported++
+				if err := decoder.Skip(); err != nil {
+					return nil, fmt.Errorf("%w: %v", ErrInvalidDrawing, err)
+				}
+				continue
+			}
+
+			m := parent
+			if transform := svgAttr(element, "transform"); transform != "" {
+				own, err := parseTransform(transform)
+				if err != nil {
+					return nil, err
+				}
+				m = parent.mul(own)
+			}
+			stack = append(stack, m)
+
+			if err := svgShape(b, element, m); err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	if root {
+		return nil, fmt.Errorf("%w: файл не содержит SVG", ErrInvalidDrawing)
+	}
+
+	return b.result()
+}
+
+// svgViewport возвращает преобразование пользовательских координат корневого элемента в миллиметры
+func svgViewport(element xml.StartElement) matrix {
+	width, widthOK := parseLength(svgAttr(element, "width"))
+	height, heightOK := parseLength(svgAttr(element, "height"))
+
+	viewBox := parseNumbers(svgAttr(element, "viewBox"))
+	if len(viewBox) != 4 || viewBox[2] <= 0 || viewBox[3] <= 0 {
+		return matrix{pixelMM, 0, 0, pixelMM, 0, 0}
+	}
+
+	// Без размеров единица viewBox считается пикселем
+	sx, sy := pixelMM, pixelMM
+	if widthOK {
+		sx = width / viewBox[2]
+	}
+	if heightOK {
+		sy = height / viewBox[3]
+	}
+	if widthOK && !heightOK {
+		sy = sx
+	}
+	if heightOK && !widthOK {
+		sx = sy
+	}
+
+	return matrix{sx, 0, 0, sy, -viewBox[0] * sx, -viewBox[1] * sy}
+}
+
+// svgShape добавляет к макету контур фигуры
+func svgShape(b *builder, element xml.StartElement, m matrix) error {
+	number := func(name string) float64 {
+		value, _ := parseLength(svgAttr(element, name))
+		return value / pixelMM
+	}
+
+	switch element.Name.Local {
+	case "path":
+		return parsePathData(b, svgAttr(element, "d"), m)
+	case "rect":
+		x, y, width, height := number("x"), number("y"), number("width"), number("height")
+		if width <= 0 || height <= 0 {
+			return nil
+		}
+		// Скругления углов (rx, ry) не учитываются: погрешность длины реза незначительна
+		return addPolygon(b, m, []Point{{x, y}, {x + width, y}, {x + width, y + height}, {x, y + height}}, true)
+	case "circle":
+		r := number("r")
+		if r <= 0 {
+			return nil
+		}
+		return addPolygon(b, m, ellipsePoints(Point{number("cx"), number("cy")}, r, r, 0, 0, 2*math.Pi), true)
+	case "ellipse":
+		rx, ry := number("rx"), number("ry")
+		if rx <= 0 || ry <= 0 {
+			return nil
+		}
+		return addPolygon(b, m, ellipsePoints(Point{number("cx"), number("cy")}, rx, ry, 0, 0, 2*math.Pi), true)
+	case "line":
+		return addPolygon(b, m, []Point{{number("x1"), number("y1")}, {number("x2"), number("y2")}}, false)
+	case "polyline", "polygon":
+		values := parseNumbers(svgAttr(element, "points"))
+		points := make([]Point, 0, len(values)/2)
+		for i := 0; i+1 < len(values); i += 2 {
+			points = append(points, Point{values[i], values[i+1]})
+		}
+		return addPolygon(b, m, points, element.Name.Local == "polygon")
+	}
+
+	return nil
+}
+
+// addPolygon добавляет к макету ломаную, заданную в пользовательских координатах
+func addPolygon(b *builder, m matrix, points []Point, closed bool) error {
+	if len(points) < 2 {
+		return nil
+	}
+	if err := b.moveTo(m.apply(points[0])); err != nil {
+		return err
+	}
+	for _, p := range points[1:] {
+		if err := b.lineTo(m.apply(p)); err != nil {
+			return err
+		}
+	}
+	if closed {
+		return b.closePath()
+	}
+	return b.flush()
+}
+
+// parsePathData разбирает атрибут d элемента path
+func parsePathData(b *builder, data string, m matrix) error {
+	scanner := &pathScanner{data: data}
+
+	var command, previous byte
+	var current, start, control Point
+	closed := false
+
+	lineTo := func(p Point) error {
+		// Рисование после Z без команды M продолжается из начала замкнутого контура
+		if closed {
+			closed = false
+			if err := b.moveTo(m.apply(start)); err != nil {
+				return err
+			}
+		}
+		current = p
+		return b.lineTo(m.apply(p))
+	}
+
+	for {
+		scanner.skipSeparators()
+		if scanner.done() {
+			break
+		}
+
+		if c := scanner.peek(); isPathCommand(c) {
+			command = c
+			scanner.pos++
+		} else if command == 0 {
+			return fmt.Errorf("%w: некорректные данные контура", ErrInvalidDrawing)
+		}
+
+		relative := command >= 'a'
+		base := Point{}
+		if relative {
+			base = current
+		}
+
+		var err error
+		switch command | 0x20 {
+		case 'm':
+			var p Point
+			if p, err = scanner.point(base); err != nil {
+				return err
+			}
+			current, start, closed = p, p, false
+			if err := b.moveTo(m.apply(p)); err != nil {
+				return err
+			}
+			// Последующие пары координат после M - это команды L
+			command = 'L' | (command & 0x20)
+		case 'l':
+			var p Point
+			if p, err = scanner.point(base); err != nil {
+				return err
+			}
+			err = lineTo(p)
+		case 'h':
+			var x float64
+			if x, err = scanner.number(); err != nil {
+				return err
+			}
+			if relative {
+				x += current.X
+			}
+			err = lineTo(Point{x, current.Y})
+		case 'v':
+			var y float64
+			if y, err = scanner.number(); err != nil {
+				return err
+			}
+			if relative {
+				y += current.Y
+			}
+			err = lineTo(Point{current.X, y})
+		case 'c', 's':
+			var c1, c2, p Point
+			if command|0x20 == 'c' {
+				if c1, err = scanner.point(base); err != nil {
+					return err
+				}
+			} else {
+				// Первая контрольная точка - отражение предыдущей
+				c1 = current
+				if previous|0x20 == 'c' || previous|0x20 == 's' {
+					c1 = Point{2*current.X - control.X, 2*current.Y - control.Y}
+				}
+			}
+			if c2, err = scanner.point(base); err != nil {
+				return err
+			}
+			if p, err = scanner.point(base); err != nil {
+				return err
+			}
+			p0 := current
+			for i := 1; i <= curveSegments && err == nil; i++ {
+				err = lineTo(cubicPoint(p0, c1, c2, p, float64(i)/curveSegments))
+			}
+			control = c2
+		case 'q', 't':
+			var c1, p Point
+			if command|0x20 == 'q' {
+				if c1, err = scanner.point(base); err != nil {
+					return err
+				}
+			} else {
+				c1 = current
+				if previous|0x20 == 'q' || previous|0x20 == 't' {
+					c1 = Point{2*current.X - control.X, 2*current.Y - control.Y}
+				}
+			}
+			if p, err = scanner.point(base); err != nil {
+				return err
+			}
+			p0 := current
+			for i := 1; i <= curveSegments && err == nil; i++ {
+				err = lineTo(quadPoint(p0, c1, p, float64(i)/curveSegments))
+			}
+			control = c1
+		case 'a':
+			var rx, ry, rotation float64
+			var large, sweep bool
+			var p Point
+			if rx, err = scanner.number(); err != nil {
+				return err
+			}
+			if ry, err = scanner.number(); err != nil {
+				return err
+			}
+			if rotation, err = scanner.number(); err != nil {
+				return err
+			}
+			if large, err = scanner.flag(); err != nil {
+				return err
+			}
+			if sweep, err = scanner.flag(); err != nil {
+				return err
+			}
+			if p, err = scanner.point(base); err != nil {
+				return err
+			}
+			for _, point := range arcPoints(current, p, rx, ry, rotation, large, sweep) {
+				if err = lineTo(point); err != nil {
+					break
+				}
+			}
+		case 'z':
+			current = start
+			closed = true
+			err = b.closePath()
+			// После Z координаты без новой команды недопустимы
+			previous, command = command, 0
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		previous = command
+	}
+
+	return b.flush()
+}
+
+// arcPoints разбивает эллиптическую дугу SVG на точки, не включая начальную
+func arcPoints(from, to Point, rx, ry, rotation float64, large, sweep bool) []Point {
+	if from == to {
+		return nil
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		return []Point{to}
+	}
+
+	// Перевод параметров дуги в параметры с центром (SVG 1.1, приложение F.6.5)
+	phi := rotation * math.Pi / 180
+	cos, sin := math.Cos(phi), math.Sin(phi)
+	dx, dy := (from.X-to.X)/2, (from.Y-to.Y)/2
+	x1 := cos*dx + sin*dy
+	y1 := -sin*dx + cos*dy
+
+	if lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry); lambda > 1 {
+		rx *= math.Sqrt(lambda)
+		ry *= math.Sqrt(lambda)
+	}
+
+	numerator := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	denominator := rx*rx*y1*y1 + ry*ry*x1*x1
+	coefficient := math.Sqrt(math.Max(0, numerator/denominator))
+	if large == sweep {
+		coefficient = -coefficient
+	}
+	cx1 := coefficient * rx * y1 / ry
+	cy1 := -coefficient * ry * x1 / rx
+
+	center := Point{
+		X: cos*cx1 - sin*cy1 + (from.X+to.X)/2,
+		Y: sin*cx1 + cos*cy1 + (from.Y+to.Y)/2,
+	}
+
+	startAngle := math.Atan2((y1-cy1)/ry, (x1-cx1)/rx)
+	endAngle := math.Atan2((-y1-cy1)/ry, (-x1-cx1)/rx)
+	delta := endAngle - startAngle
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+
+	points := ellipsePoints(center, rx, ry, phi, startAngle, delta)[1:]
+	// Последняя точка совпадает с конечной точкой дуги без погрешности вычислений
+	points[len(points)-1] = to
+	return points
+}
+
+// parseTransform разбирает атрибут transform
+func parseTransform(value string) (matrix, error) {
+	result := identity
+	for _, match := range transformPattern.FindAllStringSubmatch(value, -1) {
+		args := parseNumbers(match[2])
+		arg := func(i int, fallback float64) float64 {
+			if i < len(args) {
+				return args[i]
+			}
+			return fallback
+		}
+
+		var m matrix
+		switch match[1] {
+		case "matrix":
+			if len(args) != 6 {
+				return identity, fmt.Errorf("%w: некорректное преобразование %q", ErrInvalidDrawing, match[0])
+			}
+			copy(m[:], args)
+		case "translate":
+			m = matrix{1, 0, 0, 1, arg(0, 0), arg(1, 0)}
+		case "scale":
+			sx := arg(0, 1)
+			m = matrix{sx, 0, 0, arg(1, sx), 0, 0}
+		case "rotate":
+			angle := arg(0, 0) * math.Pi / 180
+			cos, sin := math.Cos(angle), math.Sin(angle)
+			cx, cy := arg(1, 0), arg(2, 0)
+			m = matrix{1, 0, 0, 1, cx, cy}.
+				mul(matrix{cos, sin, -sin, cos, 0, 0}).
+				mul(matrix{1, 0, 0, 1, -cx, -cy})
+		case "skewX":
+			m = matrix{1, 0, math.Tan(arg(0, 0) * math.Pi / 180), 1, 0, 0}
+		case "skewY":
+			m = matrix{1, math.Tan(arg(0, 0) * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			return identity, fmt.Errorf("%w: неизвестное преобразование %q", ErrInvalidDrawing, match[1])
+		}
+		result = result.mul(m)
+	}
+	return result, nil
+}
+
+// parseLength переводит длину SVG с единицами измерения в миллиметры.
+// Относительные единицы (%, em) не поддерживаются
+func parseLength(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	end := len(value)
+	for end > 0 && (value[end-1] >= 'a' && value[end-1] <= 'z' || value[end-1] == '%') {
+		end--
+	}
+	unit, ok := svgUnits[value[end:]]
+	if !ok {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(value[:end], 64)
+	if err != nil {
+		return 0, false
+	}
+	return number * unit, true
+}
+
+// parseNumbers разбирает список чисел, разделенных пробелами или запятыми
+func parseNumbers(value string) []float64 {
+	scanner := &pathScanner{data: value}
+	var numbers []float64
+	for {
+		scanner.skipSeparators()
+		if scanner.done() {
+			return numbers
+		}
+		number, err := scanner.number()
+		if err != nil {
+			return numbers
+		}
+		numbers = append(numbers, number)
+	}
+}
+
+func svgAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	// Свойство может быть задано в атрибуте style
+	for _, attr := range element.Attr {
+		if attr.Name.Local != "style" {
+			continue
+		}
+		for _, declaration := range strings.Split(attr.Value, ";") {
+			key, value, found := strings.Cut(declaration, ":")
+			if found && strings.TrimSpace(key) == name {
+				return strings.TrimSpace(value)
+			}
+		}
+	}
+	return ""
+}
+
+func isPathCommand(c byte) bool {
+	return strings.IndexByte("MmLlHhVvCcSsQqTtAaZz", c) >= 0
+}
+
+// pathScanner читает числа и флаги из данных контура SVG
+type pathScanner struct {
+	data string
+	pos  int
+}
+
+func (s *pathScanner) done() bool {
+	return s.pos >= len(s.data)
+}
+
+func (s *pathScanner) peek() byte {
+	return s.data[s.pos]
+}
+
+func (s *pathScanner) skipSeparators() {
+	for !s.done() && strings.IndexByte(" \t\r\n,", s.peek()) >= 0 {
+		s.pos++
+	}
+}
+
+// number читает число; числа могут идти без разделителя, например "1.5.5-2" - это 1.5, .5 и -2
+func (s *pathScanner) number() (float64, error) {
+	s.skipSeparators()
+	start := s.pos
+	if !s.done() && (s.peek() == '+' || s.peek() == '-') {
+		s.pos++
+	}
+	digits, dot := false, false
+	for !s.done() {
+		c := s.peek()
+		if c >= '0' && c <= '9' {
+			digits = true
+		} else if c == '.' && !dot {
+			dot = true
+		} else {
+			break
+		}
+		s.pos++
+	}
+	if digits && !s.done() && (s.peek() == 'e' || s.peek() == 'E') {
+		exponent := s.pos
+		s.pos++
+		if !s.done() && (s.peek() == '+' || s.peek() == '-') {
+			s.pos++
+		}
+		expDigits := false
+		for !s.done() && s.peek() >= '0' && s.peek() <= '9' {
+			s.pos++
+			expDigits = true
+		}
+		if !expDigits {
+			s.pos = exponent
+		}
+	}
+
+	if !digits {
+		return 0, fmt.Errorf("%w: ожидалось число в позиции %d", ErrInvalidDrawing, start)
+	}
+	value, err := strconv.ParseFloat(s.data[start:s.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: некорректное число %q", ErrInvalidDrawing, s.data[start:s.pos])
+	}
+	return value, nil
+}
+
+// flag читает флаг дуги; флаги могут идти без разделителя, например "a1 1 0 01 5 5"
+func (s *pathScanner) flag() (bool, error) {
+	s.skipSeparators()
+	if s.done() || (s.peek() != '0' && s.peek() != '1') {
+		return false, fmt.Errorf("%w: некорректный флаг дуги в позиции %d", ErrInvalidDrawing, s.pos)
+	}
+	value := s.peek() == '1'
+	s.pos++
+	return value, nil
+}
+
+// point читает пару координат, смещенную на base для относительных команд
+func (s *pathScanner) point(base Point) (Point, error) {
+	x, err := s.number()
+	if err != nil {
+		return Point{}, err
+	}
+	y, err := s.number()
+	if err != nil {
+		return Point{}, err
+	}
+	return Point{base.X + x, base.Y + y}, nil
+}
@@ -10,6 +10,9 @@ export interface Product {
   subcategory_id?: number;
   characteristics?: Record<string, string>;
   images: string[];
+  min_order_quantity?: number;
+  rating_avg?: number;
+  rating_count?: number;
   related_products: Product[];
   translations?: Record<string, { name: string; description: string }>;
 }
@@ -57,6 +60,193 @@ export interface OrderData {
   items: OrderItem[];
   language: LangType
   recaptchaResponse: string;
+  draft_id?: string;
+}
+
+export interface CartItem {
+  id: number;
+  product_id: number;
+  variant_id?: number;
+  quantity: number;
+  price?: number;
+  previous_price?: number;
+  product_name?: string;
+  product_image?: string;
+  variant_name?: string;
+  available: boolean;
+  warning?: string;
+}
+
+export interface Cart {
+  id: string;
+  order_id?: number;
+  items: CartItem[];
+  subtotal: number;
+  currency: string;
+  expires_at: string;
+}
+
+export interface CartCheckoutData {
+  name: string;
+  email: string;
+  phone: string;
+  comment?: string;
+  language: LangType;
+  promo_code?: string;
+  shipping_method?: string;
+  draft_id?: string;
+}
+
+export interface CheckoutDraftItem {
+  product_id: number;
+  variant_id?: number;
+  quantity: number;
+}
+
+export interface CheckoutDraftData {
+  email: string;
+  name?: string;
+  phone?: string;
+  language: LangType;
+  cart_id?: string;
+  items: CheckoutDraftItem[];
+}
+
+export interface CheckoutDraft extends CheckoutDraftData {
+  id: string;
+  order_id?: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ShippingAddress {
+  country: string;
+  region?: string;
+  city: string;
+  postal_code?: string;
+  street: string;
+  apartment?: string;
+}
+
+export interface Customer {
+  id: number;
+  email: string;
+  name: string;
+  phone: string;
+  created_at: string;
+  updated_at: string;
+  last_login_at?: string;
+}
+
+export interface CustomerLogin {
+  token: string;
+  expires_at: string;
+  customer: Customer;
+}
+
+export interface CustomerAddressData {
+  label?: string;
+  address: ShippingAddress;
+  is_default?: boolean;
+}
+
+export interface CustomerAddress extends CustomerAddressData {
+  id: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface CustomerOrder {
+  id: number;
+  status: string;
+  total_cost: number;
+  currency: string;
+  payment_status: string;
+  shipping_method_name?: string;
+  shipping_address?: ShippingAddress;
+  created_at: string;
+}
+
+export interface CustomerOrderList {
+  items: CustomerOrder[];
+  total_items: number;
+  page: number;
+  page_size: number;
+  total_pages: number;
+}
+
+export interface WishlistItem {
+  product_id: number;
+  notify_price_drop: boolean;
+  product_name?: string;
+  product_image?: string;
+  price?: number;
+  currency?: string;
+  available: boolean;
+  created_at: string;
+}
+
+export interface Wishlist {
+  id: string;
+  share_id?: string;
+  share_url?: string;
+  items: WishlistItem[];
+  updated_at: string;
+}
+
+export interface SharedWishlist {
+  items: WishlistItem[];
+  updated_at: string;
+}
+
+export interface Review {
+  id: number;
+  product_id: number;
+  author_name: string;
+  rating: number;
+  text: string;
+  language: string;
+  reply?: string;
+  replied_at?: string;
+  photos: string[];
+  created_at: string;
+}
+
+export interface ReviewList {
+  items: Review[];
+  total_items: number;
+  page: number;
+  page_size: number;
+  total_pages: number;
+}
+
+export interface ReviewOrderItem {
+  product_id: number;
+  product_name: string;
+  product_image?: string;
+  reviewed: boolean;
+}
+
+export interface ReviewOrder {
+  order_id: number;
+  name: string;
+  items: ReviewOrderItem[];
+}
+
+export interface ReviewData {
+  product_id: number;
+  rating: number;
+  text: string;
+  author_name: string;
+  language: string;
+  photos: string[];
+}
+
+// Подписанная ссылка для отзыва из письма после выполнения заказа
+export interface ReviewLink {
+  order: string;
+  expires: string;
+  signature: string;
 }
 
 export interface APIResponse<T> {
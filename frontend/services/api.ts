@@ -9,6 +9,21 @@ import type {
   GalleryItem,
   ContactFormData,
   OrderData,
+  Cart,
+  CartCheckoutData,
+  CheckoutDraft,
+  CheckoutDraftData,
+  Customer,
+  CustomerLogin,
+  CustomerAddress,
+  CustomerAddressData,
+  CustomerOrderList,
+  Wishlist,
+  SharedWishlist,
+  ReviewList,
+  ReviewOrder,
+  ReviewData,
+  ReviewLink,
 } from "../components";
 
 // API сервис
@@ -131,20 +146,312 @@ export const useApiService = () => {
     return "Произошла неизвестная ошибка";
   };
 
-  // Отправка формы обратной связи
-  const submitContactForm = (formData: ContactFormData) => {
-    return fetchApi<{ message: string }>("/contact", {
+  // Ключи идемпотентности по эндпоинтам: повторная отправка тех же данных (двойной клик,
+  // повтор после сбоя сети) идет с тем же ключом, и сервер не создает дубликат
+  const idempotencyKeys = new Map<string, { body: string; key: string }>();
+
+  const generateIdempotencyKey = () =>
+    typeof crypto !== "undefined" && "randomUUID" in crypto
+      ? crypto.randomUUID()
+      : `${Date.now()}-${Math.random().toString(36).slice(2)}`;
+
+  // Отправка данных с заголовком Idempotency-Key; после успешной отправки ключ сбрасывается
+  const postIdempotent = async <T>(
+    endpoint: string,
+    payload: unknown,
+    options: RequestInit = {}
+  ) => {
+    const body = JSON.stringify(payload);
+    let entry = idempotencyKeys.get(endpoint);
+    if (!entry || entry.body !== body) {
+      entry = { body, key: generateIdempotencyKey() };
+      idempotencyKeys.set(endpoint, entry);
+    }
+
+    const response = await fetchApi<T>(endpoint, {
+      ...options,
       method: "POST",
-      body: JSON.stringify(formData),
+      body,
+      headers: { "Idempotency-Key": entry.key },
     });
+    if (response.success) {
+      idempotencyKeys.delete(endpoint);
+    }
+    return response;
+  };
+
+  // Отправка формы обратной связи
+  const submitContactForm = (formData: ContactFormData) => {
+    return postIdempotent<{ message: string }>("/contact", formData);
   };
 
   // Создание заказа
   const createOrder = (orderData: OrderData) => {
-    return fetchApi<{ order_id: number; message: string }>("/orders", {
-      method: "POST",
-      body: JSON.stringify(orderData),
-    });
+    return postIdempotent<{ order_id: number; message: string }>(
+      "/orders",
+      orderData
+    );
+  };
+
+  // Корзина на сервере; доступ к ней дает cookie, поэтому запросы отправляются с учетными данными
+  const cartRequest: RequestInit = { credentials: "include" };
+
+  // Создание пустой корзины
+  const createCart = (language: string = "ru") => {
+    return fetchApi<Cart>("/carts", { ...cartRequest, method: "POST" }, language);
+  };
+
+  // Получение корзины с актуальными ценами
+  const getCart = (cartId: string, language: string = "ru") => {
+    return fetchApi<Cart>(`/carts/${cartId}`, cartRequest, language);
+  };
+
+  // Добавление товара в корзину
+  const addCartItem = (
+    cartId: string,
+    item: { product_id: number; variant_id?: number; quantity: number },
+    language: string = "ru"
+  ) => {
+    return fetchApi<Cart>(
+      `/carts/${cartId}/items`,
+      { ...cartRequest, method: "POST", body: JSON.stringify(item) },
+      language
+    );
+  };
+
+  // Изменение количества в позиции корзины
+  const updateCartItem = (
+    cartId: string,
+    itemId: number,
+    quantity: number,
+    language: string = "ru"
+  ) => {
+    return fetchApi<Cart>(
+      `/carts/${cartId}/items/${itemId}`,
+      { ...cartRequest, method: "PATCH", body: JSON.stringify({ quantity }) },
+      language
+    );
+  };
+
+  // Удаление позиции из корзины
+  const removeCartItem = (cartId: string, itemId: number, language: string = "ru") => {
+    return fetchApi<Cart>(
+      `/carts/${cartId}/items/${itemId}`,
+      { ...cartRequest, method: "DELETE" },
+      language
+    );
+  };
+
+  // Оформление заказа из корзины
+  const checkoutCart = (cartId: string, data: CartCheckoutData) => {
+    return postIdempotent<{ order_id: number; message: string }>(
+      `/carts/${cartId}/checkout`,
+      data,
+      cartRequest
+    );
+  };
+
+  // Сохранение черновика заказа: без ID создается новый черновик
+  const saveCheckoutDraft = (draftId: string | null, data: CheckoutDraftData) => {
+    return fetchApi<CheckoutDraft>(
+      draftId ? `/checkout-drafts/${draftId}` : "/checkout-drafts",
+      { method: draftId ? "PUT" : "POST", body: JSON.stringify(data) },
+      data.language
+    );
+  };
+
+  // Получение черновика заказа по ссылке из напоминания
+  const getCheckoutDraft = (draftId: string, language: string = "ru") => {
+    return fetchApi<CheckoutDraft>(`/checkout-drafts/${draftId}`, {}, language);
+  };
+
+  // Отказ от напоминаний по подписанной ссылке из письма
+  const unsubscribeReminders = (
+    params: { email: string; expires: string; signature: string },
+    language: string = "ru"
+  ) => {
+    const query = new URLSearchParams(params).toString();
+    return fetchApi<{ message: string }>(
+      `/reminders/unsubscribe?${query}`,
+      { method: "POST" },
+      language
+    );
+  };
+
+  // Запрос ссылки для входа в личный кабинет на email
+  const requestLoginLink = (email: string, language: string = "ru") => {
+    return fetchApi<{ message: string }>(
+      "/auth/magic-link",
+      { method: "POST", body: JSON.stringify({ email, language }) },
+      language
+    );
+  };
+
+  // Вход по токену из ссылки; в ответе - токен личного кабинета
+  const verifyLoginLink = (token: string, language: string = "ru") => {
+    return fetchApi<CustomerLogin>(
+      "/auth/magic-link/verify",
+      { method: "POST", body: JSON.stringify({ token }) },
+      language
+    );
+  };
+
+  // Запросы личного кабинета передают токен покупателя
+  const customerRequest = (token: string, options: RequestInit = {}): RequestInit => ({
+    ...options,
+    headers: { Authorization: `Bearer ${token}`, ...options.headers },
+  });
+
+  const getProfile = (token: string, language: string = "ru") => {
+    return fetchApi<Customer>("/me", customerRequest(token), language);
+  };
+
+  const updateProfile = (
+    token: string,
+    data: { name: string; phone: string },
+    language: string = "ru"
+  ) => {
+    return fetchApi<Customer>(
+      "/me",
+      customerRequest(token, { method: "PATCH", body: JSON.stringify(data) }),
+      language
+    );
+  };
+
+  const getMyOrders = (token: string, page = 1, pageSize = 10) => {
+    return fetchApi<CustomerOrderList>(
+      `/me/orders?page=${page}&page_size=${pageSize}`,
+      customerRequest(token)
+    );
+  };
+
+  const getAddresses = (token: string, language: string = "ru") => {
+    return fetchApi<CustomerAddress[]>("/me/addresses", customerRequest(token), language);
+  };
+
+  // Сохранение адреса: без ID создается новый адрес
+  const saveAddress = (
+    token: string,
+    addressId: number | null,
+    data: CustomerAddressData,
+    language: string = "ru"
+  ) => {
+    return fetchApi<CustomerAddress>(
+      addressId ? `/me/addresses/${addressId}` : "/me/addresses",
+      customerRequest(token, {
+        method: addressId ? "PUT" : "POST",
+        body: JSON.stringify(data),
+      }),
+      language
+    );
+  };
+
+  const deleteAddress = (token: string, addressId: number, language: string = "ru") => {
+    return fetchApi<null>(
+      `/me/addresses/${addressId}`,
+      customerRequest(token, { method: "DELETE" }),
+      language
+    );
+  };
+
+  // Запросы к списку избранного передают cookie списка без личного кабинета
+  // и токен покупателя, если он вошел в личный кабинет
+  const wishlistRequest = (token: string | null, options: RequestInit = {}): RequestInit =>
+    token ? { ...customerRequest(token, options), credentials: "include" } : { ...options, credentials: "include" };
+
+  const getWishlist = (token: string | null, language: string = "ru") => {
+    return fetchApi<Wishlist>("/wishlist", wishlistRequest(token), language);
+  };
+
+  const addWishlistItem = (
+    token: string | null,
+    productId: number,
+    notifyPriceDrop = false,
+    language: string = "ru"
+  ) => {
+    return fetchApi<Wishlist>(
+      "/wishlist/items",
+      wishlistRequest(token, {
+        method: "POST",
+        body: JSON.stringify({ product_id: productId, notify_price_drop: notifyPriceDrop }),
+      }),
+      language
+    );
+  };
+
+  const updateWishlistItem = (
+    token: string | null,
+    productId: number,
+    notifyPriceDrop: boolean,
+    language: string = "ru"
+  ) => {
+    return fetchApi<Wishlist>(
+      `/wishlist/items/${productId}`,
+      wishlistRequest(token, {
+        method: "PATCH",
+        body: JSON.stringify({ notify_price_drop: notifyPriceDrop }),
+      }),
+      language
+    );
+  };
+
+  const removeWishlistItem = (token: string | null, productId: number, language: string = "ru") => {
+    return fetchApi<Wishlist>(
+      `/wishlist/items/${productId}`,
+      wishlistRequest(token, { method: "DELETE" }),
+      language
+    );
+  };
+
+  // Открытие списка избранного по публичной ссылке и закрытие ссылки
+  const shareWishlist = (token: string | null, language: string = "ru") => {
+    return fetchApi<{ share_id: string; share_url: string }>(
+      "/wishlist/share",
+      wishlistRequest(token, { method: "POST" }),
+      language
+    );
+  };
+
+  const unshareWishlist = (token: string | null, language: string = "ru") => {
+    return fetchApi<null>("/wishlist/share", wishlistRequest(token, { method: "DELETE" }), language);
+  };
+
+  // Просмотр чужого списка избранного по публичной ссылке
+  const getSharedWishlist = (shareId: string, language: string = "ru") => {
+    return fetchApi<SharedWishlist>(`/wishlists/shared/${shareId}`, {}, language);
+  };
+
+  // Опубликованные отзывы о товаре
+  const getProductReviews = (productId: number, page = 1, language: string = "ru") => {
+    return fetchApi<ReviewList>(`/products/${productId}/reviews?page=${page}`, {}, language);
+  };
+
+  // Отзыв о товарах выполненного заказа по подписанной ссылке из письма
+  const reviewQuery = (link: ReviewLink) =>
+    new URLSearchParams({ expires: link.expires, signature: link.signature }).toString();
+
+  const getReviewOrder = (link: ReviewLink, language: string = "ru") => {
+    return fetchApi<ReviewOrder>(`/orders/${link.order}/reviews?${reviewQuery(link)}`, {}, language);
+  };
+
+  const createReview = (link: ReviewLink, data: ReviewData, language: string = "ru") => {
+    return fetchApi<{ id: number; status: string }>(
+      `/orders/${link.order}/reviews?${reviewQuery(link)}`,
+      { method: "POST", body: JSON.stringify(data) },
+      language
+    );
+  };
+
+  // Загрузка фотографии к отзыву; fetchApi отправляет JSON, поэтому файл отправляется напрямую
+  const uploadReviewPhoto = async (file: File): Promise<APIResponse<{ id: string }>> => {
+    const form = new FormData();
+    form.append("file", file);
+    try {
+      const response = await fetch(`${API_BASE_URL}/uploads`, { method: "POST", body: form });
+      return await response.json();
+    } catch (err) {
+      return { success: false, error: handleApiError(err) } as APIResponse<{ id: string }>;
+    }
   };
 
   return {
@@ -157,6 +464,34 @@ export const useApiService = () => {
     getGalleryItems,
     submitContactForm,
     createOrder,
+    createCart,
+    getCart,
+    addCartItem,
+    updateCartItem,
+    removeCartItem,
+    checkoutCart,
+    saveCheckoutDraft,
+    getCheckoutDraft,
+    unsubscribeReminders,
+    requestLoginLink,
+    verifyLoginLink,
+    getProfile,
+    updateProfile,
+    getMyOrders,
+    getAddresses,
+    saveAddress,
+    deleteAddress,
+    getWishlist,
+    addWishlistItem,
+    updateWishlistItem,
+    removeWishlistItem,
+    shareWishlist,
+    unshareWishlist,
+    getSharedWishlist,
+    getProductReviews,
+    getReviewOrder,
+    createReview,
+    uploadReviewPhoto,
     handleApiError,
   };
 };
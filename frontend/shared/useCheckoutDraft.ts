@@ -0,0 +1,99 @@
+import { ref } from "vue";
+import { useApiService } from "~/services/api";
+import { useCart } from "~/shared/useCart";
+import type { CheckoutDraftData, LangType } from "~/components";
+
+// ID черновика заказа хранится между сеансами, чтобы изменять тот же черновик
+const DRAFT_STORAGE_KEY = "prianik-checkout-draft";
+
+// Задержка сохранения черновика после последнего изменения формы, мс
+const SAVE_DELAY = 2000;
+
+// Контакты из восстановленного черновика для заполнения формы заказа
+const restoredContacts = ref<{ name?: string; email?: string; phone?: string } | null>(null);
+
+let saveTimer: ReturnType<typeof setTimeout> | undefined;
+
+const getDraftId = () => (process.client ? localStorage.getItem(DRAFT_STORAGE_KEY) : null);
+
+const setDraftId = (id: string | null) => {
+  if (!process.client) return;
+  if (id) {
+    localStorage.setItem(DRAFT_STORAGE_KEY, id);
+  } else {
+    localStorage.removeItem(DRAFT_STORAGE_KEY);
+  }
+};
+
+// Проверка email перед сохранением черновика; сервер проверяет формат окончательно
+const isEmail = (email: string) => /^[^\s@]+@[^\s@]+\.[^\s@]+$/.test(email);
+
+export const useCheckoutDraft = () => {
+  const { saveCheckoutDraft, getCheckoutDraft, getProductById } = useApiService();
+  const { cart, addProductToCart } = useCart();
+
+  // Сохраняет черновик с задержкой, если указан email и в корзине есть товары
+  const scheduleSave = (data: CheckoutDraftData) => {
+    clearTimeout(saveTimer);
+    if (!isEmail(data.email.trim()) || data.items.length === 0) return;
+
+    saveTimer = setTimeout(async () => {
+      try {
+        let response = await saveCheckoutDraft(getDraftId(), data);
+        // Черновик удален или по нему уже оформлен заказ - начинаем новый
+        if (!response.success && getDraftId()) {
+          setDraftId(null);
+          response = await saveCheckoutDraft(null, data);
+        }
+        if (response.success) {
+          setDraftId(response.data.id);
+        }
+      } catch (error) {
+        console.error("Ошибка при сохранении черновика заказа:", error);
+      }
+    }, SAVE_DELAY);
+  };
+
+  // Восстанавливает корзину и контакты по ссылке из напоминания; товары,
+  // которые уже есть в корзине, не добавляются повторно
+  const restoreDraft = async (id: string, language: LangType) => {
+    const response = await getCheckoutDraft(id, language);
+    if (!response.success || response.data.order_id) return false;
+
+    const draft = response.data;
+    for (const item of draft.items || []) {
+      if (cart.value.some((cartItem) => cartItem.id === item.product_id)) continue;
+      const product = await getProductById(item.product_id, language);
+      if (!product.success) continue;
+      addProductToCart(
+        {
+          id: product.data.id,
+          name: product.data.name,
+          price: product.data.price,
+          image: product.data.images?.[0],
+          currency: product.data.currency,
+        },
+        item.quantity
+      );
+    }
+
+    setDraftId(draft.id);
+    restoredContacts.value = { name: draft.name, email: draft.email, phone: draft.phone };
+    return true;
+  };
+
+  // Забывает черновик после оформления заказа
+  const clearDraft = () => {
+    clearTimeout(saveTimer);
+    setDraftId(null);
+    restoredContacts.value = null;
+  };
+
+  return {
+    restoredContacts,
+    getDraftId,
+    scheduleSave,
+    restoreDraft,
+    clearDraft,
+  };
+};
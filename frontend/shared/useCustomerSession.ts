@@ -0,0 +1,31 @@
+import { ref } from "vue";
+
+// Токен личного кабинета покупателя хранится между сеансами
+const CUSTOMER_TOKEN_KEY = "prianik-customer-token";
+
+const token = ref<string | null>(null);
+
+const loadToken = () => {
+  if (process.client && token.value === null) {
+    token.value = localStorage.getItem(CUSTOMER_TOKEN_KEY);
+  }
+};
+
+export const useCustomerSession = () => {
+  loadToken();
+
+  const setToken = (value: string | null) => {
+    token.value = value;
+    if (!process.client) return;
+    if (value) {
+      localStorage.setItem(CUSTOMER_TOKEN_KEY, value);
+    } else {
+      localStorage.removeItem(CUSTOMER_TOKEN_KEY);
+    }
+  };
+
+  return {
+    token,
+    setToken,
+  };
+};